@@ -1,17 +1,28 @@
 package cmd
 
 import (
+	"context"
 	"embed"
+	"fmt"
 	"io/fs"
 	"net/http"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"asynclab.club/asynx/backend/pkg/client"
 	"asynclab.club/asynx/backend/pkg/config"
 	"asynclab.club/asynx/backend/pkg/controller"
+	"asynclab.club/asynx/backend/pkg/event"
+	"asynclab.club/asynx/backend/pkg/mail"
+	"asynclab.club/asynx/backend/pkg/oidc"
+	"asynclab.club/asynx/backend/pkg/outbox"
 	"asynclab.club/asynx/backend/pkg/repository"
+	"asynclab.club/asynx/backend/pkg/saga"
+	"asynclab.club/asynx/backend/pkg/security"
 	"asynclab.club/asynx/backend/pkg/service"
+	"asynclab.club/asynx/backend/pkg/webhook"
 	_ "asynclab.club/asynx/docs"
 	"github.com/caarlos0/env/v11"
 	"github.com/dsx137/gg-logging/pkg/logging"
@@ -29,7 +40,8 @@ func initRouter(r *gin.Engine, embedFS embed.FS) error {
 	r.GET("/assets/*filepath", gin.WrapH(http.FileServer(http.FS(clientDistFS))))
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	r.NoRoute(func(c *gin.Context) {
-		if strings.HasPrefix(c.Request.RequestURI, "/api") {
+		if strings.HasPrefix(c.Request.RequestURI, "/api") || strings.HasPrefix(c.Request.RequestURI, "/scim") ||
+			strings.HasPrefix(c.Request.RequestURI, "/oidc") {
 			c.Status(404)
 			return
 		}
@@ -38,12 +50,41 @@ func initRouter(r *gin.Engine, embedFS embed.FS) error {
 		c.Abort()
 	})
 
-	ldapCfg, err := env.ParseAs[config.ConfigLDAP]()
+	// 自定义角色需要在创建存储之前注册，内存目录会为其创建角色组
+	rolesCfg, err := env.ParseAs[config.ConfigRoles]()
 	if err != nil {
 		return err
 	}
+	if err := registerRoles(&rolesCfg); err != nil {
+		return err
+	}
+
+	store, directory, err := newStore()
+	if err != nil {
+		return err
+	}
+	for _, role := range security.CustomRoles() {
+		group, err := store.Groups().FindByOuAndCn(security.OuGroupSupplementary.String(), role.String())
+		if err != nil {
+			return err
+		}
+		if group == nil {
+			logrus.Warnf("Role group %s not found in %s, the role cannot be granted", role, security.OuGroupSupplementary)
+		}
+	}
+
+	cacheCfg, err := env.ParseAs[config.ConfigCache]()
+	if err != nil {
+		return err
+	}
+
+	var cachedStore *repository.StoreCached
+	if cacheCfg.Enabled {
+		cachedStore = repository.NewStoreCached(store, cacheCfg.TTL)
+		store = cachedStore
+	}
 
-	ldapClient, err := client.NewLdapClient(&ldapCfg)
+	dataCfg, err := env.ParseAs[config.ConfigData]()
 	if err != nil {
 		return err
 	}
@@ -52,28 +93,236 @@ func initRouter(r *gin.Engine, embedFS embed.FS) error {
 	if err != nil {
 		return err
 	}
+	if emailCfg.Maildir == "" {
+		emailCfg.Maildir = filepath.Join(dataCfg.Dir, "maildir")
+	}
 
-	tmpl, err := embedFS.ReadFile("templates/email.html")
+	mailTransport, err := client.NewMailTransport(&emailCfg)
 	if err != nil {
 		return err
 	}
 
-	emailClient, err := client.NewEmailClient(&emailCfg, tmpl)
+	emailClient, err := client.NewEmailClient(&emailCfg, mailTransport)
 	if err != nil {
 		return err
 	}
 
-	serviceUser := service.NewServiceUser(repository.NewRepositoryUser(ldapClient))
-	serviceGroup := service.NewServiceGroup(repository.NewRepositoryGroup(ldapClient))
-	serviceManager := service.NewServiceManager(serviceUser, serviceGroup, emailClient)
+	templateCfg, err := env.ParseAs[config.ConfigMailTemplate]()
+	if err != nil {
+		return err
+	}
+
+	templateFS, _ := fs.Sub(embedFS, "templates/mail")
+	templates, err := mail.NewTemplates(&templateCfg, templateFS)
+	if err != nil {
+		return err
+	}
+
+	coordinator, err := saga.NewCoordinator(dataCfg.Dir)
+	if err != nil {
+		return err
+	}
+
+	outboxCfg, err := env.ParseAs[config.ConfigOutbox]()
+	if err != nil {
+		return err
+	}
+
+	mailOutbox, err := outbox.NewOutbox(&outboxCfg, dataCfg.Dir, emailClient, templates)
+	if err != nil {
+		return err
+	}
+	go mailOutbox.Run(context.Background())
+
+	serviceNotification := service.NewServiceNotification(store.Shared(), mailOutbox)
+
+	serviceBroadcast := service.NewServiceBroadcast(store, mailOutbox)
+
+	bus := event.NewBus()
+
+	webhookCfg, err := env.ParseAs[config.ConfigWebhook]()
+	if err != nil {
+		return err
+	}
+
+	dispatcher := webhook.NewDispatcher(&webhookCfg, store.Shared(), bus)
+	go dispatcher.Run(context.Background())
+
+	streamCfg, err := env.ParseAs[config.ConfigStream]()
+	if err != nil {
+		return err
+	}
+	stream := event.NewStream(streamCfg.Capacity, streamCfg.Buffer, bus)
+
+	if ldapClient, ok := directory.(*client.LdapClient); ok {
+		if err := watchDirectory(ldapClient, bus); err != nil {
+			return err
+		}
+	}
+
+	serviceManager := service.NewServiceManager(store, coordinator, serviceNotification, service.NewServiceDelegation(store.Shared()), bus)
+
+	roleGrantCfg, err := env.ParseAs[config.ConfigRoleGrant]()
+	if err != nil {
+		return err
+	}
+	serviceRoleGrant := service.NewServiceRoleGrant(&roleGrantCfg, serviceManager, bus)
+	accountExpiryCfg, err := env.ParseAs[config.ConfigAccountExpiry]()
+	if err != nil {
+		return err
+	}
+	serviceAccountExpiry := service.NewServiceAccountExpiry(&accountExpiryCfg, serviceManager, bus)
+	lifecycleCfg, err := env.ParseAs[config.ConfigLifecycle]()
+	if err != nil {
+		return err
+	}
+	serviceLifecycle, err := service.NewServiceLifecycle(&lifecycleCfg, serviceManager, bus)
+	if err != nil {
+		return err
+	}
+	registrationCfg, err := env.ParseAs[config.ConfigRegistration]()
+	if err != nil {
+		return err
+	}
+	serviceRegistration, err := service.NewServiceRegistration(&registrationCfg, templateCfg.SiteUrl, serviceManager, serviceNotification)
+	if err != nil {
+		return err
+	}
+	invitationCfg, err := env.ParseAs[config.ConfigInvitation]()
+	if err != nil {
+		return err
+	}
+	serviceInvitation := service.NewServiceInvitation(&invitationCfg, templateCfg.SiteUrl, serviceManager, serviceNotification)
+	serviceOperation := service.NewServiceOperation(coordinator)
+	serviceOutbox := service.NewServiceOutbox(mailOutbox)
+	serviceMail := service.NewServiceMail(templates)
+	serviceWebhook := service.NewServiceWebhook(dispatcher)
+	serviceEvent := service.NewServiceEvent(stream)
+	serviceCache := service.NewServiceCache(cachedStore, cacheCfg.TTL.String(), bus)
+
+	scimCfg, err := env.ParseAs[config.ConfigScim]()
+	if err != nil {
+		return err
+	}
+	// 多余的逗号会产生空令牌，空令牌会与空的 Bearer 匹配
+	scimCfg.Tokens = slices.DeleteFunc(scimCfg.Tokens, func(token string) bool { return strings.TrimSpace(token) == "" })
+
+	accessTokenCfg, err := env.ParseAs[config.ConfigAccessToken]()
+	if err != nil {
+		return err
+	}
+	serviceAccessToken := service.NewServiceAccessToken(&accessTokenCfg, serviceManager, bus)
+	security.SetSessionResolver(serviceManager.ResolveSession)
+	security.SetAccessTokenVerifier(serviceAccessToken.Verify)
+	serviceServiceAccount := service.NewServiceServiceAccount(serviceManager, serviceAccessToken)
+	if err := serviceManager.MarkLegacyServiceAccounts(); err != nil {
+		return err
+	}
+
+	forwardAuthCfg, err := env.ParseAs[config.ConfigForwardAuth]()
+	if err != nil {
+		return err
+	}
+
+	oidcCfg, err := env.ParseAs[config.ConfigOidc]()
+	if err != nil {
+		return err
+	}
+	var serviceOidc *service.ServiceOidc
+	if oidcCfg.Issuer != "" {
+		provider, err := oidc.NewProvider(&oidcCfg, store.Shared())
+		if err != nil {
+			return err
+		}
+		serviceOidc = service.NewServiceOidc(provider, serviceManager, bus)
+	} else {
+		logrus.Info("OIDC_ISSUER is not set, OIDC provider is disabled")
+	}
+
+	// 所有操作注册完成后再恢复上次中断的操作
+	if err := serviceOperation.Recover(); err != nil {
+		logrus.Errorf("Failed to recover unfinished operations: %v", err)
+	}
+	go coordinator.Run(context.Background(), dataCfg.OperationRetention)
+
+	schedulerCfg, err := env.ParseAs[config.ConfigScheduler]()
+	if err != nil {
+		return err
+	}
+	jobScheduler, err := newScheduler(&schedulerCfg, directory, dataCfg.Dir)
+	if err != nil {
+		return err
+	}
+	if err := registerJobs(jobScheduler, serviceManager, serviceRoleGrant, serviceAccountExpiry, serviceLifecycle, serviceRegistration, serviceInvitation, serviceAccessToken, serviceOidc); err != nil {
+		return err
+	}
+	if schedulerCfg.Enabled {
+		go jobScheduler.Run(context.Background())
+	} else {
+		logrus.Info("SCHEDULER_ENABLED is false, background jobs will not run on this instance")
+	}
+	serviceScheduler := service.NewServiceScheduler(jobScheduler)
 
 	api := r.Group("/api")
 	{
 		controller.NewControllerHello(api.Group("/hello"))
 		controller.NewControllerTokens(api.Group("/tokens"), serviceManager)
-		controller.NewControllerUser(api.Group("/users"), serviceManager)
+		controller.NewControllerAccessTokens(api.Group("/access-tokens"), serviceAccessToken)
+		controller.NewControllerServiceAccounts(api.Group("/service-accounts"), serviceServiceAccount)
+		controller.NewControllerAuth(api.Group("/auth"), &forwardAuthCfg, service.NewServiceForwardAuth(serviceManager))
+		controller.NewControllerUser(api.Group("/users"), serviceManager, serviceRoleGrant)
+		controller.NewControllerRoles(api.Group("/roles"))
+		controller.NewControllerOuAdmins(api.Group("/ou-admins"), serviceManager)
+		controller.NewControllerRoleGrants(api.Group("/role-grants"), serviceRoleGrant)
+		controller.NewControllerOperations(api.Group("/operations"), serviceOperation)
+		controller.NewControllerOutbox(api.Group("/outbox"), serviceOutbox)
+		controller.NewControllerMail(api.Group("/mail"), serviceMail)
+		controller.NewControllerBroadcasts(api.Group("/broadcasts"), serviceBroadcast)
+		controller.NewControllerWebhooks(api.Group("/webhooks"), serviceWebhook)
+		controller.NewControllerEvents(api.Group("/events"), serviceEvent, streamCfg.Heartbeat)
+		controller.NewControllerCache(api.Group("/cache"), serviceCache)
+		controller.NewControllerJobs(api.Group("/jobs"), serviceScheduler)
+		controller.NewControllerLifecycle(api.Group("/lifecycle"), serviceLifecycle)
+		controller.NewControllerRegistration(api.Group("/registration-requests"), serviceRegistration, serviceManager)
+		controller.NewControllerInvitations(api.Group("/invitations"), serviceInvitation, serviceManager)
+		if serviceOidc != nil {
+			controller.NewControllerOidc(api.Group("/oidc"), serviceOidc)
+		}
+
+		if mailSink, ok := mailTransport.(*client.MemoryMailTransport); ok {
+			logrus.Warn("Using in-memory mail transport, sent mails are available at /api/dev/mails")
+			controller.NewControllerDev(api.Group("/dev"), service.NewServiceDev(mailSink))
+		}
 	}
 
+	// SCIM 使用自己的路径和格式，不在 /api 之下
+	if len(scimCfg.Tokens) > 0 {
+		controller.NewControllerScim(r.Group("/scim/v2"), service.NewServiceScim(&scimCfg, serviceManager), scimCfg.Tokens)
+	} else {
+		logrus.Info("SCIM_TOKENS is not set, SCIM provisioning is disabled")
+	}
+
+	// 协议端点按规范放在站点根路径下，与前端同源以便读取登录状态
+	if serviceOidc != nil {
+		controller.NewControllerOidcProtocol(r.Group(""), &forwardAuthCfg, serviceOidc)
+	}
+
+	return nil
+}
+
+// registerRoles 注册配置中的自定义角色
+func registerRoles(cfg *config.ConfigRoles) error {
+	for name, value := range cfg.Permissions {
+		permissions, err := security.ParsePermissions(strings.Split(value, ","))
+		if err != nil {
+			return fmt.Errorf("role %s: %w", name, err)
+		}
+		role := security.Role(strings.TrimSpace(name))
+		if err := security.RegisterRole(role, permissions); err != nil {
+			return err
+		}
+		logrus.Infof("Registered role %s with permissions %v", role, permissions)
+	}
 	return nil
 }
 
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/client"
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/scheduler"
+	"asynclab.club/asynx/backend/pkg/service"
+	"github.com/dsx137/gg-kit/pkg/ggkit"
+	"github.com/sirupsen/logrus"
+)
+
+// newScheduler 创建任务调度器。有目录客户端时通过目录中的锁条目选主，否则认为只有一个实例
+func newScheduler(cfg *config.ConfigScheduler, directory client.DirectoryClient, dataDir string) (*scheduler.Scheduler, error) {
+	instance := cfg.Instance
+	if instance == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "asynx"
+		}
+		suffix, err := ggkit.GenerateReadableKey(6, 0)
+		if err != nil {
+			return nil, err
+		}
+		instance = fmt.Sprintf("%s-%s", hostname, suffix)
+	}
+	if strings.ContainsAny(instance, " \t\n") {
+		return nil, fmt.Errorf("scheduler instance name %q must not contain whitespace", instance)
+	}
+	if cfg.LockTTL < 3*time.Second {
+		return nil, fmt.Errorf("scheduler lock TTL %s is too short", cfg.LockTTL)
+	}
+
+	var lock scheduler.Lock = scheduler.LocalLock{}
+	if directory != nil {
+		lockDn := cfg.LockDn
+		if lockDn == "" {
+			lockDn = directory.BuildDn("cn=asynx-scheduler")
+		}
+		lock = scheduler.NewDirectoryLock(directory, lockDn)
+	} else {
+		logrus.Warn("No directory to elect a scheduler leader, run only one replica with SCHEDULER_ENABLED")
+	}
+	return scheduler.New(lock, instance, cfg.LockTTL, cfg.Schedules, dataDir)
+}
+
+// registerJobs 注册内置的后台任务
+func registerJobs(s *scheduler.Scheduler, manager *service.ServiceManager, roleGrant *service.ServiceRoleGrant, accountExpiry *service.ServiceAccountExpiry, lifecycle *service.ServiceLifecycle, registration *service.ServiceRegistration, invitation *service.ServiceInvitation, accessToken *service.ServiceAccessToken, oidc *service.ServiceOidc) error {
+	jobs := []struct {
+		name, schedule, description string
+		fn                          scheduler.Func
+	}{
+		{"role-grants", "@every 1m", "发送临时角色到期提醒并恢复已到期的角色", func(context.Context) error {
+			return roleGrant.Expire(time.Now())
+		}},
+		{"account-expiry", "@hourly", "向即将到期的账号发送提醒", func(context.Context) error {
+			return accountExpiry.Remind(time.Now())
+		}},
+		{"alumni-lifecycle", "@daily", "提醒即将转为 alumni 的成员，并转换已到期的成员", func(context.Context) error {
+			return lifecycle.Apply(time.Now())
+		}},
+		{"access-tokens-purge", "@daily", "清理过期超过保留期的个人访问令牌", func(context.Context) error {
+			_, err := accessToken.Purge(time.Now())
+			return err
+		}},
+		{"registration-requests-purge", "@daily", "清理过期未验证和处理完超过保留期的注册申请", func(context.Context) error {
+			_, err := registration.Purge(time.Now())
+			return err
+		}},
+		{"invitations-purge", "@daily", "清理过期或撤销超过保留期的邀请", func(context.Context) error {
+			_, err := invitation.Purge(time.Now())
+			return err
+		}},
+		{"oidc-purge", "@every 10m", "清理过期的 OIDC 授权请求和授权码", func(context.Context) error {
+			if oidc == nil {
+				return nil
+			}
+			_, err := oidc.Purge(time.Now())
+			return err
+		}},
+		{"uid-numbers", "0 4 * * *", "为缺少 uidNumber 的用户分配编号，发现重复的编号时报错", func(context.Context) error {
+			return manager.ReconcileUidNumbers()
+		}},
+	}
+	for _, job := range jobs {
+		if err := s.Register(job.name, job.schedule, job.description, job.fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
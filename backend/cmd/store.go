@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"asynclab.club/asynx/backend/pkg/client"
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/entity"
+	"asynclab.club/asynx/backend/pkg/event"
+	"asynclab.club/asynx/backend/pkg/repository"
+	"asynclab.club/asynx/backend/pkg/security"
+	"asynclab.club/asynx/backend/pkg/service"
+	"github.com/caarlos0/env/v11"
+	"github.com/dsx137/gg-kit/pkg/ggkit"
+	"github.com/sirupsen/logrus"
+)
+
+// newStore 创建目录存储，使用 LDAP 或内存后端时同时返回目录客户端，供监听外部修改和选主；SQL 后端没有目录客户端
+func newStore() (repository.Store, client.DirectoryClient, error) {
+	directoryCfg, err := env.ParseAs[config.ConfigDirectory]()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch directoryCfg.Backend {
+	case "ldap":
+		ldapCfg, err := env.ParseAs[config.ConfigLDAP]()
+		if err != nil {
+			return nil, nil, err
+		}
+		ldapClient, err := client.NewLdapClient(&ldapCfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return repository.NewStoreLdap(ldapClient, directoryCfg.SharedDn), ldapClient, nil
+	case "memory":
+		memoryCfg, err := env.ParseAs[config.ConfigMemory]()
+		if err != nil {
+			return nil, nil, err
+		}
+		memoryClient, err := newMemoryClient(&memoryCfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		store := repository.NewStoreLdap(memoryClient, directoryCfg.SharedDn)
+		if err := bootstrapAdmin(store, memoryCfg.AdminUid, memoryCfg.AdminPassword); err != nil {
+			return nil, nil, err
+		}
+		return store, memoryClient, nil
+	case "sql":
+		sqlCfg, err := env.ParseAs[config.ConfigSQL]()
+		if err != nil {
+			return nil, nil, err
+		}
+		sqlClient, err := client.NewSqlClient(&sqlCfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		store, err := repository.NewStoreSql(sqlClient)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := bootstrapAdmin(store, sqlCfg.AdminUid, sqlCfg.AdminPassword); err != nil {
+			return nil, nil, err
+		}
+		return store, nil, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown directory backend: %s", directoryCfg.Backend)
+	}
+}
+
+type memorySeed struct {
+	dn          string
+	objectClass []string
+	attributes  map[string][]string
+}
+
+// watchDirectory 在后台监听用户和组的外部修改，并发布到事件总线。
+// 每个实例都要监听，以便清空自己的缓存、向自己的事件流推送其他实例的写入；
+// 同一变更在每个实例上产生 Id 相同的事件，对外投递的订阅者（Webhook）按 Id 去重
+func watchDirectory(ldapClient *client.LdapClient, bus *event.Bus) error {
+	watchCfg, err := env.ParseAs[config.ConfigLdapWatch]()
+	if err != nil {
+		return err
+	}
+
+	switch watchCfg.Mode {
+	case client.WatchModeOff:
+		return nil
+	case client.WatchModeAuto, client.WatchModeSyncrepl, client.WatchModePoll:
+	default:
+		return fmt.Errorf("unknown LDAP watch mode: %s", watchCfg.Mode)
+	}
+
+	serviceWatch := service.NewServiceWatch(bus)
+	ctx := context.Background()
+	go ldapClient.Watch(ctx, &watchCfg, ldapClient.GetUserBaseDn(), config.UserObjectFilter, serviceWatch.HandleUser)
+	go ldapClient.Watch(ctx, &watchCfg, ldapClient.GetGroupBaseDn(), config.GroupObjectFilter, serviceWatch.HandleGroup)
+	return nil
+}
+
+// newMemoryClient 创建内存目录，并写入组织单元和角色组
+func newMemoryClient(cfg *config.ConfigMemory) (*client.MemoryClient, error) {
+	logrus.Warn("Using in-memory directory, all data will be lost on exit")
+
+	userBaseDn := fmt.Sprintf("ou=people,%s", cfg.BaseDN)
+	groupBaseDn := fmt.Sprintf("ou=groups,%s", cfg.BaseDN)
+
+	mem, err := client.NewMemoryClient(cfg.BaseDN, userBaseDn, groupBaseDn)
+	if err != nil {
+		return nil, err
+	}
+
+	ouClasses := []string{"organizationalUnit"}
+	seeds := []memorySeed{
+		{userBaseDn, ouClasses, nil},
+		{groupBaseDn, ouClasses, nil},
+	}
+	for _, ou := range []security.OuUser{security.OuUserSystem, security.OuUserMember, security.OuUserExternal, security.OuUserAlumni} {
+		seeds = append(seeds, memorySeed{fmt.Sprintf("ou=%s,%s", ou, userBaseDn), ouClasses, nil})
+	}
+	for _, ou := range []security.OuGroup{security.OuGroupPrimary, security.OuGroupSupplementary, security.OuGroupAdditional} {
+		seeds = append(seeds, memorySeed{fmt.Sprintf("ou=%s,%s", ou, groupBaseDn), ouClasses, nil})
+	}
+	seeds = append(seeds, memorySeed{
+		fmt.Sprintf("cn=users,ou=%s,%s", security.OuGroupPrimary, groupBaseDn),
+		config.GroupObjectClasses,
+		map[string][]string{"gidNumber": {config.LdapGidNumber}},
+	})
+	for i, role := range append([]security.Role{security.RoleAdmin, security.RoleDefault, security.RoleRestricted}, security.CustomRoles()...) {
+		seeds = append(seeds, memorySeed{
+			fmt.Sprintf("cn=%s,ou=%s,%s", role, security.OuGroupSupplementary, groupBaseDn),
+			config.GroupObjectClasses,
+			map[string][]string{"gidNumber": {strconv.Itoa(20001 + i)}},
+		})
+	}
+
+	for _, seed := range seeds {
+		if err := mem.Add(seed.dn, seed.objectClass, seed.attributes); err != nil {
+			return nil, fmt.Errorf("failed to seed memory directory: %w", err)
+		}
+	}
+
+	return mem, nil
+}
+
+// bootstrapAdmin 在存储中没有任何用户时创建一个初始管理员
+func bootstrapAdmin(store repository.Store, uid, password string) error {
+	users, err := store.Users().FindAll()
+	if err != nil {
+		return err
+	}
+	if len(users) != 0 {
+		return nil
+	}
+
+	if password == "" {
+		password, err = ggkit.GenerateReadableKey(32, 0)
+		if err != nil {
+			return err
+		}
+		logrus.Warnf("Generated bootstrap admin password for %s: %s", uid, password)
+	}
+
+	admin := &entity.User{
+		Uid:           uid,
+		Cn:            uid,
+		Ou:            security.OuUserSystem.String(),
+		Sn:            uid,
+		GivenName:     uid,
+		GidNumber:     config.LdapGidNumber,
+		UidNumber:     "1",
+		HomeDirectory: fmt.Sprintf("/home/%s", uid),
+		UserPassword:  password,
+		LoginShell:    "/bin/bash",
+	}
+
+	return store.Transaction(func(store repository.Store) error {
+		if err := store.Users().Create(admin); err != nil {
+			return fmt.Errorf("failed to create bootstrap admin: %w", err)
+		}
+		adminGroup, err := store.Groups().FindByOuAndCn(security.OuGroupSupplementary.String(), security.RoleAdmin.String())
+		if err != nil {
+			return err
+		}
+		if adminGroup == nil {
+			return fmt.Errorf("role group %s not found", security.RoleAdmin)
+		}
+		return store.Groups().AddMemberUid(adminGroup, admin.Uid)
+	})
+}
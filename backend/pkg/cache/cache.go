@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type item[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// Stats 是缓存的命中统计，计数从进程启动开始累计
+type Stats struct {
+	Name    string  `json:"name"`
+	Entries int     `json:"entries"`
+	Hits    uint64  `json:"hits"`
+	Misses  uint64  `json:"misses"`
+	HitRate float64 `json:"hitRate"`
+	Flushes uint64  `json:"flushes"`
+}
+
+// Cache 是按键过期的内存缓存，过期条目在读取时淘汰
+type Cache[V any] struct {
+	name  string
+	ttl   time.Duration
+	mu    sync.RWMutex
+	items map[string]item[V]
+	// 每次 Flush 加一，用于识别在清空之前开始的读取
+	generation uint64
+
+	hits    atomic.Uint64
+	misses  atomic.Uint64
+	flushes atomic.Uint64
+}
+
+func New[V any](name string, ttl time.Duration) *Cache[V] {
+	return &Cache[V]{name: name, ttl: ttl, items: make(map[string]item[V])}
+}
+
+func (c *Cache[V]) Get(key string) (V, bool) {
+	c.mu.RLock()
+	it, ok := c.items[key]
+	c.mu.RUnlock()
+
+	if ok && time.Now().Before(it.expiresAt) {
+		c.hits.Add(1)
+		return it.value, true
+	}
+	if ok {
+		c.mu.Lock()
+		if it, ok := c.items[key]; ok && !time.Now().Before(it.expiresAt) {
+			delete(c.items, key)
+		}
+		c.mu.Unlock()
+	}
+	c.misses.Add(1)
+	return *new(V), false
+}
+
+func (c *Cache[V]) Set(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items[key] = item[V]{value: value, expiresAt: time.Now().Add(c.ttl)}
+}
+
+// Generation 返回缓存当前的代数，应在读取后端之前取得，再交给 SetIfGeneration
+func (c *Cache[V]) Generation() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.generation
+}
+
+// SetIfGeneration 只在 generation 之后没有发生过 Flush 时写入，否则丢弃这个可能已经过时的值
+func (c *Cache[V]) SetIfGeneration(key string, value V, generation uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation != generation {
+		return false
+	}
+	c.items[key] = item[V]{value: value, expiresAt: time.Now().Add(c.ttl)}
+	return true
+}
+
+// Flush 清空缓存
+func (c *Cache[V]) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.items)
+	c.generation++
+	c.flushes.Add(1)
+}
+
+func (c *Cache[V]) Stats() Stats {
+	c.mu.RLock()
+	entries := len(c.items)
+	c.mu.RUnlock()
+
+	stats := Stats{
+		Name:    c.name,
+		Entries: entries,
+		Hits:    c.hits.Load(),
+		Misses:  c.misses.Load(),
+		Flushes: c.flushes.Load(),
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetIfGenerationDropsValuesReadBeforeFlush(t *testing.T) {
+	c := New[string]("users", time.Minute)
+
+	generation := c.Generation()
+	c.Flush()
+	if c.SetIfGeneration("uid:2024000001", "stale", generation) {
+		t.Error("value read before a flush was cached")
+	}
+	if _, ok := c.Get("uid:2024000001"); ok {
+		t.Error("stale value is in the cache")
+	}
+
+	if !c.SetIfGeneration("uid:2024000001", "fresh", c.Generation()) {
+		t.Fatal("value read after the flush was dropped")
+	}
+	if value, ok := c.Get("uid:2024000001"); !ok || value != "fresh" {
+		t.Errorf("got %q, %v", value, ok)
+	}
+}
+
+func TestEntriesExpire(t *testing.T) {
+	c := New[string]("groups", time.Millisecond)
+	c.Set("all", "value")
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := c.Get("all"); ok {
+		t.Error("expired entry was returned")
+	}
+	if stats := c.Stats(); stats.Entries != 0 || stats.Misses != 1 {
+		t.Errorf("got stats %+v", stats)
+	}
+}
@@ -0,0 +1,24 @@
+package client
+
+import "github.com/go-ldap/ldap/v3"
+
+// DirectoryClient 是仓储层依赖的目录操作集合，LdapClient 与 MemoryClient 均实现该接口
+type DirectoryClient interface {
+	BuildDn(rdn string) string
+	GetUserBaseDn() string
+	GetGroupBaseDn() string
+	Close() error
+
+	Authenticate(dn, password string) (bool, error)
+	Search(baseDN string, filter string, attributes []string) (*ldap.SearchResult, error)
+	Add(dn string, objectClass []string, attributes map[string][]string) error
+	ModifyAttributes(dn string, addAttrs, delAttrs, replaceAttrs map[string][]string) error
+	Delete(dn string) error
+	ModifyDn(dn, newRDN, newSuperior string) error
+	ModifyPassword(dn, newPassword string) error
+}
+
+var (
+	_ DirectoryClient = (*LdapClient)(nil)
+	_ DirectoryClient = (*MemoryClient)(nil)
+)
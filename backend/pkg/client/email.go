@@ -1,65 +1,93 @@
 package client
 
 import (
-	"html/template"
-	"strings"
+	"fmt"
+	"time"
 
 	"asynclab.club/asynx/backend/pkg/config"
 	"gopkg.in/gomail.v2"
 )
 
-type EmailClient struct {
-	cfg  *config.ConfigEmail
-	tmpl []byte
+// Mail 是一封已渲染好的邮件，Text 不为空时作为纯文本备选正文
+type Mail struct {
+	Id      string    `json:"id"`
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	ReplyTo string    `json:"replyTo,omitempty"`
+	Subject string    `json:"subject"`
+	Html    string    `json:"html"`
+	Text    string    `json:"text,omitempty"`
+	SentAt  time.Time `json:"sentAt"`
 }
 
-func NewEmailClient(cfg *config.ConfigEmail, tmpl []byte) (*EmailClient, error) {
-	return &EmailClient{
-		cfg:  cfg,
-		tmpl: tmpl,
-	}, nil
+func (m *Mail) message() *gomail.Message {
+	msg := gomail.NewMessage()
+	msg.SetHeader("From", m.From)
+	msg.SetHeader("To", m.To)
+	msg.SetHeader("Subject", m.Subject)
+	if m.ReplyTo != "" {
+		msg.SetHeader("Reply-To", m.ReplyTo)
+	}
+	msg.SetDateHeader("Date", m.SentAt)
+	if m.Text != "" {
+		msg.SetBody("text/plain", m.Text)
+		msg.AddAlternative("text/html", m.Html)
+	} else {
+		msg.SetBody("text/html", m.Html)
+	}
+	return msg
 }
 
-func (c *EmailClient) send(to, subject, body string) error {
-	m := gomail.NewMessage()
-	m.SetHeader("From", c.cfg.From)
-	m.SetHeader("To", to)
-	m.SetHeader("Subject", subject)
-	m.SetHeader("Reply-To", c.cfg.ReplyTo)
-	m.SetBody("text/html", body)
-
-	d := gomail.NewDialer(c.cfg.Host, c.cfg.Port, c.cfg.Username, c.cfg.Password)
-
-	return d.DialAndSend(m)
+// MailTransport 负责把邮件真正送出去
+type MailTransport interface {
+	Send(mail *Mail) error
 }
 
-// 加载嵌入的HTML模板
-func (c *EmailClient) loadTemplate(name string) (*template.Template, error) {
-	// content, err := templateFS.ReadFile("templates/" + name)
-	// if err != nil {
-	// 	return nil, err
-	// }
-	return template.New(name).Parse(string(c.tmpl))
+// NewMailTransport 按配置创建邮件传输方式：smtp、maildir 或 memory
+func NewMailTransport(cfg *config.ConfigEmail) (MailTransport, error) {
+	switch cfg.Transport {
+	case "smtp":
+		if cfg.Host == "" || cfg.From == "" {
+			return nil, fmt.Errorf("SMTP_HOST and SMTP_FROM are required by smtp mail transport")
+		}
+		return NewSmtpTransport(cfg), nil
+	case "maildir":
+		return NewMaildirTransport(cfg.Maildir)
+	case "memory":
+		return NewMemoryMailTransport(cfg.MemoryCapacity), nil
+	default:
+		return nil, fmt.Errorf("unsupported mail transport: %s", cfg.Transport)
+	}
 }
 
-// 发送邮件处理器
-func (c *EmailClient) SendMail(to string, subject string, body any) error {
-	// 加载邮件模板
-	tmpl, err := c.loadTemplate("email.html")
-	if err != nil {
-		return err
-	}
+// ----------------------------------------------------------------------------------------------------------------------
 
-	var htmlBody strings.Builder
-	err = tmpl.Execute(&htmlBody, body)
-	if err != nil {
-		return err
-	}
+type EmailClient struct {
+	cfg       *config.ConfigEmail
+	transport MailTransport
+}
+
+func NewEmailClient(cfg *config.ConfigEmail, transport MailTransport) (*EmailClient, error) {
+	return &EmailClient{
+		cfg:       cfg,
+		transport: transport,
+	}, nil
+}
 
-	err = c.send(to, subject, htmlBody.String())
-	if err != nil {
-		return err
+// Deliver 发送一封已渲染好的邮件，text 不为空时作为纯文本备选正文
+func (c *EmailClient) Deliver(to, subject, html, text string) error {
+	from := c.cfg.From
+	if from == "" {
+		from = "asynx@localhost"
 	}
 
-	return nil
+	return c.transport.Send(&Mail{
+		From:    from,
+		To:      to,
+		ReplyTo: c.cfg.ReplyTo,
+		Subject: subject,
+		Html:    html,
+		Text:    text,
+		SentAt:  time.Now(),
+	})
 }
@@ -2,6 +2,8 @@ package client
 
 import (
 	"fmt"
+	"sync"
+	"time"
 
 	"asynclab.club/asynx/backend/pkg/config"
 	"github.com/dsx137/gg-kit/pkg/ggkit"
@@ -27,6 +29,10 @@ func validateConfig(cfg *config.ConfigLDAP) error {
 type LdapClient struct {
 	connPool *ggkit.ReusePool[ldap.Conn]
 	cfg      *config.ConfigLDAP
+
+	// 最近由 asynx 写入的条目，用于在监听时区分外部修改
+	writesMu sync.Mutex
+	writes   map[string]time.Time
 }
 
 func NewLdapClient(cfg *config.ConfigLDAP) (*LdapClient, error) {
@@ -34,20 +40,9 @@ func NewLdapClient(cfg *config.ConfigLDAP) (*LdapClient, error) {
 		return nil, err
 	}
 
+	c := &LdapClient{cfg: cfg, writes: make(map[string]time.Time)}
 	pool, err := ggkit.NewReusePool(
-		func() (*ldap.Conn, error) {
-			conn, err := ldap.DialURL(cfg.Addr)
-			if err != nil {
-				logrus.Errorf("failed to dial LDAP server: %v", err)
-				return nil, fmt.Errorf("failed to dial LDAP server")
-			}
-
-			if err := conn.Bind(cfg.BindDN, cfg.BindPass); err != nil {
-				conn.Close()
-				return nil, fmt.Errorf("failed to bind with admin credentials: %w", err)
-			}
-			return conn, nil
-		},
+		c.dial,
 		func(conn *ldap.Conn) bool {
 			if conn == nil || conn.IsClosing() {
 				return false
@@ -66,7 +61,23 @@ func NewLdapClient(cfg *config.ConfigLDAP) (*LdapClient, error) {
 		return nil, fmt.Errorf("failed to create connection pool: %w", err)
 	}
 
-	return &LdapClient{connPool: pool, cfg: cfg}, nil
+	c.connPool = pool
+	return c, nil
+}
+
+// dial 建立一个以管理员身份绑定的新连接
+func (c *LdapClient) dial() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(c.cfg.Addr)
+	if err != nil {
+		logrus.Errorf("failed to dial LDAP server: %v", err)
+		return nil, fmt.Errorf("failed to dial LDAP server")
+	}
+
+	if err := conn.Bind(c.cfg.BindDN, c.cfg.BindPass); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to bind with admin credentials: %w", err)
+	}
+	return conn, nil
 }
 
 func (c *LdapClient) withConnection(fn func(*ldap.Conn) error) error {
@@ -139,6 +150,7 @@ func (c *LdapClient) Search(baseDN string, filter string, attributes []string) (
 }
 
 func (c *LdapClient) Add(dn string, objectClass []string, attributes map[string][]string) error {
+	c.markWritten(dn)
 	return c.withConnection(func(conn *ldap.Conn) error {
 		addRequest := ldap.NewAddRequest(dn, nil)
 		addRequest.Attribute("objectClass", objectClass)
@@ -150,6 +162,7 @@ func (c *LdapClient) Add(dn string, objectClass []string, attributes map[string]
 }
 
 func (c *LdapClient) ModifyAttributes(dn string, addAttrs, delAttrs, replaceAttrs map[string][]string) error {
+	c.markWritten(dn)
 	return c.withConnection(func(conn *ldap.Conn) error {
 		modifyReq := ldap.NewModifyRequest(dn, nil)
 		for attr, values := range addAttrs {
@@ -166,6 +179,7 @@ func (c *LdapClient) ModifyAttributes(dn string, addAttrs, delAttrs, replaceAttr
 }
 
 func (c *LdapClient) Delete(dn string) error {
+	c.markWritten(dn)
 	return c.withConnection(func(conn *ldap.Conn) error {
 		delRequest := ldap.NewDelRequest(dn, nil)
 		return conn.Del(delRequest)
@@ -173,6 +187,7 @@ func (c *LdapClient) Delete(dn string) error {
 }
 
 func (c *LdapClient) ModifyDn(dn, newRDN, newSuperior string) error {
+	c.markWritten(dn, movedDn(dn, newRDN, newSuperior))
 	return c.withConnection(func(conn *ldap.Conn) error {
 		ModifyDnReq := ldap.NewModifyDNRequest(dn, newRDN, true, newSuperior)
 		return conn.ModifyDN(ModifyDnReq)
@@ -180,6 +195,7 @@ func (c *LdapClient) ModifyDn(dn, newRDN, newSuperior string) error {
 }
 
 func (c *LdapClient) ModifyPassword(dn, newPassword string) error {
+	c.markWritten(dn)
 	return c.withConnection(func(conn *ldap.Conn) error {
 		passwdReq := ldap.NewPasswordModifyRequest(dn, "", newPassword)
 		_, err := conn.PasswordModify(passwdReq)
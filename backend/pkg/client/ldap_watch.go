@@ -0,0 +1,385 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/config"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/sirupsen/logrus"
+)
+
+type LdapChangeType string
+
+const (
+	LdapEntryAdded    LdapChangeType = "add"
+	LdapEntryModified LdapChangeType = "modify"
+	LdapEntryDeleted  LdapChangeType = "delete"
+)
+
+const (
+	WatchModeAuto     = "auto"
+	WatchModeSyncrepl = "syncrepl"
+	WatchModePoll     = "poll"
+	WatchModeOff      = "off"
+)
+
+// LdapChange 描述一次条目变更，新增时 Before 为空，删除时 After 为空。
+// Replica 表示修改由使用同一绑定 DN 的其他 asynx 实例写入，删除无法区分，总是为 false
+type LdapChange struct {
+	Type    LdapChangeType
+	Before  *ldap.Entry
+	After   *ldap.Entry
+	Replica bool
+}
+
+// ChangedAttributes 返回取值发生变化的普通属性名（小写）
+func (c LdapChange) ChangedAttributes() []string {
+	before, after := entryAttributes(c.Before), entryAttributes(c.After)
+	var changed []string
+	for name, values := range after {
+		if !slices.Equal(before[name], values) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+var errSyncreplUnsupported = errors.New("syncrepl is not supported by the server")
+
+// 监听时额外请求的操作属性，不参与变更比较
+var watchOperationalAttributes = []string{"entryuuid", "modifiersname", "modifytimestamp", "entrycsn"}
+
+// 写入记录的保留时间，远大于任何合理的回声窗口
+const writeRetention = 10 * time.Minute
+
+// ---------------------------------------------------------------------------------------
+
+// watchKeyDn 返回用于比较的 DN，无法解析时退回小写形式
+func watchKeyDn(dn string) string {
+	normalized, err := normalizeDn(dn)
+	if err != nil {
+		return strings.ToLower(dn)
+	}
+	return normalized
+}
+
+// movedDn 计算 ModifyDn 之后条目的新 DN
+func movedDn(dn, newRDN, newSuperior string) string {
+	if newSuperior == "" {
+		_, parent, _ := strings.Cut(dn, ",")
+		newSuperior = parent
+	}
+	return newRDN + "," + newSuperior
+}
+
+func (c *LdapClient) markWritten(dns ...string) {
+	c.writesMu.Lock()
+	defer c.writesMu.Unlock()
+
+	now := time.Now()
+	for dn, at := range c.writes {
+		if now.Sub(at) > writeRetention {
+			delete(c.writes, dn)
+		}
+	}
+	for _, dn := range dns {
+		c.writes[watchKeyDn(dn)] = now
+	}
+}
+
+func (c *LdapClient) writtenWithin(dn string, window time.Duration) bool {
+	c.writesMu.Lock()
+	defer c.writesMu.Unlock()
+
+	at, ok := c.writes[watchKeyDn(dn)]
+	return ok && time.Since(at) <= window
+}
+
+// ---------------------------------------------------------------------------------------
+
+// Watch 持续监听 baseDN 下匹配 filter 的条目变更，直到 ctx 结束。
+// 首次同步只建立基线，不产生变更；断线重连后会补发期间遗漏的变更。
+// 在回声窗口内由本客户端写过的条目视为 asynx 自己的修改，不会交给 handler
+func (c *LdapClient) Watch(ctx context.Context, cfg *config.ConfigLdapWatch, baseDN, filter string, handler func(LdapChange)) {
+	w := &ldapWatch{
+		client:  c,
+		cfg:     cfg,
+		baseDN:  baseDN,
+		filter:  filter,
+		handler: handler,
+		search:  c.Search,
+		entries: make(map[string]*ldap.Entry),
+	}
+
+	mode := cfg.Mode
+	for ctx.Err() == nil {
+		var err error
+		if mode == WatchModePoll {
+			err = w.poll(ctx)
+		} else {
+			err = w.syncrepl(ctx)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		if errors.Is(err, errSyncreplUnsupported) && mode == WatchModeAuto {
+			logrus.Warnf("LDAP watch on %s falls back to polling: %v", baseDN, err)
+			mode = WatchModePoll
+			continue
+		}
+
+		logrus.Errorf("LDAP watch on %s interrupted, retrying in %s: %v", baseDN, cfg.RetryInterval, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(cfg.RetryInterval):
+		}
+	}
+}
+
+type ldapWatch struct {
+	client  *LdapClient
+	cfg     *config.ConfigLdapWatch
+	baseDN  string
+	filter  string
+	handler func(LdapChange)
+	search  func(baseDN, filter string, attributes []string) (*ldap.SearchResult, error)
+
+	// 以 entryUUID 为键的当前条目快照
+	entries map[string]*ldap.Entry
+	synced  bool
+	// 轮询模式下已见过的最大 modifyTimestamp
+	since   string
+	polling bool
+}
+
+func (w *ldapWatch) attributes() []string {
+	return []string{"*", "entryUUID", "entryCSN", "modifiersName", "modifyTimestamp"}
+}
+
+func (w *ldapWatch) echoWindow() time.Duration {
+	if w.polling {
+		return w.cfg.EchoWindow + w.cfg.PollInterval
+	}
+	return w.cfg.EchoWindow
+}
+
+func (w *ldapWatch) emit(change LdapChange) {
+	for _, entry := range []*ldap.Entry{change.Before, change.After} {
+		if entry != nil && w.client.writtenWithin(entry.DN, w.echoWindow()) {
+			logrus.Debugf("LDAP watch ignores %s of %s written by asynx", change.Type, entry.DN)
+			return
+		}
+	}
+	if change.After != nil {
+		modifier := change.After.GetAttributeValue("modifiersName")
+		change.Replica = modifier != "" && watchKeyDn(modifier) == watchKeyDn(w.client.cfg.BindDN)
+	}
+	w.handler(change)
+}
+
+func (w *ldapWatch) upsert(key string, entry *ldap.Entry) {
+	old, ok := w.entries[key]
+	w.entries[key] = entry
+	if !w.synced {
+		return
+	}
+	if !ok {
+		w.emit(LdapChange{Type: LdapEntryAdded, After: entry})
+	} else if !sameEntry(old, entry) {
+		w.emit(LdapChange{Type: LdapEntryModified, Before: old, After: entry})
+	}
+}
+
+func (w *ldapWatch) remove(key string) {
+	old, ok := w.entries[key]
+	if !ok {
+		return
+	}
+	delete(w.entries, key)
+	if w.synced {
+		w.emit(LdapChange{Type: LdapEntryDeleted, Before: old})
+	}
+}
+
+// reconcile 在一次完整同步之后删除没有再出现的条目，并开始产生变更
+func (w *ldapWatch) reconcile(seen map[string]struct{}) {
+	for _, key := range slices.Collect(maps.Keys(w.entries)) {
+		if _, ok := seen[key]; !ok {
+			w.remove(key)
+		}
+	}
+	if !w.synced {
+		logrus.Infof("LDAP watch on %s synced %d entries", w.baseDN, len(w.entries))
+	}
+	w.synced = true
+}
+
+// syncrepl 使用 RFC 4533 的 refreshAndPersist 模式。每次连接都从完整刷新开始，刷新阶段与快照比较得到断线期间的变更
+func (w *ldapWatch) syncrepl(ctx context.Context) error {
+	w.polling = false
+
+	conn, err := w.client.dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	req := ldap.NewSearchRequest(w.baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false, w.filter, w.attributes(), nil)
+	res := conn.Syncrepl(ctx, req, 64, ldap.SyncRequestModeRefreshAndPersist, nil, false)
+
+	session := &syncSession{seen: make(map[string]struct{})}
+	received := false
+	for res.Next() {
+		received = true
+		w.handleSync(session, res.Entry(), res.Controls())
+	}
+
+	if err := res.Err(); err != nil {
+		if !received && (ldap.IsErrorWithCode(err, ldap.LDAPResultUnavailableCriticalExtension) || ldap.IsErrorWithCode(err, ldap.LDAPResultUnwillingToPerform)) {
+			return fmt.Errorf("%w: %v", errSyncreplUnsupported, err)
+		}
+		return err
+	}
+	return fmt.Errorf("syncrepl search ended by server")
+}
+
+// syncSession 记录一次 syncrepl 连接刷新阶段出现过的条目
+type syncSession struct {
+	seen      map[string]struct{}
+	refreshed bool
+}
+
+// handleSync 处理 syncrepl 返回的一条消息
+func (w *ldapWatch) handleSync(session *syncSession, entry *ldap.Entry, controls []ldap.Control) {
+	for _, control := range controls {
+		switch control := control.(type) {
+		case *ldap.ControlSyncState:
+			key := control.EntryUUID.String()
+			if control.State == ldap.SyncStateDelete {
+				w.remove(key)
+				continue
+			}
+			session.seen[key] = struct{}{}
+			if entry != nil && len(entry.Attributes) > 0 {
+				w.upsert(key, entry)
+			}
+		case *ldap.ControlSyncInfo:
+			if control.SyncIdSet != nil && control.SyncIdSet.RefreshDeletes {
+				for _, id := range control.SyncIdSet.SyncUUIDs {
+					w.remove(id.String())
+				}
+			}
+			if !session.refreshed && refreshDone(control) {
+				session.refreshed = true
+				w.reconcile(session.seen)
+			}
+		}
+	}
+}
+
+func refreshDone(info *ldap.ControlSyncInfo) bool {
+	switch {
+	case info.RefreshDelete != nil:
+		return info.RefreshDelete.RefreshDone
+	case info.RefreshPresent != nil:
+		return info.RefreshPresent.RefreshDone
+	}
+	return false
+}
+
+// poll 按 modifyTimestamp 轮询新增和修改，并比较全部 entryUUID 找出删除
+func (w *ldapWatch) poll(ctx context.Context) error {
+	w.polling = true
+
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := w.pollOnce(); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *ldapWatch) pollOnce() error {
+	filter := w.filter
+	if w.synced && w.since != "" {
+		filter = fmt.Sprintf("(&%s(modifyTimestamp>=%s))", w.filter, w.since)
+	}
+	changed, err := w.search(w.baseDN, filter, w.attributes())
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]struct{})
+	if w.synced {
+		present, err := w.search(w.baseDN, w.filter, []string{"entryUUID"})
+		if err != nil {
+			return err
+		}
+		for _, entry := range present.Entries {
+			seen[entryKey(entry)] = struct{}{}
+		}
+	}
+
+	for _, entry := range changed.Entries {
+		key := entryKey(entry)
+		seen[key] = struct{}{}
+		w.upsert(key, entry)
+		if ts := entry.GetAttributeValue("modifyTimestamp"); ts > w.since {
+			w.since = ts
+		}
+	}
+	w.reconcile(seen)
+	return nil
+}
+
+func entryKey(entry *ldap.Entry) string {
+	if id := entry.GetAttributeValue("entryUUID"); id != "" {
+		return strings.ToLower(id)
+	}
+	return watchKeyDn(entry.DN)
+}
+
+// entryAttributes 返回条目普通属性的规范形式，属性名小写、取值排序
+func entryAttributes(entry *ldap.Entry) map[string][]string {
+	attrs := make(map[string][]string)
+	if entry == nil {
+		return attrs
+	}
+	for _, attr := range entry.Attributes {
+		name := strings.ToLower(attr.Name)
+		if slices.Contains(watchOperationalAttributes, name) {
+			continue
+		}
+		values := slices.Clone(attr.Values)
+		slices.Sort(values)
+		attrs[name] = values
+	}
+	return attrs
+}
+
+func sameEntry(a, b *ldap.Entry) bool {
+	return watchKeyDn(a.DN) == watchKeyDn(b.DN) && maps.EqualFunc(entryAttributes(a), entryAttributes(b), slices.Equal)
+}
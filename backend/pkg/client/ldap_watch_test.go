@@ -0,0 +1,195 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/config"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/google/uuid"
+)
+
+const testBindDn = "cn=asynx,dc=example,dc=org"
+
+var (
+	aliceId = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	bobId   = uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	carolId = uuid.MustParse("00000000-0000-0000-0000-000000000003")
+)
+
+func newTestWatch(changes *[]LdapChange) *ldapWatch {
+	client := &LdapClient{cfg: &config.ConfigLDAP{BindDN: testBindDn}, writes: make(map[string]time.Time)}
+	return &ldapWatch{
+		client:  client,
+		cfg:     &config.ConfigLdapWatch{EchoWindow: time.Minute, PollInterval: time.Second},
+		baseDN:  "ou=People,dc=example,dc=org",
+		filter:  "(objectClass=posixAccount)",
+		handler: func(change LdapChange) { *changes = append(*changes, change) },
+		entries: make(map[string]*ldap.Entry),
+	}
+}
+
+func watchEntry(id uuid.UUID, uid, mail, modifier, timestamp string) *ldap.Entry {
+	return ldap.NewEntry("uid="+uid+",ou=People,dc=example,dc=org", map[string][]string{
+		"uid":             {uid},
+		"mail":            {mail},
+		"entryUUID":       {id.String()},
+		"modifiersName":   {modifier},
+		"modifyTimestamp": {timestamp},
+	})
+}
+
+// describe 把变更写成 "类型 uid" 的形式便于比较
+func describe(changes []LdapChange) string {
+	parts := make([]string, 0, len(changes))
+	for _, change := range changes {
+		entry := change.After
+		if entry == nil {
+			entry = change.Before
+		}
+		parts = append(parts, fmt.Sprintf("%s %s", change.Type, entry.GetAttributeValue("uid")))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func syncState(state ldap.ControlSyncStateState, id uuid.UUID) []ldap.Control {
+	return []ldap.Control{&ldap.ControlSyncState{State: state, EntryUUID: id}}
+}
+
+func refreshPresentDone() []ldap.Control {
+	return []ldap.Control{&ldap.ControlSyncInfo{RefreshPresent: &ldap.ControlSyncInfoRefreshPresent{RefreshDone: true}}}
+}
+
+func TestSyncreplRefreshBuildsBaselineThenPersistsChanges(t *testing.T) {
+	var changes []LdapChange
+	w := newTestWatch(&changes)
+
+	session := &syncSession{seen: make(map[string]struct{})}
+	w.handleSync(session, watchEntry(aliceId, "alice", "alice@example.org", "cn=admin", "1"), syncState(ldap.SyncStateAdd, aliceId))
+	w.handleSync(session, watchEntry(bobId, "bob", "bob@example.org", "cn=admin", "1"), syncState(ldap.SyncStateAdd, bobId))
+	w.handleSync(session, nil, refreshPresentDone())
+	if len(changes) != 0 {
+		t.Fatalf("refresh produced changes: %s", describe(changes))
+	}
+
+	// 持续阶段的每条消息都是一次变更，操作属性的变化不算修改
+	w.handleSync(session, watchEntry(aliceId, "alice", "alice@example.org", "cn=admin", "2"), syncState(ldap.SyncStateModify, aliceId))
+	w.handleSync(session, watchEntry(aliceId, "alice", "alice@new.example.org", "cn=admin", "3"), syncState(ldap.SyncStateModify, aliceId))
+	w.handleSync(session, watchEntry(carolId, "carol", "carol@example.org", testBindDn, "3"), syncState(ldap.SyncStateAdd, carolId))
+	w.handleSync(session, nil, syncState(ldap.SyncStateDelete, bobId))
+
+	if got := describe(changes); got != "modify alice, add carol, delete bob" {
+		t.Fatalf("got %s", got)
+	}
+	if attrs := changes[0].ChangedAttributes(); len(attrs) != 1 || attrs[0] != "mail" {
+		t.Errorf("changed attributes %v, want [mail]", attrs)
+	}
+	if changes[0].Replica || !changes[1].Replica {
+		t.Errorf("replica flags %v %v, want only the entry written with the bind DN", changes[0].Replica, changes[1].Replica)
+	}
+}
+
+func TestSyncreplReconnectReportsMissedChanges(t *testing.T) {
+	var changes []LdapChange
+	w := newTestWatch(&changes)
+
+	first := &syncSession{seen: make(map[string]struct{})}
+	for _, entry := range []struct {
+		id  uuid.UUID
+		uid string
+	}{{aliceId, "alice"}, {bobId, "bob"}, {carolId, "carol"}} {
+		w.handleSync(first, watchEntry(entry.id, entry.uid, entry.uid+"@example.org", "cn=admin", "1"), syncState(ldap.SyncStateAdd, entry.id))
+	}
+	w.handleSync(first, nil, refreshPresentDone())
+
+	// 断线期间 alice 被修改、bob 被删除；重连后的刷新只带回仍然存在的条目，之后服务器用 SyncIdSet 通知删除
+	second := &syncSession{seen: make(map[string]struct{})}
+	w.handleSync(second, watchEntry(aliceId, "alice", "alice@new.example.org", "cn=admin", "2"), syncState(ldap.SyncStateAdd, aliceId))
+	w.handleSync(second, nil, syncState(ldap.SyncStatePresent, carolId))
+	w.handleSync(second, nil, refreshPresentDone())
+	if got := describe(changes); got != "modify alice, delete bob" {
+		t.Fatalf("got %s after reconnect", got)
+	}
+
+	changes = nil
+	w.handleSync(second, nil, []ldap.Control{&ldap.ControlSyncInfo{SyncIdSet: &ldap.ControlSyncInfoSyncIdSet{RefreshDeletes: true, SyncUUIDs: []uuid.UUID{carolId}}}})
+	if got := describe(changes); got != "delete carol" {
+		t.Errorf("got %s for a sync id set", got)
+	}
+}
+
+func TestWatchIgnoresOwnWrites(t *testing.T) {
+	var changes []LdapChange
+	w := newTestWatch(&changes)
+	session := &syncSession{seen: make(map[string]struct{})}
+	w.handleSync(session, watchEntry(aliceId, "alice", "alice@example.org", testBindDn, "1"), syncState(ldap.SyncStateAdd, aliceId))
+	w.handleSync(session, nil, refreshPresentDone())
+
+	w.client.markWritten("UID=alice, ou=People,dc=example,dc=org")
+	w.handleSync(session, watchEntry(aliceId, "alice", "alice@new.example.org", testBindDn, "2"), syncState(ldap.SyncStateModify, aliceId))
+	if len(changes) != 0 {
+		t.Errorf("own write produced %s", describe(changes))
+	}
+}
+
+// fakeDirectory 按 modifyTimestamp 过滤条目，模拟轮询使用的查询
+type fakeDirectory struct {
+	entries []*ldap.Entry
+	filters []string
+}
+
+func (d *fakeDirectory) search(baseDN, filter string, attributes []string) (*ldap.SearchResult, error) {
+	d.filters = append(d.filters, filter)
+	_, since, incremental := strings.Cut(filter, "modifyTimestamp>=")
+	since, _, _ = strings.Cut(since, ")")
+
+	result := &ldap.SearchResult{}
+	for _, entry := range d.entries {
+		if !incremental || entry.GetAttributeValue("modifyTimestamp") >= since {
+			result.Entries = append(result.Entries, entry)
+		}
+	}
+	return result, nil
+}
+
+func TestPollDiffsAgainstSnapshot(t *testing.T) {
+	var changes []LdapChange
+	w := newTestWatch(&changes)
+	directory := &fakeDirectory{entries: []*ldap.Entry{
+		watchEntry(aliceId, "alice", "alice@example.org", "cn=admin", "20240101000000Z"),
+		watchEntry(bobId, "bob", "bob@example.org", "cn=admin", "20240102000000Z"),
+	}}
+	w.search = directory.search
+
+	if err := w.pollOnce(); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 || w.since != "20240102000000Z" {
+		t.Fatalf("baseline produced %s, since = %s", describe(changes), w.since)
+	}
+
+	directory.entries = []*ldap.Entry{
+		watchEntry(aliceId, "alice", "alice@new.example.org", "cn=admin", "20240103000000Z"),
+		watchEntry(carolId, "carol", "carol@example.org", "cn=admin", "20240103000000Z"),
+	}
+	if err := w.pollOnce(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(directory.filters[len(directory.filters)-2], "(modifyTimestamp>=20240102000000Z)") {
+		t.Errorf("incremental poll used filter %s", directory.filters[len(directory.filters)-2])
+	}
+	if got := describe(changes); got != "modify alice, add carol, delete bob" && got != "add carol, modify alice, delete bob" {
+		t.Errorf("got %s", got)
+	}
+
+	// 时间戳相同的条目会被再次查到，但没有变化时不产生事件
+	changes = nil
+	if err := w.pollOnce(); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("unchanged entries produced %s", describe(changes))
+	}
+}
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"asynclab.club/asynx/backend/pkg/config"
+	"github.com/google/uuid"
+	"gopkg.in/gomail.v2"
+)
+
+type SmtpTransport struct {
+	dialer *gomail.Dialer
+}
+
+func NewSmtpTransport(cfg *config.ConfigEmail) *SmtpTransport {
+	return &SmtpTransport{dialer: gomail.NewDialer(cfg.Host, cfg.Port, cfg.Username, cfg.Password)}
+}
+
+func (t *SmtpTransport) Send(mail *Mail) error {
+	return t.dialer.DialAndSend(mail.message())
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+// MaildirTransport 把邮件按 Maildir 格式写入本地目录，可以直接用邮件客户端打开
+type MaildirTransport struct {
+	dir     string
+	counter atomic.Uint64
+}
+
+func NewMaildirTransport(dir string) (*MaildirTransport, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("MAIL_MAILDIR is required by maildir mail transport")
+	}
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create maildir: %w", err)
+		}
+	}
+	return &MaildirTransport{dir: dir}, nil
+}
+
+// Send 先写入 tmp 再移动到 new，保证读取方不会看到写了一半的文件
+func (t *MaildirTransport) Send(mail *Mail) error {
+	hostname, _ := os.Hostname()
+	name := fmt.Sprintf("%d.P%dQ%d.%s", mail.SentAt.UnixNano(), os.Getpid(), t.counter.Add(1), hostname)
+
+	tmp := filepath.Join(t.dir, "tmp", name)
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := mail.message().WriteTo(f); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(t.dir, "new", name))
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+// MemoryMailTransport 只把邮件保存在内存中，最多保留 capacity 封，供开发和测试查看
+type MemoryMailTransport struct {
+	mu       sync.RWMutex
+	capacity int
+	mails    []Mail
+}
+
+func NewMemoryMailTransport(capacity int) *MemoryMailTransport {
+	return &MemoryMailTransport{capacity: max(capacity, 1)}
+}
+
+func (t *MemoryMailTransport) Send(mail *Mail) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sent := *mail
+	sent.Id = uuid.NewString()
+	t.mails = append(t.mails, sent)
+	if len(t.mails) > t.capacity {
+		t.mails = slices.Delete(t.mails, 0, len(t.mails)-t.capacity)
+	}
+	return nil
+}
+
+// List 按发送时间倒序返回邮件，to 不为空时只返回发给该地址的邮件
+func (t *MemoryMailTransport) List(to string) []Mail {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	mails := make([]Mail, 0, len(t.mails))
+	for i := len(t.mails) - 1; i >= 0; i-- {
+		if to == "" || t.mails[i].To == to {
+			mails = append(mails, t.mails[i])
+		}
+	}
+	return mails
+}
+
+func (t *MemoryMailTransport) Get(id string) (Mail, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, mail := range t.mails {
+		if mail.Id == id {
+			return mail, true
+		}
+	}
+	return Mail{}, false
+}
+
+func (t *MemoryMailTransport) Clear() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.mails = nil
+}
+
+var (
+	_ MailTransport = (*SmtpTransport)(nil)
+	_ MailTransport = (*MaildirTransport)(nil)
+	_ MailTransport = (*MemoryMailTransport)(nil)
+)
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/config"
+)
+
+func testMail(to, subject string) *Mail {
+	return &Mail{From: "noreply@example.org", To: to, Subject: subject, Html: "<p>hello</p>", Text: "hello", SentAt: time.Now()}
+}
+
+func TestNewMailTransport(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.ConfigEmail
+		wantErr bool
+	}{
+		{"smtp", config.ConfigEmail{Transport: "smtp", Host: "smtp.example.org", From: "noreply@example.org"}, false},
+		{"smtp without host", config.ConfigEmail{Transport: "smtp"}, true},
+		{"maildir", config.ConfigEmail{Transport: "maildir", Maildir: t.TempDir()}, false},
+		{"maildir without dir", config.ConfigEmail{Transport: "maildir"}, true},
+		{"memory", config.ConfigEmail{Transport: "memory"}, false},
+		{"unknown", config.ConfigEmail{Transport: "sendmail"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, err := NewMailTransport(&tt.cfg)
+			if (err != nil) != tt.wantErr || (err == nil && transport == nil) {
+				t.Errorf("got %v, %v", transport, err)
+			}
+		})
+	}
+}
+
+func TestMaildirTransportDeliversIntoNew(t *testing.T) {
+	dir := t.TempDir()
+	transport, err := NewMaildirTransport(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, to := range []string{"alice@example.org", "bob@example.org"} {
+		if err := transport.Send(testMail(to, "Welcome")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 发送完成后 tmp 中不留下文件
+	if tmp, _ := os.ReadDir(filepath.Join(dir, "tmp")); len(tmp) != 0 {
+		t.Errorf("tmp is not empty: %v", tmp)
+	}
+	delivered, err := os.ReadDir(filepath.Join(dir, "new"))
+	if err != nil || len(delivered) != 2 {
+		t.Fatalf("got %v, %v in new, want 2 mails", delivered, err)
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, "new", delivered[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, header := range []string{"From: noreply@example.org", "Subject: Welcome", "text/plain", "text/html"} {
+		if !strings.Contains(string(raw), header) {
+			t.Errorf("message does not contain %q", header)
+		}
+	}
+}
+
+func TestMemoryMailTransport(t *testing.T) {
+	transport := NewMemoryMailTransport(3)
+	for i, to := range []string{"alice@example.org", "bob@example.org", "alice@example.org", "alice@example.org"} {
+		if err := transport.Send(testMail(to, string(rune('a'+i)))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 超出容量时丢弃最早的邮件，列表按发送时间倒序
+	all := transport.List("")
+	if len(all) != 3 || all[0].Subject != "d" || all[2].Subject != "b" {
+		t.Fatalf("got %+v, want the 3 newest mails newest first", all)
+	}
+	alice := transport.List("alice@example.org")
+	if len(alice) != 2 || alice[0].Subject != "d" || alice[1].Subject != "c" {
+		t.Errorf("got %+v for alice", alice)
+	}
+	if got, ok := transport.Get(alice[1].Id); !ok || got.Subject != "c" {
+		t.Errorf("Get(%s) = %+v, %v", alice[1].Id, got, ok)
+	}
+
+	transport.Clear()
+	if len(transport.List("")) != 0 {
+		t.Error("mails kept after Clear")
+	}
+}
@@ -0,0 +1,443 @@
+package client
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+func normalizeDn(dn string) (string, error) {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return "", ldap.NewError(ldap.LDAPResultInvalidDNSyntax, err)
+	}
+	rdns := make([]string, 0, len(parsed.RDNs))
+	for _, rdn := range parsed.RDNs {
+		parts := make([]string, 0, len(rdn.Attributes))
+		for _, attr := range rdn.Attributes {
+			parts = append(parts, fmt.Sprintf("%s=%s", strings.ToLower(attr.Type), strings.ToLower(attr.Value)))
+		}
+		rdns = append(rdns, strings.Join(parts, "+"))
+	}
+	return strings.Join(rdns, ","), nil
+}
+
+func parentDn(normalized string) string {
+	if idx := strings.Index(normalized, ","); idx >= 0 {
+		return normalized[idx+1:]
+	}
+	return ""
+}
+
+// ---------------------------------------------------------------------------------------
+
+type memoryEntry struct {
+	dn    string
+	attrs []*ldap.EntryAttribute
+}
+
+func (e *memoryEntry) get(name string) *ldap.EntryAttribute {
+	for _, attr := range e.attrs {
+		if strings.EqualFold(attr.Name, name) {
+			return attr
+		}
+	}
+	return nil
+}
+
+func (e *memoryEntry) values(name string) []string {
+	if attr := e.get(name); attr != nil {
+		return attr.Values
+	}
+	return nil
+}
+
+func (e *memoryEntry) remove(name string) {
+	e.attrs = slices.DeleteFunc(e.attrs, func(attr *ldap.EntryAttribute) bool {
+		return strings.EqualFold(attr.Name, name)
+	})
+}
+
+func (e *memoryEntry) set(name string, values []string) {
+	if len(values) == 0 {
+		e.remove(name)
+		return
+	}
+	if attr := e.get(name); attr != nil {
+		attr.Values = slices.Clone(values)
+		return
+	}
+	e.attrs = append(e.attrs, ldap.NewEntryAttribute(name, slices.Clone(values)))
+}
+
+func (e *memoryEntry) toLdapEntry(attributes []string) *ldap.Entry {
+	entry := &ldap.Entry{DN: e.dn}
+	for _, attr := range e.attrs {
+		if len(attributes) != 0 && !slices.ContainsFunc(attributes, func(name string) bool {
+			return name == "*" || strings.EqualFold(name, attr.Name)
+		}) {
+			continue
+		}
+		entry.Attributes = append(entry.Attributes, ldap.NewEntryAttribute(attr.Name, slices.Clone(attr.Values)))
+	}
+	return entry
+}
+
+// ---------------------------------------------------------------------------------------
+
+// MemoryClient 是一个进程内的目录实现，用于开发和测试，不依赖外部 LDAP 服务
+type MemoryClient struct {
+	mu          sync.RWMutex
+	entries     map[string]*memoryEntry
+	baseDN      string
+	userBaseDN  string
+	groupBaseDN string
+}
+
+func NewMemoryClient(baseDN, userBaseDN, groupBaseDN string) (*MemoryClient, error) {
+	c := &MemoryClient{
+		entries:     make(map[string]*memoryEntry),
+		baseDN:      baseDN,
+		userBaseDN:  userBaseDN,
+		groupBaseDN: groupBaseDN,
+	}
+
+	// 根条目没有父条目，需要单独创建
+	key, err := normalizeDn(baseDN)
+	if err != nil {
+		return nil, err
+	}
+	c.entries[key] = &memoryEntry{dn: baseDN}
+
+	return c, nil
+}
+
+func (c *MemoryClient) BuildDn(rdn string) string {
+	if rdn != "" {
+		return fmt.Sprintf("%s,%s", rdn, c.baseDN)
+	}
+	return c.baseDN
+}
+
+func (c *MemoryClient) GetUserBaseDn() string  { return c.userBaseDN }
+func (c *MemoryClient) GetGroupBaseDn() string { return c.groupBaseDN }
+func (c *MemoryClient) Close() error           { return nil }
+
+func (c *MemoryClient) Authenticate(dn, password string) (bool, error) {
+	if dn == "" || password == "" {
+		return false, nil
+	}
+
+	key, err := normalizeDn(dn)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return false, nil
+	}
+	return slices.Contains(entry.values("userPassword"), password), nil
+}
+
+func (c *MemoryClient) Search(baseDN string, filter string, attributes []string) (*ldap.SearchResult, error) {
+	base, err := normalizeDn(baseDN)
+	if err != nil {
+		return nil, err
+	}
+
+	packet, err := ldap.CompileFilter(filter)
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if _, ok := c.entries[base]; !ok {
+		return nil, fmt.Errorf("search failed: %w", ldap.NewError(ldap.LDAPResultNoSuchObject, fmt.Errorf("no such object: %s", baseDN)))
+	}
+
+	keys := make([]string, 0, len(c.entries))
+	for key := range c.entries {
+		if key == base || strings.HasSuffix(key, ","+base) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	result := &ldap.SearchResult{}
+	for _, key := range keys {
+		entry := c.entries[key]
+		matched, err := matchFilter(entry, packet)
+		if err != nil {
+			return nil, fmt.Errorf("search failed: %w", err)
+		}
+		if matched {
+			result.Entries = append(result.Entries, entry.toLdapEntry(attributes))
+		}
+	}
+	return result, nil
+}
+
+func (c *MemoryClient) Add(dn string, objectClass []string, attributes map[string][]string) error {
+	key, err := normalizeDn(dn)
+	if err != nil {
+		return err
+	}
+	parsed, _ := ldap.ParseDN(dn)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; ok {
+		return ldap.NewError(ldap.LDAPResultEntryAlreadyExists, fmt.Errorf("entry already exists: %s", dn))
+	}
+	if _, ok := c.entries[parentDn(key)]; !ok {
+		return ldap.NewError(ldap.LDAPResultNoSuchObject, fmt.Errorf("parent of %s does not exist", dn))
+	}
+
+	entry := &memoryEntry{dn: dn}
+	entry.set("objectClass", objectClass)
+	for attr, values := range attributes {
+		entry.set(attr, values)
+	}
+	// 与真实目录一致，RDN 的值总是作为属性存在
+	for _, rdnAttr := range parsed.RDNs[0].Attributes {
+		if !slices.Contains(entry.values(rdnAttr.Type), rdnAttr.Value) {
+			entry.set(rdnAttr.Type, append(entry.values(rdnAttr.Type), rdnAttr.Value))
+		}
+	}
+
+	c.entries[key] = entry
+	return nil
+}
+
+func (c *MemoryClient) ModifyAttributes(dn string, addAttrs, delAttrs, replaceAttrs map[string][]string) error {
+	key, err := normalizeDn(dn)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return ldap.NewError(ldap.LDAPResultNoSuchObject, fmt.Errorf("no such object: %s", dn))
+	}
+
+	// 在副本上修改，任一步失败时整个请求不生效
+	modified := entry.toLdapEntry(nil)
+	staged := &memoryEntry{dn: entry.dn, attrs: modified.Attributes}
+
+	for attr, values := range addAttrs {
+		current := staged.values(attr)
+		for _, value := range values {
+			if slices.Contains(current, value) {
+				return ldap.NewError(ldap.LDAPResultAttributeOrValueExists, fmt.Errorf("%s: value %s already exists", attr, value))
+			}
+			current = append(current, value)
+		}
+		staged.set(attr, current)
+	}
+	for attr, values := range delAttrs {
+		current := staged.values(attr)
+		if current == nil {
+			return ldap.NewError(ldap.LDAPResultNoSuchAttribute, fmt.Errorf("no such attribute: %s", attr))
+		}
+		if len(values) == 0 {
+			staged.remove(attr)
+			continue
+		}
+		for _, value := range values {
+			idx := slices.Index(current, value)
+			if idx < 0 {
+				return ldap.NewError(ldap.LDAPResultNoSuchAttribute, fmt.Errorf("%s: no such value %s", attr, value))
+			}
+			current = slices.Delete(current, idx, idx+1)
+		}
+		staged.set(attr, current)
+	}
+	for attr, values := range replaceAttrs {
+		staged.set(attr, values)
+	}
+
+	c.entries[key] = staged
+	return nil
+}
+
+func (c *MemoryClient) Delete(dn string) error {
+	key, err := normalizeDn(dn)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok {
+		return ldap.NewError(ldap.LDAPResultNoSuchObject, fmt.Errorf("no such object: %s", dn))
+	}
+	for other := range c.entries {
+		if strings.HasSuffix(other, ","+key) {
+			return ldap.NewError(ldap.LDAPResultNotAllowedOnNonLeaf, fmt.Errorf("entry has children: %s", dn))
+		}
+	}
+
+	delete(c.entries, key)
+	return nil
+}
+
+func (c *MemoryClient) ModifyDn(dn, newRDN, newSuperior string) error {
+	key, err := normalizeDn(dn)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return ldap.NewError(ldap.LDAPResultNoSuchObject, fmt.Errorf("no such object: %s", dn))
+	}
+
+	if newSuperior == "" {
+		parsed, _ := ldap.ParseDN(dn)
+		parsed.RDNs = parsed.RDNs[1:]
+		newSuperior = parsed.String()
+	}
+	newDn := fmt.Sprintf("%s,%s", newRDN, newSuperior)
+	newKey, err := normalizeDn(newDn)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := c.entries[parentDn(newKey)]; !ok {
+		return ldap.NewError(ldap.LDAPResultNoSuchObject, fmt.Errorf("no such object: %s", newSuperior))
+	}
+	if _, ok := c.entries[newKey]; ok && newKey != key {
+		return ldap.NewError(ldap.LDAPResultEntryAlreadyExists, fmt.Errorf("entry already exists: %s", newDn))
+	}
+	for other := range c.entries {
+		if strings.HasSuffix(other, ","+key) {
+			return ldap.NewError(ldap.LDAPResultNotAllowedOnNonLeaf, fmt.Errorf("entry has children: %s", dn))
+		}
+	}
+
+	// deleteOldRDN=true：用新的 RDN 值替换旧值
+	oldRdn, _ := ldap.ParseDN(dn)
+	newRdn, err := ldap.ParseDN(newRDN)
+	if err != nil {
+		return ldap.NewError(ldap.LDAPResultInvalidDNSyntax, err)
+	}
+	for _, attr := range oldRdn.RDNs[0].Attributes {
+		entry.set(attr.Type, slices.DeleteFunc(slices.Clone(entry.values(attr.Type)), func(v string) bool { return v == attr.Value }))
+	}
+	for _, attr := range newRdn.RDNs[0].Attributes {
+		if !slices.Contains(entry.values(attr.Type), attr.Value) {
+			entry.set(attr.Type, append(entry.values(attr.Type), attr.Value))
+		}
+	}
+
+	delete(c.entries, key)
+	entry.dn = newDn
+	c.entries[newKey] = entry
+	return nil
+}
+
+func (c *MemoryClient) ModifyPassword(dn, newPassword string) error {
+	key, err := normalizeDn(dn)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return ldap.NewError(ldap.LDAPResultNoSuchObject, fmt.Errorf("no such object: %s", dn))
+	}
+	entry.set("userPassword", []string{newPassword})
+	return nil
+}
+
+// ---------------------------------------------------------------------------------------
+
+// matchFilter 在条目上求值由 ldap.CompileFilter 编译的过滤器，支持与、或、非、相等、存在和子串匹配
+func matchFilter(entry *memoryEntry, packet *ber.Packet) (bool, error) {
+	switch packet.Tag {
+	case ldap.FilterAnd:
+		for _, child := range packet.Children {
+			ok, err := matchFilter(entry, child)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case ldap.FilterOr:
+		for _, child := range packet.Children {
+			ok, err := matchFilter(entry, child)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case ldap.FilterNot:
+		ok, err := matchFilter(entry, packet.Children[0])
+		return !ok, err
+	case ldap.FilterPresent:
+		attr := ber.DecodeString(packet.Data.Bytes())
+		if strings.EqualFold(attr, "objectClass") {
+			return true, nil
+		}
+		return len(entry.values(attr)) != 0, nil
+	case ldap.FilterEqualityMatch:
+		attr := ber.DecodeString(packet.Children[0].Data.Bytes())
+		value := ber.DecodeString(packet.Children[1].Data.Bytes())
+		return slices.ContainsFunc(entry.values(attr), func(v string) bool {
+			return strings.EqualFold(v, value)
+		}), nil
+	case ldap.FilterSubstrings:
+		attr := ber.DecodeString(packet.Children[0].Data.Bytes())
+		return slices.ContainsFunc(entry.values(attr), func(v string) bool {
+			return matchSubstrings(strings.ToLower(v), packet.Children[1].Children)
+		}), nil
+	default:
+		return false, fmt.Errorf("unsupported filter: %s", ldap.FilterMap[uint64(packet.Tag)])
+	}
+}
+
+func matchSubstrings(value string, parts []*ber.Packet) bool {
+	for _, part := range parts {
+		sub := strings.ToLower(ber.DecodeString(part.Data.Bytes()))
+		switch part.Tag {
+		case ldap.FilterSubstringsInitial:
+			if !strings.HasPrefix(value, sub) {
+				return false
+			}
+			value = value[len(sub):]
+		case ldap.FilterSubstringsAny:
+			idx := strings.Index(value, sub)
+			if idx < 0 {
+				return false
+			}
+			value = value[idx+len(sub):]
+		case ldap.FilterSubstringsFinal:
+			if !strings.HasSuffix(value, sub) {
+				return false
+			}
+			value = ""
+		}
+	}
+	return true
+}
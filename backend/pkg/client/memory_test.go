@@ -0,0 +1,145 @@
+package client
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+func newTestMemoryClient(t *testing.T) *MemoryClient {
+	t.Helper()
+	c, err := NewMemoryClient("dc=example,dc=org", "ou=People,dc=example,dc=org", "ou=Groups,dc=example,dc=org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ou := range []string{"People", "Groups"} {
+		if err := c.Add(c.BuildDn("ou="+ou), []string{"organizationalUnit"}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	users := []struct{ uid, cn, mail string }{
+		{"2024000001", "Alice", "alice@example.org"},
+		{"2024000002", "Bob", "bob@example.org"},
+		{"2024000003", "Carol", ""},
+	}
+	for _, u := range users {
+		attributes := map[string][]string{"cn": {u.cn}, "sn": {u.cn}}
+		if u.mail != "" {
+			attributes["mail"] = []string{u.mail}
+		}
+		if err := c.Add("uid="+u.uid+",ou=People,dc=example,dc=org", []string{"inetOrgPerson"}, attributes); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Add("cn=admin,ou=Groups,dc=example,dc=org", []string{"groupOfNames"}, map[string][]string{
+		"member": {"uid=2024000001,ou=People,dc=example,dc=org"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func searchUids(t *testing.T, c *MemoryClient, baseDN, filter string) []string {
+	t.Helper()
+	result, err := c.Search(baseDN, filter, []string{"uid"})
+	if err != nil {
+		t.Fatalf("search %s: %v", filter, err)
+	}
+	uids := make([]string, 0, len(result.Entries))
+	for _, entry := range result.Entries {
+		uids = append(uids, entry.GetAttributeValue("uid"))
+	}
+	return uids
+}
+
+func isLdapError(err error, code uint16) bool {
+	var ldapErr *ldap.Error
+	return errors.As(err, &ldapErr) && ldapErr.ResultCode == code
+}
+
+func TestMemoryClientFilters(t *testing.T) {
+	c := newTestMemoryClient(t)
+	people := c.GetUserBaseDn()
+
+	cases := []struct {
+		filter string
+		want   []string
+	}{
+		{"(uid=2024000002)", []string{"2024000002"}},
+		{"(cn=ALICE)", []string{"2024000001"}},
+		{"(&(objectClass=inetOrgPerson)(mail=*))", []string{"2024000001", "2024000002"}},
+		{"(|(cn=Alice)(cn=Carol))", []string{"2024000001", "2024000003"}},
+		{"(&(objectClass=inetOrgPerson)(!(mail=*)))", []string{"2024000003"}},
+		{"(mail=*@example.org)", []string{"2024000001", "2024000002"}},
+		{"(cn=a*c*e)", []string{"2024000001"}},
+		{"(cn=*o*)", []string{"2024000002", "2024000003"}},
+		{"(uid=2024000009)", []string{}},
+	}
+	for _, tc := range cases {
+		if got := searchUids(t, c, people, tc.filter); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.filter, got, tc.want)
+		}
+	}
+
+	if _, err := c.Search(people, "(cn>=Alice)", nil); err == nil {
+		t.Error("unsupported filter: want error")
+	}
+}
+
+func TestMemoryClientSearchScope(t *testing.T) {
+	c := newTestMemoryClient(t)
+
+	// 子树搜索包含基准条目本身和所有后代，不包含兄弟条目
+	result, err := c.Search(c.BuildDn(""), "(objectClass=*)", []string{"dn"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Entries) != 7 {
+		t.Errorf("got %d entries under the base, want 7", len(result.Entries))
+	}
+	if got := searchUids(t, c, c.GetGroupBaseDn(), "(uid=*)"); len(got) != 0 {
+		t.Errorf("users found under the group base: %v", got)
+	}
+	if got := searchUids(t, c, "uid=2024000001,ou=People,dc=example,dc=org", "(objectClass=*)"); !slices.Equal(got, []string{"2024000001"}) {
+		t.Errorf("search based on an entry: got %v", got)
+	}
+
+	// DN 比较不区分大小写
+	if got := searchUids(t, c, "OU=people,DC=Example,DC=org", "(cn=Bob)"); !slices.Equal(got, []string{"2024000002"}) {
+		t.Errorf("search with differently cased base: got %v", got)
+	}
+
+	if _, err := c.Search("ou=Missing,dc=example,dc=org", "(objectClass=*)", nil); !isLdapError(err, ldap.LDAPResultNoSuchObject) {
+		t.Errorf("missing base: got %v, want no such object", err)
+	}
+}
+
+func TestMemoryClientDnUniqueness(t *testing.T) {
+	c := newTestMemoryClient(t)
+
+	if err := c.Add("UID=2024000001,ou=people,dc=EXAMPLE,dc=org", []string{"inetOrgPerson"}, nil); !isLdapError(err, ldap.LDAPResultEntryAlreadyExists) {
+		t.Errorf("add duplicate dn: got %v, want entry already exists", err)
+	}
+	if err := c.Add("uid=2024000004,ou=Missing,dc=example,dc=org", []string{"inetOrgPerson"}, nil); !isLdapError(err, ldap.LDAPResultNoSuchObject) {
+		t.Errorf("add without parent: got %v, want no such object", err)
+	}
+	if err := c.ModifyDn("uid=2024000002,ou=People,dc=example,dc=org", "uid=2024000001", ""); !isLdapError(err, ldap.LDAPResultEntryAlreadyExists) {
+		t.Errorf("rename onto existing dn: got %v, want entry already exists", err)
+	}
+	if err := c.Delete(c.GetUserBaseDn()); !isLdapError(err, ldap.LDAPResultNotAllowedOnNonLeaf) {
+		t.Errorf("delete non-leaf: got %v, want not allowed on non-leaf", err)
+	}
+
+	// 改名后旧 DN 可以再次使用，RDN 属性随之更新
+	if err := c.ModifyDn("uid=2024000003,ou=People,dc=example,dc=org", "uid=2024000005", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := searchUids(t, c, c.GetUserBaseDn(), "(cn=Carol)"); !slices.Equal(got, []string{"2024000005"}) {
+		t.Errorf("renamed entry: got %v", got)
+	}
+	if err := c.Add("uid=2024000003,ou=People,dc=example,dc=org", []string{"inetOrgPerson"}, map[string][]string{"cn": {"Dave"}}); err != nil {
+		t.Errorf("reuse renamed dn: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"asynclab.club/asynx/backend/pkg/config"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+type SqlClient struct {
+	db     *sql.DB
+	driver string
+}
+
+func NewSqlClient(cfg *config.ConfigSQL) (*SqlClient, error) {
+	var (
+		driverName string
+		dsn        = cfg.DSN
+	)
+
+	switch cfg.Driver {
+	case "sqlite":
+		driverName = "sqlite"
+		if !strings.Contains(dsn, "_pragma=foreign_keys") {
+			sep := "?"
+			if strings.Contains(dsn, "?") {
+				sep = "&"
+			}
+			dsn = fmt.Sprintf("%s%s_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)", dsn, sep)
+		}
+	case "postgres":
+		driverName = "pgx"
+	default:
+		return nil, fmt.Errorf("unsupported sql driver: %s", cfg.Driver)
+	}
+
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if cfg.Driver == "sqlite" {
+		// SQLite 只允许一个写者，单连接可以避免事务之间互相锁死
+		db.SetMaxOpenConns(1)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect database: %w", err)
+	}
+
+	return &SqlClient{db: db, driver: cfg.Driver}, nil
+}
+
+func (c *SqlClient) DB() *sql.DB    { return c.db }
+func (c *SqlClient) Driver() string { return c.driver }
+func (c *SqlClient) Close() error   { return c.db.Close() }
+
+// Rebind 将 ? 占位符转换为当前驱动使用的格式
+func (c *SqlClient) Rebind(query string) string {
+	if c.driver != "postgres" {
+		return query
+	}
+
+	var (
+		b strings.Builder
+		n int
+	)
+	for _, ch := range query {
+		if ch == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(ch)
+	}
+	return b.String()
+}
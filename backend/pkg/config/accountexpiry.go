@@ -0,0 +1,8 @@
+package config
+
+import "time"
+
+// 账号到期配置
+type ConfigAccountExpiry struct {
+	NotifyBefore time.Duration `env:"ACCOUNT_EXPIRY_NOTIFY_BEFORE" envDefault:"168h"` // 到期前多久发送提醒，0 表示不提醒
+}
@@ -0,0 +1,10 @@
+package config
+
+// 反向代理转发认证配置。
+// 转发认证 Cookie asynx_forward_auth 会随请求发给 CookieDomain 下的所有主机，反向代理转发给上游应用前必须删除它，
+// 例如 nginx 用 map 从 $http_cookie 中去掉这一项后以 proxy_set_header Cookie 传递，Traefik 用插件或上游前的中间件改写 Cookie 头，
+// 否则上游应用能读到令牌并冒充用户通过其他应用的转发认证
+type ConfigForwardAuth struct {
+	LoginUrl     string `env:"FORWARD_AUTH_LOGIN_URL"`     // 未登录时跳转的前端登录页完整地址，如 https://asynx.asynclab.club/login；为空时只返回 401
+	CookieDomain string `env:"FORWARD_AUTH_COOKIE_DOMAIN"` // 转发认证 Cookie 的域名，设置为上级域名（如 asynclab.club）后各子域名共享登录状态；为空时只发给当前主机
+}
@@ -0,0 +1,10 @@
+package config
+
+import "time"
+
+// 用户和角色组的读缓存配置。多个副本共用一个 LDAP 时，其他副本的修改最多延迟 TTL 才可见，
+// 开启目录监听后会在感知到外部修改时立即清空
+type ConfigCache struct {
+	Enabled bool          `env:"CACHE_ENABLED" envDefault:"false"`
+	TTL     time.Duration `env:"CACHE_TTL" envDefault:"30s"`
+}
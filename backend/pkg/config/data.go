@@ -0,0 +1,10 @@
+package config
+
+import "time"
+
+// 本地数据目录配置。数据目录属于单个实例，只保存操作日志、邮件发件箱和定时任务的执行状态，
+// 其他状态保存在所有实例共享的目录或数据库中
+type ConfigData struct {
+	Dir                string        `env:"DATA_DIR" envDefault:"data"`
+	OperationRetention time.Duration `env:"OPERATION_RETENTION" envDefault:"720h"` // 已结束的操作保留多久
+}
@@ -0,0 +1,15 @@
+package config
+
+// 目录后端配置
+type ConfigDirectory struct {
+	Backend string `env:"DIRECTORY_BACKEND" envDefault:"ldap"` // ldap|memory|sql
+	// 目录后端中保存令牌、邀请等共享数据的位置，为空时使用 ou=asynx,<基准 DN>，只有 asynx 的绑定账号可以读写，ACL 见 README
+	SharedDn string `env:"DIRECTORY_SHARED_DN"`
+}
+
+// 内存目录配置，仅用于开发和测试，数据不会持久化
+type ConfigMemory struct {
+	BaseDN        string `env:"MEMORY_BASE_DN" envDefault:"dc=asynclab,dc=club"`
+	AdminUid      string `env:"MEMORY_ADMIN_UID" envDefault:"admin"`
+	AdminPassword string `env:"MEMORY_ADMIN_PASSWORD"`
+}
@@ -1,11 +1,21 @@
 package config
 
-// 邮件配置
+// 邮件配置，SMTP_* 只在 Transport 为 smtp 时需要
 type ConfigEmail struct {
-	Host     string `env:"SMTP_HOST,required"`
-	Port     int    `env:"SMTP_PORT,required"`
-	Username string `env:"SMTP_USERNAME,required"`
-	Password string `env:"SMTP_PASSWORD,required"`
-	From     string `env:"SMTP_FROM,required"`
-	ReplyTo  string `env:"SMTP_REPLY_TO"`
+	Transport      string `env:"MAIL_TRANSPORT" envDefault:"smtp"`
+	Host           string `env:"SMTP_HOST"`
+	Port           int    `env:"SMTP_PORT" envDefault:"587"`
+	Username       string `env:"SMTP_USERNAME"`
+	Password       string `env:"SMTP_PASSWORD"`
+	From           string `env:"SMTP_FROM"`
+	ReplyTo        string `env:"SMTP_REPLY_TO"`
+	Maildir        string `env:"MAIL_MAILDIR"`
+	MemoryCapacity int    `env:"MAIL_MEMORY_CAPACITY" envDefault:"200"`
+}
+
+// 邮件模板配置，TemplateDir 中的同名文件会覆盖内置模板
+type ConfigMailTemplate struct {
+	Dir             string `env:"MAIL_TEMPLATE_DIR"`
+	DefaultLanguage string `env:"MAIL_DEFAULT_LANGUAGE" envDefault:"zh"`
+	SiteUrl         string `env:"MAIL_SITE_URL" envDefault:"https://asynx.internal.asynclab.club/"`
 }
@@ -0,0 +1,11 @@
+package config
+
+import "time"
+
+// 邀请链接配置
+type ConfigInvitation struct {
+	MaxTTL    time.Duration `env:"INVITATION_MAX_TTL" envDefault:"2160h"`  // 邀请链接最长有效期
+	MaxUses   int           `env:"INVITATION_MAX_USES" envDefault:"200"`   // 每个邀请链接最多可使用的次数
+	VerifyTTL time.Duration `env:"INVITATION_VERIFY_TTL" envDefault:"24h"` // 限定域名的邀请中，邮箱验证链接的有效期
+	Retention time.Duration `env:"INVITATION_RETENTION" envDefault:"720h"` // 过期或撤销的邀请保留多久后被清理
+}
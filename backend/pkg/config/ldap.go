@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"asynclab.club/asynx/backend/pkg/entity"
 	"asynclab.club/asynx/backend/pkg/util"
 )
@@ -14,12 +16,22 @@ type ConfigLDAP struct {
 	GroupBaseDN string `env:"LDAP_GROUP_BASE_DN,required"`
 }
 
+// 目录变更监听配置，用于感知绕过 asynx 直接对 LDAP 的修改
+type ConfigLdapWatch struct {
+	Mode          string        `env:"LDAP_WATCH_MODE" envDefault:"auto"` // auto|syncrepl|poll|off，auto 在服务器不支持 syncrepl 时退回轮询
+	PollInterval  time.Duration `env:"LDAP_WATCH_POLL_INTERVAL" envDefault:"30s"`
+	RetryInterval time.Duration `env:"LDAP_WATCH_RETRY_INTERVAL" envDefault:"10s"`
+	EchoWindow    time.Duration `env:"LDAP_WATCH_ECHO_WINDOW" envDefault:"10s"` // 在此时间内 asynx 自己写过的条目不再重复产生事件
+}
+
 var LdapGidNumber = "10000"
 
-var UserObjectClasses = []string{"posixAccount", "inetOrgPerson", "organizationalPerson", "person"}
+// 新建用户时使用的对象类，shadowAccount 提供 shadowExpire
+var UserObjectClasses = []string{"posixAccount", "inetOrgPerson", "organizationalPerson", "person", "shadowAccount"}
 var GroupObjectClasses = []string{"posixGroup"}
 
-var UserObjectFilter = util.BuildObjectClassCondition(UserObjectClasses)
+// 查询用户时不要求 shadowAccount，早先创建的用户没有该对象类
+var UserObjectFilter = util.BuildObjectClassCondition(UserObjectClasses[:4])
 var GroupObjectFilter = util.BuildObjectClassCondition(GroupObjectClasses)
 
 var UserAttributes []string
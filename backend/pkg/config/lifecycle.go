@@ -0,0 +1,11 @@
+package config
+
+import "time"
+
+// 成员生命周期配置，成员在入学 AlumniAfterYears 年后的 AlumniDate 转为 alumni
+type ConfigLifecycle struct {
+	AlumniAfterYears int           `env:"LIFECYCLE_ALUMNI_AFTER_YEARS" envDefault:"0"`   // 入学多少年后转为 alumni，0 表示不启用
+	AlumniDate       string        `env:"LIFECYCLE_ALUMNI_DATE" envDefault:"07-01"`      // 转为 alumni 的月日，格式为 MM-DD
+	AlumniRole       string        `env:"LIFECYCLE_ALUMNI_ROLE" envDefault:"restricted"` // 转为 alumni 后的角色，原角色不高于该角色时保持不变
+	NotifyBefore     time.Duration `env:"LIFECYCLE_NOTIFY_BEFORE" envDefault:"720h"`     // 转换前多久邮件提醒，也是预览的默认范围
+}
@@ -0,0 +1,12 @@
+package config
+
+import "time"
+
+// OIDC 提供方配置，未设置 OIDC_ISSUER 时不启用
+type ConfigOidc struct {
+	Issuer         string        `env:"OIDC_ISSUER"`                       // 对外访问地址，如 https://id.asynclab.club，必须与前端同源
+	SigningKeyFile string        `env:"OIDC_SIGNING_KEY_FILE"`             // RS256 私钥 PEM 文件，启用 OIDC 时必填，所有实例必须使用同一个密钥
+	CodeTTL        time.Duration `env:"OIDC_CODE_TTL" envDefault:"1m"`     // 授权码有效期
+	RequestTTL     time.Duration `env:"OIDC_REQUEST_TTL" envDefault:"10m"` // 等待用户登录和授权的最长时间
+	TokenTTL       time.Duration `env:"OIDC_TOKEN_TTL" envDefault:"1h"`    // 访问令牌和 ID Token 的有效期
+}
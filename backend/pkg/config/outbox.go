@@ -0,0 +1,13 @@
+package config
+
+import "time"
+
+// 邮件发件箱配置
+type ConfigOutbox struct {
+	MaxAttempts  int           `env:"OUTBOX_MAX_ATTEMPTS" envDefault:"8"`
+	BaseDelay    time.Duration `env:"OUTBOX_BASE_DELAY" envDefault:"30s"`
+	MaxDelay     time.Duration `env:"OUTBOX_MAX_DELAY" envDefault:"1h"`
+	PollInterval time.Duration `env:"OUTBOX_POLL_INTERVAL" envDefault:"10s"`
+	Retention    time.Duration `env:"OUTBOX_RETENTION" envDefault:"168h"`
+	RateLimit    int           `env:"OUTBOX_RATE_LIMIT" envDefault:"60"` // 每分钟最多投递的邮件数，0 表示不限制
+}
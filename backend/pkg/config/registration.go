@@ -0,0 +1,14 @@
+package config
+
+import "time"
+
+// 自助注册配置，申请需要工作量证明并受每个 IP 的频率限制
+type ConfigRegistration struct {
+	Enabled       bool          `env:"REGISTRATION_ENABLED" envDefault:"false"`     // 是否开放自助注册申请
+	PowDifficulty int           `env:"REGISTRATION_POW_DIFFICULTY" envDefault:"18"` // 工作量证明要求的 SHA-256 前导零比特数，0 表示不要求
+	ChallengeTTL  time.Duration `env:"REGISTRATION_CHALLENGE_TTL" envDefault:"10m"` // 工作量证明题目的有效期
+	RateLimit     int           `env:"REGISTRATION_RATE_LIMIT" envDefault:"5"`      // 每个 IP 在 RateWindow 内最多提交的申请数，0 表示不限制
+	RateWindow    time.Duration `env:"REGISTRATION_RATE_WINDOW" envDefault:"1h"`    // 频率限制的时间窗口
+	VerifyTTL     time.Duration `env:"REGISTRATION_VERIFY_TTL" envDefault:"24h"`    // 邮箱验证链接的有效期，过期未验证的申请会被清理
+	Retention     time.Duration `env:"REGISTRATION_RETENTION" envDefault:"720h"`    // 已处理的申请保留多久后被清理
+}
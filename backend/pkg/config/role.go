@@ -0,0 +1,6 @@
+package config
+
+// 自定义角色配置，内置的 admin、default、restricted 角色不能被重新定义
+type ConfigRoles struct {
+	Permissions map[string]string `env:"ROLE_PERMISSIONS" envSeparator:";" envKeyValSeparator:"="` // 角色名=权限列表，权限之间用逗号分隔，角色之间用分号分隔，如 ops=users.read,audit.read;helpdesk=users.*；每个角色需要在 supplementary 下有同名的组
+}
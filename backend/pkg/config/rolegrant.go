@@ -0,0 +1,9 @@
+package config
+
+import "time"
+
+// 临时角色配置
+type ConfigRoleGrant struct {
+	NotifyBefore time.Duration `env:"ROLE_GRANT_NOTIFY_BEFORE" envDefault:"72h"`  // 到期前多久发送提醒，0 表示不提醒
+	MaxDuration  time.Duration `env:"ROLE_GRANT_MAX_DURATION" envDefault:"4380h"` // 临时角色最长有效期
+}
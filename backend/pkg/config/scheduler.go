@@ -0,0 +1,12 @@
+package config
+
+import "time"
+
+// 后台任务调度配置
+type ConfigScheduler struct {
+	Enabled   bool              `env:"SCHEDULER_ENABLED" envDefault:"true"`                         // 关闭后本实例不参与选主，也不运行任何任务
+	Instance  string            `env:"SCHEDULER_INSTANCE"`                                          // 实例名，用于选主，为空时使用主机名加随机后缀
+	LockDn    string            `env:"SCHEDULER_LOCK_DN"`                                           // 选主锁条目的 DN，为空时使用 BaseDN 下的 cn=asynx-scheduler
+	LockTTL   time.Duration     `env:"SCHEDULER_LOCK_TTL" envDefault:"30s"`                         // 租约有效期，主实例每隔三分之一有效期续约一次
+	Schedules map[string]string `env:"SCHEDULER_SCHEDULES" envSeparator:";" envKeyValSeparator:"="` // 覆盖任务的 cron 表达式，如 role-grants=@every 5m;uid-numbers=0 4 * * *，表达式为 - 时禁用该任务
+}
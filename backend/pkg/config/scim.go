@@ -0,0 +1,8 @@
+package config
+
+// SCIM 预配配置，未设置令牌时不启用 /scim/v2
+type ConfigScim struct {
+	Tokens          []string `env:"SCIM_TOKENS" envSeparator:","`              // 身份提供方使用的 Bearer 令牌，多个用逗号分隔以便轮换
+	DefaultCategory string   `env:"SCIM_DEFAULT_CATEGORY" envDefault:"member"` // 创建用户时未指定扩展属性的默认分类
+	DefaultRole     string   `env:"SCIM_DEFAULT_ROLE" envDefault:"default"`    // 创建用户时未指定扩展属性的默认角色
+}
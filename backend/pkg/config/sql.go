@@ -0,0 +1,9 @@
+package config
+
+// SQL 存储配置
+type ConfigSQL struct {
+	Driver        string `env:"SQL_DRIVER" envDefault:"sqlite"` // sqlite|postgres
+	DSN           string `env:"SQL_DSN" envDefault:"asynx.db"`
+	AdminUid      string `env:"SQL_ADMIN_UID" envDefault:"admin"`
+	AdminPassword string `env:"SQL_ADMIN_PASSWORD"`
+}
@@ -0,0 +1,10 @@
+package config
+
+import "time"
+
+// 事件流配置
+type ConfigStream struct {
+	Capacity  int           `env:"EVENT_STREAM_CAPACITY" envDefault:"1000"` // 用于断线续传的最近事件数
+	Buffer    int           `env:"EVENT_STREAM_BUFFER" envDefault:"64"`     // 单个连接允许积压的事件数，超出后断开
+	Heartbeat time.Duration `env:"EVENT_STREAM_HEARTBEAT" envDefault:"15s"`
+}
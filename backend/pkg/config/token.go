@@ -0,0 +1,10 @@
+package config
+
+import "time"
+
+// 个人访问令牌配置
+type ConfigAccessToken struct {
+	MaxTTL     time.Duration `env:"ACCESS_TOKEN_MAX_TTL" envDefault:"8760h"`   // 令牌最长有效期
+	MaxPerUser int           `env:"ACCESS_TOKEN_MAX_PER_USER" envDefault:"20"` // 每个用户最多持有的令牌数
+	Retention  time.Duration `env:"ACCESS_TOKEN_RETENTION" envDefault:"720h"`  // 过期的令牌保留多久后被清理，保留期间用户仍能看到并轮换
+}
@@ -0,0 +1,13 @@
+package config
+
+import "time"
+
+// Webhook 投递配置
+type ConfigWebhook struct {
+	MaxAttempts  int           `env:"WEBHOOK_MAX_ATTEMPTS" envDefault:"10"`
+	BaseDelay    time.Duration `env:"WEBHOOK_BASE_DELAY" envDefault:"10s"`
+	MaxDelay     time.Duration `env:"WEBHOOK_MAX_DELAY" envDefault:"1h"`
+	PollInterval time.Duration `env:"WEBHOOK_POLL_INTERVAL" envDefault:"5s"`
+	Timeout      time.Duration `env:"WEBHOOK_TIMEOUT" envDefault:"10s"`
+	Retention    time.Duration `env:"WEBHOOK_RETENTION" envDefault:"720h"`
+}
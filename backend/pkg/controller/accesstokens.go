@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"net/http"
+
+	"asynclab.club/asynx/backend/pkg/security"
+	"asynclab.club/asynx/backend/pkg/service"
+	"github.com/dsx137/gg-gin/pkg/gggin"
+	"github.com/gin-gonic/gin"
+)
+
+type ControllerAccessTokens struct {
+	serviceAccessToken *service.ServiceAccessToken
+}
+
+func NewControllerAccessTokens(g *gin.RouterGroup, serviceAccessToken *service.ServiceAccessToken) *ControllerAccessTokens {
+	ctl := &ControllerAccessTokens{serviceAccessToken: serviceAccessToken}
+	g.GET("", security.GuardMiddleware(security.PermUsersSelf), gggin.ToGinHandler(ctl.HandleList))
+	g.POST("", security.GuardMiddleware(security.PermUsersSelf), gggin.ToGinHandler(ctl.HandleCreate))
+	g.DELETE("/:id", security.GuardMiddleware(security.PermUsersSelf), gggin.ToGinHandler(ctl.HandleRevoke))
+	return ctl
+}
+
+// @Summary      获取个人访问令牌列表
+// @Description  获取自己的个人访问令牌，不包含令牌明文。需要 users.self 权限。拥有 access-tokens.manage 权限的用户可以通过 uid 查看其他用户的令牌。
+// @Tags         access-tokens
+// @Accept       json
+// @Produce      json
+// @Param        uid  query     string  false  "用户ID，默认为当前用户"
+// @Success      200  {object} object{data=[]service.AccessToken} "成功返回令牌列表"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /access-tokens [get]
+// @Security     BearerAuth
+func (ctl *ControllerAccessTokens) HandleList(c *gin.Context) (*gggin.Response[[]*service.AccessToken], *gggin.HttpError) {
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
+	if !ok {
+		return nil, ErrHttpGuardFail
+	}
+
+	uid := c.DefaultQuery("uid", guard.Uid)
+	if !guard.Role.Can(security.PermAccessTokensManage) && guard.Uid != uid {
+		return nil, gggin.NewHttpError(http.StatusForbidden, "权限不足")
+	}
+
+	tokens, err := ctl.serviceAccessToken.List(uid)
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(tokens), nil
+}
+
+// @Summary      创建个人访问令牌
+// @Description  为当前用户创建个人访问令牌，供脚本通过 Authorization: Bearer 头调用接口。令牌明文只在本次返回，服务端只保存摘要。
+// @Description  scopes 的格式为 <资源>:<read|write>，资源是 /api 下的第一级路径（如 users、webhooks），* 表示全部资源；read 只允许 GET 请求，write 允许全部请求。令牌不能访问 access-tokens 接口。
+// @Description  role 的权限必须是自己当前角色权限的子集，为空时使用当前角色；持有者被降级后令牌也随之降级，持有者的新角色与令牌角色互不包含时令牌失效。需要 users.self 权限。
+// @Tags         access-tokens
+// @Accept       json
+// @Produce      json
+// @Param        body  body      service.AccessTokenSpec  true  "创建令牌请求"
+// @Success      200  {object} object{data=service.AccessToken} "成功返回令牌（包含明文）"
+// @Failure      400  {object} object{data=string} "请求参数错误"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /access-tokens [post]
+// @Security     BearerAuth
+func (ctl *ControllerAccessTokens) HandleCreate(c *gin.Context) (*gggin.Response[*service.AccessToken], *gggin.HttpError) {
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
+	if !ok {
+		return nil, ErrHttpGuardFail
+	}
+
+	req, err := gggin.ShouldBindJSON[service.AccessTokenSpec](c)
+	if err != nil {
+		return nil, gggin.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	token, err := ctl.serviceAccessToken.Create(guard.Uid, req)
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(token), nil
+}
+
+// @Summary      撤销个人访问令牌
+// @Description  删除个人访问令牌，立即失效。需要 users.self 权限。拥有 access-tokens.manage 权限的用户可以撤销任何用户的令牌，其他用户只能撤销自己的。
+// @Tags         access-tokens
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "令牌ID"
+// @Success      200  {object} object{data=string} "成功撤销，返回 'ok'"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      404  {object} object{data=string} "令牌不存在"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /access-tokens/{id} [delete]
+// @Security     BearerAuth
+func (ctl *ControllerAccessTokens) HandleRevoke(c *gin.Context) (*gggin.Response[string], *gggin.HttpError) {
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
+	if !ok {
+		return nil, ErrHttpGuardFail
+	}
+
+	if err := ctl.serviceAccessToken.Revoke(guard.Uid, c.Param("id"), guard.Role.Can(security.PermAccessTokensManage)); err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.Ok, nil
+}
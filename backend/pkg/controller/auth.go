@@ -0,0 +1,216 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/security"
+	"asynclab.club/asynx/backend/pkg/service"
+	"github.com/dsx137/gg-gin/pkg/gggin"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// 转发认证成功时交给上游应用的请求头
+const (
+	HeaderAuthUser   = "X-Auth-User"
+	HeaderAuthRole   = "X-Auth-Role"
+	HeaderAuthGroups = "X-Auth-Groups"
+)
+
+// 转发认证 Cookie，其中的令牌只能用于 /api/auth/verify。与前端的登录令牌分开保存，
+// 登录令牌只发给前端所在的主机，不会随请求到达各子域名上的上游应用
+const (
+	forwardAuthCookie       = "asynx_forward_auth"
+	forwardAuthCookieMaxAge = 24 * 60 * 60 // 与令牌的有效期一致
+)
+
+func setForwardAuthCookie(c *gin.Context, cfg *config.ConfigForwardAuth, token string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(forwardAuthCookie, token, maxAge, "/", cfg.CookieDomain, true, true)
+}
+
+type ControllerAuth struct {
+	cfg                *config.ConfigForwardAuth
+	serviceForwardAuth *service.ServiceForwardAuth
+}
+
+func NewControllerAuth(g *gin.RouterGroup, cfg *config.ConfigForwardAuth, serviceForwardAuth *service.ServiceForwardAuth) *ControllerAuth {
+	ctl := &ControllerAuth{cfg: cfg, serviceForwardAuth: serviceForwardAuth}
+	// nginx 的 auth_request 子请求会沿用原请求的方法，HEAD 请求也需要放行
+	g.GET("/verify", ctl.HandleVerify)
+	g.HEAD("/verify", ctl.HandleVerify)
+	g.POST("/session", gggin.ToGinHandler(ctl.HandleCreateSession))
+	g.DELETE("/session", gggin.ToGinHandler(ctl.HandleDeleteSession))
+	return ctl
+}
+
+// @Summary      签发转发认证 Cookie
+// @Description  用 Authorization 头中的登录令牌换取 asynx_forward_auth Cookie（HttpOnly、Secure、SameSite=Lax），前端在登录后调用。
+// @Description  Cookie 中的令牌只能用于 /api/auth/verify，不能访问其他接口；个人访问令牌不能换取。
+// @Description  Cookie 会发给 FORWARD_AUTH_COOKIE_DOMAIN 下的所有主机，反向代理必须在把请求转发给上游应用之前删除它
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Success      200  {object} object{data=string} "成功签发，返回 'ok'"
+// @Failure      401  {object} object{data=string} "未登录"
+// @Failure      403  {object} object{data=string} "账号已到期"
+// @Failure      404  {object} object{data=string} "用户不存在"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /auth/session [post]
+// @Security     BearerAuth
+func (ctl *ControllerAuth) HandleCreateSession(c *gin.Context) (*gggin.Response[string], *gggin.HttpError) {
+	token, _ := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	claims, err := security.ParsePaseto(token)
+	if err != nil {
+		return nil, gggin.NewHttpError(http.StatusUnauthorized, "a session token is required")
+	}
+	forwardToken, err := ctl.serviceForwardAuth.IssueToken(claims.Uid)
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	setForwardAuthCookie(c, ctl.cfg, forwardToken, forwardAuthCookieMaxAge)
+	return gggin.Ok, nil
+}
+
+// @Summary      清除转发认证 Cookie
+// @Description  前端退出登录时调用，各子域名上的应用随之失去登录状态
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Success      200  {object} object{data=string} "成功清除，返回 'ok'"
+// @Router       /auth/session [delete]
+func (ctl *ControllerAuth) HandleDeleteSession(c *gin.Context) (*gggin.Response[string], *gggin.HttpError) {
+	setForwardAuthCookie(c, ctl.cfg, "", -1)
+	return gggin.Ok, nil
+}
+
+// @Summary      反向代理转发认证
+// @Description  供 nginx auth_request 和 Traefik ForwardAuth 使用，只读取 asynx_forward_auth Cookie 中的转发认证令牌，不接受登录令牌和访问令牌。
+// @Description  反向代理应在转发给上游应用前删除该 Cookie，如 nginx 中用 map 从 $http_cookie 去掉后以 proxy_set_header Cookie 传递。
+// @Description  通过时返回 200 并设置 X-Auth-User、X-Auth-Role、X-Auth-Groups 头；未登录时跳转到登录页，登录后回到原地址；
+// @Description  权限不足时返回 403。原地址取自 X-Original-URL 头，或 X-Forwarded-Proto、X-Forwarded-Host、X-Forwarded-Uri 头。
+// @Description  nginx 的 auth_request 只接受 2xx、401 和 403，需要传 redirect=false，再通过 error_page 401 跳转到响应的 Location 头
+// @Tags         auth
+// @Param        role      query     string  false  "要求的最低角色，默认为任意有角色的用户"
+// @Param        permission  query   []string  false  "要求拥有的权限，如 audit.read，可以重复或用逗号分隔，需要全部满足"
+// @Param        group     query     []string  false  "要求所属的组，可以重复或用逗号分隔，满足其一即可"
+// @Param        redirect  query     bool    false  "未登录时是否返回 302，默认为 true；为 false 时返回 401"
+// @Success      200  "认证通过"
+// @Failure      302  "未登录，跳转到登录页"
+// @Failure      400  "参数错误"
+// @Failure      401  "未登录"
+// @Failure      403  "权限不足"
+// @Router       /auth/verify [get]
+func (ctl *ControllerAuth) HandleVerify(c *gin.Context) {
+	var required security.Role
+	if name := c.Query("role"); name != "" {
+		role, err := security.GetRoleFromName(name)
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		required = role
+	}
+	groups := queryList(c, "group")
+	permissions, err := security.ParsePermissions(queryList(c, "permission"))
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	claims := forwardAuthClaims(c)
+	if claims == nil {
+		ctl.unauthenticated(c)
+		return
+	}
+
+	identity, err := ctl.serviceForwardAuth.Identify(claims.Uid)
+	// 账号被删除或到期时按未登录处理，登录页会提示原因
+	if errors.Is(err, service.ErrNotFound) || errors.Is(err, service.ErrDenied) {
+		ctl.unauthenticated(c)
+		return
+	}
+	if err != nil {
+		logrus.Errorf("Failed to verify forward auth request of %s: %v", claims.Uid, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	if !identity.Satisfies(required, permissions, groups) {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	c.Header(HeaderAuthUser, identity.Uid)
+	c.Header(HeaderAuthRole, identity.Role.String())
+	c.Header(HeaderAuthGroups, strings.Join(identity.Groups, ","))
+	c.Status(http.StatusOK)
+}
+
+// queryList 读取可以重复或用逗号分隔的查询参数
+func queryList(c *gin.Context, key string) []string {
+	var values []string
+	for _, value := range c.QueryArray(key) {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
+
+// forwardAuthClaims 读取转发认证 Cookie 中的令牌，上游应用自己的 Authorization 头不参与认证
+func forwardAuthClaims(c *gin.Context) *security.PasetoClaims {
+	token, err := c.Cookie(forwardAuthCookie)
+	if err != nil {
+		return nil
+	}
+	claims, err := security.ParseForwardAuthPaseto(token)
+	if err != nil {
+		return nil
+	}
+	return claims
+}
+
+// unauthenticated 跳转到登录页，登录后由前端回到原地址
+func (ctl *ControllerAuth) unauthenticated(c *gin.Context) {
+	if ctl.cfg.LoginUrl == "" {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	location := ctl.cfg.LoginUrl
+	if original := originalUrl(c); original != "" {
+		location += "?" + url.Values{"redirect": {original}}.Encode()
+	}
+	c.Header("Location", location)
+
+	if redirect, err := strconv.ParseBool(c.DefaultQuery("redirect", "true")); err == nil && !redirect {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+	c.Status(http.StatusFound)
+}
+
+// originalUrl 还原被代理的原始请求地址，无法还原时返回空字符串
+func originalUrl(c *gin.Context) string {
+	original := c.GetHeader("X-Original-URL")
+	if original == "" {
+		proto, host := c.GetHeader("X-Forwarded-Proto"), c.GetHeader("X-Forwarded-Host")
+		if proto == "" || host == "" {
+			return ""
+		}
+		original = proto + "://" + host + c.GetHeader("X-Forwarded-Uri")
+	}
+
+	u, err := url.Parse(original)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return u.String()
+}
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"net/http"
+
+	"asynclab.club/asynx/backend/pkg/security"
+	"asynclab.club/asynx/backend/pkg/service"
+	"github.com/dsx137/gg-gin/pkg/gggin"
+	"github.com/gin-gonic/gin"
+)
+
+type ControllerBroadcast struct {
+	serviceBroadcast *service.ServiceBroadcast
+}
+
+func NewControllerBroadcasts(g *gin.RouterGroup, serviceBroadcast *service.ServiceBroadcast) *ControllerBroadcast {
+	ctl := &ControllerBroadcast{serviceBroadcast: serviceBroadcast}
+	g.GET("", security.GuardMiddleware(security.PermMailManage), gggin.ToGinHandler(ctl.HandleList))
+	g.POST("", security.GuardMiddleware(security.PermMailManage), gggin.ToGinHandler(ctl.HandleSend))
+	g.GET("/:id", security.GuardMiddleware(security.PermMailManage), gggin.ToGinHandler(ctl.HandleGet))
+	return ctl
+}
+
+type RequestBroadcast struct {
+	Subject string                  `json:"subject" binding:"required"`
+	Body    string                  `json:"body" binding:"required"`
+	Format  string                  `json:"format"`
+	Target  service.BroadcastTarget `json:"target"`
+	DryRun  bool                    `json:"dryRun"`
+}
+
+// @Summary      群发邮件
+// @Description  向某一账号类型、角色或组的所有用户群发邮件。正文支持 Markdown 或 HTML，经邮件模板渲染后进入发件箱，按发件箱的速率限制投递。dryRun 为 true 时只返回收件人列表。需要 mail.manage 权限。
+// @Tags         broadcasts
+// @Accept       json
+// @Produce      json
+// @Param        body  body      RequestBroadcast  true  "群发请求\nformat: markdown|html\ntarget 中 category、role、group 只能指定一个"
+// @Success      200  {object} object{data=service.Broadcast} "成功返回投递报告"
+// @Failure      400  {object} object{data=string} "请求参数错误"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "组不存在"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /broadcasts [post]
+// @Security     BearerAuth
+func (ctl *ControllerBroadcast) HandleSend(c *gin.Context) (*gggin.Response[*service.Broadcast], *gggin.HttpError) {
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
+	if !ok {
+		return nil, ErrHttpGuardFail
+	}
+
+	req, err := gggin.ShouldBindJSON[RequestBroadcast](c)
+	if err != nil {
+		return nil, gggin.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	broadcast, err := ctl.serviceBroadcast.Send(guard.Uid, req.Subject, req.Format, req.Body, req.Target, req.DryRun)
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+
+	return gggin.NewResponse(broadcast), nil
+}
+
+// @Summary      获取群发记录
+// @Description  获取所有群发记录及投递统计，不包含收件人明细。需要 mail.manage 权限。
+// @Tags         broadcasts
+// @Accept       json
+// @Produce      json
+// @Success      200  {object} object{data=[]service.Broadcast} "成功返回群发记录"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Router       /broadcasts [get]
+// @Security     BearerAuth
+func (ctl *ControllerBroadcast) HandleList(c *gin.Context) (*gggin.Response[[]service.Broadcast], *gggin.HttpError) {
+	broadcasts, err := ctl.serviceBroadcast.List()
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(broadcasts), nil
+}
+
+// @Summary      获取投递报告
+// @Description  获取一次群发的投递报告，包含每个收件人的最新投递状态。需要 mail.manage 权限。
+// @Tags         broadcasts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "群发ID"
+// @Success      200  {object} object{data=service.Broadcast} "成功返回投递报告"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "群发记录不存在"
+// @Router       /broadcasts/{id} [get]
+// @Security     BearerAuth
+func (ctl *ControllerBroadcast) HandleGet(c *gin.Context) (*gggin.Response[*service.Broadcast], *gggin.HttpError) {
+	broadcast, err := ctl.serviceBroadcast.Get(c.Param("id"))
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(broadcast), nil
+}
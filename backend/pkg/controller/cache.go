@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"asynclab.club/asynx/backend/pkg/security"
+	"asynclab.club/asynx/backend/pkg/service"
+	"github.com/dsx137/gg-gin/pkg/gggin"
+	"github.com/gin-gonic/gin"
+)
+
+type ControllerCache struct {
+	serviceCache *service.ServiceCache
+}
+
+func NewControllerCache(g *gin.RouterGroup, serviceCache *service.ServiceCache) *ControllerCache {
+	ctl := &ControllerCache{serviceCache: serviceCache}
+	g.GET("", security.GuardMiddleware(security.PermSystemManage), gggin.ToGinHandler(ctl.HandleStatus))
+	g.DELETE("", security.GuardMiddleware(security.PermSystemManage), gggin.ToGinHandler(ctl.HandleFlush))
+	return ctl
+}
+
+// @Summary      获取缓存状态
+// @Description  获取用户和角色组读缓存的条目数与命中率，命中计数从进程启动开始累计。需要 system.manage 权限。
+// @Tags         cache
+// @Accept       json
+// @Produce      json
+// @Success      200  {object} object{data=service.CacheStatus} "成功返回缓存状态"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Router       /cache [get]
+// @Security     BearerAuth
+func (ctl *ControllerCache) HandleStatus(c *gin.Context) (*gggin.Response[*service.CacheStatus], *gggin.HttpError) {
+	return gggin.NewResponse(ctl.serviceCache.Status()), nil
+}
+
+// @Summary      清空缓存
+// @Description  清空用户和角色组读缓存，用于在其他副本或外部工具修改目录后立即生效。未启用缓存时不做任何事。需要 system.manage 权限。
+// @Tags         cache
+// @Accept       json
+// @Produce      json
+// @Success      200  {object} object{data=string} "成功清空，返回 'ok'"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Router       /cache [delete]
+// @Security     BearerAuth
+func (ctl *ControllerCache) HandleFlush(c *gin.Context) (*gggin.Response[string], *gggin.HttpError) {
+	ctl.serviceCache.Flush()
+	return gggin.Ok, nil
+}
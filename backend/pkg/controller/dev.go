@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"asynclab.club/asynx/backend/pkg/client"
+	"asynclab.club/asynx/backend/pkg/security"
+	"asynclab.club/asynx/backend/pkg/service"
+	"github.com/dsx137/gg-gin/pkg/gggin"
+	"github.com/gin-gonic/gin"
+)
+
+// ControllerDev 只在 MAIL_TRANSPORT=memory 时注册
+type ControllerDev struct {
+	serviceDev *service.ServiceDev
+}
+
+func NewControllerDev(g *gin.RouterGroup, serviceDev *service.ServiceDev) *ControllerDev {
+	ctl := &ControllerDev{serviceDev: serviceDev}
+	g.GET("/mails", security.GuardMiddleware(security.PermMailManage), gggin.ToGinHandler(ctl.HandleListMails))
+	g.GET("/mails/:id", security.GuardMiddleware(security.PermMailManage), gggin.ToGinHandler(ctl.HandleGetMail))
+	g.DELETE("/mails", security.GuardMiddleware(security.PermMailManage), gggin.ToGinHandler(ctl.HandleClearMails))
+	return ctl
+}
+
+// @Summary      获取已发送邮件
+// @Description  获取内存邮件投递器中保存的邮件，按发送时间倒序。仅在 MAIL_TRANSPORT=memory 时可用。需要 mail.manage 权限。
+// @Tags         dev
+// @Accept       json
+// @Produce      json
+// @Param        to   query     string  false  "只返回发给该地址的邮件"
+// @Success      200  {object} object{data=[]client.Mail} "成功返回邮件列表"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Router       /dev/mails [get]
+// @Security     BearerAuth
+func (ctl *ControllerDev) HandleListMails(c *gin.Context) (*gggin.Response[[]client.Mail], *gggin.HttpError) {
+	return gggin.NewResponse(ctl.serviceDev.ListMails(c.Query("to"))), nil
+}
+
+// @Summary      获取已发送邮件详情
+// @Description  根据ID获取内存邮件投递器中保存的一封邮件。仅在 MAIL_TRANSPORT=memory 时可用。需要 mail.manage 权限。
+// @Tags         dev
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "邮件ID"
+// @Success      200  {object} object{data=client.Mail} "成功返回邮件"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "邮件不存在"
+// @Router       /dev/mails/{id} [get]
+// @Security     BearerAuth
+func (ctl *ControllerDev) HandleGetMail(c *gin.Context) (*gggin.Response[*client.Mail], *gggin.HttpError) {
+	mail, err := ctl.serviceDev.GetMail(c.Param("id"))
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(mail), nil
+}
+
+// @Summary      清空已发送邮件
+// @Description  清空内存邮件投递器中保存的所有邮件。仅在 MAIL_TRANSPORT=memory 时可用。需要 mail.manage 权限。
+// @Tags         dev
+// @Accept       json
+// @Produce      json
+// @Success      200  {object} object{data=string} "成功清空，返回 'ok'"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Router       /dev/mails [delete]
+// @Security     BearerAuth
+func (ctl *ControllerDev) HandleClearMails(c *gin.Context) (*gggin.Response[string], *gggin.HttpError) {
+	ctl.serviceDev.ClearMails()
+	return gggin.Ok, nil
+}
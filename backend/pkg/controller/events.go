@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/event"
+	"asynclab.club/asynx/backend/pkg/security"
+	"asynclab.club/asynx/backend/pkg/service"
+	"github.com/dsx137/gg-gin/pkg/gggin"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// EventReset 在 Last-Event-ID 已经过期时发送，客户端收到后应重新拉取全量数据
+const EventReset = "reset"
+
+type ControllerEvents struct {
+	serviceEvent *service.ServiceEvent
+	heartbeat    time.Duration
+}
+
+func NewControllerEvents(g *gin.RouterGroup, serviceEvent *service.ServiceEvent, heartbeat time.Duration) *ControllerEvents {
+	ctl := &ControllerEvents{serviceEvent: serviceEvent, heartbeat: heartbeat}
+	g.GET("", security.GuardMiddleware(security.PermAuditRead), ctl.HandleStream)
+	return ctl
+}
+
+// @Summary      订阅目录变更事件
+// @Description  以 Server-Sent Events 推送目录变更。事件名为事件类型（如 user.created、user.role_changed），id 为事件ID，data 为事件 JSON。断线后浏览器会带上 Last-Event-ID 头自动续传；该事件已不在缓冲区时先推送一条 reset 事件，客户端应重新拉取用户列表。需要 audit.read 权限。
+// @Tags         events
+// @Produce      text/event-stream
+// @Param        events         query     string  false  "逗号分隔的事件过滤条件，支持 user.*、group.* 和 *，默认订阅全部"
+// @Param        Last-Event-ID  header    string  false  "上次收到的事件ID"
+// @Success      200  {string} string "事件流"
+// @Failure      400  {object} object{data=string} "请求参数错误"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Router       /events [get]
+// @Security     BearerAuth
+func (ctl *ControllerEvents) HandleStream(c *gin.Context) {
+	var patterns []string
+	if events := c.Query("events"); events != "" {
+		patterns = strings.Split(events, ",")
+	}
+	lastId := c.GetHeader("Last-Event-ID")
+	if lastId == "" {
+		lastId = c.Query("lastEventId")
+	}
+
+	sub, err := ctl.serviceEvent.Subscribe(lastId, patterns)
+	if err != nil {
+		httpErr := service.MapErrorToHttp(err)
+		c.JSON(httpErr.StatusCode, gggin.NewResponse(httpErr.Message))
+		return
+	}
+	defer sub.Cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	if !sub.Resumed {
+		c.Render(-1, sse.Event{Event: EventReset, Data: lastId})
+	}
+	for _, e := range sub.Backlog {
+		c.Render(-1, toSse(e))
+	}
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(ctl.heartbeat)
+	defer ticker.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-ticker.C:
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		case e, ok := <-sub.Events:
+			if !ok {
+				return false
+			}
+			if sub.Accepts(e.Type) {
+				c.Render(-1, toSse(e))
+			}
+			return true
+		}
+	})
+}
+
+func toSse(e event.Event) sse.Event {
+	return sse.Event{Id: e.Id, Event: e.Type.String(), Data: e}
+}
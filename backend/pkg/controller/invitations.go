@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"net/http"
+
+	"asynclab.club/asynx/backend/pkg/security"
+	"asynclab.club/asynx/backend/pkg/service"
+	"github.com/dsx137/gg-gin/pkg/gggin"
+	"github.com/gin-gonic/gin"
+)
+
+type ControllerInvitations struct {
+	serviceInvitation *service.ServiceInvitation
+	serviceManager    *service.ServiceManager
+}
+
+func NewControllerInvitations(g *gin.RouterGroup, serviceInvitation *service.ServiceInvitation, serviceManager *service.ServiceManager) *ControllerInvitations {
+	ctl := &ControllerInvitations{serviceInvitation: serviceInvitation, serviceManager: serviceManager}
+	g.GET("/lookup", gggin.ToGinHandler(ctl.HandleLookup))
+	g.POST("/verify", gggin.ToGinHandler(ctl.HandleVerify))
+	g.POST("/accept", gggin.ToGinHandler(ctl.HandleAccept))
+	g.GET("", security.GuardMiddleware(security.PermUsersWrite), gggin.ToGinHandler(ctl.HandleList))
+	g.POST("", security.GuardMiddleware(security.PermUsersWrite), gggin.ToGinHandler(ctl.HandleCreate))
+	g.DELETE("/:id", security.GuardMiddleware(security.PermUsersWrite), gggin.ToGinHandler(ctl.HandleRevoke))
+	return ctl
+}
+
+// @Summary      获取邀请列表
+// @Description  获取全部邀请，包括已过期、已用完和已撤销的，按创建时间排序。需要 users.write 权限。
+// @Tags         invitations
+// @Accept       json
+// @Produce      json
+// @Success      200  {object} object{data=[]service.Invitation} "成功返回邀请列表"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /invitations [get]
+// @Security     BearerAuth
+func (ctl *ControllerInvitations) HandleList(c *gin.Context) (*gggin.Response[[]*service.Invitation], *gggin.HttpError) {
+	invitations, err := ctl.serviceInvitation.List()
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(invitations), nil
+}
+
+// @Summary      创建邀请
+// @Description  创建绑定账号类型（member|external|alumni）和角色的邀请链接，可以限制为某个邮箱域名或单个邮箱，限定域名时受邀人需要先验证邮箱，链接只在创建时返回一次。
+// @Description  需要 users.write 权限，委派管理员只能邀请到被委派的账号类型，没有 users.role.grant 权限时角色不能高于自己。
+// @Tags         invitations
+// @Accept       json
+// @Produce      json
+// @Param        body  body      service.InvitationSpec  true  "邀请"
+// @Success      200  {object} object{data=service.Invitation} "成功返回邀请和链接"
+// @Failure      400  {object} object{data=string} "请求参数错误"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /invitations [post]
+// @Security     BearerAuth
+func (ctl *ControllerInvitations) HandleCreate(c *gin.Context) (*gggin.Response[*service.Invitation], *gggin.HttpError) {
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
+	if !ok {
+		return nil, ErrHttpGuardFail
+	}
+	req, err := gggin.ShouldBindJSON[service.InvitationSpec](c)
+	if err != nil {
+		return nil, gggin.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := ctl.serviceManager.AuthorizeRegister(guard, req.Category, req.Role); err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+
+	invitation, err := ctl.serviceInvitation.Create(guard.Uid, req)
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(invitation), nil
+}
+
+// @Summary      撤销邀请
+// @Description  撤销邀请后链接不能再使用，已通过该邀请注册的账号不受影响。需要 users.write 权限。
+// @Tags         invitations
+// @Accept       json
+// @Produce      json
+// @Param        id  path      string  true  "邀请ID"
+// @Success      200  {object} object{data=string} "成功撤销，返回 'ok'"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "邀请不存在"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /invitations/{id} [delete]
+// @Security     BearerAuth
+func (ctl *ControllerInvitations) HandleRevoke(c *gin.Context) (*gggin.Response[string], *gggin.HttpError) {
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
+	if !ok {
+		return nil, ErrHttpGuardFail
+	}
+	if err := ctl.serviceInvitation.Revoke(guard.Uid, c.Param("id")); err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.Ok, nil
+}
+
+// @Summary      查看邀请
+// @Description  使用邀请链接中的 token 查看邀请的账号类型、角色和邮箱限制。提供验证邮件链接中的 verification 时，一并返回已验证的邮箱和姓名。无需登录。
+// @Tags         invitations
+// @Accept       json
+// @Produce      json
+// @Param        token         query     string  true   "邀请链接中的 token"
+// @Param        verification  query     string  false  "验证邮件链接中的 verification"
+// @Success      200  {object} object{data=service.InvitationPrompt} "成功返回邀请内容"
+// @Failure      400  {object} object{data=string} "邀请或邮箱验证无效、已过期、已用完或已撤销"
+// @Router       /invitations/lookup [get]
+func (ctl *ControllerInvitations) HandleLookup(c *gin.Context) (*gggin.Response[*service.InvitationPrompt], *gggin.HttpError) {
+	prompt, err := ctl.serviceInvitation.Lookup(c.Query("token"), c.Query("verification"))
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(prompt), nil
+}
+
+// @Summary      验证邀请邮箱
+// @Description  限定域名的邀请需要先验证邮箱：向受邀人填写的邮箱发送验证链接，链接中的 verification 用于接受邀请。同一邮箱每分钟最多发送一次，重新发送后之前的链接失效。无需登录。
+// @Tags         invitations
+// @Accept       json
+// @Produce      json
+// @Param        body  body      service.InvitationVerifySpec  true  "邮箱和姓名"
+// @Success      200  {object} object{data=string} "成功发送验证邮件，返回 'ok'"
+// @Failure      400  {object} object{data=string} "请求参数错误、邀请无效或邀请不限定域名"
+// @Failure      429  {object} object{data=string} "发送过于频繁"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /invitations/verify [post]
+func (ctl *ControllerInvitations) HandleVerify(c *gin.Context) (*gggin.Response[string], *gggin.HttpError) {
+	req, err := gggin.ShouldBindJSON[service.InvitationVerifySpec](c)
+	if err != nil {
+		return nil, gggin.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+	if err := ctl.serviceInvitation.RequestVerification(req); err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.Ok, nil
+}
+
+// @Summary      接受邀请
+// @Description  使用邀请注册账号，密码由受邀人设置并需满足强度要求，欢迎邮件中不附带密码。限定域名的邀请需要提供验证邮件链接中的 verification，邮箱必须与验证的邮箱一致。无需登录。
+// @Tags         invitations
+// @Accept       json
+// @Produce      json
+// @Param        body  body      service.InvitationAcceptSpec  true  "注册信息"
+// @Success      200  {object} object{data=string} "成功注册，返回 'ok'"
+// @Failure      400  {object} object{data=string} "请求参数错误或邀请无效"
+// @Failure      409  {object} object{data=string} "用户已存在"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /invitations/accept [post]
+func (ctl *ControllerInvitations) HandleAccept(c *gin.Context) (*gggin.Response[string], *gggin.HttpError) {
+	req, err := gggin.ShouldBindJSON[service.InvitationAcceptSpec](c)
+	if err != nil {
+		return nil, gggin.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+	if err := ctl.serviceInvitation.Accept(req); err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.Ok, nil
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"bytes"
+
+	"asynclab.club/asynx/backend/pkg/security"
+	"asynclab.club/asynx/backend/pkg/service"
+	"github.com/dsx137/gg-gin/pkg/gggin"
+	"github.com/gin-gonic/gin"
+)
+
+type ControllerJobs struct {
+	serviceScheduler *service.ServiceScheduler
+}
+
+func NewControllerJobs(g *gin.RouterGroup, serviceScheduler *service.ServiceScheduler) *ControllerJobs {
+	ctl := &ControllerJobs{serviceScheduler: serviceScheduler}
+	g.GET("", security.GuardMiddleware(security.PermAuditRead), gggin.ToGinHandler(ctl.HandleList))
+	g.GET("/metrics", security.GuardMiddleware(security.PermAuditRead), ctl.HandleMetrics)
+	g.POST("/:name/run", security.GuardMiddleware(security.PermSystemManage), gggin.ToGinHandler(ctl.HandleRun))
+	return ctl
+}
+
+// @Summary      获取后台任务列表
+// @Description  获取本实例的调度状态和全部后台任务，包括 cron 表达式、上次运行结果和下次运行时间。只有主实例运行任务，其他实例的记录可能不是最新的。需要 audit.read 权限。
+// @Tags         jobs
+// @Accept       json
+// @Produce      json
+// @Success      200  {object} object{data=service.SchedulerStatus} "成功返回调度状态"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Router       /jobs [get]
+// @Security     BearerAuth
+func (ctl *ControllerJobs) HandleList(c *gin.Context) (*gggin.Response[*service.SchedulerStatus], *gggin.HttpError) {
+	return gggin.NewResponse(ctl.serviceScheduler.Status()), nil
+}
+
+// @Summary      立即运行后台任务
+// @Description  在后台立即运行一次任务，不影响下次计划运行的时间，运行结果通过任务列表查看。只能在主实例上触发。需要 system.manage 权限。
+// @Tags         jobs
+// @Accept       json
+// @Produce      json
+// @Param        name  path      string  true  "任务名"
+// @Success      200  {object} object{data=string} "成功触发，返回 'ok'"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "任务不存在"
+// @Failure      409  {object} object{data=string} "本实例不是主实例或任务正在运行"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /jobs/{name}/run [post]
+// @Security     BearerAuth
+func (ctl *ControllerJobs) HandleRun(c *gin.Context) (*gggin.Response[string], *gggin.HttpError) {
+	if err := ctl.serviceScheduler.Trigger(c.Param("name")); err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.Ok, nil
+}
+
+// @Summary      获取后台任务指标
+// @Description  以 Prometheus 文本格式输出任务的运行次数、失败次数、耗时、上次成功和下次运行时间，以及本实例是否为主实例。可以使用带 jobs:read 权限范围的访问令牌抓取。需要 audit.read 权限。
+// @Tags         jobs
+// @Produce      plain
+// @Success      200  {string} string "Prometheus 指标"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Router       /jobs/metrics [get]
+// @Security     BearerAuth
+func (ctl *ControllerJobs) HandleMetrics(c *gin.Context) {
+	var buf bytes.Buffer
+	if err := ctl.serviceScheduler.WriteMetrics(&buf); err != nil {
+		httpErr := service.MapErrorToHttp(err)
+		c.JSON(httpErr.StatusCode, gggin.NewResponse(httpErr.Message))
+		return
+	}
+	c.Data(200, "text/plain; version=0.0.4; charset=utf-8", buf.Bytes())
+}
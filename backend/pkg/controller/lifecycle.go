@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/security"
+	"asynclab.club/asynx/backend/pkg/service"
+	"github.com/dsx137/gg-gin/pkg/gggin"
+	"github.com/gin-gonic/gin"
+)
+
+type ControllerLifecycle struct {
+	serviceLifecycle *service.ServiceLifecycle
+}
+
+func NewControllerLifecycle(g *gin.RouterGroup, serviceLifecycle *service.ServiceLifecycle) *ControllerLifecycle {
+	ctl := &ControllerLifecycle{serviceLifecycle: serviceLifecycle}
+	g.GET("/preview", security.GuardMiddleware(security.PermUsersRead), gggin.ToGinHandler(ctl.HandlePreview))
+	g.GET("/exemptions", security.GuardMiddleware(security.PermUsersRead), gggin.ToGinHandler(ctl.HandleListExemptions))
+	g.PUT("/exemptions/:uid", security.GuardMiddleware(security.PermUsersWrite), gggin.ToGinHandler(ctl.HandleExempt))
+	g.DELETE("/exemptions/:uid", security.GuardMiddleware(security.PermUsersWrite), gggin.ToGinHandler(ctl.HandleRemoveExemption))
+	return ctl
+}
+
+// @Summary      预览转为 alumni 的成员
+// @Description  按入学年份列出在指定时间之前会被转为 alumni 的 member 账号，包括已被豁免的成员，按转换时间排序。
+// @Description  默认列出提醒期内会被转换的成员。生命周期策略未启用时返回 400。需要 users.read 权限。
+// @Tags         lifecycle
+// @Accept       json
+// @Produce      json
+// @Param        before  query     string  false  "截止时间，RFC 3339 格式"
+// @Success      200  {object} object{data=[]service.LifecycleCandidate} "成功返回成员列表"
+// @Failure      400  {object} object{data=string} "请求参数错误或策略未启用"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /lifecycle/preview [get]
+// @Security     BearerAuth
+func (ctl *ControllerLifecycle) HandlePreview(c *gin.Context) (*gggin.Response[[]service.LifecycleCandidate], *gggin.HttpError) {
+	before := ctl.serviceLifecycle.DefaultPreviewUntil(time.Now())
+	if value := c.Query("before"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, gggin.NewHttpError(http.StatusBadRequest, "invalid before: "+err.Error())
+		}
+		before = parsed
+	}
+
+	candidates, err := ctl.serviceLifecycle.Preview(before)
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(candidates), nil
+}
+
+// @Summary      获取生命周期豁免列表
+// @Description  获取不会被转为 alumni 的成员。需要 users.read 权限。
+// @Tags         lifecycle
+// @Accept       json
+// @Produce      json
+// @Success      200  {object} object{data=[]service.LifecycleExemption} "成功返回豁免列表"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /lifecycle/exemptions [get]
+// @Security     BearerAuth
+func (ctl *ControllerLifecycle) HandleListExemptions(c *gin.Context) (*gggin.Response[[]service.LifecycleExemption], *gggin.HttpError) {
+	exemptions, err := ctl.serviceLifecycle.ListExemptions()
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(exemptions), nil
+}
+
+type RequestLifecycleExempt struct {
+	Reason string `json:"reason"`
+}
+
+// @Summary      豁免成员
+// @Description  使 member 账号不会被生命周期策略转为 alumni，已存在的豁免会被覆盖。需要 users.write 权限。
+// @Tags         lifecycle
+// @Accept       json
+// @Produce      json
+// @Param        uid   path      string                  true  "用户ID"
+// @Param        body  body      RequestLifecycleExempt  true  "豁免请求"
+// @Success      200  {object} object{data=service.LifecycleExemption} "成功返回豁免"
+// @Failure      400  {object} object{data=string} "请求参数错误或用户不是 member"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "用户不存在"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /lifecycle/exemptions/{uid} [put]
+// @Security     BearerAuth
+func (ctl *ControllerLifecycle) HandleExempt(c *gin.Context) (*gggin.Response[*service.LifecycleExemption], *gggin.HttpError) {
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
+	if !ok {
+		return nil, ErrHttpGuardFail
+	}
+
+	req, err := gggin.ShouldBindJSON[RequestLifecycleExempt](c)
+	if err != nil {
+		return nil, gggin.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	exemption, err := ctl.serviceLifecycle.Exempt(guard.Uid, c.Param("uid"), req.Reason)
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(exemption), nil
+}
+
+// @Summary      取消豁免
+// @Description  取消成员的豁免，之后的生命周期任务会按入学年份处理该成员。需要 users.write 权限。
+// @Tags         lifecycle
+// @Accept       json
+// @Produce      json
+// @Param        uid  path      string  true  "用户ID"
+// @Success      200  {object} object{data=string} "成功取消，返回 'ok'"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "豁免不存在"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /lifecycle/exemptions/{uid} [delete]
+// @Security     BearerAuth
+func (ctl *ControllerLifecycle) HandleRemoveExemption(c *gin.Context) (*gggin.Response[string], *gggin.HttpError) {
+	if err := ctl.serviceLifecycle.RemoveExemption(c.Param("uid")); err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.Ok, nil
+}
@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"asynclab.club/asynx/backend/pkg/mail"
+	"asynclab.club/asynx/backend/pkg/security"
+	"asynclab.club/asynx/backend/pkg/service"
+	"github.com/dsx137/gg-gin/pkg/gggin"
+	"github.com/gin-gonic/gin"
+)
+
+type ControllerMail struct {
+	serviceMail *service.ServiceMail
+}
+
+func NewControllerMail(g *gin.RouterGroup, serviceMail *service.ServiceMail) *ControllerMail {
+	ctl := &ControllerMail{serviceMail: serviceMail}
+	g.GET("/templates", security.GuardMiddleware(security.PermMailManage), gggin.ToGinHandler(ctl.HandleListTemplates))
+	g.GET("/templates/:name/preview", security.GuardMiddleware(security.PermMailManage), gggin.ToGinHandler(ctl.HandlePreview))
+	return ctl
+}
+
+// @Summary      获取邮件模板列表
+// @Description  获取所有可用的邮件模板名称。需要 mail.manage 权限。
+// @Tags         mail
+// @Accept       json
+// @Produce      json
+// @Success      200  {object} object{data=[]string} "成功返回模板列表"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Router       /mail/templates [get]
+// @Security     BearerAuth
+func (ctl *ControllerMail) HandleListTemplates(c *gin.Context) (*gggin.Response[[]mail.Template], *gggin.HttpError) {
+	return gggin.NewResponse(ctl.serviceMail.ListTemplates()), nil
+}
+
+// @Summary      预览邮件模板
+// @Description  使用示例数据渲染邮件模板，返回标题、HTML 正文和纯文本正文。覆盖目录中的修改会立即生效。需要 mail.manage 权限。
+// @Tags         mail
+// @Accept       json
+// @Produce      json
+// @Param        name  path      string  true   "模板名称\nwelcome|password-reset|role-changed|role-expiring|category-changed|account-disabled|account-deleted|broadcast"
+// @Param        lang  query     string  false  "语言，留空使用默认语言\nzh|en"
+// @Success      200  {object} object{data=mail.Rendered} "成功返回渲染结果"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "模板不存在"
+// @Failure      500  {object} object{data=string} "模板渲染失败"
+// @Router       /mail/templates/{name}/preview [get]
+// @Security     BearerAuth
+func (ctl *ControllerMail) HandlePreview(c *gin.Context) (*gggin.Response[*mail.Rendered], *gggin.HttpError) {
+	rendered, err := ctl.serviceMail.Preview(c.Param("name"), c.Query("lang"))
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(rendered), nil
+}
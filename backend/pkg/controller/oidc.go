@@ -0,0 +1,402 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/oidc"
+	"asynclab.club/asynx/backend/pkg/security"
+	"asynclab.club/asynx/backend/pkg/service"
+	"github.com/dsx137/gg-gin/pkg/gggin"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// 前端保存登录令牌的 Cookie，与 frontend/src/utils/auth.ts 一致
+const sessionCookie = "asynx_token"
+
+// ControllerOidcProtocol 实现 OpenID Connect 协议端点。这些端点由其他应用和浏览器直接访问，
+// 使用 OAuth 2.0 的错误格式，不在 /api 之下，也不出现在 swagger 文档中
+type ControllerOidcProtocol struct {
+	forwardAuthCfg *config.ConfigForwardAuth
+	serviceOidc    *service.ServiceOidc
+}
+
+func NewControllerOidcProtocol(g *gin.RouterGroup, forwardAuthCfg *config.ConfigForwardAuth, serviceOidc *service.ServiceOidc) *ControllerOidcProtocol {
+	ctl := &ControllerOidcProtocol{forwardAuthCfg: forwardAuthCfg, serviceOidc: serviceOidc}
+	g.GET(oidc.PathDiscovery, oidcCors, ctl.HandleDiscovery)
+	g.GET(oidc.PathJwks, oidcCors, ctl.HandleJwks)
+	g.GET(oidc.PathAuthorize, ctl.HandleAuthorize)
+	g.POST(oidc.PathAuthorize, ctl.HandleAuthorize)
+	g.POST(oidc.PathToken, oidcCors, ctl.HandleToken)
+	g.GET(oidc.PathUserInfo, oidcCors, ctl.HandleUserInfo)
+	g.POST(oidc.PathUserInfo, oidcCors, ctl.HandleUserInfo)
+	g.GET(oidc.PathLogout, ctl.HandleLogout)
+	g.POST(oidc.PathLogout, ctl.HandleLogout)
+	for _, path := range []string{oidc.PathDiscovery, oidc.PathJwks, oidc.PathToken, oidc.PathUserInfo} {
+		g.OPTIONS(path, oidcCors)
+	}
+	return ctl
+}
+
+// oidcCors 允许浏览器中的公共客户端跨域获取配置、换取令牌和用户信息
+func oidcCors(c *gin.Context) {
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.Header("Access-Control-Allow-Methods", "GET, POST")
+	c.Header("Access-Control-Allow-Headers", "Authorization, Content-Type")
+	if c.Request.Method == http.MethodOptions {
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+// renderOidcError 以 OAuth 2.0 格式返回错误，非协议错误视为服务器错误
+func renderOidcError(c *gin.Context, status int, err error) {
+	var oidcErr *oidc.Error
+	if !errors.As(err, &oidcErr) {
+		logrus.Errorf("OIDC request %s %s failed: %v", c.Request.Method, c.Request.URL.Path, err)
+		oidcErr = &oidc.Error{Code: "server_error"}
+		status = http.StatusInternalServerError
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, oidcErr)
+}
+
+func (ctl *ControllerOidcProtocol) HandleDiscovery(c *gin.Context) {
+	c.JSON(http.StatusOK, ctl.serviceOidc.Discovery())
+}
+
+func (ctl *ControllerOidcProtocol) HandleJwks(c *gin.Context) {
+	c.JSON(http.StatusOK, ctl.serviceOidc.Jwks())
+}
+
+func (ctl *ControllerOidcProtocol) HandleAuthorize(c *gin.Context) {
+	if err := c.Request.ParseForm(); err != nil {
+		renderOidcError(c, http.StatusBadRequest, oidc.NewError(oidc.ErrInvalidRequest, "%v", err))
+		return
+	}
+	session, _ := c.Cookie(sessionCookie)
+
+	location, err := ctl.serviceOidc.Authorize(c.Request.Form, session)
+	if err != nil {
+		renderOidcError(c, http.StatusBadRequest, err)
+		return
+	}
+	c.Redirect(http.StatusFound, location)
+}
+
+// clientCredentials 按 client_secret_basic、client_secret_post 的顺序读取客户端凭据
+func clientCredentials(c *gin.Context) (string, string, bool) {
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		// RFC 6749 2.3.1 要求先对凭据进行表单编码
+		if unescaped, err := url.QueryUnescape(id); err == nil {
+			id = unescaped
+		}
+		if unescaped, err := url.QueryUnescape(secret); err == nil {
+			secret = unescaped
+		}
+		return id, secret, true
+	}
+	return c.Request.PostForm.Get("client_id"), c.Request.PostForm.Get("client_secret"), false
+}
+
+func (ctl *ControllerOidcProtocol) HandleToken(c *gin.Context) {
+	if err := c.Request.ParseForm(); err != nil {
+		renderOidcError(c, http.StatusBadRequest, oidc.NewError(oidc.ErrInvalidRequest, "%v", err))
+		return
+	}
+	clientId, clientSecret, basic := clientCredentials(c)
+
+	tokens, err := ctl.serviceOidc.Token(c.Request.PostForm, clientId, clientSecret)
+	if err != nil {
+		status := http.StatusBadRequest
+		var oidcErr *oidc.Error
+		if errors.As(err, &oidcErr) && oidcErr.Code == oidc.ErrInvalidClient {
+			status = http.StatusUnauthorized
+			if basic {
+				c.Header("WWW-Authenticate", `Basic realm="asynx"`)
+			}
+		}
+		renderOidcError(c, status, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (ctl *ControllerOidcProtocol) HandleUserInfo(c *gin.Context) {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		token = c.PostForm("access_token")
+	}
+
+	info, err := ctl.serviceOidc.UserInfo(token)
+	if err != nil {
+		var oidcErr *oidc.Error
+		if errors.As(err, &oidcErr) {
+			c.Header("WWW-Authenticate", `Bearer error="`+oidcErr.Code+`"`)
+		}
+		renderOidcError(c, http.StatusUnauthorized, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, info)
+}
+
+// HandleLogout 清除前端的登录 Cookie 和转发认证 Cookie 后跳转到客户端登记的地址，没有指定时跳转到登录页
+func (ctl *ControllerOidcProtocol) HandleLogout(c *gin.Context) {
+	if err := c.Request.ParseForm(); err != nil {
+		renderOidcError(c, http.StatusBadRequest, oidc.NewError(oidc.ErrInvalidRequest, "%v", err))
+		return
+	}
+
+	location, err := ctl.serviceOidc.EndSession(c.Request.Form)
+	if err != nil {
+		renderOidcError(c, http.StatusBadRequest, err)
+		return
+	}
+	c.SetCookie(sessionCookie, "", -1, "/", "", false, false)
+	setForwardAuthCookie(c, ctl.forwardAuthCfg, "", -1)
+	c.Redirect(http.StatusFound, location)
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+type ControllerOidc struct {
+	serviceOidc *service.ServiceOidc
+}
+
+func NewControllerOidc(g *gin.RouterGroup, serviceOidc *service.ServiceOidc) *ControllerOidc {
+	ctl := &ControllerOidc{serviceOidc: serviceOidc}
+	g.GET("/clients", security.GuardMiddleware(security.PermOidcManage), gggin.ToGinHandler(ctl.HandleListClients))
+	g.POST("/clients", security.GuardMiddleware(security.PermOidcManage), gggin.ToGinHandler(ctl.HandleCreateClient))
+	g.GET("/clients/:id", security.GuardMiddleware(security.PermOidcManage), gggin.ToGinHandler(ctl.HandleGetClient))
+	g.PUT("/clients/:id", security.GuardMiddleware(security.PermOidcManage), gggin.ToGinHandler(ctl.HandleUpdateClient))
+	g.DELETE("/clients/:id", security.GuardMiddleware(security.PermOidcManage), gggin.ToGinHandler(ctl.HandleDeleteClient))
+	g.GET("/authorizations/:id", security.GuardMiddleware(security.PermUsersSelf), gggin.ToGinHandler(ctl.HandleGetAuthorization))
+	g.POST("/authorizations/:id", security.GuardMiddleware(security.PermUsersSelf), gggin.ToGinHandler(ctl.HandleDecideAuthorization))
+	g.GET("/consents", security.GuardMiddleware(security.PermUsersSelf), gggin.ToGinHandler(ctl.HandleListConsents))
+	g.DELETE("/consents/:clientId", security.GuardMiddleware(security.PermUsersSelf), gggin.ToGinHandler(ctl.HandleRevokeConsent))
+	return ctl
+}
+
+// @Summary      获取 OIDC 客户端列表
+// @Description  获取所有已注册的 OIDC 客户端，不包含密钥。需要 oidc.manage 权限。
+// @Tags         oidc
+// @Accept       json
+// @Produce      json
+// @Success      200  {object} object{data=[]oidc.Client} "成功返回客户端列表"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /oidc/clients [get]
+// @Security     BearerAuth
+func (ctl *ControllerOidc) HandleListClients(c *gin.Context) (*gggin.Response[[]*oidc.Client], *gggin.HttpError) {
+	clients, err := ctl.serviceOidc.ListClients()
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(clients), nil
+}
+
+// @Summary      注册 OIDC 客户端
+// @Description  注册一个使用 asynx 登录的应用。机密客户端的密钥自动生成，只在本次返回；公共客户端没有密钥，必须使用 PKCE。roles 为空时所有有角色的用户都可以登录。需要 oidc.manage 权限。
+// @Tags         oidc
+// @Accept       json
+// @Produce      json
+// @Param        body  body      service.OidcClientSpec  true  "注册请求\nroles: admin|default|restricted"
+// @Success      200  {object} object{data=oidc.Client} "成功返回客户端（机密客户端包含密钥）"
+// @Failure      400  {object} object{data=string} "请求参数错误"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /oidc/clients [post]
+// @Security     BearerAuth
+func (ctl *ControllerOidc) HandleCreateClient(c *gin.Context) (*gggin.Response[*oidc.Client], *gggin.HttpError) {
+	req, err := gggin.ShouldBindJSON[service.OidcClientSpec](c)
+	if err != nil {
+		return nil, gggin.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	client, err := ctl.serviceOidc.CreateClient(req)
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(client), nil
+}
+
+// @Summary      获取 OIDC 客户端
+// @Description  根据ID获取 OIDC 客户端，不包含密钥。需要 oidc.manage 权限。
+// @Tags         oidc
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "客户端ID"
+// @Success      200  {object} object{data=oidc.Client} "成功返回客户端"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "客户端不存在"
+// @Router       /oidc/clients/{id} [get]
+// @Security     BearerAuth
+func (ctl *ControllerOidc) HandleGetClient(c *gin.Context) (*gggin.Response[*oidc.Client], *gggin.HttpError) {
+	client, err := ctl.serviceOidc.GetClient(c.Param("id"))
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(client), nil
+}
+
+type RequestUpdateOidcClient struct {
+	service.OidcClientSpec
+	RotateSecret bool `json:"rotateSecret"`
+}
+
+// @Summary      修改 OIDC 客户端
+// @Description  修改客户端的名称、回跳地址、允许的角色和是否跳过授权确认。rotateSecret 为 true 或由公共客户端改为机密客户端时生成新密钥并只在本次返回。需要 oidc.manage 权限。
+// @Tags         oidc
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string  true  "客户端ID"
+// @Param        body  body      RequestUpdateOidcClient  true  "修改请求"
+// @Success      200  {object} object{data=oidc.Client} "成功返回客户端"
+// @Failure      400  {object} object{data=string} "请求参数错误"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "客户端不存在"
+// @Router       /oidc/clients/{id} [put]
+// @Security     BearerAuth
+func (ctl *ControllerOidc) HandleUpdateClient(c *gin.Context) (*gggin.Response[*oidc.Client], *gggin.HttpError) {
+	req, err := gggin.ShouldBindJSON[RequestUpdateOidcClient](c)
+	if err != nil {
+		return nil, gggin.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	client, err := ctl.serviceOidc.UpdateClient(c.Param("id"), &req.OidcClientSpec, req.RotateSecret)
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(client), nil
+}
+
+// @Summary      删除 OIDC 客户端
+// @Description  删除客户端以及所有用户对它的授权，已签发的令牌在过期前仍然有效。需要 oidc.manage 权限。
+// @Tags         oidc
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "客户端ID"
+// @Success      200  {object} object{data=string} "成功删除，返回 'ok'"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "客户端不存在"
+// @Router       /oidc/clients/{id} [delete]
+// @Security     BearerAuth
+func (ctl *ControllerOidc) HandleDeleteClient(c *gin.Context) (*gggin.Response[string], *gggin.HttpError) {
+	if err := ctl.serviceOidc.DeleteClient(c.Param("id")); err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.Ok, nil
+}
+
+// @Summary      获取授权请求
+// @Description  获取等待当前用户确认的 OIDC 授权请求，供授权确认页面展示。consentRequired 为 false 时用户已经同意过，前端可以直接提交。
+// @Tags         oidc
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "授权请求ID"
+// @Success      200  {object} object{data=service.OidcAuthorizationPrompt} "成功返回授权请求"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      404  {object} object{data=string} "授权请求不存在或已过期"
+// @Router       /oidc/authorizations/{id} [get]
+// @Security     BearerAuth
+func (ctl *ControllerOidc) HandleGetAuthorization(c *gin.Context) (*gggin.Response[*service.OidcAuthorizationPrompt], *gggin.HttpError) {
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
+	if !ok {
+		return nil, ErrHttpGuardFail
+	}
+
+	prompt, err := ctl.serviceOidc.GetAuthorization(guard.Uid, c.Param("id"))
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(prompt), nil
+}
+
+type RequestDecideAuthorization struct {
+	Approve bool `json:"approve"`
+}
+
+// @Summary      确认授权请求
+// @Description  同意或拒绝 OIDC 授权请求，返回应用的回跳地址，前端应直接跳转。同意后会记住授权的范围，之后同一应用不再询问。
+// @Tags         oidc
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string  true  "授权请求ID"
+// @Param        body  body      RequestDecideAuthorization  true  "确认请求"
+// @Success      200  {object} object{data=string} "成功返回回跳地址"
+// @Failure      400  {object} object{data=string} "请求参数错误"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      404  {object} object{data=string} "授权请求不存在或已过期"
+// @Router       /oidc/authorizations/{id} [post]
+// @Security     BearerAuth
+func (ctl *ControllerOidc) HandleDecideAuthorization(c *gin.Context) (*gggin.Response[string], *gggin.HttpError) {
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
+	if !ok {
+		return nil, ErrHttpGuardFail
+	}
+	req, err := gggin.ShouldBindJSON[RequestDecideAuthorization](c)
+	if err != nil {
+		return nil, gggin.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	location, err := ctl.serviceOidc.DecideAuthorization(guard.Uid, c.Param("id"), req.Approve)
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(location), nil
+}
+
+// @Summary      获取已授权的应用
+// @Description  获取当前用户授权过的 OIDC 应用及授权范围。
+// @Tags         oidc
+// @Accept       json
+// @Produce      json
+// @Success      200  {object} object{data=[]service.OidcConsent} "成功返回授权列表"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /oidc/consents [get]
+// @Security     BearerAuth
+func (ctl *ControllerOidc) HandleListConsents(c *gin.Context) (*gggin.Response[[]*service.OidcConsent], *gggin.HttpError) {
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
+	if !ok {
+		return nil, ErrHttpGuardFail
+	}
+	consents, err := ctl.serviceOidc.ListConsents(guard.Uid)
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(consents), nil
+}
+
+// @Summary      撤销应用授权
+// @Description  撤销当前用户对某个 OIDC 应用的授权，下次登录该应用时需要重新确认。已签发的令牌在过期前仍然有效。
+// @Tags         oidc
+// @Accept       json
+// @Produce      json
+// @Param        clientId  path      string  true  "客户端ID"
+// @Success      200  {object} object{data=string} "成功撤销，返回 'ok'"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      404  {object} object{data=string} "授权不存在"
+// @Router       /oidc/consents/{clientId} [delete]
+// @Security     BearerAuth
+func (ctl *ControllerOidc) HandleRevokeConsent(c *gin.Context) (*gggin.Response[string], *gggin.HttpError) {
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
+	if !ok {
+		return nil, ErrHttpGuardFail
+	}
+	if err := ctl.serviceOidc.RevokeConsent(guard.Uid, c.Param("clientId")); err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.Ok, nil
+}
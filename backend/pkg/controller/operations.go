@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"asynclab.club/asynx/backend/pkg/saga"
+	"asynclab.club/asynx/backend/pkg/security"
+	"asynclab.club/asynx/backend/pkg/service"
+	"github.com/dsx137/gg-gin/pkg/gggin"
+	"github.com/gin-gonic/gin"
+)
+
+type ControllerOperation struct {
+	serviceOperation *service.ServiceOperation
+}
+
+func NewControllerOperations(g *gin.RouterGroup, serviceOperation *service.ServiceOperation) *ControllerOperation {
+	ctl := &ControllerOperation{serviceOperation: serviceOperation}
+	g.GET("", security.GuardMiddleware(security.PermAuditRead), gggin.ToGinHandler(ctl.HandleList))
+	g.GET("/:id", security.GuardMiddleware(security.PermAuditRead), gggin.ToGinHandler(ctl.HandleGet))
+	g.POST("/:id/retry", security.GuardMiddleware(security.PermSystemManage), gggin.ToGinHandler(ctl.HandleRetry))
+	return ctl
+}
+
+// @Summary      获取操作日志
+// @Description  获取所有多步骤操作（注册、角色切换等）及其每一步的状态。需要 audit.read 权限。
+// @Tags         operations
+// @Accept       json
+// @Produce      json
+// @Success      200  {object} object{data=[]saga.Operation} "成功返回操作列表"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Router       /operations [get]
+// @Security     BearerAuth
+func (ctl *ControllerOperation) HandleList(c *gin.Context) (*gggin.Response[[]saga.Operation], *gggin.HttpError) {
+	return gggin.NewResponse(ctl.serviceOperation.List()), nil
+}
+
+// @Summary      获取操作详情
+// @Description  根据ID获取一个多步骤操作的状态。需要 audit.read 权限。
+// @Tags         operations
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "操作ID"
+// @Success      200  {object} object{data=saga.Operation} "成功返回操作"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "操作不存在"
+// @Router       /operations/{id} [get]
+// @Security     BearerAuth
+func (ctl *ControllerOperation) HandleGet(c *gin.Context) (*gggin.Response[*saga.Operation], *gggin.HttpError) {
+	op, err := ctl.serviceOperation.Get(c.Param("id"))
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(op), nil
+}
+
+// @Summary      重试补偿
+// @Description  对补偿失败（failed）的操作重新执行补偿。需要 system.manage 权限。
+// @Tags         operations
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "操作ID"
+// @Success      200  {object} object{data=string} "补偿完成，返回 'ok'"
+// @Failure      400  {object} object{data=string} "操作不处于 failed 状态"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "操作不存在"
+// @Failure      500  {object} object{data=string} "补偿再次失败"
+// @Router       /operations/{id}/retry [post]
+// @Security     BearerAuth
+func (ctl *ControllerOperation) HandleRetry(c *gin.Context) (*gggin.Response[string], *gggin.HttpError) {
+	if err := ctl.serviceOperation.Retry(c.Param("id")); err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.Ok, nil
+}
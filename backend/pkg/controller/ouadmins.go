@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"net/http"
+
+	"asynclab.club/asynx/backend/pkg/security"
+	"asynclab.club/asynx/backend/pkg/service"
+	"github.com/dsx137/gg-gin/pkg/gggin"
+	"github.com/gin-gonic/gin"
+)
+
+type ControllerOuAdmins struct {
+	serviceManager *service.ServiceManager
+}
+
+func NewControllerOuAdmins(g *gin.RouterGroup, serviceManager *service.ServiceManager) *ControllerOuAdmins {
+	ctl := &ControllerOuAdmins{serviceManager: serviceManager}
+	g.GET("", security.GuardMiddleware(security.PermUsersRoleGrant), gggin.ToGinHandler(ctl.HandleList))
+	g.PUT("/:uid", security.GuardMiddleware(security.PermUsersRoleGrant), gggin.ToGinHandler(ctl.HandleSet))
+	g.DELETE("/:uid", security.GuardMiddleware(security.PermUsersRoleGrant), gggin.ToGinHandler(ctl.HandleRemove))
+	return ctl
+}
+
+// @Summary      获取委派管理员列表
+// @Description  获取所有被委派管理部分账号类型的用户。需要 users.role.grant 权限。
+// @Tags         ou-admins
+// @Accept       json
+// @Produce      json
+// @Success      200  {object} object{data=[]service.OuAdmin} "成功返回委派管理员列表"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /ou-admins [get]
+// @Security     BearerAuth
+func (ctl *ControllerOuAdmins) HandleList(c *gin.Context) (*gggin.Response[[]service.OuAdmin], *gggin.HttpError) {
+	admins, err := ctl.serviceManager.ListOuAdmins()
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(admins), nil
+}
+
+type RequestSetOuAdmin struct {
+	Categories []string `json:"categories" binding:"required"`
+}
+
+// @Summary      委派账号类型管理员
+// @Description  委派用户管理指定账号类型（system|member|external|alumni）的用户，整体替换原有的委派。
+// @Description  委派管理员可以在这些账号类型中注册、删除用户，重置密码和修改角色，但不能管理角色高于自己的用户，也不能授予高于自己的角色。需要 users.role.grant 权限。
+// @Tags         ou-admins
+// @Accept       json
+// @Produce      json
+// @Param        uid   path      string             true  "用户ID"
+// @Param        body  body      RequestSetOuAdmin  true  "委派请求"
+// @Success      200  {object} object{data=service.OuAdmin} "成功返回委派"
+// @Failure      400  {object} object{data=string} "请求参数错误"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "用户不存在"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /ou-admins/{uid} [put]
+// @Security     BearerAuth
+func (ctl *ControllerOuAdmins) HandleSet(c *gin.Context) (*gggin.Response[*service.OuAdmin], *gggin.HttpError) {
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
+	if !ok {
+		return nil, ErrHttpGuardFail
+	}
+
+	req, err := gggin.ShouldBindJSON[RequestSetOuAdmin](c)
+	if err != nil {
+		return nil, gggin.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	admin, err := ctl.serviceManager.SetOuAdmin(guard.Uid, c.Param("uid"), req.Categories)
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(admin), nil
+}
+
+// @Summary      撤销委派
+// @Description  撤销用户被委派的全部管理权限。需要 users.role.grant 权限。
+// @Tags         ou-admins
+// @Accept       json
+// @Produce      json
+// @Param        uid  path      string  true  "用户ID"
+// @Success      200  {object} object{data=string} "成功撤销，返回 'ok'"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "委派不存在"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /ou-admins/{uid} [delete]
+// @Security     BearerAuth
+func (ctl *ControllerOuAdmins) HandleRemove(c *gin.Context) (*gggin.Response[string], *gggin.HttpError) {
+	if err := ctl.serviceManager.RemoveOuAdmin(c.Param("uid")); err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.Ok, nil
+}
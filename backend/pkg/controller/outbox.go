@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"asynclab.club/asynx/backend/pkg/outbox"
+	"asynclab.club/asynx/backend/pkg/security"
+	"asynclab.club/asynx/backend/pkg/service"
+	"github.com/dsx137/gg-gin/pkg/gggin"
+	"github.com/gin-gonic/gin"
+)
+
+type ControllerOutbox struct {
+	serviceOutbox *service.ServiceOutbox
+}
+
+func NewControllerOutbox(g *gin.RouterGroup, serviceOutbox *service.ServiceOutbox) *ControllerOutbox {
+	ctl := &ControllerOutbox{serviceOutbox: serviceOutbox}
+	g.GET("", security.GuardMiddleware(security.PermAuditRead), gggin.ToGinHandler(ctl.HandleList))
+	g.GET("/:id", security.GuardMiddleware(security.PermAuditRead), gggin.ToGinHandler(ctl.HandleGet))
+	g.POST("/:id/resend", security.GuardMiddleware(security.PermMailManage), gggin.ToGinHandler(ctl.HandleResend))
+	g.DELETE("/:id", security.GuardMiddleware(security.PermMailManage), gggin.ToGinHandler(ctl.HandleDelete))
+	return ctl
+}
+
+// @Summary      获取发件箱
+// @Description  获取邮件队列中的消息，可按状态过滤。已发送的消息不保留正文。需要 audit.read 权限。
+// @Tags         outbox
+// @Accept       json
+// @Produce      json
+// @Param        state  query     string  false  "消息状态\npending|sent|dead"
+// @Success      200  {object} object{data=[]outbox.Message} "成功返回消息列表"
+// @Failure      400  {object} object{data=string} "请求参数错误"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Router       /outbox [get]
+// @Security     BearerAuth
+func (ctl *ControllerOutbox) HandleList(c *gin.Context) (*gggin.Response[[]outbox.Message], *gggin.HttpError) {
+	messages, err := ctl.serviceOutbox.List(c.Query("state"))
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(messages), nil
+}
+
+// @Summary      获取邮件详情
+// @Description  根据ID获取邮件队列中的一条消息。需要 audit.read 权限。
+// @Tags         outbox
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "消息ID"
+// @Success      200  {object} object{data=outbox.Message} "成功返回消息"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "消息不存在"
+// @Router       /outbox/{id} [get]
+// @Security     BearerAuth
+func (ctl *ControllerOutbox) HandleGet(c *gin.Context) (*gggin.Response[*outbox.Message], *gggin.HttpError) {
+	msg, err := ctl.serviceOutbox.Get(c.Param("id"))
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(msg), nil
+}
+
+// @Summary      重新发送邮件
+// @Description  将死信或待发送的消息重置重试次数并立即重新投递。需要 mail.manage 权限。
+// @Tags         outbox
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "消息ID"
+// @Success      200  {object} object{data=string} "已重新入队，返回 'ok'"
+// @Failure      400  {object} object{data=string} "消息已发送"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "消息不存在"
+// @Router       /outbox/{id}/resend [post]
+// @Security     BearerAuth
+func (ctl *ControllerOutbox) HandleResend(c *gin.Context) (*gggin.Response[string], *gggin.HttpError) {
+	if err := ctl.serviceOutbox.Resend(c.Param("id")); err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.Ok, nil
+}
+
+// @Summary      删除邮件
+// @Description  从邮件队列中删除一条消息，通常用于丢弃无法投递的死信。需要 mail.manage 权限。
+// @Tags         outbox
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "消息ID"
+// @Success      200  {object} object{data=string} "成功删除，返回 'ok'"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "消息不存在"
+// @Router       /outbox/{id} [delete]
+// @Security     BearerAuth
+func (ctl *ControllerOutbox) HandleDelete(c *gin.Context) (*gggin.Response[string], *gggin.HttpError) {
+	if err := ctl.serviceOutbox.Delete(c.Param("id")); err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.Ok, nil
+}
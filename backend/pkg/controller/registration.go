@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"net/http"
+
+	"asynclab.club/asynx/backend/pkg/security"
+	"asynclab.club/asynx/backend/pkg/service"
+	"github.com/dsx137/gg-gin/pkg/gggin"
+	"github.com/gin-gonic/gin"
+)
+
+type ControllerRegistration struct {
+	serviceRegistration *service.ServiceRegistration
+	serviceManager      *service.ServiceManager
+}
+
+func NewControllerRegistration(g *gin.RouterGroup, serviceRegistration *service.ServiceRegistration, serviceManager *service.ServiceManager) *ControllerRegistration {
+	ctl := &ControllerRegistration{serviceRegistration: serviceRegistration, serviceManager: serviceManager}
+	g.GET("/challenge", gggin.ToGinHandler(ctl.HandleChallenge))
+	g.POST("", gggin.ToGinHandler(ctl.HandleSubmit))
+	g.POST("/verify", gggin.ToGinHandler(ctl.HandleVerify))
+	g.GET("", security.GuardMiddleware(security.PermUsersWrite), gggin.ToGinHandler(ctl.HandleList))
+	g.POST("/:id/approve", security.GuardMiddleware(security.PermUsersWrite), gggin.ToGinHandler(ctl.HandleApprove))
+	g.POST("/:id/reject", security.GuardMiddleware(security.PermUsersWrite), gggin.ToGinHandler(ctl.HandleReject))
+	return ctl
+}
+
+// @Summary      获取工作量证明题目
+// @Description  提交注册申请前需要找到 nonce，使 SHA-256("<challenge>:<nonce>") 至少有 difficulty 个前导零比特。每个题目只能使用一次。无需登录。
+// @Tags         registration-requests
+// @Accept       json
+// @Produce      json
+// @Success      200  {object} object{data=service.RegistrationChallenge} "成功返回题目"
+// @Failure      403  {object} object{data=string} "未开放自助注册"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /registration-requests/challenge [get]
+func (ctl *ControllerRegistration) HandleChallenge(c *gin.Context) (*gggin.Response[*service.RegistrationChallenge], *gggin.HttpError) {
+	challenge, err := ctl.serviceRegistration.Challenge()
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(challenge), nil
+}
+
+// @Summary      提交注册申请
+// @Description  以学号申请 member 账号，需要附带工作量证明，并受每个 IP 的频率限制。提交后向邮箱发送验证链接，验证后申请交由管理员审核。无需登录。
+// @Tags         registration-requests
+// @Accept       json
+// @Produce      json
+// @Param        body  body      service.RegistrationSpec  true  "注册申请"
+// @Success      200  {object} object{data=service.RegistrationRequest} "成功返回申请"
+// @Failure      400  {object} object{data=string} "请求参数错误或工作量证明无效"
+// @Failure      403  {object} object{data=string} "未开放自助注册"
+// @Failure      409  {object} object{data=string} "用户已存在或已有进行中的申请"
+// @Failure      429  {object} object{data=string} "提交过于频繁"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /registration-requests [post]
+func (ctl *ControllerRegistration) HandleSubmit(c *gin.Context) (*gggin.Response[*service.RegistrationRequest], *gggin.HttpError) {
+	req, err := gggin.ShouldBindJSON[service.RegistrationSpec](c)
+	if err != nil {
+		return nil, gggin.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	request, err := ctl.serviceRegistration.Submit(req, c.ClientIP())
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(request), nil
+}
+
+type RequestVerifyRegistration struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// @Summary      验证注册邮箱
+// @Description  使用验证邮件中链接的 token 验证邮箱，验证后申请进入审核队列。重复验证不会报错。无需登录。
+// @Tags         registration-requests
+// @Accept       json
+// @Produce      json
+// @Param        body  body      RequestVerifyRegistration  true  "验证请求"
+// @Success      200  {object} object{data=service.RegistrationRequest} "成功返回申请"
+// @Failure      400  {object} object{data=string} "令牌无效或已过期"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /registration-requests/verify [post]
+func (ctl *ControllerRegistration) HandleVerify(c *gin.Context) (*gggin.Response[*service.RegistrationRequest], *gggin.HttpError) {
+	req, err := gggin.ShouldBindJSON[RequestVerifyRegistration](c)
+	if err != nil {
+		return nil, gggin.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	request, err := ctl.serviceRegistration.Verify(req.Token)
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(request), nil
+}
+
+// @Summary      获取注册申请列表
+// @Description  获取自助注册申请，按提交时间排序。需要 users.write 权限。
+// @Tags         registration-requests
+// @Accept       json
+// @Produce      json
+// @Param        status  query     string  false  "按状态过滤: unverified|pending|approved|rejected"
+// @Success      200  {object} object{data=[]service.RegistrationRequest} "成功返回申请列表"
+// @Failure      400  {object} object{data=string} "请求参数错误"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Router       /registration-requests [get]
+// @Security     BearerAuth
+func (ctl *ControllerRegistration) HandleList(c *gin.Context) (*gggin.Response[[]*service.RegistrationRequest], *gggin.HttpError) {
+	requests, err := ctl.serviceRegistration.List(c.Query("status"))
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(requests), nil
+}
+
+type RequestApproveRegistration struct {
+	Category string `json:"category" binding:"required"`
+	Role     string `json:"role" binding:"required"`
+}
+
+// @Summary      批准注册申请
+// @Description  按已验证邮箱的申请创建账号，初始密码通过欢迎邮件发送。需要 users.write 权限，委派管理员只能使用被委派的账号类型，没有 users.role.grant 权限时角色不能高于自己。
+// @Tags         registration-requests
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string                      true  "申请ID"
+// @Param        body  body      RequestApproveRegistration  true  "账号类型（system|member|external|alumni）和角色"
+// @Success      200  {object} object{data=service.RegistrationRequest} "成功返回申请"
+// @Failure      400  {object} object{data=string} "请求参数错误"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "申请不存在"
+// @Failure      409  {object} object{data=string} "申请不在待审核状态或用户已存在"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /registration-requests/{id}/approve [post]
+// @Security     BearerAuth
+func (ctl *ControllerRegistration) HandleApprove(c *gin.Context) (*gggin.Response[*service.RegistrationRequest], *gggin.HttpError) {
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
+	if !ok {
+		return nil, ErrHttpGuardFail
+	}
+	req, err := gggin.ShouldBindJSON[RequestApproveRegistration](c)
+	if err != nil {
+		return nil, gggin.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := ctl.serviceManager.AuthorizeRegister(guard, req.Category, req.Role); err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+
+	request, err := ctl.serviceRegistration.Approve(guard.Uid, c.Param("id"), req.Category, req.Role)
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(request), nil
+}
+
+type RequestRejectRegistration struct {
+	Reason string `json:"reason"`
+}
+
+// @Summary      拒绝注册申请
+// @Description  拒绝待审核或尚未验证邮箱的申请，不会通知申请人。需要 users.write 权限。
+// @Tags         registration-requests
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string                     true  "申请ID"
+// @Param        body  body      RequestRejectRegistration  true  "拒绝理由"
+// @Success      200  {object} object{data=service.RegistrationRequest} "成功返回申请"
+// @Failure      400  {object} object{data=string} "请求参数错误"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "申请不存在"
+// @Failure      409  {object} object{data=string} "申请已处理"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /registration-requests/{id}/reject [post]
+// @Security     BearerAuth
+func (ctl *ControllerRegistration) HandleReject(c *gin.Context) (*gggin.Response[*service.RegistrationRequest], *gggin.HttpError) {
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
+	if !ok {
+		return nil, ErrHttpGuardFail
+	}
+	req, err := gggin.ShouldBindJSON[RequestRejectRegistration](c)
+	if err != nil {
+		return nil, gggin.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	request, err := ctl.serviceRegistration.Reject(guard.Uid, c.Param("id"), req.Reason)
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(request), nil
+}
@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"asynclab.club/asynx/backend/pkg/security"
+	"asynclab.club/asynx/backend/pkg/service"
+	"github.com/dsx137/gg-gin/pkg/gggin"
+	"github.com/gin-gonic/gin"
+)
+
+type ControllerRoleGrants struct {
+	serviceRoleGrant *service.ServiceRoleGrant
+}
+
+func NewControllerRoleGrants(g *gin.RouterGroup, serviceRoleGrant *service.ServiceRoleGrant) *ControllerRoleGrants {
+	ctl := &ControllerRoleGrants{serviceRoleGrant: serviceRoleGrant}
+	g.GET("", security.GuardMiddleware(security.PermUsersRoleGrant), gggin.ToGinHandler(ctl.HandleList))
+	g.DELETE("/:uid", security.GuardMiddleware(security.PermUsersRoleGrant), gggin.ToGinHandler(ctl.HandleCancel))
+	return ctl
+}
+
+// @Summary      获取临时角色列表
+// @Description  获取尚未到期的临时角色，按到期时间排序，包括到期后恢复的角色和是否已发送提醒。需要 users.role.grant 权限。
+// @Tags         role-grants
+// @Accept       json
+// @Produce      json
+// @Success      200  {object} object{data=[]service.RoleGrant} "成功返回临时角色列表"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /role-grants [get]
+// @Security     BearerAuth
+func (ctl *ControllerRoleGrants) HandleList(c *gin.Context) (*gggin.Response[[]service.RoleGrant], *gggin.HttpError) {
+	grants, err := ctl.serviceRoleGrant.List()
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(grants), nil
+}
+
+// @Summary      取消角色期限
+// @Description  取消临时角色的期限，用户永久保留当前角色。需要 users.role.grant 权限。
+// @Tags         role-grants
+// @Accept       json
+// @Produce      json
+// @Param        uid  path      string  true  "用户ID"
+// @Success      200  {object} object{data=string} "成功取消，返回 'ok'"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "临时角色不存在"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /role-grants/{uid} [delete]
+// @Security     BearerAuth
+func (ctl *ControllerRoleGrants) HandleCancel(c *gin.Context) (*gggin.Response[string], *gggin.HttpError) {
+	if err := ctl.serviceRoleGrant.Cancel(c.Param("uid")); err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.Ok, nil
+}
@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"slices"
+
+	"asynclab.club/asynx/backend/pkg/security"
+	"github.com/dsx137/gg-gin/pkg/gggin"
+	"github.com/gin-gonic/gin"
+)
+
+// RoleInfo 是角色及其拥有的权限
+type RoleInfo struct {
+	Name        security.Role         `json:"name"`
+	Permissions []security.Permission `json:"permissions"`
+	Builtin     bool                  `json:"builtin"`
+}
+
+type ControllerRoles struct{}
+
+func NewControllerRoles(g *gin.RouterGroup) *ControllerRoles {
+	ctl := &ControllerRoles{}
+	g.GET("", security.GuardMiddleware(security.PermUsersSelf), gggin.ToGinHandler(ctl.HandleList))
+	return ctl
+}
+
+// @Summary      获取角色列表
+// @Description  获取可以授予用户的角色及其权限，包括内置的 admin、default、restricted 和通过 ROLE_PERMISSIONS 配置的自定义角色。需要 users.self 权限。
+// @Tags         roles
+// @Accept       json
+// @Produce      json
+// @Success      200  {object} object{data=[]RoleInfo} "成功返回角色列表"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Router       /roles [get]
+// @Security     BearerAuth
+func (ctl *ControllerRoles) HandleList(c *gin.Context) (*gggin.Response[[]RoleInfo], *gggin.HttpError) {
+	roles := append(slices.DeleteFunc(security.AllRoles(), func(r security.Role) bool { return r == security.RoleAnonymous }), security.CustomRoles()...)
+	infos := make([]RoleInfo, 0, len(roles))
+	for _, role := range roles {
+		infos = append(infos, RoleInfo{
+			Name:        role,
+			Permissions: role.Permissions(),
+			Builtin:     slices.Contains(security.AllRoles(), role),
+		})
+	}
+	return gggin.NewResponse(infos), nil
+}
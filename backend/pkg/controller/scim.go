@@ -0,0 +1,331 @@
+package controller
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"asynclab.club/asynx/backend/pkg/scim"
+	"asynclab.club/asynx/backend/pkg/service"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ControllerScim 实现 SCIM 2.0 协议端点（RFC 7644），供身份提供方预配用户和组。
+// 请求和响应都使用 SCIM 自己的格式，不经过 gggin 的响应包装，因此也不出现在 swagger 文档中
+type ControllerScim struct {
+	serviceScim *service.ServiceScim
+	basePath    string
+}
+
+func NewControllerScim(g *gin.RouterGroup, serviceScim *service.ServiceScim, tokens []string) *ControllerScim {
+	ctl := &ControllerScim{serviceScim: serviceScim, basePath: g.BasePath()}
+	g.Use(scimAuth(tokens))
+
+	g.GET("/ServiceProviderConfig", ctl.HandleServiceProviderConfig)
+	g.GET("/ResourceTypes", ctl.HandleListResourceTypes)
+	g.GET("/ResourceTypes/:id", ctl.HandleGetResourceType)
+	g.GET("/Schemas", ctl.HandleListSchemas)
+	g.GET("/Schemas/:id", ctl.HandleGetSchema)
+
+	g.GET("/Users", ctl.HandleListUsers)
+	g.POST("/Users", ctl.HandleCreateUser)
+	g.GET("/Users/:id", ctl.HandleGetUser)
+	g.PUT("/Users/:id", ctl.HandleReplaceUser)
+	g.PATCH("/Users/:id", ctl.HandlePatchUser)
+	g.DELETE("/Users/:id", ctl.HandleDeleteUser)
+
+	g.GET("/Groups", ctl.HandleListGroups)
+	g.POST("/Groups", ctl.HandleUnsupported)
+	g.GET("/Groups/:id", ctl.HandleGetGroup)
+	g.PUT("/Groups/:id", ctl.HandleReplaceGroup)
+	g.PATCH("/Groups/:id", ctl.HandlePatchGroup)
+	g.DELETE("/Groups/:id", ctl.HandleUnsupported)
+	return ctl
+}
+
+// scimAuth 校验预配令牌，任一令牌匹配即可
+func scimAuth(tokens []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if ok && strings.TrimSpace(token) != "" {
+			for _, expected := range tokens {
+				if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1 {
+					c.Next()
+					return
+				}
+			}
+		}
+		c.Header("WWW-Authenticate", `Bearer realm="scim"`)
+		renderScim(c, http.StatusUnauthorized, scim.NewError(http.StatusUnauthorized, "", "invalid provisioning token"))
+		c.Abort()
+	}
+}
+
+func renderScim(c *gin.Context, status int, body any) {
+	c.Header("Content-Type", scim.ContentType)
+	c.JSON(status, body)
+}
+
+func renderScimError(c *gin.Context, err error) {
+	var scimErr *scim.Error
+	switch {
+	case errors.As(err, &scimErr):
+	case errors.Is(err, service.ErrNotFound):
+		scimErr = scim.NewError(http.StatusNotFound, "", err.Error())
+	case errors.Is(err, service.ErrExists):
+		scimErr = scim.NewError(http.StatusConflict, scim.ErrUniqueness, err.Error())
+	case errors.Is(err, service.ErrInvalid):
+		scimErr = scim.NewError(http.StatusBadRequest, scim.ErrInvalidValue, err.Error())
+	default:
+		logrus.Errorf("SCIM request %s %s failed: %v", c.Request.Method, c.Request.URL.Path, err)
+		scimErr = scim.NewError(http.StatusInternalServerError, "", err.Error())
+	}
+	renderScim(c, scimErr.StatusCode(), scimErr)
+}
+
+// location 返回资源的绝对地址
+func (ctl *ControllerScim) location(c *gin.Context, path string) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + ctl.basePath + path
+}
+
+// listParams 解析 filter、startIndex 和 count 查询参数
+func listParams(c *gin.Context) (scim.Filter, int, int, error) {
+	var filter scim.Filter
+	if expr := c.Query("filter"); expr != "" {
+		f, err := scim.ParseFilter(expr)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		filter = f
+	}
+
+	startIndex, count := 1, scim.MaxResults
+	for name, target := range map[string]*int{"startIndex": &startIndex, "count": &count} {
+		if value := c.Query(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, 0, 0, scim.NewError(http.StatusBadRequest, scim.ErrInvalidValue, "invalid "+name)
+			}
+			*target = n
+		}
+	}
+	return filter, startIndex, count, nil
+}
+
+func bindScim(c *gin.Context, target any) error {
+	if err := c.ShouldBindJSON(target); err != nil {
+		return scim.NewError(http.StatusBadRequest, scim.ErrInvalidSyntax, err.Error())
+	}
+	return nil
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+func (ctl *ControllerScim) HandleServiceProviderConfig(c *gin.Context) {
+	config := scim.NewServiceProviderConfig()
+	config.Meta.Location = ctl.location(c, "/ServiceProviderConfig")
+	renderScim(c, http.StatusOK, config)
+}
+
+func (ctl *ControllerScim) HandleListResourceTypes(c *gin.Context) {
+	resourceTypes := scim.ResourceTypes()
+	for _, resourceType := range resourceTypes {
+		resourceType.Meta.Location = ctl.location(c, "/ResourceTypes/"+resourceType.Id)
+	}
+	renderScim(c, http.StatusOK, scim.NewListResponse(resourceTypes, 1, len(resourceTypes)))
+}
+
+func (ctl *ControllerScim) HandleGetResourceType(c *gin.Context) {
+	for _, resourceType := range scim.ResourceTypes() {
+		if resourceType.Id == c.Param("id") {
+			resourceType.Meta.Location = ctl.location(c, "/ResourceTypes/"+resourceType.Id)
+			renderScim(c, http.StatusOK, resourceType)
+			return
+		}
+	}
+	renderScimError(c, scim.NewError(http.StatusNotFound, "", "resource type not found"))
+}
+
+func (ctl *ControllerScim) HandleListSchemas(c *gin.Context) {
+	schemas := scim.Schemas()
+	for _, schema := range schemas {
+		schema.Meta.Location = ctl.location(c, "/Schemas/"+schema.Id)
+	}
+	renderScim(c, http.StatusOK, scim.NewListResponse(schemas, 1, len(schemas)))
+}
+
+func (ctl *ControllerScim) HandleGetSchema(c *gin.Context) {
+	for _, schema := range scim.Schemas() {
+		if schema.Id == c.Param("id") {
+			schema.Meta.Location = ctl.location(c, "/Schemas/"+schema.Id)
+			renderScim(c, http.StatusOK, schema)
+			return
+		}
+	}
+	renderScimError(c, scim.NewError(http.StatusNotFound, "", "schema not found"))
+}
+
+func (ctl *ControllerScim) HandleUnsupported(c *gin.Context) {
+	renderScimError(c, scim.NewError(http.StatusNotImplemented, "", "groups are managed by asynx and cannot be created or deleted"))
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+func (ctl *ControllerScim) withUserLocation(c *gin.Context, user *scim.User) *scim.User {
+	user.Meta.Location = ctl.location(c, "/Users/"+user.Id)
+	for i := range user.Groups {
+		user.Groups[i].Ref = ctl.location(c, "/Groups/"+user.Groups[i].Value)
+	}
+	return user
+}
+
+func (ctl *ControllerScim) withGroupLocation(c *gin.Context, group *scim.Group) *scim.Group {
+	group.Meta.Location = ctl.location(c, "/Groups/"+group.Id)
+	for i := range group.Members {
+		group.Members[i].Ref = ctl.location(c, "/Users/"+group.Members[i].Value)
+	}
+	return group
+}
+
+func (ctl *ControllerScim) HandleListUsers(c *gin.Context) {
+	filter, startIndex, count, err := listParams(c)
+	if err != nil {
+		renderScimError(c, err)
+		return
+	}
+	users, err := ctl.serviceScim.ListUsers(filter)
+	if err != nil {
+		renderScimError(c, err)
+		return
+	}
+	list := scim.NewListResponse(users, startIndex, count)
+	for _, user := range list.Resources {
+		ctl.withUserLocation(c, user)
+	}
+	renderScim(c, http.StatusOK, list)
+}
+
+func (ctl *ControllerScim) HandleGetUser(c *gin.Context) {
+	user, err := ctl.serviceScim.GetUser(c.Param("id"))
+	if err != nil {
+		renderScimError(c, err)
+		return
+	}
+	renderScim(c, http.StatusOK, ctl.withUserLocation(c, user))
+}
+
+func (ctl *ControllerScim) HandleCreateUser(c *gin.Context) {
+	var request scim.User
+	if err := bindScim(c, &request); err != nil {
+		renderScimError(c, err)
+		return
+	}
+	user, err := ctl.serviceScim.CreateUser(&request)
+	if err != nil {
+		renderScimError(c, err)
+		return
+	}
+	ctl.withUserLocation(c, user)
+	c.Header("Location", user.Meta.Location)
+	renderScim(c, http.StatusCreated, user)
+}
+
+func (ctl *ControllerScim) HandleReplaceUser(c *gin.Context) {
+	var request scim.User
+	if err := bindScim(c, &request); err != nil {
+		renderScimError(c, err)
+		return
+	}
+	user, err := ctl.serviceScim.ReplaceUser(c.Param("id"), &request)
+	if err != nil {
+		renderScimError(c, err)
+		return
+	}
+	renderScim(c, http.StatusOK, ctl.withUserLocation(c, user))
+}
+
+func (ctl *ControllerScim) HandlePatchUser(c *gin.Context) {
+	var request scim.PatchRequest
+	if err := bindScim(c, &request); err != nil {
+		renderScimError(c, err)
+		return
+	}
+	user, err := ctl.serviceScim.PatchUser(c.Param("id"), request.Operations)
+	if err != nil {
+		renderScimError(c, err)
+		return
+	}
+	renderScim(c, http.StatusOK, ctl.withUserLocation(c, user))
+}
+
+func (ctl *ControllerScim) HandleDeleteUser(c *gin.Context) {
+	if err := ctl.serviceScim.DeleteUser(c.Param("id")); err != nil {
+		renderScimError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+func (ctl *ControllerScim) HandleListGroups(c *gin.Context) {
+	filter, startIndex, count, err := listParams(c)
+	if err != nil {
+		renderScimError(c, err)
+		return
+	}
+	groups, err := ctl.serviceScim.ListGroups(filter)
+	if err != nil {
+		renderScimError(c, err)
+		return
+	}
+	list := scim.NewListResponse(groups, startIndex, count)
+	for _, group := range list.Resources {
+		ctl.withGroupLocation(c, group)
+	}
+	renderScim(c, http.StatusOK, list)
+}
+
+func (ctl *ControllerScim) HandleGetGroup(c *gin.Context) {
+	group, err := ctl.serviceScim.GetGroup(c.Param("id"))
+	if err != nil {
+		renderScimError(c, err)
+		return
+	}
+	renderScim(c, http.StatusOK, ctl.withGroupLocation(c, group))
+}
+
+func (ctl *ControllerScim) HandleReplaceGroup(c *gin.Context) {
+	var request scim.Group
+	if err := bindScim(c, &request); err != nil {
+		renderScimError(c, err)
+		return
+	}
+	group, err := ctl.serviceScim.ReplaceGroup(c.Param("id"), &request)
+	if err != nil {
+		renderScimError(c, err)
+		return
+	}
+	renderScim(c, http.StatusOK, ctl.withGroupLocation(c, group))
+}
+
+func (ctl *ControllerScim) HandlePatchGroup(c *gin.Context) {
+	var request scim.PatchRequest
+	if err := bindScim(c, &request); err != nil {
+		renderScimError(c, err)
+		return
+	}
+	group, err := ctl.serviceScim.PatchGroup(c.Param("id"), request.Operations)
+	if err != nil {
+		renderScimError(c, err)
+		return
+	}
+	renderScim(c, http.StatusOK, ctl.withGroupLocation(c, group))
+}
@@ -0,0 +1,269 @@
+package controller
+
+import (
+	"net/http"
+
+	"asynclab.club/asynx/backend/pkg/security"
+	"asynclab.club/asynx/backend/pkg/service"
+	"github.com/dsx137/gg-gin/pkg/gggin"
+	"github.com/gin-gonic/gin"
+)
+
+type ControllerServiceAccounts struct {
+	serviceServiceAccount *service.ServiceServiceAccount
+}
+
+func NewControllerServiceAccounts(g *gin.RouterGroup, serviceServiceAccount *service.ServiceServiceAccount) *ControllerServiceAccounts {
+	ctl := &ControllerServiceAccounts{serviceServiceAccount: serviceServiceAccount}
+	g.GET("", security.GuardMiddleware(security.PermServiceAccountsManage), gggin.ToGinHandler(ctl.HandleList))
+	g.POST("", security.GuardMiddleware(security.PermServiceAccountsManage), gggin.ToGinHandler(ctl.HandleCreate))
+	g.GET("/:uid", security.GuardMiddleware(security.PermServiceAccountsManage), gggin.ToGinHandler(ctl.HandleGet))
+	g.PUT("/:uid", security.GuardMiddleware(security.PermServiceAccountsManage), gggin.ToGinHandler(ctl.HandleUpdate))
+	g.DELETE("/:uid", security.GuardMiddleware(security.PermServiceAccountsManage), gggin.ToGinHandler(ctl.HandleDelete))
+	g.GET("/:uid/keys", security.GuardMiddleware(security.PermServiceAccountsManage), gggin.ToGinHandler(ctl.HandleListKeys))
+	g.POST("/:uid/keys", security.GuardMiddleware(security.PermServiceAccountsManage), gggin.ToGinHandler(ctl.HandleCreateKey))
+	g.POST("/:uid/keys/:id/rotate", security.GuardMiddleware(security.PermServiceAccountsManage), gggin.ToGinHandler(ctl.HandleRotateKey))
+	g.DELETE("/:uid/keys/:id", security.GuardMiddleware(security.PermServiceAccountsManage), gggin.ToGinHandler(ctl.HandleRevokeKey))
+	return ctl
+}
+
+// @Summary      获取服务账号列表
+// @Description  获取所有服务账号及其负责人、团队和允许的权限范围。需要 service-accounts.manage 权限。
+// @Tags         service-accounts
+// @Accept       json
+// @Produce      json
+// @Success      200  {object} object{data=[]service.ServiceAccount} "成功返回服务账号列表"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /service-accounts [get]
+// @Security     BearerAuth
+func (ctl *ControllerServiceAccounts) HandleList(c *gin.Context) (*gggin.Response[[]*service.ServiceAccount], *gggin.HttpError) {
+	accounts, err := ctl.serviceServiceAccount.List()
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(accounts), nil
+}
+
+type RequestCreateServiceAccount struct {
+	Username string `json:"username" binding:"required"`
+	service.ServiceAccountSpec
+}
+
+// @Summary      创建服务账号
+// @Description  在 system 下创建服务账号。服务账号没有邮箱，不能通过密码登录，只能使用 API 密钥访问接口；scopes 限制了其密钥可以申请的权限范围，格式与个人访问令牌相同。
+// @Description  用户名由小写字母、数字和连字符组成，以字母开头，长度 3 到 32。需要 service-accounts.manage 权限，没有 users.role.grant 权限时角色不能高于自己。
+// @Tags         service-accounts
+// @Accept       json
+// @Produce      json
+// @Param        body  body      RequestCreateServiceAccount  true  "创建服务账号请求"
+// @Success      200  {object} object{data=service.ServiceAccount} "成功返回服务账号"
+// @Failure      400  {object} object{data=string} "请求参数错误"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      409  {object} object{data=string} "用户已存在"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /service-accounts [post]
+// @Security     BearerAuth
+func (ctl *ControllerServiceAccounts) HandleCreate(c *gin.Context) (*gggin.Response[*service.ServiceAccount], *gggin.HttpError) {
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
+	if !ok {
+		return nil, ErrHttpGuardFail
+	}
+	req, err := gggin.ShouldBindJSON[RequestCreateServiceAccount](c)
+	if err != nil {
+		return nil, gggin.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	account, err := ctl.serviceServiceAccount.Create(guard, req.Username, &req.ServiceAccountSpec)
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(account), nil
+}
+
+// @Summary      获取服务账号
+// @Description  根据用户名获取服务账号。需要 service-accounts.manage 权限。
+// @Tags         service-accounts
+// @Accept       json
+// @Produce      json
+// @Param        uid  path      string  true  "服务账号用户名"
+// @Success      200  {object} object{data=service.ServiceAccount} "成功返回服务账号"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "服务账号不存在"
+// @Router       /service-accounts/{uid} [get]
+// @Security     BearerAuth
+func (ctl *ControllerServiceAccounts) HandleGet(c *gin.Context) (*gggin.Response[*service.ServiceAccount], *gggin.HttpError) {
+	account, err := ctl.serviceServiceAccount.Get(c.Param("uid"))
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(account), nil
+}
+
+// @Summary      修改服务账号
+// @Description  修改服务账号的描述、负责人、权限范围和角色。收窄权限范围时，超出新范围的密钥会被撤销。需要 service-accounts.manage 权限，没有 users.role.grant 权限时原角色和新角色都不能高于自己。
+// @Tags         service-accounts
+// @Accept       json
+// @Produce      json
+// @Param        uid   path      string                      true  "服务账号用户名"
+// @Param        body  body      service.ServiceAccountSpec  true  "修改请求"
+// @Success      200  {object} object{data=service.ServiceAccount} "成功返回服务账号"
+// @Failure      400  {object} object{data=string} "请求参数错误"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "服务账号不存在"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /service-accounts/{uid} [put]
+// @Security     BearerAuth
+func (ctl *ControllerServiceAccounts) HandleUpdate(c *gin.Context) (*gggin.Response[*service.ServiceAccount], *gggin.HttpError) {
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
+	if !ok {
+		return nil, ErrHttpGuardFail
+	}
+	req, err := gggin.ShouldBindJSON[service.ServiceAccountSpec](c)
+	if err != nil {
+		return nil, gggin.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	account, err := ctl.serviceServiceAccount.Update(guard, c.Param("uid"), req)
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(account), nil
+}
+
+// @Summary      删除服务账号
+// @Description  删除服务账号及其全部密钥。需要 service-accounts.manage 权限，没有 users.role.grant 权限时服务账号的角色不能高于自己。
+// @Tags         service-accounts
+// @Accept       json
+// @Produce      json
+// @Param        uid  path      string  true  "服务账号用户名"
+// @Success      200  {object} object{data=string} "成功删除，返回 'ok'"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "服务账号不存在"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /service-accounts/{uid} [delete]
+// @Security     BearerAuth
+func (ctl *ControllerServiceAccounts) HandleDelete(c *gin.Context) (*gggin.Response[string], *gggin.HttpError) {
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
+	if !ok {
+		return nil, ErrHttpGuardFail
+	}
+	if err := ctl.serviceServiceAccount.Delete(guard, c.Param("uid")); err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.Ok, nil
+}
+
+// @Summary      获取服务账号的密钥列表
+// @Description  获取服务账号的 API 密钥，不包含密钥明文。需要 service-accounts.manage 权限，没有 users.role.grant 权限时服务账号的角色不能高于自己。
+// @Tags         service-accounts
+// @Accept       json
+// @Produce      json
+// @Param        uid  path      string  true  "服务账号用户名"
+// @Success      200  {object} object{data=[]service.AccessToken} "成功返回密钥列表"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "服务账号不存在"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /service-accounts/{uid}/keys [get]
+// @Security     BearerAuth
+func (ctl *ControllerServiceAccounts) HandleListKeys(c *gin.Context) (*gggin.Response[[]*service.AccessToken], *gggin.HttpError) {
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
+	if !ok {
+		return nil, ErrHttpGuardFail
+	}
+	keys, err := ctl.serviceServiceAccount.ListKeys(guard, c.Param("uid"))
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(keys), nil
+}
+
+// @Summary      签发服务账号密钥
+// @Description  为服务账号签发 API 密钥，权限范围必须在服务账号允许的范围之内，角色的权限必须是服务账号角色权限的子集。密钥明文只在本次返回。需要 service-accounts.manage 权限，没有 users.role.grant 权限时服务账号的角色不能高于自己。
+// @Tags         service-accounts
+// @Accept       json
+// @Produce      json
+// @Param        uid   path      string                   true  "服务账号用户名"
+// @Param        body  body      service.AccessTokenSpec  true  "签发请求"
+// @Success      200  {object} object{data=service.AccessToken} "成功返回密钥（包含明文）"
+// @Failure      400  {object} object{data=string} "请求参数错误"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "服务账号不存在"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /service-accounts/{uid}/keys [post]
+// @Security     BearerAuth
+func (ctl *ControllerServiceAccounts) HandleCreateKey(c *gin.Context) (*gggin.Response[*service.AccessToken], *gggin.HttpError) {
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
+	if !ok {
+		return nil, ErrHttpGuardFail
+	}
+	req, err := gggin.ShouldBindJSON[service.AccessTokenSpec](c)
+	if err != nil {
+		return nil, gggin.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	key, err := ctl.serviceServiceAccount.CreateKey(guard, c.Param("uid"), req)
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(key), nil
+}
+
+// @Summary      轮换服务账号密钥
+// @Description  以相同的名称、权限范围、角色和有效时长签发新密钥，并立即撤销旧密钥。新密钥明文只在本次返回。需要 service-accounts.manage 权限，没有 users.role.grant 权限时服务账号的角色不能高于自己。
+// @Tags         service-accounts
+// @Accept       json
+// @Produce      json
+// @Param        uid  path      string  true  "服务账号用户名"
+// @Param        id   path      string  true  "密钥ID"
+// @Success      200  {object} object{data=service.AccessToken} "成功返回新密钥（包含明文）"
+// @Failure      400  {object} object{data=string} "请求参数错误"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "服务账号或密钥不存在"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /service-accounts/{uid}/keys/{id}/rotate [post]
+// @Security     BearerAuth
+func (ctl *ControllerServiceAccounts) HandleRotateKey(c *gin.Context) (*gggin.Response[*service.AccessToken], *gggin.HttpError) {
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
+	if !ok {
+		return nil, ErrHttpGuardFail
+	}
+	key, err := ctl.serviceServiceAccount.RotateKey(guard, c.Param("uid"), c.Param("id"))
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(key), nil
+}
+
+// @Summary      撤销服务账号密钥
+// @Description  删除服务账号的 API 密钥，立即失效。需要 service-accounts.manage 权限，没有 users.role.grant 权限时服务账号的角色不能高于自己。
+// @Tags         service-accounts
+// @Accept       json
+// @Produce      json
+// @Param        uid  path      string  true  "服务账号用户名"
+// @Param        id   path      string  true  "密钥ID"
+// @Success      200  {object} object{data=string} "成功撤销，返回 'ok'"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "服务账号或密钥不存在"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /service-accounts/{uid}/keys/{id} [delete]
+// @Security     BearerAuth
+func (ctl *ControllerServiceAccounts) HandleRevokeKey(c *gin.Context) (*gggin.Response[string], *gggin.HttpError) {
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
+	if !ok {
+		return nil, ErrHttpGuardFail
+	}
+	if err := ctl.serviceServiceAccount.RevokeKey(guard, c.Param("uid"), c.Param("id")); err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.Ok, nil
+}
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"net/http"
+	"time"
 
 	"asynclab.club/asynx/backend/pkg/security"
 	"asynclab.club/asynx/backend/pkg/service"
@@ -10,27 +11,84 @@ import (
 )
 
 type ControllerUser struct {
-	serviceManager *service.ServiceManager
+	serviceManager   *service.ServiceManager
+	serviceRoleGrant *service.ServiceRoleGrant
 }
 
-func NewControllerUser(g *gin.RouterGroup, serviceManager *service.ServiceManager) *ControllerUser {
-	ctl := &ControllerUser{serviceManager: serviceManager}
-	g.GET("", security.GuardMiddleware(security.RoleDefault), gggin.ToGinHandler(ctl.HandleListProfiles))
-	g.POST("", security.GuardMiddleware(security.RoleAdmin), gggin.ToGinHandler(ctl.HandleRegister))
-	g.GET("/:uid", security.GuardMiddleware(security.RoleRestricted), gggin.ToGinHandler(ctl.HandleGetProfile))
-	g.DELETE("/:uid", security.GuardMiddleware(security.RoleAdmin), gggin.ToGinHandler(ctl.HandleUnregister))
-	g.PUT("/:uid/password", security.GuardMiddleware(security.RoleRestricted), gggin.ToGinHandler(ctl.HandleChangePassword))
-	g.PUT("/:uid/category", security.GuardMiddleware(security.RoleAdmin), gggin.ToGinHandler(ctl.HandleModifyCategory))
-	g.PUT("/:uid/role", security.GuardMiddleware(security.RoleAdmin), gggin.ToGinHandler(ctl.HandleModifyRole))
+func NewControllerUser(g *gin.RouterGroup, serviceManager *service.ServiceManager, serviceRoleGrant *service.ServiceRoleGrant) *ControllerUser {
+	ctl := &ControllerUser{serviceManager: serviceManager, serviceRoleGrant: serviceRoleGrant}
+	g.GET("", security.GuardMiddlewareOr(security.PermUsersRead, ctl.widenList), gggin.ToGinHandler(ctl.HandleListProfiles))
+	g.POST("", security.GuardMiddlewareOr(security.PermUsersWrite, ctl.widenDelegated), gggin.ToGinHandler(ctl.HandleRegister))
+	g.GET("/:uid", security.GuardMiddlewareOr(security.PermUsersRead, ctl.widenRead), gggin.ToGinHandler(ctl.HandleGetProfile))
+	g.DELETE("/:uid", security.GuardMiddlewareOr(security.PermUsersWrite, ctl.widenManage), gggin.ToGinHandler(ctl.HandleUnregister))
+	g.PUT("/:uid/password", security.GuardMiddlewareOr(security.PermUsersWrite, ctl.widenSelfOrManage), gggin.ToGinHandler(ctl.HandleChangePassword))
+	g.PUT("/:uid/category", security.GuardMiddleware(security.PermUsersWrite), gggin.ToGinHandler(ctl.HandleModifyCategory))
+	g.PUT("/:uid/role", security.GuardMiddlewareOr(security.PermUsersRoleGrant, ctl.widenManage), gggin.ToGinHandler(ctl.HandleModifyRole))
+	g.PUT("/:uid/expiry", security.GuardMiddlewareOr(security.PermUsersWrite, ctl.widenManage), gggin.ToGinHandler(ctl.HandleModifyExpiry))
+	g.PUT("/:uid/language", security.GuardMiddlewareOr(security.PermUsersWrite, ctl.widenSelf), gggin.ToGinHandler(ctl.HandleModifyLanguage))
+	g.GET("/:uid/notifications", security.GuardMiddlewareOr(security.PermUsersRead, ctl.widenSelf), gggin.ToGinHandler(ctl.HandleGetNotificationSettings))
+	g.PUT("/:uid/notifications", security.GuardMiddlewareOr(security.PermUsersWrite, ctl.widenSelf), gggin.ToGinHandler(ctl.HandleModifyNotificationSettings))
 
 	// Deprecated
-	g.GET("/:uid/category", security.GuardMiddleware(security.RoleRestricted), gggin.ToGinHandler(ctl.HandleGetCategory))
-	g.GET("/:uid/role", security.GuardMiddleware(security.RoleRestricted), gggin.ToGinHandler(ctl.HandleGetRole))
+	g.GET("/:uid/category", security.GuardMiddlewareOr(security.PermUsersRead, ctl.widenRead), gggin.ToGinHandler(ctl.HandleGetCategory))
+	g.GET("/:uid/role", security.GuardMiddlewareOr(security.PermUsersRead, ctl.widenRead), gggin.ToGinHandler(ctl.HandleGetRole))
 	return ctl
 }
 
+// targetUid 返回路径中的用户，'me' 表示当前用户
+func targetUid(c *gin.Context, guard *security.GuardResult) string {
+	if uid := c.Param("uid"); uid != "me" {
+		return uid
+	}
+	return guard.Uid
+}
+
+// 以下函数在调用者没有路由要求的权限时决定是否放行：本人访问自己，或委派管理员访问被委派账号类型中的用户
+
+func (ctl *ControllerUser) widenSelf(c *gin.Context, guard *security.GuardResult) *gggin.HttpError {
+	if targetUid(c, guard) != guard.Uid {
+		return gggin.NewHttpError(http.StatusForbidden, "权限不足")
+	}
+	return nil
+}
+
+func (ctl *ControllerUser) widenList(c *gin.Context, guard *security.GuardResult) *gggin.HttpError {
+	if err := ctl.serviceManager.AuthorizeList(guard); err != nil {
+		return service.MapErrorToHttp(err)
+	}
+	return nil
+}
+
+func (ctl *ControllerUser) widenDelegated(c *gin.Context, guard *security.GuardResult) *gggin.HttpError {
+	if err := ctl.serviceManager.AuthorizeDelegated(guard); err != nil {
+		return service.MapErrorToHttp(err)
+	}
+	return nil
+}
+
+func (ctl *ControllerUser) widenRead(c *gin.Context, guard *security.GuardResult) *gggin.HttpError {
+	if _, err := ctl.serviceManager.GetUserWithGuard(guard, targetUid(c, guard)); err != nil {
+		return service.MapErrorToHttp(err)
+	}
+	return nil
+}
+
+func (ctl *ControllerUser) widenManage(c *gin.Context, guard *security.GuardResult) *gggin.HttpError {
+	if err := ctl.serviceManager.AuthorizeManage(guard, targetUid(c, guard)); err != nil {
+		return service.MapErrorToHttp(err)
+	}
+	return nil
+}
+
+func (ctl *ControllerUser) widenSelfOrManage(c *gin.Context, guard *security.GuardResult) *gggin.HttpError {
+	if targetUid(c, guard) == guard.Uid {
+		return nil
+	}
+	return ctl.widenManage(c, guard)
+}
+
 // @Summary      获取用户列表
-// @Description  获取所有用户列表信息（包含角色和类别）。需要 ADMIN 角色权限才能查看所有用户，DEFAULT 用户只能查看自己组织单元的用户。
+// @Description  获取所有用户列表信息（包含角色和类别）。需要 users.read 权限；拥有 users.self 权限时，拥有 users.read.ou 权限可以查看自己组织单元的用户，委派管理员还可以查看被委派账号类型的用户；都不满足时返回 403。
 // @Tags         users
 // @Accept       json
 // @Produce      json
@@ -55,7 +113,7 @@ func (ctl *ControllerUser) HandleListProfiles(c *gin.Context) (*gggin.Response[[
 }
 
 // @Summary      获取用户信息
-// @Description  根据用户ID获取用户详细信息（包含角色和类别）。需要 RESTRICTED 或更高权限。ADMIN 用户可以查看所有用户信息，DEFAULT 用户只能查看自己组织单元的用户信息，RESTRICTED 用户只能查看自己的信息。
+// @Description  根据用户ID获取用户详细信息（包含角色和类别）。需要 users.read 权限；拥有 users.self 权限时可以查看自己的信息，拥有 users.read.ou 权限时还可以查看自己组织单元的用户信息，委派管理员还可以查看被委派账号类型的用户信息。
 // @Tags         users
 // @Accept       json
 // @Produce      json
@@ -92,7 +150,7 @@ type RequestChangePassword struct {
 }
 
 // @Summary      修改密码
-// @Description  修改指定用户的密码。需要 RESTRICTED 或更高权限。ADMIN 用户可以修改任何用户密码，其他用户只能修改自己的密码。
+// @Description  修改指定用户的密码。需要 users.write 权限；拥有 users.self 权限时可以修改自己的密码，委派管理员还可以修改被委派账号类型中用户的密码；没有 users.role.grant 权限时不能修改角色高于自己的用户的密码。修改他人密码时总会邮件通知该用户。
 // @Tags         users
 // @Accept       json
 // @Produce      json
@@ -116,8 +174,10 @@ func (ctl *ControllerUser) HandleChangePassword(c *gin.Context) (*gggin.Response
 	if uid == "me" {
 		uid = guard.Uid
 	}
-	if guard.Role != security.RoleAdmin && guard.Uid != uid {
-		return nil, gggin.NewHttpError(http.StatusForbidden, "权限不足")
+	if guard.Uid != uid {
+		if err := ctl.serviceManager.AuthorizeManage(guard, uid); err != nil {
+			return nil, service.MapErrorToHttp(err)
+		}
 	}
 
 	req, err := gggin.ShouldBindJSON[RequestChangePassword](c)
@@ -125,7 +185,7 @@ func (ctl *ControllerUser) HandleChangePassword(c *gin.Context) (*gggin.Response
 		return nil, gggin.NewHttpError(http.StatusBadRequest, err.Error())
 	}
 
-	err = ctl.serviceManager.ChangePassword(uid, req.Password)
+	err = ctl.serviceManager.ChangePassword(guard.Uid, uid, req.Password)
 	if err != nil {
 		return nil, service.MapErrorToHttp(err)
 
@@ -139,12 +199,12 @@ type RequestModifyCategory struct {
 }
 
 // @Summary      更改账号类型
-// @Description  修改指定用户的账号类型。需要 ADMIN 角色权限。
+// @Description  修改指定用户的账号类型。需要 users.write 权限。
 // @Tags         users
 // @Accept       json
 // @Produce      json
 // @Param        uid   path      string  true  "用户ID，不能使用 'me'"
-// @Param        body  body      RequestModifyCategory  true  "修改账号类型请求\nsystem|member|external"
+// @Param        body  body      RequestModifyCategory  true  "修改账号类型请求\nsystem|member|external|alumni"
 // @Success      200  {object} object{data=string} "成功修改账号类型，返回 'ok'"
 // @Failure      400  {object} object{data=string} "请求参数错误"
 // @Failure      401  {object} object{data=string} "未授权访问"
@@ -178,11 +238,13 @@ func (ctl *ControllerUser) HandleModifyCategory(c *gin.Context) (*gggin.Response
 }
 
 type RequestModifyRole struct {
-	Role string `json:"role" binding:"required"`
+	Role      string     `json:"role" binding:"required"`
+	ExpiresAt *time.Time `json:"expiresAt"` // 为空时永久授予
 }
 
 // @Summary      更改账号角色
-// @Description  修改指定用户的账号角色。需要 ADMIN 角色权限。非SYSTEM用户必须用学号作为用户名。不允许操作当前登录用户。
+// @Description  修改指定用户的账号角色。指定 expiresAt 时为临时授予，到期后自动恢复为授予前的角色，到期前会邮件提醒；不指定时永久授予，并取消原有的期限。
+// @Description  需要 users.role.grant 权限；委派管理员也可以修改被委派账号类型中的用户，但授予的角色和被修改用户的原角色都不能高于自己。非SYSTEM用户必须用学号作为用户名。不允许操作当前登录用户。
 // @Tags         users
 // @Accept       json
 // @Produce      json
@@ -212,25 +274,204 @@ func (ctl *ControllerUser) HandleModifyRole(c *gin.Context) (*gggin.Response[str
 		return nil, gggin.NewHttpError(http.StatusBadRequest, err.Error())
 	}
 
+	if err := ctl.serviceManager.AuthorizeRoleGrant(guard, uid, req.Role); err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+
+	if req.ExpiresAt != nil {
+		if _, err := ctl.serviceRoleGrant.Grant(guard.Uid, uid, req.Role, *req.ExpiresAt); err != nil {
+			return nil, service.MapErrorToHttp(err)
+		}
+		return gggin.Ok, nil
+	}
+
 	err = ctl.serviceManager.GrantRoleByUidAndRoleName(uid, req.Role)
 	if err != nil {
 		return nil, service.MapErrorToHttp(err)
 	}
+	ctl.serviceRoleGrant.Forget(uid)
+
+	return gggin.Ok, nil
+}
+
+type RequestModifyExpiry struct {
+	ExpiresAt *time.Time `json:"expiresAt"` // 为空时取消到期
+}
+
+// @Summary      更改账号到期时间
+// @Description  设置或取消指定用户的账号到期时间，常用于 external 类型的访客账号。到期时间向上取整到天（UTC）并写入 shadowExpire，使用 PAM 的主机同样会拒绝登录；到期后不能再登录或使用访问令牌，到期前会邮件提醒。
+// @Description  需要 users.write 权限，委派管理员只能修改被委派账号类型中角色不高于自己的用户。不允许操作当前登录用户。
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        uid   path      string  true  "用户ID，不能使用 'me'"
+// @Param        body  body      RequestModifyExpiry  true  "修改到期时间请求"
+// @Success      200  {object} object{data=string} "成功修改到期时间，返回 'ok'"
+// @Failure      400  {object} object{data=string} "请求参数错误"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "用户不存在"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /users/{uid}/expiry [put]
+// @Security     BearerAuth
+func (ctl *ControllerUser) HandleModifyExpiry(c *gin.Context) (*gggin.Response[string], *gggin.HttpError) {
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
+	if !ok {
+		return nil, ErrHttpGuardFail
+	}
+
+	uid := c.Param("uid")
+	if uid == "me" || uid == guard.Uid {
+		return nil, ErrHttpForceForbidden
+	}
+
+	req, err := gggin.ShouldBindJSON[RequestModifyExpiry](c)
+	if err != nil {
+		return nil, gggin.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := ctl.serviceManager.AuthorizeManage(guard, uid); err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+
+	if err := ctl.serviceManager.ModifyExpiry(uid, req.ExpiresAt); err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+
+	return gggin.Ok, nil
+}
+
+type RequestModifyLanguage struct {
+	Language string `json:"language"`
+}
+
+// @Summary      更改邮件语言
+// @Description  修改指定用户接收通知邮件所使用的语言，留空表示使用默认语言。需要 users.write 权限；拥有 users.self 权限时可以修改自己。
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        uid   path      string  true  "用户ID，使用 'me' 可修改当前用户"
+// @Param        body  body      RequestModifyLanguage  true  "修改语言请求\nzh|en"
+// @Success      200  {object} object{data=string} "成功修改语言，返回 'ok'"
+// @Failure      400  {object} object{data=string} "请求参数错误"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "用户不存在"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /users/{uid}/language [put]
+// @Security     BearerAuth
+func (ctl *ControllerUser) HandleModifyLanguage(c *gin.Context) (*gggin.Response[string], *gggin.HttpError) {
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
+	if !ok {
+		return nil, ErrHttpGuardFail
+	}
+
+	uid := c.Param("uid")
+	if uid == "me" {
+		uid = guard.Uid
+	}
+
+	req, err := gggin.ShouldBindJSON[RequestModifyLanguage](c)
+	if err != nil {
+		return nil, gggin.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	err = ctl.serviceManager.ModifyLanguage(uid, req.Language)
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+
+	return gggin.Ok, nil
+}
+
+// @Summary      获取通知设置
+// @Description  获取指定用户的邮件通知设置，mandatory 的通知与账号安全相关，不能关闭。需要 users.read 权限；拥有 users.self 权限时可以查看自己。
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        uid   path      string  true  "用户ID，使用 'me' 可查看当前用户"
+// @Success      200  {object} object{data=[]service.NotificationSetting} "成功返回通知设置"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "用户不存在"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /users/{uid}/notifications [get]
+// @Security     BearerAuth
+func (ctl *ControllerUser) HandleGetNotificationSettings(c *gin.Context) (*gggin.Response[[]service.NotificationSetting], *gggin.HttpError) {
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
+	if !ok {
+		return nil, ErrHttpGuardFail
+	}
+
+	uid := c.Param("uid")
+	if uid == "me" {
+		uid = guard.Uid
+	}
+
+	settings, err := ctl.serviceManager.GetNotificationSettings(uid)
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+
+	return gggin.NewResponse(settings), nil
+}
+
+type RequestModifyNotificationSettings struct {
+	Disabled []string `json:"disabled"`
+}
+
+// @Summary      更改通知设置
+// @Description  设置指定用户关闭的邮件通知，未列出的通知均为开启。密码被重置等安全通知不能关闭。需要 users.write 权限；拥有 users.self 权限时可以修改自己。
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        uid   path      string  true  "用户ID，使用 'me' 可修改当前用户"
+// @Param        body  body      RequestModifyNotificationSettings  true  "关闭的通知\nrole-changed|role-expiring|category-changed|account-deleted"
+// @Success      200  {object} object{data=string} "成功修改通知设置，返回 'ok'"
+// @Failure      400  {object} object{data=string} "请求参数错误"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "用户不存在"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /users/{uid}/notifications [put]
+// @Security     BearerAuth
+func (ctl *ControllerUser) HandleModifyNotificationSettings(c *gin.Context) (*gggin.Response[string], *gggin.HttpError) {
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
+	if !ok {
+		return nil, ErrHttpGuardFail
+	}
+
+	uid := c.Param("uid")
+	if uid == "me" {
+		uid = guard.Uid
+	}
+
+	req, err := gggin.ShouldBindJSON[RequestModifyNotificationSettings](c)
+	if err != nil {
+		return nil, gggin.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	err = ctl.serviceManager.ModifyNotificationSettings(uid, req.Disabled)
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
 
 	return gggin.Ok, nil
 }
 
 type RequestRegister struct {
-	Username  string `json:"username" binding:"required"`
-	SurName   string `json:"surName" binding:"required"`
-	GivenName string `json:"givenName" binding:"required"`
-	Mail      string `json:"mail" binding:"required"`
-	Category  string `json:"category" binding:"required"`
-	Role      string `json:"role" binding:"required"`
+	Username  string     `json:"username" binding:"required"`
+	SurName   string     `json:"surName" binding:"required"`
+	GivenName string     `json:"givenName" binding:"required"`
+	Mail      string     `json:"mail" binding:"required"`
+	Category  string     `json:"category" binding:"required"`
+	Role      string     `json:"role" binding:"required"`
+	Language  string     `json:"language"`
+	ExpiresAt *time.Time `json:"expiresAt"` // 账号到期时间，向上取整到天（UTC），为空时不过期
 }
 
 // @Summary      注册新用户
-// @Description  创建新用户账号。需要 ADMIN 角色权限。
+// @Description  创建新用户账号，可以指定账号到期时间。需要 users.write 权限；委派管理员可以在被委派的账号类型中创建用户。没有 users.role.grant 权限时角色不能高于自己。
 // @Tags         users
 // @Accept       json
 // @Produce      json
@@ -244,7 +485,7 @@ type RequestRegister struct {
 // @Router       /users [post]
 // @Security     BearerAuth
 func (ctl *ControllerUser) HandleRegister(c *gin.Context) (*gggin.Response[string], *gggin.HttpError) {
-	_, ok := gggin.Get[*security.GuardResult](c, "guard")
+	guard, ok := gggin.Get[*security.GuardResult](c, "guard")
 	if !ok {
 		return nil, ErrHttpGuardFail
 	}
@@ -253,7 +494,11 @@ func (ctl *ControllerUser) HandleRegister(c *gin.Context) (*gggin.Response[strin
 		return nil, gggin.NewHttpError(http.StatusBadRequest, err.Error())
 	}
 
-	err = ctl.serviceManager.Register(req.Username, req.SurName, req.GivenName, req.Mail, req.Category, req.Role)
+	if err := ctl.serviceManager.AuthorizeRegister(guard, req.Category, req.Role); err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+
+	err = ctl.serviceManager.Register(req.Username, req.SurName, req.GivenName, req.Mail, req.Category, req.Role, req.Language, req.ExpiresAt)
 	if err != nil {
 		return nil, service.MapErrorToHttp(err)
 	}
@@ -262,7 +507,7 @@ func (ctl *ControllerUser) HandleRegister(c *gin.Context) (*gggin.Response[strin
 }
 
 // @Summary      删除用户
-// @Description  删除指定用户账号。需要 ADMIN 角色权限。不允许删除当前登录用户。
+// @Description  删除指定用户账号。需要 users.write 权限，委派管理员只能删除被委派账号类型中角色不高于自己的用户。不允许删除当前登录用户。
 // @Tags         users
 // @Accept       json
 // @Produce      json
@@ -286,6 +531,10 @@ func (ctl *ControllerUser) HandleUnregister(c *gin.Context) (*gggin.Response[str
 		return nil, ErrHttpForceForbidden
 	}
 
+	if err := ctl.serviceManager.AuthorizeManage(guard, uid); err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+
 	err := ctl.serviceManager.Unregister(uid)
 	if err != nil {
 		return nil, service.MapErrorToHttp(err)
@@ -298,7 +547,7 @@ func (ctl *ControllerUser) HandleUnregister(c *gin.Context) (*gggin.Response[str
 
 // @Deprecated
 // @Summary      获取账号角色
-// @Description  获取指定用户的账号角色（admin|default|restricted）。需要 RESTRICTED 或更高权限。ADMIN 用户可以查看所有用户信息，DEFAULT 用户只能查看自己组织单元的用户信息，RESTRICTED 用户只能查看自己的信息。
+// @Description  获取指定用户的账号角色（admin|default|restricted 或自定义角色）。需要 users.read 权限；拥有 users.self 权限时可以查看自己的信息，拥有 users.read.ou 权限时还可以查看自己组织单元的用户信息，委派管理员还可以查看被委派账号类型的用户信息。
 // @Tags         users
 // @Accept       json
 // @Produce      json
@@ -336,12 +585,12 @@ func (ctl *ControllerUser) HandleGetRole(c *gin.Context) (*gggin.Response[securi
 
 // @Deprecated
 // @Summary      获取账号类型
-// @Description  获取指定用户的账号类型（system|member|external）。需要 RESTRICTED 或更高权限。ADMIN 用户可以查看所有用户信息，DEFAULT 用户只能查看自己组织单元的用户信息，RESTRICTED 用户只能查看自己的信息。
+// @Description  获取指定用户的账号类型（system|member|external|alumni）。需要 users.read 权限；拥有 users.self 权限时可以查看自己的信息，拥有 users.read.ou 权限时还可以查看自己组织单元的用户信息，委派管理员还可以查看被委派账号类型的用户信息。
 // @Tags         users
 // @Accept       json
 // @Produce      json
 // @Param        uid   path      string  true  "用户ID，使用 'me' 可获取当前用户类型"
-// @Success      200  {object} object{data=string} "成功返回账号类型: system|member|external"
+// @Success      200  {object} object{data=string} "成功返回账号类型: system|member|external|alumni"
 // @Failure      400  {object} object{data=string} "请求参数错误"
 // @Failure      401  {object} object{data=string} "未授权访问"
 // @Failure      403  {object} object{data=string} "权限不足"
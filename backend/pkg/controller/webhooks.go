@@ -0,0 +1,216 @@
+package controller
+
+import (
+	"net/http"
+
+	"asynclab.club/asynx/backend/pkg/security"
+	"asynclab.club/asynx/backend/pkg/service"
+	"asynclab.club/asynx/backend/pkg/webhook"
+	"github.com/dsx137/gg-gin/pkg/gggin"
+	"github.com/gin-gonic/gin"
+)
+
+type ControllerWebhook struct {
+	serviceWebhook *service.ServiceWebhook
+}
+
+func NewControllerWebhooks(g *gin.RouterGroup, serviceWebhook *service.ServiceWebhook) *ControllerWebhook {
+	ctl := &ControllerWebhook{serviceWebhook: serviceWebhook}
+	g.GET("", security.GuardMiddleware(security.PermWebhooksManage), gggin.ToGinHandler(ctl.HandleList))
+	g.POST("", security.GuardMiddleware(security.PermWebhooksManage), gggin.ToGinHandler(ctl.HandleCreate))
+	g.GET("/:id", security.GuardMiddleware(security.PermWebhooksManage), gggin.ToGinHandler(ctl.HandleGet))
+	g.PUT("/:id", security.GuardMiddleware(security.PermWebhooksManage), gggin.ToGinHandler(ctl.HandleUpdate))
+	g.DELETE("/:id", security.GuardMiddleware(security.PermWebhooksManage), gggin.ToGinHandler(ctl.HandleDelete))
+	g.GET("/:id/deliveries", security.GuardMiddleware(security.PermAuditRead), gggin.ToGinHandler(ctl.HandleListDeliveries))
+	g.GET("/:id/deliveries/:deliveryId", security.GuardMiddleware(security.PermAuditRead), gggin.ToGinHandler(ctl.HandleGetDelivery))
+	g.POST("/:id/deliveries/:deliveryId/redeliver", security.GuardMiddleware(security.PermWebhooksManage), gggin.ToGinHandler(ctl.HandleRedeliver))
+	return ctl
+}
+
+// @Summary      获取 Webhook 列表
+// @Description  获取所有已注册的 Webhook 端点，不包含密钥。需要 webhooks.manage 权限。
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Success      200  {object} object{data=[]webhook.Endpoint} "成功返回端点列表"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /webhooks [get]
+// @Security     BearerAuth
+func (ctl *ControllerWebhook) HandleList(c *gin.Context) (*gggin.Response[[]*webhook.Endpoint], *gggin.HttpError) {
+	endpoints, err := ctl.serviceWebhook.List()
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(endpoints), nil
+}
+
+type RequestCreateWebhook struct {
+	Url         string   `json:"url" binding:"required"`
+	Events      []string `json:"events" binding:"required"`
+	Description string   `json:"description"`
+	Secret      string   `json:"secret"`
+}
+
+// @Summary      注册 Webhook
+// @Description  注册一个 Webhook 端点。事件发生时向该地址 POST JSON，请求头 X-Asynx-Signature 为 "sha256=" 加上以密钥对 "<X-Asynx-Timestamp>.<body>" 计算的 HMAC-SHA256。密钥留空时自动生成，只在本次返回。需要 webhooks.manage 权限。
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        body  body      RequestCreateWebhook  true  "注册请求\nevents: user.created|user.deleted|user.updated|user.role_changed|user.category_changed|user.password_changed|group.member_added|group.member_removed，支持 user.*、group.* 和 *"
+// @Success      200  {object} object{data=webhook.Endpoint} "成功返回端点（包含密钥）"
+// @Failure      400  {object} object{data=string} "请求参数错误"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      500  {object} object{data=string} "服务器内部错误"
+// @Router       /webhooks [post]
+// @Security     BearerAuth
+func (ctl *ControllerWebhook) HandleCreate(c *gin.Context) (*gggin.Response[*webhook.Endpoint], *gggin.HttpError) {
+	req, err := gggin.ShouldBindJSON[RequestCreateWebhook](c)
+	if err != nil {
+		return nil, gggin.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	endpoint, err := ctl.serviceWebhook.Create(req.Url, req.Events, req.Description, req.Secret)
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(endpoint), nil
+}
+
+// @Summary      获取 Webhook
+// @Description  根据ID获取 Webhook 端点，不包含密钥。需要 webhooks.manage 权限。
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "端点ID"
+// @Success      200  {object} object{data=webhook.Endpoint} "成功返回端点"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "端点不存在"
+// @Router       /webhooks/{id} [get]
+// @Security     BearerAuth
+func (ctl *ControllerWebhook) HandleGet(c *gin.Context) (*gggin.Response[*webhook.Endpoint], *gggin.HttpError) {
+	endpoint, err := ctl.serviceWebhook.Get(c.Param("id"))
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(endpoint), nil
+}
+
+type RequestUpdateWebhook struct {
+	Url          string   `json:"url" binding:"required"`
+	Events       []string `json:"events" binding:"required"`
+	Active       bool     `json:"active"`
+	Description  string   `json:"description"`
+	RotateSecret bool     `json:"rotateSecret"`
+}
+
+// @Summary      修改 Webhook
+// @Description  修改 Webhook 端点的地址、订阅事件和启用状态。rotateSecret 为 true 时生成新密钥并只在本次返回。需要 webhooks.manage 权限。
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string  true  "端点ID"
+// @Param        body  body      RequestUpdateWebhook  true  "修改请求"
+// @Success      200  {object} object{data=webhook.Endpoint} "成功返回端点"
+// @Failure      400  {object} object{data=string} "请求参数错误"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "端点不存在"
+// @Router       /webhooks/{id} [put]
+// @Security     BearerAuth
+func (ctl *ControllerWebhook) HandleUpdate(c *gin.Context) (*gggin.Response[*webhook.Endpoint], *gggin.HttpError) {
+	req, err := gggin.ShouldBindJSON[RequestUpdateWebhook](c)
+	if err != nil {
+		return nil, gggin.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	endpoint, err := ctl.serviceWebhook.Update(c.Param("id"), req.Url, req.Events, req.Active, req.Description, req.RotateSecret)
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(endpoint), nil
+}
+
+// @Summary      删除 Webhook
+// @Description  删除 Webhook 端点及其投递记录。需要 webhooks.manage 权限。
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "端点ID"
+// @Success      200  {object} object{data=string} "成功删除，返回 'ok'"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "端点不存在"
+// @Router       /webhooks/{id} [delete]
+// @Security     BearerAuth
+func (ctl *ControllerWebhook) HandleDelete(c *gin.Context) (*gggin.Response[string], *gggin.HttpError) {
+	if err := ctl.serviceWebhook.Delete(c.Param("id")); err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.Ok, nil
+}
+
+// @Summary      获取投递记录
+// @Description  获取 Webhook 端点的投递历史，按时间倒序。需要 audit.read 权限。
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "端点ID"
+// @Success      200  {object} object{data=[]webhook.Delivery} "成功返回投递记录"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "端点不存在"
+// @Router       /webhooks/{id}/deliveries [get]
+// @Security     BearerAuth
+func (ctl *ControllerWebhook) HandleListDeliveries(c *gin.Context) (*gggin.Response[[]webhook.Delivery], *gggin.HttpError) {
+	deliveries, err := ctl.serviceWebhook.ListDeliveries(c.Param("id"))
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(deliveries), nil
+}
+
+// @Summary      获取投递详情
+// @Description  获取一次 Webhook 投递的详情，包含事件内容和最后一次响应。需要 audit.read 权限。
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id          path      string  true  "端点ID"
+// @Param        deliveryId  path      string  true  "投递ID"
+// @Success      200  {object} object{data=webhook.Delivery} "成功返回投递详情"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "投递记录不存在"
+// @Router       /webhooks/{id}/deliveries/{deliveryId} [get]
+// @Security     BearerAuth
+func (ctl *ControllerWebhook) HandleGetDelivery(c *gin.Context) (*gggin.Response[*webhook.Delivery], *gggin.HttpError) {
+	delivery, err := ctl.serviceWebhook.GetDelivery(c.Param("id"), c.Param("deliveryId"))
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(delivery), nil
+}
+
+// @Summary      重新投递
+// @Description  以同一事件重新投递一次，生成新的投递记录，原记录保持不变。需要 webhooks.manage 权限。
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id          path      string  true  "端点ID"
+// @Param        deliveryId  path      string  true  "投递ID"
+// @Success      200  {object} object{data=webhook.Delivery} "成功返回新的投递记录"
+// @Failure      401  {object} object{data=string} "未授权访问"
+// @Failure      403  {object} object{data=string} "权限不足"
+// @Failure      404  {object} object{data=string} "投递记录不存在"
+// @Router       /webhooks/{id}/deliveries/{deliveryId}/redeliver [post]
+// @Security     BearerAuth
+func (ctl *ControllerWebhook) HandleRedeliver(c *gin.Context) (*gggin.Response[*webhook.Delivery], *gggin.HttpError) {
+	delivery, err := ctl.serviceWebhook.Redeliver(c.Param("id"), c.Param("deliveryId"))
+	if err != nil {
+		return nil, service.MapErrorToHttp(err)
+	}
+	return gggin.NewResponse(delivery), nil
+}
@@ -1,15 +1,19 @@
 package entity
 
 type User struct {
-	Uid           string `ldap:"uid" json:"uid"`
-	Cn            string `ldap:"cn,dnAttr:cn,idx:2" json:"cn"`
-	Ou            string `ldap:"ou,dnAttr:ou,idx:1" json:"ou"`
-	Sn            string `ldap:"sn" json:"sn"`
-	GivenName     string `ldap:"givenName" json:"givenName"`
-	GidNumber     string `ldap:"gidNumber" json:"gidNumber"`
-	UidNumber     string `ldap:"uidNumber" json:"uidNumber"`
-	HomeDirectory string `ldap:"homeDirectory" json:"homeDirectory"`
-	Mail          string `ldap:"mail" json:"mail"`
-	UserPassword  string `ldap:"userPassword" json:"userPassword"`
-	LoginShell    string `ldap:"loginShell" json:"loginShell"`
+	Uid               string `ldap:"uid" json:"uid"`
+	Cn                string `ldap:"cn,dnAttr:cn,idx:2" json:"cn"`
+	Ou                string `ldap:"ou,dnAttr:ou,idx:1" json:"ou"`
+	Sn                string `ldap:"sn" json:"sn"`
+	GivenName         string `ldap:"givenName" json:"givenName"`
+	GidNumber         string `ldap:"gidNumber" json:"gidNumber"`
+	UidNumber         string `ldap:"uidNumber" json:"uidNumber"`
+	HomeDirectory     string `ldap:"homeDirectory" json:"homeDirectory"`
+	Mail              string `ldap:"mail" json:"mail"`
+	UserPassword      string `ldap:"userPassword" json:"userPassword"`
+	LoginShell        string `ldap:"loginShell" json:"loginShell"`
+	PreferredLanguage string `ldap:"preferredLanguage" json:"preferredLanguage"`
+	ShadowExpire      string `ldap:"shadowExpire" json:"shadowExpire"` // 账号到期日，自 1970-01-01 起的天数，为空表示不过期
+	Description       string `ldap:"description" json:"description"`   // 服务账号在此保存负责人、团队等元数据
+	EmployeeType      string `ldap:"employeeType" json:"employeeType"` // 账号类型，服务账号为 service-account
 }
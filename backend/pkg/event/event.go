@@ -0,0 +1,106 @@
+package event
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Type string
+
+const (
+	UserCreated         Type = "user.created"
+	UserDeleted         Type = "user.deleted"
+	UserUpdated         Type = "user.updated"
+	UserRoleChanged     Type = "user.role_changed"
+	UserCategoryChanged Type = "user.category_changed"
+	UserPasswordChanged Type = "user.password_changed"
+	GroupMemberAdded    Type = "group.member_added"
+	GroupMemberRemoved  Type = "group.member_removed"
+)
+
+func AllTypes() []Type {
+	return []Type{UserCreated, UserDeleted, UserUpdated, UserRoleChanged, UserCategoryChanged, UserPasswordChanged, GroupMemberAdded, GroupMemberRemoved}
+}
+
+func (t Type) String() string { return string(t) }
+
+// Match 判断事件类型是否匹配过滤条件，支持 * 和 user.* 这样的前缀通配
+func (t Type) Match(pattern string) bool {
+	if pattern == "*" || pattern == string(t) {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(pattern, "*")
+	return ok && strings.HasPrefix(string(t), prefix)
+}
+
+// ValidatePattern 检查过滤条件是否至少能匹配一种事件
+func ValidatePattern(pattern string) bool {
+	for _, t := range AllTypes() {
+		if t.Match(pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// 事件来源。目录监听在每个实例上运行，SourceReplica 是其他 asynx 实例的写入在本实例上的回声，
+// 产生写入的实例已经以 SourceAsynx 发布过，只用于刷新本实例的缓存和事件流
+const (
+	SourceAsynx     = "asynx"
+	SourceDirectory = "directory"
+	SourceReplica   = "replica"
+)
+
+// Event 描述一次目录变更。user.* 的 Subject 为 uid，group.* 的 Subject 为组的 cn。
+// 来自目录监听的事件 Id 由条目的 entryUUID 和 entryCSN 构成，所有实例上相同，可用于去重
+type Event struct {
+	Id      string            `json:"id"`
+	Type    Type              `json:"type"`
+	Time    time.Time         `json:"time"`
+	Source  string            `json:"source"`
+	Actor   string            `json:"actor,omitempty"`
+	Subject string            `json:"subject"`
+	Data    map[string]string `json:"data,omitempty"`
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+// Bus 把事件同步分发给所有订阅者，订阅者不应阻塞
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []func(Event)
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+func (b *Bus) Subscribe(handler func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Publish 补全事件的 Id、Time 和 Source 后分发
+func (b *Bus) Publish(e Event) {
+	if e.Id == "" {
+		e.Id = uuid.NewString()
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.Source == "" {
+		e.Source = SourceAsynx
+	}
+
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(e)
+	}
+}
@@ -0,0 +1,76 @@
+package event
+
+import (
+	"slices"
+	"sync"
+)
+
+// Stream 在内存中保留最近的事件，并把新事件推送给订阅者
+type Stream struct {
+	mu          sync.Mutex
+	capacity    int
+	buffer      int
+	recent      []Event
+	subscribers map[chan Event]struct{}
+}
+
+func NewStream(capacity int, buffer int, bus *Bus) *Stream {
+	s := &Stream{
+		capacity:    max(capacity, 1),
+		buffer:      max(buffer, 1),
+		subscribers: make(map[chan Event]struct{}),
+	}
+	bus.Subscribe(s.append)
+	return s
+}
+
+func (s *Stream) append(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.recent) == s.capacity {
+		copy(s.recent, s.recent[1:])
+		s.recent[len(s.recent)-1] = e
+	} else {
+		s.recent = append(s.recent, e)
+	}
+
+	for ch := range s.subscribers {
+		select {
+		case ch <- e:
+		default:
+			// 跟不上的订阅者直接断开，由客户端带 Last-Event-ID 重连
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe 返回 lastId 之后的积压事件和后续事件的通道。
+// lastId 已被挤出缓冲区时 resumed 为 false，此时积压为空，调用方应让客户端重新拉取全量数据
+func (s *Stream) Subscribe(lastId string) (backlog []Event, events <-chan Event, cancel func(), resumed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resumed = true
+	if lastId != "" {
+		index := slices.IndexFunc(s.recent, func(e Event) bool { return e.Id == lastId })
+		if index < 0 {
+			resumed = false
+		} else {
+			backlog = slices.Clone(s.recent[index+1:])
+		}
+	}
+
+	ch := make(chan Event, s.buffer)
+	s.subscribers[ch] = struct{}{}
+	cancel = func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.subscribers[ch]; ok {
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+	}
+	return backlog, ch, cancel, resumed
+}
@@ -0,0 +1,122 @@
+package event
+
+import (
+	"fmt"
+	"testing"
+)
+
+func publishN(bus *Bus, n int) {
+	for i := range n {
+		bus.Publish(Event{Id: fmt.Sprintf("e%d", i+1), Type: UserUpdated, Subject: "2024000001"})
+	}
+}
+
+func eventIds(events []Event) []string {
+	ids := make([]string, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.Id)
+	}
+	return ids
+}
+
+func TestSubscribeResumesAfterLastEventId(t *testing.T) {
+	bus := NewBus()
+	stream := NewStream(3, 8, bus)
+	publishN(bus, 5)
+
+	tests := []struct {
+		lastId      string
+		wantBacklog string
+		wantResumed bool
+	}{
+		{"", "[]", true},
+		{"e3", "[e4 e5]", true},
+		{"e5", "[]", true},
+		// e1 已被挤出缓冲区
+		{"e1", "[]", false},
+		{"unknown", "[]", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lastId, func(t *testing.T) {
+			backlog, _, cancel, resumed := stream.Subscribe(tt.lastId)
+			defer cancel()
+			if got := fmt.Sprint(eventIds(backlog)); got != tt.wantBacklog || resumed != tt.wantResumed {
+				t.Errorf("got %s resumed=%v, want %s resumed=%v", got, resumed, tt.wantBacklog, tt.wantResumed)
+			}
+		})
+	}
+}
+
+func TestSubscriberReceivesNewEvents(t *testing.T) {
+	bus := NewBus()
+	stream := NewStream(10, 8, bus)
+	_, events, cancel, _ := stream.Subscribe("")
+	publishN(bus, 2)
+
+	for _, want := range []string{"e1", "e2"} {
+		if e := <-events; e.Id != want || e.Source != SourceAsynx || e.Time.IsZero() {
+			t.Errorf("got %+v, want %s with source and time filled", e, want)
+		}
+	}
+
+	// 取消后通道被关闭，重复取消是安全的
+	cancel()
+	cancel()
+	if _, ok := <-events; ok {
+		t.Error("channel is open after cancel")
+	}
+	publishN(bus, 1)
+}
+
+func TestSlowSubscriberIsDisconnected(t *testing.T) {
+	bus := NewBus()
+	stream := NewStream(10, 2, bus)
+	_, slow, cancel, _ := stream.Subscribe("")
+	defer cancel()
+	_, fast, cancelFast, _ := stream.Subscribe("")
+	defer cancelFast()
+
+	bus.Publish(Event{Id: "e1", Type: UserCreated})
+	<-fast
+	bus.Publish(Event{Id: "e2", Type: UserCreated})
+	<-fast
+	bus.Publish(Event{Id: "e3", Type: UserCreated})
+
+	// 缓冲区满时断开，已缓冲的事件仍可读出，之后通道关闭
+	if got := fmt.Sprint(eventIds(drain(slow))); got != "[e1 e2]" {
+		t.Errorf("slow subscriber got %s before disconnect", got)
+	}
+	if e := <-fast; e.Id != "e3" {
+		t.Errorf("fast subscriber got %+v, want e3", e)
+	}
+}
+
+func drain(ch <-chan Event) []Event {
+	var events []Event
+	for e := range ch {
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestTypeMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    bool
+	}{
+		{"*", true},
+		{"user.*", true},
+		{"user.role_changed", true},
+		{"user.created", false},
+		{"group.*", false},
+		{"user", false},
+	}
+	for _, tt := range tests {
+		if got := UserRoleChanged.Match(tt.pattern); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+	if ValidatePattern("users.*") || !ValidatePattern("group.*") {
+		t.Error("ValidatePattern accepted an unknown pattern or rejected a known one")
+	}
+}
@@ -0,0 +1,99 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"golang.org/x/net/html"
+)
+
+type ContentFormat string
+
+const (
+	ContentMarkdown ContentFormat = "markdown"
+	ContentHtml     ContentFormat = "html"
+)
+
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// RenderContent 将管理员编写的正文转换为 HTML 和纯文本两种形式。
+// Markdown 中的原始 HTML 会被忽略；html 格式则原样使用，纯文本由其中的文字提取。
+func RenderContent(format ContentFormat, body string) (htmltemplate.HTML, string, error) {
+	switch format {
+	case "", ContentMarkdown:
+		var b bytes.Buffer
+		if err := markdown.Convert([]byte(body), &b); err != nil {
+			return "", "", err
+		}
+		return htmltemplate.HTML(b.String()), strings.TrimSpace(body), nil
+	case ContentHtml:
+		text, err := htmlToText(body)
+		if err != nil {
+			return "", "", err
+		}
+		return htmltemplate.HTML(body), text, nil
+	default:
+		return "", "", fmt.Errorf("unsupported content format: %s", format)
+	}
+}
+
+var (
+	spaces     = regexp.MustCompile(`\s+`)
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+func htmlToText(body string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			b.WriteString(spaces.ReplaceAllString(n.Data, " "))
+			return
+		case html.ElementNode:
+			switch n.Data {
+			case "script", "style", "head":
+				return
+			case "br":
+				b.WriteString("\n")
+				return
+			case "li":
+				b.WriteString("\n- ")
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "p", "div", "h1", "h2", "h3", "h4", "h5", "h6", "ul", "ol", "table", "tr", "blockquote", "pre":
+				b.WriteString("\n\n")
+			case "a":
+				for _, attr := range n.Attr {
+					if attr.Key == "href" && attr.Val != "" {
+						fmt.Fprintf(&b, " (%s)", attr.Val)
+					}
+				}
+			}
+		}
+	}
+	walk(doc)
+
+	lines := strings.Split(b.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")), nil
+}
@@ -0,0 +1,122 @@
+package mail
+
+import htmltemplate "html/template"
+
+// Account 是所有模板共用的收件人信息
+type Account struct {
+	Surname   string
+	GivenName string
+	Username  string
+}
+
+// Welcome 的 Password 为空时表示密码由用户自己设置
+type Welcome struct {
+	Account
+	Password string
+}
+
+// PasswordReset 用于管理员重置密码，Password 为空时表示不在邮件中附带新密码
+type PasswordReset struct {
+	Account
+	Password string
+}
+
+type RoleChanged struct {
+	Account
+	From string
+	To   string
+}
+
+// RoleExpiring 提醒用户临时授予的角色即将到期，到期后恢复为 Previous
+type RoleExpiring struct {
+	Account
+	Role      string
+	Previous  string
+	ExpiresAt string
+}
+
+// AccountExpiring 提醒用户账号即将到期
+type AccountExpiring struct {
+	Account
+	ExpiresAt string
+}
+
+// AlumniTransition 提醒成员账号将在 Date 转为 alumni，角色降为 Role
+type AlumniTransition struct {
+	Account
+	Date string
+	Role string
+}
+
+type CategoryChanged struct {
+	Account
+	From string
+	To   string
+}
+
+type AccountDisabled struct {
+	Account
+	Reason string
+}
+
+type AccountDeleted struct {
+	Account
+}
+
+// RegistrationVerify 发给自助注册的申请人，Link 用于验证邮箱
+type RegistrationVerify struct {
+	Account
+	Link      string
+	ExpiresAt string
+}
+
+// InvitationVerify 发给使用限定域名邀请的受邀人，Link 用于验证邮箱并继续注册
+type InvitationVerify struct {
+	Account
+	Link      string
+	ExpiresAt string
+}
+
+// Broadcast 是管理员群发的邮件，Html 和 Text 由 RenderContent 生成
+type Broadcast struct {
+	Account
+	Subject string
+	Html    htmltemplate.HTML
+	Text    string
+}
+
+var sampleAccount = Account{Surname: "张", GivenName: "三", Username: "2024000001"}
+
+// Sample 返回用于预览的示例数据
+func Sample(name Template) any {
+	switch name {
+	case TemplateWelcome:
+		return Welcome{Account: sampleAccount, Password: "correct-horse-battery-staple"}
+	case TemplatePasswordReset:
+		return PasswordReset{Account: sampleAccount, Password: "correct-horse-battery-staple"}
+	case TemplateRoleChanged:
+		return RoleChanged{Account: sampleAccount, From: "restricted", To: "default"}
+	case TemplateRoleExpiring:
+		return RoleExpiring{Account: sampleAccount, Role: "admin", Previous: "default", ExpiresAt: "2025-01-31 23:59 CST"}
+	case TemplateAccountExpiring:
+		return AccountExpiring{Account: sampleAccount, ExpiresAt: "2025-07-01 00:00 UTC"}
+	case TemplateAlumniTransition:
+		return AlumniTransition{Account: sampleAccount, Date: "2028-07-01", Role: "restricted"}
+	case TemplateCategoryChanged:
+		return CategoryChanged{Account: sampleAccount, From: "external", To: "member"}
+	case TemplateAccountDisabled:
+		return AccountDisabled{Account: sampleAccount, Reason: "账号已过期"}
+	case TemplateAccountDeleted:
+		return AccountDeleted{Account: sampleAccount}
+	case TemplateRegistrationVerify:
+		return RegistrationVerify{Account: sampleAccount, Link: "https://asynx.internal.asynclab.club/register?token=0123456789abcdef", ExpiresAt: "2025-01-02 15:04 CST"}
+	case TemplateInvitationVerify:
+		return InvitationVerify{Account: Account{Surname: "张", GivenName: "三"}, Link: "https://asynx.internal.asynclab.club/invite?token=0123456789abcdef&verification=fedcba9876543210", ExpiresAt: "2025-01-02 15:04 CST"}
+	case TemplateBroadcast:
+		content := "服务器将于 **本周六 22:00** 停机维护，预计持续两小时。\n\n- 维护期间无法登录\n- 请提前保存工作"
+		html, text, _ := RenderContent(ContentMarkdown, content)
+		return Broadcast{Account: sampleAccount, Subject: "服务器维护通知", Html: html, Text: text}
+	default:
+		return sampleAccount
+	}
+}
@@ -0,0 +1,159 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	texttemplate "text/template"
+
+	"asynclab.club/asynx/backend/pkg/config"
+)
+
+type Template string
+
+const (
+	TemplateWelcome            Template = "welcome"
+	TemplatePasswordReset      Template = "password-reset"
+	TemplateRoleChanged        Template = "role-changed"
+	TemplateRoleExpiring       Template = "role-expiring"
+	TemplateAccountExpiring    Template = "account-expiring"
+	TemplateAlumniTransition   Template = "alumni-transition"
+	TemplateCategoryChanged    Template = "category-changed"
+	TemplateAccountDisabled    Template = "account-disabled"
+	TemplateAccountDeleted     Template = "account-deleted"
+	TemplateRegistrationVerify Template = "registration-verify"
+	TemplateInvitationVerify   Template = "invitation-verify"
+	TemplateBroadcast          Template = "broadcast"
+)
+
+func AllTemplates() []Template {
+	return []Template{TemplateWelcome, TemplatePasswordReset, TemplateRoleChanged, TemplateRoleExpiring, TemplateAccountExpiring, TemplateAlumniTransition, TemplateCategoryChanged, TemplateAccountDisabled, TemplateAccountDeleted, TemplateRegistrationVerify, TemplateInvitationVerify, TemplateBroadcast}
+}
+
+func (t Template) String() string { return string(t) }
+
+func GetTemplateFromName(name string) (Template, error) {
+	t := Template(name)
+	if !slices.Contains(AllTemplates(), t) {
+		return "", fmt.Errorf("unknown mail template: %s", name)
+	}
+	return t, nil
+}
+
+var Languages = []string{"zh", "en"}
+
+// Rendered 是渲染完成的邮件，Text 为纯文本备选正文
+type Rendered struct {
+	Subject string `json:"subject"`
+	Html    string `json:"html"`
+	Text    string `json:"text"`
+}
+
+// Templates 按名称和语言查找并渲染邮件模板。
+// 每个模板由 <name>.<lang>.html 和 <name>.<lang>.txt 组成，txt 中以 {{define "subject"}} 定义标题；
+// html 只需定义 "title" 和 "content"，外框来自 layout.<lang>.html。
+// 每次渲染都重新读取文件，覆盖目录中的修改无需重启即可生效。
+type Templates struct {
+	cfg      *config.ConfigMailTemplate
+	embedded fs.FS
+}
+
+func NewTemplates(cfg *config.ConfigMailTemplate, embedded fs.FS) (*Templates, error) {
+	if !slices.Contains(Languages, cfg.DefaultLanguage) {
+		return nil, fmt.Errorf("unsupported default mail language: %s", cfg.DefaultLanguage)
+	}
+	return &Templates{cfg: cfg, embedded: embedded}, nil
+}
+
+// Language 将用户偏好（如 en-US、zh_CN）规范为支持的语言，无法识别时使用默认语言
+func (t *Templates) Language(preferred string) string {
+	lang, _, _ := strings.Cut(strings.ToLower(strings.ReplaceAll(preferred, "_", "-")), "-")
+	if slices.Contains(Languages, lang) {
+		return lang
+	}
+	return t.cfg.DefaultLanguage
+}
+
+// readFile 优先读取覆盖目录中的文件
+func (t *Templates) readFile(file string) ([]byte, error) {
+	if t.cfg.Dir != "" {
+		content, err := os.ReadFile(filepath.Join(t.cfg.Dir, file))
+		if err == nil {
+			return content, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return fs.ReadFile(t.embedded, file)
+}
+
+func (t *Templates) funcs(lang string) map[string]any {
+	return map[string]any{
+		"lang": func() string { return lang },
+		"site": func() string { return t.cfg.SiteUrl },
+	}
+}
+
+func (t *Templates) renderHtml(name Template, lang string, data any) (string, error) {
+	tmpl := htmltemplate.New(name.String()).Funcs(t.funcs(lang))
+	for _, file := range []string{"style.html", fmt.Sprintf("layout.%s.html", lang), fmt.Sprintf("%s.%s.html", name, lang)} {
+		content, err := t.readFile(file)
+		if err != nil {
+			return "", err
+		}
+		if tmpl, err = tmpl.Parse(string(content)); err != nil {
+			return "", fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+	}
+
+	var b strings.Builder
+	if err := tmpl.ExecuteTemplate(&b, "layout", data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func (t *Templates) renderText(name Template, lang string, data any) (subject, text string, err error) {
+	file := fmt.Sprintf("%s.%s.txt", name, lang)
+	content, err := t.readFile(file)
+	if err != nil {
+		return "", "", err
+	}
+	tmpl, err := texttemplate.New(file).Funcs(t.funcs(lang)).Parse(string(content))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+
+	var b strings.Builder
+	if err := tmpl.ExecuteTemplate(&b, "subject", data); err != nil {
+		return "", "", err
+	}
+	subject = strings.TrimSpace(b.String())
+
+	b.Reset()
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", "", err
+	}
+	return subject, strings.TrimSpace(b.String()) + "\n", nil
+}
+
+// Render 渲染指定模板，lang 为空或不支持时使用默认语言
+func (t *Templates) Render(name Template, lang string, data any) (*Rendered, error) {
+	lang = t.Language(lang)
+
+	subject, text, err := t.renderText(name, lang, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render mail template %s (%s): %w", name, lang, err)
+	}
+	html, err := t.renderHtml(name, lang, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render mail template %s (%s): %w", name, lang, err)
+	}
+	return &Rendered{Subject: subject, Html: html, Text: text}, nil
+}
@@ -0,0 +1,108 @@
+package mail
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"asynclab.club/asynx/backend/pkg/config"
+)
+
+func newTestTemplates(t *testing.T, dir string) *Templates {
+	t.Helper()
+	templates, err := NewTemplates(&config.ConfigMailTemplate{Dir: dir, DefaultLanguage: "zh", SiteUrl: "https://asynx.example.org/"}, os.DirFS("../../../templates/mail"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return templates
+}
+
+func TestAllTemplatesRenderInAllLanguages(t *testing.T) {
+	templates := newTestTemplates(t, "")
+	for _, name := range AllTemplates() {
+		for _, lang := range Languages {
+			t.Run(name.String()+"."+lang, func(t *testing.T) {
+				rendered, err := templates.Render(name, lang, Sample(name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if rendered.Subject == "" || strings.Contains(rendered.Subject, "\n") {
+					t.Errorf("got subject %q", rendered.Subject)
+				}
+				if !strings.Contains(rendered.Html, `<html lang="`+lang) || strings.Contains(rendered.Html, "<no value>") {
+					t.Errorf("html is not a complete %s document", lang)
+				}
+				if rendered.Text == "" || strings.Contains(rendered.Text, "<no value>") || strings.Contains(rendered.Text, "<p>") {
+					t.Errorf("got text part %q", rendered.Text)
+				}
+			})
+		}
+	}
+}
+
+func TestTemplateEscapesUserData(t *testing.T) {
+	templates := newTestTemplates(t, "")
+	data := Welcome{Account: Account{Surname: "<script>", GivenName: "x", Username: "2024000001"}}
+	rendered, err := templates.Render(TemplateWelcome, "en", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(rendered.Html, "<script>") {
+		t.Error("user data was not escaped in the html part")
+	}
+	// 纯文本中不转义
+	if !strings.Contains(rendered.Text, "<script>") {
+		t.Errorf("got text part %q", rendered.Text)
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	templates := newTestTemplates(t, "")
+	for preferred, want := range map[string]string{"en": "en", "en-US": "en", "EN_gb": "en", "zh-CN": "zh", "fr": "zh", "": "zh"} {
+		if got := templates.Language(preferred); got != want {
+			t.Errorf("Language(%q) = %s, want %s", preferred, got, want)
+		}
+	}
+	if _, err := NewTemplates(&config.ConfigMailTemplate{DefaultLanguage: "fr"}, os.DirFS(".")); err == nil {
+		t.Error("unsupported default language was accepted")
+	}
+}
+
+func TestOverrideDirectoryTakesPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	override := "{{define \"subject\"}}Custom {{.Username}}{{end -}}\nHello {{.Username}}\n"
+	if err := os.WriteFile(filepath.Join(dir, "welcome.en.txt"), []byte(override), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	templates := newTestTemplates(t, dir)
+
+	rendered, err := templates.Render(TemplateWelcome, "en", Sample(TemplateWelcome))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rendered.Subject != "Custom 2024000001" || rendered.Text != "Hello 2024000001\n" {
+		t.Errorf("got %q / %q, want the overridden text template", rendered.Subject, rendered.Text)
+	}
+	// 没有覆盖的文件仍使用内置模板
+	if zh, err := templates.Render(TemplateWelcome, "zh", Sample(TemplateWelcome)); err != nil || strings.HasPrefix(zh.Subject, "Custom") {
+		t.Errorf("got %+v, %v for a language without override", zh, err)
+	}
+
+	// 覆盖文件被修改后无需重启即可生效
+	if err := os.WriteFile(filepath.Join(dir, "welcome.en.txt"), []byte("{{define \"subject\"}}Changed{{end -}}\nx\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := templates.Render(TemplateWelcome, "en", Sample(TemplateWelcome)); err != nil || changed.Subject != "Changed" {
+		t.Errorf("got %+v, %v after editing the override", changed, err)
+	}
+}
+
+func TestGetTemplateFromName(t *testing.T) {
+	if name, err := GetTemplateFromName("password-reset"); err != nil || name != TemplatePasswordReset {
+		t.Errorf("got %s, %v", name, err)
+	}
+	if _, err := GetTemplateFromName("../layout"); err == nil {
+		t.Error("unknown template name was accepted")
+	}
+}
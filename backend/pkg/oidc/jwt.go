@@ -0,0 +1,163 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// 只支持 RS256，所有客户端库都实现了它
+
+const (
+	typeJwt         = "JWT"
+	typeAccessToken = "at+jwt" // RFC 9068，防止把 ID Token 当作访问令牌使用
+)
+
+var errInvalidToken = errors.New("invalid token")
+
+type signingKey struct {
+	private *rsa.PrivateKey
+	kid     string
+}
+
+// loadSigningKey 读取 PEM 格式的 RSA 私钥。所有实例必须使用同一个密钥，否则一个实例签发的令牌无法用另一个实例的 JWKS 验证，
+// 因此不会自动生成
+func loadSigningKey(path string) (*signingKey, error) {
+	if path == "" {
+		return nil, errors.New("OIDC_SIGNING_KEY_FILE is required, generate a key shared by all instances with: openssl genpkey -algorithm RSA -pkeyopt rsa_keygen_bits:2048")
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	private, err := parsePrivateKey(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(der)
+	return &signingKey{private: private, kid: base64.RawURLEncoding.EncodeToString(sum[:12])}, nil
+}
+
+func parsePrivateKey(content []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	private, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("signing key must be an RSA key")
+	}
+	return private, nil
+}
+
+// Jwk 是 JWKS 中的一个公钥
+type Jwk struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func (k *signingKey) jwk() Jwk {
+	public := k.private.PublicKey
+	return Jwk{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: "RS256",
+		Kid: k.kid,
+		N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+	}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+func (k *signingKey) sign(typ string, claims map[string]any) (string, error) {
+	header, err := json.Marshal(jwtHeader{Alg: "RS256", Typ: typ, Kid: k.kid})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, k.private, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// verify 校验签名和 typ，返回载荷，过期等声明由调用方检查
+func (k *signingKey) verify(token string, typ string, claims any) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return errInvalidToken
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return err
+	}
+	if header.Alg != "RS256" || header.Kid != k.kid || !strings.EqualFold(header.Typ, typ) {
+		return errInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return errInvalidToken
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&k.private.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		return errInvalidToken
+	}
+	return decodeSegment(parts[1], claims)
+}
+
+func decodeSegment(segment string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errInvalidToken
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return errInvalidToken
+	}
+	return nil
+}
+
+// halfHash 计算 at_hash：SHA-256 摘要左半部分的 base64url 编码
+func halfHash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
@@ -0,0 +1,483 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/persist"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	ScopeOpenId  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+	// ScopeGroups 提供 groups、role 和 category 声明
+	ScopeGroups = "groups"
+)
+
+func Scopes() []string { return []string{ScopeOpenId, ScopeProfile, ScopeEmail, ScopeGroups} }
+
+const (
+	PathAuthorize = "/oidc/authorize"
+	PathToken     = "/oidc/token"
+	PathUserInfo  = "/oidc/userinfo"
+	PathJwks      = "/oidc/jwks"
+	PathLogout    = "/oidc/logout"
+	PathDiscovery = "/.well-known/openid-configuration"
+)
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+type Client struct {
+	Id                     string    `json:"id"`
+	Name                   string    `json:"name"`
+	Secret                 string    `json:"secret,omitempty"` // 只在创建或轮换时返回
+	SecretHash             string    `json:"secretHash,omitempty"`
+	Public                 bool      `json:"public"` // 公共客户端没有密钥，必须使用 PKCE
+	RedirectUris           []string  `json:"redirectUris"`
+	PostLogoutRedirectUris []string  `json:"postLogoutRedirectUris"`
+	Roles                  []string  `json:"roles"`       // 允许登录的角色，为空时不限制
+	SkipConsent            bool      `json:"skipConsent"` // 实验室内部应用可以跳过授权确认
+	CreatedAt              time.Time `json:"createdAt"`
+	UpdatedAt              time.Time `json:"updatedAt"`
+}
+
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Client) VerifySecret(secret string) bool {
+	return c.SecretHash != "" && subtle.ConstantTimeCompare([]byte(HashSecret(secret)), []byte(c.SecretHash)) == 1
+}
+
+// Consent 记录用户同意某个客户端使用的 scope
+type Consent struct {
+	Uid       string    `json:"uid"`
+	ClientId  string    `json:"clientId"`
+	Scopes    []string  `json:"scopes"`
+	GrantedAt time.Time `json:"grantedAt"`
+}
+
+func (c *Consent) Covers(scopes []string) bool {
+	for _, scope := range scopes {
+		if !slices.Contains(c.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+func consentKey(uid, clientId string) string { return uid + ":" + clientId }
+
+// AuthorizationRequest 是已通过校验、等待用户登录和授权的请求
+type AuthorizationRequest struct {
+	Id            string    `json:"id"`
+	ClientId      string    `json:"clientId"`
+	RedirectUri   string    `json:"redirectUri"`
+	Scopes        []string  `json:"scopes"`
+	State         string    `json:"state,omitempty"`
+	Nonce         string    `json:"nonce,omitempty"`
+	CodeChallenge string    `json:"codeChallenge,omitempty"`
+	ForceConsent  bool      `json:"forceConsent,omitempty"` // prompt=consent
+	ExpiresAt     time.Time `json:"expiresAt"`
+	Decided       bool      `json:"decided,omitempty"` // 用户已做出决定，请求不能再次使用
+}
+
+// authorizationCode 以授权码的摘要为键保存，Used 保证授权码只能被一个实例兑换一次
+type authorizationCode struct {
+	Request   AuthorizationRequest `json:"request"`
+	Uid       string               `json:"uid"`
+	ExpiresAt time.Time            `json:"expiresAt"`
+	Used      bool                 `json:"used,omitempty"`
+}
+
+var errUsed = errors.New("already used")
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func NewError(code string, format string, args ...any) *Error {
+	return &Error{Code: code, Description: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string { return e.Code + ": " + e.Description }
+
+const (
+	ErrInvalidRequest          = "invalid_request"
+	ErrInvalidClient           = "invalid_client"
+	ErrInvalidGrant            = "invalid_grant"
+	ErrUnauthorizedClient      = "unauthorized_client"
+	ErrUnsupportedGrantType    = "unsupported_grant_type"
+	ErrUnsupportedResponseType = "unsupported_response_type"
+	ErrInvalidScope            = "invalid_scope"
+	ErrAccessDenied            = "access_denied"
+	ErrLoginRequired           = "login_required"
+	ErrConsentRequired         = "consent_required"
+	ErrInvalidToken            = "invalid_token"
+)
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+// Provider 保存客户端、用户授权和签名密钥，并签发授权码和令牌。
+// 客户端、授权、授权请求和授权码都保存在共享存储中，授权和换取令牌可以落到不同的实例上；
+// 签名密钥来自所有实例共享的文件
+type Provider struct {
+	cfg      *config.ConfigOidc
+	key      *signingKey
+	clients  *persist.SharedCollection[Client]
+	consents *persist.SharedCollection[Consent]
+	requests *persist.SharedCollection[AuthorizationRequest]
+	codes    *persist.SharedCollection[authorizationCode]
+}
+
+func NewProvider(cfg *config.ConfigOidc, shared persist.SharedBackend) (*Provider, error) {
+	key, err := loadSigningKey(cfg.SigningKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &Provider{
+		cfg:      cfg,
+		key:      key,
+		clients:  persist.NewSharedCollection[Client](shared, "oidc-clients"),
+		consents: persist.NewSharedCollection[Consent](shared, "oidc-consents"),
+		requests: persist.NewSharedCollection[AuthorizationRequest](shared, "oidc-requests"),
+		codes:    persist.NewSharedCollection[authorizationCode](shared, "oidc-codes"),
+	}, nil
+}
+
+func (p *Provider) Issuer() string { return strings.TrimSuffix(p.cfg.Issuer, "/") }
+
+type Discovery struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JwksUri                           string   `json:"jwks_uri"`
+	EndSessionEndpoint                string   `json:"end_session_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	ResponseModesSupported            []string `json:"response_modes_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IdTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+	AuthorizationResponseIss          bool     `json:"authorization_response_iss_parameter_supported"`
+}
+
+func (p *Provider) Discovery() *Discovery {
+	issuer := p.Issuer()
+	return &Discovery{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + PathAuthorize,
+		TokenEndpoint:                     issuer + PathToken,
+		UserInfoEndpoint:                  issuer + PathUserInfo,
+		JwksUri:                           issuer + PathJwks,
+		EndSessionEndpoint:                issuer + PathLogout,
+		ScopesSupported:                   Scopes(),
+		ResponseTypesSupported:            []string{"code"},
+		ResponseModesSupported:            []string{"query"},
+		GrantTypesSupported:               []string{"authorization_code"},
+		SubjectTypesSupported:             []string{"public"},
+		IdTokenSigningAlgValuesSupported:  []string{"RS256"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported: []string{
+			"iss", "sub", "aud", "exp", "iat", "nonce", "at_hash",
+			"name", "family_name", "given_name", "preferred_username", "locale",
+			"email", "groups", "role", "category",
+		},
+		AuthorizationResponseIss: true,
+	}
+}
+
+func (p *Provider) Jwks() map[string][]Jwk {
+	return map[string][]Jwk{"keys": {p.key.jwk()}}
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+func (p *Provider) ListClients() ([]Client, error) { return p.clients.List() }
+
+func (p *Provider) GetClient(id string) (Client, bool, error) { return p.clients.Get(id) }
+
+func (p *Provider) PutClient(client Client) error {
+	client.Secret = ""
+	return p.clients.Put(client.Id, client)
+}
+
+// DeleteClient 删除客户端以及所有用户对它的授权
+func (p *Provider) DeleteClient(id string) error {
+	if err := p.clients.Delete(id); err != nil {
+		return err
+	}
+	_, err := p.consents.DeleteFunc(func(_ string, consent Consent) bool { return consent.ClientId == id })
+	return err
+}
+
+func (p *Provider) GetConsent(uid, clientId string) (Consent, bool, error) {
+	return p.consents.Get(consentKey(uid, clientId))
+}
+
+func (p *Provider) PutConsent(consent Consent) error {
+	return p.consents.Put(consentKey(consent.Uid, consent.ClientId), consent)
+}
+
+func (p *Provider) ListConsents(uid string) ([]Consent, error) {
+	all, err := p.consents.List()
+	if err != nil {
+		return nil, err
+	}
+	var consents []Consent
+	for _, consent := range all {
+		if consent.Uid == uid {
+			consents = append(consents, consent)
+		}
+	}
+	return consents, nil
+}
+
+func (p *Provider) DeleteConsent(uid, clientId string) error {
+	return p.consents.Delete(consentKey(uid, clientId))
+}
+
+// ForgetUser 删除用户的所有授权
+func (p *Provider) ForgetUser(uid string) error {
+	_, err := p.consents.DeleteFunc(func(_ string, consent Consent) bool { return consent.Uid == uid })
+	return err
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+func randomToken() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Purge 删除已过期的授权请求和授权码，返回删除数量
+func (p *Provider) Purge(now time.Time) (int, error) {
+	requests, err := p.requests.DeleteFunc(func(_ string, request AuthorizationRequest) bool { return now.After(request.ExpiresAt) })
+	if err != nil {
+		return requests, err
+	}
+	codes, err := p.codes.DeleteFunc(func(_ string, c authorizationCode) bool { return now.After(c.ExpiresAt) })
+	return requests + codes, err
+}
+
+// PutRequest 保存等待用户授权的请求，返回请求ID
+func (p *Provider) PutRequest(request AuthorizationRequest) (*AuthorizationRequest, error) {
+	request.Id = uuid.NewString()
+	request.ExpiresAt = time.Now().Add(p.cfg.RequestTTL)
+	if err := p.requests.Create(request.Id, request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (p *Provider) GetRequest(id string) (AuthorizationRequest, bool, error) {
+	request, ok, err := p.requests.Get(id)
+	if err != nil || !ok || request.Decided || time.Now().After(request.ExpiresAt) {
+		return AuthorizationRequest{}, false, err
+	}
+	return request, true, nil
+}
+
+// TakeRequest 取出并删除请求，每个请求只能被决定一次，多个实例同时决定时只有一个成功
+func (p *Provider) TakeRequest(id string) (AuthorizationRequest, bool, error) {
+	var request AuthorizationRequest
+	err := p.requests.Update(id, func(r *AuthorizationRequest) error {
+		if r.Decided {
+			return errUsed
+		}
+		r.Decided = true
+		request = *r
+		return nil
+	})
+	if errors.Is(err, persist.ErrNotFound) || errors.Is(err, errUsed) {
+		return AuthorizationRequest{}, false, nil
+	}
+	if err != nil {
+		return AuthorizationRequest{}, false, err
+	}
+	if err := p.requests.Delete(id); err != nil {
+		logrus.Warnf("Failed to remove authorization request %s: %v", id, err)
+	}
+	if time.Now().After(request.ExpiresAt) {
+		return AuthorizationRequest{}, false, nil
+	}
+	return request, true, nil
+}
+
+// IssueCode 为已授权的请求签发一次性授权码，只保存授权码的摘要
+func (p *Provider) IssueCode(request AuthorizationRequest, uid string) (string, error) {
+	code := randomToken()
+	c := authorizationCode{Request: request, Uid: uid, ExpiresAt: time.Now().Add(p.cfg.CodeTTL)}
+	if err := p.codes.Create(HashSecret(code), c); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// takeCode 标记授权码已使用并删除，多个实例同时兑换时只有一个成功
+func (p *Provider) takeCode(code string) (*authorizationCode, error) {
+	key := HashSecret(code)
+	var c authorizationCode
+	err := p.codes.Update(key, func(stored *authorizationCode) error {
+		if stored.Used {
+			return errUsed
+		}
+		stored.Used = true
+		c = *stored
+		return nil
+	})
+	if errors.Is(err, persist.ErrNotFound) || errors.Is(err, errUsed) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := p.codes.Delete(key); err != nil {
+		logrus.Warnf("Failed to remove used authorization code: %v", err)
+	}
+	return &c, nil
+}
+
+// ExchangeCode 校验并消费授权码，返回原始请求和用户
+func (p *Provider) ExchangeCode(code, clientId, redirectUri, codeVerifier string) (*AuthorizationRequest, string, error) {
+	c, err := p.takeCode(code)
+	if err != nil {
+		return nil, "", err
+	}
+	if c == nil || time.Now().After(c.ExpiresAt) {
+		return nil, "", NewError(ErrInvalidGrant, "authorization code is invalid or expired")
+	}
+	if c.Request.ClientId != clientId {
+		return nil, "", NewError(ErrInvalidGrant, "authorization code was issued to another client")
+	}
+	if c.Request.RedirectUri != redirectUri {
+		return nil, "", NewError(ErrInvalidGrant, "redirect_uri does not match the authorization request")
+	}
+	if c.Request.CodeChallenge != "" {
+		sum := sha256.Sum256([]byte(codeVerifier))
+		challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+		if subtle.ConstantTimeCompare([]byte(challenge), []byte(c.Request.CodeChallenge)) != 1 {
+			return nil, "", NewError(ErrInvalidGrant, "code_verifier does not match code_challenge")
+		}
+	} else if codeVerifier != "" {
+		return nil, "", NewError(ErrInvalidGrant, "code_verifier was sent without code_challenge")
+	}
+	return &c.Request, c.Uid, nil
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	IdToken     string `json:"id_token"`
+	Scope       string `json:"scope"`
+}
+
+// AccessClaims 是访问令牌的载荷
+type AccessClaims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	Audience  string `json:"aud"`
+	ClientId  string `json:"client_id"`
+	Scope     string `json:"scope"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	Id        string `json:"jti"`
+}
+
+func (c *AccessClaims) Scopes() []string { return strings.Fields(c.Scope) }
+
+// IssueTokens 签发访问令牌和 ID Token，claims 为按 scope 筛选后的用户声明
+func (p *Provider) IssueTokens(request *AuthorizationRequest, uid string, claims map[string]any) (*TokenResponse, error) {
+	now := time.Now()
+	exp := now.Add(p.cfg.TokenTTL)
+	scope := strings.Join(request.Scopes, " ")
+
+	accessToken, err := p.key.sign(typeAccessToken, map[string]any{
+		"iss":       p.Issuer(),
+		"sub":       uid,
+		"aud":       request.ClientId,
+		"client_id": request.ClientId,
+		"scope":     scope,
+		"iat":       now.Unix(),
+		"exp":       exp.Unix(),
+		"jti":       uuid.NewString(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	idClaims := make(map[string]any, len(claims)+7)
+	for k, v := range claims {
+		idClaims[k] = v
+	}
+	idClaims["iss"] = p.Issuer()
+	idClaims["sub"] = uid
+	idClaims["aud"] = request.ClientId
+	idClaims["iat"] = now.Unix()
+	idClaims["exp"] = exp.Unix()
+	idClaims["at_hash"] = halfHash(accessToken)
+	if request.Nonce != "" {
+		idClaims["nonce"] = request.Nonce
+	}
+	idToken, err := p.key.sign(typeJwt, idClaims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(p.cfg.TokenTTL.Seconds()),
+		IdToken:     idToken,
+		Scope:       scope,
+	}, nil
+}
+
+func (p *Provider) ParseAccessToken(token string) (*AccessClaims, error) {
+	var claims AccessClaims
+	if err := p.key.verify(token, typeAccessToken, &claims); err != nil {
+		return nil, NewError(ErrInvalidToken, "access token is invalid")
+	}
+	if claims.Issuer != p.Issuer() || time.Now().Unix() >= claims.ExpiresAt {
+		return nil, NewError(ErrInvalidToken, "access token is expired")
+	}
+	return &claims, nil
+}
+
+// ParseIdTokenHint 校验登出请求中的 id_token_hint，允许已过期的 ID Token，返回用户和客户端
+func (p *Provider) ParseIdTokenHint(token string) (string, string, error) {
+	var claims struct {
+		Issuer   string `json:"iss"`
+		Subject  string `json:"sub"`
+		Audience string `json:"aud"`
+	}
+	if err := p.key.verify(token, typeJwt, &claims); err != nil || claims.Issuer != p.Issuer() {
+		return "", "", NewError(ErrInvalidRequest, "id_token_hint is invalid")
+	}
+	return claims.Subject, claims.Audience, nil
+}
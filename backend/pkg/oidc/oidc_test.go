@@ -0,0 +1,216 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/persist"
+)
+
+// writeTestKey 生成一个 RSA 私钥写入临时文件
+func writeTestKey(t *testing.T) string {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "signing-key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newTestProviders 创建共享同一个存储和签名密钥的两个实例
+func newTestProviders(t *testing.T) (*Provider, *Provider) {
+	t.Helper()
+	cfg := &config.ConfigOidc{
+		Issuer:         "https://id.example.org",
+		SigningKeyFile: writeTestKey(t),
+		CodeTTL:        time.Minute,
+		RequestTTL:     10 * time.Minute,
+		TokenTTL:       time.Hour,
+	}
+	shared := persist.NewMemoryBackend()
+	replicaA, err := NewProvider(cfg, shared)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replicaB, err := NewProvider(cfg, shared)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return replicaA, replicaB
+}
+
+func challengeOf(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestSigningKeyIsRequired(t *testing.T) {
+	_, err := NewProvider(&config.ConfigOidc{Issuer: "https://id.example.org"}, persist.NewMemoryBackend())
+	if err == nil {
+		t.Fatal("provider started without a signing key")
+	}
+}
+
+func TestExchangeCodeWithPkce(t *testing.T) {
+	const verifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	request := AuthorizationRequest{
+		ClientId:      "app",
+		RedirectUri:   "https://app.example.org/callback",
+		Scopes:        []string{ScopeOpenId},
+		CodeChallenge: challengeOf(verifier),
+	}
+
+	tests := []struct {
+		name                            string
+		clientId, redirectUri, verifier string
+		wantErr                         bool
+	}{
+		{"matching verifier", "app", request.RedirectUri, verifier, false},
+		{"wrong verifier", "app", request.RedirectUri, "not-the-verifier", true},
+		{"missing verifier", "app", request.RedirectUri, "", true},
+		{"another client", "other", request.RedirectUri, verifier, true},
+		{"another redirect uri", "app", "https://evil.example.org/callback", verifier, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer, exchanger := newTestProviders(t)
+			code, err := issuer.IssueCode(request, "2024000001")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, uid, err := exchanger.ExchangeCode(code, tt.clientId, tt.redirectUri, tt.verifier)
+			if tt.wantErr {
+				var oidcErr *Error
+				if !errors.As(err, &oidcErr) || oidcErr.Code != ErrInvalidGrant {
+					t.Fatalf("err = %v, want invalid_grant", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if uid != "2024000001" || got.ClientId != "app" {
+				t.Errorf("exchanged uid = %s client = %s", uid, got.ClientId)
+			}
+		})
+	}
+}
+
+func TestVerifierWithoutChallengeIsRejected(t *testing.T) {
+	provider, _ := newTestProviders(t)
+	request := AuthorizationRequest{ClientId: "app", RedirectUri: "https://app.example.org/callback"}
+	code, err := provider.IssueCode(request, "2024000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := provider.ExchangeCode(code, "app", request.RedirectUri, "verifier"); err == nil {
+		t.Error("code_verifier accepted for a request without code_challenge")
+	}
+}
+
+func TestCodeIsExchangedOnceAcrossInstances(t *testing.T) {
+	replicaA, replicaB := newTestProviders(t)
+	request := AuthorizationRequest{ClientId: "app", RedirectUri: "https://app.example.org/callback"}
+	code, err := replicaA.IssueCode(request, "2024000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	exchanged := 0
+	for _, provider := range []*Provider{replicaA, replicaB, replicaA, replicaB} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := provider.ExchangeCode(code, "app", request.RedirectUri, ""); err == nil {
+				mu.Lock()
+				exchanged++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if exchanged != 1 {
+		t.Errorf("code exchanged %d times, want 1", exchanged)
+	}
+}
+
+func TestRequestIsDecidedOnceAcrossInstances(t *testing.T) {
+	replicaA, replicaB := newTestProviders(t)
+	pending, err := replicaA.PutRequest(AuthorizationRequest{ClientId: "app", RedirectUri: "https://app.example.org/callback", State: "xyz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok, err := replicaB.GetRequest(pending.Id)
+	if err != nil || !ok || got.State != "xyz" || got.RedirectUri != pending.RedirectUri {
+		t.Fatalf("request on another instance = %+v, %v, %v", got, ok, err)
+	}
+	if _, ok, err := replicaB.TakeRequest(pending.Id); err != nil || !ok {
+		t.Fatalf("take = %v, %v", ok, err)
+	}
+	if _, ok, err := replicaA.TakeRequest(pending.Id); err != nil || ok {
+		t.Errorf("request decided twice: %v, %v", ok, err)
+	}
+}
+
+func TestPurgeRemovesExpiredState(t *testing.T) {
+	provider, _ := newTestProviders(t)
+	request := AuthorizationRequest{ClientId: "app", RedirectUri: "https://app.example.org/callback"}
+	if _, err := provider.PutRequest(request); err != nil {
+		t.Fatal(err)
+	}
+	code, err := provider.IssueCode(request, "2024000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := provider.Purge(time.Now()); err != nil || n != 0 {
+		t.Fatalf("purged %d fresh entries, err = %v", n, err)
+	}
+	if n, err := provider.Purge(time.Now().Add(time.Hour)); err != nil || n != 2 {
+		t.Fatalf("purged %d entries, want 2, err = %v", n, err)
+	}
+	if _, _, err := provider.ExchangeCode(code, "app", request.RedirectUri, ""); err == nil {
+		t.Error("purged code was exchanged")
+	}
+}
+
+func TestTokensVerifyOnAnotherInstance(t *testing.T) {
+	replicaA, replicaB := newTestProviders(t)
+	request := &AuthorizationRequest{ClientId: "app", Scopes: []string{ScopeOpenId}}
+	tokens, err := replicaA.IssueTokens(request, "2024000001", map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := replicaB.ParseAccessToken(tokens.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "2024000001" || claims.ClientId != "app" {
+		t.Errorf("claims = %+v", claims)
+	}
+	if _, err := replicaB.ParseAccessToken(tokens.IdToken); err == nil {
+		t.Error("ID Token accepted as an access token")
+	}
+}
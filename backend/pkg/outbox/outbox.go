@@ -0,0 +1,302 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/client"
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/mail"
+	"asynclab.club/asynx/backend/pkg/persist"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type MessageState string
+
+const (
+	MessagePending MessageState = "pending"
+	MessageSent    MessageState = "sent"
+	// MessageDead 表示超过最大重试次数，进入死信列表等待管理员处理
+	MessageDead MessageState = "dead"
+)
+
+type Message struct {
+	Id            string       `json:"id"`
+	To            string       `json:"to"`
+	Subject       string       `json:"subject"`
+	Template      string       `json:"template,omitempty"`
+	Language      string       `json:"language,omitempty"`
+	Body          string       `json:"body,omitempty"`
+	Text          string       `json:"text,omitempty"`
+	Bulk          bool         `json:"bulk,omitempty"` // 群发邮件，投递优先级低于普通邮件
+	Ref           string       `json:"ref,omitempty"`  // 调用方关联的记录，投递结果通过 Subscribe 回报
+	State         MessageState `json:"state"`
+	Attempts      int          `json:"attempts"`
+	LastError     string       `json:"lastError,omitempty"`
+	NextAttemptAt time.Time    `json:"nextAttemptAt"`
+	CreatedAt     time.Time    `json:"createdAt"`
+	SentAt        *time.Time   `json:"sentAt,omitempty"`
+}
+
+// Outbox 是一个持久化的邮件队列，由后台 worker 负责投递和重试
+type Outbox struct {
+	cfg         *config.ConfigOutbox
+	emailClient *client.EmailClient
+	templates   *mail.Templates
+	messages    *persist.Collection[Message]
+	wake        chan struct{}
+	sending     sync.Mutex
+	lastSent    time.Time
+	mu          sync.RWMutex
+	handlers    []func(Message)
+}
+
+func NewOutbox(cfg *config.ConfigOutbox, dataDir string, emailClient *client.EmailClient, templates *mail.Templates) (*Outbox, error) {
+	messages, err := persist.OpenCollection[Message](dataDir, "outbox")
+	if err != nil {
+		return nil, err
+	}
+	return &Outbox{
+		cfg:         cfg,
+		emailClient: emailClient,
+		templates:   templates,
+		messages:    messages,
+		wake:        make(chan struct{}, 1),
+	}, nil
+}
+
+func (o *Outbox) notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Subscribe 注册处理函数，带 Ref 的邮件发送成功、进入死信或被重新发送时调用
+func (o *Outbox) Subscribe(handler func(Message)) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.handlers = append(o.handlers, handler)
+}
+
+func (o *Outbox) publish(msg Message) {
+	if msg.Ref == "" {
+		return
+	}
+	o.mu.RLock()
+	handlers := o.handlers
+	o.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(msg)
+	}
+}
+
+// Enqueue 按收件人的语言渲染模板并写入队列，写入成功即返回，实际投递由 worker 异步完成
+func (o *Outbox) Enqueue(to string, name mail.Template, lang string, data any) (*Message, error) {
+	return o.enqueue(to, name, lang, data, false, "")
+}
+
+// EnqueueBulk 与 Enqueue 相同，但只在没有到期的普通邮件时才会投递
+func (o *Outbox) EnqueueBulk(ref, to string, name mail.Template, lang string, data any) (*Message, error) {
+	return o.enqueue(to, name, lang, data, true, ref)
+}
+
+func (o *Outbox) enqueue(to string, name mail.Template, lang string, data any, bulk bool, ref string) (*Message, error) {
+	lang = o.templates.Language(lang)
+	rendered, err := o.templates.Render(name, lang, data)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	msg := Message{
+		Id:            uuid.NewString(),
+		To:            to,
+		Subject:       rendered.Subject,
+		Template:      name.String(),
+		Language:      lang,
+		Body:          rendered.Html,
+		Text:          rendered.Text,
+		Bulk:          bulk,
+		Ref:           ref,
+		State:         MessagePending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	if err := o.messages.Put(msg.Id, msg); err != nil {
+		return nil, fmt.Errorf("failed to enqueue mail: %w", err)
+	}
+
+	o.notify()
+	return &msg, nil
+}
+
+func (o *Outbox) Get(id string) (Message, bool) {
+	return o.messages.Get(id)
+}
+
+// List 按创建时间倒序返回消息，state 为空时返回全部
+func (o *Outbox) List(state MessageState) []Message {
+	messages := slices.DeleteFunc(o.messages.List(), func(msg Message) bool {
+		return state != "" && msg.State != state
+	})
+	slices.SortFunc(messages, func(a, b Message) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return messages
+}
+
+// Resend 将死信或待发送的消息重新放回队列并立即尝试投递
+func (o *Outbox) Resend(id string) error {
+	var resent Message
+	err := o.messages.Update(id, func(msg *Message) error {
+		if msg.State == MessageSent {
+			return fmt.Errorf("message %s has already been sent", id)
+		}
+		msg.State = MessagePending
+		msg.Attempts = 0
+		msg.NextAttemptAt = time.Now()
+		resent = *msg
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	o.publish(resent)
+	o.notify()
+	return nil
+}
+
+func (o *Outbox) Delete(id string) error {
+	return o.messages.Delete(id)
+}
+
+// backoff 返回第 attempts 次失败后的等待时间
+func (o *Outbox) backoff(attempts int) time.Duration {
+	delay := o.cfg.BaseDelay
+	for i := 1; i < attempts && delay < o.cfg.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, o.cfg.MaxDelay)
+}
+
+func (o *Outbox) deliver(msg Message) {
+	err := o.emailClient.Deliver(msg.To, msg.Subject, msg.Body, msg.Text)
+
+	var (
+		finished Message
+		done     bool
+	)
+	updateErr := o.messages.Update(msg.Id, func(m *Message) error {
+		if m.State != MessagePending {
+			return nil
+		}
+		m.Attempts++
+		if err == nil {
+			now := time.Now()
+			m.State = MessageSent
+			m.SentAt = &now
+			m.LastError = ""
+			// 正文可能包含初始密码等敏感信息，发送后不再保留
+			m.Body = ""
+			m.Text = ""
+			finished, done = *m, true
+			return nil
+		}
+
+		m.LastError = err.Error()
+		if m.Attempts >= o.cfg.MaxAttempts {
+			m.State = MessageDead
+			logrus.Errorf("Mail %s to %s moved to dead letters after %d attempts: %v", m.Id, m.To, m.Attempts, err)
+			finished, done = *m, true
+			return nil
+		}
+		m.NextAttemptAt = time.Now().Add(o.backoff(m.Attempts))
+		logrus.Warnf("Failed to deliver mail %s to %s (attempt %d), retry at %s: %v", m.Id, m.To, m.Attempts, m.NextAttemptAt.Format(time.RFC3339), err)
+		return nil
+	})
+	if updateErr != nil {
+		logrus.Errorf("Failed to update mail %s: %v", msg.Id, updateErr)
+		return
+	}
+	if done {
+		o.publish(finished)
+	}
+}
+
+// next 返回下一条到期的消息，普通邮件优先于群发邮件
+func (o *Outbox) next(now time.Time) (Message, bool) {
+	var (
+		found Message
+		ok    bool
+	)
+	for _, msg := range o.messages.List() {
+		if msg.State != MessagePending || msg.NextAttemptAt.After(now) {
+			continue
+		}
+		if !ok || (found.Bulk && !msg.Bulk) || (found.Bulk == msg.Bulk && msg.NextAttemptAt.Before(found.NextAttemptAt)) {
+			found, ok = msg, true
+		}
+	}
+	return found, ok
+}
+
+// throttle 按 RateLimit 等待到可以投递下一封邮件，ctx 结束时返回 false
+func (o *Outbox) throttle(ctx context.Context) bool {
+	if o.cfg.RateLimit <= 0 {
+		return true
+	}
+
+	wait := time.Until(o.lastSent.Add(time.Minute / time.Duration(o.cfg.RateLimit)))
+	if wait <= 0 {
+		return true
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(wait):
+		return true
+	}
+}
+
+// Flush 投递所有到期的消息，每投递一封都重新挑选下一封
+func (o *Outbox) Flush(ctx context.Context) {
+	o.sending.Lock()
+	defer o.sending.Unlock()
+
+	for {
+		msg, ok := o.next(time.Now())
+		if !ok || !o.throttle(ctx) {
+			break
+		}
+		o.deliver(msg)
+		o.lastSent = time.Now()
+	}
+
+	before := time.Now().Add(-o.cfg.Retention)
+	if _, err := o.messages.DeleteFunc(func(_ string, msg Message) bool {
+		return msg.State == MessageSent && msg.SentAt != nil && msg.SentAt.Before(before)
+	}); err != nil {
+		logrus.Errorf("Failed to prune sent mails: %v", err)
+	}
+}
+
+// Run 启动投递循环，直到 ctx 结束
+func (o *Outbox) Run(ctx context.Context) {
+	ticker := time.NewTicker(o.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		o.Flush(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-o.wake:
+		}
+	}
+}
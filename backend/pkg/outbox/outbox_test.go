@@ -0,0 +1,182 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/client"
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/mail"
+)
+
+// flakyTransport 在 failures 次失败后开始投递成功，记录收件人的顺序
+type flakyTransport struct {
+	mu       sync.Mutex
+	failures int
+	sent     []string
+}
+
+func (t *flakyTransport) Send(m *client.Mail) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.failures > 0 {
+		t.failures--
+		return errors.New("connection refused")
+	}
+	t.sent = append(t.sent, m.To)
+	return nil
+}
+
+func newTestOutbox(t *testing.T, dataDir string, transport client.MailTransport) *Outbox {
+	t.Helper()
+	emailClient, err := client.NewEmailClient(&config.ConfigEmail{}, transport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates, err := mail.NewTemplates(&config.ConfigMailTemplate{DefaultLanguage: "zh", SiteUrl: "https://asynx.example.org/"}, os.DirFS("../../../templates/mail"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.ConfigOutbox{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: 5 * time.Minute, Retention: time.Hour}
+	outbox, err := NewOutbox(cfg, dataDir, emailClient, templates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return outbox
+}
+
+func enqueueWelcome(t *testing.T, outbox *Outbox, to string) *Message {
+	t.Helper()
+	msg, err := outbox.Enqueue(to, mail.TemplateWelcome, "en-US", mail.Sample(mail.TemplateWelcome))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestEnqueueRendersAndDelivers(t *testing.T) {
+	transport := &flakyTransport{}
+	outbox := newTestOutbox(t, "", transport)
+	msg := enqueueWelcome(t, outbox, "a@example.org")
+	if msg.Language != "en" || msg.Subject == "" || msg.Body == "" || msg.Text == "" {
+		t.Fatalf("got message %+v, want a rendered english mail", msg)
+	}
+
+	outbox.Flush(context.Background())
+	sent, _ := outbox.Get(msg.Id)
+	if sent.State != MessageSent || sent.SentAt == nil || sent.Attempts != 1 {
+		t.Errorf("got %+v, want sent after one attempt", sent)
+	}
+	// 正文可能包含初始密码，发送后不再保留
+	if sent.Body != "" || sent.Text != "" {
+		t.Error("body of a sent mail was kept")
+	}
+}
+
+func TestFailedDeliveryIsRetriedThenDead(t *testing.T) {
+	transport := &flakyTransport{failures: 3}
+	outbox := newTestOutbox(t, "", transport)
+	msg := enqueueWelcome(t, outbox, "a@example.org")
+
+	outbox.Flush(context.Background())
+	retried, _ := outbox.Get(msg.Id)
+	if retried.State != MessagePending || retried.Attempts != 1 || retried.LastError == "" {
+		t.Fatalf("got %+v after one failure, want pending with the error", retried)
+	}
+	if !retried.NextAttemptAt.After(time.Now()) {
+		t.Errorf("retry is not delayed: %s", retried.NextAttemptAt)
+	}
+
+	// 到期之前不会重试
+	outbox.Flush(context.Background())
+	if again, _ := outbox.Get(msg.Id); again.Attempts != 1 {
+		t.Fatalf("retried before the backoff elapsed: %d attempts", again.Attempts)
+	}
+
+	for range 2 {
+		if err := outbox.messages.Update(msg.Id, func(m *Message) error {
+			m.NextAttemptAt = time.Now()
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		outbox.Flush(context.Background())
+	}
+	dead, _ := outbox.Get(msg.Id)
+	if dead.State != MessageDead || dead.Attempts != 3 {
+		t.Fatalf("got %+v after max attempts, want dead", dead)
+	}
+	if list := outbox.List(MessageDead); len(list) != 1 {
+		t.Errorf("dead letters: %+v", list)
+	}
+
+	// 重新发送时清零重试次数
+	if err := outbox.Resend(msg.Id); err != nil {
+		t.Fatal(err)
+	}
+	outbox.Flush(context.Background())
+	if sent, _ := outbox.Get(msg.Id); sent.State != MessageSent || sent.Attempts != 1 {
+		t.Errorf("got %+v after resend, want sent", sent)
+	}
+	if err := outbox.Resend(msg.Id); err == nil {
+		t.Error("a sent mail was resent")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	outbox := newTestOutbox(t, "", &flakyTransport{})
+	for attempts, want := range map[int]time.Duration{1: time.Minute, 2: 2 * time.Minute, 3: 4 * time.Minute, 4: 5 * time.Minute, 10: 5 * time.Minute} {
+		if got := outbox.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestBulkMailIsDeliveredAfterOrdinaryMail(t *testing.T) {
+	transport := &flakyTransport{}
+	outbox := newTestOutbox(t, "", transport)
+	for _, to := range []string{"bulk1@example.org", "bulk2@example.org"} {
+		if _, err := outbox.EnqueueBulk("", to, mail.TemplateBroadcast, "zh", mail.Sample(mail.TemplateBroadcast)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	enqueueWelcome(t, outbox, "new@example.org")
+
+	outbox.Flush(context.Background())
+	if len(transport.sent) != 3 || transport.sent[0] != "new@example.org" {
+		t.Errorf("delivery order %v, want the ordinary mail first", transport.sent)
+	}
+}
+
+func TestPendingMailSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	msg := enqueueWelcome(t, newTestOutbox(t, dir, &flakyTransport{}), "a@example.org")
+
+	transport := &flakyTransport{}
+	restarted := newTestOutbox(t, dir, transport)
+	restarted.Flush(context.Background())
+	if sent, ok := restarted.Get(msg.Id); !ok || sent.State != MessageSent || len(transport.sent) != 1 {
+		t.Errorf("got %+v, %v after restart, want the queued mail delivered", sent, ok)
+	}
+}
+
+func TestSubscribeReportsFinishedMessagesWithRef(t *testing.T) {
+	outbox := newTestOutbox(t, "", &flakyTransport{})
+	var finished []Message
+	outbox.Subscribe(func(msg Message) { finished = append(finished, msg) })
+
+	bulk, err := outbox.EnqueueBulk("broadcast/2024000001", "a@example.org", mail.TemplateBroadcast, "zh", mail.Sample(mail.TemplateBroadcast))
+	if err != nil {
+		t.Fatal(err)
+	}
+	enqueueWelcome(t, outbox, "b@example.org")
+
+	outbox.Flush(context.Background())
+	if len(finished) != 1 || finished[0].Id != bulk.Id || finished[0].State != MessageSent {
+		t.Errorf("got %+v, want only the mail with a ref reported as sent", finished)
+	}
+}
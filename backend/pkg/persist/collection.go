@@ -0,0 +1,308 @@
+package persist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// Collection 是一个以 JSON 文件持久化的键值集合，每次写入都会原子地替换整个文件并落盘。
+// 适合数据量不大、写入不频繁的运行时状态；path 为空时只保存在内存中。
+// 由 OpenEntryCollection 打开时每个条目保存为单独的文件，写入只替换被修改的条目。
+// 保存的条目不会被原地修改，Get 和 List 返回的值可以在锁外安全读取，其中的切片和映射与其他读者共享，不应修改。
+type Collection[T any] struct {
+	mu    sync.RWMutex
+	path  string
+	dir   string // 每个条目一个文件时的目录
+	items map[string]T
+}
+
+func OpenCollection[T any](dir, name string) (*Collection[T], error) {
+	c := &Collection[T]{items: make(map[string]T)}
+	if dir == "" {
+		return c, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create data dir: %w", err)
+	}
+	c.path = filepath.Join(dir, name+".json")
+
+	content, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", c.path, err)
+	}
+	if err := json.Unmarshal(content, &c.items); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", c.path, err)
+	}
+	return c, nil
+}
+
+// OpenEntryCollection 打开每个条目一个文件的集合，适合条目较多且单个条目频繁修改的状态，如操作日志。
+// 条目的 id 会作为文件名，只能包含字母、数字、- 和 _。旧版本保存在 <name>.json 中的条目会被迁移过来
+func OpenEntryCollection[T any](dir, name string) (*Collection[T], error) {
+	c := &Collection[T]{items: make(map[string]T)}
+	if dir == "" {
+		return c, nil
+	}
+
+	c.dir = filepath.Join(dir, name)
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create data dir: %w", err)
+	}
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", c.dir, err)
+	}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() || !validEntryId(id) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(c.dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Name(), err)
+		}
+		var item T
+		if err := json.Unmarshal(content, &item); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", filepath.Join(c.dir, entry.Name()), err)
+		}
+		c.items[id] = item
+	}
+
+	if err := c.migrate(filepath.Join(dir, name+".json")); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// migrate 把保存在单个文件中的条目拆分为每个条目一个文件，完成后删除原文件
+func (c *Collection[T]) migrate(path string) error {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var items map[string]T
+	if err := json.Unmarshal(content, &items); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for id, item := range items {
+		if _, ok := c.items[id]; ok || !validEntryId(id) {
+			continue
+		}
+		c.items[id] = item
+		if err := c.flushEntry(id); err != nil {
+			return err
+		}
+	}
+	return os.Remove(path)
+}
+
+func validEntryId(id string) bool {
+	return id != "" && !strings.ContainsFunc(id, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_')
+	})
+}
+
+// save 持久化对 id 的修改，条目已被删除时删除对应的文件
+func (c *Collection[T]) save(id string) error {
+	if c.dir != "" {
+		return c.flushEntry(id)
+	}
+	return c.flush()
+}
+
+func (c *Collection[T]) flushEntry(id string) error {
+	path := filepath.Join(c.dir, id+".json")
+	item, ok := c.items[id]
+	if !ok {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return syncDir(c.dir)
+	}
+
+	content, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := writeFileSync(tmp, content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	return syncDir(c.dir)
+}
+
+func (c *Collection[T]) flush() error {
+	if c.path == "" {
+		return nil
+	}
+
+	content, err := json.MarshalIndent(c.items, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := c.path + ".tmp"
+	if err := writeFileSync(tmp, content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(c.path))
+}
+
+// writeFileSync 写入文件并等待内容落盘，否则崩溃后重命名可能指向不完整的文件
+func writeFileSync(path string, content []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// syncDir 使目录中的重命名落盘
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", dir, err)
+	}
+	return nil
+}
+
+// clone 通过 JSON 深拷贝条目，与写入文件的内容一致
+func clone[T any](item T) (T, error) {
+	var copied T
+	content, err := json.Marshal(item)
+	if err != nil {
+		return copied, err
+	}
+	err = json.Unmarshal(content, &copied)
+	return copied, err
+}
+
+func (c *Collection[T]) Get(id string) (T, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	item, ok := c.items[id]
+	return item, ok
+}
+
+// Put 保存 item 的副本，调用方之后修改 item 不会影响已保存的条目
+func (c *Collection[T]) Put(id string, item T) error {
+	if c.dir != "" && !validEntryId(id) {
+		return fmt.Errorf("invalid id %q", id)
+	}
+	item, err := clone(item)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items[id] = item
+	return c.save(id)
+}
+
+// Update 在锁内读取、修改并写回条目，fn 返回错误时不做任何修改。
+// fn 修改的是条目的深拷贝，不会与 Get 和 List 的读者产生竞争
+func (c *Collection[T]) Update(id string, fn func(item *T) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current, ok := c.items[id]
+	if !ok {
+		return fmt.Errorf("%s: %w", id, ErrNotFound)
+	}
+	item, err := clone(current)
+	if err != nil {
+		return err
+	}
+	if err := fn(&item); err != nil {
+		return err
+	}
+	c.items[id] = item
+	return c.save(id)
+}
+
+func (c *Collection[T]) Delete(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.items[id]; !ok {
+		return nil
+	}
+	delete(c.items, id)
+	return c.save(id)
+}
+
+// DeleteFunc 删除所有满足条件的条目，返回删除数量
+func (c *Collection[T]) DeleteFunc(del func(id string, item T) bool) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var deleted []string
+	for id, item := range c.items {
+		if del(id, item) {
+			delete(c.items, id)
+			deleted = append(deleted, id)
+		}
+	}
+	if len(deleted) == 0 {
+		return 0, nil
+	}
+	if c.dir == "" {
+		return len(deleted), c.flush()
+	}
+	for _, id := range deleted {
+		if err := os.Remove(filepath.Join(c.dir, id+".json")); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return len(deleted), err
+		}
+	}
+	return len(deleted), syncDir(c.dir)
+}
+
+// List 按 id 升序返回所有条目
+func (c *Collection[T]) List() []T {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ids := make([]string, 0, len(c.items))
+	for id := range c.items {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	items := make([]T, 0, len(ids))
+	for _, id := range ids {
+		items = append(items, c.items[id])
+	}
+	return items
+}
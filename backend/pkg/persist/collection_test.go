@@ -0,0 +1,170 @@
+package persist
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type record struct {
+	Name  string   `json:"name"`
+	Steps []string `json:"steps"`
+}
+
+func TestCollectionPersistsAcrossReopen(t *testing.T) {
+	dir := t.TempDir()
+	c, err := OpenCollection[record](dir, "records")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Put("a", record{Name: "a", Steps: []string{"pending"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Update("a", func(r *record) error {
+		r.Steps[0] = "done"
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenCollection[record](dir, "records")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := reopened.Get("a")
+	if !ok || got.Steps[0] != "done" {
+		t.Fatalf("got %+v, %v after reopen, want step done", got, ok)
+	}
+}
+
+func TestCollectionUpdateDoesNotMutateReadValues(t *testing.T) {
+	c, err := OpenCollection[record]("", "records")
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := []string{"pending"}
+	if err := c.Put("a", record{Name: "a", Steps: steps}); err != nil {
+		t.Fatal(err)
+	}
+	steps[0] = "changed by caller"
+
+	before, _ := c.Get("a")
+	if err := c.Update("a", func(r *record) error {
+		r.Steps[0] = "done"
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if before.Steps[0] != "pending" {
+		t.Fatalf("value read before Update changed to %q", before.Steps[0])
+	}
+	if after, _ := c.Get("a"); after.Steps[0] != "done" {
+		t.Fatalf("got %q after Update, want done", after.Steps[0])
+	}
+}
+
+func TestCollectionConcurrentUpdateAndList(t *testing.T) {
+	c, err := OpenCollection[record]("", "records")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Put("a", record{Name: "a", Steps: make([]string, 4)}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := range 200 {
+			_ = c.Update("a", func(r *record) error {
+				r.Steps[i%len(r.Steps)] = "running"
+				return nil
+			})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 200 {
+			for _, r := range c.List() {
+				for _, step := range r.Steps {
+					_ = step
+				}
+			}
+		}
+	}()
+	wg.Wait()
+}
+
+func TestEntryCollectionWritesOneFilePerEntry(t *testing.T) {
+	dir := t.TempDir()
+	c, err := OpenEntryCollection[record](dir, "records")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if err := c.Put(id, record{Name: id, Steps: []string{"pending"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before, err := os.Stat(filepath.Join(dir, "records", "b.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := c.Update("a", func(r *record) error {
+		r.Steps[0] = "done"
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// 修改一个条目不会重写其他条目
+	if after, err := os.Stat(filepath.Join(dir, "records", "b.json")); err != nil || !after.ModTime().Equal(before.ModTime()) {
+		t.Errorf("b.json was rewritten by an update of a: %v", err)
+	}
+
+	if n, err := c.DeleteFunc(func(id string, _ record) bool { return id == "b" }); err != nil || n != 1 {
+		t.Fatalf("DeleteFunc = %d, %v", n, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "records", "b.json")); !os.IsNotExist(err) {
+		t.Errorf("b.json still exists after delete: %v", err)
+	}
+
+	reopened, err := OpenEntryCollection[record](dir, "records")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reopened.List(); len(got) != 1 || got[0].Steps[0] != "done" {
+		t.Errorf("got %+v after reopen, want a with step done", got)
+	}
+	if err := reopened.Put("../escape", record{}); err == nil {
+		t.Error("id with a path separator was accepted")
+	}
+}
+
+func TestEntryCollectionMigratesSingleFile(t *testing.T) {
+	dir := t.TempDir()
+	old, err := OpenCollection[record](dir, "records")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := old.Put("a", record{Name: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := OpenEntryCollection[record](dir, "records")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := c.Get("a"); !ok || got.Name != "a" {
+		t.Fatalf("got %+v, %v after migration", got, ok)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "records.json")); !os.IsNotExist(err) {
+		t.Errorf("records.json still exists after migration: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "records", "a.json")); err != nil {
+		t.Errorf("a.json missing after migration: %v", err)
+	}
+}
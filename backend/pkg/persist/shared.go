@@ -0,0 +1,266 @@
+package persist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrExists   = errors.New("already exists")
+	ErrConflict = errors.New("modified concurrently")
+	ErrTooLarge = errors.New("value too large")
+)
+
+// 条目被其他实例并发修改时，SharedCollection.Update 最多重试的次数
+const sharedRetries = 8
+
+// SharedEntry 是共享后端中的一个条目，Version 用于比较并交换，由后端决定格式
+type SharedEntry struct {
+	Id      string
+	Value   []byte
+	Version string
+}
+
+// SharedBackend 保存所有实例共享的集合，条目的值是 JSON。id 不区分大小写，由调用方保证唯一。
+// 与 Collection 不同，每次读写都直接访问后端，任一实例的修改对其他实例立即可见
+type SharedBackend interface {
+	// Get 返回条目，不存在时返回 ErrNotFound
+	Get(collection, id string) (*SharedEntry, error)
+	// List 返回集合中的全部条目，顺序不确定
+	List(collection string) ([]*SharedEntry, error)
+	// Create 创建条目，已存在时返回 ErrExists
+	Create(collection, id string, value []byte) error
+	// Swap 在条目的版本仍为 version 时替换其值，否则返回 ErrConflict，条目不存在时返回 ErrNotFound
+	Swap(collection, id, version string, value []byte) error
+	// Delete 删除条目，不存在时不报错
+	Delete(collection, id string) error
+}
+
+// SharedCollection 是保存在共享后端中的键值集合，用于需要在多个实例之间共享的状态。
+// 读取总是返回新解码的副本，调用方可以随意修改
+type SharedCollection[T any] struct {
+	backend SharedBackend
+	name    string
+}
+
+func NewSharedCollection[T any](backend SharedBackend, name string) *SharedCollection[T] {
+	return &SharedCollection[T]{backend: backend, name: name}
+}
+
+func (c *SharedCollection[T]) decode(entry *SharedEntry) (T, error) {
+	var item T
+	if err := json.Unmarshal(entry.Value, &item); err != nil {
+		return item, fmt.Errorf("failed to parse %s/%s: %w", c.name, entry.Id, err)
+	}
+	return item, nil
+}
+
+// Get 读取条目，不存在时返回 false
+func (c *SharedCollection[T]) Get(id string) (T, bool, error) {
+	var zero T
+	entry, err := c.backend.Get(c.name, id)
+	if errors.Is(err, ErrNotFound) {
+		return zero, false, nil
+	}
+	if err != nil {
+		return zero, false, err
+	}
+	item, err := c.decode(entry)
+	if err != nil {
+		return zero, false, err
+	}
+	return item, true, nil
+}
+
+// List 按 id 升序返回所有条目
+func (c *SharedCollection[T]) List() ([]T, error) {
+	entries, err := c.backend.List(c.name)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(entries, func(a, b *SharedEntry) int { return strings.Compare(a.Id, b.Id) })
+
+	items := make([]T, 0, len(entries))
+	for _, entry := range entries {
+		item, err := c.decode(entry)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// Create 保存新条目，id 已存在时返回 ErrExists
+func (c *SharedCollection[T]) Create(id string, item T) error {
+	value, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	return c.backend.Create(c.name, id, value)
+}
+
+// Put 保存条目，已存在时整体覆盖
+func (c *SharedCollection[T]) Put(id string, item T) error {
+	value, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	for range sharedRetries {
+		entry, err := c.backend.Get(c.name, id)
+		if errors.Is(err, ErrNotFound) {
+			err = c.backend.Create(c.name, id, value)
+		} else if err == nil {
+			err = c.backend.Swap(c.name, id, entry.Version, value)
+		}
+		if errors.Is(err, ErrExists) || errors.Is(err, ErrConflict) || errors.Is(err, ErrNotFound) {
+			continue
+		}
+		return err
+	}
+	return fmt.Errorf("%s/%s: %w", c.name, id, ErrConflict)
+}
+
+// Update 读取、修改并写回条目，fn 返回错误时不做任何修改。
+// 条目被其他实例并发修改时会重新读取并再次调用 fn，fn 不应有其他副作用
+func (c *SharedCollection[T]) Update(id string, fn func(item *T) error) error {
+	for range sharedRetries {
+		entry, err := c.backend.Get(c.name, id)
+		if errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("%s: %w", id, ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		item, err := c.decode(entry)
+		if err != nil {
+			return err
+		}
+		if err := fn(&item); err != nil {
+			return err
+		}
+		value, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		err = c.backend.Swap(c.name, id, entry.Version, value)
+		if errors.Is(err, ErrConflict) {
+			continue
+		}
+		if errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("%s: %w", id, ErrNotFound)
+		}
+		return err
+	}
+	return fmt.Errorf("%s/%s: %w", c.name, id, ErrConflict)
+}
+
+func (c *SharedCollection[T]) Delete(id string) error {
+	return c.backend.Delete(c.name, id)
+}
+
+// DeleteFunc 删除所有满足条件的条目，返回删除数量
+func (c *SharedCollection[T]) DeleteFunc(del func(id string, item T) bool) (int, error) {
+	entries, err := c.backend.List(c.name)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, entry := range entries {
+		item, err := c.decode(entry)
+		if err != nil {
+			return n, err
+		}
+		if !del(entry.Id, item) {
+			continue
+		}
+		if err := c.backend.Delete(c.name, entry.Id); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// ---------------------------------------------------------------------------------------
+
+// MemoryBackend 是只在本进程内共享的后端，用于测试
+type MemoryBackend struct {
+	mu      sync.Mutex
+	entries map[string]SharedEntry
+	version int
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{entries: make(map[string]SharedEntry)}
+}
+
+func memoryKey(collection, id string) string {
+	return collection + "/" + strings.ToLower(id)
+}
+
+func (b *MemoryBackend) Get(collection, id string) (*SharedEntry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry, ok := b.entries[memoryKey(collection, id)]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", id, ErrNotFound)
+	}
+	entry.Value = slices.Clone(entry.Value)
+	return &entry, nil
+}
+
+func (b *MemoryBackend) List(collection string) ([]*SharedEntry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var entries []*SharedEntry
+	for key, entry := range b.entries {
+		if strings.HasPrefix(key, collection+"/") {
+			entry.Value = slices.Clone(entry.Value)
+			entries = append(entries, &entry)
+		}
+	}
+	return entries, nil
+}
+
+func (b *MemoryBackend) Create(collection, id string, value []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	key := memoryKey(collection, id)
+	if _, ok := b.entries[key]; ok {
+		return fmt.Errorf("%s: %w", id, ErrExists)
+	}
+	b.version++
+	b.entries[key] = SharedEntry{Id: id, Value: slices.Clone(value), Version: strconv.Itoa(b.version)}
+	return nil
+}
+
+func (b *MemoryBackend) Swap(collection, id, version string, value []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	key := memoryKey(collection, id)
+	entry, ok := b.entries[key]
+	if !ok {
+		return fmt.Errorf("%s: %w", id, ErrNotFound)
+	}
+	if entry.Version != version {
+		return fmt.Errorf("%s: %w", id, ErrConflict)
+	}
+	b.version++
+	b.entries[key] = SharedEntry{Id: entry.Id, Value: slices.Clone(value), Version: strconv.Itoa(b.version)}
+	return nil
+}
+
+func (b *MemoryBackend) Delete(collection, id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.entries, memoryKey(collection, id))
+	return nil
+}
+
+var _ SharedBackend = (*MemoryBackend)(nil)
@@ -0,0 +1,89 @@
+package persist
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSharedCollectionCreateAndPut(t *testing.T) {
+	c := NewSharedCollection[record](NewMemoryBackend(), "records")
+
+	if err := c.Create("a", record{Name: "first"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Create("a", record{Name: "second"}); !errors.Is(err, ErrExists) {
+		t.Errorf("create existing: got %v, want ErrExists", err)
+	}
+	if err := c.Put("a", record{Name: "third"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Put("b", record{Name: "new"}); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := c.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Name != "third" || items[1].Name != "new" {
+		t.Errorf("got %+v, want third and new in id order", items)
+	}
+	if _, ok, err := c.Get("missing"); ok || err != nil {
+		t.Errorf("get missing: got %v, %v", ok, err)
+	}
+}
+
+func TestSharedCollectionUpdateRetriesOnConflict(t *testing.T) {
+	backend := NewMemoryBackend()
+	c := NewSharedCollection[record](backend, "records")
+	other := NewSharedCollection[record](backend, "records")
+	if err := c.Create("a", record{Name: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一次调用 fn 时另一个实例修改了条目，写回失败后应基于新值重试
+	calls := 0
+	err := c.Update("a", func(r *record) error {
+		calls++
+		if calls == 1 {
+			if err := other.Update("a", func(r *record) error {
+				r.Steps = append(r.Steps, "other")
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		r.Steps = append(r.Steps, "mine")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("fn called %d times, want 2", calls)
+	}
+	got, _, _ := c.Get("a")
+	if len(got.Steps) != 2 || got.Steps[0] != "other" || got.Steps[1] != "mine" {
+		t.Errorf("got steps %v, want [other mine]", got.Steps)
+	}
+
+	if err := c.Update("missing", func(*record) error { return nil }); !errors.Is(err, ErrNotFound) {
+		t.Errorf("update missing: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestSharedCollectionDeleteFunc(t *testing.T) {
+	c := NewSharedCollection[record](NewMemoryBackend(), "records")
+	for _, name := range []string{"a", "b", "c"} {
+		if err := c.Create(name, record{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n, err := c.DeleteFunc(func(id string, r record) bool { return id != "b" })
+	if err != nil || n != 2 {
+		t.Fatalf("got %d, %v, want 2 deleted", n, err)
+	}
+	if items, _ := c.List(); len(items) != 1 || items[0].Name != "b" {
+		t.Errorf("got %+v after delete, want only b", items)
+	}
+}
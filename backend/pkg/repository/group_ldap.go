@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"fmt"
+
+	"asynclab.club/asynx/backend/pkg/client"
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/entity"
+	"asynclab.club/asynx/backend/pkg/transfer"
+	"asynclab.club/asynx/backend/pkg/util"
+)
+
+var groupAttributes []string
+
+func init() {
+	var err error
+	groupAttributes, err = util.GetAttributeKeys[entity.Group]()
+	if err != nil {
+		panic(err)
+	}
+}
+
+type RepositoryGroupLdap struct {
+	client client.DirectoryClient
+}
+
+func NewRepositoryGroupLdap(client client.DirectoryClient) *RepositoryGroupLdap {
+	return &RepositoryGroupLdap{
+		client: client,
+	}
+}
+
+func (r *RepositoryGroupLdap) GetGroupBaseDn() string {
+	return r.client.GetGroupBaseDn()
+}
+
+func (r *RepositoryGroupLdap) BuildDn(group *entity.Group) string {
+	return fmt.Sprintf("cn=%s,ou=%s,%s", group.Cn, group.Ou, r.GetGroupBaseDn())
+}
+
+func (r *RepositoryGroupLdap) find(rdn string, filter string) ([]*entity.Group, error) {
+	baseDN := r.GetGroupBaseDn()
+	if rdn != "" {
+		baseDN = fmt.Sprintf("%s,%s", rdn, baseDN)
+	}
+
+	result, err := r.client.Search(baseDN, fmt.Sprintf("(&(%s)(%s))", config.GroupObjectFilter, filter), groupAttributes)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]*entity.Group, 0, len(result.Entries))
+	for _, entry := range result.Entries {
+		group, err := transfer.ParseFromLdap[entity.Group](entry)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func (r *RepositoryGroupLdap) FindAllByOu(ou string) ([]*entity.Group, error) {
+	group, err := r.find(fmt.Sprintf("ou=%s", ou), "objectClass=*")
+	return group, err
+}
+
+func (r *RepositoryGroupLdap) FindByOuAndCn(ou string, cn string) (group *entity.Group, err error) {
+	groups, err := r.find(fmt.Sprintf("ou=%s", ou), fmt.Sprintf("cn=%s", cn))
+	if len(groups) != 0 {
+		group = groups[0]
+	}
+	return
+}
+
+func (r *RepositoryGroupLdap) FindAll() ([]*entity.Group, error) {
+	return r.find("", "objectClass=*")
+}
+
+func (r *RepositoryGroupLdap) FindAllByOuAndMemberUid(ou string, uid string) ([]*entity.Group, error) {
+	return r.find(fmt.Sprintf("ou=%s", ou), fmt.Sprintf("memberUid=%s", uid))
+}
+
+func (r *RepositoryGroupLdap) AddMemberUid(group *entity.Group, uid string) error {
+	return r.client.ModifyAttributes(r.BuildDn(group), map[string][]string{"memberUid": {uid}}, nil, nil)
+}
+
+func (r *RepositoryGroupLdap) RemoveMemberUid(group *entity.Group, uid string) error {
+	return r.client.ModifyAttributes(r.BuildDn(group), nil, map[string][]string{"memberUid": {uid}}, nil)
+}
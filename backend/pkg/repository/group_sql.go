@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"fmt"
+
+	"asynclab.club/asynx/backend/pkg/entity"
+)
+
+type RepositoryGroupSql struct {
+	session *sqlSession
+}
+
+func (r *RepositoryGroupSql) find(where string, args ...any) ([]*entity.Group, error) {
+	query := `SELECT g.ou, g.cn, g.gid_number, m.uid FROM posix_groups g LEFT JOIN group_members m ON m.group_ou = g.ou AND m.group_cn = g.cn`
+	if where != "" {
+		query = fmt.Sprintf("%s WHERE %s", query, where)
+	}
+	rows, err := r.session.query(query+` ORDER BY g.ou, g.cn, m.uid`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := make([]*entity.Group, 0)
+	var last *entity.Group
+	for rows.Next() {
+		var (
+			ou, cn, gidNumber string
+			uid               *string
+		)
+		if err := rows.Scan(&ou, &cn, &gidNumber, &uid); err != nil {
+			return nil, err
+		}
+		if last == nil || last.Ou != ou || last.Cn != cn {
+			last = &entity.Group{Ou: ou, Cn: cn, GidNumber: gidNumber}
+			groups = append(groups, last)
+		}
+		if uid != nil {
+			last.MemberUid = append(last.MemberUid, *uid)
+		}
+	}
+	return groups, rows.Err()
+}
+
+func (r *RepositoryGroupSql) FindAll() ([]*entity.Group, error) {
+	return r.find("")
+}
+
+func (r *RepositoryGroupSql) FindAllByOu(ou string) ([]*entity.Group, error) {
+	return r.find(`g.ou = ?`, ou)
+}
+
+func (r *RepositoryGroupSql) FindByOuAndCn(ou string, cn string) (group *entity.Group, err error) {
+	groups, err := r.find(`g.ou = ? AND g.cn = ?`, ou, cn)
+	if len(groups) != 0 {
+		group = groups[0]
+	}
+	return
+}
+
+func (r *RepositoryGroupSql) FindAllByOuAndMemberUid(ou string, uid string) ([]*entity.Group, error) {
+	return r.find(`g.ou = ? AND EXISTS (SELECT 1 FROM group_members x WHERE x.group_ou = g.ou AND x.group_cn = g.cn AND x.uid = ?)`, ou, uid)
+}
+
+func (r *RepositoryGroupSql) AddMemberUid(group *entity.Group, uid string) error {
+	_, err := r.session.exec(`INSERT INTO group_members (group_ou, group_cn, uid) VALUES (?, ?, ?)`, group.Ou, group.Cn, uid)
+	return err
+}
+
+func (r *RepositoryGroupSql) RemoveMemberUid(group *entity.Group, uid string) error {
+	result, err := r.session.exec(`DELETE FROM group_members WHERE group_ou = ? AND group_cn = ? AND uid = ?`, group.Ou, group.Cn, uid)
+	return checkAffected(result, err, uid)
+}
+
+var _ RepositoryGroup = (*RepositoryGroupSql)(nil)
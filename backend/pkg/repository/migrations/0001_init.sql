@@ -0,0 +1,32 @@
+CREATE TABLE users (
+    uid            VARCHAR(64)  PRIMARY KEY,
+    cn             VARCHAR(64)  NOT NULL UNIQUE,
+    ou             VARCHAR(64)  NOT NULL,
+    sn             VARCHAR(255) NOT NULL DEFAULT '',
+    given_name     VARCHAR(255) NOT NULL DEFAULT '',
+    gid_number     VARCHAR(16)  NOT NULL DEFAULT '',
+    uid_number     VARCHAR(16)  NOT NULL DEFAULT '',
+    home_directory VARCHAR(255) NOT NULL DEFAULT '',
+    mail           VARCHAR(255) NOT NULL DEFAULT '',
+    password_hash  VARCHAR(255) NOT NULL DEFAULT '',
+    login_shell    VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_users_ou ON users (ou);
+
+CREATE TABLE posix_groups (
+    ou         VARCHAR(64) NOT NULL,
+    cn         VARCHAR(64) NOT NULL,
+    gid_number VARCHAR(16) NOT NULL DEFAULT '',
+    PRIMARY KEY (ou, cn)
+);
+
+CREATE TABLE group_members (
+    group_ou VARCHAR(64) NOT NULL,
+    group_cn VARCHAR(64) NOT NULL,
+    uid      VARCHAR(64) NOT NULL,
+    PRIMARY KEY (group_ou, group_cn, uid),
+    FOREIGN KEY (group_ou, group_cn) REFERENCES posix_groups (ou, cn) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_group_members_uid ON group_members (uid);
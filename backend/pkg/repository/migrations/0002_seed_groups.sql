@@ -0,0 +1,4 @@
+INSERT INTO posix_groups (ou, cn, gid_number) VALUES ('primary', 'users', '10000');
+INSERT INTO posix_groups (ou, cn, gid_number) VALUES ('supplementary', 'admin', '20001');
+INSERT INTO posix_groups (ou, cn, gid_number) VALUES ('supplementary', 'default', '20002');
+INSERT INTO posix_groups (ou, cn, gid_number) VALUES ('supplementary', 'restricted', '20003');
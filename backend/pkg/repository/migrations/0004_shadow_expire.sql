@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN shadow_expire VARCHAR(16) NOT NULL DEFAULT '';
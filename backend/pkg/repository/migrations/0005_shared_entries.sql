@@ -0,0 +1,7 @@
+CREATE TABLE shared_entries (
+    collection VARCHAR(64)  NOT NULL,
+    id         VARCHAR(255) NOT NULL,
+    value      TEXT         NOT NULL,
+    revision   INTEGER      NOT NULL DEFAULT 1,
+    PRIMARY KEY (collection, id)
+);
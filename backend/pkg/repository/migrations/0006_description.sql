@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN description TEXT NOT NULL DEFAULT '';
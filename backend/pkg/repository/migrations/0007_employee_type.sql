@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN employee_type VARCHAR(64) NOT NULL DEFAULT '';
+UPDATE users SET employee_type = 'service-account' WHERE ou = 'system' AND login_shell = '/usr/sbin/nologin';
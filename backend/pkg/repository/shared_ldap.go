@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"asynclab.club/asynx/backend/pkg/client"
+	"asynclab.club/asynx/backend/pkg/persist"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// SharedLdap 把共享集合保存在目录中：每个集合是 baseDn 下的 ou=<集合>，每个条目是其下的 cn=<id>。
+// 与 scheduler.DirectoryLock 一样使用 organizationalRole 的 description 保存值，格式为 "<版本> <JSON>"，
+// 修改时删除旧值并添加新值，旧值已被其他实例改掉时目录会拒绝整个请求，以此实现比较并交换
+// 单个条目的值上限，目录服务器会拒绝过大的请求
+const maxSharedLdapValue = 512 << 10
+
+// checkSize 在值超过上限时返回 ErrTooLarge
+func checkSize(collection, id string, value []byte) error {
+	if len(value) > maxSharedLdapValue {
+		return fmt.Errorf("%s/%s is %d bytes, limit is %d: %w", collection, id, len(value), maxSharedLdapValue, persist.ErrTooLarge)
+	}
+	return nil
+}
+
+type SharedLdap struct {
+	client client.DirectoryClient
+	baseDn string
+}
+
+func NewSharedLdap(client client.DirectoryClient, baseDn string) *SharedLdap {
+	return &SharedLdap{client: client, baseDn: baseDn}
+}
+
+func (s *SharedLdap) collectionDn(collection string) string {
+	return fmt.Sprintf("ou=%s,%s", ldap.EscapeDN(collection), s.baseDn)
+}
+
+func (s *SharedLdap) entryDn(collection, id string) string {
+	return fmt.Sprintf("cn=%s,%s", ldap.EscapeDN(id), s.collectionDn(collection))
+}
+
+func parseSharedEntry(entry *ldap.Entry) (*persist.SharedEntry, error) {
+	version := entry.GetAttributeValue("description")
+	_, value, ok := strings.Cut(version, " ")
+	if !ok {
+		return nil, fmt.Errorf("invalid shared entry %s", entry.DN)
+	}
+	return &persist.SharedEntry{Id: entry.GetAttributeValue("cn"), Value: []byte(value), Version: version}, nil
+}
+
+func (s *SharedLdap) Get(collection, id string) (*persist.SharedEntry, error) {
+	result, err := s.client.Search(s.entryDn(collection, id), "(objectClass=organizationalRole)", []string{"cn", "description"})
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) || (err == nil && len(result.Entries) == 0) {
+		return nil, fmt.Errorf("%s: %w", id, persist.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseSharedEntry(result.Entries[0])
+}
+
+func (s *SharedLdap) List(collection string) ([]*persist.SharedEntry, error) {
+	result, err := s.client.Search(s.collectionDn(collection), "(objectClass=organizationalRole)", []string{"cn", "description"})
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*persist.SharedEntry, 0, len(result.Entries))
+	for _, e := range result.Entries {
+		entry, err := parseSharedEntry(e)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// ensureOu 创建组织单元，已存在时不报错
+func (s *SharedLdap) ensureOu(dn, ou string) error {
+	err := s.client.Add(dn, []string{"top", "organizationalUnit"}, map[string][]string{"ou": {ou}})
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultEntryAlreadyExists) {
+		return nil
+	}
+	return err
+}
+
+func (s *SharedLdap) Create(collection, id string, value []byte) error {
+	if err := checkSize(collection, id, value); err != nil {
+		return err
+	}
+	add := func() error {
+		return s.client.Add(s.entryDn(collection, id), []string{"top", "organizationalRole"}, map[string][]string{
+			"cn":          {id},
+			"description": {"1 " + string(value)},
+		})
+	}
+
+	err := add()
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		// 第一次写入时创建集合所在的组织单元
+		base, _, _ := strings.Cut(s.baseDn, ",")
+		if err := s.ensureOu(s.baseDn, strings.TrimPrefix(base, "ou=")); err != nil {
+			return fmt.Errorf("failed to create %s: %w", s.baseDn, err)
+		}
+		if err := s.ensureOu(s.collectionDn(collection), collection); err != nil {
+			return fmt.Errorf("failed to create %s: %w", s.collectionDn(collection), err)
+		}
+		err = add()
+	}
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultEntryAlreadyExists) {
+		return fmt.Errorf("%s: %w", id, persist.ErrExists)
+	}
+	return err
+}
+
+func (s *SharedLdap) Swap(collection, id, version string, value []byte) error {
+	if err := checkSize(collection, id, value); err != nil {
+		return err
+	}
+	revision, _, _ := strings.Cut(version, " ")
+	n, err := strconv.Atoi(revision)
+	if err != nil {
+		return fmt.Errorf("invalid version of %s: %w", id, err)
+	}
+	// 版本号随每次修改递增，新值与旧值不会相同
+	next := fmt.Sprintf("%d %s", n+1, value)
+
+	err = s.client.ModifyAttributes(s.entryDn(collection, id),
+		map[string][]string{"description": {next}}, map[string][]string{"description": {version}}, nil)
+	switch {
+	case ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject):
+		return fmt.Errorf("%s: %w", id, persist.ErrNotFound)
+	case ldap.IsErrorAnyOf(err, ldap.LDAPResultNoSuchAttribute, ldap.LDAPResultAttributeOrValueExists):
+		return fmt.Errorf("%s: %w", id, persist.ErrConflict)
+	}
+	return err
+}
+
+func (s *SharedLdap) Delete(collection, id string) error {
+	err := s.client.Delete(s.entryDn(collection, id))
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		return nil
+	}
+	return err
+}
+
+var _ persist.SharedBackend = (*SharedLdap)(nil)
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"asynclab.club/asynx/backend/pkg/persist"
+)
+
+// SharedSql 把共享集合保存在 shared_entries 表中，revision 随每次修改递增，用作比较并交换的版本
+type SharedSql struct {
+	session *sqlSession
+}
+
+func scanSharedEntry(scan func(dest ...any) error) (*persist.SharedEntry, error) {
+	var (
+		entry    persist.SharedEntry
+		value    string
+		revision int64
+	)
+	if err := scan(&entry.Id, &value, &revision); err != nil {
+		return nil, err
+	}
+	entry.Value = []byte(value)
+	entry.Version = strconv.FormatInt(revision, 10)
+	return &entry, nil
+}
+
+func (s *SharedSql) Get(collection, id string) (*persist.SharedEntry, error) {
+	row := s.session.queryRow(`SELECT id, value, revision FROM shared_entries WHERE collection = ? AND id = ?`, collection, id)
+	entry, err := scanSharedEntry(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", id, persist.ErrNotFound)
+	}
+	return entry, err
+}
+
+func (s *SharedSql) List(collection string) ([]*persist.SharedEntry, error) {
+	rows, err := s.session.query(`SELECT id, value, revision FROM shared_entries WHERE collection = ?`, collection)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*persist.SharedEntry
+	for rows.Next() {
+		entry, err := scanSharedEntry(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (s *SharedSql) Create(collection, id string, value []byte) error {
+	result, err := s.session.exec(
+		`INSERT INTO shared_entries (collection, id, value) VALUES (?, ?, ?) ON CONFLICT (collection, id) DO NOTHING`,
+		collection, id, string(value),
+	)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return fmt.Errorf("%s: %w", id, persist.ErrExists)
+	}
+	return nil
+}
+
+func (s *SharedSql) Swap(collection, id, version string, value []byte) error {
+	revision, err := strconv.ParseInt(version, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid version of %s: %w", id, err)
+	}
+	result, err := s.session.exec(
+		`UPDATE shared_entries SET value = ?, revision = revision + 1 WHERE collection = ? AND id = ? AND revision = ?`,
+		string(value), collection, id, revision,
+	)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected != 0 {
+		return nil
+	}
+	if _, err := s.Get(collection, id); err != nil {
+		return err
+	}
+	return fmt.Errorf("%s: %w", id, persist.ErrConflict)
+}
+
+func (s *SharedSql) Delete(collection, id string) error {
+	_, err := s.session.exec(`DELETE FROM shared_entries WHERE collection = ? AND id = ?`, collection, id)
+	return err
+}
+
+var _ persist.SharedBackend = (*SharedSql)(nil)
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"asynclab.club/asynx/backend/pkg/client"
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/persist"
+)
+
+func sharedBackends(t *testing.T) map[string]persist.SharedBackend {
+	t.Helper()
+	mem, err := client.NewMemoryClient("dc=example,dc=org", "ou=people,dc=example,dc=org", "ou=groups,dc=example,dc=org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlClient, err := client.NewSqlClient(&config.ConfigSQL{Driver: "sqlite", DSN: filepath.Join(t.TempDir(), "asynx.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlClient.Close() })
+	sqlStore, err := NewStoreSql(sqlClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]persist.SharedBackend{
+		"ldap": NewStoreLdap(mem, "").Shared(),
+		"sql":  sqlStore.Shared(),
+	}
+}
+
+func TestSharedBackends(t *testing.T) {
+	for name, backend := range sharedBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			if entries, err := backend.List("tokens"); err != nil || len(entries) != 0 {
+				t.Fatalf("list empty collection: got %v, %v", entries, err)
+			}
+			if _, err := backend.Get("tokens", "a"); !errors.Is(err, persist.ErrNotFound) {
+				t.Errorf("get missing: got %v, want ErrNotFound", err)
+			}
+
+			if err := backend.Create("tokens", "a", []byte(`{"n":1}`)); err != nil {
+				t.Fatal(err)
+			}
+			if err := backend.Create("tokens", "a", []byte(`{"n":2}`)); !errors.Is(err, persist.ErrExists) {
+				t.Errorf("create existing: got %v, want ErrExists", err)
+			}
+			if err := backend.Create("grants", "a", []byte(`{"n":3}`)); err != nil {
+				t.Errorf("same id in another collection: %v", err)
+			}
+
+			first, err := backend.Get("tokens", "a")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if first.Id != "a" || string(first.Value) != `{"n":1}` {
+				t.Errorf("got %s=%s", first.Id, first.Value)
+			}
+
+			// 只有持有当前版本的写入能成功
+			if err := backend.Swap("tokens", "a", first.Version, []byte(`{"n":4}`)); err != nil {
+				t.Fatal(err)
+			}
+			if err := backend.Swap("tokens", "a", first.Version, []byte(`{"n":5}`)); !errors.Is(err, persist.ErrConflict) {
+				t.Errorf("swap with stale version: got %v, want ErrConflict", err)
+			}
+			// 值相同的修改也会更新版本
+			second, _ := backend.Get("tokens", "a")
+			if err := backend.Swap("tokens", "a", second.Version, second.Value); err != nil {
+				t.Fatal(err)
+			}
+			if err := backend.Swap("tokens", "a", second.Version, []byte(`{"n":6}`)); !errors.Is(err, persist.ErrConflict) {
+				t.Errorf("swap after identical write: got %v, want ErrConflict", err)
+			}
+			if err := backend.Swap("tokens", "missing", second.Version, []byte(`{}`)); !errors.Is(err, persist.ErrNotFound) {
+				t.Errorf("swap missing: got %v, want ErrNotFound", err)
+			}
+
+			if err := backend.Create("tokens", "b", []byte(`{"n":7}`)); err != nil {
+				t.Fatal(err)
+			}
+			entries, err := backend.List("tokens")
+			if err != nil {
+				t.Fatal(err)
+			}
+			values := []string{}
+			for _, entry := range entries {
+				values = append(values, entry.Id+"="+string(entry.Value))
+			}
+			slices.Sort(values)
+			if !slices.Equal(values, []string{`a={"n":4}`, `b={"n":7}`}) {
+				t.Errorf("got %v", values)
+			}
+
+			if err := backend.Delete("tokens", "a"); err != nil {
+				t.Fatal(err)
+			}
+			if err := backend.Delete("tokens", "a"); err != nil {
+				t.Errorf("delete missing: %v", err)
+			}
+			if _, err := backend.Get("tokens", "a"); !errors.Is(err, persist.ErrNotFound) {
+				t.Errorf("get deleted: got %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestSharedLdapRejectsLargeValues(t *testing.T) {
+	backend := sharedBackends(t)["ldap"]
+	large := []byte(`"` + strings.Repeat("x", maxSharedLdapValue) + `"`)
+	if err := backend.Create("broadcasts", "a", large); !errors.Is(err, persist.ErrTooLarge) {
+		t.Errorf("create: got %v, want ErrTooLarge", err)
+	}
+
+	if err := backend.Create("broadcasts", "a", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := backend.Get("broadcasts", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Swap("broadcasts", "a", entry.Version, large); !errors.Is(err, persist.ErrTooLarge) {
+		t.Errorf("swap: got %v, want ErrTooLarge", err)
+	}
+	if current, _ := backend.Get("broadcasts", "a"); string(current.Value) != `{}` {
+		t.Errorf("got %s after rejected swap", current.Value)
+	}
+}
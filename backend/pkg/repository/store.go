@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"asynclab.club/asynx/backend/pkg/client"
+	"asynclab.club/asynx/backend/pkg/persist"
+)
+
+// Store 聚合同一存储后端上的仓储
+type Store interface {
+	Users() RepositoryUser
+	Groups() RepositoryGroup
+	// Shared 返回同一后端上所有实例共享的集合存储
+	Shared() persist.SharedBackend
+	// Transaction 在一个事务内执行 fn，不支持事务的后端会直接执行 fn
+	Transaction(fn func(store Store) error) error
+	Close() error
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+type StoreLdap struct {
+	client client.DirectoryClient
+	users  *RepositoryUserLdap
+	groups *RepositoryGroupLdap
+	shared *SharedLdap
+}
+
+// NewStoreLdap 创建目录存储，共享集合保存在 sharedDn 下，为空时使用 ou=asynx,<基准 DN>
+func NewStoreLdap(client client.DirectoryClient, sharedDn string) *StoreLdap {
+	if sharedDn == "" {
+		sharedDn = client.BuildDn("ou=asynx")
+	}
+	return &StoreLdap{
+		client: client,
+		users:  NewRepositoryUserLdap(client),
+		groups: NewRepositoryGroupLdap(client),
+		shared: NewSharedLdap(client, sharedDn),
+	}
+}
+
+func (s *StoreLdap) Users() RepositoryUser         { return s.users }
+func (s *StoreLdap) Groups() RepositoryGroup       { return s.groups }
+func (s *StoreLdap) Shared() persist.SharedBackend { return s.shared }
+func (s *StoreLdap) Close() error                  { return s.client.Close() }
+
+// LDAP 没有跨条目的事务，由调用方负责补偿
+func (s *StoreLdap) Transaction(fn func(store Store) error) error {
+	return fn(s)
+}
+
+var _ Store = (*StoreLdap)(nil)
@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"slices"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/cache"
+	"asynclab.club/asynx/backend/pkg/entity"
+	"asynclab.club/asynx/backend/pkg/persist"
+)
+
+// StoreCached 为另一个 Store 加上读缓存。asynx 自己的写入会清空对应的缓存，
+// 其他副本或外部工具的修改只能等待过期或由调用方 Flush
+type StoreCached struct {
+	store  Store
+	users  *RepositoryUserCached
+	groups *RepositoryGroupCached
+}
+
+func NewStoreCached(store Store, ttl time.Duration) *StoreCached {
+	return &StoreCached{
+		store: store,
+		users: &RepositoryUserCached{
+			repository: store.Users(),
+			cache:      cache.New[any]("users", ttl),
+		},
+		groups: &RepositoryGroupCached{
+			repository: store.Groups(),
+			cache:      cache.New[any]("groups", ttl),
+		},
+	}
+}
+
+func (s *StoreCached) Users() RepositoryUser   { return s.users }
+func (s *StoreCached) Groups() RepositoryGroup { return s.groups }
+func (s *StoreCached) Close() error            { return s.store.Close() }
+
+// Shared 不经过缓存，其他实例的修改立即可见
+func (s *StoreCached) Shared() persist.SharedBackend { return s.store.Shared() }
+
+// Transaction 内的读取绕过缓存，写入照常清空缓存，结束后再清空一次以丢弃期间读到的旧值
+func (s *StoreCached) Transaction(fn func(store Store) error) error {
+	defer s.Flush()
+	return s.store.Transaction(func(store Store) error {
+		return fn(&StoreCached{
+			store:  store,
+			users:  &RepositoryUserCached{repository: store.Users(), cache: s.users.cache, bypass: true},
+			groups: &RepositoryGroupCached{repository: store.Groups(), cache: s.groups.cache, bypass: true},
+		})
+	})
+}
+
+func (s *StoreCached) Flush() {
+	s.users.cache.Flush()
+	s.groups.cache.Flush()
+}
+
+func (s *StoreCached) Stats() []cache.Stats {
+	return []cache.Stats{s.users.cache.Stats(), s.groups.cache.Stats()}
+}
+
+var _ Store = (*StoreCached)(nil)
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+// 缓存中保存的是副本，调用方修改返回的实体不会影响缓存
+
+func cloneUser(user *entity.User) *entity.User {
+	if user == nil {
+		return nil
+	}
+	clone := *user
+	return &clone
+}
+
+func cloneUsers(users []*entity.User) []*entity.User {
+	clones := make([]*entity.User, 0, len(users))
+	for _, user := range users {
+		clones = append(clones, cloneUser(user))
+	}
+	return clones
+}
+
+func cloneGroup(group *entity.Group) *entity.Group {
+	if group == nil {
+		return nil
+	}
+	clone := *group
+	clone.MemberUid = slices.Clone(group.MemberUid)
+	return &clone
+}
+
+func cloneGroups(groups []*entity.Group) []*entity.Group {
+	clones := make([]*entity.Group, 0, len(groups))
+	for _, group := range groups {
+		clones = append(clones, cloneGroup(group))
+	}
+	return clones
+}
+
+// cached 从缓存读取 key，未命中时调用 load 并缓存结果，错误不会被缓存。
+// 读取期间缓存被清空时不写回，否则与写入并发的读取可能把写入前的旧值放回缓存
+func cached[T any](c *cache.Cache[any], bypass bool, key string, clone func(T) T, load func() (T, error)) (T, error) {
+	if bypass {
+		return load()
+	}
+	if value, ok := c.Get(key); ok {
+		return clone(value.(T)), nil
+	}
+	generation := c.Generation()
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+	c.SetIfGeneration(key, clone(value), generation)
+	return value, nil
+}
+
+// invalidate 执行写操作并清空缓存，无论写入是否成功
+func invalidate(c *cache.Cache[any], write func() error) error {
+	defer c.Flush()
+	return write()
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+type RepositoryUserCached struct {
+	repository RepositoryUser
+	cache      *cache.Cache[any]
+	bypass     bool
+}
+
+// Authenticate 总是访问后端，不使用缓存
+func (r *RepositoryUserCached) Authenticate(uid, password string) (bool, error) {
+	return r.repository.Authenticate(uid, password)
+}
+
+func (r *RepositoryUserCached) FindByUid(uid string) (*entity.User, error) {
+	return cached(r.cache, r.bypass, "uid:"+uid, cloneUser, func() (*entity.User, error) {
+		return r.repository.FindByUid(uid)
+	})
+}
+
+func (r *RepositoryUserCached) FindByOuAndUid(ou string, uid string) (*entity.User, error) {
+	return cached(r.cache, r.bypass, "ou:"+ou+":uid:"+uid, cloneUser, func() (*entity.User, error) {
+		return r.repository.FindByOuAndUid(ou, uid)
+	})
+}
+
+func (r *RepositoryUserCached) FindAll() ([]*entity.User, error) {
+	return cached(r.cache, r.bypass, "all", cloneUsers, r.repository.FindAll)
+}
+
+func (r *RepositoryUserCached) FindAllByOu(ou string) ([]*entity.User, error) {
+	return cached(r.cache, r.bypass, "ou:"+ou, cloneUsers, func() ([]*entity.User, error) {
+		return r.repository.FindAllByOu(ou)
+	})
+}
+
+func (r *RepositoryUserCached) Create(user *entity.User) error {
+	return invalidate(r.cache, func() error { return r.repository.Create(user) })
+}
+
+func (r *RepositoryUserCached) ModifyAttributes(user *entity.User) error {
+	return invalidate(r.cache, func() error { return r.repository.ModifyAttributes(user) })
+}
+
+func (r *RepositoryUserCached) ModifyOu(user *entity.User, ou string) error {
+	return invalidate(r.cache, func() error { return r.repository.ModifyOu(user, ou) })
+}
+
+func (r *RepositoryUserCached) ModifyPassword(user *entity.User, newPassword string) error {
+	return invalidate(r.cache, func() error { return r.repository.ModifyPassword(user, newPassword) })
+}
+
+func (r *RepositoryUserCached) Delete(user *entity.User) error {
+	return invalidate(r.cache, func() error { return r.repository.Delete(user) })
+}
+
+var _ RepositoryUser = (*RepositoryUserCached)(nil)
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+type RepositoryGroupCached struct {
+	repository RepositoryGroup
+	cache      *cache.Cache[any]
+	bypass     bool
+}
+
+func (r *RepositoryGroupCached) FindAll() ([]*entity.Group, error) {
+	return cached(r.cache, r.bypass, "all", cloneGroups, r.repository.FindAll)
+}
+
+func (r *RepositoryGroupCached) FindAllByOu(ou string) ([]*entity.Group, error) {
+	return cached(r.cache, r.bypass, "ou:"+ou, cloneGroups, func() ([]*entity.Group, error) {
+		return r.repository.FindAllByOu(ou)
+	})
+}
+
+func (r *RepositoryGroupCached) FindByOuAndCn(ou string, cn string) (*entity.Group, error) {
+	return cached(r.cache, r.bypass, "ou:"+ou+":cn:"+cn, cloneGroup, func() (*entity.Group, error) {
+		return r.repository.FindByOuAndCn(ou, cn)
+	})
+}
+
+func (r *RepositoryGroupCached) FindAllByOuAndMemberUid(ou string, uid string) ([]*entity.Group, error) {
+	return cached(r.cache, r.bypass, "ou:"+ou+":member:"+uid, cloneGroups, func() ([]*entity.Group, error) {
+		return r.repository.FindAllByOuAndMemberUid(ou, uid)
+	})
+}
+
+func (r *RepositoryGroupCached) AddMemberUid(group *entity.Group, uid string) error {
+	return invalidate(r.cache, func() error { return r.repository.AddMemberUid(group, uid) })
+}
+
+func (r *RepositoryGroupCached) RemoveMemberUid(group *entity.Group, uid string) error {
+	return invalidate(r.cache, func() error { return r.repository.RemoveMemberUid(group, uid) })
+}
+
+var _ RepositoryGroup = (*RepositoryGroupCached)(nil)
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/entity"
+)
+
+// racingUsers 在后端读取完成、结果还没有返回时执行 during，模拟读取与写入并发
+type racingUsers struct {
+	RepositoryUser
+	during func()
+}
+
+func (r *racingUsers) FindByUid(uid string) (*entity.User, error) {
+	user, err := r.RepositoryUser.FindByUid(uid)
+	if r.during != nil {
+		during := r.during
+		r.during = nil
+		during()
+	}
+	return user, err
+}
+
+func newTestCachedStore(t *testing.T) (*StoreCached, *entity.User) {
+	t.Helper()
+	store := NewStoreCached(openSqlStore(t, filepath.Join(t.TempDir(), "asynx.db")), time.Hour)
+	user := &entity.User{Uid: "2024000001", Cn: "2024000001", Ou: "member", Sn: "张", GivenName: "三", Mail: "a@example.org"}
+	if err := store.Users().Create(user); err != nil {
+		t.Fatal(err)
+	}
+	return store, user
+}
+
+func TestCachedStoreInvalidatesOnWrite(t *testing.T) {
+	store, user := newTestCachedStore(t)
+
+	cached, err := store.Users().FindByUid(user.Uid)
+	if err != nil || cached.Mail != "a@example.org" {
+		t.Fatalf("got %+v, %v", cached, err)
+	}
+	// 返回的是副本，修改它不影响缓存
+	cached.Mail = "changed@example.org"
+	if again, _ := store.Users().FindByUid(user.Uid); again.Mail != "a@example.org" {
+		t.Errorf("caller modified the cached user: %s", again.Mail)
+	}
+
+	user.Mail = "b@example.org"
+	if err := store.Users().ModifyAttributes(user); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := store.Users().FindByUid(user.Uid); got.Mail != "b@example.org" {
+		t.Errorf("got %s after write, want the new value", got.Mail)
+	}
+}
+
+func TestCachedStoreDropsFillStartedBeforeWrite(t *testing.T) {
+	store, user := newTestCachedStore(t)
+	users := store.Users().(*RepositoryUserCached)
+
+	// 读取已经拿到旧值时另一个请求完成了写入
+	users.repository = &racingUsers{RepositoryUser: users.repository, during: func() {
+		user.Mail = "b@example.org"
+		if err := store.Users().ModifyAttributes(user); err != nil {
+			t.Fatal(err)
+		}
+	}}
+	if stale, _ := store.Users().FindByUid(user.Uid); stale.Mail != "a@example.org" {
+		t.Fatalf("got %s from the racing read", stale.Mail)
+	}
+
+	if got, _ := store.Users().FindByUid(user.Uid); got.Mail != "b@example.org" {
+		t.Errorf("got %s, the read that raced with the write was cached", got.Mail)
+	}
+}
+
+func TestCachedTransactionReadsBypassCache(t *testing.T) {
+	store, user := newTestCachedStore(t)
+	if _, err := store.Users().FindByUid(user.Uid); err != nil {
+		t.Fatal(err)
+	}
+
+	err := store.Transaction(func(tx Store) error {
+		user.Mail = "b@example.org"
+		if err := tx.Users().ModifyAttributes(user); err != nil {
+			return err
+		}
+		got, err := tx.Users().FindByUid(user.Uid)
+		if err == nil && got.Mail != "b@example.org" {
+			t.Errorf("got %s inside the transaction", got.Mail)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := store.Users().FindByUid(user.Uid); got.Mail != "b@example.org" {
+		t.Errorf("got %s after the transaction", got.Mail)
+	}
+}
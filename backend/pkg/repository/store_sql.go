@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+
+	"asynclab.club/asynx/backend/pkg/client"
+	"asynclab.club/asynx/backend/pkg/persist"
+	"github.com/sirupsen/logrus"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// sqlSession 表示一次数据库会话，tx 为空时直接使用连接池
+type sqlSession struct {
+	client *client.SqlClient
+	tx     *sql.Tx
+}
+
+func (s *sqlSession) exec(query string, args ...any) (sql.Result, error) {
+	if s.tx != nil {
+		return s.tx.Exec(s.client.Rebind(query), args...)
+	}
+	return s.client.DB().Exec(s.client.Rebind(query), args...)
+}
+
+func (s *sqlSession) query(query string, args ...any) (*sql.Rows, error) {
+	if s.tx != nil {
+		return s.tx.Query(s.client.Rebind(query), args...)
+	}
+	return s.client.DB().Query(s.client.Rebind(query), args...)
+}
+
+func (s *sqlSession) queryRow(query string, args ...any) *sql.Row {
+	if s.tx != nil {
+		return s.tx.QueryRow(s.client.Rebind(query), args...)
+	}
+	return s.client.DB().QueryRow(s.client.Rebind(query), args...)
+}
+
+// transaction 在事务中执行 fn，已处于事务中时直接复用
+func (s *sqlSession) transaction(fn func(session *sqlSession) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+
+	tx, err := s.client.DB().Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(&sqlSession{client: s.client, tx: tx}); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			logrus.Warnf("Failed to rollback transaction: %v", rbErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+type StoreSql struct {
+	session *sqlSession
+	users   *RepositoryUserSql
+	groups  *RepositoryGroupSql
+	shared  *SharedSql
+}
+
+func NewStoreSql(client *client.SqlClient) (*StoreSql, error) {
+	session := &sqlSession{client: client}
+	if err := migrate(session); err != nil {
+		return nil, err
+	}
+	return newStoreSql(session), nil
+}
+
+func newStoreSql(session *sqlSession) *StoreSql {
+	return &StoreSql{
+		session: session,
+		users:   &RepositoryUserSql{session: session},
+		groups:  &RepositoryGroupSql{session: session},
+		shared:  &SharedSql{session: session},
+	}
+}
+
+func (s *StoreSql) Users() RepositoryUser         { return s.users }
+func (s *StoreSql) Groups() RepositoryGroup       { return s.groups }
+func (s *StoreSql) Shared() persist.SharedBackend { return s.shared }
+func (s *StoreSql) Close() error                  { return s.session.client.Close() }
+
+func (s *StoreSql) Transaction(fn func(store Store) error) error {
+	return s.session.transaction(func(session *sqlSession) error {
+		return fn(newStoreSql(session))
+	})
+}
+
+var _ Store = (*StoreSql)(nil)
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+// migrate 按文件名顺序执行 migrations 目录下尚未执行的脚本
+func migrate(session *sqlSession) error {
+	if _, err := session.exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version VARCHAR(255) PRIMARY KEY)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	names, err := fs.Glob(migrationsFS, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		version := strings.TrimSuffix(strings.TrimPrefix(name, "migrations/"), ".sql")
+
+		var count int
+		if err := session.queryRow(`SELECT COUNT(*) FROM schema_migrations WHERE version = ?`, version).Scan(&count); err != nil {
+			return err
+		}
+		if count != 0 {
+			continue
+		}
+
+		content, err := migrationsFS.ReadFile(name)
+		if err != nil {
+			return err
+		}
+
+		err = session.transaction(func(session *sqlSession) error {
+			for _, stmt := range strings.Split(string(content), ";") {
+				if strings.TrimSpace(stmt) == "" {
+					continue
+				}
+				if _, err := session.exec(stmt); err != nil {
+					return err
+				}
+			}
+			_, err := session.exec(`INSERT INTO schema_migrations (version) VALUES (?)`, version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", version, err)
+		}
+		logrus.Infof("Applied migration %s", version)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"asynclab.club/asynx/backend/pkg/client"
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/entity"
+)
+
+func openSqlStore(t *testing.T, dsn string) *StoreSql {
+	t.Helper()
+	sqlClient, err := client.NewSqlClient(&config.ConfigSQL{Driver: "sqlite", DSN: dsn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlClient.Close() })
+	store, err := NewStoreSql(sqlClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestMigrationsApplyOnce(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "asynx.db")
+	first := openSqlStore(t, dsn)
+	// 再次打开时不会重复执行已执行的脚本，否则 ALTER TABLE 和种子数据会失败
+	second := openSqlStore(t, dsn)
+
+	names, err := fs.Glob(migrationsFS, "migrations/*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var applied int
+	if err := second.session.queryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied); err != nil {
+		t.Fatal(err)
+	}
+	if applied != len(names) {
+		t.Errorf("got %d applied migrations, want %d", applied, len(names))
+	}
+
+	groups, err := first.Groups().FindAllByOu("supplementary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cns []string
+	for _, group := range groups {
+		cns = append(cns, group.Cn)
+	}
+	if !slices.Equal(cns, []string{"admin", "default", "restricted"}) {
+		t.Errorf("got seeded role groups %v", cns)
+	}
+}
+
+func TestSqlUsersAndGroups(t *testing.T) {
+	store := openSqlStore(t, filepath.Join(t.TempDir(), "asynx.db"))
+	users, groups := store.Users(), store.Groups()
+
+	user := &entity.User{Uid: "2024000001", Cn: "2024000001", Ou: "member", Sn: "张", GivenName: "三", Mail: "a@example.org", UserPassword: "correct horse"}
+	if err := users.Create(user); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Create(user); err == nil {
+		t.Error("duplicate uid was accepted")
+	}
+
+	// 密码以 argon2id 哈希保存
+	var hash string
+	if err := store.session.queryRow(`SELECT password_hash FROM users WHERE uid = ?`, user.Uid).Scan(&hash); err != nil {
+		t.Fatal(err)
+	}
+	if hash == user.UserPassword || hash[:10] != "$argon2id$" {
+		t.Errorf("password stored as %q", hash)
+	}
+	for password, want := range map[string]bool{"correct horse": true, "wrong": false, "": false} {
+		if ok, err := users.Authenticate(user.Uid, password); err != nil || ok != want {
+			t.Errorf("authenticate with %q: got %v, %v, want %v", password, ok, err, want)
+		}
+	}
+	if ok, err := users.Authenticate("2024999999", "correct horse"); err != nil || ok {
+		t.Errorf("authenticate unknown user: got %v, %v", ok, err)
+	}
+
+	user.Mail = "b@example.org"
+	user.UserPassword = "battery staple"
+	if err := users.ModifyAttributes(user); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := users.FindByOuAndUid("member", user.Uid); err != nil || got == nil || got.Mail != "b@example.org" {
+		t.Errorf("got %+v, %v after modify", got, err)
+	}
+	if ok, _ := users.Authenticate(user.Uid, "battery staple"); !ok {
+		t.Error("password was not changed by ModifyAttributes")
+	}
+	if err := users.ModifyOu(&entity.User{Uid: "2024999999"}, "alumni"); err == nil {
+		t.Error("modify of a missing user succeeded")
+	}
+
+	role, err := groups.FindByOuAndCn("supplementary", "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := groups.AddMemberUid(role, user.Uid); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := groups.FindAllByOuAndMemberUid("supplementary", user.Uid); err != nil || len(found) != 1 || found[0].Cn != "default" {
+		t.Errorf("got groups %+v, %v", found, err)
+	}
+	if err := groups.RemoveMemberUid(role, user.Uid); err != nil {
+		t.Fatal(err)
+	}
+	if err := groups.RemoveMemberUid(role, user.Uid); err == nil {
+		t.Error("removing a missing member succeeded")
+	}
+}
+
+func TestSqlTransactionRollsBack(t *testing.T) {
+	store := openSqlStore(t, filepath.Join(t.TempDir(), "asynx.db"))
+	errAbort := errors.New("abort")
+
+	err := store.Transaction(func(tx Store) error {
+		if err := tx.Users().Create(&entity.User{Uid: "2024000001", Cn: "2024000001", Ou: "member"}); err != nil {
+			return err
+		}
+		role, err := tx.Groups().FindByOuAndCn("supplementary", "default")
+		if err != nil {
+			return err
+		}
+		if err := tx.Groups().AddMemberUid(role, "2024000001"); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("got %v, want the error of fn", err)
+	}
+	if user, err := store.Users().FindByUid("2024000001"); err != nil || user != nil {
+		t.Errorf("user survived the rollback: %+v, %v", user, err)
+	}
+	if found, err := store.Groups().FindAllByOuAndMemberUid("supplementary", "2024000001"); err != nil || len(found) != 0 {
+		t.Errorf("membership survived the rollback: %+v, %v", found, err)
+	}
+}
+
+func TestSqlKeepsEmployeeType(t *testing.T) {
+	store := openSqlStore(t, filepath.Join(t.TempDir(), "asynx.db"))
+	user := &entity.User{Uid: "svc-ci", Cn: "svc-ci", Ou: "system", LoginShell: "/usr/sbin/nologin", EmployeeType: "service-account"}
+	if err := store.Users().Create(user); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := store.Users().FindByUid("svc-ci"); err != nil || got.EmployeeType != "service-account" {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+	user.EmployeeType = ""
+	if err := store.Users().ModifyAttributes(user); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := store.Users().FindByUid("svc-ci"); got.EmployeeType != "" {
+		t.Errorf("employee type %q was not cleared", got.EmployeeType)
+	}
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"fmt"
+
+	"asynclab.club/asynx/backend/pkg/client"
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/entity"
+	"asynclab.club/asynx/backend/pkg/transfer"
+	"github.com/go-ldap/ldap/v3"
+)
+
+type RepositoryUserLdap struct {
+	client client.DirectoryClient
+}
+
+func NewRepositoryUserLdap(client client.DirectoryClient) *RepositoryUserLdap {
+	return &RepositoryUserLdap{
+		client: client,
+	}
+}
+
+func (r *RepositoryUserLdap) GetUserBaseDn() string {
+	return r.client.GetUserBaseDn()
+}
+
+func (r *RepositoryUserLdap) BuildDn(user *entity.User) string {
+	return fmt.Sprintf("cn=%s,ou=%s,%s", user.Cn, user.Ou, r.GetUserBaseDn())
+}
+
+func (r *RepositoryUserLdap) Authenticate(uid, password string) (bool, error) {
+	user, err := r.FindByUid(uid)
+	if err != nil {
+		return false, err
+	}
+	if user == nil {
+		return false, nil
+	}
+	return r.client.Authenticate(r.BuildDn(user), password)
+}
+
+func (r *RepositoryUserLdap) find(rdn string, filter string) ([]*entity.User, error) {
+	baseDN := r.GetUserBaseDn()
+	if rdn != "" {
+		baseDN = fmt.Sprintf("%s,%s", rdn, baseDN)
+	}
+
+	result, err := r.client.Search(baseDN, fmt.Sprintf("(&(%s)(%s))", config.UserObjectFilter, filter), config.UserAttributes)
+	if err != nil {
+		return nil, err
+	}
+	users := make([]*entity.User, 0, len(result.Entries))
+	for _, entry := range result.Entries {
+		user, err := transfer.ParseFromLdap[entity.User](entry)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func (r *RepositoryUserLdap) FindByUid(uid string) (user *entity.User, err error) {
+	users, err := r.find("", fmt.Sprintf("uid=%s", uid))
+	if len(users) != 0 {
+		user = users[0]
+	}
+	return
+}
+
+func (r *RepositoryUserLdap) FindByOuAndUid(ou string, uid string) (user *entity.User, err error) {
+	users, err := r.find(fmt.Sprintf("ou=%s", ou), fmt.Sprintf("(uid=%s)", uid))
+	if len(users) != 0 {
+		user = users[0]
+	}
+	return
+}
+
+func (r *RepositoryUserLdap) FindAll() ([]*entity.User, error) {
+	return r.find("", "objectClass=*")
+}
+
+func (r *RepositoryUserLdap) FindAllByOu(ou string) ([]*entity.User, error) {
+	return r.find(fmt.Sprintf("ou=%s", ou), "objectClass=*")
+}
+
+func (r *RepositoryUserLdap) Create(user *entity.User) error {
+	attributes, err := transfer.ParseToLdapAttributes(user)
+	if err != nil {
+		return err
+	}
+
+	return r.client.Add(r.BuildDn(user), config.UserObjectClasses, attributes)
+}
+
+func (r *RepositoryUserLdap) ModifyAttributes(user *entity.User) error {
+	attributes, err := transfer.ParseToLdapAttributes(user)
+	if err != nil {
+		return err
+	}
+
+	dn := r.BuildDn(user)
+	if user.PreferredLanguage == "" {
+		// 替换为空值即恢复默认语言
+		attributes["preferredLanguage"] = nil
+	}
+	if user.Description == "" {
+		attributes["description"] = nil
+	}
+	if user.EmployeeType == "" {
+		attributes["employeeType"] = nil
+	}
+	if user.ShadowExpire == "" {
+		// 替换为空值即删除到期日
+		attributes["shadowExpire"] = nil
+	} else {
+		// 早先创建的用户没有 shadowAccount，设置到期日前补上
+		err := r.client.ModifyAttributes(dn, map[string][]string{"objectClass": {"shadowAccount"}}, nil, nil)
+		if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultAttributeOrValueExists) {
+			return err
+		}
+	}
+
+	return r.client.ModifyAttributes(dn, nil, nil, attributes)
+}
+
+func (r *RepositoryUserLdap) ModifyOu(user *entity.User, ou string) error {
+	return r.client.ModifyDn(r.BuildDn(user), fmt.Sprintf("cn=%s", user.Cn), fmt.Sprintf("ou=%s,%s", ou, r.GetUserBaseDn()))
+}
+
+func (r *RepositoryUserLdap) ModifyPassword(user *entity.User, newPassword string) error {
+	return r.client.ModifyPassword(r.BuildDn(user), newPassword)
+}
+
+func (r *RepositoryUserLdap) Delete(user *entity.User) error {
+	return r.client.Delete(r.BuildDn(user))
+}
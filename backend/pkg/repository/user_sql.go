@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"asynclab.club/asynx/backend/pkg/entity"
+	"asynclab.club/asynx/backend/pkg/security"
+)
+
+const userColumns = `uid, cn, ou, sn, given_name, gid_number, uid_number, home_directory, mail, login_shell, preferred_language, shadow_expire, description, employee_type`
+
+type RepositoryUserSql struct {
+	session *sqlSession
+}
+
+func scanUsers(rows *sql.Rows) ([]*entity.User, error) {
+	defer rows.Close()
+
+	users := make([]*entity.User, 0)
+	for rows.Next() {
+		user := &entity.User{}
+		if err := rows.Scan(
+			&user.Uid, &user.Cn, &user.Ou, &user.Sn, &user.GivenName, &user.GidNumber,
+			&user.UidNumber, &user.HomeDirectory, &user.Mail, &user.LoginShell, &user.PreferredLanguage, &user.ShadowExpire, &user.Description, &user.EmployeeType,
+		); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+func (r *RepositoryUserSql) find(where string, args ...any) ([]*entity.User, error) {
+	query := fmt.Sprintf(`SELECT %s FROM users`, userColumns)
+	if where != "" {
+		query = fmt.Sprintf("%s WHERE %s", query, where)
+	}
+	rows, err := r.session.query(query+` ORDER BY uid`, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanUsers(rows)
+}
+
+func (r *RepositoryUserSql) Authenticate(uid, password string) (bool, error) {
+	if uid == "" || password == "" {
+		return false, nil
+	}
+
+	var hash string
+	err := r.session.queryRow(`SELECT password_hash FROM users WHERE uid = ?`, uid).Scan(&hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if hash == "" {
+		return false, nil
+	}
+	return security.VerifyPassword(hash, password)
+}
+
+func (r *RepositoryUserSql) FindByUid(uid string) (user *entity.User, err error) {
+	users, err := r.find(`uid = ?`, uid)
+	if len(users) != 0 {
+		user = users[0]
+	}
+	return
+}
+
+func (r *RepositoryUserSql) FindByOuAndUid(ou string, uid string) (user *entity.User, err error) {
+	users, err := r.find(`ou = ? AND uid = ?`, ou, uid)
+	if len(users) != 0 {
+		user = users[0]
+	}
+	return
+}
+
+func (r *RepositoryUserSql) FindAll() ([]*entity.User, error) {
+	return r.find("")
+}
+
+func (r *RepositoryUserSql) FindAllByOu(ou string) ([]*entity.User, error) {
+	return r.find(`ou = ?`, ou)
+}
+
+func (r *RepositoryUserSql) Create(user *entity.User) error {
+	hash := ""
+	if user.UserPassword != "" {
+		var err error
+		if hash, err = security.HashPassword(user.UserPassword); err != nil {
+			return err
+		}
+	}
+
+	_, err := r.session.exec(
+		fmt.Sprintf(`INSERT INTO users (%s, password_hash) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, userColumns),
+		user.Uid, user.Cn, user.Ou, user.Sn, user.GivenName, user.GidNumber,
+		user.UidNumber, user.HomeDirectory, user.Mail, user.LoginShell, user.PreferredLanguage, user.ShadowExpire, user.Description, user.EmployeeType, hash,
+	)
+	return err
+}
+
+func (r *RepositoryUserSql) ModifyAttributes(user *entity.User) error {
+	return r.session.transaction(func(session *sqlSession) error {
+		result, err := session.exec(
+			`UPDATE users SET sn = ?, given_name = ?, gid_number = ?, uid_number = ?, home_directory = ?, mail = ?, login_shell = ?, preferred_language = ?, shadow_expire = ?, description = ?, employee_type = ? WHERE uid = ?`,
+			user.Sn, user.GivenName, user.GidNumber, user.UidNumber, user.HomeDirectory, user.Mail, user.LoginShell, user.PreferredLanguage, user.ShadowExpire, user.Description, user.EmployeeType, user.Uid,
+		)
+		if err := checkAffected(result, err, user.Uid); err != nil {
+			return err
+		}
+		if user.UserPassword != "" {
+			return (&RepositoryUserSql{session: session}).ModifyPassword(user, user.UserPassword)
+		}
+		return nil
+	})
+}
+
+func (r *RepositoryUserSql) ModifyOu(user *entity.User, ou string) error {
+	result, err := r.session.exec(`UPDATE users SET ou = ? WHERE uid = ?`, ou, user.Uid)
+	return checkAffected(result, err, user.Uid)
+}
+
+func (r *RepositoryUserSql) ModifyPassword(user *entity.User, newPassword string) error {
+	hash, err := security.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	result, err := r.session.exec(`UPDATE users SET password_hash = ? WHERE uid = ?`, hash, user.Uid)
+	return checkAffected(result, err, user.Uid)
+}
+
+func (r *RepositoryUserSql) Delete(user *entity.User) error {
+	result, err := r.session.exec(`DELETE FROM users WHERE uid = ?`, user.Uid)
+	return checkAffected(result, err, user.Uid)
+}
+
+func checkAffected(result sql.Result, err error, key string) error {
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("no such object: %s", key)
+	}
+	return nil
+}
+
+var _ RepositoryUser = (*RepositoryUserSql)(nil)
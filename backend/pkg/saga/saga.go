@@ -0,0 +1,336 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/persist"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type OperationState string
+
+const (
+	OperationRunning      OperationState = "running"
+	OperationSucceeded    OperationState = "succeeded"
+	OperationCompensating OperationState = "compensating"
+	OperationCompensated  OperationState = "compensated"
+	// OperationFailed 表示补偿也失败了，需要管理员介入
+	OperationFailed OperationState = "failed"
+)
+
+type StepState string
+
+const (
+	StepPending     StepState = "pending"
+	StepRunning     StepState = "running"
+	StepDone        StepState = "done"
+	StepCompensated StepState = "compensated"
+)
+
+// RecoverPolicy 决定进程重启后如何处理未完成的操作
+type RecoverPolicy string
+
+const (
+	// RecoverResume 从第一个未完成的步骤继续执行，要求每个步骤都是幂等的
+	RecoverResume RecoverPolicy = "resume"
+	// RecoverRollback 对所有已开始的步骤执行补偿，要求每个补偿都是幂等的
+	RecoverRollback RecoverPolicy = "rollback"
+)
+
+type StepRecord struct {
+	Name      string    `json:"name"`
+	State     StepState `json:"state"`
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type Operation struct {
+	Id        string          `json:"id"`
+	Kind      string          `json:"kind"`
+	State     OperationState  `json:"state"`
+	Payload   json.RawMessage `json:"payload" swaggertype:"object"`
+	Steps     []StepRecord    `json:"steps"`
+	Error     string          `json:"error,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+}
+
+func (o *Operation) Finished() bool {
+	return o.State == OperationSucceeded || o.State == OperationCompensated
+}
+
+// Step 是操作中的一个步骤。payload 中标记为 json:"-" 的字段不会写入日志，
+// 因此重启恢复时不可用，依赖这类字段的操作应使用 RecoverRollback。
+type Step[P any] struct {
+	Name       string
+	Action     func(payload *P) error
+	Compensate func(payload *P) error
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+type step struct {
+	name       string
+	action     func(payload any) error
+	compensate func(payload any) error
+}
+
+type definition struct {
+	policy     RecoverPolicy
+	steps      []step
+	newPayload func() any
+}
+
+type Coordinator struct {
+	mu          sync.Mutex
+	journal     *persist.Collection[Operation]
+	definitions map[string]*definition
+}
+
+// NewCoordinator 打开操作日志，每个操作保存为单独的文件，记录一个步骤只需要重写该操作的文件
+func NewCoordinator(dataDir string) (*Coordinator, error) {
+	journal, err := persist.OpenEntryCollection[Operation](dataDir, "operations")
+	if err != nil {
+		return nil, err
+	}
+	return &Coordinator{journal: journal, definitions: make(map[string]*definition)}, nil
+}
+
+func Register[P any](c *Coordinator, kind string, policy RecoverPolicy, steps ...Step[P]) {
+	def := &definition{
+		policy:     policy,
+		newPayload: func() any { return new(P) },
+	}
+	for _, s := range steps {
+		st := step{name: s.Name, action: func(payload any) error { return s.Action(payload.(*P)) }}
+		if s.Compensate != nil {
+			st.compensate = func(payload any) error { return s.Compensate(payload.(*P)) }
+		}
+		def.steps = append(def.steps, st)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.definitions[kind] = def
+}
+
+// Execute 同步执行一个操作，任一步骤失败时按相反顺序补偿已开始的步骤，并返回该步骤的错误
+func Execute[P any](c *Coordinator, kind string, payload *P) error {
+	def, err := c.definition(kind)
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	op := Operation{
+		Id:        uuid.NewString(),
+		Kind:      kind,
+		State:     OperationRunning,
+		Payload:   raw,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	for _, s := range def.steps {
+		op.Steps = append(op.Steps, StepRecord{Name: s.name, State: StepPending, UpdatedAt: now})
+	}
+	if err := c.journal.Put(op.Id, op); err != nil {
+		return fmt.Errorf("failed to record operation: %w", err)
+	}
+
+	return c.run(op.Id, def, payload)
+}
+
+func (c *Coordinator) definition(kind string) (*definition, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	def, ok := c.definitions[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown operation kind: %s", kind)
+	}
+	return def, nil
+}
+
+func (c *Coordinator) setStep(id string, idx int, state StepState, stepErr error) error {
+	return c.journal.Update(id, func(op *Operation) error {
+		op.Steps[idx].State = state
+		if stepErr != nil {
+			op.Steps[idx].Error = stepErr.Error()
+		} else if state == StepDone {
+			op.Steps[idx].Error = ""
+		}
+		op.Steps[idx].UpdatedAt = time.Now()
+		op.UpdatedAt = op.Steps[idx].UpdatedAt
+		return nil
+	})
+}
+
+func (c *Coordinator) setState(id string, state OperationState, opErr error) error {
+	return c.journal.Update(id, func(op *Operation) error {
+		op.State = state
+		if opErr != nil {
+			op.Error = opErr.Error()
+		}
+		op.UpdatedAt = time.Now()
+		return nil
+	})
+}
+
+// run 从第一个未完成的步骤开始向前执行
+func (c *Coordinator) run(id string, def *definition, payload any) error {
+	op, _ := c.journal.Get(id)
+
+	for idx, s := range def.steps {
+		if op.Steps[idx].State == StepDone {
+			continue
+		}
+
+		if err := c.setStep(id, idx, StepRunning, nil); err != nil {
+			return err
+		}
+		if err := s.action(payload); err != nil {
+			_ = c.setStep(id, idx, StepRunning, err)
+			if compErr := c.compensate(id, def, payload, err); compErr != nil {
+				logrus.Errorf("Operation %s (%s) failed and could not be compensated: %v", id, op.Kind, compErr)
+			}
+			return err
+		}
+		if err := c.setStep(id, idx, StepDone, nil); err != nil {
+			return err
+		}
+	}
+
+	return c.setState(id, OperationSucceeded, nil)
+}
+
+// compensate 按相反顺序补偿所有已开始的步骤，已开始但未完成的步骤同样需要补偿，因为它可能已部分生效
+func (c *Coordinator) compensate(id string, def *definition, payload any, cause error) error {
+	if err := c.setState(id, OperationCompensating, cause); err != nil {
+		return err
+	}
+
+	op, _ := c.journal.Get(id)
+	for idx := len(def.steps) - 1; idx >= 0; idx-- {
+		state := op.Steps[idx].State
+		if state != StepDone && state != StepRunning {
+			continue
+		}
+
+		if s := def.steps[idx]; s.compensate != nil {
+			if err := s.compensate(payload); err != nil {
+				_ = c.setStep(id, idx, state, err)
+				_ = c.setState(id, OperationFailed, nil)
+				return fmt.Errorf("compensation of step %s failed: %w", s.name, err)
+			}
+		}
+		if err := c.setStep(id, idx, StepCompensated, nil); err != nil {
+			return err
+		}
+	}
+
+	return c.setState(id, OperationCompensated, nil)
+}
+
+// Recover 处理上次进程退出时未完成的操作，应在所有操作注册之后、开始处理请求之前调用
+func (c *Coordinator) Recover() error {
+	var errs []error
+	for _, op := range c.journal.List() {
+		if op.State != OperationRunning && op.State != OperationCompensating {
+			continue
+		}
+
+		def, err := c.definition(op.Kind)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("operation %s: %w", op.Id, err))
+			continue
+		}
+
+		payload := def.newPayload()
+		if err := json.Unmarshal(op.Payload, payload); err != nil {
+			errs = append(errs, fmt.Errorf("operation %s: %w", op.Id, err))
+			continue
+		}
+
+		if op.State == OperationRunning && def.policy == RecoverResume {
+			logrus.Warnf("Resuming unfinished operation %s (%s)", op.Id, op.Kind)
+			err = c.run(op.Id, def, payload)
+		} else {
+			logrus.Warnf("Rolling back unfinished operation %s (%s)", op.Id, op.Kind)
+			err = c.compensate(op.Id, def, payload, errors.New("interrupted"))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("operation %s: %w", op.Id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Retry 重新执行失败操作的补偿
+func (c *Coordinator) Retry(id string) error {
+	op, ok := c.journal.Get(id)
+	if !ok {
+		return fmt.Errorf("operation %s: %w", id, persist.ErrNotFound)
+	}
+	if op.State != OperationFailed {
+		return fmt.Errorf("operation %s is %s, only failed operations can be retried", id, op.State)
+	}
+
+	def, err := c.definition(op.Kind)
+	if err != nil {
+		return err
+	}
+	payload := def.newPayload()
+	if err := json.Unmarshal(op.Payload, payload); err != nil {
+		return err
+	}
+	return c.compensate(id, def, payload, nil)
+}
+
+func (c *Coordinator) Get(id string) (Operation, bool) {
+	return c.journal.Get(id)
+}
+
+// List 按创建时间倒序返回所有操作
+func (c *Coordinator) List() []Operation {
+	ops := c.journal.List()
+	slices.SortFunc(ops, func(a, b Operation) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return ops
+}
+
+// Prune 删除早于 before 的已结束操作
+func (c *Coordinator) Prune(before time.Time) (int, error) {
+	return c.journal.DeleteFunc(func(_ string, op Operation) bool {
+		return op.Finished() && op.UpdatedAt.Before(before)
+	})
+}
+
+// Run 每小时清理一次超过保留期的已结束操作，直到 ctx 结束。操作日志属于单个实例，因此每个实例都要运行
+func (c *Coordinator) Run(ctx context.Context, retention time.Duration) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		n, err := c.Prune(time.Now().Add(-retention))
+		if err != nil {
+			logrus.Errorf("Failed to prune finished operations: %v", err)
+		} else if n > 0 {
+			logrus.Infof("Pruned %d finished operations", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package saga
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type payload struct {
+	Uid string `json:"uid"`
+}
+
+// recorder 记录步骤和补偿的执行顺序
+type recorder struct {
+	calls []string
+	fail  string
+}
+
+func (r *recorder) step(name string) Step[payload] {
+	return Step[payload]{
+		Name: name,
+		Action: func(p *payload) error {
+			r.calls = append(r.calls, name)
+			if r.fail == name {
+				return errors.New(name + " failed")
+			}
+			return nil
+		},
+		Compensate: func(p *payload) error {
+			r.calls = append(r.calls, "undo "+name)
+			return nil
+		},
+	}
+}
+
+func TestFailedStepCompensatesStartedSteps(t *testing.T) {
+	c, err := NewCoordinator(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := &recorder{fail: "grant"}
+	Register(c, "register", RecoverRollback, rec.step("create"), rec.step("grant"), rec.step("mail"))
+
+	if err := Execute(c, "register", &payload{Uid: "2024000001"}); err == nil {
+		t.Fatal("operation succeeded, want the error of grant")
+	}
+	want := []string{"create", "grant", "undo grant", "undo create"}
+	if len(rec.calls) != len(want) {
+		t.Fatalf("got calls %v, want %v", rec.calls, want)
+	}
+	for i := range want {
+		if rec.calls[i] != want[i] {
+			t.Fatalf("got calls %v, want %v", rec.calls, want)
+		}
+	}
+	ops := c.List()
+	if len(ops) != 1 || ops[0].State != OperationCompensated || ops[0].Steps[2].State != StepPending {
+		t.Errorf("got journal %+v", ops)
+	}
+}
+
+func TestRecoverResumesFromJournal(t *testing.T) {
+	dir := t.TempDir()
+	c, err := NewCoordinator(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 模拟进程在第二步执行时退出
+	now := time.Now()
+	op := Operation{Id: "op-1", Kind: "register", State: OperationRunning, Payload: []byte(`{"uid":"2024000001"}`), CreatedAt: now, UpdatedAt: now}
+	op.Steps = []StepRecord{{Name: "create", State: StepDone}, {Name: "grant", State: StepRunning}}
+	if err := c.journal.Put(op.Id, op); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted, err := NewCoordinator(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := &recorder{}
+	Register(restarted, "register", RecoverResume, rec.step("create"), rec.step("grant"))
+	if err := restarted.Recover(); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.calls) != 1 || rec.calls[0] != "grant" {
+		t.Errorf("got calls %v, want only grant to run again", rec.calls)
+	}
+	if got, _ := restarted.Get("op-1"); got.State != OperationSucceeded {
+		t.Errorf("got state %s after recovery, want succeeded", got.State)
+	}
+}
+
+func TestPruneKeepsUnfinishedOperations(t *testing.T) {
+	c, err := NewCoordinator(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	for id, state := range map[string]OperationState{"done": OperationSucceeded, "failed": OperationFailed, "running": OperationRunning} {
+		if err := c.journal.Put(id, Operation{Id: id, State: state, CreatedAt: old, UpdatedAt: old}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := c.Prune(time.Now().Add(-24 * time.Hour))
+	if err != nil || n != 1 {
+		t.Fatalf("pruned %d, %v, want 1", n, err)
+	}
+	if _, ok := c.Get("done"); ok {
+		t.Error("finished operation was kept")
+	}
+	for _, id := range []string{"failed", "running"} {
+		if _, ok := c.Get(id); !ok {
+			t.Errorf("operation %s was pruned", id)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/client"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Lock 是选主用的租约锁，同一时刻只有一个持有者
+type Lock interface {
+	// TryAcquire 尝试获取或续期租约，返回当前是否持有租约以及租约的持有者
+	TryAcquire(holder string, ttl time.Duration) (bool, string, error)
+	Release(holder string) error
+}
+
+// LocalLock 总是成功，用于没有共享目录的单实例部署
+type LocalLock struct{}
+
+func (LocalLock) TryAcquire(holder string, _ time.Duration) (bool, string, error) {
+	return true, holder, nil
+}
+
+func (LocalLock) Release(string) error { return nil }
+
+// DirectoryLock 把租约保存在目录条目的 description 属性中，格式为 "<持有者> <到期 Unix 时间>"。
+// 修改时删除旧值并添加新值，旧值已被其他实例改掉时目录会拒绝整个请求，以此实现比较并交换
+type DirectoryLock struct {
+	client client.DirectoryClient
+	dn     string
+}
+
+func NewDirectoryLock(client client.DirectoryClient, dn string) *DirectoryLock {
+	return &DirectoryLock{client: client, dn: dn}
+}
+
+type lease struct {
+	holder    string
+	expiresAt time.Time
+}
+
+func parseLease(value string) (lease, error) {
+	holder, expiresAt, ok := strings.Cut(value, " ")
+	if !ok {
+		return lease{}, fmt.Errorf("invalid lease %q", value)
+	}
+	unix, err := strconv.ParseInt(expiresAt, 10, 64)
+	if err != nil {
+		return lease{}, fmt.Errorf("invalid lease %q: %w", value, err)
+	}
+	return lease{holder: holder, expiresAt: time.Unix(unix, 0)}, nil
+}
+
+func (l lease) String() string {
+	return fmt.Sprintf("%s %d", l.holder, l.expiresAt.Unix())
+}
+
+// read 读取当前租约，条目不存在时返回空字符串
+func (l *DirectoryLock) read() (string, error) {
+	result, err := l.client.Search(l.dn, "(objectClass=*)", []string{"description"})
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(result.Entries) == 0 {
+		return "", nil
+	}
+	return result.Entries[0].GetAttributeValue("description"), nil
+}
+
+func (l *DirectoryLock) TryAcquire(holder string, ttl time.Duration) (bool, string, error) {
+	now := time.Now()
+	next := lease{holder: holder, expiresAt: now.Add(ttl)}
+
+	current, err := l.read()
+	if err != nil {
+		return false, "", err
+	}
+	if current == "" {
+		cn := strings.TrimPrefix(strings.Split(l.dn, ",")[0], "cn=")
+		err := l.client.Add(l.dn, []string{"top", "organizationalRole"}, map[string][]string{
+			"cn":          {cn},
+			"description": {next.String()},
+		})
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultEntryAlreadyExists) {
+			return false, "", nil
+		}
+		if err != nil {
+			return false, "", err
+		}
+		return true, holder, nil
+	}
+
+	existing, err := parseLease(current)
+	if err != nil {
+		return false, "", err
+	}
+	if existing.holder != holder && now.Before(existing.expiresAt) {
+		return false, existing.holder, nil
+	}
+	if current == next.String() {
+		// 同一秒内续约，租约没有变化
+		return true, holder, nil
+	}
+
+	err = l.client.ModifyAttributes(l.dn, map[string][]string{"description": {next.String()}}, map[string][]string{"description": {current}}, nil)
+	if ldap.IsErrorAnyOf(err, ldap.LDAPResultNoSuchAttribute, ldap.LDAPResultAttributeOrValueExists) {
+		// 其他实例抢先修改了租约
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	return true, holder, nil
+}
+
+// Release 让租约立即过期，其他实例下次尝试时即可接管
+func (l *DirectoryLock) Release(holder string) error {
+	current, err := l.read()
+	if err != nil || current == "" {
+		return err
+	}
+	existing, err := parseLease(current)
+	if err != nil || existing.holder != holder {
+		return err
+	}
+	released := lease{holder: holder, expiresAt: time.Unix(0, 0)}
+	return l.client.ModifyAttributes(l.dn, map[string][]string{"description": {released.String()}}, map[string][]string{"description": {current}}, nil)
+}
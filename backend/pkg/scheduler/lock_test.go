@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/client"
+)
+
+const testLockDn = "cn=scheduler,dc=example,dc=org"
+
+func newTestDirectory(t *testing.T) *client.MemoryClient {
+	t.Helper()
+	c, err := client.NewMemoryClient("dc=example,dc=org", "ou=People,dc=example,dc=org", "ou=Groups,dc=example,dc=org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestDirectoryLockLease(t *testing.T) {
+	directory := newTestDirectory(t)
+	lockA, lockB := NewDirectoryLock(directory, testLockDn), NewDirectoryLock(directory, testLockDn)
+
+	steps := []struct {
+		name       string
+		lock       *DirectoryLock
+		holder     string
+		ttl        time.Duration
+		wantOk     bool
+		wantHolder string
+	}{
+		{"first instance creates the lease", lockA, "a", time.Minute, true, "a"},
+		{"second instance sees the holder", lockB, "b", time.Minute, false, "a"},
+		{"holder renews", lockA, "a", 2 * time.Minute, true, "a"},
+		// 以负的有效期续约，租约立即过期
+		{"holder renews with an expired lease", lockA, "a", -time.Minute, true, "a"},
+		{"expired lease is taken over", lockB, "b", time.Minute, true, "b"},
+		{"previous holder loses the lease", lockA, "a", time.Minute, false, "b"},
+	}
+	for _, step := range steps {
+		ok, holder, err := step.lock.TryAcquire(step.holder, step.ttl)
+		if err != nil || ok != step.wantOk || holder != step.wantHolder {
+			t.Fatalf("%s: got %v %q %v, want %v %q", step.name, ok, holder, err, step.wantOk, step.wantHolder)
+		}
+	}
+
+	// 只有持有者可以释放，释放后其他实例立即接管
+	if err := lockA.Release("a"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _, _ := lockA.TryAcquire("a", time.Minute); ok {
+		t.Error("release by a non-holder freed the lease")
+	}
+	if err := lockB.Release("b"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, holder, err := lockA.TryAcquire("a", time.Minute); err != nil || !ok || holder != "a" {
+		t.Errorf("got %v %q %v after release, want a to take over", ok, holder, err)
+	}
+}
+
+// race 让多个实例同时获取租约，返回获取成功的实例数
+func race(t *testing.T, directory *client.MemoryClient) int {
+	t.Helper()
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	winners := 0
+	for _, holder := range []string{"a", "b", "c", "d", "e"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, _, err := NewDirectoryLock(directory, testLockDn).TryAcquire(holder, time.Minute)
+			if err != nil {
+				t.Error(err)
+			}
+			if ok {
+				mu.Lock()
+				winners++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return winners
+}
+
+func TestDirectoryLockHasOneWinner(t *testing.T) {
+	if winners := race(t, newTestDirectory(t)); winners != 1 {
+		t.Errorf("%d instances created the lease, want 1", winners)
+	}
+
+	// 接管过期的租约时同样只有一个实例成功
+	directory := newTestDirectory(t)
+	if _, _, err := NewDirectoryLock(directory, testLockDn).TryAcquire("crashed", -time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if winners := race(t, directory); winners != 1 {
+		t.Errorf("%d instances took over the expired lease, want 1", winners)
+	}
+}
+
+func TestLocalLockAlwaysSucceeds(t *testing.T) {
+	if ok, holder, err := (LocalLock{}).TryAcquire("a", time.Minute); !ok || holder != "a" || err != nil {
+		t.Errorf("got %v %q %v", ok, holder, err)
+	}
+}
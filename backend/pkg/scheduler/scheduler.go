@@ -0,0 +1,380 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/persist"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobRunning  = errors.New("job is already running")
+	// ErrNotLeader 表示本实例不是主实例，任务只能在主实例上运行
+	ErrNotLeader = errors.New("not the scheduler leader")
+)
+
+// Disabled 作为任务的 cron 表达式时表示不定时运行，只能手动触发
+const Disabled = "-"
+
+type JobStatus string
+
+const (
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+)
+
+type Func func(ctx context.Context) error
+
+// JobState 是任务的运行记录，持久化以便重启后继续按计划运行
+type JobState struct {
+	Name          string     `json:"name"`
+	Description   string     `json:"description"`
+	Schedule      string     `json:"schedule"`
+	Running       bool       `json:"running"`
+	NextRunAt     *time.Time `json:"nextRunAt,omitempty"` // 禁用的任务没有下次运行时间
+	LastRunAt     *time.Time `json:"lastRunAt,omitempty"`
+	LastRunBy     string     `json:"lastRunBy,omitempty"` // 上次运行任务的实例
+	LastDuration  string     `json:"lastDuration,omitempty"`
+	LastStatus    JobStatus  `json:"lastStatus,omitempty"`
+	LastError     string     `json:"lastError,omitempty"`
+	LastSuccessAt *time.Time `json:"lastSuccessAt,omitempty"`
+	Runs          int        `json:"runs"`
+	Failures      int        `json:"failures"`
+}
+
+type job struct {
+	name     string
+	schedule cron.Schedule // 为 nil 时表示禁用
+	fn       Func
+}
+
+// Scheduler 按 cron 表达式运行后台任务。多个实例共享同一个 Lock 时只有持有租约的主实例运行任务
+type Scheduler struct {
+	lock      Lock
+	instance  string
+	ttl       time.Duration
+	overrides map[string]string
+	states    *persist.Collection[JobState]
+
+	mu        sync.Mutex
+	ctx       context.Context // Run 的 ctx，手动触发的任务也随之取消
+	jobs      map[string]*job
+	leader    bool
+	holder    string
+	checkedAt time.Time // 上次尝试获取租约的时间
+	renewedAt time.Time // 上次成功获取租约的时间
+	wg        sync.WaitGroup
+}
+
+func New(lock Lock, instance string, ttl time.Duration, overrides map[string]string, dataDir string) (*Scheduler, error) {
+	states, err := persist.OpenCollection[JobState](dataDir, "jobs")
+	if err != nil {
+		return nil, err
+	}
+	return &Scheduler{
+		lock:      lock,
+		instance:  instance,
+		ttl:       ttl,
+		overrides: overrides,
+		states:    states,
+		jobs:      make(map[string]*job),
+	}, nil
+}
+
+// Register 注册任务，schedule 会被配置中的同名表达式覆盖
+func (s *Scheduler) Register(name, schedule, description string, fn Func) error {
+	if override, ok := s.overrides[name]; ok {
+		schedule = override
+	}
+	var parsed cron.Schedule
+	if schedule != Disabled {
+		var err error
+		parsed, err = cron.ParseStandard(schedule)
+		if err != nil {
+			return fmt.Errorf("invalid schedule %q of job %s: %w", schedule, name, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("job %s already registered", name)
+	}
+	s.jobs[name] = &job{name: name, schedule: parsed, fn: fn}
+
+	state, ok := s.states.Get(name)
+	// 计划不变时沿用保存的下次运行时间，停机期间错过的运行会在启动后补上一次
+	if !ok || state.Schedule != schedule || state.NextRunAt == nil {
+		state.NextRunAt = nil
+		if parsed != nil {
+			next := parsed.Next(time.Now())
+			state.NextRunAt = &next
+		}
+	}
+	if parsed == nil {
+		state.NextRunAt = nil
+	}
+	state.Name = name
+	state.Description = description
+	state.Schedule = schedule
+	state.Running = false
+	return s.states.Put(name, state)
+}
+
+// List 返回所有任务的状态，按名称排序
+func (s *Scheduler) List() []JobState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	states := make([]JobState, 0, len(s.jobs))
+	for name := range s.jobs {
+		if state, ok := s.states.Get(name); ok {
+			states = append(states, state)
+		}
+	}
+	slices.SortFunc(states, func(a, b JobState) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return states
+}
+
+// Leader 返回本实例是否为主实例，以及已知的主实例名
+func (s *Scheduler) Leader() (bool, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.leader, s.holder
+}
+
+func (s *Scheduler) Instance() string {
+	return s.instance
+}
+
+// Trigger 立即运行任务，不影响下次计划运行的时间
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[name]
+	if !ok {
+		return ErrJobNotFound
+	}
+	if !s.leader {
+		return ErrNotLeader
+	}
+	return s.start(s.ctx, j, false)
+}
+
+// start 在后台运行任务，调用时需持有 mu
+func (s *Scheduler) start(ctx context.Context, j *job, scheduled bool) error {
+	state, _ := s.states.Get(j.name)
+	if state.Running {
+		return ErrJobRunning
+	}
+	state.Running = true
+	if scheduled && j.schedule != nil {
+		next := j.schedule.Next(time.Now())
+		state.NextRunAt = &next
+	}
+	if err := s.states.Put(j.name, state); err != nil {
+		return err
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.execute(ctx, j)
+	}()
+	return nil
+}
+
+func (s *Scheduler) execute(ctx context.Context, j *job) {
+	startedAt := time.Now()
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		return j.fn(ctx)
+	}()
+	duration := time.Since(startedAt)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	updateErr := s.states.Update(j.name, func(state *JobState) error {
+		state.Running = false
+		state.LastRunAt = &startedAt
+		state.LastRunBy = s.instance
+		state.LastDuration = duration.String()
+		state.Runs++
+		if err != nil {
+			state.LastStatus = JobFailed
+			state.LastError = err.Error()
+			state.Failures++
+			return nil
+		}
+		finishedAt := startedAt.Add(duration)
+		state.LastStatus = JobSucceeded
+		state.LastError = ""
+		state.LastSuccessAt = &finishedAt
+		return nil
+	})
+	if updateErr != nil {
+		logrus.Errorf("Failed to record run of job %s: %v", j.name, updateErr)
+	}
+	if err != nil {
+		logrus.Errorf("Job %s failed after %s: %v", j.name, duration, err)
+		return
+	}
+	logrus.Debugf("Job %s finished in %s", j.name, duration)
+}
+
+// elect 每隔三分之一租约有效期获取或续期一次租约
+func (s *Scheduler) elect(now time.Time) {
+	s.mu.Lock()
+	wait := now.Sub(s.checkedAt) < s.ttl/3
+	s.mu.Unlock()
+	if wait {
+		return
+	}
+
+	leader, holder, err := s.lock.TryAcquire(s.instance, s.ttl)
+	if err != nil {
+		logrus.Errorf("Failed to acquire scheduler lock: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkedAt = now
+	// 续约失败时在租约到期前仍保持主实例身份，避免短暂的目录故障打断任务
+	if err != nil && s.leader && now.Sub(s.renewedAt) < s.ttl {
+		return
+	}
+	if leader != s.leader {
+		if leader {
+			logrus.Infof("Scheduler instance %s became leader", s.instance)
+		} else {
+			logrus.Infof("Scheduler instance %s is no longer leader", s.instance)
+		}
+	}
+	s.leader = leader
+	s.holder = holder
+	if leader {
+		s.renewedAt = now
+	}
+}
+
+// runDue 启动所有已到运行时间的任务
+func (s *Scheduler) runDue(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.leader {
+		return
+	}
+	for name, j := range s.jobs {
+		state, ok := s.states.Get(name)
+		if !ok || state.Running || state.NextRunAt == nil || now.Before(*state.NextRunAt) {
+			continue
+		}
+		if err := s.start(ctx, j, true); err != nil {
+			logrus.Errorf("Failed to start job %s: %v", name, err)
+		}
+	}
+}
+
+// Run 参与选主并在成为主实例后按计划运行任务，直到 ctx 结束
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		s.elect(now)
+		s.runDue(ctx, now)
+		select {
+		case <-ctx.Done():
+			s.wg.Wait()
+			if leader, _ := s.Leader(); leader {
+				if err := s.lock.Release(s.instance); err != nil {
+					logrus.Warnf("Failed to release scheduler lock: %v", err)
+				}
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// WriteMetrics 以 Prometheus 文本格式输出任务指标
+func (s *Scheduler) WriteMetrics(w io.Writer) error {
+	states := s.List()
+	leader, _ := s.Leader()
+
+	metrics := []struct {
+		name, kind, help string
+		value            func(state JobState) (float64, bool)
+	}{
+		{"asynx_job_runs_total", "counter", "Total number of job runs.", func(state JobState) (float64, bool) {
+			return float64(state.Runs), true
+		}},
+		{"asynx_job_failures_total", "counter", "Total number of failed job runs.", func(state JobState) (float64, bool) {
+			return float64(state.Failures), true
+		}},
+		{"asynx_job_running", "gauge", "Whether the job is running.", func(state JobState) (float64, bool) {
+			return boolMetric(state.Running), true
+		}},
+		{"asynx_job_last_duration_seconds", "gauge", "Duration of the last job run.", func(state JobState) (float64, bool) {
+			duration, err := time.ParseDuration(state.LastDuration)
+			return duration.Seconds(), err == nil
+		}},
+		{"asynx_job_last_success_timestamp_seconds", "gauge", "Unix time of the last successful job run.", func(state JobState) (float64, bool) {
+			return timeMetric(state.LastSuccessAt)
+		}},
+		{"asynx_job_next_run_timestamp_seconds", "gauge", "Unix time of the next scheduled job run.", func(state JobState) (float64, bool) {
+			return timeMetric(state.NextRunAt)
+		}},
+	}
+
+	for _, metric := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind); err != nil {
+			return err
+		}
+		for _, state := range states {
+			value, ok := metric.value(state)
+			if !ok {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "%s{job=%q} %s\n", metric.name, state.Name, strconv.FormatFloat(value, 'f', -1, 64)); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "# HELP asynx_scheduler_leader Whether this instance is the scheduler leader.\n# TYPE asynx_scheduler_leader gauge\nasynx_scheduler_leader{instance=%q} %d\n", s.instance, int(boolMetric(leader)))
+	return err
+}
+
+func boolMetric(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func timeMetric(t *time.Time) (float64, bool) {
+	if t == nil {
+		return 0, false
+	}
+	return float64(t.Unix()), true
+}
@@ -0,0 +1,182 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestScheduler(t *testing.T, lock Lock, instance string, dataDir string) *Scheduler {
+	t.Helper()
+	s, err := New(lock, instance, 30*time.Second, map[string]string{"disabled": Disabled}, dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.ctx = context.Background()
+	return s
+}
+
+// flakyLock 在 fail 为 true 时返回错误，用于模拟目录故障
+type flakyLock struct {
+	Lock
+	fail atomic.Bool
+}
+
+func (l *flakyLock) TryAcquire(holder string, ttl time.Duration) (bool, string, error) {
+	if l.fail.Load() {
+		return false, "", errors.New("directory unavailable")
+	}
+	return l.Lock.TryAcquire(holder, ttl)
+}
+
+func TestOnlyLeaderRunsJobs(t *testing.T) {
+	lock := NewDirectoryLock(newTestDirectory(t), testLockDn)
+	var runs atomic.Int32
+	count := func(context.Context) error { runs.Add(1); return nil }
+
+	replicas := []*Scheduler{newTestScheduler(t, lock, "a", ""), newTestScheduler(t, lock, "b", "")}
+	now := time.Now()
+	for _, s := range replicas {
+		if err := s.Register("sync", "* * * * *", "", count); err != nil {
+			t.Fatal(err)
+		}
+		s.elect(now)
+	}
+	if leader, holder := replicas[0].Leader(); !leader || holder != "a" {
+		t.Fatalf("a: leader = %v, holder = %s", leader, holder)
+	}
+	if leader, holder := replicas[1].Leader(); leader || holder != "a" {
+		t.Fatalf("b: leader = %v, holder = %s", leader, holder)
+	}
+
+	due := now.Add(2 * time.Minute)
+	for _, s := range replicas {
+		s.runDue(context.Background(), due)
+		s.wg.Wait()
+	}
+	if runs.Load() != 1 {
+		t.Errorf("job ran %d times, want once on the leader", runs.Load())
+	}
+	if err := replicas[1].Trigger("sync"); !errors.Is(err, ErrNotLeader) {
+		t.Errorf("trigger on a follower: got %v, want ErrNotLeader", err)
+	}
+}
+
+func TestLeaderSurvivesTransientLockErrors(t *testing.T) {
+	lock := &flakyLock{Lock: LocalLock{}}
+	s := newTestScheduler(t, lock, "a", "")
+	now := time.Now()
+	s.elect(now)
+
+	// 续约失败但租约还没有到期，仍然是主实例
+	lock.fail.Store(true)
+	s.elect(now.Add(s.ttl / 2))
+	if leader, _ := s.Leader(); !leader {
+		t.Fatal("lost leadership before the lease expired")
+	}
+	s.elect(now.Add(s.ttl + time.Second))
+	if leader, _ := s.Leader(); leader {
+		t.Error("kept leadership after the lease expired")
+	}
+
+	lock.fail.Store(false)
+	s.elect(now.Add(2 * s.ttl))
+	if leader, _ := s.Leader(); !leader {
+		t.Error("did not regain leadership after the directory recovered")
+	}
+}
+
+func TestTriggerRecordsRuns(t *testing.T) {
+	s := newTestScheduler(t, LocalLock{}, "a", "")
+	release := make(chan struct{})
+	jobs := map[string]Func{
+		"ok":    func(context.Context) error { return nil },
+		"fail":  func(context.Context) error { return errors.New("boom") },
+		"panic": func(context.Context) error { panic("oops") },
+		"slow":  func(context.Context) error { <-release; return nil },
+	}
+	for name, fn := range jobs {
+		if err := s.Register(name, "@daily", "", fn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Trigger("ok"); !errors.Is(err, ErrNotLeader) {
+		t.Fatalf("trigger before election: got %v, want ErrNotLeader", err)
+	}
+	s.elect(time.Now())
+
+	if err := s.Trigger("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("got %v for an unknown job", err)
+	}
+	if err := s.Trigger("slow"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Trigger("slow"); !errors.Is(err, ErrJobRunning) {
+		t.Errorf("got %v for a running job, want ErrJobRunning", err)
+	}
+	close(release)
+	for _, name := range []string{"ok", "fail", "panic"} {
+		if err := s.Trigger(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.wg.Wait()
+
+	states := make(map[string]JobState)
+	for _, state := range s.List() {
+		states[state.Name] = state
+	}
+	tests := []struct {
+		name      string
+		status    JobStatus
+		lastError string
+	}{
+		{"ok", JobSucceeded, ""},
+		{"slow", JobSucceeded, ""},
+		{"fail", JobFailed, "boom"},
+		{"panic", JobFailed, "panic: oops"},
+	}
+	for _, tt := range tests {
+		state := states[tt.name]
+		if state.Running || state.Runs != 1 || state.LastStatus != tt.status || state.LastError != tt.lastError || state.LastRunBy != "a" {
+			t.Errorf("%s: got %+v", tt.name, state)
+		}
+	}
+}
+
+func TestScheduleSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	s := newTestScheduler(t, LocalLock{}, "a", dir)
+	if err := s.Register("sync", "@hourly", "", func(context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Register("disabled", "@hourly", "", func(context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	first := *s.List()[1].NextRunAt
+
+	// 计划不变时沿用保存的下次运行时间，配置覆盖为 "-" 的任务不会定时运行
+	restarted := newTestScheduler(t, LocalLock{}, "a", dir)
+	if err := restarted.Register("sync", "@hourly", "", func(context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := restarted.Register("disabled", "@hourly", "", func(context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	states := restarted.List()
+	if states[0].Name != "disabled" || states[0].NextRunAt != nil || states[0].Schedule != Disabled {
+		t.Errorf("got %+v for the disabled job", states[0])
+	}
+	if !states[1].NextRunAt.Equal(first) {
+		t.Errorf("next run moved from %s to %s after restart", first, states[1].NextRunAt)
+	}
+
+	if err := restarted.Register("sync", "@daily", "", nil); err == nil {
+		t.Error("job registered twice")
+	}
+	if err := newTestScheduler(t, LocalLock{}, "a", "").Register("bad", "every minute", "", nil); err == nil {
+		t.Error("invalid schedule was accepted")
+	}
+}
@@ -0,0 +1,467 @@
+package scim
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Filter 是解析后的 SCIM 过滤表达式（RFC 7644 3.4.2.2），在资源的 JSON 对象上求值
+type Filter interface {
+	Match(doc map[string]any) bool
+}
+
+// ParseFilter 解析 filter 查询参数
+func ParseFilter(s string) (Filter, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, badRequest(ErrInvalidFilter, "unexpected %q in filter", p.peek().text)
+	}
+	return f, nil
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenOpen
+	tokenClose
+	tokenOpenBracket
+	tokenCloseBracket
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokenOpen, "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokenClose, ")"})
+			i++
+		case c == '[':
+			tokens = append(tokens, token{tokenOpenBracket, "["})
+			i++
+		case c == ']':
+			tokens = append(tokens, token{tokenCloseBracket, "]"})
+			i++
+		case c == '"':
+			j := i + 1
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				return nil, badRequest(ErrInvalidFilter, "unterminated string in filter")
+			}
+			var value string
+			if err := json.Unmarshal([]byte(s[i:j+1]), &value); err != nil {
+				return nil, badRequest(ErrInvalidFilter, "invalid string %s in filter", s[i:j+1])
+			}
+			tokens = append(tokens, token{tokenString, value})
+			i = j + 1
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t()[]\"", rune(s[j])) {
+				j++
+			}
+			tokens = append(tokens, token{tokenWord, s[i:j]})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *filterParser) done() bool { return p.pos >= len(p.tokens) }
+
+func (p *filterParser) peek() token {
+	if p.done() {
+		return token{kind: -1}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() token {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *filterParser) keyword(word string) bool {
+	t := p.peek()
+	if t.kind == tokenWord && strings.EqualFold(t.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) expect(kind tokenKind, text string) error {
+	if p.peek().kind != kind {
+		return badRequest(ErrInvalidFilter, "expected %q in filter", text)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *filterParser) parseOr() (Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orFilter{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (Filter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andFilter{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (Filter, error) {
+	if p.keyword("not") {
+		f, err := p.parseGroup()
+		if err != nil {
+			return nil, err
+		}
+		return notFilter{f}, nil
+	}
+	if p.peek().kind == tokenOpen {
+		return p.parseGroup()
+	}
+	return p.parseAttribute()
+}
+
+func (p *filterParser) parseGroup() (Filter, error) {
+	if err := p.expect(tokenOpen, "("); err != nil {
+		return nil, err
+	}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(tokenClose, ")"); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (p *filterParser) parseAttribute() (Filter, error) {
+	t := p.next()
+	if t.kind != tokenWord {
+		return nil, badRequest(ErrInvalidFilter, "expected attribute path in filter")
+	}
+	path := t.text
+
+	if p.peek().kind == tokenOpenBracket {
+		p.next()
+		sub, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenCloseBracket, "]"); err != nil {
+			return nil, err
+		}
+		return valuePathFilter{path: path, filter: sub}, nil
+	}
+
+	op := p.next()
+	if op.kind != tokenWord {
+		return nil, badRequest(ErrInvalidFilter, "expected operator after %q", path)
+	}
+	operator := strings.ToLower(op.text)
+	switch operator {
+	case "pr":
+		return presentFilter{path}, nil
+	case "eq", "ne", "co", "sw", "ew", "gt", "ge", "lt", "le":
+	default:
+		return nil, badRequest(ErrInvalidFilter, "unknown operator %q", op.text)
+	}
+
+	v := p.next()
+	var value any
+	switch {
+	case v.kind == tokenString:
+		value = v.text
+	case v.kind == tokenWord:
+		if err := json.Unmarshal([]byte(strings.ToLower(v.text)), &value); err != nil {
+			return nil, badRequest(ErrInvalidFilter, "invalid value %q in filter", v.text)
+		}
+	default:
+		return nil, badRequest(ErrInvalidFilter, "expected value after %q", op.text)
+	}
+	return compareFilter{path: path, operator: operator, value: value}, nil
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+type andFilter struct{ left, right Filter }
+
+func (f andFilter) Match(doc map[string]any) bool { return f.left.Match(doc) && f.right.Match(doc) }
+
+type orFilter struct{ left, right Filter }
+
+func (f orFilter) Match(doc map[string]any) bool { return f.left.Match(doc) || f.right.Match(doc) }
+
+type notFilter struct{ filter Filter }
+
+func (f notFilter) Match(doc map[string]any) bool { return !f.filter.Match(doc) }
+
+type presentFilter struct{ path string }
+
+// Match 在属性有非空取值时成立，复杂属性（如 name）至少有一个子属性即可
+func (f presentFilter) Match(doc map[string]any) bool {
+	for _, v := range resolveRaw(doc, f.path) {
+		for _, element := range asList(v) {
+			switch element := element.(type) {
+			case nil:
+			case string:
+				if element != "" {
+					return true
+				}
+			case map[string]any:
+				if len(element) > 0 {
+					return true
+				}
+			default:
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type compareFilter struct {
+	path     string
+	operator string
+	value    any
+}
+
+func (f compareFilter) Match(doc map[string]any) bool {
+	values := resolve(doc, f.path)
+	if f.operator == "ne" {
+		for _, v := range values {
+			if compare(v, "eq", f.value) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, v := range values {
+		if compare(v, f.operator, f.value) {
+			return true
+		}
+	}
+	return false
+}
+
+// valuePathFilter 匹配多值属性中任一元素满足子过滤条件的资源，如 emails[type eq "work"]
+type valuePathFilter struct {
+	path   string
+	filter Filter
+}
+
+func (f valuePathFilter) Match(doc map[string]any) bool {
+	return len(matchingElements(doc, f.path, f.filter)) > 0
+}
+
+func matchingElements(doc map[string]any, path string, filter Filter) []map[string]any {
+	var matched []map[string]any
+	for _, v := range resolveRaw(doc, path) {
+		for _, element := range asList(v) {
+			if m, ok := element.(map[string]any); ok && filter.Match(m) {
+				matched = append(matched, m)
+			}
+		}
+	}
+	return matched
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+// splitPath 拆出 schema 前缀，返回扩展容器 URN（核心 schema 时为空）与其中的属性路径
+func splitPath(path string) (string, string) {
+	for _, urn := range extensionSchemas {
+		if hasPrefixFold(path, urn) {
+			return urn, strings.TrimPrefix(path[len(urn):], ":")
+		}
+	}
+	for _, urn := range []string{SchemaUser, SchemaGroup} {
+		if hasPrefixFold(path, urn+":") {
+			return "", path[len(urn)+1:]
+		}
+	}
+	return "", path
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// lookup 大小写不敏感地查找属性，返回实际键名
+func lookup(m map[string]any, name string) (string, any, bool) {
+	if v, ok := m[name]; ok {
+		return name, v, true
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, name) {
+			return k, v, true
+		}
+	}
+	return name, nil, false
+}
+
+func asList(v any) []any {
+	if list, ok := v.([]any); ok {
+		return list
+	}
+	return []any{v}
+}
+
+// resolveRaw 返回路径指向的原始值，多值属性下的子属性会展开为列表
+func resolveRaw(doc map[string]any, path string) []any {
+	urn, attr := splitPath(path)
+	current := []any{doc}
+	if urn != "" {
+		_, ext, ok := lookup(doc, urn)
+		if !ok {
+			return nil
+		}
+		current = []any{ext}
+		if attr == "" {
+			return current
+		}
+	}
+	for _, name := range strings.Split(attr, ".") {
+		var next []any
+		for _, c := range current {
+			for _, element := range asList(c) {
+				if m, ok := element.(map[string]any); ok {
+					if _, v, ok := lookup(m, name); ok {
+						next = append(next, v)
+					}
+				}
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+// resolve 返回可比较的取值，多值属性取每个元素的 value 子属性
+func resolve(doc map[string]any, path string) []any {
+	var values []any
+	for _, v := range resolveRaw(doc, path) {
+		for _, element := range asList(v) {
+			if m, ok := element.(map[string]any); ok {
+				_, element, _ = lookup(m, "value")
+			}
+			values = append(values, element)
+		}
+	}
+	return values
+}
+
+func compare(actual any, operator string, expected any) bool {
+	switch a := actual.(type) {
+	case string:
+		e, ok := expected.(string)
+		if !ok {
+			return false
+		}
+		a, e = strings.ToLower(a), strings.ToLower(e)
+		switch operator {
+		case "eq":
+			return a == e
+		case "co":
+			return strings.Contains(a, e)
+		case "sw":
+			return strings.HasPrefix(a, e)
+		case "ew":
+			return strings.HasSuffix(a, e)
+		case "gt":
+			return a > e
+		case "ge":
+			return a >= e
+		case "lt":
+			return a < e
+		case "le":
+			return a <= e
+		}
+	case float64:
+		e, ok := expected.(float64)
+		if !ok {
+			return false
+		}
+		switch operator {
+		case "eq":
+			return a == e
+		case "gt":
+			return a > e
+		case "ge":
+			return a >= e
+		case "lt":
+			return a < e
+		case "le":
+			return a <= e
+		}
+	case bool:
+		e, ok := expected.(bool)
+		return ok && operator == "eq" && a == e
+	case nil:
+		return operator == "eq" && expected == nil
+	}
+	return false
+}
+
+// EqualityValue 在过滤器恰好是 `<path> eq "value"` 时返回该值，供调用方直接按键查找
+func EqualityValue(f Filter, path string) (string, bool) {
+	c, ok := f.(compareFilter)
+	if !ok || c.operator != "eq" || !strings.EqualFold(c.path, path) {
+		return "", false
+	}
+	value, ok := c.value.(string)
+	return value, ok
+}
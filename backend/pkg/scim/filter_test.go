@@ -0,0 +1,109 @@
+package scim
+
+import (
+	"errors"
+	"testing"
+)
+
+func testUserDocument(t *testing.T) map[string]any {
+	t.Helper()
+	active := true
+	doc, err := ToDocument(&User{
+		Schemas:     []string{SchemaUser, SchemaAsynxUser},
+		Id:          "2024000001",
+		UserName:    "2024000001",
+		Name:        &Name{FamilyName: "Zhang", GivenName: "San"},
+		DisplayName: "Zhang San",
+		Emails:      []MultiValued{{Value: "zhang@example.org", Type: "work", Primary: true}, {Value: "san@home.example.org", Type: "home"}},
+		Active:      &active,
+		Asynx:       &AsynxUser{Category: "member", Role: "admin"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestFilterMatch(t *testing.T) {
+	doc := testUserDocument(t)
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{`userName eq "2024000001"`, true},
+		{`USERNAME Eq "2024000001"`, true},
+		{`userName eq "2024000002"`, false},
+		{`name.familyName sw "zh"`, true},
+		{`displayName co "san"`, true},
+		{`displayName ew "zhang"`, false},
+		{`emails.value ew "@home.example.org"`, true},
+		{`emails[type eq "work" and value co "zhang"]`, true},
+		{`emails[type eq "work" and value co "home"]`, false},
+		{`active eq true`, true},
+		{`active eq false`, false},
+		{`title pr`, false},
+		{`name pr`, true},
+		{`emails pr`, true},
+		{`name.formatted pr`, false},
+		{`not (userName eq "2024000001")`, false},
+		{`userName eq "x" or name.givenName eq "SAN"`, true},
+		{`userName eq "2024000001" and (active eq false or displayName pr)`, true},
+		{`urn:ietf:params:scim:schemas:extension:asynx:2.0:User:role eq "admin"`, true},
+		{`urn:ietf:params:scim:schemas:extension:asynx:2.0:User:category eq "alumni"`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			f, err := ParseFilter(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.Match(doc); got != tt.want {
+				t.Errorf("Match = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	for _, filter := range []string{
+		``,
+		`userName`,
+		`userName eq`,
+		`userName like "a"`,
+		`userName eq "a`,
+		`(userName eq "a"`,
+		`userName eq "a" extra`,
+		`emails[type eq "work"`,
+	} {
+		t.Run(filter, func(t *testing.T) {
+			_, err := ParseFilter(filter)
+			var scimErr *Error
+			if !errors.As(err, &scimErr) || scimErr.StatusCode() != 400 {
+				t.Errorf("got %v, want a 400 SCIM error", err)
+			}
+		})
+	}
+}
+
+func TestEqualityValue(t *testing.T) {
+	tests := []struct {
+		filter    string
+		wantValue string
+		wantOk    bool
+	}{
+		{`userName eq "2024000001"`, "2024000001", true},
+		{`username EQ "2024000001"`, "2024000001", true},
+		{`userName sw "2024"`, "", false},
+		{`userName eq "a" or userName eq "b"`, "", false},
+		{`displayName eq "a"`, "", false},
+	}
+	for _, tt := range tests {
+		f, err := ParseFilter(tt.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value, ok := EqualityValue(f, "userName"); value != tt.wantValue || ok != tt.wantOk {
+			t.Errorf("EqualityValue(%s) = %q, %v", tt.filter, value, ok)
+		}
+	}
+}
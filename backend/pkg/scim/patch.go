@@ -0,0 +1,296 @@
+package scim
+
+import (
+	"reflect"
+	"strings"
+)
+
+type PatchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path,omitempty"`
+	Value any    `json:"value,omitempty"`
+}
+
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// ApplyPatch 按 RFC 7644 3.5.2 在资源的 JSON 对象上依次执行 PATCH 操作
+func ApplyPatch(doc map[string]any, operations []PatchOperation) error {
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		switch op {
+		case "add", "replace":
+			if operation.Path == "" {
+				value, ok := operation.Value.(map[string]any)
+				if !ok {
+					return badRequest(ErrInvalidValue, "%s without path requires an object value", op)
+				}
+				// 部分身份提供方会把 "name.givenName" 之类的路径直接作为键
+				for path, v := range value {
+					if err := set(doc, path, v, op == "replace"); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if err := set(doc, operation.Path, operation.Value, op == "replace"); err != nil {
+				return err
+			}
+		case "remove":
+			if operation.Path == "" {
+				return badRequest(ErrNoTarget, "remove requires a path")
+			}
+			if err := remove(doc, operation.Path, operation.Value); err != nil {
+				return err
+			}
+		default:
+			return badRequest(ErrInvalidSyntax, "unknown patch operation %q", operation.Op)
+		}
+	}
+	return nil
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+// patchPath 是解析后的 PATCH 路径 attr[filter].sub，container 为 attr 所在的对象
+type patchPath struct {
+	container map[string]any
+	attr      string
+	filter    Filter
+	sub       string
+}
+
+func parsePatchPath(doc map[string]any, path string, create bool) (*patchPath, error) {
+	p := &patchPath{}
+
+	attrPath := path
+	if open := strings.Index(path, "["); open >= 0 {
+		close := strings.LastIndex(path, "]")
+		if close < open {
+			return nil, badRequest(ErrInvalidPath, "invalid path %q", path)
+		}
+		filter, err := ParseFilter(path[open+1 : close])
+		if err != nil {
+			return nil, badRequest(ErrInvalidPath, "invalid filter in path %q", path)
+		}
+		attrPath = path[:open]
+		p.filter = filter
+		p.sub = strings.TrimPrefix(path[close+1:], ".")
+	}
+
+	urn, attr := splitPath(attrPath)
+	p.container = doc
+	if urn != "" {
+		if attr == "" {
+			p.attr = urn
+			return p, nil
+		}
+		key, ext, ok := lookup(doc, urn)
+		container, isMap := ext.(map[string]any)
+		if !ok || !isMap {
+			if !create {
+				return nil, badRequest(ErrNoTarget, "no value at path %q", path)
+			}
+			container = make(map[string]any)
+			doc[key] = container
+		}
+		p.container = container
+	}
+
+	if p.filter == nil {
+		attr, p.sub, _ = strings.Cut(attr, ".")
+	}
+	if attr == "" {
+		return nil, badRequest(ErrInvalidPath, "invalid path %q", path)
+	}
+	p.attr = attr
+	return p, nil
+}
+
+// set 执行 add（replace 为 false）或 replace
+func set(doc map[string]any, path string, value any, replace bool) error {
+	p, err := parsePatchPath(doc, path, true)
+	if err != nil {
+		return err
+	}
+
+	if p.filter != nil {
+		key, existing, _ := lookup(p.container, p.attr)
+		elements := filterElements(existing, p.filter)
+		if len(elements) == 0 {
+			// 过滤条件是简单的相等比较时按条件创建新元素，如 emails[type eq "work"].value
+			element, ok := elementFromFilter(p.filter)
+			if !ok {
+				return badRequest(ErrNoTarget, "no value matches path %q", path)
+			}
+			list, _ := existing.([]any)
+			p.container[key] = append(list, element)
+			elements = []map[string]any{element}
+		}
+		for _, element := range elements {
+			if p.sub != "" {
+				merge(element, p.sub, value, replace)
+				continue
+			}
+			fields, ok := value.(map[string]any)
+			if !ok {
+				return badRequest(ErrInvalidValue, "value for %q must be an object", path)
+			}
+			for k, v := range fields {
+				merge(element, k, v, replace)
+			}
+		}
+		return nil
+	}
+
+	if p.sub != "" {
+		key, existing, _ := lookup(p.container, p.attr)
+		complex, ok := existing.(map[string]any)
+		if !ok {
+			if existing != nil {
+				return badRequest(ErrInvalidPath, "%q is not a complex attribute", p.attr)
+			}
+			complex = make(map[string]any)
+			p.container[key] = complex
+		}
+		merge(complex, p.sub, value, replace)
+		return nil
+	}
+
+	merge(p.container, p.attr, value, replace)
+	return nil
+}
+
+// merge 写入单个属性：多值属性 add 时追加不重复的元素，复杂属性合并子属性，其他情况直接覆盖
+func merge(container map[string]any, attr string, value any, replace bool) {
+	key, existing, ok := lookup(container, attr)
+	if !ok {
+		container[key] = value
+		return
+	}
+	switch existing := existing.(type) {
+	case []any:
+		if replace {
+			container[key] = asList(value)
+			return
+		}
+		for _, v := range asList(value) {
+			if !containsValue(existing, v) {
+				existing = append(existing, v)
+			}
+		}
+		container[key] = existing
+	case map[string]any:
+		fields, ok := value.(map[string]any)
+		if !ok {
+			container[key] = value
+			return
+		}
+		for k, v := range fields {
+			merge(existing, k, v, replace)
+		}
+	default:
+		container[key] = value
+	}
+}
+
+func remove(doc map[string]any, path string, value any) error {
+	p, err := parsePatchPath(doc, path, false)
+	if err != nil {
+		return err
+	}
+	key, existing, ok := lookup(p.container, p.attr)
+	if !ok {
+		return nil
+	}
+
+	if p.filter != nil {
+		list, _ := existing.([]any)
+		kept := make([]any, 0, len(list))
+		for _, element := range list {
+			m, ok := element.(map[string]any)
+			if !ok || !p.filter.Match(m) {
+				kept = append(kept, element)
+				continue
+			}
+			if p.sub != "" {
+				subKey, _, _ := lookup(m, p.sub)
+				delete(m, subKey)
+				kept = append(kept, m)
+			}
+		}
+		setOrDelete(p.container, key, kept)
+		return nil
+	}
+
+	if p.sub != "" {
+		if complex, ok := existing.(map[string]any); ok {
+			subKey, _, _ := lookup(complex, p.sub)
+			delete(complex, subKey)
+		}
+		return nil
+	}
+
+	// 带 value 的 remove 只删除多值属性中列出的元素，部分身份提供方以此移除组成员
+	if list, ok := existing.([]any); ok && value != nil {
+		kept := make([]any, 0, len(list))
+		for _, element := range list {
+			if !containsValue(asList(value), element) {
+				kept = append(kept, element)
+			}
+		}
+		setOrDelete(p.container, key, kept)
+		return nil
+	}
+
+	delete(p.container, key)
+	return nil
+}
+
+func setOrDelete(container map[string]any, key string, list []any) {
+	if len(list) == 0 {
+		delete(container, key)
+		return
+	}
+	container[key] = list
+}
+
+func filterElements(v any, filter Filter) []map[string]any {
+	var matched []map[string]any
+	list, _ := v.([]any)
+	for _, element := range list {
+		if m, ok := element.(map[string]any); ok && filter.Match(m) {
+			matched = append(matched, m)
+		}
+	}
+	return matched
+}
+
+func elementFromFilter(filter Filter) (map[string]any, bool) {
+	c, ok := filter.(compareFilter)
+	if !ok || c.operator != "eq" || strings.Contains(c.path, ".") {
+		return nil, false
+	}
+	return map[string]any{c.path: c.value}, true
+}
+
+// containsValue 比较多值属性的元素，带 value 子属性的元素只比较 value
+func containsValue(list []any, v any) bool {
+	for _, element := range list {
+		if reflect.DeepEqual(elementValue(element), elementValue(v)) {
+			return true
+		}
+	}
+	return false
+}
+
+func elementValue(v any) any {
+	if m, ok := v.(map[string]any); ok {
+		if _, value, ok := lookup(m, "value"); ok {
+			return value
+		}
+	}
+	return v
+}
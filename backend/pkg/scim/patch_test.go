@@ -0,0 +1,139 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// patchUser 把 operations 应用到测试用户上，operations 为 PatchRequest 中的 JSON
+func patchUser(t *testing.T, operations string) (*User, error) {
+	t.Helper()
+	var request PatchRequest
+	if err := json.Unmarshal([]byte(`{"Operations":`+operations+`}`), &request); err != nil {
+		t.Fatal(err)
+	}
+	doc := testUserDocument(t)
+	if err := ApplyPatch(doc, request.Operations); err != nil {
+		return nil, err
+	}
+	user, err := FromDocument[User](doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user, nil
+}
+
+func TestApplyPatchToUser(t *testing.T) {
+	tests := []struct {
+		name       string
+		operations string
+		check      func(u *User) bool
+	}{
+		{"replace sub-attribute", `[{"op":"Replace","path":"name.givenName","value":"Si"}]`,
+			func(u *User) bool { return u.Name.GivenName == "Si" && u.Name.FamilyName == "Zhang" }},
+		{"replace filtered element", `[{"op":"replace","path":"emails[type eq \"work\"].value","value":"new@example.org"}]`,
+			func(u *User) bool { return u.PrimaryEmail() == "new@example.org" && len(u.Emails) == 2 }},
+		{"add creates filtered element", `[{"op":"add","path":"emails[type eq \"other\"].value","value":"other@example.org"}]`,
+			func(u *User) bool {
+				return len(u.Emails) == 3 && u.Emails[2].Type == "other" && u.Emails[2].Value == "other@example.org"
+			}},
+		{"add without path", `[{"op":"add","value":{"displayName":"Li Si","name.familyName":"Li"}}]`,
+			func(u *User) bool {
+				return u.DisplayName == "Li Si" && u.Name.FamilyName == "Li" && u.Name.GivenName == "San"
+			}},
+		{"replace active", `[{"op":"replace","path":"active","value":false}]`,
+			func(u *User) bool { return u.Active != nil && !*u.Active }},
+		{"extension attribute", `[{"op":"replace","path":"urn:ietf:params:scim:schemas:extension:asynx:2.0:User:role","value":"default"}]`,
+			func(u *User) bool { return u.Asynx.Role == "default" && u.Asynx.Category == "member" }},
+		{"remove sub-attribute", `[{"op":"remove","path":"name.givenName"}]`,
+			func(u *User) bool { return u.Name.GivenName == "" && u.Name.FamilyName == "Zhang" }},
+		{"remove filtered element", `[{"op":"remove","path":"emails[type eq \"home\"]"}]`,
+			func(u *User) bool { return len(u.Emails) == 1 && u.Emails[0].Type == "work" }},
+		{"remove missing attribute", `[{"op":"remove","path":"title"}]`,
+			func(u *User) bool { return u.UserName == "2024000001" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := patchUser(t, tt.operations)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(user) {
+				t.Errorf("got %+v", user)
+			}
+		})
+	}
+}
+
+func TestApplyPatchErrors(t *testing.T) {
+	tests := []struct {
+		operations string
+		scimType   string
+	}{
+		{`[{"op":"move","path":"userName"}]`, ErrInvalidSyntax},
+		{`[{"op":"remove"}]`, ErrNoTarget},
+		{`[{"op":"add","value":"x"}]`, ErrInvalidValue},
+		{`[{"op":"replace","path":"emails[type eq \"work\"","value":"x"}]`, ErrInvalidPath},
+		{`[{"op":"replace","path":"emails[value co \"none\"].type","value":"x"}]`, ErrNoTarget},
+		{`[{"op":"replace","path":"userName.first","value":"x"}]`, ErrInvalidPath},
+	}
+	for _, tt := range tests {
+		t.Run(tt.operations, func(t *testing.T) {
+			_, err := patchUser(t, tt.operations)
+			var scimErr *Error
+			if !errors.As(err, &scimErr) || scimErr.ScimType != tt.scimType {
+				t.Errorf("got %v, want %s", err, tt.scimType)
+			}
+		})
+	}
+}
+
+func TestApplyPatchToGroupMembers(t *testing.T) {
+	doc, err := ToDocument(&Group{
+		Schemas:     []string{SchemaGroup},
+		DisplayName: "infra",
+		Members:     []MultiValued{{Value: "2024000001"}, {Value: "2024000002"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	members := func() []string {
+		group, err := FromDocument[Group](doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var uids []string
+		for _, member := range group.Members {
+			uids = append(uids, member.Value)
+		}
+		return uids
+	}
+
+	tests := []struct {
+		name      string
+		operation PatchOperation
+		want      []string
+	}{
+		// 已经存在的成员不会重复添加
+		{"add members", PatchOperation{Op: "add", Path: "members", Value: []any{map[string]any{"value": "2024000002"}, map[string]any{"value": "2024000003"}}},
+			[]string{"2024000001", "2024000002", "2024000003"}},
+		{"remove by filter", PatchOperation{Op: "remove", Path: `members[value eq "2024000001"]`},
+			[]string{"2024000002", "2024000003"}},
+		{"remove by value", PatchOperation{Op: "remove", Path: "members", Value: []any{map[string]any{"value": "2024000003"}}},
+			[]string{"2024000002"}},
+		{"replace members", PatchOperation{Op: "replace", Path: "members", Value: []any{map[string]any{"value": "2024000004"}}},
+			[]string{"2024000004"}},
+		{"remove all", PatchOperation{Op: "remove", Path: "members"},
+			nil},
+	}
+	for _, tt := range tests {
+		if err := ApplyPatch(doc, []PatchOperation{tt.operation}); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := members(); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got members %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
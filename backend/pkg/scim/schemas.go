@@ -0,0 +1,229 @@
+package scim
+
+// 服务发现端点返回的静态描述（RFC 7643 第 5-7 节）
+
+type Supported struct {
+	Supported bool `json:"supported"`
+}
+
+type FilterSupported struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type BulkSupported struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type AuthenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary"`
+}
+
+type ServiceProviderConfig struct {
+	Schemas               []string               `json:"schemas"`
+	Patch                 Supported              `json:"patch"`
+	Bulk                  BulkSupported          `json:"bulk"`
+	Filter                FilterSupported        `json:"filter"`
+	ChangePassword        Supported              `json:"changePassword"`
+	Sort                  Supported              `json:"sort"`
+	Etag                  Supported              `json:"etag"`
+	AuthenticationSchemes []AuthenticationScheme `json:"authenticationSchemes"`
+	Meta                  *Meta                  `json:"meta,omitempty"`
+}
+
+func NewServiceProviderConfig() *ServiceProviderConfig {
+	return &ServiceProviderConfig{
+		Schemas:        []string{SchemaServiceProviderConfig},
+		Patch:          Supported{true},
+		Bulk:           BulkSupported{},
+		Filter:         FilterSupported{Supported: true, MaxResults: MaxResults},
+		ChangePassword: Supported{true},
+		Sort:           Supported{false},
+		Etag:           Supported{false},
+		AuthenticationSchemes: []AuthenticationScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "Bearer Token",
+			Description: "Provisioning token configured by SCIM_TOKENS",
+			Primary:     true,
+		}},
+		Meta: &Meta{ResourceType: "ServiceProviderConfig"},
+	}
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+type SchemaExtension struct {
+	Schema   string `json:"schema"`
+	Required bool   `json:"required"`
+}
+
+type ResourceType struct {
+	Schemas          []string          `json:"schemas"`
+	Id               string            `json:"id"`
+	Name             string            `json:"name"`
+	Endpoint         string            `json:"endpoint"`
+	Description      string            `json:"description"`
+	Schema           string            `json:"schema"`
+	SchemaExtensions []SchemaExtension `json:"schemaExtensions"`
+	Meta             *Meta             `json:"meta,omitempty"`
+}
+
+func ResourceTypes() []*ResourceType {
+	return []*ResourceType{
+		{
+			Schemas:          []string{SchemaResourceType},
+			Id:               "User",
+			Name:             "User",
+			Endpoint:         "/Users",
+			Description:      "Directory account",
+			Schema:           SchemaUser,
+			SchemaExtensions: []SchemaExtension{{Schema: SchemaAsynxUser}},
+			Meta:             &Meta{ResourceType: "ResourceType"},
+		},
+		{
+			Schemas:          []string{SchemaResourceType},
+			Id:               "Group",
+			Name:             "Group",
+			Endpoint:         "/Groups",
+			Description:      "Role group or additional group",
+			Schema:           SchemaGroup,
+			SchemaExtensions: []SchemaExtension{{Schema: SchemaAsynxGroup}},
+			Meta:             &Meta{ResourceType: "ResourceType"},
+		},
+	}
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+type Attribute struct {
+	Name          string       `json:"name"`
+	Type          string       `json:"type"`
+	MultiValued   bool         `json:"multiValued"`
+	Description   string       `json:"description,omitempty"`
+	Required      bool         `json:"required"`
+	CaseExact     bool         `json:"caseExact"`
+	Mutability    string       `json:"mutability"`
+	Returned      string       `json:"returned"`
+	Uniqueness    string       `json:"uniqueness"`
+	SubAttributes []*Attribute `json:"subAttributes,omitempty"`
+}
+
+type Schema struct {
+	Schemas     []string     `json:"schemas"`
+	Id          string       `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Attributes  []*Attribute `json:"attributes"`
+	Meta        *Meta        `json:"meta,omitempty"`
+}
+
+func attribute(name, typ, mutability, description string) *Attribute {
+	return &Attribute{
+		Name:        name,
+		Type:        typ,
+		Description: description,
+		Mutability:  mutability,
+		Returned:    "default",
+		Uniqueness:  "none",
+	}
+}
+
+func multiValued(name, mutability, description string, subAttributes ...*Attribute) *Attribute {
+	a := attribute(name, "complex", mutability, description)
+	a.MultiValued = true
+	a.SubAttributes = subAttributes
+	return a
+}
+
+func Schemas() []*Schema {
+	userName := attribute("userName", "string", "immutable", "Username (uid), member accounts use the 10-digit student number")
+	userName.Required = true
+	userName.Uniqueness = "server"
+
+	password := attribute("password", "string", "writeOnly", "Password, never returned")
+	password.Returned = "never"
+
+	displayName := attribute("displayName", "string", "immutable", "Group name (cn)")
+	displayName.Required = true
+
+	schemas := []*Schema{
+		{
+			Id:          SchemaUser,
+			Name:        "User",
+			Description: "User Account",
+			Attributes: []*Attribute{
+				userName,
+				{
+					Name:       "name",
+					Type:       "complex",
+					Mutability: "readWrite",
+					Returned:   "default",
+					Uniqueness: "none",
+					SubAttributes: []*Attribute{
+						attribute("familyName", "string", "readWrite", "Surname (sn)"),
+						attribute("givenName", "string", "readWrite", "Given name"),
+						attribute("formatted", "string", "readOnly", "Surname followed by given name"),
+					},
+				},
+				attribute("displayName", "string", "readOnly", "Surname followed by given name"),
+				multiValued("emails", "readWrite", "Mail address, only the primary one is stored",
+					attribute("value", "string", "readWrite", ""),
+					attribute("type", "string", "readWrite", ""),
+					attribute("primary", "boolean", "readWrite", ""),
+				),
+				attribute("preferredLanguage", "string", "readWrite", "Notification language"),
+				attribute("active", "boolean", "readWrite", "False once the account has expired, setting it to false expires the account today"),
+				password,
+				multiValued("groups", "readOnly", "Role group and additional groups",
+					attribute("value", "string", "readOnly", ""),
+					attribute("display", "string", "readOnly", ""),
+					attribute("$ref", "reference", "readOnly", ""),
+				),
+			},
+		},
+		{
+			Id:          SchemaAsynxUser,
+			Name:        "AsynxUser",
+			Description: "asynx account attributes",
+			Attributes: []*Attribute{
+				attribute("category", "string", "readWrite", "Account category: system, member or external"),
+				attribute("role", "string", "readWrite", "Role: admin, default or restricted"),
+				attribute("uidNumber", "string", "readOnly", "POSIX uid number"),
+				attribute("homeDirectory", "string", "readOnly", "POSIX home directory"),
+				attribute("loginShell", "string", "readOnly", "POSIX login shell"),
+			},
+		},
+		{
+			Id:          SchemaGroup,
+			Name:        "Group",
+			Description: "Group",
+			Attributes: []*Attribute{
+				displayName,
+				multiValued("members", "readWrite", "Member usernames",
+					attribute("value", "string", "immutable", ""),
+					attribute("display", "string", "readOnly", ""),
+					attribute("$ref", "reference", "readOnly", ""),
+				),
+			},
+		},
+		{
+			Id:          SchemaAsynxGroup,
+			Name:        "AsynxGroup",
+			Description: "asynx group attributes",
+			Attributes: []*Attribute{
+				attribute("ou", "string", "readOnly", "supplementary for role groups, additional otherwise"),
+				attribute("gidNumber", "string", "readOnly", "POSIX gid number"),
+			},
+		},
+	}
+	for _, schema := range schemas {
+		schema.Schemas = []string{SchemaSchema}
+		schema.Meta = &Meta{ResourceType: "Schema"}
+	}
+	return schemas
+}
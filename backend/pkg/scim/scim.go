@@ -0,0 +1,197 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// SCIM 2.0（RFC 7643/7644）的资源与消息格式
+
+const (
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaAsynxUser             = "urn:ietf:params:scim:schemas:extension:asynx:2.0:User"
+	SchemaAsynxGroup            = "urn:ietf:params:scim:schemas:extension:asynx:2.0:Group"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SchemaSchema                = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+)
+
+// 扩展 schema 在资源中以完整 URN 作为属性名
+var extensionSchemas = []string{SchemaAsynxUser, SchemaAsynxGroup}
+
+const ContentType = "application/scim+json"
+
+// 列表接口单页最多返回的资源数
+const MaxResults = 200
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+type Meta struct {
+	ResourceType string `json:"resourceType"`
+	Location     string `json:"location,omitempty"`
+}
+
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+}
+
+// MultiValued 是 emails、groups、members 等多值属性的元素
+type MultiValued struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+type AsynxUser struct {
+	Category      string `json:"category,omitempty"`
+	Role          string `json:"role,omitempty"`
+	UidNumber     string `json:"uidNumber,omitempty"`
+	HomeDirectory string `json:"homeDirectory,omitempty"`
+	LoginShell    string `json:"loginShell,omitempty"`
+}
+
+type User struct {
+	Schemas           []string      `json:"schemas"`
+	Id                string        `json:"id,omitempty"`
+	UserName          string        `json:"userName"`
+	Name              *Name         `json:"name,omitempty"`
+	DisplayName       string        `json:"displayName,omitempty"`
+	Emails            []MultiValued `json:"emails,omitempty"`
+	PreferredLanguage string        `json:"preferredLanguage,omitempty"`
+	Active            *bool         `json:"active,omitempty"`   // 账号未到期时为 true
+	Password          string        `json:"password,omitempty"` // 只写，不会出现在响应中
+	Groups            []MultiValued `json:"groups,omitempty"`
+	Asynx             *AsynxUser    `json:"urn:ietf:params:scim:schemas:extension:asynx:2.0:User,omitempty"`
+	Meta              *Meta         `json:"meta,omitempty"`
+}
+
+// PrimaryEmail 返回 primary 邮箱，没有标记时返回第一个
+func (u *User) PrimaryEmail() string {
+	for _, email := range u.Emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+	return ""
+}
+
+type AsynxGroup struct {
+	Ou        string `json:"ou,omitempty"`
+	GidNumber string `json:"gidNumber,omitempty"`
+}
+
+type Group struct {
+	Schemas     []string      `json:"schemas"`
+	Id          string        `json:"id,omitempty"`
+	DisplayName string        `json:"displayName"`
+	Members     []MultiValued `json:"members,omitempty"`
+	Asynx       *AsynxGroup   `json:"urn:ietf:params:scim:schemas:extension:asynx:2.0:Group,omitempty"`
+	Meta        *Meta         `json:"meta,omitempty"`
+}
+
+type ListResponse[T any] struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []T      `json:"Resources"`
+}
+
+// NewListResponse 按 1 起始的 startIndex 和 count 截取一页
+func NewListResponse[T any](all []T, startIndex int, count int) *ListResponse[T] {
+	startIndex = max(startIndex, 1)
+	count = min(max(count, 0), MaxResults)
+
+	from := min(startIndex-1, len(all))
+	to := min(from+count, len(all))
+	page := all[from:to]
+	if page == nil {
+		page = []T{}
+	}
+	return &ListResponse[T]{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: len(all),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(page),
+		Resources:    page,
+	}
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+const (
+	ErrInvalidFilter = "invalidFilter"
+	ErrTooMany       = "tooMany"
+	ErrUniqueness    = "uniqueness"
+	ErrMutability    = "mutability"
+	ErrInvalidSyntax = "invalidSyntax"
+	ErrInvalidPath   = "invalidPath"
+	ErrNoTarget      = "noTarget"
+	ErrInvalidValue  = "invalidValue"
+)
+
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+	code     int
+}
+
+func NewError(code int, scimType string, detail string) *Error {
+	return &Error{
+		Schemas:  []string{SchemaError},
+		Status:   strconv.Itoa(code),
+		ScimType: scimType,
+		Detail:   detail,
+		code:     code,
+	}
+}
+
+func (e *Error) Error() string { return e.Detail }
+
+func (e *Error) StatusCode() int { return e.code }
+
+func badRequest(scimType string, format string, args ...any) *Error {
+	return NewError(http.StatusBadRequest, scimType, fmt.Sprintf(format, args...))
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+// ToDocument 把资源转为通用的 JSON 对象，用于过滤和 PATCH
+func ToDocument(resource any) (map[string]any, error) {
+	b, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func FromDocument[T any](doc map[string]any) (*T, error) {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var resource T
+	if err := json.Unmarshal(b, &resource); err != nil {
+		return nil, badRequest(ErrInvalidValue, "invalid resource: %v", err)
+	}
+	return &resource, nil
+}
@@ -1,6 +1,9 @@
 package security
 
 import (
+	"errors"
+	"strings"
+
 	"github.com/dsx137/gg-gin/pkg/gggin"
 	"github.com/gin-gonic/gin"
 )
@@ -10,7 +13,20 @@ type GuardResult struct {
 	Role Role
 }
 
-func Guard(c *gin.Context, role Role) (string, Role, *gggin.HttpError) {
+// Guard 校验令牌并检查令牌持有者的角色是否拥有 permission
+func Guard(c *gin.Context, permission Permission) (string, Role, *gggin.HttpError) {
+	uid, role, err := authenticate(c)
+	if err != nil {
+		return "", RoleAnonymous, err
+	}
+	if !role.Can(permission) {
+		return "", RoleAnonymous, gggin.NewHttpError(403, "权限不足")
+	}
+	return uid, role, nil
+}
+
+// authenticate 校验令牌并返回令牌持有者及其当前角色
+func authenticate(c *gin.Context) (string, Role, *gggin.HttpError) {
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
 		return "", RoleAnonymous, gggin.NewHttpError(401, "缺少Authorization头")
@@ -20,21 +36,31 @@ func Guard(c *gin.Context, role Role) (string, Role, *gggin.HttpError) {
 	}
 
 	tokenString := authHeader[len("Bearer "):]
-	claims, err := ParsePaseto(tokenString)
+	var (
+		claims *PasetoClaims
+		err    error
+	)
+	if strings.HasPrefix(tokenString, AccessTokenPrefix) && accessTokenVerifier != nil {
+		claims, err = accessTokenVerifier(tokenString, c.Request.Method, c.FullPath())
+	} else {
+		claims, err = ParsePaseto(tokenString)
+		if err == nil && sessionResolver != nil {
+			claims.Role, err = sessionResolver(claims.Uid)
+		}
+	}
+	if errors.Is(err, ErrAccessTokenScope) {
+		return "", RoleAnonymous, gggin.NewHttpError(403, "令牌权限范围不包含该接口")
+	}
 	if err != nil {
 		return "", RoleAnonymous, gggin.NewHttpError(401, "无效的令牌: "+err.Error())
 	}
 
-	if !claims.Role.Support(role) {
-		return "", RoleAnonymous, gggin.NewHttpError(403, "权限不足")
-	}
-
 	return claims.Uid, claims.Role, nil
 }
 
-func GuardMiddleware(role Role) gin.HandlerFunc {
+func GuardMiddleware(permission Permission) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		uid, role, err := Guard(c, role)
+		uid, role, err := Guard(c, permission)
 		if err != nil {
 			c.JSON(err.StatusCode, err.Message)
 			c.Abort()
@@ -45,3 +71,28 @@ func GuardMiddleware(role Role) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// GuardMiddlewareOr 要求 permission，没有时只允许拥有 users.self 且 widen 返回 nil 的调用者访问，
+// 用于本人访问自己的资源或委派管理员管理被委派的账号
+func GuardMiddlewareOr(permission Permission, widen func(c *gin.Context, guard *GuardResult) *gggin.HttpError) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, role, err := authenticate(c)
+		guard := &GuardResult{Uid: uid, Role: role}
+		switch {
+		case err != nil:
+		case role.Can(permission):
+		case !role.Can(PermUsersSelf):
+			err = gggin.NewHttpError(403, "权限不足")
+		default:
+			err = widen(c, guard)
+		}
+		if err != nil {
+			c.JSON(err.StatusCode, err.Message)
+			c.Abort()
+			return
+		}
+
+		c.Set("guard", guard)
+		c.Next()
+	}
+}
@@ -0,0 +1,99 @@
+package security
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"aidanwoods.dev/go-paseto"
+	"asynclab.club/asynx/backend/pkg/config"
+	"github.com/dsx137/gg-gin/pkg/gggin"
+	"github.com/gin-gonic/gin"
+)
+
+func guardRequest(token string, permission Permission) (Role, int) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	c.Request.Header.Set("Authorization", "Bearer "+token)
+	_, role, err := Guard(c, permission)
+	if err != nil {
+		return role, err.StatusCode
+	}
+	return role, http.StatusOK
+}
+
+func TestGuardResolvesSessionRole(t *testing.T) {
+	config.PasetoKey = paseto.NewV4SymmetricKey()
+	t.Cleanup(func() { SetSessionResolver(nil) })
+
+	// 令牌签发时持有临时的 admin 角色
+	token, err := GeneratePaseto("2024000001", RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		resolver   SessionResolver
+		permission Permission
+		wantRole   Role
+		wantStatus int
+	}{
+		{"role still held", func(string) (Role, error) { return RoleAdmin, nil }, PermOidcManage, RoleAdmin, http.StatusOK},
+		{"grant reverted", func(string) (Role, error) { return RoleDefault, nil }, PermOidcManage, RoleAnonymous, http.StatusForbidden},
+		{"grant reverted, lower permission", func(string) (Role, error) { return RoleDefault, nil }, PermUsersSelf, RoleDefault, http.StatusOK},
+		{"user removed", func(string) (Role, error) { return RoleAnonymous, errors.New("not found") }, PermUsersSelf, RoleAnonymous, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetSessionResolver(tt.resolver)
+			role, status := guardRequest(token, tt.permission)
+			if status != tt.wantStatus || role != tt.wantRole {
+				t.Errorf("got %s %d, want %s %d", role, status, tt.wantRole, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestGuardMiddlewareOrWidensOnlyForSelf(t *testing.T) {
+	config.PasetoKey = paseto.NewV4SymmetricKey()
+	t.Cleanup(func() { SetSessionResolver(nil) })
+	SetSessionResolver(nil)
+
+	self := func(c *gin.Context, guard *GuardResult) *gggin.HttpError {
+		if c.Param("uid") != guard.Uid {
+			return gggin.NewHttpError(http.StatusForbidden, "权限不足")
+		}
+		return nil
+	}
+	router := gin.New()
+	router.GET("/users/:uid", GuardMiddlewareOr(PermUsersRead, self), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name       string
+		role       Role
+		uid        string
+		wantStatus int
+	}{
+		{"strict permission", RoleAdmin, "2024000002", http.StatusOK},
+		{"self", RoleRestricted, "2024000001", http.StatusOK},
+		{"other user", RoleRestricted, "2024000002", http.StatusForbidden},
+		{"no users.self", RoleAnonymous, "2024000001", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := GeneratePaseto("2024000001", tt.role)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/users/"+tt.uid, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("got %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package security
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPasswordHash(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=65536,t=3,p=2$") {
+		t.Errorf("got hash %q", hash)
+	}
+	// 每次使用不同的盐
+	if again, _ := HashPassword("correct horse"); again == hash {
+		t.Error("two hashes of the same password are equal")
+	}
+
+	tests := []struct {
+		name     string
+		encoded  string
+		password string
+		ok       bool
+		wantErr  bool
+	}{
+		{"matching password", hash, "correct horse", true, false},
+		{"wrong password", hash, "correct horse ", false, false},
+		{"other algorithm", strings.Replace(hash, "argon2id", "argon2i", 1), "correct horse", false, true},
+		{"other version", strings.Replace(hash, "v=19", "v=16", 1), "correct horse", false, true},
+		{"bad parameters", strings.Replace(hash, "m=65536", "m=x", 1), "correct horse", false, true},
+		{"truncated", hash[:strings.LastIndex(hash, "$")], "correct horse", false, true},
+		{"ldap hash", "{SSHA}abcdef", "correct horse", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := VerifyPassword(tt.encoded, tt.password)
+			if ok != tt.ok || (err != nil) != tt.wantErr {
+				t.Errorf("got %v, %v, want %v, err=%v", ok, err, tt.ok, tt.wantErr)
+			}
+		})
+	}
+}
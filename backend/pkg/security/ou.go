@@ -8,6 +8,7 @@ const (
 	OuUserSystem   OuUser = "system"
 	OuUserMember   OuUser = "member"
 	OuUserExternal OuUser = "external"
+	OuUserAlumni   OuUser = "alumni" // 毕业的成员，由生命周期策略从 member 转入
 	OuUserUnknown  OuUser = "unknown"
 )
 
@@ -20,6 +21,8 @@ func GetOuUserFromName(ouName string) (OuUser, error) {
 		return OuUserMember, nil
 	case "external":
 		return OuUserExternal, nil
+	case "alumni":
+		return OuUserAlumni, nil
 	default:
 		return OuUserUnknown, fmt.Errorf("unknown ou user: %s", ouName)
 	}
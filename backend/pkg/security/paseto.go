@@ -1,6 +1,7 @@
 package security
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -13,13 +14,38 @@ type PasetoClaims struct {
 	Role Role   `json:"role"`
 }
 
+// SessionResolver 返回登录令牌持有者的当前角色，令牌中的角色只是签发时的快照
+type SessionResolver func(uid string) (Role, error)
+
+var sessionResolver SessionResolver
+
+// SetSessionResolver 注册登录令牌的角色解析逻辑，未注册时 Guard 使用令牌中的角色
+func SetSessionResolver(resolver SessionResolver) {
+	sessionResolver = resolver
+}
+
+// 转发认证令牌的 audience，这种令牌只能用于 /api/auth/verify，不能访问其他接口
+const ForwardAuthAudience = "forward-auth"
+
 func GeneratePaseto(uid string, role Role) (string, error) {
+	return generatePaseto(uid, role, "")
+}
+
+// GenerateForwardAuthPaseto 生成只能用于转发认证的令牌
+func GenerateForwardAuthPaseto(uid string, role Role) (string, error) {
+	return generatePaseto(uid, role, ForwardAuthAudience)
+}
+
+func generatePaseto(uid string, role Role, audience string) (string, error) {
 	token := paseto.NewToken()
 
 	token.SetSubject(uid)
 	token.SetIssuedAt(time.Now())
 	token.SetExpiration(time.Now().Add(time.Hour * 24))
 	token.SetIssuer("asynx")
+	if audience != "" {
+		token.SetAudience(audience)
+	}
 
 	token.Set("claims", &PasetoClaims{
 		Uid:  uid,
@@ -29,11 +55,27 @@ func GeneratePaseto(uid string, role Role) (string, error) {
 	return token.V4Encrypt(config.PasetoKey, nil), nil
 }
 
+// ParsePaseto 解析登录令牌，拒绝转发认证令牌
 func ParsePaseto(tokenString string) (*PasetoClaims, error) {
+	return parsePaseto(tokenString, func(token paseto.Token) error {
+		if audience, err := token.GetAudience(); err == nil && audience != "" {
+			return errors.New("token is not a session token")
+		}
+		return nil
+	})
+}
+
+// ParseForwardAuthPaseto 解析转发认证令牌，拒绝登录令牌
+func ParseForwardAuthPaseto(tokenString string) (*PasetoClaims, error) {
+	return parsePaseto(tokenString, paseto.ForAudience(ForwardAuthAudience))
+}
+
+func parsePaseto(tokenString string, audience paseto.Rule) (*PasetoClaims, error) {
 	parser := paseto.NewParser()
 
 	parser.AddRule(paseto.NotExpired())
 	parser.AddRule(paseto.IssuedBy("asynx"))
+	parser.AddRule(audience)
 
 	parsedToken, err := parser.ParseV4Local(config.PasetoKey, tokenString, nil)
 	if err != nil {
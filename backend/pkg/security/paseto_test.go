@@ -0,0 +1,45 @@
+package security
+
+import (
+	"testing"
+
+	"aidanwoods.dev/go-paseto"
+	"asynclab.club/asynx/backend/pkg/config"
+)
+
+func TestForwardAuthTokensAreSeparateFromSessionTokens(t *testing.T) {
+	config.PasetoKey = paseto.NewV4SymmetricKey()
+
+	session, err := GeneratePaseto("2024000001", RoleDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forward, err := GenerateForwardAuthPaseto("2024000001", RoleDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		parse func(string) (*PasetoClaims, error)
+		token string
+		ok    bool
+	}{
+		{"session token as session", ParsePaseto, session, true},
+		{"forward auth token as session", ParsePaseto, forward, false},
+		{"forward auth token for verify", ParseForwardAuthPaseto, forward, true},
+		{"session token for verify", ParseForwardAuthPaseto, session, false},
+		{"garbage", ParseForwardAuthPaseto, "v4.local.garbage", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := tt.parse(tt.token)
+			if (err == nil) != tt.ok {
+				t.Fatalf("got err %v, want ok=%v", err, tt.ok)
+			}
+			if tt.ok && claims.Uid != "2024000001" {
+				t.Errorf("got uid %s", claims.Uid)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package security
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+type Permission string
+
+const (
+	PermUsersSelf             Permission = "users.self"              // 查看和修改自己的资料、密码、通知设置，管理自己的令牌和授权
+	PermUsersReadOu           Permission = "users.read.ou"           // 查看同一分类下的用户
+	PermUsersRead             Permission = "users.read"              // 查看所有用户
+	PermUsersWrite            Permission = "users.write"             // 注册和删除用户，修改任何用户的分类、密码和设置
+	PermUsersRoleGrant        Permission = "users.role.grant"        // 修改用户的角色
+	PermGroupsManage          Permission = "groups.manage"           // 管理附加组的成员
+	PermAuditRead             Permission = "audit.read"              // 查看操作记录、发件箱、事件流和 Webhook 投递记录
+	PermMailManage            Permission = "mail.manage"             // 预览模板、重发和删除邮件、群发通知
+	PermWebhooksManage        Permission = "webhooks.manage"         // 管理 Webhook 端点
+	PermOidcManage            Permission = "oidc.manage"             // 管理 OIDC 客户端
+	PermServiceAccountsManage Permission = "service-accounts.manage" // 管理服务账号及其密钥
+	PermAccessTokensManage    Permission = "access-tokens.manage"    // 查看和撤销其他用户的个人访问令牌
+	PermSystemManage          Permission = "system.manage"           // 重试操作、清空缓存等运维操作
+)
+
+func (p Permission) String() string { return string(p) }
+
+func AllPermissions() []Permission {
+	return []Permission{
+		PermUsersSelf, PermUsersReadOu, PermUsersRead, PermUsersWrite, PermUsersRoleGrant, PermGroupsManage,
+		PermAuditRead, PermMailManage, PermWebhooksManage, PermOidcManage, PermServiceAccountsManage,
+		PermAccessTokensManage, PermSystemManage,
+	}
+}
+
+// 内置角色的权限与原来的 admin > default > restricted 等级一致
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin:      AllPermissions(),
+	RoleDefault:    {PermUsersSelf, PermUsersReadOu},
+	RoleRestricted: {PermUsersSelf},
+	RoleAnonymous:  {},
+}
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]{1,31}$`)
+
+// ParsePermissions 解析权限列表，支持 * 表示全部权限，以及 users.* 这样的前缀通配
+func ParsePermissions(names []string) ([]Permission, error) {
+	var permissions []Permission
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		var matched []Permission
+		if prefix, ok := strings.CutSuffix(name, "*"); ok {
+			for _, p := range AllPermissions() {
+				if strings.HasPrefix(p.String(), prefix) {
+					matched = append(matched, p)
+				}
+			}
+		} else if slices.Contains(AllPermissions(), Permission(name)) {
+			matched = []Permission{Permission(name)}
+		}
+		if len(matched) == 0 {
+			return nil, fmt.Errorf("unknown permission: %s", name)
+		}
+		for _, p := range matched {
+			if !slices.Contains(permissions, p) {
+				permissions = append(permissions, p)
+			}
+		}
+	}
+	return permissions, nil
+}
+
+// RegisterRole 定义自定义角色，只能在启动时调用。内置角色不能被重新定义
+func RegisterRole(role Role, permissions []Permission) error {
+	if _, ok := rolePermissions[role]; ok {
+		return fmt.Errorf("role %s is already defined", role)
+	}
+	if !roleNamePattern.MatchString(role.String()) {
+		return fmt.Errorf("invalid role name %q, must match %s", role, roleNamePattern)
+	}
+	if len(permissions) == 0 {
+		return fmt.Errorf("role %s has no permissions", role)
+	}
+	rolePermissions[role] = permissions
+	return nil
+}
+
+// CustomRoles 返回通过配置定义的角色
+func CustomRoles() []Role {
+	var roles []Role
+	for _, role := range slices.Sorted(maps.Keys(rolePermissions)) {
+		if !slices.Contains(AllRoles(), role) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+func (r Role) Permissions() []Permission { return rolePermissions[r] }
+
+// Can 检查角色是否拥有权限，users.read 包含 users.read.ou
+func (r Role) Can(p Permission) bool {
+	if p == PermUsersReadOu && slices.Contains(rolePermissions[r], PermUsersRead) {
+		return true
+	}
+	return slices.Contains(rolePermissions[r], p)
+}
@@ -0,0 +1,71 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/config"
+)
+
+// 工作量证明题目的格式为 "<随机数>.<到期 Unix 时间>.<签名>"，签名使用 Paseto 密钥派生，服务端无需保存题目
+
+func powSignature(payload string) string {
+	mac := hmac.New(sha256.New, config.PasetoKey.ExportBytes())
+	mac.Write([]byte("pow:" + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// GeneratePowChallenge 生成在 ttl 后过期的题目
+func GeneratePowChallenge(ttl time.Duration) (string, time.Time, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := time.Now().Add(ttl)
+	payload := fmt.Sprintf("%s.%d", hex.EncodeToString(b), expiresAt.Unix())
+	return payload + "." + powSignature(payload), expiresAt, nil
+}
+
+// VerifyPowChallenge 校验题目的签名和有效期，返回题目的到期时间
+func VerifyPowChallenge(challenge string, now time.Time) (time.Time, error) {
+	i := strings.LastIndex(challenge, ".")
+	if i < 0 {
+		return time.Time{}, errors.New("malformed challenge")
+	}
+	payload, signature := challenge[:i], challenge[i+1:]
+	if !hmac.Equal([]byte(signature), []byte(powSignature(payload))) {
+		return time.Time{}, errors.New("invalid challenge signature")
+	}
+	_, expiry, _ := strings.Cut(payload, ".")
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return time.Time{}, errors.New("malformed challenge")
+	}
+	expiresAt := time.Unix(unix, 0)
+	if now.After(expiresAt) {
+		return time.Time{}, errors.New("challenge has expired")
+	}
+	return expiresAt, nil
+}
+
+// PowSolved 检查 SHA-256("<题目>:<解>") 是否至少有 difficulty 个前导零比特
+func PowSolved(challenge, nonce string, difficulty int) bool {
+	sum := sha256.Sum256([]byte(challenge + ":" + nonce))
+	zeros := 0
+	for _, b := range sum {
+		if b != 0 {
+			zeros += bits.LeadingZeros8(b)
+			break
+		}
+		zeros += 8
+	}
+	return zeros >= difficulty
+}
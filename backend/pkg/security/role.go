@@ -2,6 +2,7 @@ package security
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"asynclab.club/asynx/backend/pkg/entity"
@@ -20,42 +21,34 @@ func AllRoles() []Role { return []Role{RoleAdmin, RoleDefault, RoleRestricted, R
 
 func (r Role) String() string { return string(r) }
 
+// Support 检查 r 是否拥有 other 的全部权限，内置角色之间与原来的等级关系一致
 func (r Role) Support(other Role) bool {
-	switch r {
-	case RoleAdmin:
-		return other == RoleAdmin || other == RoleDefault || other == RoleRestricted
-	case RoleDefault:
-		return other == RoleDefault || other == RoleRestricted
-	case RoleRestricted:
-		return other == RoleRestricted
-	default:
+	if !r.IsValid() || !other.IsValid() || r == RoleAnonymous || other == RoleAnonymous {
 		return false
 	}
+	for _, p := range other.Permissions() {
+		if !r.Can(p) {
+			return false
+		}
+	}
+	return true
 }
 
 func (r Role) IsValid() bool {
-	switch r {
-	case RoleAdmin, RoleDefault, RoleRestricted, RoleAnonymous:
-		return true
-	default:
-		return false
-	}
+	_, ok := rolePermissions[r]
+	return ok
 }
 
+// IsHigherThan 检查 r 的权限是否严格包含 other 的权限，任何有效角色都高于 anonymous。
+// 自定义角色之间可能互不包含，此时两者都不高于对方
 func (r Role) IsHigherThan(other Role) bool {
-	if !r.IsValid() || !other.IsValid() {
+	if !r.IsValid() || !other.IsValid() || r == RoleAnonymous {
 		return false
 	}
-	switch r {
-	case RoleAdmin:
-		return other != RoleAdmin
-	case RoleDefault:
-		return other == RoleRestricted || other == RoleAnonymous
-	case RoleRestricted:
-		return other == RoleAnonymous
-	default:
-		return false
+	if other == RoleAnonymous {
+		return true
 	}
+	return r.Support(other) && !other.Support(r)
 }
 
 func GetRoleFromName(authority string) (Role, error) {
@@ -66,27 +59,33 @@ func GetRoleFromName(authority string) (Role, error) {
 		return RoleDefault, nil
 	case "restricted":
 		return RoleRestricted, nil
-	default:
-		return RoleAnonymous, fmt.Errorf("unknown role: %s", authority)
 	}
+	if role := Role(strings.ToLower(authority)); role != RoleAnonymous && role.IsValid() {
+		return role, nil
+	}
+	return RoleAnonymous, fmt.Errorf("unknown role: %s", authority)
 }
 
 func GetRoleFromLdapGroup(ldapGroup *entity.Group) (Role, error) {
 	return GetRoleFromName(ldapGroup.Cn)
 }
 
+// GetRoleFromLdapGroups 返回用户所属角色组中权限最多的角色，它必须包含其他所有角色的权限。
+// 用户属于互不包含的角色时无法确定应有的权限，返回错误而不是任选其一
 func GetRoleFromLdapGroups(ldapGroups []*entity.Group) (Role, error) {
-	highestRole, valid := RoleAnonymous, false
+	var roles []Role
 	for _, ldapGroup := range ldapGroups {
-		if role, err := GetRoleFromLdapGroup(ldapGroup); err == nil {
-			if role.IsHigherThan(highestRole) {
-				highestRole = role
-				valid = true
-			}
+		if role, err := GetRoleFromLdapGroup(ldapGroup); err == nil && !slices.Contains(roles, role) {
+			roles = append(roles, role)
 		}
 	}
-	if !valid {
-		return highestRole, fmt.Errorf("no valid role found")
+	if len(roles) == 0 {
+		return RoleAnonymous, fmt.Errorf("no valid role found")
+	}
+	for _, candidate := range roles {
+		if !slices.ContainsFunc(roles, func(role Role) bool { return !candidate.Support(role) }) {
+			return candidate, nil
+		}
 	}
-	return highestRole, nil
+	return RoleAnonymous, fmt.Errorf("roles %v do not include each other", roles)
 }
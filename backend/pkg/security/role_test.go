@@ -0,0 +1,78 @@
+package security
+
+import (
+	"testing"
+
+	"asynclab.club/asynx/backend/pkg/entity"
+)
+
+func TestRoleOrdering(t *testing.T) {
+	// auditor 和 mailer 的权限数量相同但互不包含，operator 包含两者
+	for role, permissions := range map[Role][]Permission{
+		"auditor":  {PermUsersSelf, PermAuditRead},
+		"mailer":   {PermUsersSelf, PermMailManage},
+		"operator": {PermUsersSelf, PermUsersReadOu, PermAuditRead, PermMailManage},
+	} {
+		if !role.IsValid() {
+			if err := RegisterRole(role, permissions); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tests := []struct {
+		r, other Role
+		higher   bool
+	}{
+		{RoleAdmin, RoleDefault, true},
+		{RoleDefault, RoleRestricted, true},
+		{RoleRestricted, RoleAnonymous, true},
+		{RoleRestricted, RoleDefault, false},
+		{RoleDefault, RoleDefault, false},
+		{RoleAnonymous, RoleAnonymous, false},
+		{"auditor", RoleRestricted, true},
+		{"auditor", RoleDefault, false},
+		{RoleDefault, "auditor", false},
+		{"auditor", "mailer", false},
+		{"mailer", "auditor", false},
+		{"operator", "auditor", true},
+		{RoleAdmin, "operator", true},
+		{"unknown", RoleAnonymous, false},
+	}
+	for _, tt := range tests {
+		if got := tt.r.IsHigherThan(tt.other); got != tt.higher {
+			t.Errorf("%s.IsHigherThan(%s) = %v, want %v", tt.r, tt.other, got, tt.higher)
+		}
+	}
+
+	groups := func(cns ...string) []*entity.Group {
+		var result []*entity.Group
+		for _, cn := range cns {
+			result = append(result, &entity.Group{Cn: cn})
+		}
+		return result
+	}
+	roleTests := []struct {
+		name   string
+		groups []*entity.Group
+		want   Role
+		ok     bool
+	}{
+		{"single role", groups("default"), RoleDefault, true},
+		{"built-in roles", groups("restricted", "admin", "default"), RoleAdmin, true},
+		{"unknown groups ignored", groups("staff", "restricted"), RoleRestricted, true},
+		{"custom role above built-in", groups("restricted", "auditor"), "auditor", true},
+		{"incomparable roles", groups("auditor", "mailer"), RoleAnonymous, false},
+		{"incomparable roles under a common role", groups("auditor", "operator", "mailer"), "operator", true},
+		{"incomparable with built-in", groups("default", "auditor"), RoleAnonymous, false},
+		{"no role", groups("staff"), RoleAnonymous, false},
+	}
+	for _, tt := range roleTests {
+		t.Run(tt.name, func(t *testing.T) {
+			role, err := GetRoleFromLdapGroups(tt.groups)
+			if (err == nil) != tt.ok || role != tt.want {
+				t.Errorf("got %s, %v, want %s ok=%v", role, err, tt.want, tt.ok)
+			}
+		})
+	}
+}
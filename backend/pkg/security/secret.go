@@ -0,0 +1,69 @@
+package security
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"asynclab.club/asynx/backend/pkg/config"
+)
+
+// 共享存储中的密钥以 "enc:v1:<base64>" 格式保存，AES-GCM 密钥由 Paseto 密钥派生，所有实例需要配置相同的 PASETO_SECRET
+
+const sealedPrefix = "enc:v1:"
+
+func secretCipher() (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, config.PasetoKey.ExportBytes())
+	mac.Write([]byte("secret"))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// SealSecret 加密密钥，空字符串保持不变
+func SealSecret(secret string) (string, error) {
+	if secret == "" {
+		return "", nil
+	}
+	aead, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(secret), nil)
+	return sealedPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenSecret 解密 SealSecret 的结果，没有前缀的旧数据按明文返回
+func OpenSecret(sealed string) (string, error) {
+	encoded, ok := strings.CutPrefix(sealed, sealedPrefix)
+	if !ok {
+		return sealed, nil
+	}
+	data, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	aead, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+	if len(data) < aead.NonceSize() {
+		return "", errors.New("sealed secret too short")
+	}
+	secret, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("failed to decrypt secret, check PASETO_SECRET")
+	}
+	return string(secret), nil
+}
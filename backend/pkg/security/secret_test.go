@@ -0,0 +1,28 @@
+package security
+
+import (
+	"testing"
+
+	"aidanwoods.dev/go-paseto"
+	"asynclab.club/asynx/backend/pkg/config"
+)
+
+func TestSealedSecretNeedsTheSameKey(t *testing.T) {
+	config.PasetoKey = paseto.NewV4SymmetricKey()
+
+	sealed, err := SealSecret("s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret, err := OpenSecret(sealed); err != nil || secret != "s3cret" {
+		t.Errorf("got %q, %v, want s3cret", secret, err)
+	}
+	if secret, err := OpenSecret("legacy"); err != nil || secret != "legacy" {
+		t.Errorf("got %q, %v for a plaintext secret, want it unchanged", secret, err)
+	}
+
+	config.PasetoKey = paseto.NewV4SymmetricKey()
+	if _, err := OpenSecret(sealed); err == nil {
+		t.Error("opened a secret sealed with another key")
+	}
+}
@@ -0,0 +1,84 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// 个人访问令牌的前缀，用于与 PASETO 令牌区分，也便于密钥扫描工具识别
+const AccessTokenPrefix = "asynx_pat_"
+
+// 令牌权限范围不包含当前接口
+var ErrAccessTokenScope = errors.New("access token scope does not cover this endpoint")
+
+// AccessTokenVerifier 校验个人访问令牌能否访问 method 和 route（gin 的路由模板）对应的接口
+type AccessTokenVerifier func(token string, method string, route string) (*PasetoClaims, error)
+
+var accessTokenVerifier AccessTokenVerifier
+
+// SetAccessTokenVerifier 注册个人访问令牌的校验逻辑，未注册时 Guard 只接受 PASETO 令牌
+func SetAccessTokenVerifier(verifier AccessTokenVerifier) {
+	accessTokenVerifier = verifier
+}
+
+// 权限范围的访问级别，write 包含 read
+const (
+	AccessRead  = "read"
+	AccessWrite = "write"
+)
+
+// ParseAccessScope 解析 <资源>:<read|write> 格式的权限范围，资源是 /api 下的第一级路径，* 表示全部资源
+func ParseAccessScope(scope string) (string, string, error) {
+	resource, access, ok := strings.Cut(scope, ":")
+	if !ok || resource == "" || (access != AccessRead && access != AccessWrite) {
+		return "", "", fmt.Errorf("invalid scope %q, expected <resource>:read or <resource>:write", scope)
+	}
+	if resource != "*" && strings.ContainsAny(resource, "/*:") {
+		return "", "", fmt.Errorf("invalid scope resource %q", resource)
+	}
+	return resource, access, nil
+}
+
+// RouteResource 返回路由所属的资源，即 /api 下的第一级路径
+func RouteResource(route string) string {
+	rest, ok := strings.CutPrefix(route, "/api/")
+	if !ok {
+		return ""
+	}
+	resource, _, _ := strings.Cut(rest, "/")
+	return resource
+}
+
+// AccessScopesAllow 检查权限范围是否允许访问接口，GET 和 HEAD 只需要 read
+func AccessScopesAllow(scopes []string, method string, route string) bool {
+	resource := RouteResource(route)
+	if resource == "" {
+		return false
+	}
+	readOnly := method == http.MethodGet || method == http.MethodHead
+	return slices.ContainsFunc(scopes, func(scope string) bool {
+		r, access, err := ParseAccessScope(scope)
+		if err != nil || (r != "*" && r != resource) {
+			return false
+		}
+		return access == AccessWrite || readOnly
+	})
+}
+
+// AccessScopeCovered 检查 scope 是否在 allowed 允许的范围之内
+func AccessScopeCovered(allowed []string, scope string) bool {
+	resource, access, err := ParseAccessScope(scope)
+	if err != nil {
+		return false
+	}
+	return slices.ContainsFunc(allowed, func(a string) bool {
+		r, level, err := ParseAccessScope(a)
+		if err != nil || (r != "*" && r != resource) {
+			return false
+		}
+		return level == AccessWrite || access == AccessRead
+	})
+}
@@ -40,3 +40,21 @@ func ValidateMemberUsernameLegality(username string) error {
 
 	return nil
 }
+
+// EnrollmentYear 返回学号前 4 位表示的入学年份
+func EnrollmentYear(username string) (int, error) {
+	if err := ValidateMemberUsernameLegality(username); err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(username[:4])
+}
+
+var serviceAccountUsernamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]{2,31}$`)
+
+// ValidateServiceAccountUsername 服务账号的用户名由小写字母、数字和连字符组成，以字母开头，长度 3 到 32
+func ValidateServiceAccountUsername(username string) error {
+	if !serviceAccountUsernamePattern.MatchString(username) {
+		return fmt.Errorf("service account username must match %s, got %s", serviceAccountUsernamePattern, username)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/security"
+)
+
+// ForwardAuthIdentity 是转发认证通过后交给上游应用的用户信息
+type ForwardAuthIdentity struct {
+	Uid    string
+	Role   security.Role
+	Groups []string
+}
+
+// Satisfies 检查角色是否满足要求（role 为空时任意角色均可）并拥有全部 permissions，并且在 groups 非空时至少属于其中一个组
+func (i *ForwardAuthIdentity) Satisfies(role security.Role, permissions []security.Permission, groups []string) bool {
+	if i.Role == security.RoleAnonymous || (role != "" && !i.Role.Support(role)) {
+		return false
+	}
+	for _, permission := range permissions {
+		if !i.Role.Can(permission) {
+			return false
+		}
+	}
+	if len(groups) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(groups, func(group string) bool { return slices.Contains(i.Groups, group) })
+}
+
+type ServiceForwardAuth struct {
+	serviceUser  *ServiceUser
+	serviceGroup *ServiceGroup
+}
+
+func NewServiceForwardAuth(manager *ServiceManager) *ServiceForwardAuth {
+	return &ServiceForwardAuth{
+		serviceUser:  manager.serviceUser,
+		serviceGroup: manager.serviceGroup,
+	}
+}
+
+// Identify 按目录中的当前状态查询角色和组，令牌签发后被降级、移出组或到期的用户会立即失去访问权限
+func (s *ServiceForwardAuth) Identify(uid string) (*ForwardAuthIdentity, error) {
+	user, err := s.serviceUser.FindByUid(uid)
+	if err != nil {
+		return nil, err
+	}
+	if IsAccountExpired(user, time.Now()) {
+		return nil, WrapError(ErrDenied, fmt.Sprintf("account %s has expired", uid))
+	}
+	role, err := s.serviceGroup.GetRole(user)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := s.serviceGroup.FindCnsByMemberUid(uid)
+	if err != nil {
+		return nil, err
+	}
+	return &ForwardAuthIdentity{Uid: uid, Role: role, Groups: groups}, nil
+}
+
+// IssueToken 为已登录的用户签发转发认证令牌，到期的账号不能签发
+func (s *ServiceForwardAuth) IssueToken(uid string) (string, error) {
+	identity, err := s.Identify(uid)
+	if err != nil {
+		return "", err
+	}
+	return security.GenerateForwardAuthPaseto(identity.Uid, identity.Role)
+}
@@ -0,0 +1,308 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/entity"
+	"asynclab.club/asynx/backend/pkg/mail"
+	"asynclab.club/asynx/backend/pkg/outbox"
+	"asynclab.club/asynx/backend/pkg/persist"
+	"asynclab.club/asynx/backend/pkg/repository"
+	"asynclab.club/asynx/backend/pkg/security"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// BroadcastTarget 选择群发的收件人，Category、Role、Group 必须且只能指定一个
+type BroadcastTarget struct {
+	Category string `json:"category,omitempty"`
+	Role     string `json:"role,omitempty"`
+	Group    string `json:"group,omitempty"`
+	GroupOu  string `json:"groupOu,omitempty"` // Group 所在的组织单元，默认为 additional
+}
+
+const (
+	RecipientDryRun  = "dry-run"
+	RecipientSkipped = "skipped"
+)
+
+type BroadcastRecipient struct {
+	Uid       string `json:"uid"`
+	Mail      string `json:"mail"`
+	MessageId string `json:"messageId,omitempty"`
+	State     string `json:"state"` // pending|sent|dead|skipped|dry-run
+	Error     string `json:"error,omitempty"`
+}
+
+type BroadcastSummary struct {
+	Total   int `json:"total"`
+	Pending int `json:"pending"`
+	Sent    int `json:"sent"`
+	Dead    int `json:"dead"`
+	Skipped int `json:"skipped"`
+}
+
+type Broadcast struct {
+	Id         string               `json:"id"`
+	Subject    string               `json:"subject"`
+	Format     mail.ContentFormat   `json:"format"`
+	Target     BroadcastTarget      `json:"target"`
+	Operator   string               `json:"operator"`
+	DryRun     bool                 `json:"dryRun"`
+	CreatedAt  time.Time            `json:"createdAt"`
+	Recipients []BroadcastRecipient `json:"recipients,omitempty"`
+	Summary    BroadcastSummary     `json:"summary"`
+}
+
+type ServiceBroadcast struct {
+	serviceUser  *ServiceUser
+	serviceGroup *ServiceGroup
+	outbox       *outbox.Outbox
+	broadcasts   *persist.SharedCollection[Broadcast]
+}
+
+// NewServiceBroadcast 创建群发服务，群发记录保存在共享存储中
+func NewServiceBroadcast(store repository.Store, outbox *outbox.Outbox) *ServiceBroadcast {
+	s := &ServiceBroadcast{
+		serviceUser: NewServiceUser(store.Users()),
+		// 只用于查询，不需要注册角色切换操作
+		serviceGroup: &ServiceGroup{repositoryGroup: store.Groups()},
+		outbox:       outbox,
+		broadcasts:   persist.NewSharedCollection[Broadcast](store.Shared(), "broadcasts"),
+	}
+	outbox.Subscribe(s.track)
+	return s
+}
+
+// track 把群发邮件的投递结果写回群发记录，Ref 的格式为 "<群发 Id>/<uid>"
+func (s *ServiceBroadcast) track(msg outbox.Message) {
+	id, uid, ok := strings.Cut(msg.Ref, "/")
+	if !ok || !msg.Bulk {
+		return
+	}
+	err := s.broadcasts.Update(id, func(broadcast *Broadcast) error {
+		for i := range broadcast.Recipients {
+			if recipient := &broadcast.Recipients[i]; recipient.Uid == uid {
+				recipient.MessageId = msg.Id
+				recipient.State = string(msg.State)
+				recipient.Error = msg.LastError
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logrus.Errorf("Failed to record delivery of mail %s in broadcast %s: %v", msg.Id, id, err)
+	}
+}
+
+// excluded 判断用户是否不接收群发
+func excluded(user *entity.User, target *BroadcastTarget, now time.Time) bool {
+	if IsServiceAccount(user) || IsAccountExpired(user, now) {
+		return true
+	}
+	return user.Ou == security.OuUserAlumni.String() && target.Category != security.OuUserAlumni.String()
+}
+
+// resolve 按目标查找收件人，结果按 uid 排序
+func (s *ServiceBroadcast) resolve(target *BroadcastTarget) ([]*entity.User, error) {
+	users, err := s.candidates(target)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	users = slices.DeleteFunc(users, func(user *entity.User) bool { return excluded(user, target, now) })
+	slices.SortFunc(users, func(a, b *entity.User) int { return strings.Compare(a.Uid, b.Uid) })
+	return users, nil
+}
+
+func (s *ServiceBroadcast) candidates(target *BroadcastTarget) ([]*entity.User, error) {
+	specified := 0
+	for _, v := range []string{target.Category, target.Role, target.Group} {
+		if v != "" {
+			specified++
+		}
+	}
+	if specified != 1 {
+		return nil, WrapError(ErrInvalid, "exactly one of category, role and group must be specified")
+	}
+
+	if target.Category != "" {
+		ou, err := security.GetOuUserFromName(target.Category)
+		if err != nil {
+			return nil, WrapError(ErrInvalid, err.Error())
+		}
+		return s.serviceUser.FindAllByOu(ou)
+	}
+
+	var group *entity.Group
+	if target.Role != "" {
+		role, err := security.GetRoleFromName(target.Role)
+		if err != nil || role == security.RoleAnonymous {
+			return nil, WrapError(ErrInvalid, fmt.Sprintf("invalid role: %s", target.Role))
+		}
+		if group, err = s.serviceGroup.FindByOuAndCn(security.OuGroupSupplementary, role.String()); err != nil {
+			return nil, err
+		}
+	} else {
+		ouName := target.GroupOu
+		if ouName == "" {
+			ouName = security.OuGroupAdditional.String()
+		}
+		ou, err := security.GetOuGroupFromName(ouName)
+		if err != nil {
+			return nil, WrapError(ErrInvalid, err.Error())
+		}
+		if group, err = s.serviceGroup.FindByOuAndCn(ou, target.Group); err != nil {
+			return nil, err
+		}
+	}
+
+	users, err := s.serviceUser.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(users, func(user *entity.User) bool {
+		return !slices.Contains(group.MemberUid, user.Uid)
+	}), nil
+}
+
+// Send 向目标用户群发邮件，dryRun 时只返回收件人而不发送，也不保存记录
+func (s *ServiceBroadcast) Send(operator string, subject string, format string, body string, target BroadcastTarget, dryRun bool) (*Broadcast, error) {
+	if subject == "" || body == "" {
+		return nil, WrapError(ErrInvalid, "subject and body are required")
+	}
+
+	contentFormat := mail.ContentFormat(format)
+	if contentFormat == "" {
+		contentFormat = mail.ContentMarkdown
+	}
+	html, text, err := mail.RenderContent(contentFormat, body)
+	if err != nil {
+		return nil, WrapError(ErrInvalid, err.Error())
+	}
+
+	users, err := s.resolve(&target)
+	if err != nil {
+		return nil, err
+	}
+
+	broadcast := &Broadcast{
+		Id:         uuid.NewString(),
+		Subject:    subject,
+		Format:     contentFormat,
+		Target:     target,
+		Operator:   operator,
+		DryRun:     dryRun,
+		CreatedAt:  time.Now(),
+		Recipients: make([]BroadcastRecipient, 0, len(users)),
+	}
+
+	for _, user := range users {
+		recipient := BroadcastRecipient{Uid: user.Uid, Mail: user.Mail}
+		switch {
+		case user.Mail == "":
+			recipient.State = RecipientSkipped
+			recipient.Error = "no mail address"
+		case dryRun:
+			recipient.State = RecipientDryRun
+		default:
+			recipient.State = string(outbox.MessagePending)
+		}
+		broadcast.Recipients = append(broadcast.Recipients, recipient)
+	}
+
+	if dryRun {
+		summarize(broadcast)
+		return broadcast, nil
+	}
+
+	// 先保存记录，投递结果才能写回
+	if err := s.broadcasts.Create(broadcast.Id, *broadcast); err != nil {
+		return nil, err
+	}
+	enqueued := make(map[string]BroadcastRecipient, len(users))
+	for _, user := range users {
+		if user.Mail == "" {
+			continue
+		}
+		msg, err := s.outbox.EnqueueBulk(broadcast.Id+"/"+user.Uid, user.Mail, mail.TemplateBroadcast, user.PreferredLanguage, mail.Broadcast{
+			Account: mailAccount(user),
+			Subject: subject,
+			Html:    html,
+			Text:    text,
+		})
+		if err != nil {
+			enqueued[user.Uid] = BroadcastRecipient{State: RecipientSkipped, Error: err.Error()}
+		} else {
+			enqueued[user.Uid] = BroadcastRecipient{MessageId: msg.Id}
+		}
+	}
+	// 已经投递完成的收件人由 track 写入了结果，这里只补上邮件 Id
+	err = s.broadcasts.Update(broadcast.Id, func(b *Broadcast) error {
+		for i := range b.Recipients {
+			recipient := &b.Recipients[i]
+			result, ok := enqueued[recipient.Uid]
+			switch {
+			case !ok:
+			case result.State == RecipientSkipped:
+				recipient.State, recipient.Error = result.State, result.Error
+			case recipient.MessageId == "":
+				recipient.MessageId = result.MessageId
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(broadcast.Id)
+}
+
+// summarize 统计每个收件人的投递状态
+func summarize(broadcast *Broadcast) {
+	summary := BroadcastSummary{Total: len(broadcast.Recipients)}
+	for _, recipient := range broadcast.Recipients {
+		switch recipient.State {
+		case string(outbox.MessagePending):
+			summary.Pending++
+		case string(outbox.MessageSent):
+			summary.Sent++
+		case string(outbox.MessageDead):
+			summary.Dead++
+		case RecipientSkipped:
+			summary.Skipped++
+		}
+	}
+	broadcast.Summary = summary
+}
+
+// List 按创建时间倒序返回群发记录，不包含收件人明细
+func (s *ServiceBroadcast) List() ([]Broadcast, error) {
+	broadcasts, err := s.broadcasts.List()
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(broadcasts, func(a, b Broadcast) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	for i := range broadcasts {
+		summarize(&broadcasts[i])
+		broadcasts[i].Recipients = nil
+	}
+	return broadcasts, nil
+}
+
+// Get 返回包含每个收件人投递状态的群发报告
+func (s *ServiceBroadcast) Get(id string) (*Broadcast, error) {
+	broadcast, ok, err := s.broadcasts.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, WrapError(ErrNotFound, fmt.Sprintf("broadcast %s not found", id))
+	}
+	summarize(&broadcast)
+	return &broadcast, nil
+}
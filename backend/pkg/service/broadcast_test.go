@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/outbox"
+	"asynclab.club/asynx/backend/pkg/security"
+)
+
+func newTestBroadcast(env *testEnv) *ServiceBroadcast {
+	return NewServiceBroadcast(env.manager.store, env.outbox)
+}
+
+func recipientUids(broadcast *Broadcast) []string {
+	uids := make([]string, 0, len(broadcast.Recipients))
+	for _, recipient := range broadcast.Recipients {
+		uids = append(uids, recipient.Uid)
+	}
+	return uids
+}
+
+func bulkMessages(o *outbox.Outbox) int {
+	n := 0
+	for _, msg := range o.List("") {
+		if msg.Bulk {
+			n++
+		}
+	}
+	return n
+}
+
+func TestBroadcastRequiresExactlyOneTarget(t *testing.T) {
+	s := newTestBroadcast(newTestEnv(t))
+	for _, target := range []BroadcastTarget{
+		{},
+		{Category: "member", Role: "default"},
+		{Role: "default", Group: "lab-ops"},
+	} {
+		if _, err := s.Send("admin", "通知", "markdown", "正文", target, true); !errors.Is(err, ErrInvalid) {
+			t.Errorf("target %+v: got %v, want ErrInvalid", target, err)
+		}
+	}
+}
+
+func TestBroadcastResolvesTargets(t *testing.T) {
+	env := newTestEnv(t)
+	registerMember(t, env.manager, "2024000001", "admin")
+	registerMember(t, env.manager, "2024000002", "default")
+	if err := env.manager.Register("2024000003", "王", "五", "ww@example.org", security.OuUserExternal.String(), "default", "zh", nil); err != nil {
+		t.Fatal(err)
+	}
+	groupDn := fmt.Sprintf("cn=lab-ops,ou=%s,ou=groups,dc=asynclab,dc=club", security.OuGroupAdditional)
+	if err := env.directory.Add(groupDn, config.GroupObjectClasses, map[string][]string{
+		"gidNumber": {"30001"},
+		"memberUid": {"2024000003", "2024000001"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestBroadcast(env)
+
+	tests := []struct {
+		name   string
+		target BroadcastTarget
+		want   []string
+	}{
+		{"role", BroadcastTarget{Role: "default"}, []string{"2024000002", "2024000003"}},
+		{"category", BroadcastTarget{Category: "external"}, []string{"2024000003"}},
+		{"group", BroadcastTarget{Group: "lab-ops"}, []string{"2024000001", "2024000003"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broadcast, err := s.Send("admin", "通知", "markdown", "正文", tt.target, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := recipientUids(broadcast); !slices.Equal(got, tt.want) {
+				t.Errorf("got recipients %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := s.Send("admin", "通知", "markdown", "正文", BroadcastTarget{Group: "no-such-group"}, true); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown group: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestBroadcastSkipsInactiveAccounts(t *testing.T) {
+	env := newTestEnv(t)
+	for _, uid := range []string{"2024000001", "2024000002", "2020000001"} {
+		registerMember(t, env.manager, uid, "default")
+	}
+	if err := env.manager.Deactivate("2024000002"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.manager.ModifyCategory("2020000001", security.OuUserAlumni.String()); err != nil {
+		t.Fatal(err)
+	}
+	guard := &security.GuardResult{Uid: "admin", Role: security.RoleAdmin}
+	if _, err := newTestServiceAccounts(env).Create(guard, "svc-ci", &ServiceAccountSpec{DisplayName: "CI", Owner: "2024000001", Scopes: []string{"users:read"}, Role: "default"}); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestBroadcast(env)
+
+	broadcast, err := s.Send("admin", "通知", "markdown", "正文", BroadcastTarget{Role: "default"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := recipientUids(broadcast); !slices.Equal(got, []string{"2024000001"}) {
+		t.Errorf("got recipients %v, want only the active member", got)
+	}
+
+	// 明确以 alumni 类别为目标时发给校友
+	broadcast, err = s.Send("admin", "通知", "markdown", "正文", BroadcastTarget{Category: "alumni"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := recipientUids(broadcast); !slices.Equal(got, []string{"2020000001"}) {
+		t.Errorf("got recipients %v for the alumni category", got)
+	}
+}
+
+func TestBroadcastDryRunDoesNotPersistOrEnqueue(t *testing.T) {
+	env := newTestEnv(t)
+	registerMember(t, env.manager, "2024000001", "default")
+	s := newTestBroadcast(env)
+
+	broadcast, err := s.Send("admin", "通知", "markdown", "正文", BroadcastTarget{Role: "default"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(broadcast.Recipients) != 1 || broadcast.Recipients[0].State != RecipientDryRun || broadcast.Recipients[0].MessageId != "" {
+		t.Errorf("got recipients %+v, want one dry-run recipient", broadcast.Recipients)
+	}
+	if list, err := s.List(); err != nil || len(list) != 0 {
+		t.Errorf("got %v, %v, want no saved broadcast", list, err)
+	}
+	if _, err := s.Get(broadcast.Id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v for the dry run, want ErrNotFound", err)
+	}
+	if n := bulkMessages(env.outbox); n != 0 {
+		t.Errorf("dry run enqueued %d mails", n)
+	}
+}
+
+func TestBroadcastReportIsSharedAcrossInstances(t *testing.T) {
+	env := newTestEnv(t)
+	for _, uid := range []string{"2024000001", "2024000002", "2024000003"} {
+		registerMember(t, env.manager, uid, "default")
+	}
+	dn := fmt.Sprintf("cn=2024000002,ou=%s,ou=people,dc=asynclab,dc=club", security.OuUserMember)
+	if err := env.directory.ModifyAttributes(dn, nil, map[string][]string{"mail": nil}, nil); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestBroadcast(env)
+
+	broadcast, err := s.Send("admin", "通知", "markdown", "正文", BroadcastTarget{Role: "default"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (BroadcastSummary{Total: 3, Pending: 2, Skipped: 1}); broadcast.Summary != want {
+		t.Errorf("got summary %+v before delivery, want %+v", broadcast.Summary, want)
+	}
+	skipped := broadcast.Recipients[slices.IndexFunc(broadcast.Recipients, func(r BroadcastRecipient) bool { return r.Uid == "2024000002" })]
+	if skipped.State != RecipientSkipped || skipped.Error == "" {
+		t.Errorf("got %+v for the user without mail, want skipped", skipped)
+	}
+	if broadcast.Recipients[0].MessageId == "" {
+		t.Error("pending recipient has no message id")
+	}
+
+	// 投递结果写回共享记录，另一个实例不需要访问本实例的发件箱
+	env.outbox.Flush(context.Background())
+	replicaOutbox, err := outbox.NewOutbox(&config.ConfigOutbox{}, "", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replica := NewServiceBroadcast(env.manager.store, replicaOutbox)
+	report, err := replica.Get(broadcast.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (BroadcastSummary{Total: 3, Sent: 2, Skipped: 1}); report.Summary != want {
+		t.Errorf("got summary %+v after delivery, want %+v", report.Summary, want)
+	}
+	if list, err := replica.List(); err != nil || len(list) != 1 || list[0].Recipients != nil || list[0].Summary.Sent != 2 {
+		t.Errorf("got %+v, %v from List", list, err)
+	}
+	if len(env.deliveredMails("2024000001@example.org")) != 2 {
+		t.Error("broadcast was not delivered next to the welcome mail")
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"asynclab.club/asynx/backend/pkg/cache"
+	"asynclab.club/asynx/backend/pkg/event"
+	"asynclab.club/asynx/backend/pkg/repository"
+)
+
+type CacheStatus struct {
+	Enabled bool          `json:"enabled"`
+	TTL     string        `json:"ttl,omitempty"`
+	Caches  []cache.Stats `json:"caches"`
+}
+
+type ServiceCache struct {
+	store *repository.StoreCached
+	ttl   string
+}
+
+// NewServiceCache 创建缓存管理服务，store 为 nil 表示未启用缓存。
+// 目录监听发现的外部修改和其他实例的写入会清空缓存，本实例的写入已在仓储层清空
+func NewServiceCache(store *repository.StoreCached, ttl string, bus *event.Bus) *ServiceCache {
+	s := &ServiceCache{store: store, ttl: ttl}
+	if store != nil {
+		bus.Subscribe(func(e event.Event) {
+			if e.Source == event.SourceDirectory || e.Source == event.SourceReplica {
+				store.Flush()
+			}
+		})
+	}
+	return s
+}
+
+func (s *ServiceCache) Status() *CacheStatus {
+	if s.store == nil {
+		return &CacheStatus{Caches: []cache.Stats{}}
+	}
+	return &CacheStatus{Enabled: true, TTL: s.ttl, Caches: s.store.Stats()}
+}
+
+func (s *ServiceCache) Flush() {
+	if s.store != nil {
+		s.store.Flush()
+	}
+}
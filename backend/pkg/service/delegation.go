@@ -0,0 +1,92 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/persist"
+	"asynclab.club/asynx/backend/pkg/security"
+	"github.com/sirupsen/logrus"
+)
+
+// OuAdmin 是被委派管理部分账号类型的用户
+type OuAdmin struct {
+	Uid       string            `json:"uid"`
+	Ous       []security.OuUser `json:"ous"` // 可以管理的账号类型
+	GrantedBy string            `json:"grantedBy"`
+	CreatedAt time.Time         `json:"createdAt"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+}
+
+// ServiceDelegation 保存账号类型的委派管理员。委派用于授权，保存在共享存储中，
+// 在任一实例上撤销后所有实例立即生效
+type ServiceDelegation struct {
+	admins *persist.SharedCollection[OuAdmin]
+}
+
+func NewServiceDelegation(shared persist.SharedBackend) *ServiceDelegation {
+	return &ServiceDelegation{admins: persist.NewSharedCollection[OuAdmin](shared, "ou-admins")}
+}
+
+func (s *ServiceDelegation) List() ([]OuAdmin, error) {
+	admins, err := s.admins.List()
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(admins, func(a, b OuAdmin) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return admins, nil
+}
+
+// ManagedOus 返回 uid 被委派管理的账号类型
+func (s *ServiceDelegation) ManagedOus(uid string) ([]security.OuUser, error) {
+	admin, _, err := s.admins.Get(uid)
+	if err != nil {
+		return nil, err
+	}
+	return admin.Ous, nil
+}
+
+// Manages 检查 uid 是否被委派管理 ou，读取失败时返回错误，调用方应拒绝操作
+func (s *ServiceDelegation) Manages(uid string, ou security.OuUser) (bool, error) {
+	ous, err := s.ManagedOus(uid)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(ous, ou), nil
+}
+
+func (s *ServiceDelegation) set(uid string, ous []security.OuUser, operator string) (OuAdmin, error) {
+	now := time.Now()
+	admin := OuAdmin{Uid: uid, Ous: ous, GrantedBy: operator, CreatedAt: now, UpdatedAt: now}
+	err := s.admins.Update(uid, func(a *OuAdmin) error {
+		a.Ous = ous
+		a.GrantedBy = operator
+		a.UpdatedAt = now
+		admin = *a
+		return nil
+	})
+	if errors.Is(err, persist.ErrNotFound) {
+		// 另一个实例可能同时创建了委派，此时以后写入的为准
+		return admin, s.admins.Put(uid, admin)
+	}
+	return admin, err
+}
+
+func (s *ServiceDelegation) remove(uid string) error {
+	_, ok, err := s.admins.Get(uid)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return WrapError(ErrNotFound, "ou admin "+uid+" not found")
+	}
+	return s.admins.Delete(uid)
+}
+
+// Forget 删除用户被委派的管理权限，用于删除账号
+func (s *ServiceDelegation) Forget(uid string) {
+	if err := s.admins.Delete(uid); err != nil {
+		logrus.Warnf("Failed to remove ou admin %s: %v", uid, err)
+	}
+}
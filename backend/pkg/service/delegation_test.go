@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"asynclab.club/asynx/backend/pkg/event"
+	"asynclab.club/asynx/backend/pkg/security"
+)
+
+func TestOuAdminManagesOnlyDelegatedCategories(t *testing.T) {
+	manager := newTestEnv(t).manager
+	registerMember(t, manager, "2024000001", "default")
+	registerMember(t, manager, "2024000002", "default")
+	registerMember(t, manager, "2024000003", "admin")
+	if err := manager.Register("2024100001", "李", "四", "ext@example.org", security.OuUserExternal.String(), "restricted", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.SetOuAdmin("admin", "2024000001", []string{security.OuUserExternal.String()}); err != nil {
+		t.Fatal(err)
+	}
+	guard := &security.GuardResult{Uid: "2024000001", Role: security.RoleDefault}
+
+	if err := manager.AuthorizeManage(guard, "2024100001"); err != nil {
+		t.Errorf("manage user in delegated category: %v", err)
+	}
+	if err := manager.AuthorizeManage(guard, "2024000002"); !errors.Is(err, ErrDenied) {
+		t.Errorf("manage user outside delegated category: got %v, want ErrDenied", err)
+	}
+	if err := manager.AuthorizeRegister(guard, security.OuUserExternal.String(), "default"); err != nil {
+		t.Errorf("register in delegated category: %v", err)
+	}
+	if err := manager.AuthorizeRegister(guard, security.OuUserExternal.String(), "admin"); !errors.Is(err, ErrDenied) {
+		t.Errorf("register with a role above the delegate: got %v, want ErrDenied", err)
+	}
+	if err := manager.AuthorizeRegister(guard, security.OuUserMember.String(), "default"); !errors.Is(err, ErrDenied) {
+		t.Errorf("register outside delegated category: got %v, want ErrDenied", err)
+	}
+	if err := manager.AuthorizeRoleGrant(guard, "2024100001", "default"); err != nil {
+		t.Errorf("grant role in delegated category: %v", err)
+	}
+	if err := manager.AuthorizeRoleGrant(guard, "2024000003", "restricted"); !errors.Is(err, ErrDenied) {
+		t.Errorf("grant role outside delegated category: got %v, want ErrDenied", err)
+	}
+
+	// 委派管理员只能看到被委派类型和自己所在类型的用户
+	if _, err := manager.GetUserWithGuard(guard, "2024100001"); err != nil {
+		t.Errorf("read user in delegated category: %v", err)
+	}
+	if _, err := manager.GetUserWithGuard(&security.GuardResult{Uid: "2024000002", Role: security.RoleRestricted}, "2024100001"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("read user without delegation: got %v, want ErrNotFound", err)
+	}
+
+	// 没有 users.write 和 users.read 时，只有委派管理员能进入注册和列表接口
+	restricted := &security.GuardResult{Uid: "2024000002", Role: security.RoleRestricted}
+	if err := manager.AuthorizeDelegated(guard); err != nil {
+		t.Errorf("delegate: %v", err)
+	}
+	if err := manager.AuthorizeDelegated(restricted); !errors.Is(err, ErrDenied) {
+		t.Errorf("user without delegation: got %v, want ErrDenied", err)
+	}
+	if err := manager.AuthorizeList(guard); err != nil {
+		t.Errorf("list as delegate: %v", err)
+	}
+	if err := manager.AuthorizeList(restricted); !errors.Is(err, ErrDenied) {
+		t.Errorf("list without users.read.ou or delegation: got %v, want ErrDenied", err)
+	}
+}
+
+func TestOuAdminRevokeAppliesToAllInstances(t *testing.T) {
+	env := newTestEnv(t)
+	registerMember(t, env.manager, "2024000001", "default")
+	if err := env.manager.Register("2024100001", "李", "四", "ext@example.org", security.OuUserExternal.String(), "default", "", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// 两个实例连接同一个目录，各自创建委派服务
+	replicaA := env.manager
+	replicaB := NewServiceManager(env.manager.store, env.manager.coordinator, env.manager.notification, NewServiceDelegation(env.manager.store.Shared()), event.NewBus())
+	guard := &security.GuardResult{Uid: "2024000001", Role: security.RoleDefault}
+
+	if _, err := replicaA.SetOuAdmin("admin", "2024000001", []string{security.OuUserExternal.String()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := replicaB.AuthorizeManage(guard, "2024100001"); err != nil {
+		t.Fatalf("replica B does not see the delegation made on replica A: %v", err)
+	}
+
+	if err := replicaB.RemoveOuAdmin("2024000001"); err != nil {
+		t.Fatal(err)
+	}
+	if err := replicaA.AuthorizeManage(guard, "2024100001"); !errors.Is(err, ErrDenied) {
+		t.Errorf("replica A after revoke on replica B: got %v, want ErrDenied", err)
+	}
+	if admins, err := replicaA.ListOuAdmins(); err != nil || len(admins) != 0 {
+		t.Errorf("replica A still lists %+v, %v", admins, err)
+	}
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"fmt"
+
+	"asynclab.club/asynx/backend/pkg/client"
+)
+
+// ServiceDev 提供仅在开发模式下可用的调试功能
+type ServiceDev struct {
+	mailSink *client.MemoryMailTransport
+}
+
+func NewServiceDev(mailSink *client.MemoryMailTransport) *ServiceDev {
+	return &ServiceDev{mailSink: mailSink}
+}
+
+func (s *ServiceDev) ListMails(to string) []client.Mail {
+	return s.mailSink.List(to)
+}
+
+func (s *ServiceDev) GetMail(id string) (*client.Mail, error) {
+	mail, ok := s.mailSink.Get(id)
+	if !ok {
+		return nil, WrapError(ErrNotFound, fmt.Sprintf("mail %s not found", id))
+	}
+	return &mail, nil
+}
+
+func (s *ServiceDev) ClearMails() {
+	s.mailSink.Clear()
+}
@@ -9,9 +9,12 @@ import (
 )
 
 var (
-	ErrNotFound = errors.New("not found")
-	ErrExists   = errors.New("already exists")
-	ErrInvalid  = errors.New("invalid objet")
+	ErrNotFound        = errors.New("not found")
+	ErrExists          = errors.New("already exists")
+	ErrInvalid         = errors.New("invalid objet")
+	ErrDenied          = errors.New("permission denied")
+	ErrConflict        = errors.New("conflict")
+	ErrTooManyRequests = errors.New("too many requests")
 )
 
 type ServiceError struct {
@@ -35,6 +38,12 @@ func MapErrorToHttp(err error) *gggin.HttpError {
 		return gggin.NewHttpError(http.StatusConflict, fmt.Sprintf("对象已存在: %s", err.Error()))
 	case errors.Is(err, ErrInvalid):
 		return gggin.NewHttpError(http.StatusBadRequest, fmt.Sprintf("无效的对象: %s", err.Error()))
+	case errors.Is(err, ErrDenied):
+		return gggin.NewHttpError(http.StatusForbidden, fmt.Sprintf("权限不足: %s", err.Error()))
+	case errors.Is(err, ErrConflict):
+		return gggin.NewHttpError(http.StatusConflict, fmt.Sprintf("操作冲突: %s", err.Error()))
+	case errors.Is(err, ErrTooManyRequests):
+		return gggin.NewHttpError(http.StatusTooManyRequests, fmt.Sprintf("请求过于频繁: %s", err.Error()))
 	default:
 		return gggin.NewHttpError(http.StatusInternalServerError, err.Error())
 	}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+
+	"asynclab.club/asynx/backend/pkg/event"
+)
+
+type ServiceEvent struct {
+	stream *event.Stream
+}
+
+func NewServiceEvent(stream *event.Stream) *ServiceEvent {
+	return &ServiceEvent{stream: stream}
+}
+
+// Subscription 是一个事件流连接，Events 关闭表示连接被服务端断开
+type Subscription struct {
+	Backlog  []event.Event
+	Events   <-chan event.Event
+	Cancel   func()
+	Resumed  bool
+	patterns []string
+}
+
+func (s *Subscription) Accepts(t event.Type) bool {
+	return len(s.patterns) == 0 || slices.ContainsFunc(s.patterns, t.Match)
+}
+
+// Subscribe 订阅 lastId 之后的事件，patterns 为空时订阅全部事件
+func (s *ServiceEvent) Subscribe(lastId string, patterns []string) (*Subscription, error) {
+	for _, pattern := range patterns {
+		if !event.ValidatePattern(pattern) {
+			return nil, WrapError(ErrInvalid, fmt.Sprintf("unknown event: %s", pattern))
+		}
+	}
+
+	backlog, events, cancel, resumed := s.stream.Subscribe(lastId)
+	sub := &Subscription{Events: events, Cancel: cancel, Resumed: resumed, patterns: patterns}
+	for _, e := range backlog {
+		if sub.Accepts(e.Type) {
+			sub.Backlog = append(sub.Backlog, e)
+		}
+	}
+	return sub, nil
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"asynclab.club/asynx/backend/pkg/event"
+)
+
+func TestSubscribeFiltersBacklogByPattern(t *testing.T) {
+	bus := event.NewBus()
+	s := NewServiceEvent(event.NewStream(10, 8, bus))
+	for i, typ := range []event.Type{event.UserCreated, event.GroupMemberAdded, event.UserRoleChanged} {
+		bus.Publish(event.Event{Id: string(rune('a' + i)), Type: typ})
+	}
+
+	sub, err := s.Subscribe("a", []string{"user.*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Cancel()
+	if !sub.Resumed || len(sub.Backlog) != 1 || sub.Backlog[0].Type != event.UserRoleChanged {
+		t.Errorf("got backlog %+v, want only user.role_changed after a", sub.Backlog)
+	}
+	if sub.Accepts(event.GroupMemberRemoved) {
+		t.Error("group event accepted by a user.* subscription")
+	}
+
+	if _, err := s.Subscribe("", []string{"users.*"}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("got %v for an unknown pattern, want ErrInvalid", err)
+	}
+}
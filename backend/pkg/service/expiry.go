@@ -0,0 +1,104 @@
+package service
+
+import (
+	"strconv"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/entity"
+	"asynclab.club/asynx/backend/pkg/event"
+	"asynclab.club/asynx/backend/pkg/persist"
+	"github.com/sirupsen/logrus"
+)
+
+// 账号到期日保存在 shadowExpire 中，单位是自 1970-01-01 起的天数。
+// PAM 从该日零点（UTC）起拒绝登录，asynx 与之保持一致，因此到期时间只精确到天
+const shadowDay = 24 * time.Hour
+
+// AccountExpiresAt 返回账号失效的时间，没有到期日时返回 nil
+func AccountExpiresAt(user *entity.User) *time.Time {
+	if user.ShadowExpire == "" {
+		return nil
+	}
+	days, err := strconv.ParseInt(user.ShadowExpire, 10, 64)
+	// 按 shadow(5) 的约定，-1 表示不过期
+	if err != nil || days < 0 {
+		return nil
+	}
+	expiresAt := time.Unix(days*int64(shadowDay/time.Second), 0).UTC()
+	return &expiresAt
+}
+
+func IsAccountExpired(user *entity.User, now time.Time) bool {
+	expiresAt := AccountExpiresAt(user)
+	return expiresAt != nil && !now.Before(*expiresAt)
+}
+
+// toShadowExpire 把到期时间向上取整到天，账号不会早于 expiresAt 失效
+func toShadowExpire(expiresAt time.Time) string {
+	day := int64(shadowDay / time.Second)
+	return strconv.FormatInt((expiresAt.Unix()+day-1)/day, 10)
+}
+
+// expiryReminder 记录已经提醒过的到期日，到期日被修改后会重新提醒
+type expiryReminder struct {
+	ShadowExpire string    `json:"shadowExpire"`
+	NotifiedAt   time.Time `json:"notifiedAt"`
+}
+
+// ServiceAccountExpiry 在账号到期前发送提醒
+type ServiceAccountExpiry struct {
+	cfg       *config.ConfigAccountExpiry
+	manager   *ServiceManager
+	reminders *persist.SharedCollection[expiryReminder]
+}
+
+// NewServiceAccountExpiry 创建到期提醒服务，提醒记录保存在共享存储中，主实例切换后不会重复提醒
+func NewServiceAccountExpiry(cfg *config.ConfigAccountExpiry, manager *ServiceManager, bus *event.Bus) *ServiceAccountExpiry {
+	s := &ServiceAccountExpiry{
+		cfg:       cfg,
+		manager:   manager,
+		reminders: persist.NewSharedCollection[expiryReminder](manager.store.Shared(), "expiry-reminders"),
+	}
+	bus.Subscribe(func(e event.Event) {
+		if e.Type != event.UserDeleted {
+			return
+		}
+		if err := s.reminders.Delete(e.Subject); err != nil {
+			logrus.Errorf("Failed to remove expiry reminder of %s: %v", e.Subject, err)
+		}
+	})
+	return s
+}
+
+// Remind 向即将到期的账号发送提醒，每个到期日只提醒一次
+func (s *ServiceAccountExpiry) Remind(now time.Time) error {
+	if s.cfg.NotifyBefore <= 0 {
+		return nil
+	}
+	users, err := s.manager.serviceUser.FindAll()
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		expiresAt := AccountExpiresAt(user)
+		if expiresAt == nil || !now.Before(*expiresAt) || expiresAt.Sub(now) > s.cfg.NotifyBefore {
+			continue
+		}
+		reminder, ok, err := s.reminders.Get(user.Uid)
+		if err != nil {
+			return err
+		}
+		if ok && reminder.ShadowExpire == user.ShadowExpire {
+			continue
+		}
+
+		s.manager.notification.AccountExpiring(user, *expiresAt)
+		if err := s.reminders.Put(user.Uid, expiryReminder{ShadowExpire: user.ShadowExpire, NotifiedAt: now}); err != nil {
+			return err
+		}
+		logrus.Infof("Reminded %s of account expiry at %s", user.Uid, expiresAt.Format(time.DateOnly))
+	}
+	return nil
+}
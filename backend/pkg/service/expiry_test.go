@@ -0,0 +1,68 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/event"
+	"asynclab.club/asynx/backend/pkg/security"
+)
+
+func registerExpiringMember(t *testing.T, manager *ServiceManager, uid string, expiresAt time.Time) {
+	t.Helper()
+	if err := manager.Register(uid, "张", "三", uid+"@example.org", security.OuUserMember.String(), "default", "zh", &expiresAt); err != nil {
+		t.Fatalf("register %s: %v", uid, err)
+	}
+}
+
+func TestSessionOfExpiredAccountIsRejected(t *testing.T) {
+	env := newTestEnv(t)
+	registerExpiringMember(t, env.manager, "2024000001", time.Now().Add(48*time.Hour))
+	if role, err := env.manager.ResolveSession("2024000001"); err != nil || role != security.RoleDefault {
+		t.Fatalf("got role %s, %v before expiry, want default", role, err)
+	}
+
+	// 登录之后账号被停用，已签发的令牌不能再使用
+	if err := env.manager.Deactivate("2024000001"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.manager.ResolveSession("2024000001"); !errors.Is(err, ErrDenied) {
+		t.Errorf("got %v for an expired account, want ErrDenied", err)
+	}
+}
+
+func TestExpiryReminderIsSentOnceAcrossInstances(t *testing.T) {
+	env := newTestEnv(t)
+	expiresAt := time.Now().Add(72 * time.Hour)
+	registerExpiringMember(t, env.manager, "2024000001", expiresAt)
+	welcomed := len(env.deliveredMails("2024000001@example.org"))
+
+	cfg := &config.ConfigAccountExpiry{NotifyBefore: 7 * 24 * time.Hour}
+	replicaA := NewServiceAccountExpiry(cfg, env.manager, event.NewBus())
+	replicaB := NewServiceAccountExpiry(cfg, env.manager, event.NewBus())
+
+	if err := replicaA.Remind(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	// 主实例切换后不会重复提醒
+	if err := replicaB.Remind(time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if mails := env.deliveredMails("2024000001@example.org"); len(mails) != welcomed+1 {
+		t.Fatalf("got %d reminders, want 1", len(mails)-welcomed)
+	}
+
+	// 到期日被修改后重新提醒
+	later := expiresAt.Add(48 * time.Hour)
+	if err := env.manager.ModifyExpiry("2024000001", &later); err != nil {
+		t.Fatal(err)
+	}
+	if err := replicaB.Remind(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if mails := env.deliveredMails("2024000001@example.org"); len(mails) != welcomed+2 {
+		t.Errorf("got %d reminders after the expiry changed, want 2", len(mails)-welcomed)
+	}
+}
@@ -3,20 +3,73 @@ package service
 import (
 	"errors"
 	"fmt"
+	"slices"
 
 	"asynclab.club/asynx/backend/pkg/entity"
 	"asynclab.club/asynx/backend/pkg/repository"
+	"asynclab.club/asynx/backend/pkg/saga"
 	"asynclab.club/asynx/backend/pkg/security"
 	"github.com/sirupsen/logrus"
 )
 
+const OperationSwitchRole = "group.switch-role"
+
+type switchRolePayload struct {
+	Uid  string        `json:"uid"`
+	From security.Role `json:"from"`
+	To   security.Role `json:"to"`
+}
+
 type ServiceGroup struct {
-	repositoryGroup *repository.RepositoryGroup
+	repositoryGroup repository.RepositoryGroup
+	coordinator     *saga.Coordinator
 }
 
-func NewServiceGroup(repo *repository.RepositoryGroup) *ServiceGroup {
-	return &ServiceGroup{
+func NewServiceGroup(repo repository.RepositoryGroup, coordinator *saga.Coordinator) *ServiceGroup {
+	s := &ServiceGroup{
 		repositoryGroup: repo,
+		coordinator:     coordinator,
+	}
+
+	// 先离开旧组再加入新组，中途中断时用户暂时没有角色，重启后继续完成切换
+	saga.Register(coordinator, OperationSwitchRole, saga.RecoverResume,
+		saga.Step[switchRolePayload]{
+			Name:       "leave-old-group",
+			Action:     func(p *switchRolePayload) error { return s.ensureMembership(p.From, p.Uid, false) },
+			Compensate: func(p *switchRolePayload) error { return s.ensureMembership(p.From, p.Uid, true) },
+		},
+		saga.Step[switchRolePayload]{
+			Name:       "join-new-group",
+			Action:     func(p *switchRolePayload) error { return s.ensureMembership(p.To, p.Uid, true) },
+			Compensate: func(p *switchRolePayload) error { return s.ensureMembership(p.To, p.Uid, false) },
+		},
+	)
+
+	return s
+}
+
+// withRepository 返回使用另一个仓储（通常绑定在事务上）的副本
+func (s *ServiceGroup) withRepository(repo repository.RepositoryGroup) *ServiceGroup {
+	return &ServiceGroup{repositoryGroup: repo, coordinator: s.coordinator}
+}
+
+// ensureMembership 幂等地把 uid 加入或移出角色组
+func (s *ServiceGroup) ensureMembership(role security.Role, uid string, member bool) error {
+	group, err := s.FindByOuAndCn(security.OuGroupSupplementary, role.String())
+	if errors.Is(err, ErrNotFound) && !member {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	switch isMember := slices.Contains(group.MemberUid, uid); {
+	case member && !isMember:
+		return s.repositoryGroup.AddMemberUid(group, uid)
+	case !member && isMember:
+		return s.repositoryGroup.RemoveMemberUid(group, uid)
+	default:
+		return nil
 	}
 }
 
@@ -43,6 +96,21 @@ func (s *ServiceGroup) FindAllByOuAndMemberUid(ou security.OuGroup, uid string)
 	return s.repositoryGroup.FindAllByOuAndMemberUid(ou.String(), uid)
 }
 
+// FindCnsByMemberUid 返回用户所在的角色组和附加组的组名
+func (s *ServiceGroup) FindCnsByMemberUid(uid string) ([]string, error) {
+	cns := []string{}
+	for _, ou := range []security.OuGroup{security.OuGroupSupplementary, security.OuGroupAdditional} {
+		groups, err := s.FindAllByOuAndMemberUid(ou, uid)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range groups {
+			cns = append(cns, group.Cn)
+		}
+	}
+	return cns, nil
+}
+
 func (s *ServiceGroup) GetRoleByUid(uid string) (security.Role, error) {
 	groups, err := s.FindAllByOuAndMemberUid(security.OuGroupSupplementary, uid)
 	if err != nil {
@@ -64,14 +132,14 @@ func (s *ServiceGroup) GetRole(user *entity.User) (security.Role, error) {
 }
 
 func (s *ServiceGroup) RevokeRoleByUid(uid string) error {
-	attr := map[string][]string{"memberUid": {uid}}
-
-	roleGroups, err := s.FindAllByOu(security.OuGroupSupplementary)
+	roleGroups, err := s.FindAllByOuAndMemberUid(security.OuGroupSupplementary, uid)
 	if err != nil {
 		return err
 	}
 	for _, group := range roleGroups {
-		return s.repositoryGroup.ModifyAttributes(s.repositoryGroup.BuildDn(group), nil, attr, nil)
+		if err := s.repositoryGroup.RemoveMemberUid(group, uid); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -88,8 +156,6 @@ func (s *ServiceGroup) GrantRoleByUid(uid string, newRole security.Role) error {
 		return nil
 	}
 
-	attr := map[string][]string{"memberUid": {uid}}
-
 	// 如果新角色是匿名（移除所有角色）
 	if newRole == security.RoleAnonymous {
 		if oldRole != security.RoleAnonymous {
@@ -111,34 +177,30 @@ func (s *ServiceGroup) GrantRoleByUid(uid string, newRole security.Role) error {
 
 	// 如果用户之前没有角色（直接添加）
 	if oldRole == security.RoleAnonymous {
-		return s.repositoryGroup.ModifyAttributes(s.repositoryGroup.BuildDn(newGroup), attr, nil, nil) // 添加用户
+		return s.repositoryGroup.AddMemberUid(newGroup, uid) // 添加用户
 	}
 
 	// 如果是角色切换：先从旧组移除，再添加到新组
-	oldGroup, err := s.FindByOuAndCn(security.OuGroupSupplementary, oldRole.String())
-	oldNotFound := errors.Is(err, ErrNotFound)
-	if err != nil && !oldNotFound {
-		return err
-	}
-
-	if !oldNotFound {
-		if err := s.repositoryGroup.ModifyAttributes(s.repositoryGroup.BuildDn(oldGroup), nil, attr, nil); err != nil {
-			return err
-		}
-	}
-	if err := s.repositoryGroup.ModifyAttributes(s.repositoryGroup.BuildDn(newGroup), attr, nil, nil); err != nil {
-		// 回滚
-		if !oldNotFound {
-			if err = s.repositoryGroup.ModifyAttributes(s.repositoryGroup.BuildDn(oldGroup), attr, nil, nil); err != nil {
-				logrus.Warningf("Failed to rollback group modification when grant role: %v", err)
-			}
-		}
-		return err
-	}
-
-	return nil
+	return saga.Execute(s.coordinator, OperationSwitchRole, &switchRolePayload{Uid: uid, From: oldRole, To: newRole})
 }
 
 func (s *ServiceGroup) GrantRole(user *entity.User, role security.Role) error {
 	return s.GrantRoleByUid(user.Uid, role)
 }
+
+// SetMembership 幂等地把 uid 加入或移出指定组，返回成员关系是否发生变化
+func (s *ServiceGroup) SetMembership(ou security.OuGroup, cn string, uid string, member bool) (bool, error) {
+	group, err := s.FindByOuAndCn(ou, cn)
+	if err != nil {
+		return false, err
+	}
+
+	switch isMember := slices.Contains(group.MemberUid, uid); {
+	case member && !isMember:
+		return true, s.repositoryGroup.AddMemberUid(group, uid)
+	case !member && isMember:
+		return true, s.repositoryGroup.RemoveMemberUid(group, uid)
+	default:
+		return false, nil
+	}
+}
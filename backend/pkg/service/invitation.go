@@ -0,0 +1,493 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/persist"
+	"asynclab.club/asynx/backend/pkg/security"
+	"github.com/sirupsen/logrus"
+)
+
+type InvitationStatus string
+
+const (
+	InvitationActive    InvitationStatus = "active"
+	InvitationExpired   InvitationStatus = "expired"
+	InvitationExhausted InvitationStatus = "exhausted" // 使用次数已用完
+	InvitationRevoked   InvitationStatus = "revoked"
+)
+
+// Invitation 是绑定账号类型和角色的邀请链接，只保存令牌的摘要
+type Invitation struct {
+	Id         string           `json:"id"`
+	Category   string           `json:"category"`
+	Role       string           `json:"role"`
+	Domain     string           `json:"domain,omitempty"` // 只允许该域名下的邮箱使用
+	Mail       string           `json:"mail,omitempty"`   // 只允许该邮箱使用
+	Note       string           `json:"note,omitempty"`
+	MaxUses    int              `json:"maxUses"`
+	Uses       int              `json:"uses"`
+	UsedBy     []string         `json:"usedBy"`
+	Status     InvitationStatus `json:"status"`
+	Link       string           `json:"link,omitempty"` // 邀请链接，只在创建时返回
+	SecretHash string           `json:"secretHash,omitempty"`
+	CreatedBy  string           `json:"createdBy"`
+	CreatedAt  time.Time        `json:"createdAt"`
+	ExpiresAt  time.Time        `json:"expiresAt"`
+	RevokedBy  string           `json:"revokedBy,omitempty"`
+	RevokedAt  *time.Time       `json:"revokedAt,omitempty"`
+}
+
+type InvitationSpec struct {
+	Category  string    `json:"category" binding:"required"`
+	Role      string    `json:"role" binding:"required"`
+	Domain    string    `json:"domain"` // 与 Mail 至多指定一个
+	Mail      string    `json:"mail"`   // 指定时 MaxUses 只能为 1
+	Note      string    `json:"note"`
+	MaxUses   int       `json:"maxUses" binding:"required"`
+	ExpiresAt time.Time `json:"expiresAt" binding:"required"` // RFC 3339 格式
+}
+
+// InvitationPrompt 是受邀人打开链接时看到的邀请内容。限定域名的邀请在邮箱验证后，Mail 和姓名为验证时填写的内容
+type InvitationPrompt struct {
+	Category  string    `json:"category"`
+	Role      string    `json:"role"`
+	Domain    string    `json:"domain,omitempty"`
+	Mail      string    `json:"mail,omitempty"`
+	SurName   string    `json:"surName,omitempty"`
+	GivenName string    `json:"givenName,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type InvitationAcceptSpec struct {
+	Token        string `json:"token" binding:"required"`
+	Verification string `json:"verification"` // 限定域名的邀请需要提供验证邮件链接中的 verification
+	Username     string `json:"username" binding:"required"`
+	SurName      string `json:"surName" binding:"required"`
+	GivenName    string `json:"givenName" binding:"required"`
+	Mail         string `json:"mail" binding:"required"`
+	Password     string `json:"password" binding:"required"`
+	Language     string `json:"language"`
+}
+
+type InvitationVerifySpec struct {
+	Token     string `json:"token" binding:"required"`
+	SurName   string `json:"surName" binding:"required"`
+	GivenName string `json:"givenName" binding:"required"`
+	Mail      string `json:"mail" binding:"required"`
+	Language  string `json:"language"`
+}
+
+// InvitationVerification 证明受邀人能收到限定域名中某个邮箱的邮件，只保存令牌的摘要
+type InvitationVerification struct {
+	Id           string    `json:"id"`
+	InvitationId string    `json:"invitationId"`
+	Mail         string    `json:"mail"`
+	SurName      string    `json:"surName"`
+	GivenName    string    `json:"givenName"`
+	SecretHash   string    `json:"secretHash"`
+	CreatedAt    time.Time `json:"createdAt"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+// 同一邀请向同一邮箱发送验证邮件的最小间隔
+const invitationVerifyInterval = time.Minute
+
+// ServiceInvitation 管理邀请链接，受邀人使用链接自行设置密码完成注册。
+// 限定域名的邀请可能被转发给域名之外的人，受邀人需要先验证邮箱
+type ServiceInvitation struct {
+	cfg           *config.ConfigInvitation
+	siteUrl       string
+	manager       *ServiceManager
+	notification  *ServiceNotification
+	invitations   *persist.SharedCollection[Invitation]
+	verifications *persist.SharedCollection[InvitationVerification]
+
+	mu sync.Mutex // 串行化本实例上的验证邮件发送，避免重复发送
+}
+
+// NewServiceInvitation 创建邀请服务，邀请和邮箱验证保存在共享存储中，邀请链接可以在任一实例上使用
+func NewServiceInvitation(cfg *config.ConfigInvitation, siteUrl string, manager *ServiceManager, notification *ServiceNotification) *ServiceInvitation {
+	shared := manager.store.Shared()
+	return &ServiceInvitation{
+		cfg:           cfg,
+		siteUrl:       siteUrl,
+		manager:       manager,
+		notification:  notification,
+		invitations:   persist.NewSharedCollection[Invitation](shared, "invitations"),
+		verifications: persist.NewSharedCollection[InvitationVerification](shared, "invitation-verifications"),
+	}
+}
+
+func (s *ServiceInvitation) status(invitation Invitation, now time.Time) InvitationStatus {
+	switch {
+	case invitation.RevokedAt != nil:
+		return InvitationRevoked
+	case !now.Before(invitation.ExpiresAt):
+		return InvitationExpired
+	case invitation.Uses >= invitation.MaxUses:
+		return InvitationExhausted
+	default:
+		return InvitationActive
+	}
+}
+
+func (s *ServiceInvitation) withoutSecret(invitation Invitation, now time.Time) *Invitation {
+	invitation.SecretHash = ""
+	invitation.Status = s.status(invitation, now)
+	return &invitation
+}
+
+// Create 创建邀请，邀请链接只在返回值中出现一次
+func (s *ServiceInvitation) Create(operator string, spec *InvitationSpec) (*Invitation, error) {
+	ou, err := security.GetOuUserFromName(spec.Category)
+	if err != nil {
+		return nil, WrapError(ErrInvalid, err.Error())
+	}
+	if ou == security.OuUserSystem {
+		return nil, WrapError(ErrInvalid, fmt.Sprintf("cannot invite users into %s", ou))
+	}
+	role, err := security.GetRoleFromName(spec.Role)
+	if err != nil {
+		return nil, WrapError(ErrInvalid, err.Error())
+	}
+
+	domain := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(spec.Domain), "@"))
+	if domain != "" && spec.Mail != "" {
+		return nil, WrapError(ErrInvalid, "domain and mail cannot both be specified")
+	}
+	if domain != "" {
+		if err := security.ValidateEmailFormat("user@" + domain); err != nil {
+			return nil, WrapError(ErrInvalid, fmt.Sprintf("invalid domain: %s", spec.Domain))
+		}
+	}
+	if spec.Mail != "" {
+		if err := security.ValidateEmailFormat(spec.Mail); err != nil {
+			return nil, WrapError(ErrInvalid, err.Error())
+		}
+		if spec.MaxUses != 1 {
+			return nil, WrapError(ErrInvalid, "an invitation for a single address can only be used once")
+		}
+	}
+	if spec.MaxUses < 1 || spec.MaxUses > s.cfg.MaxUses {
+		return nil, WrapError(ErrInvalid, fmt.Sprintf("maxUses must be between 1 and %d", s.cfg.MaxUses))
+	}
+
+	now := time.Now()
+	if !spec.ExpiresAt.After(now) {
+		return nil, WrapError(ErrInvalid, "expiresAt must be in the future")
+	}
+	if spec.ExpiresAt.After(now.Add(s.cfg.MaxTTL)) {
+		return nil, WrapError(ErrInvalid, fmt.Sprintf("invitation lifetime cannot exceed %s", s.cfg.MaxTTL))
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	invitation := Invitation{
+		Id:         hex.EncodeToString(id),
+		Category:   ou.String(),
+		Role:       role.String(),
+		Domain:     domain,
+		Mail:       spec.Mail,
+		Note:       strings.TrimSpace(spec.Note),
+		MaxUses:    spec.MaxUses,
+		UsedBy:     []string{},
+		SecretHash: hashTokenSecret(secret),
+		CreatedBy:  operator,
+		CreatedAt:  now,
+		ExpiresAt:  spec.ExpiresAt,
+	}
+	if err := s.invitations.Create(invitation.Id, invitation); err != nil {
+		return nil, err
+	}
+	result := s.withoutSecret(invitation, now)
+	result.Link = strings.TrimSuffix(s.siteUrl, "/") + "/invite?token=" + url.QueryEscape(invitation.Id+"."+secret)
+	return result, nil
+}
+
+// List 返回全部邀请，按创建时间排序
+func (s *ServiceInvitation) List() ([]*Invitation, error) {
+	invitations, err := s.invitations.List()
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(invitations, func(a, b Invitation) int { return a.CreatedAt.Compare(b.CreatedAt) })
+
+	now := time.Now()
+	result := make([]*Invitation, 0, len(invitations))
+	for _, invitation := range invitations {
+		result = append(result, s.withoutSecret(invitation, now))
+	}
+	return result, nil
+}
+
+// Revoke 撤销邀请，已使用邀请注册的账号不受影响
+func (s *ServiceInvitation) Revoke(operator string, id string) error {
+	now := time.Now()
+	err := s.invitations.Update(id, func(invitation *Invitation) error {
+		if invitation.RevokedAt == nil {
+			invitation.RevokedAt = &now
+			invitation.RevokedBy = operator
+		}
+		return nil
+	})
+	if errors.Is(err, persist.ErrNotFound) {
+		return WrapError(ErrNotFound, fmt.Sprintf("invitation %s not found", id))
+	}
+	return err
+}
+
+// find 按令牌查找仍可使用的邀请
+func (s *ServiceInvitation) find(token string, now time.Time) (Invitation, error) {
+	id, secret, _ := strings.Cut(token, ".")
+	invitation, ok, err := s.invitations.Get(id)
+	if err != nil {
+		return invitation, err
+	}
+	if !ok || subtle.ConstantTimeCompare([]byte(invitation.SecretHash), []byte(hashTokenSecret(secret))) != 1 {
+		return invitation, WrapError(ErrInvalid, "invalid invitation")
+	}
+	if status := s.status(invitation, now); status != InvitationActive {
+		return invitation, WrapError(ErrInvalid, fmt.Sprintf("invitation is %s", status))
+	}
+	return invitation, nil
+}
+
+// findVerification 按 verification 查找邀请的邮箱验证
+func (s *ServiceInvitation) findVerification(invitation Invitation, verification string, now time.Time) (InvitationVerification, error) {
+	id, secret, _ := strings.Cut(verification, ".")
+	v, ok, err := s.verifications.Get(id)
+	if err != nil {
+		return v, err
+	}
+	if !ok || v.InvitationId != invitation.Id || subtle.ConstantTimeCompare([]byte(v.SecretHash), []byte(hashTokenSecret(secret))) != 1 {
+		return v, WrapError(ErrInvalid, "invalid mail verification")
+	}
+	if !now.Before(v.ExpiresAt) {
+		return v, WrapError(ErrInvalid, "mail verification has expired, please request a new one")
+	}
+	return v, nil
+}
+
+// checkInvitationMail 检查邮箱是否满足邀请的限制
+func checkInvitationMail(invitation Invitation, mail string) error {
+	if err := security.ValidateEmailFormat(mail); err != nil {
+		return WrapError(ErrInvalid, err.Error())
+	}
+	if invitation.Mail != "" && !strings.EqualFold(mail, invitation.Mail) {
+		return WrapError(ErrInvalid, "this invitation is for a different mail address")
+	}
+	if invitation.Domain != "" && !strings.HasSuffix(strings.ToLower(mail), "@"+invitation.Domain) {
+		return WrapError(ErrInvalid, fmt.Sprintf("this invitation is only for mail addresses under %s", invitation.Domain))
+	}
+	return nil
+}
+
+// Lookup 返回邀请的内容，供受邀人在注册前确认。verification 不为空时一并返回已验证的邮箱
+func (s *ServiceInvitation) Lookup(token string, verification string) (*InvitationPrompt, error) {
+	now := time.Now()
+	invitation, err := s.find(token, now)
+	if err != nil {
+		return nil, err
+	}
+	prompt := &InvitationPrompt{
+		Category:  invitation.Category,
+		Role:      invitation.Role,
+		Domain:    invitation.Domain,
+		Mail:      invitation.Mail,
+		Note:      invitation.Note,
+		ExpiresAt: invitation.ExpiresAt,
+	}
+	if verification != "" && invitation.Domain != "" {
+		v, err := s.findVerification(invitation, verification, now)
+		if err != nil {
+			return nil, err
+		}
+		prompt.Mail, prompt.SurName, prompt.GivenName = v.Mail, v.SurName, v.GivenName
+	}
+	return prompt, nil
+}
+
+// RequestVerification 向限定域名的邀请中受邀人填写的邮箱发送验证链接，受邀人打开链接后才能完成注册
+func (s *ServiceInvitation) RequestVerification(spec *InvitationVerifySpec) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	invitation, err := s.find(spec.Token, now)
+	if err != nil {
+		return err
+	}
+	if invitation.Domain == "" {
+		return WrapError(ErrInvalid, "this invitation does not require mail verification")
+	}
+	if err := checkInvitationMail(invitation, spec.Mail); err != nil {
+		return err
+	}
+	surName, givenName := strings.TrimSpace(spec.SurName), strings.TrimSpace(spec.GivenName)
+	if surName == "" || givenName == "" {
+		return WrapError(ErrInvalid, "surName and givenName are required")
+	}
+	if err := validateLanguage(spec.Language); err != nil {
+		return err
+	}
+
+	sameMail := func(v InvitationVerification) bool {
+		return v.InvitationId == invitation.Id && strings.EqualFold(v.Mail, spec.Mail)
+	}
+	verifications, err := s.verifications.List()
+	if err != nil {
+		return err
+	}
+	for _, v := range verifications {
+		if sameMail(v) && now.Sub(v.CreatedAt) < invitationVerifyInterval {
+			return WrapError(ErrTooManyRequests, "a verification mail was sent to this address recently, please try again later")
+		}
+	}
+	// 重新发送时之前的链接失效
+	if _, err := s.verifications.DeleteFunc(func(_ string, v InvitationVerification) bool { return sameMail(v) }); err != nil {
+		return err
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	secret, err := generateSecret()
+	if err != nil {
+		return err
+	}
+	verification := InvitationVerification{
+		Id:           hex.EncodeToString(id),
+		InvitationId: invitation.Id,
+		Mail:         spec.Mail,
+		SurName:      surName,
+		GivenName:    givenName,
+		SecretHash:   hashTokenSecret(secret),
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(s.cfg.VerifyTTL),
+	}
+	if verification.ExpiresAt.After(invitation.ExpiresAt) {
+		verification.ExpiresAt = invitation.ExpiresAt
+	}
+	if err := s.verifications.Create(verification.Id, verification); err != nil {
+		return err
+	}
+
+	link := strings.TrimSuffix(s.siteUrl, "/") + "/invite?token=" + url.QueryEscape(spec.Token) + "&verification=" + url.QueryEscape(verification.Id+"."+secret)
+	if err := s.notification.InvitationVerify(&verification, spec.Language, link); err != nil {
+		if err := s.verifications.Delete(verification.Id); err != nil {
+			logrus.Warnf("Failed to remove invitation verification %s: %v", verification.Id, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// Accept 使用邀请注册账号，密码由受邀人设置，不会通过邮件发送。限定域名的邀请只能使用已验证的邮箱
+func (s *ServiceInvitation) Accept(spec *InvitationAcceptSpec) error {
+	now := time.Now()
+	invitation, err := s.find(spec.Token, now)
+	if err != nil {
+		return err
+	}
+	if err := checkInvitationMail(invitation, spec.Mail); err != nil {
+		return err
+	}
+	var verification InvitationVerification
+	if invitation.Domain != "" {
+		if spec.Verification == "" {
+			return WrapError(ErrInvalid, "the mail address must be verified before accepting this invitation")
+		}
+		verification, err = s.findVerification(invitation, spec.Verification, now)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(verification.Mail, spec.Mail) {
+			return WrapError(ErrInvalid, "the mail address differs from the verified one")
+		}
+	}
+	surName, givenName := strings.TrimSpace(spec.SurName), strings.TrimSpace(spec.GivenName)
+	if surName == "" || givenName == "" {
+		return WrapError(ErrInvalid, "surName and givenName are required")
+	}
+
+	// 先占用一次使用次数，多个实例同时使用同一邀请时不会超出次数
+	err = s.invitations.Update(invitation.Id, func(invitation *Invitation) error {
+		if status := s.status(*invitation, now); status != InvitationActive {
+			return WrapError(ErrInvalid, fmt.Sprintf("invitation is %s", status))
+		}
+		invitation.Uses++
+		invitation.UsedBy = append(invitation.UsedBy, spec.Username)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.manager.RegisterWithPassword(spec.Username, surName, givenName, spec.Mail, invitation.Category, invitation.Role, spec.Language, spec.Password)
+	if err != nil {
+		s.release(invitation.Id, spec.Username)
+		return err
+	}
+	if verification.Id != "" {
+		if err := s.verifications.Delete(verification.Id); err != nil {
+			logrus.Warnf("Failed to remove invitation verification %s: %v", verification.Id, err)
+		}
+	}
+	return nil
+}
+
+// release 归还注册失败时占用的使用次数
+func (s *ServiceInvitation) release(id string, username string) {
+	err := s.invitations.Update(id, func(invitation *Invitation) error {
+		if i := slices.Index(invitation.UsedBy, username); i >= 0 {
+			invitation.UsedBy = slices.Delete(invitation.UsedBy, i, i+1)
+			invitation.Uses--
+		}
+		return nil
+	})
+	if err != nil {
+		logrus.Errorf("Failed to release use of invitation %s by %s: %v", id, username, err)
+	}
+}
+
+// Purge 删除过期或撤销超过保留期的邀请和过期的邮箱验证，返回删除的邀请数量
+func (s *ServiceInvitation) Purge(now time.Time) (int, error) {
+	before := now.Add(-s.cfg.Retention)
+	n, err := s.invitations.DeleteFunc(func(_ string, invitation Invitation) bool {
+		if invitation.RevokedAt != nil {
+			return invitation.RevokedAt.Before(before)
+		}
+		return invitation.ExpiresAt.Before(before)
+	})
+	if n > 0 {
+		logrus.Infof("Purged %d invitations", n)
+	}
+	if err != nil {
+		return n, err
+	}
+
+	_, err = s.verifications.DeleteFunc(func(_ string, v InvitationVerification) bool {
+		return !now.Before(v.ExpiresAt)
+	})
+	return n, err
+}
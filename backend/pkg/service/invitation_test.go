@@ -0,0 +1,85 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/security"
+)
+
+func TestInvitationsAreSharedBetweenInstances(t *testing.T) {
+	env := newTestEnv(t)
+
+	// 管理员在实例 A 上创建邀请，受邀人的请求落到不同的实例
+	cfg := &config.ConfigInvitation{MaxTTL: 24 * time.Hour, MaxUses: 10, VerifyTTL: time.Hour, Retention: time.Hour}
+	replicaA := NewServiceInvitation(cfg, "https://asynx.example.org/", env.manager, env.manager.notification)
+	replicaB := NewServiceInvitation(cfg, "https://asynx.example.org/", env.manager, env.manager.notification)
+
+	invitation, err := replicaA.Create("admin", &InvitationSpec{
+		Category:  security.OuUserMember.String(),
+		Role:      "default",
+		Domain:    "example.org",
+		MaxUses:   1,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	link, err := url.Parse(invitation.Link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := link.Query().Get("token")
+
+	if err := replicaB.RequestVerification(&InvitationVerifySpec{Token: token, SurName: "张", GivenName: "三", Mail: "zs@example.org"}); err != nil {
+		t.Fatal(err)
+	}
+	mails := env.deliveredMails("zs@example.org")
+	if len(mails) != 1 {
+		t.Fatalf("got %d mails, want one verification mail", len(mails))
+	}
+	match := regexp.MustCompile(`https://\S+/invite\?\S+`).FindString(mails[0].Text)
+	verifyLink, err := url.Parse(strings.TrimRight(match, `"`))
+	if err != nil || verifyLink.Query().Get("verification") == "" {
+		t.Fatalf("verification mail has no link:\n%s", mails[0].Text)
+	}
+
+	accept := func(replica *ServiceInvitation, username string) error {
+		return replica.Accept(&InvitationAcceptSpec{
+			Token:        token,
+			Verification: verifyLink.Query().Get("verification"),
+			Username:     username,
+			SurName:      "张",
+			GivenName:    "三",
+			Mail:         "zs@example.org",
+			Password:     "Corr3ct-Horse-Battery",
+		})
+	}
+	// 注册失败时归还占用的使用次数
+	if err := accept(replicaA, "not a username"); err == nil {
+		t.Fatal("accepted an invalid username")
+	}
+	if err := accept(replicaA, "2024000001"); err != nil {
+		t.Fatalf("accept on replica A with verification from replica B: %v", err)
+	}
+	if _, err := env.manager.serviceUser.FindByUid("2024000001"); err != nil {
+		t.Errorf("accepted invitation did not create the account: %v", err)
+	}
+
+	// 使用次数在实例之间共享
+	if err := accept(replicaB, "2024000002"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("second use on replica B: got %v, want ErrInvalid", err)
+	}
+	invitations, err := replicaB.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(invitations) != 1 || invitations[0].Status != InvitationExhausted || invitations[0].Uses != 1 {
+		t.Errorf("replica B lists %+v, want one exhausted invitation", invitations)
+	}
+}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/entity"
+	"asynclab.club/asynx/backend/pkg/event"
+	"asynclab.club/asynx/backend/pkg/persist"
+	"asynclab.club/asynx/backend/pkg/security"
+	"github.com/sirupsen/logrus"
+)
+
+// LifecycleExemption 使成员不会被生命周期策略转为 alumni
+type LifecycleExemption struct {
+	Uid       string    `json:"uid"`
+	Reason    string    `json:"reason"`
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// LifecycleCandidate 是将被或应被转为 alumni 的成员
+type LifecycleCandidate struct {
+	Username       string        `json:"username"`
+	SurName        string        `json:"surName"`
+	GivenName      string        `json:"givenName"`
+	Role           security.Role `json:"role"`
+	EnrollmentYear int           `json:"enrollmentYear"`
+	DueAt          time.Time     `json:"dueAt"`                // 转为 alumni 的时间
+	Exempt         bool          `json:"exempt"`               // 已被豁免，不会转换
+	NotifiedAt     *time.Time    `json:"notifiedAt,omitempty"` // 已发送提醒的时间
+}
+
+// lifecycleNotice 记录已经提醒过的转换时间，策略修改导致转换时间变化后会重新提醒
+type lifecycleNotice struct {
+	Uid        string    `json:"uid"`
+	DueAt      time.Time `json:"dueAt"`
+	NotifiedAt time.Time `json:"notifiedAt"`
+}
+
+// ServiceLifecycle 按入学年份把成员转为 alumni 并降低角色。
+// 转换任务只在主实例上运行，豁免和提醒记录保存在共享存储中，在任一实例上设置的豁免都会生效
+type ServiceLifecycle struct {
+	cfg        *config.ConfigLifecycle
+	manager    *ServiceManager
+	role       security.Role
+	date       time.Time // 只使用月日
+	exemptions *persist.SharedCollection[LifecycleExemption]
+	notices    *persist.SharedCollection[lifecycleNotice]
+}
+
+func NewServiceLifecycle(cfg *config.ConfigLifecycle, manager *ServiceManager, bus *event.Bus) (*ServiceLifecycle, error) {
+	if cfg.AlumniAfterYears < 0 {
+		return nil, fmt.Errorf("invalid LIFECYCLE_ALUMNI_AFTER_YEARS: %d", cfg.AlumniAfterYears)
+	}
+	date, err := time.Parse("01-02", cfg.AlumniDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LIFECYCLE_ALUMNI_DATE %q: %w", cfg.AlumniDate, err)
+	}
+	role, err := security.GetRoleFromName(cfg.AlumniRole)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LIFECYCLE_ALUMNI_ROLE: %w", err)
+	}
+
+	s := &ServiceLifecycle{
+		cfg:        cfg,
+		manager:    manager,
+		role:       role,
+		date:       date,
+		exemptions: persist.NewSharedCollection[LifecycleExemption](manager.store.Shared(), "lifecycle-exemptions"),
+		notices:    persist.NewSharedCollection[lifecycleNotice](manager.store.Shared(), "lifecycle-notices"),
+	}
+	bus.Subscribe(func(e event.Event) {
+		if e.Type != event.UserDeleted {
+			return
+		}
+		for _, c := range []interface{ Delete(string) error }{s.exemptions, s.notices} {
+			if err := c.Delete(e.Subject); err != nil {
+				logrus.Errorf("Failed to remove lifecycle state of %s: %v", e.Subject, err)
+			}
+		}
+	})
+	return s, nil
+}
+
+func (s *ServiceLifecycle) enabled() bool {
+	return s.cfg.AlumniAfterYears > 0
+}
+
+// dueAt 返回成员转为 alumni 的时间，用户名不是学号时返回 false
+func (s *ServiceLifecycle) dueAt(user *entity.User) (int, time.Time, bool) {
+	year, err := security.EnrollmentYear(user.Uid)
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	return year, time.Date(year+s.cfg.AlumniAfterYears, s.date.Month(), s.date.Day(), 0, 0, 0, 0, time.Local), true
+}
+
+// Preview 返回在 before 之前应转为 alumni 的成员，包括已被豁免的，按转换时间排序
+func (s *ServiceLifecycle) Preview(before time.Time) ([]LifecycleCandidate, error) {
+	if !s.enabled() {
+		return nil, WrapError(ErrInvalid, "lifecycle policy is disabled, set LIFECYCLE_ALUMNI_AFTER_YEARS to enable it")
+	}
+	users, err := s.manager.serviceUser.FindAllByOu(security.OuUserMember)
+	if err != nil {
+		return nil, err
+	}
+	exemptions, err := s.exemptions.List()
+	if err != nil {
+		return nil, err
+	}
+	exempt := make(map[string]bool, len(exemptions))
+	for _, exemption := range exemptions {
+		exempt[exemption.Uid] = true
+	}
+	noticeList, err := s.notices.List()
+	if err != nil {
+		return nil, err
+	}
+	notices := make(map[string]lifecycleNotice, len(noticeList))
+	for _, notice := range noticeList {
+		notices[notice.Uid] = notice
+	}
+
+	candidates := make([]LifecycleCandidate, 0)
+	for _, user := range users {
+		year, due, ok := s.dueAt(user)
+		if !ok || due.After(before) {
+			continue
+		}
+		role, err := s.manager.serviceGroup.GetRole(user)
+		if err != nil {
+			return nil, err
+		}
+		candidate := LifecycleCandidate{
+			Username:       user.Uid,
+			SurName:        user.Sn,
+			GivenName:      user.GivenName,
+			Role:           role,
+			EnrollmentYear: year,
+			DueAt:          due,
+			Exempt:         exempt[user.Uid],
+		}
+		if notice, ok := notices[user.Uid]; ok && notice.DueAt.Equal(due) {
+			candidate.NotifiedAt = &notice.NotifiedAt
+		}
+		candidates = append(candidates, candidate)
+	}
+	slices.SortFunc(candidates, func(a, b LifecycleCandidate) int { return a.DueAt.Compare(b.DueAt) })
+	return candidates, nil
+}
+
+// DefaultPreviewUntil 返回预览的默认截止时间，即会在提醒期内被转换的成员
+func (s *ServiceLifecycle) DefaultPreviewUntil(now time.Time) time.Time {
+	return now.Add(s.cfg.NotifyBefore)
+}
+
+// Apply 提醒即将转换的成员，并把已到期的成员转为 alumni
+func (s *ServiceLifecycle) Apply(now time.Time) error {
+	if !s.enabled() {
+		return nil
+	}
+	candidates, err := s.Preview(s.DefaultPreviewUntil(now))
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, candidate := range candidates {
+		if candidate.Exempt {
+			continue
+		}
+		if !now.Before(candidate.DueAt) {
+			if err := s.transition(candidate.Username); err != nil {
+				errs = append(errs, fmt.Errorf("failed to move %s to %s: %w", candidate.Username, security.OuUserAlumni, err))
+			}
+			continue
+		}
+		if candidate.NotifiedAt == nil {
+			if err := s.remind(candidate, now); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *ServiceLifecycle) remind(candidate LifecycleCandidate, now time.Time) error {
+	user, err := s.manager.serviceUser.FindByUid(candidate.Username)
+	if err != nil {
+		return err
+	}
+	s.manager.notification.AlumniTransition(user, candidate.DueAt, s.targetRole(candidate.Role).String())
+	return s.notices.Put(user.Uid, lifecycleNotice{Uid: user.Uid, DueAt: candidate.DueAt, NotifiedAt: now})
+}
+
+// targetRole 返回转换后的角色，原角色不高于策略中的角色时保持不变
+func (s *ServiceLifecycle) targetRole(current security.Role) security.Role {
+	if s.role.Support(current) {
+		return current
+	}
+	return s.role
+}
+
+func (s *ServiceLifecycle) transition(uid string) error {
+	// 预览之后可能在其他实例上刚设置了豁免
+	if _, exempt, err := s.exemptions.Get(uid); err != nil || exempt {
+		return err
+	}
+	if err := s.manager.ModifyCategory(uid, security.OuUserAlumni.String()); err != nil {
+		return err
+	}
+	user, err := s.manager.serviceUser.FindByUid(uid)
+	if err != nil {
+		return err
+	}
+	current, err := s.manager.serviceGroup.GetRole(user)
+	if err != nil {
+		return err
+	}
+	if role := s.targetRole(current); role != current {
+		if err := s.manager.grantRole(user, role); err != nil {
+			return err
+		}
+	}
+	if err := s.notices.Delete(uid); err != nil {
+		logrus.Warnf("Failed to remove lifecycle notice of %s: %v", uid, err)
+	}
+	logrus.Infof("Moved %s to %s by lifecycle policy", uid, security.OuUserAlumni)
+	return nil
+}
+
+// ListExemptions 返回全部豁免，按用户名排序
+func (s *ServiceLifecycle) ListExemptions() ([]LifecycleExemption, error) {
+	return s.exemptions.List()
+}
+
+// Exempt 豁免成员，已存在的豁免会被覆盖
+func (s *ServiceLifecycle) Exempt(operator string, uid string, reason string) (*LifecycleExemption, error) {
+	user, err := s.manager.serviceUser.FindByUid(uid)
+	if err != nil {
+		return nil, err
+	}
+	if user.Ou != security.OuUserMember.String() {
+		return nil, WrapError(ErrInvalid, fmt.Sprintf("only %s accounts can be exempted, %s is %s", security.OuUserMember, uid, user.Ou))
+	}
+	exemption := LifecycleExemption{Uid: uid, Reason: reason, CreatedBy: operator, CreatedAt: time.Now()}
+	if err := s.exemptions.Put(uid, exemption); err != nil {
+		return nil, err
+	}
+	return &exemption, nil
+}
+
+func (s *ServiceLifecycle) RemoveExemption(uid string) error {
+	_, ok, err := s.exemptions.Get(uid)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return WrapError(ErrNotFound, fmt.Sprintf("exemption of %s not found", uid))
+	}
+	return s.exemptions.Delete(uid)
+}
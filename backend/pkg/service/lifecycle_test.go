@@ -0,0 +1,75 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/event"
+	"asynclab.club/asynx/backend/pkg/security"
+)
+
+func newTestLifecycle(t *testing.T, manager *ServiceManager) *ServiceLifecycle {
+	t.Helper()
+	cfg := &config.ConfigLifecycle{AlumniAfterYears: 4, AlumniDate: "07-01", AlumniRole: "restricted", NotifyBefore: 30 * 24 * time.Hour}
+	lifecycle, err := NewServiceLifecycle(cfg, manager, event.NewBus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lifecycle
+}
+
+func TestLifecycleApply(t *testing.T) {
+	env := newTestEnv(t)
+	registerMember(t, env.manager, "2020000001", "admin")
+	registerMember(t, env.manager, "2020000002", "default")
+	registerMember(t, env.manager, "2022000001", "default")
+	registerMember(t, env.manager, "2023000001", "default")
+	welcomed := len(env.deliveredMails("2022000001@example.org"))
+
+	// 设置豁免的实例和运行任务的主实例不同
+	replicaA, replicaB := newTestLifecycle(t, env.manager), newTestLifecycle(t, env.manager)
+	if _, err := replicaB.Exempt("admin", "2020000002", "teaching assistant"); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.Local)
+	candidates, err := replicaA.Preview(replicaA.DefaultPreviewUntil(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 3 || candidates[0].Username != "2020000001" || !candidates[1].Exempt || candidates[2].Username != "2022000001" {
+		t.Fatalf("got candidates %+v", candidates)
+	}
+	if err := replicaA.Apply(now); err != nil {
+		t.Fatal(err)
+	}
+
+	moved, err := env.manager.serviceUser.FindByUid("2020000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.Ou != security.OuUserAlumni.String() {
+		t.Errorf("due member is in %s, want alumni", moved.Ou)
+	}
+	if role, _ := env.manager.GetRole(moved); role != security.RoleRestricted {
+		t.Errorf("due member has role %s, want restricted", role)
+	}
+	if exempt, _ := env.manager.serviceUser.FindByUid("2020000002"); exempt.Ou != security.OuUserMember.String() {
+		t.Errorf("member exempted on replica B moved to %s", exempt.Ou)
+	}
+	if mails := env.deliveredMails("2022000001@example.org"); len(mails) != welcomed+1 {
+		t.Errorf("got %d new mails for the member due in two weeks, want one reminder", len(mails)-welcomed)
+	}
+	if mails := env.deliveredMails("2023000001@example.org"); len(mails) != welcomed {
+		t.Errorf("member due next year got %d new mails", len(mails)-welcomed)
+	}
+
+	// 主实例切换后不会重复提醒
+	if err := replicaB.Apply(now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if mails := env.deliveredMails("2022000001@example.org"); len(mails) != welcomed+1 {
+		t.Errorf("got %d new mails after failover, want the reminder only once", len(mails)-welcomed)
+	}
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"asynclab.club/asynx/backend/pkg/mail"
+)
+
+type ServiceMail struct {
+	templates *mail.Templates
+}
+
+func NewServiceMail(templates *mail.Templates) *ServiceMail {
+	return &ServiceMail{templates: templates}
+}
+
+func (s *ServiceMail) ListTemplates() []mail.Template {
+	return mail.AllTemplates()
+}
+
+// Preview 使用示例数据渲染模板
+func (s *ServiceMail) Preview(name string, lang string) (*mail.Rendered, error) {
+	tmpl, err := mail.GetTemplateFromName(name)
+	if err != nil {
+		return nil, WrapError(ErrNotFound, err.Error())
+	}
+	return s.templates.Render(tmpl, lang, mail.Sample(tmpl))
+}
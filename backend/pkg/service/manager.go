@@ -5,10 +5,16 @@ import (
 	"fmt"
 	"slices"
 	"strconv"
+	"strings"
+	"time"
 
-	"asynclab.club/asynx/backend/pkg/client"
+	"asynclab.club/asynx/backend/pkg/cache"
 	"asynclab.club/asynx/backend/pkg/config"
 	"asynclab.club/asynx/backend/pkg/entity"
+	"asynclab.club/asynx/backend/pkg/event"
+	"asynclab.club/asynx/backend/pkg/mail"
+	"asynclab.club/asynx/backend/pkg/repository"
+	"asynclab.club/asynx/backend/pkg/saga"
 	"asynclab.club/asynx/backend/pkg/security"
 	"asynclab.club/asynx/backend/pkg/util"
 	"github.com/dsx137/gg-kit/pkg/ggkit"
@@ -22,18 +28,69 @@ type UserProfile struct {
 	Mail      string          `json:"mail"`
 	Role      security.Role   `json:"role"`
 	Category  security.OuUser `json:"category"`
+	Language  string          `json:"language"`
+	ExpiresAt *time.Time      `json:"expiresAt,omitempty"` // 账号失效的时间，为空表示不过期
 }
 
 // ----------------------------------------------------------------------------------------------------------------------
 
+const OperationRegister = "user.register"
+
+type registerPayload struct {
+	User entity.User   `json:"user"`
+	Role security.Role `json:"role"`
+	// 初始密码只保存在内存中，不写入操作日志
+	Password string `json:"-"`
+	// 密码由用户自己设置，欢迎邮件中不附带密码
+	PasswordChosen bool `json:"passwordChosen,omitempty"`
+}
+
 type ServiceManager struct {
+	store        repository.Store
+	coordinator  *saga.Coordinator
 	serviceUser  *ServiceUser
 	serviceGroup *ServiceGroup
-	emailClient  *client.EmailClient
+	notification *ServiceNotification
+	delegation   *ServiceDelegation
+	bus          *event.Bus
+	sessions     *cache.Cache[session]
+}
+
+// 登录令牌持有者角色的缓存时间，总线上的任何变更都会清空缓存
+const sessionTTL = 10 * time.Second
+
+type session struct {
+	role      security.Role
+	expiresAt *time.Time
 }
 
-func NewServiceManager(serviceUser *ServiceUser, serviceGroup *ServiceGroup, emailClient *client.EmailClient) *ServiceManager {
-	return &ServiceManager{serviceUser: serviceUser, serviceGroup: serviceGroup, emailClient: emailClient}
+func NewServiceManager(store repository.Store, coordinator *saga.Coordinator, notification *ServiceNotification, delegation *ServiceDelegation, bus *event.Bus) *ServiceManager {
+	s := &ServiceManager{
+		store:        store,
+		coordinator:  coordinator,
+		serviceUser:  NewServiceUser(store.Users()),
+		serviceGroup: NewServiceGroup(store.Groups(), coordinator),
+		notification: notification,
+		delegation:   delegation,
+		bus:          bus,
+		sessions:     cache.New[session]("sessions", sessionTTL),
+	}
+	bus.Subscribe(func(event.Event) { s.sessions.Flush() })
+
+	// 初始密码无法从日志恢复，中断的注册只能回滚
+	saga.Register(coordinator, OperationRegister, saga.RecoverRollback,
+		saga.Step[registerPayload]{
+			Name:       "create-account",
+			Action:     s.createAccount,
+			Compensate: s.removeCreatedAccount,
+		},
+		saga.Step[registerPayload]{
+			Name:   "enqueue-welcome-mail",
+			Action: s.enqueueWelcomeMail,
+		},
+	)
+
+	return s
 }
 
 func (s *ServiceManager) Authenticate(username, password string) (string, error) {
@@ -45,6 +102,17 @@ func (s *ServiceManager) Authenticate(username, password string) (string, error)
 		return "", WrapError(ErrInvalid, fmt.Sprintf("Invalid credentials"))
 	}
 
+	user, err := s.serviceUser.FindByUid(username)
+	if err != nil {
+		return "", err
+	}
+	if IsServiceAccount(user) {
+		return "", WrapError(ErrInvalid, "service accounts cannot log in interactively")
+	}
+	if IsAccountExpired(user, time.Now()) {
+		return "", WrapError(ErrDenied, fmt.Sprintf("account %s expired on %s", user.Uid, AccountExpiresAt(user).Format(time.DateOnly)))
+	}
+
 	role, err := s.serviceGroup.GetRoleByUid(username)
 	if err != nil {
 		return "", err
@@ -57,7 +125,46 @@ func (s *ServiceManager) Authenticate(username, password string) (string, error)
 	return token, nil
 }
 
-func (s *ServiceManager) Register(username, surName, givenName, mail, category, roleName string) error {
+// ResolveSession 返回登录令牌持有者的当前角色，注册为 security.SessionResolver。账号到期后登录令牌随之失效
+func (s *ServiceManager) ResolveSession(uid string) (security.Role, error) {
+	entry, ok := s.sessions.Get(uid)
+	if !ok {
+		generation := s.sessions.Generation()
+		user, err := s.serviceUser.FindByUid(uid)
+		if err != nil {
+			return security.RoleAnonymous, err
+		}
+		role, err := s.serviceGroup.GetRole(user)
+		if err != nil {
+			return security.RoleAnonymous, err
+		}
+		entry = session{role: role, expiresAt: AccountExpiresAt(user)}
+		s.sessions.SetIfGeneration(uid, entry, generation)
+	}
+	if entry.expiresAt != nil && !time.Now().Before(*entry.expiresAt) {
+		return security.RoleAnonymous, WrapError(ErrDenied, fmt.Sprintf("account %s has expired", uid))
+	}
+	return entry.role, nil
+}
+
+// Register 注册用户，expiresAt 不为空时账号到期后不能再登录
+func (s *ServiceManager) Register(username, surName, givenName, mail, category, roleName, language string, expiresAt *time.Time) error {
+	return s.register(username, surName, givenName, mail, category, roleName, language, expiresAt, "")
+}
+
+// RegisterWithPassword 使用用户自己设置的密码注册，欢迎邮件中不附带密码
+func (s *ServiceManager) RegisterWithPassword(username, surName, givenName, mail, category, roleName, language, password string) error {
+	if err := security.ValidatePasswordLegality(password); err != nil {
+		return WrapError(ErrInvalid, err.Error())
+	}
+	if err := security.ValidatePasswordStrength(password); err != nil {
+		return WrapError(ErrInvalid, err.Error())
+	}
+	return s.register(username, surName, givenName, mail, category, roleName, language, nil, password)
+}
+
+// register 注册用户，password 为空时生成随机密码并通过欢迎邮件发送
+func (s *ServiceManager) register(username, surName, givenName, mail, category, roleName, language string, expiresAt *time.Time, password string) error {
 	ou, err := security.GetOuUserFromName(category)
 	if err != nil {
 		return WrapError(ErrInvalid, err.Error())
@@ -78,7 +185,114 @@ func (s *ServiceManager) Register(username, surName, givenName, mail, category,
 		return WrapError(ErrInvalid, err.Error())
 	}
 
+	if err := validateLanguage(language); err != nil {
+		return err
+	}
+
+	shadowExpire, err := validateExpiresAt(expiresAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.serviceUser.FindByUid(username)
+	if err == nil {
+		return WrapError(ErrExists, fmt.Sprintf("user %s already exists", username))
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	uidNumber, err := s.GenerateNextUidNumber()
+	if err != nil {
+		return err
+	}
+
+	chosen := password != ""
+	if !chosen {
+		if password, err = ggkit.GenerateReadableKey(32, 0); err != nil {
+			return err
+		}
+	}
+
+	user := &entity.User{
+		Uid:               username,
+		Cn:                username,
+		Ou:                ou.String(),
+		Sn:                surName,
+		GivenName:         givenName,
+		GidNumber:         config.LdapGidNumber,
+		UidNumber:         uidNumber,
+		HomeDirectory:     fmt.Sprintf("/home/%s", username),
+		Mail:              mail,
+		LoginShell:        "/bin/bash",
+		PreferredLanguage: language,
+		ShadowExpire:      shadowExpire,
+	}
+
+	err = saga.Execute(s.coordinator, OperationRegister, &registerPayload{
+		User:           *user,
+		Role:           role,
+		Password:       password,
+		PasswordChosen: chosen,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.bus.Publish(event.Event{
+		Type:    event.UserCreated,
+		Subject: user.Uid,
+		Data:    map[string]string{"category": user.Ou, "role": role.String()},
+	})
+	s.publishRoleChanged(user.Uid, security.RoleAnonymous, role)
+	return nil
+}
+
+// 服务账号的登录 shell，拒绝 SSH 等交互式登录
+const ServiceAccountShell = "/usr/sbin/nologin"
+
+// 服务账号条目的 employeeType，system 下的其他账号（如引导管理员）没有这个标记
+const ServiceAccountType = "service-account"
+
+func IsServiceAccount(user *entity.User) bool {
+	return user.Ou == security.OuUserSystem.String() && user.EmployeeType == ServiceAccountType
+}
+
+// MarkLegacyServiceAccounts 为早先只靠登录 shell 识别的服务账号补上 employeeType，启动时执行，已标记的账号不受影响
+func (s *ServiceManager) MarkLegacyServiceAccounts() error {
+	users, err := s.serviceUser.FindAllByOu(security.OuUserSystem)
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		if user.EmployeeType != "" || user.LoginShell != ServiceAccountShell {
+			continue
+		}
+		user.EmployeeType = ServiceAccountType
+		if err := s.serviceUser.ModifyAttributes(user); err != nil {
+			return fmt.Errorf("failed to mark service account %s: %w", user.Uid, err)
+		}
+		logrus.Infof("Marked %s as a service account", user.Uid)
+	}
+	return nil
+}
+
+// RegisterServiceAccount 在 system 下创建服务账号，metadata 写入条目的 description。
+// 服务账号没有邮箱，不发送欢迎邮件，密码随机生成且不告知任何人
+func (s *ServiceManager) RegisterServiceAccount(username, roleName, metadata string) error {
+	if err := security.ValidateServiceAccountUsername(username); err != nil {
+		return WrapError(ErrInvalid, err.Error())
+	}
+
+	role, err := security.GetRoleFromName(roleName)
+	if err != nil {
+		return WrapError(ErrInvalid, err.Error())
+	}
+
 	_, err = s.serviceUser.FindByUid(username)
+	if err == nil {
+		return WrapError(ErrExists, fmt.Sprintf("user %s already exists", username))
+	}
 	if !errors.Is(err, ErrNotFound) {
 		return err
 	}
@@ -93,45 +307,66 @@ func (s *ServiceManager) Register(username, surName, givenName, mail, category,
 		return err
 	}
 
-	user := &entity.User{
+	user := entity.User{
 		Uid:           username,
 		Cn:            username,
-		Ou:            ou.String(),
-		Sn:            surName,
-		GivenName:     givenName,
+		Ou:            security.OuUserSystem.String(),
+		Sn:            username,
 		GidNumber:     config.LdapGidNumber,
 		UidNumber:     uidNumber,
 		HomeDirectory: fmt.Sprintf("/home/%s", username),
-		Mail:          mail,
-		UserPassword:  password,
-		LoginShell:    "/bin/bash",
+		LoginShell:    ServiceAccountShell,
+		Description:   metadata,
+		EmployeeType:  ServiceAccountType,
 	}
-
-	if err := s.serviceUser.Create(user); err != nil {
+	if err := s.createAccount(&registerPayload{User: user, Role: role, Password: password}); err != nil {
 		return err
 	}
 
-	if err := s.serviceGroup.GrantRole(user, role); err != nil {
-		_ = s.unregister(user) // rollback
-		return err
-	}
+	s.bus.Publish(event.Event{
+		Type:    event.UserCreated,
+		Subject: user.Uid,
+		Data:    map[string]string{"category": user.Ou, "role": role.String()},
+	})
+	s.publishRoleChanged(user.Uid, security.RoleAnonymous, role)
+	return nil
+}
 
-	if err := s.emailClient.SendMail(
-		user.Mail,
-		"异步实验室",
-		struct {
-			Surname, GivenName, Username, Password string
-		}{
-			Surname:   user.Sn,
-			GivenName: user.GivenName,
-			Username:  user.Uid,
-			Password:  user.UserPassword,
-		},
-	); err != nil {
-		_ = s.unregister(user) // rollback
+func (s *ServiceManager) createAccount(p *registerPayload) error {
+	user := p.User
+	user.UserPassword = p.Password
+
+	return s.store.Transaction(func(store repository.Store) error {
+		if err := NewServiceUser(store.Users()).Create(&user); err != nil {
+			return err
+		}
+		return s.serviceGroup.withRepository(store.Groups()).GrantRole(&user, p.Role)
+	})
+}
+
+// removeCreatedAccount 只删除由本次注册创建的账号，通过 uidNumber 区分同名的已有账号
+func (s *ServiceManager) removeCreatedAccount(p *registerPayload) error {
+	user, err := s.serviceUser.FindByUid(p.User.Uid)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
 		return err
 	}
+	if user.UidNumber != p.User.UidNumber {
+		return nil
+	}
+	return s.unregister(user)
+}
 
+func (s *ServiceManager) enqueueWelcomeMail(p *registerPayload) error {
+	password := p.Password
+	if p.PasswordChosen {
+		password = ""
+	}
+	if err := s.notification.Welcome(&p.User, password, p.PasswordChosen); err != nil {
+		return fmt.Errorf("failed to send welcome mail to %s: %w", p.User.Uid, err)
+	}
 	return nil
 }
 
@@ -154,7 +389,23 @@ func (s *ServiceManager) Unregister(uid string) error {
 		return err
 	}
 
-	return s.unregister(user)
+	role, err := s.serviceGroup.GetRole(user)
+	if err != nil {
+		return err
+	}
+
+	if err := s.unregister(user); err != nil {
+		return err
+	}
+
+	s.bus.Publish(event.Event{Type: event.UserDeleted, Subject: user.Uid, Data: map[string]string{"category": user.Ou}})
+	s.publishRoleChanged(user.Uid, role, security.RoleAnonymous)
+	if !IsServiceAccount(user) {
+		s.notification.AccountDeleted(user)
+	}
+	s.notification.Forget(user.Uid)
+	s.delegation.Forget(user.Uid)
+	return nil
 }
 
 func (s *ServiceManager) GetRole(user *entity.User) (security.Role, error) {
@@ -172,14 +423,58 @@ func (s *ServiceManager) GrantRoleByUidAndRoleName(uid string, roleName string)
 		return WrapError(ErrInvalid, err.Error())
 	}
 
+	return s.grantRole(user, role)
+}
+
+// RevokeRole 把用户移出角色组，之后用户不再具有任何角色的权限
+func (s *ServiceManager) RevokeRole(uid string) error {
+	user, err := s.serviceUser.FindByUid(uid)
+	if err != nil {
+		return err
+	}
+	return s.grantRole(user, security.RoleAnonymous)
+}
+
+func (s *ServiceManager) grantRole(user *entity.User, role security.Role) error {
+	oldRole, err := s.serviceGroup.GetRole(user)
+	if err != nil {
+		return err
+	}
+
 	err = s.serviceGroup.GrantRole(user, role)
 	if err != nil {
 		return err
 	}
 
+	if oldRole != role {
+		s.bus.Publish(event.Event{
+			Type:    event.UserRoleChanged,
+			Subject: user.Uid,
+			Data:    map[string]string{"from": oldRole.String(), "to": role.String()},
+		})
+		s.publishRoleChanged(user.Uid, oldRole, role)
+		// 服务账号没有邮箱，不发送通知
+		if !IsServiceAccount(user) {
+			s.notification.RoleChanged(user, oldRole.String(), role.String())
+		}
+	}
 	return nil
 }
 
+// publishRoleChanged 发布角色组的成员变更事件，匿名角色没有对应的组
+func (s *ServiceManager) publishRoleChanged(uid string, from security.Role, to security.Role) {
+	if from == to {
+		return
+	}
+	ou := security.OuGroupSupplementary.String()
+	if from != security.RoleAnonymous {
+		s.bus.Publish(event.Event{Type: event.GroupMemberRemoved, Subject: from.String(), Data: map[string]string{"uid": uid, "ou": ou}})
+	}
+	if to != security.RoleAnonymous {
+		s.bus.Publish(event.Event{Type: event.GroupMemberAdded, Subject: to.String(), Data: map[string]string{"uid": uid, "ou": ou}})
+	}
+}
+
 func (s *ServiceManager) GenerateNextUidNumber() (string, error) {
 	users, err := s.serviceUser.FindAll()
 	if err != nil {
@@ -195,36 +490,195 @@ func (s *ServiceManager) GenerateNextUidNumber() (string, error) {
 	return strconv.Itoa(uidNumber), nil
 }
 
-func (s *ServiceManager) GetUserWithGuard(guard *security.GuardResult, uid string) (*entity.User, error) {
-	var (
-		user *entity.User
-		err  error
-	)
+// ReconcileUidNumbers 为缺少有效 uidNumber 的用户分配编号，并报告重复的编号。
+// 重复的编号不会被自动修改，因为修改会改变文件的归属，需要管理员处理
+func (s *ServiceManager) ReconcileUidNumbers() error {
+	users, err := s.serviceUser.FindAll()
+	if err != nil {
+		return err
+	}
 
-	authUser, err := s.serviceUser.FindByUid(guard.Uid)
+	owners := make(map[int][]string)
+	for _, user := range users {
+		if n, err := strconv.Atoi(user.UidNumber); err == nil && n > 0 {
+			owners[n] = append(owners[n], user.Uid)
+			continue
+		}
+		uidNumber, err := s.GenerateNextUidNumber()
+		if err != nil {
+			return err
+		}
+		old := user.UidNumber
+		user.UidNumber = uidNumber
+		if err := s.serviceUser.ModifyAttributes(user); err != nil {
+			return fmt.Errorf("failed to assign uidNumber to %s: %w", user.Uid, err)
+		}
+		logrus.Warnf("Assigned uidNumber %s to %s, whose uidNumber was %q", uidNumber, user.Uid, old)
+	}
+
+	var duplicates []string
+	for n, uids := range owners {
+		if len(uids) > 1 {
+			slices.Sort(uids)
+			duplicates = append(duplicates, fmt.Sprintf("%d (%s)", n, strings.Join(uids, ", ")))
+		}
+	}
+	if len(duplicates) > 0 {
+		slices.Sort(duplicates)
+		return fmt.Errorf("duplicate uidNumbers: %s", strings.Join(duplicates, "; "))
+	}
+	return nil
+}
+
+// readableOus 返回 guard 可以查看的账号类型：拥有 users.read.ou 时包括自己所在的类型，以及被委派管理的类型
+func (s *ServiceManager) readableOus(guard *security.GuardResult) ([]security.OuUser, error) {
+	ous, err := s.delegation.ManagedOus(guard.Uid)
 	if err != nil {
 		return nil, err
 	}
+	if !guard.Role.Can(security.PermUsersReadOu) {
+		return ous, nil
+	}
 
+	authUser, err := s.serviceUser.FindByUid(guard.Uid)
+	if err != nil {
+		return nil, err
+	}
 	ou, err := security.GetOuUserFromName(authUser.Ou)
 	if err != nil {
 		return nil, err
 	}
+	if !slices.Contains(ous, ou) {
+		ous = append(ous, ou)
+	}
+	return ous, nil
+}
 
-	switch guard.Role {
-	case security.RoleAdmin:
-		user, err = s.serviceUser.FindByUid(uid)
-	case security.RoleDefault:
-		user, err = s.serviceUser.FindByOuAndUid(ou, uid)
-	default:
-		if guard.Uid != uid {
-			return nil, nil
+func (s *ServiceManager) GetUserWithGuard(guard *security.GuardResult, uid string) (*entity.User, error) {
+	if guard.Uid == uid || guard.Role.Can(security.PermUsersRead) {
+		return s.serviceUser.FindByUid(uid)
+	}
+
+	ous, err := s.readableOus(guard)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.serviceUser.FindByUid(uid)
+	if err != nil {
+		return nil, err
+	}
+	// 看不到的用户按不存在处理，不暴露用户名是否被占用
+	if !slices.Contains(ous, security.OuUser(user.Ou)) {
+		return nil, WrapError(ErrNotFound, fmt.Sprintf("user %s not found", uid))
+	}
+	return user, nil
+}
+
+// AuthorizeList 检查没有 users.read 的 guard 能否查看某个账号类型的用户列表
+func (s *ServiceManager) AuthorizeList(guard *security.GuardResult) error {
+	ous, err := s.readableOus(guard)
+	if err != nil {
+		return err
+	}
+	if len(ous) == 0 {
+		return WrapError(ErrDenied, "no category is visible")
+	}
+	return nil
+}
+
+// AuthorizeDelegated 检查 guard 是否被委派管理至少一个账号类型
+func (s *ServiceManager) AuthorizeDelegated(guard *security.GuardResult) error {
+	ous, err := s.delegation.ManagedOus(guard.Uid)
+	if err != nil {
+		return err
+	}
+	if len(ous) == 0 {
+		return WrapError(ErrDenied, fmt.Sprintf("%s does not manage any category", guard.Uid))
+	}
+	return nil
+}
+
+// AuthorizeManage 检查 guard 能否修改 uid 的密码或删除 uid。拥有 users.write 时可以管理任何账号类型的用户，
+// 委派管理员只能管理被委派的账号类型；没有 users.role.grant 时不能管理角色高于自己的用户
+func (s *ServiceManager) AuthorizeManage(guard *security.GuardResult, uid string) error {
+	_, err := s.authorizeManage(guard, uid)
+	return err
+}
+
+func (s *ServiceManager) authorizeManage(guard *security.GuardResult, uid string) (*entity.User, error) {
+	user, err := s.serviceUser.FindByUid(uid)
+	if err != nil {
+		return nil, err
+	}
+	if !guard.Role.Can(security.PermUsersWrite) {
+		if err := s.authorizeCategory(guard, user.Ou); err != nil {
+			return nil, err
 		}
+	}
+	if guard.Role.Can(security.PermUsersRoleGrant) {
+		return user, nil
+	}
+
+	role, err := s.serviceGroup.GetRole(user)
+	if err != nil {
+		return nil, err
+	}
+	if role != security.RoleAnonymous && !guard.Role.Support(role) {
+		return nil, WrapError(ErrDenied, fmt.Sprintf("role %s of %s exceeds role %s", role, user.Uid, guard.Role))
+	}
+	return user, nil
+}
 
-		user, err = s.serviceUser.FindByUid(uid)
+// AuthorizeRegister 检查 guard 能否以 roleName 注册 category 类型的用户，没有 users.role.grant 时角色不能高于自己
+func (s *ServiceManager) AuthorizeRegister(guard *security.GuardResult, category string, roleName string) error {
+	if !guard.Role.Can(security.PermUsersWrite) {
+		if err := s.authorizeCategory(guard, category); err != nil {
+			return err
+		}
 	}
+	return authorizeRoleName(guard, roleName)
+}
 
-	return user, err
+// AuthorizeRoleGrant 检查 guard 能否把 uid 的角色改为 roleName。没有 users.role.grant 时只有委派管理员可以修改，
+// 并且只能修改被委派账号类型中的用户，授予的角色不能高于自己
+func (s *ServiceManager) AuthorizeRoleGrant(guard *security.GuardResult, uid string, roleName string) error {
+	if guard.Role.Can(security.PermUsersRoleGrant) {
+		return nil
+	}
+	user, err := s.authorizeManage(guard, uid)
+	if err != nil {
+		return err
+	}
+	if err := s.authorizeCategory(guard, user.Ou); err != nil {
+		return err
+	}
+	return authorizeRoleName(guard, roleName)
+}
+
+// authorizeCategory 检查 guard 是否被委派管理 category 类型的账号，无法读取委派时拒绝
+func (s *ServiceManager) authorizeCategory(guard *security.GuardResult, category string) error {
+	manages, err := s.delegation.Manages(guard.Uid, security.OuUser(category))
+	if err != nil {
+		return err
+	}
+	if !manages {
+		return WrapError(ErrDenied, fmt.Sprintf("%s does not manage category %s", guard.Uid, category))
+	}
+	return nil
+}
+
+func authorizeRoleName(guard *security.GuardResult, roleName string) error {
+	if guard.Role.Can(security.PermUsersRoleGrant) {
+		return nil
+	}
+	role, err := security.GetRoleFromName(roleName)
+	if err != nil {
+		return WrapError(ErrInvalid, err.Error())
+	}
+	if !guard.Role.Support(role) {
+		return WrapError(ErrDenied, fmt.Sprintf("role %s exceeds role %s", role, guard.Role))
+	}
+	return nil
 }
 
 func (s *ServiceManager) GetProfile(guard *security.GuardResult, uid string) (*UserProfile, error) {
@@ -249,6 +703,8 @@ func (s *ServiceManager) GetProfile(guard *security.GuardResult, uid string) (*U
 		Role:      role,
 		SurName:   user.Sn,
 		Username:  user.Uid,
+		Language:  user.PreferredLanguage,
+		ExpiresAt: AccountExpiresAt(user),
 	}, nil
 }
 
@@ -258,27 +714,28 @@ func (s *ServiceManager) ListProfiles(guard *security.GuardResult) ([]*UserProfi
 		profiles []*UserProfile
 		err      error
 	)
-	switch guard.Role {
-	case security.RoleAdmin:
+	switch {
+	case guard.Role.Can(security.PermUsersRead):
 		users, err = s.serviceUser.FindAll()
 		if err != nil {
 			return nil, err
 		}
 		break
 	default:
-		user, err := s.serviceUser.FindByUid(guard.Uid)
+		ous, err := s.readableOus(guard)
 		if err != nil {
 			return nil, err
 		}
-
-		ou, err := security.GetOuUserFromName(user.Ou)
-		if err != nil {
-			return nil, err
+		if len(ous) == 0 {
+			return nil, WrapError(ErrDenied, "no category is visible")
 		}
 
-		users, err = s.serviceUser.FindAllByOu(ou)
-		if err != nil {
-			return nil, err
+		for _, ou := range ous {
+			ouUsers, err := s.serviceUser.FindAllByOu(ou)
+			if err != nil {
+				return nil, err
+			}
+			users = append(users, ouUsers...)
 		}
 		break
 	}
@@ -295,6 +752,8 @@ func (s *ServiceManager) ListProfiles(guard *security.GuardResult) ([]*UserProfi
 			Mail:      user.Mail,
 			Role:      security.RoleAnonymous,
 			Category:  category,
+			Language:  user.PreferredLanguage,
+			ExpiresAt: AccountExpiresAt(user),
 		})
 	}
 
@@ -311,9 +770,14 @@ func (s *ServiceManager) ListProfiles(guard *security.GuardResult) ([]*UserProfi
 			}
 		}
 
+		if len(userGroups) == 0 {
+			continue
+		}
+		// 角色组配置错误的用户按没有角色列出，不影响其他用户
 		role, err := security.GetRoleFromLdapGroups(userGroups)
 		if err != nil {
-			return nil, fmt.Errorf("error getting role for user %s: %w", profile.Username, err)
+			logrus.Warnf("Failed to get role for user %s: %v", profile.Username, err)
+			continue
 		}
 
 		profile.Role = role
@@ -322,7 +786,48 @@ func (s *ServiceManager) ListProfiles(guard *security.GuardResult) ([]*UserProfi
 	return profiles, nil
 }
 
-func (s *ServiceManager) ChangePassword(uid string, password string) error {
+func (s *ServiceManager) ListOuAdmins() ([]OuAdmin, error) {
+	return s.delegation.List()
+}
+
+// SetOuAdmin 委派 uid 管理 categories 中的账号类型，整体替换原有的委派
+func (s *ServiceManager) SetOuAdmin(operator string, uid string, categories []string) (*OuAdmin, error) {
+	user, err := s.serviceUser.FindByUid(uid)
+	if err != nil {
+		return nil, err
+	}
+	if IsServiceAccount(user) {
+		return nil, WrapError(ErrInvalid, fmt.Sprintf("%s is a service account", uid))
+	}
+	if len(categories) == 0 {
+		return nil, WrapError(ErrInvalid, "at least one category is required")
+	}
+
+	ous := make([]security.OuUser, 0, len(categories))
+	for _, category := range categories {
+		ou, err := security.GetOuUserFromName(category)
+		if err != nil {
+			return nil, WrapError(ErrInvalid, err.Error())
+		}
+		if !slices.Contains(ous, ou) {
+			ous = append(ous, ou)
+		}
+	}
+
+	admin, err := s.delegation.set(uid, ous, operator)
+	if err != nil {
+		return nil, err
+	}
+	logrus.Infof("%s delegated categories %v to %s", operator, ous, uid)
+	return &admin, nil
+}
+
+func (s *ServiceManager) RemoveOuAdmin(uid string) error {
+	return s.delegation.remove(uid)
+}
+
+// ChangePassword 修改用户密码，operator 为执行操作的用户，修改他人密码时总会通知该用户
+func (s *ServiceManager) ChangePassword(operator string, uid string, password string) error {
 	user, err := s.serviceUser.FindByUid(uid)
 	if err != nil {
 		return err
@@ -337,6 +842,11 @@ func (s *ServiceManager) ChangePassword(uid string, password string) error {
 	if err := s.serviceUser.ModifyPassword(user, password); err != nil {
 		return err
 	}
+
+	s.bus.Publish(event.Event{Type: event.UserPasswordChanged, Actor: operator, Subject: user.Uid})
+	if operator != uid {
+		s.notification.PasswordReset(user)
+	}
 	return nil
 }
 
@@ -351,10 +861,177 @@ func (s *ServiceManager) ModifyCategory(uid string, category string) error {
 		return WrapError(ErrInvalid, err.Error())
 	}
 
+	oldOu := user.Ou
 	err = s.serviceUser.ModifyOu(user, ou)
 	if err != nil {
 		return err
 	}
 
+	if oldOu != ou.String() {
+		s.bus.Publish(event.Event{
+			Type:    event.UserCategoryChanged,
+			Subject: user.Uid,
+			Data:    map[string]string{"from": oldOu, "to": ou.String()},
+		})
+		s.notification.CategoryChanged(user, oldOu, ou.String())
+	}
+	return nil
+}
+
+func (s *ServiceManager) GetNotificationSettings(uid string) ([]NotificationSetting, error) {
+	if _, err := s.serviceUser.FindByUid(uid); err != nil {
+		return nil, err
+	}
+	return s.notification.GetSettings(uid)
+}
+
+func (s *ServiceManager) ModifyNotificationSettings(uid string, disabled []string) error {
+	if _, err := s.serviceUser.FindByUid(uid); err != nil {
+		return err
+	}
+	return s.notification.SetDisabled(uid, disabled)
+}
+
+func (s *ServiceManager) ModifyLanguage(uid string, language string) error {
+	user, err := s.serviceUser.FindByUid(uid)
+	if err != nil {
+		return err
+	}
+
+	if err := validateLanguage(language); err != nil {
+		return err
+	}
+
+	if user.PreferredLanguage == language {
+		return nil
+	}
+
+	user.PreferredLanguage = language
+	if err := s.serviceUser.ModifyAttributes(user); err != nil {
+		return err
+	}
+
+	s.bus.Publish(event.Event{Type: event.UserUpdated, Subject: user.Uid, Data: map[string]string{"attributes": "preferredlanguage"}})
+	return nil
+}
+
+// ModifyProfile 修改姓名和邮箱，没有变化时不写入
+func (s *ServiceManager) ModifyProfile(uid string, surName string, givenName string, mail string) error {
+	user, err := s.serviceUser.FindByUid(uid)
+	if err != nil {
+		return err
+	}
+
+	var changed []string
+	if user.Sn != surName {
+		user.Sn = surName
+		changed = append(changed, "sn")
+	}
+	if user.GivenName != givenName {
+		user.GivenName = givenName
+		changed = append(changed, "givenname")
+	}
+	// 只校验被修改的邮箱，服务账号等没有邮箱的账号仍可修改姓名
+	if user.Mail != mail {
+		if err := security.ValidateEmailFormat(mail); err != nil {
+			return WrapError(ErrInvalid, err.Error())
+		}
+		user.Mail = mail
+		changed = append(changed, "mail")
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	if err := s.serviceUser.ModifyAttributes(user); err != nil {
+		return err
+	}
+
+	s.bus.Publish(event.Event{Type: event.UserUpdated, Subject: user.Uid, Data: map[string]string{"attributes": strings.Join(changed, ",")}})
+	return nil
+}
+
+// validateExpiresAt 检查到期时间并转换为 shadowExpire，为空时表示不过期
+func validateExpiresAt(expiresAt *time.Time) (string, error) {
+	if expiresAt == nil {
+		return "", nil
+	}
+	if !expiresAt.After(time.Now()) {
+		return "", WrapError(ErrInvalid, "expiresAt must be in the future")
+	}
+	return toShadowExpire(*expiresAt), nil
+}
+
+// ModifyExpiry 修改账号的到期时间，expiresAt 为空时取消到期
+func (s *ServiceManager) ModifyExpiry(uid string, expiresAt *time.Time) error {
+	user, err := s.serviceUser.FindByUid(uid)
+	if err != nil {
+		return err
+	}
+	shadowExpire, err := validateExpiresAt(expiresAt)
+	if err != nil {
+		return err
+	}
+	return s.setShadowExpire(user, shadowExpire)
+}
+
+// Deactivate 使账号从今天起到期，之后不能再登录或使用令牌。已到期的账号保持不变
+func (s *ServiceManager) Deactivate(uid string) error {
+	user, err := s.serviceUser.FindByUid(uid)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if IsAccountExpired(user, now) {
+		return nil
+	}
+	return s.setShadowExpire(user, strconv.FormatInt(now.Unix()/int64(shadowDay/time.Second), 10))
+}
+
+func (s *ServiceManager) setShadowExpire(user *entity.User, shadowExpire string) error {
+	if user.ShadowExpire == shadowExpire {
+		return nil
+	}
+
+	user.ShadowExpire = shadowExpire
+	if err := s.serviceUser.ModifyAttributes(user); err != nil {
+		return err
+	}
+
+	s.bus.Publish(event.Event{Type: event.UserUpdated, Subject: user.Uid, Data: map[string]string{"attributes": "shadowexpire"}})
+	return nil
+}
+
+// JoinGroup 把用户加入附加组，角色组的成员只能通过 GrantRoleByUidAndRoleName 修改
+func (s *ServiceManager) JoinGroup(uid string, cn string) error {
+	return s.setAdditionalMembership(uid, cn, true)
+}
+
+func (s *ServiceManager) LeaveGroup(uid string, cn string) error {
+	return s.setAdditionalMembership(uid, cn, false)
+}
+
+func (s *ServiceManager) setAdditionalMembership(uid string, cn string, member bool) error {
+	if _, err := s.serviceUser.FindByUid(uid); err != nil {
+		return err
+	}
+
+	changed, err := s.serviceGroup.SetMembership(security.OuGroupAdditional, cn, uid, member)
+	if err != nil || !changed {
+		return err
+	}
+
+	eventType := event.GroupMemberAdded
+	if !member {
+		eventType = event.GroupMemberRemoved
+	}
+	s.bus.Publish(event.Event{Type: eventType, Subject: cn, Data: map[string]string{"uid": uid, "ou": security.OuGroupAdditional.String()}})
+	return nil
+}
+
+func validateLanguage(language string) error {
+	if language != "" && !slices.Contains(mail.Languages, language) {
+		return WrapError(ErrInvalid, fmt.Sprintf("unsupported language: %s", language))
+	}
 	return nil
 }
@@ -0,0 +1,335 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"asynclab.club/asynx/backend/pkg/client"
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/event"
+	"asynclab.club/asynx/backend/pkg/mail"
+	"asynclab.club/asynx/backend/pkg/outbox"
+	"asynclab.club/asynx/backend/pkg/repository"
+	"asynclab.club/asynx/backend/pkg/saga"
+	"asynclab.club/asynx/backend/pkg/security"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// newTestDirectory 创建与 DIRECTORY_BACKEND=memory 相同结构的内存目录
+func newTestDirectory(t *testing.T) *client.MemoryClient {
+	t.Helper()
+	baseDn := "dc=asynclab,dc=club"
+	userBaseDn := "ou=people," + baseDn
+	groupBaseDn := "ou=groups," + baseDn
+	mem, err := client.NewMemoryClient(baseDn, userBaseDn, groupBaseDn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	add := func(dn string, objectClass []string, attributes map[string][]string) {
+		if err := mem.Add(dn, objectClass, attributes); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ouClasses := []string{"organizationalUnit"}
+	add(userBaseDn, ouClasses, nil)
+	add(groupBaseDn, ouClasses, nil)
+	for _, ou := range []security.OuUser{security.OuUserSystem, security.OuUserMember, security.OuUserExternal, security.OuUserAlumni} {
+		add(fmt.Sprintf("ou=%s,%s", ou, userBaseDn), ouClasses, nil)
+	}
+	for _, ou := range []security.OuGroup{security.OuGroupPrimary, security.OuGroupSupplementary, security.OuGroupAdditional} {
+		add(fmt.Sprintf("ou=%s,%s", ou, groupBaseDn), ouClasses, nil)
+	}
+	add(fmt.Sprintf("cn=users,ou=%s,%s", security.OuGroupPrimary, groupBaseDn), config.GroupObjectClasses,
+		map[string][]string{"gidNumber": {config.LdapGidNumber}})
+	for i, role := range []security.Role{security.RoleAdmin, security.RoleDefault, security.RoleRestricted} {
+		add(fmt.Sprintf("cn=%s,ou=%s,%s", role, security.OuGroupSupplementary, groupBaseDn), config.GroupObjectClasses,
+			map[string][]string{"gidNumber": {fmt.Sprint(20001 + i)}})
+	}
+	return mem
+}
+
+type testEnv struct {
+	manager   *ServiceManager
+	outbox    *outbox.Outbox
+	mails     *client.MemoryMailTransport
+	directory *client.MemoryClient
+}
+
+// newTestEnv 创建使用内存目录和内存邮件的 ServiceManager，所有状态只保存在内存中
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	directory := newTestDirectory(t)
+	store := repository.NewStoreLdap(directory, "")
+
+	mails := client.NewMemoryMailTransport(100)
+	emailClient, err := client.NewEmailClient(&config.ConfigEmail{Transport: "memory"}, mails)
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates, err := mail.NewTemplates(&config.ConfigMailTemplate{DefaultLanguage: "zh", SiteUrl: "https://asynx.example.org/"}, os.DirFS("../../../templates/mail"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mailOutbox, err := outbox.NewOutbox(&config.ConfigOutbox{MaxAttempts: 1}, "", emailClient, templates)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	coordinator, err := saga.NewCoordinator("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	notification := NewServiceNotification(store.Shared(), mailOutbox)
+	return &testEnv{
+		manager:   NewServiceManager(store, coordinator, notification, NewServiceDelegation(store.Shared()), event.NewBus()),
+		outbox:    mailOutbox,
+		mails:     mails,
+		directory: directory,
+	}
+}
+
+func registerMember(t *testing.T, manager *ServiceManager, uid, roleName string) {
+	t.Helper()
+	if err := manager.Register(uid, "张", "三", uid+"@example.org", security.OuUserMember.String(), roleName, "zh", nil); err != nil {
+		t.Fatalf("register %s: %v", uid, err)
+	}
+}
+
+func roleMembers(t *testing.T, group *ServiceGroup, role security.Role) []string {
+	t.Helper()
+	g, err := group.FindByOuAndCn(security.OuGroupSupplementary, role.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g.MemberUid
+}
+
+func TestRegisterCreatesAccountWithRole(t *testing.T) {
+	manager := newTestEnv(t).manager
+	registerMember(t, manager, "2024000001", "default")
+	registerMember(t, manager, "2024000002", "default")
+
+	user, err := manager.serviceUser.FindByUid("2024000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Ou != security.OuUserMember.String() || user.Mail != "2024000001@example.org" || user.PreferredLanguage != "zh" {
+		t.Errorf("unexpected user %+v", user)
+	}
+	other, err := manager.serviceUser.FindByUid("2024000002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.UidNumber == "" || user.UidNumber == other.UidNumber {
+		t.Errorf("uidNumbers %q and %q should be distinct", user.UidNumber, other.UidNumber)
+	}
+	if role, err := manager.GetRole(user); err != nil || role != security.RoleDefault {
+		t.Errorf("got role %s, %v, want default", role, err)
+	}
+}
+
+func TestRegisterRejectsInvalidAndDuplicate(t *testing.T) {
+	manager := newTestEnv(t).manager
+	registerMember(t, manager, "2024000001", "default")
+
+	err := manager.Register("2024000001", "李", "四", "other@example.org", security.OuUserMember.String(), "default", "", nil)
+	if !errors.Is(err, ErrExists) {
+		t.Errorf("duplicate uid: got %v, want ErrExists", err)
+	}
+	err = manager.Register("2024000002", "李", "四", "not-a-mail", security.OuUserMember.String(), "default", "", nil)
+	if !errors.Is(err, ErrInvalid) {
+		t.Errorf("invalid mail: got %v, want ErrInvalid", err)
+	}
+	err = manager.Register("2024000003", "李", "四", "li@example.org", security.OuUserMember.String(), "no-such-role", "", nil)
+	if !errors.Is(err, ErrInvalid) {
+		t.Errorf("unknown role: got %v, want ErrInvalid", err)
+	}
+	if _, err := manager.serviceUser.FindByUid("2024000002"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("rejected registration created an account: %v", err)
+	}
+}
+
+func TestUnregisterRemovesAccountAndRole(t *testing.T) {
+	manager := newTestEnv(t).manager
+	registerMember(t, manager, "2024000001", "default")
+
+	if err := manager.Unregister("2024000001"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.serviceUser.FindByUid("2024000001"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v after unregister, want ErrNotFound", err)
+	}
+	if members := roleMembers(t, manager.serviceGroup, security.RoleDefault); slices.Contains(members, "2024000001") {
+		t.Errorf("unregistered user is still in the default group: %v", members)
+	}
+	if err := manager.Unregister("2024000001"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unregister twice: got %v, want ErrNotFound", err)
+	}
+
+	// 注销后可以用同一学号重新注册
+	registerMember(t, manager, "2024000001", "restricted")
+}
+
+func TestGrantRoleByUid(t *testing.T) {
+	manager := newTestEnv(t).manager
+	group := manager.serviceGroup
+	registerMember(t, manager, "2024000001", "default")
+	registerMember(t, manager, "2024000002", "default")
+
+	// 切换角色时离开旧组、加入新组，不影响组内其他成员
+	if err := group.GrantRoleByUid("2024000001", security.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if role, _ := group.GetRoleByUid("2024000001"); role != security.RoleAdmin {
+		t.Errorf("got role %s, want admin", role)
+	}
+	if members := roleMembers(t, group, security.RoleDefault); !slices.Equal(members, []string{"2024000002"}) {
+		t.Errorf("default group members %v, want only 2024000002", members)
+	}
+
+	// 角色不变时不做修改
+	if err := group.GrantRoleByUid("2024000001", security.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if members := roleMembers(t, group, security.RoleAdmin); !slices.Equal(members, []string{"2024000001"}) {
+		t.Errorf("admin group members %v, want only 2024000001", members)
+	}
+
+	// 授予匿名角色即移出所有角色组，之后可以直接加入新组
+	if err := group.GrantRoleByUid("2024000001", security.RoleAnonymous); err != nil {
+		t.Fatal(err)
+	}
+	if role, _ := group.GetRoleByUid("2024000001"); role != security.RoleAnonymous {
+		t.Errorf("got role %s, want anonymous", role)
+	}
+	if err := group.GrantRoleByUid("2024000001", security.RoleRestricted); err != nil {
+		t.Fatal(err)
+	}
+	if role, _ := group.GetRoleByUid("2024000001"); role != security.RoleRestricted {
+		t.Errorf("got role %s, want restricted", role)
+	}
+}
+
+// deliveredMails 投递发件箱中的邮件，返回内存邮件中发给 to 的邮件
+func (e *testEnv) deliveredMails(to string) []client.Mail {
+	e.outbox.Flush(context.Background())
+	return e.mails.List(to)
+}
+
+func TestRegisterSendsWelcomeMail(t *testing.T) {
+	env := newTestEnv(t)
+	registerMember(t, env.manager, "2024000001", "default")
+
+	mails := env.deliveredMails("2024000001@example.org")
+	if len(mails) != 1 {
+		t.Fatalf("got %d mails, want one welcome mail", len(mails))
+	}
+	welcome := mails[0]
+	if welcome.Subject != "异步实验室 - 账号注册成功" {
+		t.Errorf("got subject %q", welcome.Subject)
+	}
+	if !strings.Contains(welcome.Text, "张三，你好") || !strings.Contains(welcome.Text, "登录账号：2024000001") {
+		t.Errorf("welcome mail does not address the user:\n%s", welcome.Text)
+	}
+	if !strings.Contains(welcome.Html, "2024000001") {
+		t.Error("html part does not contain the username")
+	}
+
+	// 邮件中的临时密码就是账号的初始密码
+	match := regexp.MustCompile(`临时密码：(\S+)`).FindStringSubmatch(welcome.Text)
+	if match == nil {
+		t.Fatalf("welcome mail has no temporary password:\n%s", welcome.Text)
+	}
+	if ok, err := env.manager.serviceUser.Authenticate("2024000001", match[1]); err != nil || !ok {
+		t.Errorf("temporary password from the mail does not authenticate: %v, %v", ok, err)
+	}
+}
+
+func TestRegisterWithPasswordSendsWelcomeMailWithoutPassword(t *testing.T) {
+	env := newTestEnv(t)
+	password := "Str0ng-Passw0rd!xy"
+	if err := env.manager.RegisterWithPassword("2024000001", "Zhang", "San", "zs@example.org", security.OuUserMember.String(), "default", "en", password); err != nil {
+		t.Fatal(err)
+	}
+
+	mails := env.deliveredMails("zs@example.org")
+	if len(mails) != 1 {
+		t.Fatalf("got %d mails, want one welcome mail", len(mails))
+	}
+	welcome := mails[0]
+	if welcome.Subject != "AsyncLab - Your account is ready" {
+		t.Errorf("got subject %q, want the english welcome mail", welcome.Subject)
+	}
+	if strings.Contains(welcome.Text, "Temporary password") || strings.Contains(welcome.Text, password) || strings.Contains(welcome.Html, password) {
+		t.Errorf("welcome mail contains a password:\n%s", welcome.Text)
+	}
+	if len(env.deliveredMails("")) != 1 {
+		t.Error("registration sent mails to other addresses")
+	}
+}
+
+// countingDirectory 记录对目录的查询次数
+type countingDirectory struct {
+	client.DirectoryClient
+	searches atomic.Int64
+}
+
+func (d *countingDirectory) Search(baseDN string, filter string, attributes []string) (*ldap.SearchResult, error) {
+	d.searches.Add(1)
+	return d.DirectoryClient.Search(baseDN, filter, attributes)
+}
+
+func TestResolveSessionCachesRoleUntilChanged(t *testing.T) {
+	env := newTestEnv(t)
+	registerMember(t, env.manager, "2024000001", "admin")
+
+	directory := &countingDirectory{DirectoryClient: env.directory}
+	coordinator, err := saga.NewCoordinator("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := event.NewBus()
+	manager := NewServiceManager(repository.NewStoreLdap(directory, ""), coordinator, env.manager.notification, env.manager.delegation, bus)
+
+	resolve := func(want security.Role) int64 {
+		t.Helper()
+		before := directory.searches.Load()
+		for range 10 {
+			if role, err := manager.ResolveSession("2024000001"); err != nil || role != want {
+				t.Fatalf("got %v, %v, want %v", role, err, want)
+			}
+		}
+		return directory.searches.Load() - before
+	}
+
+	if searches := resolve(security.RoleAdmin); searches == 0 {
+		t.Fatal("first request did not read the directory")
+	}
+	if searches := resolve(security.RoleAdmin); searches != 0 {
+		t.Errorf("cached session read the directory %d times", searches)
+	}
+
+	// 降级立即生效，之后的请求重新使用缓存
+	if err := manager.GrantRoleByUidAndRoleName("2024000001", "restricted"); err != nil {
+		t.Fatal(err)
+	}
+	resolve(security.RoleRestricted)
+	if searches := resolve(security.RoleRestricted); searches != 0 {
+		t.Errorf("cached session read the directory %d times after the downgrade", searches)
+	}
+
+	// 目录监听发现的外部修改同样清空缓存
+	if err := env.manager.GrantRoleByUidAndRoleName("2024000001", "default"); err != nil {
+		t.Fatal(err)
+	}
+	bus.Publish(event.Event{Type: event.UserRoleChanged, Subject: "2024000001", Source: event.SourceDirectory})
+	resolve(security.RoleDefault)
+}
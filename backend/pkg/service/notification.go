@@ -0,0 +1,188 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/entity"
+	"asynclab.club/asynx/backend/pkg/mail"
+	"asynclab.club/asynx/backend/pkg/outbox"
+	"asynclab.club/asynx/backend/pkg/persist"
+	"github.com/sirupsen/logrus"
+)
+
+// 可由用户关闭的通知
+var optionalNotifications = []mail.Template{mail.TemplateRoleChanged, mail.TemplateRoleExpiring, mail.TemplateCategoryChanged, mail.TemplateAccountDeleted}
+
+// 与账号安全相关、不允许关闭的通知
+var mandatoryNotifications = []mail.Template{mail.TemplatePasswordReset, mail.TemplateAccountExpiring, mail.TemplateAlumniTransition}
+
+type NotificationSetting struct {
+	Kind      mail.Template `json:"kind"`
+	Enabled   bool          `json:"enabled"`
+	Mandatory bool          `json:"mandatory"`
+}
+
+type notificationPreference struct {
+	Disabled []mail.Template `json:"disabled"`
+}
+
+// ServiceNotification 负责向用户发送账号相关的通知邮件
+type ServiceNotification struct {
+	outbox      *outbox.Outbox
+	preferences *persist.SharedCollection[notificationPreference]
+}
+
+// NewServiceNotification 创建通知服务，通知设置保存在共享存储中，所有实例看到相同的设置
+func NewServiceNotification(shared persist.SharedBackend, outbox *outbox.Outbox) *ServiceNotification {
+	return &ServiceNotification{
+		outbox:      outbox,
+		preferences: persist.NewSharedCollection[notificationPreference](shared, "notification-preferences"),
+	}
+}
+
+func (s *ServiceNotification) GetSettings(uid string) ([]NotificationSetting, error) {
+	pref, _, err := s.preferences.Get(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := make([]NotificationSetting, 0, len(optionalNotifications)+len(mandatoryNotifications))
+	for _, kind := range mandatoryNotifications {
+		settings = append(settings, NotificationSetting{Kind: kind, Enabled: true, Mandatory: true})
+	}
+	for _, kind := range optionalNotifications {
+		settings = append(settings, NotificationSetting{Kind: kind, Enabled: !slices.Contains(pref.Disabled, kind)})
+	}
+	return settings, nil
+}
+
+// SetDisabled 用 disabled 整体替换用户关闭的通知
+func (s *ServiceNotification) SetDisabled(uid string, disabled []string) error {
+	pref := notificationPreference{Disabled: make([]mail.Template, 0, len(disabled))}
+	for _, name := range disabled {
+		kind := mail.Template(name)
+		if slices.Contains(mandatoryNotifications, kind) {
+			return WrapError(ErrInvalid, fmt.Sprintf("notification %s cannot be disabled", name))
+		}
+		if !slices.Contains(optionalNotifications, kind) {
+			return WrapError(ErrInvalid, fmt.Sprintf("unknown notification: %s", name))
+		}
+		if !slices.Contains(pref.Disabled, kind) {
+			pref.Disabled = append(pref.Disabled, kind)
+		}
+	}
+
+	if len(pref.Disabled) == 0 {
+		return s.preferences.Delete(uid)
+	}
+	return s.preferences.Put(uid, pref)
+}
+
+// Forget 删除用户的通知设置，应在账号删除后调用
+func (s *ServiceNotification) Forget(uid string) {
+	if err := s.preferences.Delete(uid); err != nil {
+		logrus.Warnf("Failed to remove notification preferences of %s: %v", uid, err)
+	}
+}
+
+func (s *ServiceNotification) enqueue(user *entity.User, kind mail.Template, data any) error {
+	if user.Mail == "" {
+		return fmt.Errorf("user %s has no mail address", user.Uid)
+	}
+	_, err := s.outbox.Enqueue(user.Mail, kind, user.PreferredLanguage, data)
+	return err
+}
+
+// notify 发送一条通知，失败只记录日志，不影响触发通知的操作
+func (s *ServiceNotification) notify(user *entity.User, kind mail.Template, data any) {
+	if slices.Contains(optionalNotifications, kind) {
+		pref, ok, err := s.preferences.Get(user.Uid)
+		if err != nil {
+			// 读取设置失败时仍然发送，宁可多发也不漏发
+			logrus.Warnf("Failed to read notification preferences of %s: %v", user.Uid, err)
+		} else if ok && slices.Contains(pref.Disabled, kind) {
+			logrus.Debugf("Notification %s to %s skipped by preference", kind, user.Uid)
+			return
+		}
+	}
+
+	if err := s.enqueue(user, kind, data); err != nil {
+		logrus.Warnf("Failed to notify %s of %s: %v", user.Uid, kind, err)
+	}
+}
+
+// Welcome 发送包含初始密码的欢迎邮件，失败时返回错误以便回滚注册。chosen 表示密码由用户自己设置，邮件中不附带密码
+func (s *ServiceNotification) Welcome(user *entity.User, password string, chosen bool) error {
+	if password == "" && !chosen {
+		return errors.New("initial password is not available")
+	}
+	return s.enqueue(user, mail.TemplateWelcome, mail.Welcome{Account: mailAccount(user), Password: password})
+}
+
+func (s *ServiceNotification) RoleChanged(user *entity.User, from, to string) {
+	s.notify(user, mail.TemplateRoleChanged, mail.RoleChanged{Account: mailAccount(user), From: from, To: to})
+}
+
+func (s *ServiceNotification) RoleExpiring(user *entity.User, role, previous string, expiresAt time.Time) {
+	s.notify(user, mail.TemplateRoleExpiring, mail.RoleExpiring{
+		Account:   mailAccount(user),
+		Role:      role,
+		Previous:  previous,
+		ExpiresAt: expiresAt.Format("2006-01-02 15:04 MST"),
+	})
+}
+
+func (s *ServiceNotification) AccountExpiring(user *entity.User, expiresAt time.Time) {
+	s.notify(user, mail.TemplateAccountExpiring, mail.AccountExpiring{
+		Account:   mailAccount(user),
+		ExpiresAt: expiresAt.Format("2006-01-02 15:04 MST"),
+	})
+}
+
+func (s *ServiceNotification) AlumniTransition(user *entity.User, date time.Time, role string) {
+	s.notify(user, mail.TemplateAlumniTransition, mail.AlumniTransition{
+		Account: mailAccount(user),
+		Date:    date.Format(time.DateOnly),
+		Role:    role,
+	})
+}
+
+func (s *ServiceNotification) CategoryChanged(user *entity.User, from, to string) {
+	s.notify(user, mail.TemplateCategoryChanged, mail.CategoryChanged{Account: mailAccount(user), From: from, To: to})
+}
+
+// PasswordReset 在他人修改了用户的密码时通知用户，新密码不会出现在邮件中
+func (s *ServiceNotification) PasswordReset(user *entity.User) {
+	s.notify(user, mail.TemplatePasswordReset, mail.PasswordReset{Account: mailAccount(user)})
+}
+
+func (s *ServiceNotification) AccountDeleted(user *entity.User) {
+	s.notify(user, mail.TemplateAccountDeleted, mail.AccountDeleted{Account: mailAccount(user)})
+}
+
+// RegistrationVerify 向自助注册的申请人发送验证邮箱的链接，申请人还没有账号，不受通知设置影响
+func (s *ServiceNotification) RegistrationVerify(request *RegistrationRequest, link string, expiresAt time.Time) error {
+	_, err := s.outbox.Enqueue(request.Mail, mail.TemplateRegistrationVerify, request.Language, mail.RegistrationVerify{
+		Account:   mail.Account{Surname: request.SurName, GivenName: request.GivenName, Username: request.Username},
+		Link:      link,
+		ExpiresAt: expiresAt.Format("2006-01-02 15:04 MST"),
+	})
+	return err
+}
+
+// InvitationVerify 向使用限定域名邀请的受邀人发送验证邮箱的链接，受邀人还没有账号，不受通知设置影响
+func (s *ServiceNotification) InvitationVerify(verification *InvitationVerification, language string, link string) error {
+	_, err := s.outbox.Enqueue(verification.Mail, mail.TemplateInvitationVerify, language, mail.InvitationVerify{
+		Account:   mail.Account{Surname: verification.SurName, GivenName: verification.GivenName},
+		Link:      link,
+		ExpiresAt: verification.ExpiresAt.Format("2006-01-02 15:04 MST"),
+	})
+	return err
+}
+
+func mailAccount(user *entity.User) mail.Account {
+	return mail.Account{Surname: user.Sn, GivenName: user.GivenName, Username: user.Uid}
+}
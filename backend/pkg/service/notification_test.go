@@ -0,0 +1,54 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"asynclab.club/asynx/backend/pkg/mail"
+	"asynclab.club/asynx/backend/pkg/persist"
+)
+
+func notificationEnabled(t *testing.T, s *ServiceNotification, uid string, kind mail.Template) bool {
+	t.Helper()
+	settings, err := s.GetSettings(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, setting := range settings {
+		if setting.Kind == kind {
+			return setting.Enabled
+		}
+	}
+	t.Fatalf("notification %s not listed", kind)
+	return false
+}
+
+func TestNotificationPreferencesAreSharedAcrossInstances(t *testing.T) {
+	shared := persist.NewMemoryBackend()
+	replicaA := NewServiceNotification(shared, nil)
+	replicaB := NewServiceNotification(shared, nil)
+
+	if err := replicaA.SetDisabled("2024000001", []string{mail.TemplateRoleChanged.String()}); err != nil {
+		t.Fatal(err)
+	}
+	if notificationEnabled(t, replicaB, "2024000001", mail.TemplateRoleChanged) {
+		t.Error("preference set on one instance is not seen by another")
+	}
+
+	replicaB.Forget("2024000001")
+	if !notificationEnabled(t, replicaA, "2024000001", mail.TemplateRoleChanged) {
+		t.Error("preference kept after the account was forgotten")
+	}
+}
+
+func TestMandatoryNotificationCannotBeDisabled(t *testing.T) {
+	s := NewServiceNotification(persist.NewMemoryBackend(), nil)
+	for _, name := range []string{mail.TemplatePasswordReset.String(), "unknown"} {
+		if err := s.SetDisabled("2024000001", []string{name}); !errors.Is(err, ErrInvalid) {
+			t.Errorf("disabling %s: got %v, want ErrInvalid", name, err)
+		}
+	}
+	if !notificationEnabled(t, s, "2024000001", mail.TemplatePasswordReset) {
+		t.Error("mandatory notification reported as disabled")
+	}
+}
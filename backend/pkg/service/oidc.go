@@ -0,0 +1,633 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/event"
+	"asynclab.club/asynx/backend/pkg/oidc"
+	"asynclab.club/asynx/backend/pkg/security"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// 前端的授权确认页面，授权请求ID通过 request 查询参数传递
+const OidcConsentPath = "/oauth/consent"
+
+type ServiceOidc struct {
+	provider     *oidc.Provider
+	serviceUser  *ServiceUser
+	serviceGroup *ServiceGroup
+}
+
+func NewServiceOidc(provider *oidc.Provider, manager *ServiceManager, bus *event.Bus) *ServiceOidc {
+	s := &ServiceOidc{
+		provider:     provider,
+		serviceUser:  manager.serviceUser,
+		serviceGroup: manager.serviceGroup,
+	}
+	// 用户被删除后，同名的新用户不应继承原来的授权
+	bus.Subscribe(func(e event.Event) {
+		if e.Type != event.UserDeleted {
+			return
+		}
+		if err := provider.ForgetUser(e.Subject); err != nil {
+			logrus.Errorf("Failed to remove OIDC consents of %s: %v", e.Subject, err)
+		}
+	})
+	return s
+}
+
+func (s *ServiceOidc) Discovery() *oidc.Discovery { return s.provider.Discovery() }
+
+func (s *ServiceOidc) Jwks() map[string][]oidc.Jwk { return s.provider.Jwks() }
+
+// Purge 清理过期的授权请求和授权码
+func (s *ServiceOidc) Purge(now time.Time) (int, error) { return s.provider.Purge(now) }
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+type OidcClientSpec struct {
+	Name                   string   `json:"name" binding:"required"`
+	RedirectUris           []string `json:"redirectUris" binding:"required"`
+	PostLogoutRedirectUris []string `json:"postLogoutRedirectUris"`
+	Roles                  []string `json:"roles"`
+	Public                 bool     `json:"public"`
+	SkipConsent            bool     `json:"skipConsent"`
+}
+
+func validateClientSpec(spec *OidcClientSpec) error {
+	if strings.TrimSpace(spec.Name) == "" {
+		return WrapError(ErrInvalid, "client name is required")
+	}
+	if len(spec.RedirectUris) == 0 {
+		return WrapError(ErrInvalid, "at least one redirect uri is required")
+	}
+	for _, uri := range slices.Concat(spec.RedirectUris, spec.PostLogoutRedirectUris) {
+		u, err := url.Parse(uri)
+		if err != nil || u.Scheme == "" || u.Fragment != "" || (u.Host == "" && (u.Scheme == "http" || u.Scheme == "https")) {
+			return WrapError(ErrInvalid, fmt.Sprintf("invalid redirect uri: %s", uri))
+		}
+	}
+	for _, role := range spec.Roles {
+		if _, err := security.GetRoleFromName(role); err != nil {
+			return WrapError(ErrInvalid, err.Error())
+		}
+	}
+	return nil
+}
+
+// withoutClientSecret 隐藏密钥和摘要，密钥只在创建或轮换时返回一次
+func withoutClientSecret(client oidc.Client) *oidc.Client {
+	client.Secret = ""
+	client.SecretHash = ""
+	return &client
+}
+
+func (s *ServiceOidc) ListClients() ([]*oidc.Client, error) {
+	clients, err := s.provider.ListClients()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*oidc.Client, 0, len(clients))
+	for _, client := range clients {
+		result = append(result, withoutClientSecret(client))
+	}
+	return result, nil
+}
+
+func (s *ServiceOidc) getClient(id string) (oidc.Client, error) {
+	client, ok, err := s.provider.GetClient(id)
+	if err != nil {
+		return client, err
+	}
+	if !ok {
+		return client, WrapError(ErrNotFound, fmt.Sprintf("client %s not found", id))
+	}
+	return client, nil
+}
+
+func (s *ServiceOidc) GetClient(id string) (*oidc.Client, error) {
+	client, err := s.getClient(id)
+	if err != nil {
+		return nil, err
+	}
+	return withoutClientSecret(client), nil
+}
+
+// CreateClient 注册客户端，机密客户端的密钥只在本次返回
+func (s *ServiceOidc) CreateClient(spec *OidcClientSpec) (*oidc.Client, error) {
+	if err := validateClientSpec(spec); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	client := oidc.Client{
+		Id:        uuid.NewString(),
+		CreatedAt: now,
+	}
+	applyClientSpec(&client, spec, now)
+	if !client.Public {
+		if err := rotateClientSecret(&client); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.provider.PutClient(client); err != nil {
+		return nil, err
+	}
+	client.SecretHash = ""
+	return &client, nil
+}
+
+// UpdateClient 修改客户端，rotateSecret 为 true 或由公共客户端改为机密客户端时生成新密钥并在返回值中给出
+func (s *ServiceOidc) UpdateClient(id string, spec *OidcClientSpec, rotateSecret bool) (*oidc.Client, error) {
+	client, err := s.getClient(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateClientSpec(spec); err != nil {
+		return nil, err
+	}
+
+	applyClientSpec(&client, spec, time.Now())
+	rotate := !client.Public && (rotateSecret || client.SecretHash == "")
+	if client.Public {
+		client.SecretHash = ""
+	} else if rotate {
+		if err := rotateClientSecret(&client); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.provider.PutClient(client); err != nil {
+		return nil, err
+	}
+
+	if rotate {
+		client.SecretHash = ""
+		return &client, nil
+	}
+	return withoutClientSecret(client), nil
+}
+
+func applyClientSpec(client *oidc.Client, spec *OidcClientSpec, now time.Time) {
+	client.Name = strings.TrimSpace(spec.Name)
+	client.RedirectUris = spec.RedirectUris
+	client.PostLogoutRedirectUris = spec.PostLogoutRedirectUris
+	client.Roles = spec.Roles
+	client.Public = spec.Public
+	client.SkipConsent = spec.SkipConsent
+	client.UpdatedAt = now
+}
+
+func rotateClientSecret(client *oidc.Client) error {
+	secret, err := generateSecret()
+	if err != nil {
+		return err
+	}
+	client.Secret = secret
+	client.SecretHash = oidc.HashSecret(secret)
+	return nil
+}
+
+func (s *ServiceOidc) DeleteClient(id string) error {
+	if _, err := s.getClient(id); err != nil {
+		return err
+	}
+	return s.provider.DeleteClient(id)
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+// buildRedirect 在回跳地址上追加查询参数
+func buildRedirect(uri string, params url.Values) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	query := u.Query()
+	for k, values := range params {
+		for _, v := range values {
+			if v != "" {
+				query.Add(k, v)
+			}
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func (s *ServiceOidc) errorRedirect(request *oidc.AuthorizationRequest, err *oidc.Error) string {
+	return buildRedirect(request.RedirectUri, url.Values{
+		"error":             {err.Code},
+		"error_description": {err.Description},
+		"state":             {request.State},
+		"iss":               {s.provider.Issuer()},
+	})
+}
+
+func (s *ServiceOidc) approve(request *oidc.AuthorizationRequest, uid string) (string, error) {
+	code, err := s.provider.IssueCode(*request, uid)
+	if err != nil {
+		return "", err
+	}
+	return buildRedirect(request.RedirectUri, url.Values{
+		"code":  {code},
+		"state": {request.State},
+		"iss":   {s.provider.Issuer()},
+	}), nil
+}
+
+// checkRole 检查用户的角色是否允许登录该客户端，没有角色的用户不能登录任何客户端
+func (s *ServiceOidc) checkRole(client *oidc.Client, uid string) *oidc.Error {
+	user, err := s.serviceUser.FindByUid(uid)
+	if err != nil {
+		return oidc.NewError(oidc.ErrAccessDenied, "user not found")
+	}
+	if IsAccountExpired(user, time.Now()) {
+		return oidc.NewError(oidc.ErrAccessDenied, "account has expired")
+	}
+	role, err := s.serviceGroup.GetRole(user)
+	if err != nil || role == security.RoleAnonymous {
+		return oidc.NewError(oidc.ErrAccessDenied, "user has no role")
+	}
+	if len(client.Roles) > 0 && !slices.Contains(client.Roles, role.String()) {
+		return oidc.NewError(oidc.ErrAccessDenied, "role %s is not allowed to use %s", role, client.Name)
+	}
+	return nil
+}
+
+func (s *ServiceOidc) consentRequired(client *oidc.Client, request *oidc.AuthorizationRequest, uid string) (bool, error) {
+	if client.SkipConsent {
+		return false, nil
+	}
+	if request.ForceConsent {
+		return true, nil
+	}
+	consent, ok, err := s.provider.GetConsent(uid, client.Id)
+	if err != nil {
+		return false, err
+	}
+	return !ok || !consent.Covers(request.Scopes), nil
+}
+
+// Authorize 处理授权请求，返回应重定向到的地址。client_id 或 redirect_uri 无效时返回 *oidc.Error，
+// 不能重定向；其他错误通过 redirect_uri 返回给客户端。
+// sessionToken 是浏览器中前端保存的登录令牌，已登录且无需确认时直接签发授权码
+func (s *ServiceOidc) Authorize(params url.Values, sessionToken string) (string, error) {
+	client, ok, err := s.provider.GetClient(params.Get("client_id"))
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", oidc.NewError(oidc.ErrInvalidClient, "unknown client_id")
+	}
+	redirectUri := params.Get("redirect_uri")
+	if !slices.Contains(client.RedirectUris, redirectUri) {
+		return "", oidc.NewError(oidc.ErrInvalidRequest, "redirect_uri is not registered for this client")
+	}
+
+	request := &oidc.AuthorizationRequest{
+		ClientId:      client.Id,
+		RedirectUri:   redirectUri,
+		State:         params.Get("state"),
+		Nonce:         params.Get("nonce"),
+		CodeChallenge: params.Get("code_challenge"),
+	}
+
+	if params.Get("response_type") != "code" {
+		return s.errorRedirect(request, oidc.NewError(oidc.ErrUnsupportedResponseType, "only response_type=code is supported")), nil
+	}
+	if mode := params.Get("response_mode"); mode != "" && mode != "query" {
+		return s.errorRedirect(request, oidc.NewError(oidc.ErrInvalidRequest, "only response_mode=query is supported")), nil
+	}
+
+	// 未知的 scope 按规范忽略
+	for _, scope := range strings.Fields(params.Get("scope")) {
+		if slices.Contains(oidc.Scopes(), scope) && !slices.Contains(request.Scopes, scope) {
+			request.Scopes = append(request.Scopes, scope)
+		}
+	}
+	if !slices.Contains(request.Scopes, oidc.ScopeOpenId) {
+		return s.errorRedirect(request, oidc.NewError(oidc.ErrInvalidScope, "scope must include openid")), nil
+	}
+
+	if request.CodeChallenge != "" && params.Get("code_challenge_method") != "S256" {
+		return s.errorRedirect(request, oidc.NewError(oidc.ErrInvalidRequest, "only code_challenge_method=S256 is supported")), nil
+	}
+	if request.CodeChallenge == "" && client.Public {
+		return s.errorRedirect(request, oidc.NewError(oidc.ErrInvalidRequest, "public clients must use PKCE")), nil
+	}
+
+	prompts := strings.Fields(params.Get("prompt"))
+	request.ForceConsent = slices.Contains(prompts, "consent")
+
+	var uid string
+	if sessionToken != "" {
+		if claims, err := security.ParsePaseto(sessionToken); err == nil {
+			uid = claims.Uid
+		}
+	}
+
+	if slices.Contains(prompts, "none") {
+		if uid == "" {
+			return s.errorRedirect(request, oidc.NewError(oidc.ErrLoginRequired, "user is not logged in")), nil
+		}
+		if err := s.checkRole(&client, uid); err != nil {
+			return s.errorRedirect(request, err), nil
+		}
+		required, err := s.consentRequired(&client, request, uid)
+		if err != nil {
+			return "", err
+		}
+		if required {
+			return s.errorRedirect(request, oidc.NewError(oidc.ErrConsentRequired, "user has not authorized this client")), nil
+		}
+		return s.approve(request, uid)
+	}
+
+	if uid != "" && !slices.Contains(prompts, "login") && s.checkRole(&client, uid) == nil {
+		required, err := s.consentRequired(&client, request, uid)
+		if err != nil {
+			return "", err
+		}
+		if !required {
+			return s.approve(request, uid)
+		}
+	}
+
+	pending, err := s.provider.PutRequest(*request)
+	if err != nil {
+		return "", err
+	}
+	return OidcConsentPath + "?" + url.Values{"request": {pending.Id}}.Encode(), nil
+}
+
+type OidcAuthorizationPrompt struct {
+	Id              string    `json:"id"`
+	ClientId        string    `json:"clientId"`
+	ClientName      string    `json:"clientName"`
+	Scopes          []string  `json:"scopes"`
+	ConsentRequired bool      `json:"consentRequired"` // 为 false 时前端可以直接同意
+	ExpiresAt       time.Time `json:"expiresAt"`
+}
+
+func (s *ServiceOidc) getRequest(id string) (*oidc.AuthorizationRequest, *oidc.Client, error) {
+	request, ok, err := s.provider.GetRequest(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, WrapError(ErrNotFound, fmt.Sprintf("authorization request %s not found or expired", id))
+	}
+	client, err := s.getClient(request.ClientId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &request, &client, nil
+}
+
+// GetAuthorization 返回待确认的授权请求，供前端展示
+func (s *ServiceOidc) GetAuthorization(uid string, id string) (*OidcAuthorizationPrompt, error) {
+	request, client, err := s.getRequest(id)
+	if err != nil {
+		return nil, err
+	}
+	required, err := s.consentRequired(client, request, uid)
+	if err != nil {
+		return nil, err
+	}
+	return &OidcAuthorizationPrompt{
+		Id:              request.Id,
+		ClientId:        client.Id,
+		ClientName:      client.Name,
+		Scopes:          request.Scopes,
+		ConsentRequired: required,
+		ExpiresAt:       request.ExpiresAt,
+	}, nil
+}
+
+// DecideAuthorization 记录用户的决定并结束授权请求，返回客户端的回跳地址
+func (s *ServiceOidc) DecideAuthorization(uid string, id string, approve bool) (string, error) {
+	request, ok, err := s.provider.TakeRequest(id)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", WrapError(ErrNotFound, fmt.Sprintf("authorization request %s not found or expired", id))
+	}
+	client, err := s.getClient(request.ClientId)
+	if err != nil {
+		return "", err
+	}
+
+	if !approve {
+		return s.errorRedirect(&request, oidc.NewError(oidc.ErrAccessDenied, "user denied the request")), nil
+	}
+	if err := s.checkRole(&client, uid); err != nil {
+		return s.errorRedirect(&request, err), nil
+	}
+
+	if !client.SkipConsent {
+		consent, _, err := s.provider.GetConsent(uid, client.Id)
+		if err != nil {
+			return "", err
+		}
+		scopes := slices.Clone(consent.Scopes)
+		for _, scope := range request.Scopes {
+			if !slices.Contains(scopes, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+		err = s.provider.PutConsent(oidc.Consent{Uid: uid, ClientId: client.Id, Scopes: scopes, GrantedAt: time.Now()})
+		if err != nil {
+			return "", err
+		}
+	}
+	return s.approve(&request, uid)
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+// authenticateClient 校验客户端凭据，公共客户端只需要 client_id
+func (s *ServiceOidc) authenticateClient(clientId, clientSecret string) (*oidc.Client, error) {
+	client, ok, err := s.provider.GetClient(clientId)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, oidc.NewError(oidc.ErrInvalidClient, "unknown client")
+	}
+	if !client.Public && !client.VerifySecret(clientSecret) {
+		return nil, oidc.NewError(oidc.ErrInvalidClient, "client authentication failed")
+	}
+	return &client, nil
+}
+
+// Token 用授权码换取令牌
+func (s *ServiceOidc) Token(form url.Values, clientId, clientSecret string) (*oidc.TokenResponse, error) {
+	if form.Get("grant_type") != "authorization_code" {
+		return nil, oidc.NewError(oidc.ErrUnsupportedGrantType, "only authorization_code is supported")
+	}
+	client, err := s.authenticateClient(clientId, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	request, uid, err := s.provider.ExchangeCode(form.Get("code"), client.Id, form.Get("redirect_uri"), form.Get("code_verifier"))
+	if err != nil {
+		return nil, err
+	}
+	// 签发授权码之后角色可能已经改变
+	if err := s.checkRole(client, uid); err != nil {
+		return nil, oidc.NewError(oidc.ErrInvalidGrant, "%s", err.Description)
+	}
+
+	claims, err := s.claims(uid, request.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	return s.provider.IssueTokens(request, uid, claims)
+}
+
+// UserInfo 返回访问令牌对应用户的最新声明
+func (s *ServiceOidc) UserInfo(accessToken string) (map[string]any, error) {
+	claims, err := s.provider.ParseAccessToken(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok, err := s.provider.GetClient(claims.ClientId); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, oidc.NewError(oidc.ErrInvalidToken, "client no longer exists")
+	}
+
+	user, err := s.serviceUser.FindByUid(claims.Subject)
+	if errors.Is(err, ErrNotFound) {
+		return nil, oidc.NewError(oidc.ErrInvalidToken, "user no longer exists")
+	}
+	if err != nil {
+		return nil, err
+	}
+	// 访问令牌签发后账号可能已经到期
+	if IsAccountExpired(user, time.Now()) {
+		return nil, oidc.NewError(oidc.ErrInvalidToken, "account has expired")
+	}
+
+	info, err := s.claims(claims.Subject, claims.Scopes())
+	if err != nil {
+		return nil, err
+	}
+	info["sub"] = claims.Subject
+	return info, nil
+}
+
+// claims 按 scope 返回用户声明，角色和分组来自目录中的角色组和附加组
+func (s *ServiceOidc) claims(uid string, scopes []string) (map[string]any, error) {
+	user, err := s.serviceUser.FindByUid(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := make(map[string]any)
+	if slices.Contains(scopes, oidc.ScopeProfile) {
+		claims["name"] = user.Sn + user.GivenName
+		claims["family_name"] = user.Sn
+		claims["given_name"] = user.GivenName
+		claims["preferred_username"] = user.Uid
+		if user.PreferredLanguage != "" {
+			claims["locale"] = user.PreferredLanguage
+		}
+	}
+	if slices.Contains(scopes, oidc.ScopeEmail) && user.Mail != "" {
+		claims["email"] = user.Mail
+	}
+	if slices.Contains(scopes, oidc.ScopeGroups) {
+		role, err := s.serviceGroup.GetRoleByUid(uid)
+		if err != nil {
+			return nil, err
+		}
+		groups, err := s.serviceGroup.FindCnsByMemberUid(uid)
+		if err != nil {
+			return nil, err
+		}
+		claims["role"] = role.String()
+		claims["category"] = user.Ou
+		claims["groups"] = groups
+	}
+	return claims, nil
+}
+
+// EndSession 处理 RP 发起的登出，返回登出后的跳转地址。只会跳转到客户端登记过的地址
+func (s *ServiceOidc) EndSession(params url.Values) (string, error) {
+	clientId := params.Get("client_id")
+	if hint := params.Get("id_token_hint"); hint != "" {
+		_, audience, err := s.provider.ParseIdTokenHint(hint)
+		if err != nil {
+			return "", err
+		}
+		if clientId != "" && clientId != audience {
+			return "", oidc.NewError(oidc.ErrInvalidRequest, "client_id does not match id_token_hint")
+		}
+		clientId = audience
+	}
+
+	redirectUri := params.Get("post_logout_redirect_uri")
+	if redirectUri == "" {
+		return "/login", nil
+	}
+	client, ok, err := s.provider.GetClient(clientId)
+	if err != nil {
+		return "", err
+	}
+	if !ok || !slices.Contains(client.PostLogoutRedirectUris, redirectUri) {
+		return "", oidc.NewError(oidc.ErrInvalidRequest, "post_logout_redirect_uri is not registered")
+	}
+	return buildRedirect(redirectUri, url.Values{"state": {params.Get("state")}}), nil
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+type OidcConsent struct {
+	ClientId   string    `json:"clientId"`
+	ClientName string    `json:"clientName"`
+	Scopes     []string  `json:"scopes"`
+	GrantedAt  time.Time `json:"grantedAt"`
+}
+
+func (s *ServiceOidc) ListConsents(uid string) ([]*OidcConsent, error) {
+	consents, err := s.provider.ListConsents(uid)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*OidcConsent, 0, len(consents))
+	for _, consent := range consents {
+		client, ok, err := s.provider.GetClient(consent.ClientId)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		result = append(result, &OidcConsent{
+			ClientId:   client.Id,
+			ClientName: client.Name,
+			Scopes:     consent.Scopes,
+			GrantedAt:  consent.GrantedAt,
+		})
+	}
+	return result, nil
+}
+
+// RevokeConsent 撤销授权，已签发的令牌在过期前仍然有效
+func (s *ServiceOidc) RevokeConsent(uid string, clientId string) error {
+	if _, ok, err := s.provider.GetConsent(uid, clientId); err != nil {
+		return err
+	} else if !ok {
+		return WrapError(ErrNotFound, fmt.Sprintf("consent for client %s not found", clientId))
+	}
+	return s.provider.DeleteConsent(uid, clientId)
+}
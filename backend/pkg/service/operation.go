@@ -0,0 +1,43 @@
+package service
+
+import (
+	"fmt"
+
+	"asynclab.club/asynx/backend/pkg/saga"
+)
+
+type ServiceOperation struct {
+	coordinator *saga.Coordinator
+}
+
+func NewServiceOperation(coordinator *saga.Coordinator) *ServiceOperation {
+	return &ServiceOperation{coordinator: coordinator}
+}
+
+func (s *ServiceOperation) List() []saga.Operation {
+	return s.coordinator.List()
+}
+
+func (s *ServiceOperation) Get(id string) (*saga.Operation, error) {
+	op, ok := s.coordinator.Get(id)
+	if !ok {
+		return nil, WrapError(ErrNotFound, fmt.Sprintf("operation %s not found", id))
+	}
+	return &op, nil
+}
+
+func (s *ServiceOperation) Retry(id string) error {
+	op, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	if op.State != saga.OperationFailed {
+		return WrapError(ErrInvalid, fmt.Sprintf("operation %s is %s, only failed operations can be retried", id, op.State))
+	}
+	return s.coordinator.Retry(id)
+}
+
+// Recover 处理上次进程退出时未完成的操作
+func (s *ServiceOperation) Recover() error {
+	return s.coordinator.Recover()
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"fmt"
+
+	"asynclab.club/asynx/backend/pkg/outbox"
+)
+
+type ServiceOutbox struct {
+	outbox *outbox.Outbox
+}
+
+func NewServiceOutbox(outbox *outbox.Outbox) *ServiceOutbox {
+	return &ServiceOutbox{outbox: outbox}
+}
+
+func (s *ServiceOutbox) List(stateName string) ([]outbox.Message, error) {
+	state := outbox.MessageState(stateName)
+	switch state {
+	case "", outbox.MessagePending, outbox.MessageSent, outbox.MessageDead:
+	default:
+		return nil, WrapError(ErrInvalid, fmt.Sprintf("unknown message state: %s", stateName))
+	}
+	return s.outbox.List(state), nil
+}
+
+func (s *ServiceOutbox) Get(id string) (*outbox.Message, error) {
+	msg, ok := s.outbox.Get(id)
+	if !ok {
+		return nil, WrapError(ErrNotFound, fmt.Sprintf("message %s not found", id))
+	}
+	return &msg, nil
+}
+
+func (s *ServiceOutbox) Resend(id string) error {
+	msg, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	if msg.State == outbox.MessageSent {
+		return WrapError(ErrInvalid, fmt.Sprintf("message %s has already been sent", id))
+	}
+	return s.outbox.Resend(id)
+}
+
+func (s *ServiceOutbox) Delete(id string) error {
+	if _, err := s.Get(id); err != nil {
+		return err
+	}
+	return s.outbox.Delete(id)
+}
@@ -0,0 +1,407 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/persist"
+	"asynclab.club/asynx/backend/pkg/security"
+	"github.com/sirupsen/logrus"
+)
+
+type RegistrationStatus string
+
+const (
+	RegistrationUnverified RegistrationStatus = "unverified" // 等待申请人验证邮箱
+	RegistrationPending    RegistrationStatus = "pending"    // 等待管理员审核
+	RegistrationApproved   RegistrationStatus = "approved"
+	RegistrationRejected   RegistrationStatus = "rejected"
+)
+
+// RegistrationRequest 是自助注册申请，只保存验证令牌的摘要
+type RegistrationRequest struct {
+	Id           string             `json:"id"`
+	Username     string             `json:"username"`
+	SurName      string             `json:"surName"`
+	GivenName    string             `json:"givenName"`
+	Mail         string             `json:"mail"`
+	Language     string             `json:"language"`
+	Note         string             `json:"note"` // 申请人的附言
+	Status       RegistrationStatus `json:"status"`
+	ClientIp     string             `json:"clientIp"`
+	TokenHash    string             `json:"tokenHash,omitempty"`
+	CreatedAt    time.Time          `json:"createdAt"`
+	VerifiedAt   *time.Time         `json:"verifiedAt,omitempty"`
+	DecidedAt    *time.Time         `json:"decidedAt,omitempty"`
+	DecidedBy    string             `json:"decidedBy,omitempty"`
+	Category     string             `json:"category,omitempty"`     // 批准时选择的账号类型
+	Role         string             `json:"role,omitempty"`         // 批准时选择的角色
+	RejectReason string             `json:"rejectReason,omitempty"` // 拒绝的理由，只对管理员可见
+}
+
+type RegistrationSpec struct {
+	Username  string `json:"username" binding:"required"`
+	SurName   string `json:"surName" binding:"required"`
+	GivenName string `json:"givenName" binding:"required"`
+	Mail      string `json:"mail" binding:"required"`
+	Language  string `json:"language"`
+	Note      string `json:"note"`
+	Challenge string `json:"challenge"` // 由 GET /registration-requests/challenge 获取
+	Nonce     string `json:"nonce"`     // 使 SHA-256("<challenge>:<nonce>") 满足难度的解
+}
+
+type RegistrationChallenge struct {
+	Challenge  string    `json:"challenge"`
+	Difficulty int       `json:"difficulty"` // 要求的 SHA-256 前导零比特数
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// ServiceRegistration 处理自助注册申请：申请人提交并验证邮箱后，由管理员审核并创建账号
+type ServiceRegistration struct {
+	cfg          *config.ConfigRegistration
+	siteUrl      string
+	manager      *ServiceManager
+	notification *ServiceNotification
+	requests     *persist.SharedCollection[RegistrationRequest]
+	solved       *persist.SharedCollection[time.Time]   // 已使用的题目及其到期时间，防止同一个解被重复提交
+	attempts     *persist.SharedCollection[[]time.Time] // 每个 IP 在时间窗口内的提交时间
+}
+
+// NewServiceRegistration 创建注册服务，申请、已使用的题目和频率限制保存在共享存储中
+func NewServiceRegistration(cfg *config.ConfigRegistration, siteUrl string, manager *ServiceManager, notification *ServiceNotification) (*ServiceRegistration, error) {
+	if cfg.PowDifficulty < 0 || cfg.PowDifficulty > 32 {
+		return nil, fmt.Errorf("invalid REGISTRATION_POW_DIFFICULTY: %d, must be between 0 and 32", cfg.PowDifficulty)
+	}
+	return &ServiceRegistration{
+		cfg:          cfg,
+		siteUrl:      siteUrl,
+		manager:      manager,
+		notification: notification,
+		requests:     persist.NewSharedCollection[RegistrationRequest](manager.store.Shared(), "registration-requests"),
+		solved:       persist.NewSharedCollection[time.Time](manager.store.Shared(), "registration-challenges"),
+		attempts:     persist.NewSharedCollection[[]time.Time](manager.store.Shared(), "registration-attempts"),
+	}, nil
+}
+
+func withoutTokenHash(request RegistrationRequest) *RegistrationRequest {
+	request.TokenHash = ""
+	return &request
+}
+
+func hashTokenSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *ServiceRegistration) checkEnabled() error {
+	if !s.cfg.Enabled {
+		return WrapError(ErrDenied, "self-service registration is disabled")
+	}
+	return nil
+}
+
+// Challenge 生成工作量证明题目
+func (s *ServiceRegistration) Challenge() (*RegistrationChallenge, error) {
+	if err := s.checkEnabled(); err != nil {
+		return nil, err
+	}
+	challenge, expiresAt, err := security.GeneratePowChallenge(s.cfg.ChallengeTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &RegistrationChallenge{Challenge: challenge, Difficulty: s.cfg.PowDifficulty, ExpiresAt: expiresAt}, nil
+}
+
+// checkProofOfWork 校验工作量证明，每个题目只能使用一次
+func (s *ServiceRegistration) checkProofOfWork(challenge, nonce string, now time.Time) error {
+	if s.cfg.PowDifficulty == 0 {
+		return nil
+	}
+	expiresAt, err := security.VerifyPowChallenge(challenge, now)
+	if err != nil {
+		return WrapError(ErrInvalid, err.Error())
+	}
+	if !security.PowSolved(challenge, nonce, s.cfg.PowDifficulty) {
+		return WrapError(ErrInvalid, "proof of work does not meet the difficulty")
+	}
+
+	// 只有创建成功的实例能使用这个题目
+	id, _, _ := strings.Cut(challenge, ".")
+	err = s.solved.Create(id, expiresAt)
+	if errors.Is(err, persist.ErrExists) {
+		return WrapError(ErrInvalid, "challenge has already been used")
+	}
+	return err
+}
+
+// allow 记录一次提交，超出每个 IP 的频率限制时返回错误
+func (s *ServiceRegistration) allow(ip string, now time.Time) error {
+	if s.cfg.RateLimit <= 0 {
+		return nil
+	}
+	since := now.Add(-s.cfg.RateWindow)
+	for {
+		err := s.attempts.Update(ip, func(times *[]time.Time) error {
+			*times = slices.DeleteFunc(*times, func(t time.Time) bool { return t.Before(since) })
+			if len(*times) >= s.cfg.RateLimit {
+				return WrapError(ErrTooManyRequests, fmt.Sprintf("at most %d registration requests per %s are allowed", s.cfg.RateLimit, s.cfg.RateWindow))
+			}
+			*times = append(*times, now)
+			return nil
+		})
+		if !errors.Is(err, persist.ErrNotFound) {
+			return err
+		}
+		if err := s.attempts.Create(ip, []time.Time{now}); !errors.Is(err, persist.ErrExists) {
+			return err
+		}
+	}
+}
+
+// open 返回申请是否仍在进行中，已过期未验证的申请不算
+func (s *ServiceRegistration) open(request RegistrationRequest, now time.Time) bool {
+	switch request.Status {
+	case RegistrationPending:
+		return true
+	case RegistrationUnverified:
+		return now.Before(request.CreatedAt.Add(s.cfg.VerifyTTL))
+	default:
+		return false
+	}
+}
+
+// Submit 创建申请并向申请人发送验证邮件
+func (s *ServiceRegistration) Submit(spec *RegistrationSpec, clientIp string) (*RegistrationRequest, error) {
+	if err := s.checkEnabled(); err != nil {
+		return nil, err
+	}
+	surName, givenName := strings.TrimSpace(spec.SurName), strings.TrimSpace(spec.GivenName)
+	if surName == "" || givenName == "" {
+		return nil, WrapError(ErrInvalid, "surName and givenName are required")
+	}
+	if err := security.ValidateMemberUsernameLegality(spec.Username); err != nil {
+		return nil, WrapError(ErrInvalid, err.Error())
+	}
+	if err := security.ValidateEmailFormat(spec.Mail); err != nil {
+		return nil, WrapError(ErrInvalid, err.Error())
+	}
+	if err := validateLanguage(spec.Language); err != nil {
+		return nil, err
+	}
+
+	// 填写错误时不消耗题目
+	now := time.Now()
+	if err := s.checkProofOfWork(spec.Challenge, spec.Nonce, now); err != nil {
+		return nil, err
+	}
+	if err := s.allow(clientIp, now); err != nil {
+		return nil, err
+	}
+
+	_, err := s.manager.serviceUser.FindByUid(spec.Username)
+	if err == nil {
+		return nil, WrapError(ErrExists, fmt.Sprintf("user %s already exists", spec.Username))
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	requests, err := s.requests.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, request := range requests {
+		if s.open(request, now) && (request.Username == spec.Username || strings.EqualFold(request.Mail, spec.Mail)) {
+			return nil, WrapError(ErrExists, "a registration request with the same username or mail is in progress")
+		}
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	request := RegistrationRequest{
+		Id:        hex.EncodeToString(id),
+		Username:  spec.Username,
+		SurName:   surName,
+		GivenName: givenName,
+		Mail:      spec.Mail,
+		Language:  spec.Language,
+		Note:      strings.TrimSpace(spec.Note),
+		Status:    RegistrationUnverified,
+		ClientIp:  clientIp,
+		TokenHash: hashTokenSecret(secret),
+		CreatedAt: now,
+	}
+	if err := s.requests.Create(request.Id, request); err != nil {
+		return nil, err
+	}
+
+	link := strings.TrimSuffix(s.siteUrl, "/") + "/register?token=" + url.QueryEscape(request.Id+"."+secret)
+	if err := s.notification.RegistrationVerify(&request, link, now.Add(s.cfg.VerifyTTL)); err != nil {
+		if err := s.requests.Delete(request.Id); err != nil {
+			logrus.Warnf("Failed to remove registration request %s: %v", request.Id, err)
+		}
+		return nil, err
+	}
+	return withoutTokenHash(request), nil
+}
+
+// Verify 验证申请人的邮箱，验证后申请进入审核队列；重复验证不会报错
+func (s *ServiceRegistration) Verify(token string) (*RegistrationRequest, error) {
+	id, secret, _ := strings.Cut(token, ".")
+	request, ok, err := s.requests.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || subtle.ConstantTimeCompare([]byte(request.TokenHash), []byte(hashTokenSecret(secret))) != 1 {
+		return nil, WrapError(ErrInvalid, "invalid verification token")
+	}
+	if request.Status != RegistrationUnverified {
+		return withoutTokenHash(request), nil
+	}
+	now := time.Now()
+	if !s.open(request, now) {
+		return nil, WrapError(ErrInvalid, "verification link has expired, please submit a new request")
+	}
+
+	err = s.requests.Update(id, func(r *RegistrationRequest) error {
+		// 另一个实例可能已经处理了这个申请
+		if r.Status == RegistrationUnverified {
+			r.Status = RegistrationPending
+			r.VerifiedAt = &now
+		}
+		request = *r
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return withoutTokenHash(request), nil
+}
+
+// List 返回指定状态的申请，status 为空时返回全部，按提交时间排序
+func (s *ServiceRegistration) List(status string) ([]*RegistrationRequest, error) {
+	if status != "" && !slices.Contains([]RegistrationStatus{RegistrationUnverified, RegistrationPending, RegistrationApproved, RegistrationRejected}, RegistrationStatus(status)) {
+		return nil, WrapError(ErrInvalid, fmt.Sprintf("unknown registration status: %s", status))
+	}
+	requests, err := s.requests.List()
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(requests, func(a, b RegistrationRequest) int { return a.CreatedAt.Compare(b.CreatedAt) })
+
+	result := make([]*RegistrationRequest, 0, len(requests))
+	for _, request := range requests {
+		if status == "" || request.Status == RegistrationStatus(status) {
+			result = append(result, withoutTokenHash(request))
+		}
+	}
+	return result, nil
+}
+
+// Approve 按申请创建账号，账号类型和角色由管理员选择，初始密码通过欢迎邮件发送。
+// 创建账号失败时申请恢复为待审核
+func (s *ServiceRegistration) Approve(operator string, id string, category string, role string) (*RegistrationRequest, error) {
+	var request RegistrationRequest
+	now := time.Now()
+	err := s.requests.Update(id, func(r *RegistrationRequest) error {
+		if r.Status != RegistrationPending {
+			return WrapError(ErrConflict, fmt.Sprintf("registration request %s is %s", id, r.Status))
+		}
+		r.Status = RegistrationApproved
+		r.Category = category
+		r.Role = role
+		r.DecidedBy = operator
+		r.DecidedAt = &now
+		request = *r
+		return nil
+	})
+	if errors.Is(err, persist.ErrNotFound) {
+		return nil, WrapError(ErrNotFound, fmt.Sprintf("registration request %s not found", id))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.manager.Register(request.Username, request.SurName, request.GivenName, request.Mail, category, role, request.Language, nil)
+	if err != nil {
+		rollbackErr := s.requests.Update(id, func(r *RegistrationRequest) error {
+			r.Status = RegistrationPending
+			r.Category, r.Role, r.DecidedBy, r.DecidedAt = "", "", "", nil
+			return nil
+		})
+		if rollbackErr != nil {
+			logrus.Errorf("Failed to reset registration request %s to pending: %v", id, rollbackErr)
+		}
+		return nil, err
+	}
+	return withoutTokenHash(request), nil
+}
+
+// Reject 拒绝申请，尚未验证邮箱的申请也可以直接拒绝
+func (s *ServiceRegistration) Reject(operator string, id string, reason string) (*RegistrationRequest, error) {
+	var request RegistrationRequest
+	now := time.Now()
+	err := s.requests.Update(id, func(r *RegistrationRequest) error {
+		if r.Status != RegistrationPending && r.Status != RegistrationUnverified {
+			return WrapError(ErrConflict, fmt.Sprintf("registration request %s is %s", id, r.Status))
+		}
+		r.Status = RegistrationRejected
+		r.RejectReason = strings.TrimSpace(reason)
+		r.DecidedBy = operator
+		r.DecidedAt = &now
+		request = *r
+		return nil
+	})
+	if errors.Is(err, persist.ErrNotFound) {
+		return nil, WrapError(ErrNotFound, fmt.Sprintf("registration request %s not found", id))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return withoutTokenHash(request), nil
+}
+
+// Purge 删除过期的申请、题目和提交记录，返回删除的申请数量
+func (s *ServiceRegistration) Purge(now time.Time) (int, error) {
+	before := now.Add(-s.cfg.Retention)
+	n, err := s.requests.DeleteFunc(func(_ string, request RegistrationRequest) bool {
+		switch request.Status {
+		case RegistrationUnverified:
+			return !s.open(request, now)
+		case RegistrationApproved, RegistrationRejected:
+			return request.DecidedAt != nil && request.DecidedAt.Before(before)
+		default:
+			return false
+		}
+	})
+	if n > 0 {
+		logrus.Infof("Purged %d registration requests", n)
+	}
+	if err != nil {
+		return n, err
+	}
+
+	if _, err := s.solved.DeleteFunc(func(_ string, expiresAt time.Time) bool { return now.After(expiresAt) }); err != nil {
+		return n, err
+	}
+	since := now.Add(-s.cfg.RateWindow)
+	_, err = s.attempts.DeleteFunc(func(_ string, times []time.Time) bool {
+		return !slices.ContainsFunc(times, func(t time.Time) bool { return !t.Before(since) })
+	})
+	return n, err
+}
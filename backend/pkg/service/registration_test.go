@@ -0,0 +1,165 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"aidanwoods.dev/go-paseto"
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/security"
+)
+
+func newTestRegistrations(t *testing.T, env *testEnv, cfg *config.ConfigRegistration) (*ServiceRegistration, *ServiceRegistration) {
+	t.Helper()
+	replicaA, err := NewServiceRegistration(cfg, "https://asynx.example.org/", env.manager, env.manager.notification)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replicaB, err := NewServiceRegistration(cfg, "https://asynx.example.org/", env.manager, env.manager.notification)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return replicaA, replicaB
+}
+
+// verificationToken 从最近一封验证邮件中取出令牌
+func verificationToken(t *testing.T, env *testEnv, to string) string {
+	t.Helper()
+	mails := env.deliveredMails(to)
+	if len(mails) == 0 {
+		t.Fatalf("no verification mail to %s", to)
+	}
+	match := regexp.MustCompile(`token=([^\s"&]+)`).FindStringSubmatch(mails[0].Text)
+	if match == nil {
+		t.Fatalf("verification mail has no token:\n%s", mails[0].Text)
+	}
+	token, err := url.QueryUnescape(match[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestRegistrationRequestsAreSharedBetweenInstances(t *testing.T) {
+	env := newTestEnv(t)
+
+	// 申请人在实例 A 上提交，验证链接和审核请求落到实例 B
+	replicaA, replicaB := newTestRegistrations(t, env, &config.ConfigRegistration{Enabled: true, VerifyTTL: time.Hour, Retention: time.Hour})
+
+	request, err := replicaA.Submit(&RegistrationSpec{Username: "2024000001", SurName: "张", GivenName: "三", Mail: "zs@example.org"}, "203.0.113.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified, err := replicaB.Verify(verificationToken(t, env, "zs@example.org")); err != nil || verified.Status != RegistrationPending {
+		t.Fatalf("verify on replica B: got %+v, %v", verified, err)
+	}
+	if pending, err := replicaA.List(string(RegistrationPending)); err != nil || len(pending) != 1 || pending[0].Id != request.Id {
+		t.Fatalf("replica A lists pending %+v, %v, want the request verified on replica B", pending, err)
+	}
+
+	if _, err := replicaB.Approve("admin", request.Id, security.OuUserMember.String(), "default"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.manager.serviceUser.FindByUid("2024000001"); err != nil {
+		t.Errorf("approved request did not create the account: %v", err)
+	}
+	if _, err := replicaA.Reject("admin", request.Id, ""); !errors.Is(err, ErrConflict) {
+		t.Errorf("reject on replica A after approval on replica B: got %v, want ErrConflict", err)
+	}
+}
+
+// solvePow 求出满足难度的 nonce
+func solvePow(challenge string, difficulty int) string {
+	for i := 0; ; i++ {
+		if nonce := strconv.Itoa(i); security.PowSolved(challenge, nonce, difficulty) {
+			return nonce
+		}
+	}
+}
+
+func TestRegistrationChallengeIsUsedOnceAcrossInstances(t *testing.T) {
+	config.PasetoKey = paseto.NewV4SymmetricKey()
+	env := newTestEnv(t)
+	replicaA, replicaB := newTestRegistrations(t, env, &config.ConfigRegistration{Enabled: true, PowDifficulty: 4, ChallengeTTL: time.Minute, VerifyTTL: time.Hour})
+
+	challenge, err := replicaA.Challenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := &RegistrationSpec{Username: "2024000001", SurName: "张", GivenName: "三", Mail: "not-a-mail", Challenge: challenge.Challenge}
+	spec.Nonce = solvePow(spec.Challenge, challenge.Difficulty)
+
+	// 填写错误的申请不消耗题目
+	if _, err := replicaA.Submit(spec, "203.0.113.1"); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("got %v for an invalid mail, want ErrInvalid", err)
+	}
+	spec.Mail = "zs@example.org"
+	if _, err := replicaA.Submit(spec, "203.0.113.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 同一个解不能在另一个实例上再次使用
+	spec.Username, spec.Mail = "2024000002", "ls@example.org"
+	if _, err := replicaB.Submit(spec, "203.0.113.2"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("got %v for a reused challenge, want ErrInvalid", err)
+	}
+}
+
+func TestRegistrationRateLimitIsSharedAcrossInstances(t *testing.T) {
+	env := newTestEnv(t)
+	replicaA, replicaB := newTestRegistrations(t, env, &config.ConfigRegistration{Enabled: true, RateLimit: 1, RateWindow: time.Hour, VerifyTTL: time.Hour})
+
+	if _, err := replicaA.Submit(&RegistrationSpec{Username: "2024000001", SurName: "张", GivenName: "三", Mail: "zs@example.org"}, "203.0.113.1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := replicaB.Submit(&RegistrationSpec{Username: "2024000002", SurName: "李", GivenName: "四", Mail: "ls@example.org"}, "203.0.113.1"); !errors.Is(err, ErrTooManyRequests) {
+		t.Errorf("got %v for the second request from the same ip, want ErrTooManyRequests", err)
+	}
+	if _, err := replicaB.Submit(&RegistrationSpec{Username: "2024000002", SurName: "李", GivenName: "四", Mail: "ls@example.org"}, "203.0.113.2"); err != nil {
+		t.Errorf("request from another ip: %v", err)
+	}
+
+	// 时间窗口过后清理提交记录
+	if _, err := replicaA.Purge(time.Now().Add(2 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if attempts, err := replicaA.attempts.List(); err != nil || len(attempts) != 0 {
+		t.Errorf("got %v, %v after purge, want no attempts", attempts, err)
+	}
+}
+
+func TestApproveResetsRequestWhenRegistrationFails(t *testing.T) {
+	env := newTestEnv(t)
+	registrations, _ := newTestRegistrations(t, env, &config.ConfigRegistration{Enabled: true, VerifyTTL: time.Hour})
+	request, err := registrations.Submit(&RegistrationSpec{Username: "2024000001", SurName: "张", GivenName: "三", Mail: "zs@example.org"}, "203.0.113.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registrations.Verify(verificationToken(t, env, "zs@example.org")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 审核期间同一学号已由管理员直接注册
+	registerMember(t, env.manager, "2024000001", "default")
+	if _, err := registrations.Approve("admin", request.Id, security.OuUserMember.String(), "default"); !errors.Is(err, ErrExists) {
+		t.Fatalf("got %v, want ErrExists", err)
+	}
+	pending, err := registrations.List(string(RegistrationPending))
+	if err != nil || len(pending) != 1 || pending[0].DecidedBy != "" || pending[0].Role != "" {
+		t.Errorf("got %+v, %v, want the request back in the pending queue", pending, err)
+	}
+
+	if err := env.manager.Unregister("2024000001"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registrations.Approve("admin", request.Id, security.OuUserMember.String(), "default"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registrations.Approve("admin", request.Id, security.OuUserMember.String(), "default"); !errors.Is(err, ErrConflict) {
+		t.Errorf("got %v when approving twice, want ErrConflict", err)
+	}
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/event"
+	"asynclab.club/asynx/backend/pkg/persist"
+	"asynclab.club/asynx/backend/pkg/security"
+	"github.com/sirupsen/logrus"
+)
+
+// RoleGrant 是有期限的角色授予，到期后用户恢复为 Previous
+type RoleGrant struct {
+	Uid        string        `json:"uid"`
+	Role       security.Role `json:"role"`
+	Previous   security.Role `json:"previous"`
+	GrantedBy  string        `json:"grantedBy"`
+	GrantedAt  time.Time     `json:"grantedAt"`
+	ExpiresAt  time.Time     `json:"expiresAt"`
+	NotifiedAt *time.Time    `json:"notifiedAt,omitempty"` // 已发送到期提醒的时间
+}
+
+type ServiceRoleGrant struct {
+	cfg     *config.ConfigRoleGrant
+	manager *ServiceManager
+	grants  *persist.SharedCollection[RoleGrant]
+}
+
+// NewServiceRoleGrant 创建临时角色服务，期限保存在共享存储中
+func NewServiceRoleGrant(cfg *config.ConfigRoleGrant, manager *ServiceManager, bus *event.Bus) *ServiceRoleGrant {
+	s := &ServiceRoleGrant{
+		cfg:     cfg,
+		manager: manager,
+		grants:  persist.NewSharedCollection[RoleGrant](manager.store.Shared(), "role-grants"),
+	}
+	// 角色被改成其他角色或用户被删除时，期限随之失效
+	bus.Subscribe(func(e event.Event) {
+		if e.Type != event.UserDeleted && e.Type != event.UserRoleChanged {
+			return
+		}
+		grant, ok, err := s.grants.Get(e.Subject)
+		if err != nil {
+			logrus.Errorf("Failed to read role grant of %s: %v", e.Subject, err)
+			return
+		}
+		if !ok {
+			return
+		}
+		switch {
+		case e.Type == event.UserDeleted:
+		case e.Type == event.UserRoleChanged && e.Data["to"] != grant.Role.String():
+		default:
+			return
+		}
+		if err := s.grants.Delete(e.Subject); err != nil {
+			logrus.Errorf("Failed to remove role grant of %s: %v", e.Subject, err)
+		}
+	})
+	return s
+}
+
+// List 返回尚未到期的临时角色，按到期时间排序
+func (s *ServiceRoleGrant) List() ([]RoleGrant, error) {
+	grants, err := s.grants.List()
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(grants, func(a, b RoleGrant) int { return a.ExpiresAt.Compare(b.ExpiresAt) })
+	return grants, nil
+}
+
+// Grant 临时授予角色。已有同一角色的临时授予时只更新到期时间，到期后仍恢复为最初的角色
+func (s *ServiceRoleGrant) Grant(operator string, uid string, roleName string, expiresAt time.Time) (*RoleGrant, error) {
+	role, err := security.GetRoleFromName(roleName)
+	if err != nil {
+		return nil, WrapError(ErrInvalid, err.Error())
+	}
+	now := time.Now()
+	if !expiresAt.After(now) {
+		return nil, WrapError(ErrInvalid, "expiresAt must be in the future")
+	}
+	if expiresAt.Sub(now) > s.cfg.MaxDuration {
+		return nil, WrapError(ErrInvalid, fmt.Sprintf("role grant cannot last longer than %s", s.cfg.MaxDuration))
+	}
+
+	current, err := s.manager.serviceGroup.GetRoleByUid(uid)
+	if err != nil {
+		return nil, err
+	}
+	existing, ok, err := s.grants.Get(uid)
+	if err != nil {
+		return nil, err
+	}
+	grant := existing
+	if !ok || grant.Role != role {
+		previous := current
+		if ok {
+			previous = grant.Previous
+		}
+		if previous == role || (!ok && current == role) {
+			return nil, WrapError(ErrInvalid, fmt.Sprintf("%s already has role %s", uid, role))
+		}
+		grant = RoleGrant{Uid: uid, Role: role, Previous: previous}
+	}
+	grant.GrantedBy = operator
+	grant.GrantedAt = now
+	grant.ExpiresAt = expiresAt
+	grant.NotifiedAt = nil
+
+	// 先保存期限再修改角色，角色变更事件据此判断是否保留期限
+	if err := s.grants.Put(uid, grant); err != nil {
+		return nil, err
+	}
+	if err := s.manager.GrantRoleByUidAndRoleName(uid, role.String()); err != nil {
+		restore := func() error { return s.grants.Delete(uid) }
+		if ok {
+			restore = func() error { return s.grants.Put(uid, existing) }
+		}
+		if err := restore(); err != nil {
+			logrus.Errorf("Failed to restore role grant of %s: %v", uid, err)
+		}
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// Cancel 取消期限，用户保留当前角色
+func (s *ServiceRoleGrant) Cancel(uid string) error {
+	if _, ok, err := s.grants.Get(uid); err != nil {
+		return err
+	} else if !ok {
+		return WrapError(ErrNotFound, fmt.Sprintf("role grant of %s not found", uid))
+	}
+	return s.grants.Delete(uid)
+}
+
+// Forget 删除期限，用于把角色改为永久
+func (s *ServiceRoleGrant) Forget(uid string) {
+	if err := s.grants.Delete(uid); err != nil {
+		logrus.Warnf("Failed to remove role grant of %s: %v", uid, err)
+	}
+}
+
+// Expire 发送到期提醒并恢复已到期的角色
+func (s *ServiceRoleGrant) Expire(now time.Time) error {
+	grants, err := s.grants.List()
+	if err != nil {
+		return err
+	}
+	for _, grant := range grants {
+		if !now.Before(grant.ExpiresAt) {
+			s.revert(grant)
+			continue
+		}
+		if s.cfg.NotifyBefore > 0 && grant.NotifiedAt == nil && grant.ExpiresAt.Sub(now) <= s.cfg.NotifyBefore {
+			s.remind(grant, now)
+		}
+	}
+	return nil
+}
+
+func (s *ServiceRoleGrant) revert(grant RoleGrant) {
+	user, err := s.manager.serviceUser.FindByUid(grant.Uid)
+	if err != nil {
+		logrus.Errorf("Failed to revert role of %s: %v", grant.Uid, err)
+		return
+	}
+	// 先删除期限，恢复角色产生的变更事件不再需要处理
+	if err := s.grants.Delete(grant.Uid); err != nil {
+		logrus.Errorf("Failed to remove role grant of %s: %v", grant.Uid, err)
+		return
+	}
+	if err := s.manager.grantRole(user, grant.Previous); err != nil {
+		logrus.Errorf("Failed to revert role of %s to %s: %v", grant.Uid, grant.Previous, err)
+		// 保留期限以便下次重试
+		if err := s.grants.Put(grant.Uid, grant); err != nil {
+			logrus.Errorf("Failed to restore role grant of %s: %v", grant.Uid, err)
+		}
+		return
+	}
+	logrus.Infof("Role %s of %s expired, reverted to %s", grant.Role, grant.Uid, grant.Previous)
+}
+
+func (s *ServiceRoleGrant) remind(grant RoleGrant, now time.Time) {
+	user, err := s.manager.serviceUser.FindByUid(grant.Uid)
+	if err != nil {
+		logrus.Errorf("Failed to remind %s of role expiry: %v", grant.Uid, err)
+		return
+	}
+	s.manager.notification.RoleExpiring(user, grant.Role.String(), grant.Previous.String(), grant.ExpiresAt)
+	err = s.grants.Update(grant.Uid, func(g *RoleGrant) error {
+		g.NotifiedAt = &now
+		return nil
+	})
+	if err != nil {
+		logrus.Errorf("Failed to record role expiry reminder of %s: %v", grant.Uid, err)
+	}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/event"
+	"asynclab.club/asynx/backend/pkg/security"
+)
+
+func TestRoleGrantsExpireOnAnyInstance(t *testing.T) {
+	env := newTestEnv(t)
+	registerMember(t, env.manager, "2024000001", "default")
+
+	// 在实例 A 上授予，由运行到期任务的实例 B 恢复
+	cfg := &config.ConfigRoleGrant{MaxDuration: 24 * time.Hour}
+	replicaA := NewServiceRoleGrant(cfg, env.manager, event.NewBus())
+	replicaB := NewServiceRoleGrant(cfg, env.manager, event.NewBus())
+
+	expiresAt := time.Now().Add(time.Hour)
+	if _, err := replicaA.Grant("admin", "2024000001", "admin", expiresAt); err != nil {
+		t.Fatal(err)
+	}
+	if grants, err := replicaB.List(); err != nil || len(grants) != 1 || grants[0].Previous != security.RoleDefault {
+		t.Fatalf("replica B lists %+v, %v, want the grant made on replica A", grants, err)
+	}
+
+	if err := replicaB.Expire(expiresAt.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	role, err := env.manager.serviceGroup.GetRoleByUid("2024000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role != security.RoleDefault {
+		t.Errorf("got role %s after expiry, want default", role)
+	}
+	if grants, err := replicaA.List(); err != nil || len(grants) != 0 {
+		t.Errorf("replica A still lists %+v, %v", grants, err)
+	}
+}
+
+func TestSessionLosesGrantedRoleAfterExpiry(t *testing.T) {
+	env := newTestEnv(t)
+	registerMember(t, env.manager, "2024000001", "default")
+
+	roleGrant := NewServiceRoleGrant(&config.ConfigRoleGrant{MaxDuration: 24 * time.Hour}, env.manager, event.NewBus())
+	expiresAt := time.Now().Add(time.Hour)
+	if _, err := roleGrant.Grant("admin", "2024000001", "admin", expiresAt); err != nil {
+		t.Fatal(err)
+	}
+	if role, err := env.manager.ResolveSession("2024000001"); err != nil || role != security.RoleAdmin {
+		t.Fatalf("got role %s, %v while the grant is active, want admin", role, err)
+	}
+
+	if err := roleGrant.Expire(expiresAt.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	// 授予期间登录得到的令牌仍然声明 admin，但 Guard 使用的是当前角色
+	if role, err := env.manager.ResolveSession("2024000001"); err != nil || role != security.RoleDefault {
+		t.Errorf("got role %s, %v after the grant expired, want default", role, err)
+	}
+}
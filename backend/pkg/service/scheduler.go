@@ -0,0 +1,53 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"asynclab.club/asynx/backend/pkg/scheduler"
+)
+
+// SchedulerStatus 是本实例看到的调度状态
+type SchedulerStatus struct {
+	Instance string               `json:"instance"`
+	IsLeader bool                 `json:"isLeader"`
+	Leader   string               `json:"leader,omitempty"` // 已知的主实例，租约被释放时为空
+	Jobs     []scheduler.JobState `json:"jobs"`
+}
+
+type ServiceScheduler struct {
+	scheduler *scheduler.Scheduler
+}
+
+func NewServiceScheduler(scheduler *scheduler.Scheduler) *ServiceScheduler {
+	return &ServiceScheduler{scheduler: scheduler}
+}
+
+func (s *ServiceScheduler) Status() *SchedulerStatus {
+	isLeader, leader := s.scheduler.Leader()
+	return &SchedulerStatus{
+		Instance: s.scheduler.Instance(),
+		IsLeader: isLeader,
+		Leader:   leader,
+		Jobs:     s.scheduler.List(),
+	}
+}
+
+func (s *ServiceScheduler) Trigger(name string) error {
+	err := s.scheduler.Trigger(name)
+	switch {
+	case errors.Is(err, scheduler.ErrJobNotFound):
+		return WrapError(ErrNotFound, fmt.Sprintf("job %s not found", name))
+	case errors.Is(err, scheduler.ErrNotLeader):
+		_, leader := s.scheduler.Leader()
+		return WrapError(ErrConflict, fmt.Sprintf("jobs run on the leader instance %s", leader))
+	case errors.Is(err, scheduler.ErrJobRunning):
+		return WrapError(ErrConflict, fmt.Sprintf("job %s is already running", name))
+	}
+	return err
+}
+
+func (s *ServiceScheduler) WriteMetrics(w io.Writer) error {
+	return s.scheduler.WriteMetrics(w)
+}
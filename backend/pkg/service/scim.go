@@ -0,0 +1,446 @@
+package service
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/entity"
+	"asynclab.club/asynx/backend/pkg/mail"
+	"asynclab.club/asynx/backend/pkg/scim"
+	"asynclab.club/asynx/backend/pkg/security"
+)
+
+// SCIM 预配时修改密码的操作者
+const ScimOperator = "scim"
+
+// ServiceScim 把用户、角色组和附加组映射为 SCIM 资源。写操作都经过 ServiceManager，
+// 因此与管理接口一样发布事件、发送通知
+type ServiceScim struct {
+	cfg          *config.ConfigScim
+	manager      *ServiceManager
+	serviceUser  *ServiceUser
+	serviceGroup *ServiceGroup
+}
+
+func NewServiceScim(cfg *config.ConfigScim, manager *ServiceManager) *ServiceScim {
+	return &ServiceScim{
+		cfg:          cfg,
+		manager:      manager,
+		serviceUser:  manager.serviceUser,
+		serviceGroup: manager.serviceGroup,
+	}
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+// scimGroupId 组的 SCIM id 为 "<ou>:<cn>"，角色组和附加组可能同名
+func scimGroupId(group *entity.Group) string {
+	return group.Ou + ":" + group.Cn
+}
+
+func parseScimGroupId(id string) (security.OuGroup, string, error) {
+	ou, cn, ok := strings.Cut(id, ":")
+	if !ok || cn == "" || (ou != security.OuGroupSupplementary.String() && ou != security.OuGroupAdditional.String()) {
+		return "", "", WrapError(ErrNotFound, fmt.Sprintf("group %s not found", id))
+	}
+	return security.OuGroup(ou), cn, nil
+}
+
+// scimGroups 返回可以通过 SCIM 管理的组：有效角色的角色组和全部附加组
+func (s *ServiceScim) scimGroups() ([]*entity.Group, error) {
+	roleGroups, err := s.serviceGroup.FindAllByOu(security.OuGroupSupplementary)
+	if err != nil {
+		return nil, err
+	}
+	additional, err := s.serviceGroup.FindAllByOu(security.OuGroupAdditional)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []*entity.Group
+	for _, group := range roleGroups {
+		if role := security.Role(group.Cn); role.IsValid() && role != security.RoleAnonymous {
+			group.Ou = security.OuGroupSupplementary.String()
+			groups = append(groups, group)
+		}
+	}
+	for _, group := range additional {
+		group.Ou = security.OuGroupAdditional.String()
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func (s *ServiceScim) toUser(user *entity.User, groups []*entity.Group) *scim.User {
+	active := !IsAccountExpired(user, time.Now())
+	resource := &scim.User{
+		Schemas:  []string{scim.SchemaUser, scim.SchemaAsynxUser},
+		Id:       user.Uid,
+		UserName: user.Uid,
+		Name: &scim.Name{
+			Formatted:  user.Sn + user.GivenName,
+			FamilyName: user.Sn,
+			GivenName:  user.GivenName,
+		},
+		DisplayName:       user.Sn + user.GivenName,
+		PreferredLanguage: user.PreferredLanguage,
+		Active:            &active,
+		Asynx: &scim.AsynxUser{
+			Category:      user.Ou,
+			Role:          security.RoleAnonymous.String(),
+			UidNumber:     user.UidNumber,
+			HomeDirectory: user.HomeDirectory,
+			LoginShell:    user.LoginShell,
+		},
+		Meta: &scim.Meta{ResourceType: "User"},
+	}
+	if user.Mail != "" {
+		resource.Emails = []scim.MultiValued{{Value: user.Mail, Type: "work", Primary: true}}
+	}
+
+	var roleGroups []*entity.Group
+	for _, group := range groups {
+		if !slices.Contains(group.MemberUid, user.Uid) {
+			continue
+		}
+		resource.Groups = append(resource.Groups, scim.MultiValued{Value: scimGroupId(group), Display: group.Cn})
+		if group.Ou == security.OuGroupSupplementary.String() {
+			roleGroups = append(roleGroups, group)
+		}
+	}
+	if role, err := security.GetRoleFromLdapGroups(roleGroups); err == nil {
+		resource.Asynx.Role = role.String()
+	}
+	return resource
+}
+
+func (s *ServiceScim) toGroup(group *entity.Group) *scim.Group {
+	resource := &scim.Group{
+		Schemas:     []string{scim.SchemaGroup, scim.SchemaAsynxGroup},
+		Id:          scimGroupId(group),
+		DisplayName: group.Cn,
+		Asynx:       &scim.AsynxGroup{Ou: group.Ou, GidNumber: group.GidNumber},
+		Meta:        &scim.Meta{ResourceType: "Group"},
+	}
+	for _, uid := range group.MemberUid {
+		resource.Members = append(resource.Members, scim.MultiValued{Value: uid, Display: uid})
+	}
+	return resource
+}
+
+// filterResources 在资源的 JSON 形式上求值过滤器
+func filterResources[T any](resources []*T, filter scim.Filter) ([]*T, error) {
+	if filter == nil {
+		return resources, nil
+	}
+	matched := make([]*T, 0, len(resources))
+	for _, resource := range resources {
+		doc, err := scim.ToDocument(resource)
+		if err != nil {
+			return nil, err
+		}
+		if filter.Match(doc) {
+			matched = append(matched, resource)
+		}
+	}
+	return matched, nil
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+func (s *ServiceScim) ListUsers(filter scim.Filter) ([]*scim.User, error) {
+	var (
+		users []*entity.User
+		err   error
+	)
+	// 身份提供方在创建前总会按 userName 查询，直接按 uid 查找以免遍历全部用户
+	if uid, ok := scim.EqualityValue(filter, "userName"); ok {
+		user, err := s.serviceUser.FindByUid(uid)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		if user != nil {
+			users = append(users, user)
+		}
+	} else {
+		users, err = s.serviceUser.FindAll()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	groups, err := s.scimGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]*scim.User, 0, len(users))
+	for _, user := range users {
+		resources = append(resources, s.toUser(user, groups))
+	}
+	return filterResources(resources, filter)
+}
+
+func (s *ServiceScim) GetUser(uid string) (*scim.User, error) {
+	user, err := s.serviceUser.FindByUid(uid)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := s.scimGroups()
+	if err != nil {
+		return nil, err
+	}
+	return s.toUser(user, groups), nil
+}
+
+func (s *ServiceScim) CreateUser(resource *scim.User) (*scim.User, error) {
+	if resource.UserName == "" {
+		return nil, scim.NewError(http.StatusBadRequest, scim.ErrInvalidValue, "userName is required")
+	}
+	if _, err := s.serviceUser.FindByUid(resource.UserName); !errors.Is(err, ErrNotFound) {
+		if err != nil {
+			return nil, err
+		}
+		return nil, scim.NewError(http.StatusConflict, scim.ErrUniqueness, fmt.Sprintf("user %s already exists", resource.UserName))
+	}
+
+	category, roleName := s.cfg.DefaultCategory, s.cfg.DefaultRole
+	if resource.Asynx != nil {
+		category = cmp.Or(resource.Asynx.Category, category)
+		roleName = cmp.Or(resource.Asynx.Role, roleName)
+	}
+	var surName, givenName string
+	if resource.Name != nil {
+		surName, givenName = resource.Name.FamilyName, resource.Name.GivenName
+	}
+
+	// 身份提供方提供了密码时欢迎邮件中不附带密码，也不发送密码被重置的通知
+	language := scimLanguage(resource.PreferredLanguage)
+	var err error
+	if resource.Password != "" {
+		err = s.manager.RegisterWithPassword(resource.UserName, surName, givenName, resource.PrimaryEmail(), category, roleName, language, resource.Password)
+	} else {
+		err = s.manager.Register(resource.UserName, surName, givenName, resource.PrimaryEmail(), category, roleName, language, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if resource.Active != nil && !*resource.Active {
+		if err := s.manager.Deactivate(resource.UserName); err != nil {
+			return nil, err
+		}
+	}
+	return s.GetUser(resource.UserName)
+}
+
+// ReplaceUser 以 PUT 语义更新用户。未提供的姓名、邮箱、语言、active 和扩展属性保持不变，只读属性被忽略。
+// active 为 false 时账号从今天起到期，为 true 时取消已到期账号的到期日
+func (s *ServiceScim) ReplaceUser(uid string, resource *scim.User) (*scim.User, error) {
+	current, err := s.GetUser(uid)
+	if err != nil {
+		return nil, err
+	}
+	if resource.UserName != "" && resource.UserName != current.UserName {
+		return nil, scim.NewError(http.StatusBadRequest, scim.ErrMutability, "userName is immutable")
+	}
+
+	surName, givenName := current.Name.FamilyName, current.Name.GivenName
+	if resource.Name != nil {
+		surName, givenName = resource.Name.FamilyName, resource.Name.GivenName
+	}
+	email := cmp.Or(resource.PrimaryEmail(), current.PrimaryEmail())
+	if err := s.manager.ModifyProfile(uid, surName, givenName, email); err != nil {
+		return nil, err
+	}
+
+	if resource.Asynx != nil {
+		if resource.Asynx.Category != "" && resource.Asynx.Category != current.Asynx.Category {
+			if err := s.manager.ModifyCategory(uid, resource.Asynx.Category); err != nil {
+				return nil, err
+			}
+		}
+		if resource.Asynx.Role != "" && resource.Asynx.Role != current.Asynx.Role {
+			if err := s.manager.GrantRoleByUidAndRoleName(uid, resource.Asynx.Role); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if resource.PreferredLanguage != "" {
+		if err := s.manager.ModifyLanguage(uid, scimLanguage(resource.PreferredLanguage)); err != nil {
+			return nil, err
+		}
+	}
+
+	if resource.Active != nil && *resource.Active != *current.Active {
+		if *resource.Active {
+			err = s.manager.ModifyExpiry(uid, nil)
+		} else {
+			err = s.manager.Deactivate(uid)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if resource.Password != "" {
+		if err := s.manager.ChangePassword(ScimOperator, uid, resource.Password); err != nil {
+			return nil, err
+		}
+	}
+	return s.GetUser(uid)
+}
+
+func (s *ServiceScim) PatchUser(uid string, operations []scim.PatchOperation) (*scim.User, error) {
+	current, err := s.GetUser(uid)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := scim.ToDocument(current)
+	if err != nil {
+		return nil, err
+	}
+	if err := scim.ApplyPatch(doc, operations); err != nil {
+		return nil, err
+	}
+	patched, err := scim.FromDocument[scim.User](doc)
+	if err != nil {
+		return nil, err
+	}
+	// PUT 中缺少的语言表示不变，PATCH 移除语言表示恢复默认语言
+	if current.PreferredLanguage != "" && patched.PreferredLanguage == "" {
+		if err := s.manager.ModifyLanguage(uid, ""); err != nil {
+			return nil, err
+		}
+	}
+	return s.ReplaceUser(uid, patched)
+}
+
+func (s *ServiceScim) DeleteUser(uid string) error {
+	return s.manager.Unregister(uid)
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+func (s *ServiceScim) ListGroups(filter scim.Filter) ([]*scim.Group, error) {
+	groups, err := s.scimGroups()
+	if err != nil {
+		return nil, err
+	}
+	resources := make([]*scim.Group, 0, len(groups))
+	for _, group := range groups {
+		resources = append(resources, s.toGroup(group))
+	}
+	return filterResources(resources, filter)
+}
+
+func (s *ServiceScim) findGroup(id string) (*entity.Group, error) {
+	ou, cn, err := parseScimGroupId(id)
+	if err != nil {
+		return nil, err
+	}
+	if ou == security.OuGroupSupplementary {
+		if role := security.Role(cn); !role.IsValid() || role == security.RoleAnonymous {
+			return nil, WrapError(ErrNotFound, fmt.Sprintf("group %s not found", id))
+		}
+	}
+	group, err := s.serviceGroup.FindByOuAndCn(ou, cn)
+	if err != nil {
+		return nil, err
+	}
+	group.Ou = ou.String()
+	return group, nil
+}
+
+func (s *ServiceScim) GetGroup(id string) (*scim.Group, error) {
+	group, err := s.findGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.toGroup(group), nil
+}
+
+// ReplaceGroup 把组成员设置为 resource.Members。加入角色组会把用户从原角色组移出，
+// 移出角色组则撤销该用户的角色
+func (s *ServiceScim) ReplaceGroup(id string, resource *scim.Group) (*scim.Group, error) {
+	group, err := s.findGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	if resource.DisplayName != "" && resource.DisplayName != group.Cn {
+		return nil, scim.NewError(http.StatusBadRequest, scim.ErrMutability, "displayName is immutable")
+	}
+
+	var members []string
+	for _, member := range resource.Members {
+		if member.Value != "" && !slices.Contains(members, member.Value) {
+			members = append(members, member.Value)
+		}
+	}
+
+	isRoleGroup := group.Ou == security.OuGroupSupplementary.String()
+	for _, uid := range members {
+		if slices.Contains(group.MemberUid, uid) {
+			continue
+		}
+		if isRoleGroup {
+			err = s.manager.GrantRoleByUidAndRoleName(uid, group.Cn)
+		} else {
+			err = s.manager.JoinGroup(uid, group.Cn)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, uid := range group.MemberUid {
+		if slices.Contains(members, uid) {
+			continue
+		}
+		if isRoleGroup {
+			err = s.manager.RevokeRole(uid)
+		} else {
+			err = s.manager.LeaveGroup(uid, group.Cn)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s.GetGroup(id)
+}
+
+func (s *ServiceScim) PatchGroup(id string, operations []scim.PatchOperation) (*scim.Group, error) {
+	current, err := s.GetGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := scim.ToDocument(current)
+	if err != nil {
+		return nil, err
+	}
+	if err := scim.ApplyPatch(doc, operations); err != nil {
+		return nil, err
+	}
+	patched, err := scim.FromDocument[scim.Group](doc)
+	if err != nil {
+		return nil, err
+	}
+	return s.ReplaceGroup(id, patched)
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+// scimLanguage 把 BCP 47 语言标签（如 en-US）映射为支持的通知语言，无法识别时留空使用默认语言
+func scimLanguage(tag string) string {
+	language, _, _ := strings.Cut(strings.ToLower(strings.ReplaceAll(tag, "_", "-")), "-")
+	if slices.Contains(mail.Languages, language) {
+		return language
+	}
+	return ""
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/event"
+	"asynclab.club/asynx/backend/pkg/scim"
+	"asynclab.club/asynx/backend/pkg/security"
+)
+
+func newTestScim(env *testEnv) *ServiceScim {
+	return NewServiceScim(&config.ConfigScim{DefaultCategory: "member", DefaultRole: "default"}, env.manager)
+}
+
+func TestScimReplacesAccountWithoutEmail(t *testing.T) {
+	env := newTestEnv(t)
+	registerMember(t, env.manager, "2024000001", "default")
+	accounts := NewServiceServiceAccount(env.manager, NewServiceAccessToken(&config.ConfigAccessToken{MaxTTL: time.Hour, MaxPerUser: 5}, env.manager, event.NewBus()))
+	guard := &security.GuardResult{Uid: "admin", Role: security.RoleAdmin}
+	if _, err := accounts.Create(guard, "svc-ci", &ServiceAccountSpec{DisplayName: "CI", Owner: "2024000001", Scopes: []string{"users:read"}, Role: "default"}); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestScim(env)
+
+	// 服务账号没有邮箱，只修改姓名的 PUT 和 PATCH 不应因为邮箱校验失败
+	replaced, err := s.ReplaceUser("svc-ci", &scim.User{UserName: "svc-ci", Name: &scim.Name{FamilyName: "CI", GivenName: "Runner"}})
+	if err != nil {
+		t.Fatalf("PUT without email: %v", err)
+	}
+	if replaced.Name.GivenName != "Runner" || len(replaced.Emails) != 0 {
+		t.Errorf("got %+v", replaced)
+	}
+	patched, err := s.PatchUser("svc-ci", []scim.PatchOperation{{Op: "replace", Path: "name.givenName", Value: "Builder"}})
+	if err != nil {
+		t.Fatalf("PATCH without email: %v", err)
+	}
+	if patched.Name.GivenName != "Builder" {
+		t.Errorf("got %+v", patched.Name)
+	}
+}
+
+func TestScimRejectsInvalidChangedEmail(t *testing.T) {
+	env := newTestEnv(t)
+	registerMember(t, env.manager, "2024000001", "default")
+	s := newTestScim(env)
+
+	_, err := s.PatchUser("2024000001", []scim.PatchOperation{{Op: "replace", Path: `emails[type eq "work"].value`, Value: "not-an-email"}})
+	if !errors.Is(err, ErrInvalid) {
+		t.Errorf("got %v, want ErrInvalid", err)
+	}
+	if _, err := s.ReplaceUser("2024000001", &scim.User{UserName: "2024000002"}); err == nil {
+		t.Error("userName was changed")
+	}
+}
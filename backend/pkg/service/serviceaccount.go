@@ -0,0 +1,330 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/entity"
+	"asynclab.club/asynx/backend/pkg/security"
+	"github.com/sirupsen/logrus"
+)
+
+// ServiceAccount 是供自动化程序使用的账号，只能通过 API 密钥访问接口。
+// 除 Uid 和 Role 外的字段以 JSON 保存在目录条目的 description 中，所有实例读到的都是同一份
+type ServiceAccount struct {
+	Uid         string        `json:"uid"`
+	DisplayName string        `json:"displayName"`
+	Description string        `json:"description"`
+	Owner       string        `json:"owner"` // 负责人的用户名
+	Team        string        `json:"team"`
+	Scopes      []string      `json:"scopes"` // 密钥可以申请的权限范围
+	Role        security.Role `json:"role"`   // 读取时从角色组查询，不保存
+	CreatedAt   time.Time     `json:"createdAt"`
+	UpdatedAt   time.Time     `json:"updatedAt"`
+}
+
+// serviceAccountMetadata 是写入 description 的部分
+type serviceAccountMetadata struct {
+	DisplayName string    `json:"displayName"`
+	Description string    `json:"description"`
+	Owner       string    `json:"owner"`
+	Team        string    `json:"team"`
+	Scopes      []string  `json:"scopes"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+func encodeServiceAccount(account *ServiceAccount) (string, error) {
+	data, err := json.Marshal(serviceAccountMetadata{
+		DisplayName: account.DisplayName,
+		Description: account.Description,
+		Owner:       account.Owner,
+		Team:        account.Team,
+		Scopes:      account.Scopes,
+		CreatedAt:   account.CreatedAt,
+		UpdatedAt:   account.UpdatedAt,
+	})
+	return string(data), err
+}
+
+func decodeServiceAccount(user *entity.User) (ServiceAccount, error) {
+	account := ServiceAccount{Uid: user.Uid}
+	if user.Description == "" {
+		return account, nil
+	}
+	var metadata serviceAccountMetadata
+	if err := json.Unmarshal([]byte(user.Description), &metadata); err != nil {
+		return account, fmt.Errorf("invalid metadata of service account %s: %w", user.Uid, err)
+	}
+	account.DisplayName = metadata.DisplayName
+	account.Description = metadata.Description
+	account.Owner = metadata.Owner
+	account.Team = metadata.Team
+	account.Scopes = metadata.Scopes
+	account.CreatedAt = metadata.CreatedAt
+	account.UpdatedAt = metadata.UpdatedAt
+	return account, nil
+}
+
+type ServiceAccountSpec struct {
+	DisplayName string   `json:"displayName"`
+	Description string   `json:"description"`
+	Owner       string   `json:"owner" binding:"required"`
+	Team        string   `json:"team"`
+	Scopes      []string `json:"scopes" binding:"required"`
+	Role        string   `json:"role" binding:"required"`
+}
+
+type ServiceServiceAccount struct {
+	manager      *ServiceManager
+	accessTokens *ServiceAccessToken
+}
+
+func NewServiceServiceAccount(manager *ServiceManager, accessTokens *ServiceAccessToken) *ServiceServiceAccount {
+	return &ServiceServiceAccount{manager: manager, accessTokens: accessTokens}
+}
+
+func (s *ServiceServiceAccount) validateSpec(spec *ServiceAccountSpec) error {
+	if _, err := s.manager.serviceUser.FindByUid(spec.Owner); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return WrapError(ErrInvalid, fmt.Sprintf("owner %s does not exist", spec.Owner))
+		}
+		return err
+	}
+	if len(spec.Scopes) == 0 {
+		return WrapError(ErrInvalid, "at least one scope is required")
+	}
+	for _, scope := range spec.Scopes {
+		resource, _, err := security.ParseAccessScope(scope)
+		if err != nil {
+			return WrapError(ErrInvalid, err.Error())
+		}
+		if resource == accessTokenResource {
+			return WrapError(ErrInvalid, "service accounts cannot manage access tokens")
+		}
+	}
+	if _, err := security.GetRoleFromName(spec.Role); err != nil {
+		return WrapError(ErrInvalid, err.Error())
+	}
+	return nil
+}
+
+func (s *ServiceServiceAccount) withRole(account ServiceAccount) (*ServiceAccount, error) {
+	role, err := s.manager.serviceGroup.GetRoleByUid(account.Uid)
+	if err != nil {
+		return nil, err
+	}
+	account.Role = role
+	return &account, nil
+}
+
+func (s *ServiceServiceAccount) findUser(uid string) (*entity.User, error) {
+	user, err := s.manager.serviceUser.FindByUid(uid)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if err != nil || !IsServiceAccount(user) {
+		return nil, WrapError(ErrNotFound, fmt.Sprintf("service account %s not found", uid))
+	}
+	return user, nil
+}
+
+func (s *ServiceServiceAccount) getAccount(uid string) (ServiceAccount, error) {
+	user, err := s.findUser(uid)
+	if err != nil {
+		return ServiceAccount{}, err
+	}
+	return decodeServiceAccount(user)
+}
+
+func (s *ServiceServiceAccount) List() ([]*ServiceAccount, error) {
+	users, err := s.manager.serviceUser.FindAllByOu(security.OuUserSystem)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*ServiceAccount, 0, len(users))
+	for _, user := range users {
+		if !IsServiceAccount(user) {
+			continue
+		}
+		// 元数据被外部工具改坏的账号不影响列出其他账号，可以通过更新修复
+		account, err := decodeServiceAccount(user)
+		if err != nil {
+			logrus.Warnf("Skipped service account %s: %v", user.Uid, err)
+			continue
+		}
+		withRole, err := s.withRole(account)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, withRole)
+	}
+	return result, nil
+}
+
+func (s *ServiceServiceAccount) Get(uid string) (*ServiceAccount, error) {
+	account, err := s.getAccount(uid)
+	if err != nil {
+		return nil, err
+	}
+	return s.withRole(account)
+}
+
+// authorizeAccount 检查 guard 能否管理 uid 及其密钥，没有 users.role.grant 时不能管理角色高于自己的服务账号
+func (s *ServiceServiceAccount) authorizeAccount(guard *security.GuardResult, uid string) error {
+	if guard.Role.Can(security.PermUsersRoleGrant) {
+		return nil
+	}
+	role, err := s.manager.serviceGroup.GetRoleByUid(uid)
+	if err != nil {
+		return err
+	}
+	if role != security.RoleAnonymous && !guard.Role.Support(role) {
+		return WrapError(ErrDenied, fmt.Sprintf("role %s of %s exceeds role %s", role, uid, guard.Role))
+	}
+	return nil
+}
+
+// Create 创建服务账号，没有 users.role.grant 时角色不能高于 guard
+func (s *ServiceServiceAccount) Create(guard *security.GuardResult, uid string, spec *ServiceAccountSpec) (*ServiceAccount, error) {
+	if err := s.validateSpec(spec); err != nil {
+		return nil, err
+	}
+	if err := authorizeRoleName(guard, spec.Role); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	account := ServiceAccount{Uid: uid, CreatedAt: now}
+	applyServiceAccountSpec(&account, spec, now)
+	metadata, err := encodeServiceAccount(&account)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.manager.RegisterServiceAccount(uid, spec.Role, metadata); err != nil {
+		return nil, err
+	}
+	return s.withRole(account)
+}
+
+// Update 修改服务账号，收窄权限范围时撤销超出新范围的密钥。没有 users.role.grant 时原角色和新角色都不能高于 guard
+func (s *ServiceServiceAccount) Update(guard *security.GuardResult, uid string, spec *ServiceAccountSpec) (*ServiceAccount, error) {
+	user, err := s.findUser(uid)
+	if err != nil {
+		return nil, err
+	}
+	// 更新会整体重写元数据，损坏的元数据借此修复
+	account, err := decodeServiceAccount(user)
+	if err != nil {
+		logrus.Warnf("Rewriting metadata of service account %s: %v", uid, err)
+	}
+	if err := s.validateSpec(spec); err != nil {
+		return nil, err
+	}
+	if err := s.authorizeAccount(guard, uid); err != nil {
+		return nil, err
+	}
+	if err := authorizeRoleName(guard, spec.Role); err != nil {
+		return nil, err
+	}
+
+	role, _ := security.GetRoleFromName(spec.Role) // 已由 validateSpec 校验
+	if err := s.manager.grantRole(user, role); err != nil {
+		return nil, err
+	}
+	applyServiceAccountSpec(&account, spec, time.Now())
+	if user.Description, err = encodeServiceAccount(&account); err != nil {
+		return nil, err
+	}
+	if err := s.manager.serviceUser.ModifyAttributes(user); err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.accessTokens.RevokeFunc(uid, func(token *AccessToken) bool {
+		return !allScopesCovered(account.Scopes, token.Scopes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if revoked > 0 {
+		logrus.Infof("Revoked %d keys of service account %s outside its new scopes", revoked, uid)
+	}
+	return s.withRole(account)
+}
+
+func applyServiceAccountSpec(account *ServiceAccount, spec *ServiceAccountSpec, now time.Time) {
+	account.DisplayName = strings.TrimSpace(spec.DisplayName)
+	account.Description = spec.Description
+	account.Owner = spec.Owner
+	account.Team = spec.Team
+	account.Scopes = spec.Scopes
+	account.UpdatedAt = now
+}
+
+func allScopesCovered(allowed []string, scopes []string) bool {
+	for _, scope := range scopes {
+		if !security.AccessScopeCovered(allowed, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// Delete 删除服务账号，元数据随目录条目一起删除，密钥由 user.deleted 事件清理。没有 users.role.grant 时不能删除角色高于 guard 的服务账号
+func (s *ServiceServiceAccount) Delete(guard *security.GuardResult, uid string) error {
+	if _, err := s.findUser(uid); err != nil {
+		return err
+	}
+	if err := s.authorizeAccount(guard, uid); err != nil {
+		return err
+	}
+	return s.manager.Unregister(uid)
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+func (s *ServiceServiceAccount) ListKeys(guard *security.GuardResult, uid string) ([]*AccessToken, error) {
+	if _, err := s.findUser(uid); err != nil {
+		return nil, err
+	}
+	if err := s.authorizeAccount(guard, uid); err != nil {
+		return nil, err
+	}
+	return s.accessTokens.List(uid)
+}
+
+// CreateKey 为服务账号签发 API 密钥，权限范围必须在服务账号允许的范围之内
+func (s *ServiceServiceAccount) CreateKey(guard *security.GuardResult, uid string, spec *AccessTokenSpec) (*AccessToken, error) {
+	account, err := s.getAccount(uid)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeAccount(guard, uid); err != nil {
+		return nil, err
+	}
+	return s.accessTokens.create(uid, spec, account.Scopes, "")
+}
+
+func (s *ServiceServiceAccount) RotateKey(guard *security.GuardResult, uid string, id string) (*AccessToken, error) {
+	account, err := s.getAccount(uid)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeAccount(guard, uid); err != nil {
+		return nil, err
+	}
+	return s.accessTokens.Rotate(uid, id, account.Scopes)
+}
+
+func (s *ServiceServiceAccount) RevokeKey(guard *security.GuardResult, uid string, id string) error {
+	if _, err := s.findUser(uid); err != nil {
+		return err
+	}
+	if err := s.authorizeAccount(guard, uid); err != nil {
+		return err
+	}
+	return s.accessTokens.Revoke(uid, id, false)
+}
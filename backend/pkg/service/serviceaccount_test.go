@@ -0,0 +1,185 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/entity"
+	"asynclab.club/asynx/backend/pkg/event"
+	"asynclab.club/asynx/backend/pkg/security"
+)
+
+func TestServiceAccountsAreSharedBetweenInstances(t *testing.T) {
+	env := newTestEnv(t)
+	registerMember(t, env.manager, "2024000001", "default")
+
+	// 两个实例连接同一个目录
+	cfg := &config.ConfigAccessToken{MaxTTL: 24 * time.Hour, MaxPerUser: 5, Retention: time.Hour}
+	replicaA := NewServiceServiceAccount(env.manager, NewServiceAccessToken(cfg, env.manager, event.NewBus()))
+	replicaB := NewServiceServiceAccount(env.manager, NewServiceAccessToken(cfg, env.manager, event.NewBus()))
+	guard := &security.GuardResult{Uid: "admin", Role: security.RoleAdmin}
+
+	spec := &ServiceAccountSpec{
+		DisplayName: "CI",
+		Owner:       "2024000001",
+		Team:        "infra",
+		Scopes:      []string{"users:read"},
+		Role:        "default",
+	}
+	if _, err := replicaA.Create(guard, "svc-ci", spec); err != nil {
+		t.Fatal(err)
+	}
+
+	account, err := replicaB.Get("svc-ci")
+	if err != nil {
+		t.Fatalf("service account created on replica A not found on replica B: %v", err)
+	}
+	if account.Owner != "2024000001" || account.Team != "infra" || account.Role != security.RoleDefault {
+		t.Errorf("got %+v", account)
+	}
+
+	spec.Team = "platform"
+	if _, err := replicaB.Update(guard, "svc-ci", spec); err != nil {
+		t.Fatal(err)
+	}
+	accounts, err := replicaA.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 1 || accounts[0].Team != "platform" {
+		t.Errorf("replica A lists %+v, want svc-ci in team platform", accounts)
+	}
+
+	key, err := replicaA.CreateKey(guard, "svc-ci", &AccessTokenSpec{Name: "deploy", Scopes: []string{"users:read"}, ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys, err := replicaB.ListKeys(guard, "svc-ci"); err != nil || len(keys) != 1 || keys[0].Id != key.Id {
+		t.Errorf("replica B lists keys %v, %v, want the key created on replica A", keys, err)
+	}
+
+	// 普通用户不是服务账号
+	if _, err := replicaA.Get("2024000001"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get member: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestServiceAccountsAboveGuardRoleAreProtected(t *testing.T) {
+	env := newTestEnv(t)
+	registerMember(t, env.manager, "2024000001", "default")
+
+	cfg := &config.ConfigAccessToken{MaxTTL: 24 * time.Hour, MaxPerUser: 5, Retention: time.Hour}
+	accounts := NewServiceServiceAccount(env.manager, NewServiceAccessToken(cfg, env.manager, event.NewBus()))
+	admin := &security.GuardResult{Uid: "admin", Role: security.RoleAdmin}
+	// 有 service-accounts.manage 但没有 users.role.grant 的管理者
+	manager := &security.GuardResult{Uid: "2024000001", Role: security.RoleDefault}
+
+	spec := &ServiceAccountSpec{Owner: "2024000001", Scopes: []string{"users:read"}, Role: "admin"}
+	if _, err := accounts.Create(admin, "svc-admin", spec); err != nil {
+		t.Fatal(err)
+	}
+	key, err := accounts.CreateKey(admin, "svc-admin", &AccessTokenSpec{Name: "deploy", Scopes: []string{"users:read"}, ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"list keys", func() error { _, err := accounts.ListKeys(manager, "svc-admin"); return err }},
+		{"create key", func() error {
+			_, err := accounts.CreateKey(manager, "svc-admin", &AccessTokenSpec{Name: "x", Scopes: []string{"users:read"}, ExpiresAt: time.Now().Add(time.Hour)})
+			return err
+		}},
+		{"rotate key", func() error { _, err := accounts.RotateKey(manager, "svc-admin", key.Id); return err }},
+		{"revoke key", func() error { return accounts.RevokeKey(manager, "svc-admin", key.Id) }},
+		{"delete", func() error { return accounts.Delete(manager, "svc-admin") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, ErrDenied) {
+				t.Errorf("got %v, want ErrDenied", err)
+			}
+		})
+	}
+
+	if keys, err := accounts.ListKeys(admin, "svc-admin"); err != nil || len(keys) != 1 {
+		t.Errorf("admin lists keys %v, %v, want the key to survive", keys, err)
+	}
+	if err := accounts.Delete(admin, "svc-admin"); err != nil {
+		t.Errorf("admin delete: %v", err)
+	}
+}
+
+func newTestServiceAccounts(env *testEnv) *ServiceServiceAccount {
+	cfg := &config.ConfigAccessToken{MaxTTL: 24 * time.Hour, MaxPerUser: 5, Retention: time.Hour}
+	return NewServiceServiceAccount(env.manager, NewServiceAccessToken(cfg, env.manager, event.NewBus()))
+}
+
+func TestServiceAccountListSkipsInvalidMetadata(t *testing.T) {
+	env := newTestEnv(t)
+	registerMember(t, env.manager, "2024000001", "default")
+	accounts := newTestServiceAccounts(env)
+	admin := &security.GuardResult{Uid: "admin", Role: security.RoleAdmin}
+	spec := &ServiceAccountSpec{Owner: "2024000001", Scopes: []string{"users:read"}, Role: "default"}
+	for _, uid := range []string{"svc-broken", "svc-ci"} {
+		if _, err := accounts.Create(admin, uid, spec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 外部工具改坏了 description
+	broken, err := env.manager.serviceUser.FindByUid("svc-broken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	broken.Description = "not json"
+	if err := env.manager.serviceUser.ModifyAttributes(broken); err != nil {
+		t.Fatal(err)
+	}
+
+	listed, err := accounts.List()
+	if err != nil || len(listed) != 1 || listed[0].Uid != "svc-ci" {
+		t.Fatalf("got %v, %v, want only the valid account", listed, err)
+	}
+	spec.Team = "infra"
+	if _, err := accounts.Update(admin, "svc-broken", spec); err != nil {
+		t.Fatalf("update did not repair the metadata: %v", err)
+	}
+	if repaired, err := accounts.Get("svc-broken"); err != nil || repaired.Team != "infra" {
+		t.Errorf("got %+v, %v after repair", repaired, err)
+	}
+}
+
+func TestServiceAccountsAreMarkedExplicitly(t *testing.T) {
+	env := newTestEnv(t)
+	// 早先创建的服务账号只有 nologin shell，引导管理员同样在 system 下
+	for _, user := range []*entity.User{
+		{Uid: "svc-legacy", Cn: "svc-legacy", Ou: security.OuUserSystem.String(), Sn: "svc-legacy", LoginShell: ServiceAccountShell},
+		{Uid: "root", Cn: "root", Ou: security.OuUserSystem.String(), Sn: "root", LoginShell: "/bin/bash"},
+	} {
+		if err := env.manager.serviceUser.Create(user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	accounts := newTestServiceAccounts(env)
+	if listed, err := accounts.List(); err != nil || len(listed) != 0 {
+		t.Fatalf("got %v, %v before marking, want no service accounts", listed, err)
+	}
+
+	for range 2 {
+		if err := env.manager.MarkLegacyServiceAccounts(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	listed, err := accounts.List()
+	if err != nil || len(listed) != 1 || listed[0].Uid != "svc-legacy" {
+		t.Errorf("got %v, %v, want only the legacy service account", listed, err)
+	}
+	if root, _ := env.manager.serviceUser.FindByUid("root"); IsServiceAccount(root) {
+		t.Error("bootstrap admin was marked as a service account")
+	}
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/event"
+	"asynclab.club/asynx/backend/pkg/persist"
+	"asynclab.club/asynx/backend/pkg/security"
+	"github.com/sirupsen/logrus"
+)
+
+// 令牌不能管理令牌，泄露的令牌无法借此续期或扩大权限
+const accessTokenResource = "access-tokens"
+
+// 最近使用时间的最小记录间隔，避免每个请求都写盘
+const accessTokenTouchInterval = 5 * time.Minute
+
+var errInvalidAccessToken = errors.New("invalid access token")
+
+// AccessToken 是用户为脚本创建的个人访问令牌，只保存摘要
+type AccessToken struct {
+	Id         string        `json:"id"`
+	Uid        string        `json:"uid"`
+	Name       string        `json:"name"`
+	Scopes     []string      `json:"scopes"`
+	Role       security.Role `json:"role"`
+	Token      string        `json:"token,omitempty"` // 明文令牌，只在创建时返回
+	SecretHash string        `json:"secretHash,omitempty"`
+	CreatedAt  time.Time     `json:"createdAt"`
+	ExpiresAt  time.Time     `json:"expiresAt"`
+	LastUsedAt *time.Time    `json:"lastUsedAt,omitempty"`
+}
+
+type AccessTokenSpec struct {
+	Name      string    `json:"name" binding:"required"`
+	Scopes    []string  `json:"scopes" binding:"required"`
+	Role      string    `json:"role"`                         // 为空时使用当前角色
+	ExpiresAt time.Time `json:"expiresAt" binding:"required"` // RFC 3339 格式
+}
+
+type ServiceAccessToken struct {
+	cfg          *config.ConfigAccessToken
+	tokens       *persist.SharedCollection[AccessToken]
+	serviceUser  *ServiceUser
+	serviceGroup *ServiceGroup
+}
+
+// NewServiceAccessToken 创建令牌服务，令牌保存在共享存储中，在任一实例上创建或撤销的令牌对所有实例立即生效
+func NewServiceAccessToken(cfg *config.ConfigAccessToken, manager *ServiceManager, bus *event.Bus) *ServiceAccessToken {
+	s := &ServiceAccessToken{
+		cfg:          cfg,
+		tokens:       persist.NewSharedCollection[AccessToken](manager.store.Shared(), "access-tokens"),
+		serviceUser:  manager.serviceUser,
+		serviceGroup: manager.serviceGroup,
+	}
+	bus.Subscribe(func(e event.Event) {
+		if e.Type != event.UserDeleted {
+			return
+		}
+		if _, err := s.tokens.DeleteFunc(func(_ string, token AccessToken) bool { return token.Uid == e.Subject }); err != nil {
+			logrus.Errorf("Failed to remove access tokens of %s: %v", e.Subject, err)
+		}
+	})
+	return s
+}
+
+func hashAccessTokenSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func withoutTokenSecret(token AccessToken) *AccessToken {
+	token.Token = ""
+	token.SecretHash = ""
+	return &token
+}
+
+// List 返回用户的全部令牌，包括已过期的
+func (s *ServiceAccessToken) List(uid string) ([]*AccessToken, error) {
+	tokens, err := s.tokens.List()
+	if err != nil {
+		return nil, err
+	}
+	var result []*AccessToken
+	for _, token := range tokens {
+		if token.Uid == uid {
+			result = append(result, withoutTokenSecret(token))
+		}
+	}
+	return result, nil
+}
+
+// Create 为用户创建令牌，明文令牌只在返回值中出现一次
+func (s *ServiceAccessToken) Create(uid string, spec *AccessTokenSpec) (*AccessToken, error) {
+	return s.create(uid, spec, nil, "")
+}
+
+// create 创建令牌，allowed 非空时令牌的权限范围必须在其之内；replacing 是轮换时将被替换的令牌，不计入数量限制
+func (s *ServiceAccessToken) create(uid string, spec *AccessTokenSpec, allowed []string, replacing string) (*AccessToken, error) {
+	name := strings.TrimSpace(spec.Name)
+	if name == "" {
+		return nil, WrapError(ErrInvalid, "token name is required")
+	}
+	if len(spec.Scopes) == 0 {
+		return nil, WrapError(ErrInvalid, "at least one scope is required")
+	}
+	for _, scope := range spec.Scopes {
+		resource, _, err := security.ParseAccessScope(scope)
+		if err != nil {
+			return nil, WrapError(ErrInvalid, err.Error())
+		}
+		if resource == accessTokenResource {
+			return nil, WrapError(ErrInvalid, "access tokens cannot manage access tokens")
+		}
+		if allowed != nil && !security.AccessScopeCovered(allowed, scope) {
+			return nil, WrapError(ErrInvalid, fmt.Sprintf("scope %s is not allowed", scope))
+		}
+	}
+
+	now := time.Now()
+	if !spec.ExpiresAt.After(now) {
+		return nil, WrapError(ErrInvalid, "expiresAt must be in the future")
+	}
+	if spec.ExpiresAt.After(now.Add(s.cfg.MaxTTL)) {
+		return nil, WrapError(ErrInvalid, fmt.Sprintf("token lifetime cannot exceed %s", s.cfg.MaxTTL))
+	}
+	tokens, err := s.List(uid)
+	if err != nil {
+		return nil, err
+	}
+	count := 0
+	for _, token := range tokens {
+		if token.Id != replacing {
+			count++
+		}
+	}
+	if count >= s.cfg.MaxPerUser {
+		return nil, WrapError(ErrInvalid, fmt.Sprintf("a user can hold at most %d tokens", s.cfg.MaxPerUser))
+	}
+
+	if _, err := s.serviceUser.FindByUid(uid); err != nil {
+		return nil, err
+	}
+	owner, err := s.serviceGroup.GetRoleByUid(uid)
+	if err != nil {
+		return nil, err
+	}
+	role := owner
+	if spec.Role != "" {
+		if role, err = security.GetRoleFromName(spec.Role); err != nil {
+			return nil, WrapError(ErrInvalid, err.Error())
+		}
+	}
+	if owner == security.RoleAnonymous || !owner.Support(role) {
+		return nil, WrapError(ErrInvalid, fmt.Sprintf("token role %s exceeds role %s of %s", role, owner, uid))
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	token := AccessToken{
+		Id:         hex.EncodeToString(id),
+		Uid:        uid,
+		Name:       name,
+		Scopes:     spec.Scopes,
+		Role:       role,
+		SecretHash: hashAccessTokenSecret(secret),
+		CreatedAt:  now,
+		ExpiresAt:  spec.ExpiresAt,
+	}
+	if err := s.tokens.Create(token.Id, token); err != nil {
+		return nil, err
+	}
+	token.Token = security.AccessTokenPrefix + token.Id + "_" + secret
+	token.SecretHash = ""
+	return &token, nil
+}
+
+// Rotate 用同样的名称、权限范围、角色和有效时长签发新令牌并撤销旧令牌，allowed 的含义与 create 相同
+func (s *ServiceAccessToken) Rotate(uid string, id string, allowed []string) (*AccessToken, error) {
+	old, ok, err := s.tokens.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || old.Uid != uid {
+		return nil, WrapError(ErrNotFound, fmt.Sprintf("token %s not found", id))
+	}
+
+	lifetime := min(old.ExpiresAt.Sub(old.CreatedAt), s.cfg.MaxTTL)
+	token, err := s.create(uid, &AccessTokenSpec{
+		Name:      old.Name,
+		Scopes:    old.Scopes,
+		Role:      old.Role.String(),
+		ExpiresAt: time.Now().Add(lifetime),
+	}, allowed, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tokens.Delete(id); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// RevokeFunc 撤销 uid 的令牌中 del 返回 true 的那些，返回撤销的数量
+func (s *ServiceAccessToken) RevokeFunc(uid string, del func(token *AccessToken) bool) (int, error) {
+	return s.tokens.DeleteFunc(func(_ string, token AccessToken) bool { return token.Uid == uid && del(&token) })
+}
+
+// Revoke 删除令牌，只能删除 uid 自己的令牌，admin 为 true 时可以删除任何人的令牌
+func (s *ServiceAccessToken) Revoke(uid string, id string, admin bool) error {
+	token, ok, err := s.tokens.Get(id)
+	if err != nil {
+		return err
+	}
+	if !ok || (token.Uid != uid && !admin) {
+		return WrapError(ErrNotFound, fmt.Sprintf("token %s not found", id))
+	}
+	return s.tokens.Delete(id)
+}
+
+// Purge 删除过期超过保留期的令牌，返回删除的数量
+func (s *ServiceAccessToken) Purge(now time.Time) (int, error) {
+	before := now.Add(-s.cfg.Retention)
+	n, err := s.tokens.DeleteFunc(func(_ string, token AccessToken) bool { return token.ExpiresAt.Before(before) })
+	if n > 0 {
+		logrus.Infof("Purged %d expired access tokens", n)
+	}
+	return n, err
+}
+
+// Verify 校验令牌并返回其身份，注册为 security.AccessTokenVerifier。
+// 角色取令牌角色与持有者当前角色中较低的一个，持有者被降级后令牌随之降级
+func (s *ServiceAccessToken) Verify(raw string, method string, route string) (*security.PasetoClaims, error) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(raw, security.AccessTokenPrefix), "_")
+	if !ok {
+		return nil, errInvalidAccessToken
+	}
+	token, ok, err := s.tokens.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || subtle.ConstantTimeCompare([]byte(token.SecretHash), []byte(hashAccessTokenSecret(secret))) != 1 {
+		return nil, errInvalidAccessToken
+	}
+	now := time.Now()
+	if now.After(token.ExpiresAt) {
+		return nil, errors.New("access token has expired")
+	}
+
+	if security.RouteResource(route) == accessTokenResource || !security.AccessScopesAllow(token.Scopes, method, route) {
+		return nil, security.ErrAccessTokenScope
+	}
+
+	user, err := s.serviceUser.FindByUid(token.Uid)
+	if err != nil {
+		return nil, err
+	}
+	if IsAccountExpired(user, now) {
+		return nil, fmt.Errorf("account %s has expired", token.Uid)
+	}
+	owner, err := s.serviceGroup.GetRole(user)
+	if err != nil {
+		return nil, err
+	}
+	// 持有者降级后令牌随之降级；角色之间互不包含时不能确定降级后的权限，令牌暂时失效
+	role := token.Role
+	if !owner.Support(role) {
+		if !role.Support(owner) {
+			return nil, fmt.Errorf("token role %s is not covered by role %s of %s", role, owner, token.Uid)
+		}
+		role = owner
+	}
+	if role == security.RoleAnonymous {
+		return nil, errors.New("token owner has no role")
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > accessTokenTouchInterval {
+		err := s.tokens.Update(id, func(t *AccessToken) error {
+			t.LastUsedAt = &now
+			return nil
+		})
+		if err != nil {
+			logrus.Warnf("Failed to record usage of access token %s: %v", id, err)
+		}
+	}
+	return &security.PasetoClaims{Uid: token.Uid, Role: role}, nil
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/event"
+)
+
+func TestAccessTokensAreSharedBetweenInstances(t *testing.T) {
+	env := newTestEnv(t)
+	registerMember(t, env.manager, "2024000001", "default")
+
+	// 两个实例连接同一个目录
+	cfg := &config.ConfigAccessToken{MaxTTL: 24 * time.Hour, MaxPerUser: 5, Retention: time.Hour}
+	replicaA := NewServiceAccessToken(cfg, env.manager, event.NewBus())
+	replicaB := NewServiceAccessToken(cfg, env.manager, event.NewBus())
+
+	token, err := replicaA.Create("2024000001", &AccessTokenSpec{
+		Name:      "script",
+		Scopes:    []string{"users:read"},
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := replicaB.Verify(token.Token, http.MethodGet, "/api/users")
+	if err != nil {
+		t.Fatalf("token created on replica A rejected by replica B: %v", err)
+	}
+	if claims.Uid != "2024000001" {
+		t.Errorf("got uid %s", claims.Uid)
+	}
+	if tokens, err := replicaB.List("2024000001"); err != nil || len(tokens) != 1 {
+		t.Errorf("replica B lists %d tokens, %v, want 1", len(tokens), err)
+	}
+
+	if err := replicaB.Revoke("2024000001", token.Id, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := replicaA.Verify(token.Token, http.MethodGet, "/api/users"); err == nil {
+		t.Error("token revoked on replica B still accepted by replica A")
+	}
+}
@@ -9,10 +9,10 @@ import (
 )
 
 type ServiceUser struct {
-	repositoryUser *repository.RepositoryUser
+	repositoryUser repository.RepositoryUser
 }
 
-func NewServiceUser(repo *repository.RepositoryUser) *ServiceUser {
+func NewServiceUser(repo repository.RepositoryUser) *ServiceUser {
 	return &ServiceUser{repositoryUser: repo}
 }
 
@@ -54,12 +54,19 @@ func (s *ServiceUser) Create(user *entity.User) error {
 	return s.repositoryUser.Create(user)
 }
 
+// ModifyAttributes 更新用户的普通属性，不会修改密码
+func (s *ServiceUser) ModifyAttributes(user *entity.User) error {
+	modified := *user
+	modified.UserPassword = ""
+	return s.repositoryUser.ModifyAttributes(&modified)
+}
+
 func (s *ServiceUser) ModifyPassword(user *entity.User, newPassword string) error {
 	return s.repositoryUser.ModifyPassword(user, newPassword)
 }
 
 func (s *ServiceUser) ModifyOu(user *entity.User, ou security.OuUser) error {
-	return s.repositoryUser.ModifyDn(user, fmt.Sprintf("cn=%s", user.Cn), fmt.Sprintf("ou=%s,%s", ou.String(), s.repositoryUser.GetUserBaseDn()))
+	return s.repositoryUser.ModifyOu(user, ou.String())
 }
 
 func (s *ServiceUser) Delete(user *entity.User) error {
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"slices"
+	"strings"
+
+	"asynclab.club/asynx/backend/pkg/client"
+	"asynclab.club/asynx/backend/pkg/event"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// ServiceWatch 把绕过 asynx 直接对目录做的修改转换为与 asynx 自身写入相同的事件
+type ServiceWatch struct {
+	bus *event.Bus
+}
+
+func NewServiceWatch(bus *event.Bus) *ServiceWatch {
+	return &ServiceWatch{bus: bus}
+}
+
+// entryOu 返回条目 DN 中紧随 RDN 的 ou，即用户的分类或组的 ou
+func entryOu(entry *ldap.Entry) string {
+	if entry == nil {
+		return ""
+	}
+	dn, err := ldap.ParseDN(entry.DN)
+	if err != nil {
+		return ""
+	}
+	for _, rdn := range dn.RDNs[min(1, len(dn.RDNs)):] {
+		for _, attr := range rdn.Attributes {
+			if strings.EqualFold(attr.Type, "ou") {
+				return attr.Value
+			}
+		}
+	}
+	return ""
+}
+
+// changeId 返回变更事件在所有实例上相同的 Id，由 entryUUID、条目版本、事件类型和 key 组成。
+// 版本优先使用 entryCSN，没有时退回精确到秒的 modifyTimestamp；条目删除后不会再有版本，以 deleted 代替
+func changeId(change client.LdapChange, t event.Type, key string) string {
+	entry, version := change.After, "deleted"
+	if entry != nil {
+		version = entry.GetAttributeValue("entryCSN")
+		if version == "" {
+			version = entry.GetAttributeValue("modifyTimestamp")
+		}
+	} else {
+		entry = change.Before
+	}
+	id := entry.GetAttributeValue("entryUUID")
+	if id == "" {
+		id = entry.DN
+	}
+	parts := []string{event.SourceDirectory, strings.ToLower(id), version, t.String()}
+	if key != "" {
+		parts = append(parts, key)
+	}
+	return strings.Join(parts, ":")
+}
+
+// publish 发布变更事件，key 用于区分同一变更产生的多个同类事件
+func (s *ServiceWatch) publish(e event.Event, change client.LdapChange, key string) {
+	e.Id = changeId(change, e.Type, key)
+	e.Source = event.SourceDirectory
+	if change.Replica {
+		e.Source = event.SourceReplica
+	}
+	if change.After != nil {
+		e.Actor = change.After.GetAttributeValue("modifiersName")
+	}
+	s.bus.Publish(e)
+}
+
+func (s *ServiceWatch) HandleUser(change client.LdapChange) {
+	switch change.Type {
+	case client.LdapEntryAdded:
+		s.publish(event.Event{
+			Type:    event.UserCreated,
+			Subject: change.After.GetAttributeValue("uid"),
+			Data:    map[string]string{"category": entryOu(change.After)},
+		}, change, "")
+	case client.LdapEntryDeleted:
+		s.publish(event.Event{
+			Type:    event.UserDeleted,
+			Subject: change.Before.GetAttributeValue("uid"),
+			Data:    map[string]string{"category": entryOu(change.Before)},
+		}, change, "")
+	case client.LdapEntryModified:
+		uid := change.After.GetAttributeValue("uid")
+		if from, to := entryOu(change.Before), entryOu(change.After); from != to {
+			s.publish(event.Event{
+				Type:    event.UserCategoryChanged,
+				Subject: uid,
+				Data:    map[string]string{"from": from, "to": to},
+			}, change, "")
+		}
+
+		changed := change.ChangedAttributes()
+		if slices.Contains(changed, "userpassword") {
+			s.publish(event.Event{Type: event.UserPasswordChanged, Subject: uid}, change, "")
+		}
+		changed = slices.DeleteFunc(changed, func(name string) bool { return name == "userpassword" || name == "ou" })
+		if len(changed) > 0 {
+			s.publish(event.Event{
+				Type:    event.UserUpdated,
+				Subject: uid,
+				Data:    map[string]string{"attributes": strings.Join(changed, ",")},
+			}, change, "")
+		}
+	}
+}
+
+// HandleGroup 根据 memberUid 的差异发布成员变更事件
+func (s *ServiceWatch) HandleGroup(change client.LdapChange) {
+	entry := change.After
+	if entry == nil {
+		entry = change.Before
+	}
+	cn := entry.GetAttributeValue("cn")
+	ou := entryOu(entry)
+
+	var before, after []string
+	if change.Before != nil {
+		before = change.Before.GetAttributeValues("memberUid")
+	}
+	if change.After != nil {
+		after = change.After.GetAttributeValues("memberUid")
+	}
+
+	for _, uid := range before {
+		if !slices.Contains(after, uid) {
+			s.publish(event.Event{Type: event.GroupMemberRemoved, Subject: cn, Data: map[string]string{"uid": uid, "ou": ou}}, change, uid)
+		}
+	}
+	for _, uid := range after {
+		if !slices.Contains(before, uid) {
+			s.publish(event.Event{Type: event.GroupMemberAdded, Subject: cn, Data: map[string]string{"uid": uid, "ou": ou}}, change, uid)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"asynclab.club/asynx/backend/pkg/client"
+	"asynclab.club/asynx/backend/pkg/event"
+	"github.com/go-ldap/ldap/v3"
+)
+
+func userEntry(csn string, attributes map[string][]string) *ldap.Entry {
+	attributes["entryUUID"] = []string{"6B0F4C2E-1111-4A5B-9C3D-000000000001"}
+	attributes["entryCSN"] = []string{csn}
+	attributes["modifiersName"] = []string{"cn=admin,dc=asynclab,dc=club"}
+	return ldap.NewEntry("uid=2024000001,ou=member,ou=people,dc=asynclab,dc=club", attributes)
+}
+
+// collect 返回一个实例上 ServiceWatch 发布的事件
+func collect(handle func(*ServiceWatch, client.LdapChange), change client.LdapChange) []event.Event {
+	bus := event.NewBus()
+	var events []event.Event
+	bus.Subscribe(func(e event.Event) { events = append(events, e) })
+	handle(NewServiceWatch(bus), change)
+	return events
+}
+
+func TestWatchEventIdsAreStableAcrossInstances(t *testing.T) {
+	change := client.LdapChange{
+		Type:   client.LdapEntryModified,
+		Before: userEntry("20261019000000.000001Z#000000#000#000000", map[string][]string{"uid": {"2024000001"}, "mail": {"a@example.org"}, "userPassword": {"old"}}),
+		After:  userEntry("20261019000100.000001Z#000000#000#000000", map[string][]string{"uid": {"2024000001"}, "mail": {"b@example.org"}, "userPassword": {"new"}}),
+	}
+
+	replicaA, replicaB := collect((*ServiceWatch).HandleUser, change), collect((*ServiceWatch).HandleUser, change)
+	types := func(events []event.Event) []event.Type {
+		var types []event.Type
+		for _, e := range events {
+			types = append(types, e.Type)
+		}
+		return types
+	}
+	if got := types(replicaA); !slices.Equal(got, []event.Type{event.UserPasswordChanged, event.UserUpdated}) {
+		t.Fatalf("got events %v", got)
+	}
+	for i := range replicaA {
+		if replicaA[i].Id != replicaB[i].Id {
+			t.Errorf("event %s has id %q on replica A and %q on replica B", replicaA[i].Type, replicaA[i].Id, replicaB[i].Id)
+		}
+		if replicaA[i].Source != event.SourceDirectory || replicaA[i].Actor != "cn=admin,dc=asynclab,dc=club" {
+			t.Errorf("got source %q, actor %q", replicaA[i].Source, replicaA[i].Actor)
+		}
+	}
+	if replicaA[0].Id == replicaA[1].Id {
+		t.Errorf("events of the same change share id %q", replicaA[0].Id)
+	}
+	if data := replicaA[1].Data["attributes"]; data != "mail" {
+		t.Errorf("got changed attributes %q, want mail", data)
+	}
+
+	// 同一条目的下一次修改有新的 Id
+	next := change
+	next.Before, next.After = change.After, userEntry("20261019000200.000001Z#000000#000#000000", map[string][]string{"uid": {"2024000001"}, "mail": {"c@example.org"}, "userPassword": {"new"}})
+	if events := collect((*ServiceWatch).HandleUser, next); len(events) != 1 || events[0].Id == replicaA[1].Id {
+		t.Errorf("next modification got events %+v", events)
+	}
+}
+
+func TestWatchMarksReplicaWritesAndGroupMembers(t *testing.T) {
+	group := func(members ...string) *ldap.Entry {
+		return ldap.NewEntry("cn=admin,ou=supplementary,ou=groups,dc=asynclab,dc=club", map[string][]string{
+			"cn":        {"admin"},
+			"entryUUID": {"6b0f4c2e-2222-4a5b-9c3d-000000000002"},
+			"entryCSN":  {"20261019000000.000001Z#000000#000#000000"},
+			"memberUid": members,
+		})
+	}
+	events := collect((*ServiceWatch).HandleGroup, client.LdapChange{Type: client.LdapEntryModified, Before: group("2024000001", "2024000002"), After: group("2024000002", "2024000003"), Replica: true})
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want one removal and one addition", len(events))
+	}
+	removed, added := events[0], events[1]
+	if removed.Type != event.GroupMemberRemoved || removed.Data["uid"] != "2024000001" || added.Type != event.GroupMemberAdded || added.Data["uid"] != "2024000003" {
+		t.Errorf("got %+v and %+v", removed, added)
+	}
+	for _, e := range events {
+		if e.Source != event.SourceReplica {
+			t.Errorf("write of another instance got source %q, want replica", e.Source)
+		}
+		if e.Subject != "admin" || e.Data["ou"] != "supplementary" {
+			t.Errorf("got subject %q, ou %q", e.Subject, e.Data["ou"])
+		}
+	}
+
+	deleted := collect((*ServiceWatch).HandleUser, client.LdapChange{Type: client.LdapEntryDeleted, Before: userEntry("20261019000000.000001Z#000000#000#000000", map[string][]string{"uid": {"2024000001"}})})
+	if len(deleted) != 1 || deleted[0].Type != event.UserDeleted || deleted[0].Data["category"] != "member" {
+		t.Fatalf("got %+v", deleted)
+	}
+	if deleted[0].Id != "directory:6b0f4c2e-1111-4a5b-9c3d-000000000001:deleted:user.deleted" {
+		t.Errorf("got id %q", deleted[0].Id)
+	}
+}
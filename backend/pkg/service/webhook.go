@@ -0,0 +1,191 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/event"
+	"asynclab.club/asynx/backend/pkg/persist"
+	"asynclab.club/asynx/backend/pkg/webhook"
+	"github.com/google/uuid"
+)
+
+type ServiceWebhook struct {
+	dispatcher *webhook.Dispatcher
+}
+
+func NewServiceWebhook(dispatcher *webhook.Dispatcher) *ServiceWebhook {
+	return &ServiceWebhook{dispatcher: dispatcher}
+}
+
+func validateEndpoint(endpointUrl string, events []string) error {
+	u, err := url.Parse(endpointUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return WrapError(ErrInvalid, fmt.Sprintf("invalid webhook url: %s", endpointUrl))
+	}
+	if len(events) == 0 {
+		return WrapError(ErrInvalid, "at least one event is required")
+	}
+	for _, pattern := range events {
+		if !event.ValidatePattern(pattern) {
+			return WrapError(ErrInvalid, fmt.Sprintf("unknown event: %s", pattern))
+		}
+	}
+	return nil
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// withoutSecret 隐藏密钥，密钥只在创建或轮换时返回一次
+func withoutSecret(endpoint webhook.Endpoint) *webhook.Endpoint {
+	endpoint.Secret = ""
+	return &endpoint
+}
+
+func (s *ServiceWebhook) List() ([]*webhook.Endpoint, error) {
+	endpoints, err := s.dispatcher.ListEndpoints()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*webhook.Endpoint, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		result = append(result, withoutSecret(endpoint))
+	}
+	return result, nil
+}
+
+func (s *ServiceWebhook) get(id string) (webhook.Endpoint, error) {
+	endpoint, ok, err := s.dispatcher.GetEndpoint(id)
+	if err != nil {
+		return endpoint, err
+	}
+	if !ok {
+		return endpoint, WrapError(ErrNotFound, fmt.Sprintf("webhook %s not found", id))
+	}
+	return endpoint, nil
+}
+
+func (s *ServiceWebhook) Get(id string) (*webhook.Endpoint, error) {
+	endpoint, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return withoutSecret(endpoint), nil
+}
+
+// Create 注册新端点，secret 为空时自动生成，返回值中包含密钥
+func (s *ServiceWebhook) Create(endpointUrl string, events []string, description string, secret string) (*webhook.Endpoint, error) {
+	if err := validateEndpoint(endpointUrl, events); err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		var err error
+		if secret, err = generateSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	endpoint := webhook.Endpoint{
+		Id:          uuid.NewString(),
+		Url:         endpointUrl,
+		Secret:      secret,
+		Events:      events,
+		Active:      true,
+		Description: description,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.dispatcher.CreateEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+	return &endpoint, nil
+}
+
+// Update 修改端点，rotateSecret 为 true 时生成新密钥并在返回值中给出
+func (s *ServiceWebhook) Update(id string, endpointUrl string, events []string, active bool, description string, rotateSecret bool) (*webhook.Endpoint, error) {
+	if err := validateEndpoint(endpointUrl, events); err != nil {
+		return nil, err
+	}
+	secret := ""
+	if rotateSecret {
+		var err error
+		if secret, err = generateSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	var endpoint webhook.Endpoint
+	err := s.dispatcher.UpdateEndpoint(id, func(e *webhook.Endpoint) error {
+		e.Url = endpointUrl
+		e.Events = events
+		e.Active = active
+		e.Description = description
+		e.UpdatedAt = time.Now()
+		if rotateSecret {
+			e.Secret = secret
+		}
+		endpoint = *e
+		return nil
+	})
+	if errors.Is(err, persist.ErrNotFound) {
+		return nil, WrapError(ErrNotFound, fmt.Sprintf("webhook %s not found", id))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if rotateSecret {
+		return &endpoint, nil
+	}
+	return withoutSecret(endpoint), nil
+}
+
+func (s *ServiceWebhook) Delete(id string) error {
+	if _, err := s.get(id); err != nil {
+		return err
+	}
+	return s.dispatcher.DeleteEndpoint(id)
+}
+
+func (s *ServiceWebhook) ListDeliveries(id string) ([]webhook.Delivery, error) {
+	if _, err := s.get(id); err != nil {
+		return nil, err
+	}
+	return s.dispatcher.ListDeliveries(id)
+}
+
+func (s *ServiceWebhook) GetDelivery(endpointId string, id string) (*webhook.Delivery, error) {
+	delivery, ok, err := s.dispatcher.GetDelivery(id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || delivery.EndpointId != endpointId {
+		return nil, WrapError(ErrNotFound, fmt.Sprintf("delivery %s not found", id))
+	}
+	return &delivery, nil
+}
+
+func (s *ServiceWebhook) Redeliver(endpointId string, id string) (*webhook.Delivery, error) {
+	if _, err := s.GetDelivery(endpointId, id); err != nil {
+		return nil, err
+	}
+	delivery, err := s.dispatcher.Redeliver(id)
+	if errors.Is(err, persist.ErrNotFound) {
+		return nil, WrapError(ErrNotFound, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
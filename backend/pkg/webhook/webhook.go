@@ -0,0 +1,427 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/event"
+	"asynclab.club/asynx/backend/pkg/persist"
+	"asynclab.club/asynx/backend/pkg/security"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	HeaderEvent = "X-Asynx-Event"
+	// HeaderDelivery 为投递 Id，重试时不变，接收方可以据此去重
+	HeaderDelivery  = "X-Asynx-Delivery"
+	HeaderTimestamp = "X-Asynx-Timestamp"
+	// HeaderSignature 为 sha256=<hex>，签名内容是 "<timestamp>.<body>"，接收方应同时校验时间戳以防重放
+	HeaderSignature = "X-Asynx-Signature"
+)
+
+type Endpoint struct {
+	Id          string    `json:"id"`
+	Url         string    `json:"url"`
+	Secret      string    `json:"secret,omitempty"`
+	Events      []string  `json:"events"`
+	Active      bool      `json:"active"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Accepts 判断端点是否订阅了该事件
+func (e *Endpoint) Accepts(t event.Type) bool {
+	return e.Active && slices.ContainsFunc(e.Events, t.Match)
+}
+
+type DeliveryState string
+
+const (
+	DeliveryPending   DeliveryState = "pending"
+	DeliverySucceeded DeliveryState = "succeeded"
+	DeliveryFailed    DeliveryState = "failed"
+)
+
+type Delivery struct {
+	Id            string        `json:"id"`
+	EndpointId    string        `json:"endpointId"`
+	Event         event.Event   `json:"event"`
+	State         DeliveryState `json:"state"`
+	Attempts      int           `json:"attempts"`
+	StatusCode    int           `json:"statusCode,omitempty"`
+	Response      string        `json:"response,omitempty"`
+	LastError     string        `json:"lastError,omitempty"`
+	NextAttemptAt time.Time     `json:"nextAttemptAt"`
+	CreatedAt     time.Time     `json:"createdAt"`
+	DeliveredAt   *time.Time    `json:"deliveredAt,omitempty"`
+	RedeliveryOf  string        `json:"redeliveryOf,omitempty"`
+}
+
+// errClaimed 表示投递已被其他实例领取或已经结束
+var errClaimed = errors.New("delivery claimed by another instance")
+
+// Dispatcher 把总线上的事件投递给订阅的端点，失败时按指数退避重试。
+// 端点和投递记录保存在共享存储中，端点密钥加密保存
+type Dispatcher struct {
+	cfg        *config.ConfigWebhook
+	client     *http.Client
+	endpoints  *persist.SharedCollection[Endpoint]
+	deliveries *persist.SharedCollection[Delivery]
+	wake       chan struct{}
+	sending    sync.Mutex
+}
+
+func NewDispatcher(cfg *config.ConfigWebhook, shared persist.SharedBackend, bus *event.Bus) *Dispatcher {
+	d := &Dispatcher{
+		cfg:        cfg,
+		client:     &http.Client{Timeout: cfg.Timeout},
+		endpoints:  persist.NewSharedCollection[Endpoint](shared, "webhooks"),
+		deliveries: persist.NewSharedCollection[Delivery](shared, "webhook-deliveries"),
+		wake:       make(chan struct{}, 1),
+	}
+	bus.Subscribe(d.handle)
+	return d
+}
+
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Dispatcher) handle(e event.Event) {
+	// 其他实例的写入已由该实例以 asynx 来源发布
+	if e.Source == event.SourceReplica {
+		return
+	}
+	endpoints, err := d.endpoints.List()
+	if err != nil {
+		logrus.Errorf("Failed to list webhooks for event %s: %v", e.Id, err)
+		return
+	}
+	queued := false
+	for _, endpoint := range endpoints {
+		if !endpoint.Accepts(e.Type) {
+			continue
+		}
+		_, err := d.enqueue(DeliveryId(endpoint.Id, e.Id), endpoint.Id, e, "")
+		if errors.Is(err, persist.ErrExists) {
+			// 其他实例已经为同一事件排队
+			continue
+		}
+		if err != nil {
+			logrus.Errorf("Failed to queue webhook %s for event %s: %v", endpoint.Id, e.Id, err)
+			continue
+		}
+		queued = true
+	}
+	if queued {
+		d.notify()
+	}
+}
+
+// DeliveryId 返回事件对端点的投递 Id
+func DeliveryId(endpointId string, eventId string) string {
+	sum := sha256.Sum256([]byte(endpointId + "\n" + eventId))
+	return hex.EncodeToString(sum[:16])
+}
+
+func (d *Dispatcher) enqueue(id string, endpointId string, e event.Event, redeliveryOf string) (*Delivery, error) {
+	now := time.Now()
+	delivery := Delivery{
+		Id:            id,
+		EndpointId:    endpointId,
+		Event:         e,
+		State:         DeliveryPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		RedeliveryOf:  redeliveryOf,
+	}
+	if err := d.deliveries.Create(delivery.Id, delivery); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+func sealEndpoint(endpoint *Endpoint) error {
+	secret, err := security.SealSecret(endpoint.Secret)
+	if err != nil {
+		return fmt.Errorf("endpoint %s: %w", endpoint.Id, err)
+	}
+	endpoint.Secret = secret
+	return nil
+}
+
+func openEndpoint(endpoint *Endpoint) error {
+	secret, err := security.OpenSecret(endpoint.Secret)
+	if err != nil {
+		return fmt.Errorf("endpoint %s: %w", endpoint.Id, err)
+	}
+	endpoint.Secret = secret
+	return nil
+}
+
+func (d *Dispatcher) ListEndpoints() ([]Endpoint, error) {
+	endpoints, err := d.endpoints.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := range endpoints {
+		if err := openEndpoint(&endpoints[i]); err != nil {
+			return nil, err
+		}
+	}
+	slices.SortFunc(endpoints, func(a, b Endpoint) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return endpoints, nil
+}
+
+func (d *Dispatcher) GetEndpoint(id string) (Endpoint, bool, error) {
+	endpoint, ok, err := d.endpoints.Get(id)
+	if err != nil || !ok {
+		return endpoint, ok, err
+	}
+	return endpoint, true, openEndpoint(&endpoint)
+}
+
+func (d *Dispatcher) CreateEndpoint(endpoint Endpoint) error {
+	if err := sealEndpoint(&endpoint); err != nil {
+		return err
+	}
+	return d.endpoints.Create(endpoint.Id, endpoint)
+}
+
+// UpdateEndpoint 读取、修改并写回端点，其他实例同时修改时 fn 会被再次调用
+func (d *Dispatcher) UpdateEndpoint(id string, fn func(endpoint *Endpoint) error) error {
+	return d.endpoints.Update(id, func(endpoint *Endpoint) error {
+		if err := openEndpoint(endpoint); err != nil {
+			return err
+		}
+		if err := fn(endpoint); err != nil {
+			return err
+		}
+		return sealEndpoint(endpoint)
+	})
+}
+
+// DeleteEndpoint 删除端点及其投递记录，正在进行的投递结束后不再记录结果
+func (d *Dispatcher) DeleteEndpoint(id string) error {
+	if err := d.endpoints.Delete(id); err != nil {
+		return err
+	}
+	_, err := d.deliveries.DeleteFunc(func(_ string, delivery Delivery) bool { return delivery.EndpointId == id })
+	return err
+}
+
+// ListDeliveries 按创建时间倒序返回端点的投递记录
+func (d *Dispatcher) ListDeliveries(endpointId string) ([]Delivery, error) {
+	deliveries, err := d.deliveries.List()
+	if err != nil {
+		return nil, err
+	}
+	deliveries = slices.DeleteFunc(deliveries, func(delivery Delivery) bool {
+		return delivery.EndpointId != endpointId
+	})
+	slices.SortFunc(deliveries, func(a, b Delivery) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return deliveries, nil
+}
+
+func (d *Dispatcher) GetDelivery(id string) (Delivery, bool, error) {
+	return d.deliveries.Get(id)
+}
+
+// Redeliver 以同一事件创建一次新的投递，新投递有自己的 Id，原记录保持不变
+func (d *Dispatcher) Redeliver(id string) (*Delivery, error) {
+	delivery, ok, err := d.deliveries.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("delivery %s: %w", id, persist.ErrNotFound)
+	}
+	if _, ok, err := d.endpoints.Get(delivery.EndpointId); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, fmt.Errorf("endpoint %s: %w", delivery.EndpointId, persist.ErrNotFound)
+	}
+
+	redelivery, err := d.enqueue(uuid.NewString(), delivery.EndpointId, delivery.Event, delivery.Id)
+	if err != nil {
+		return nil, err
+	}
+	d.notify()
+	return redelivery, nil
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+
+// Sign 计算请求签名
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.cfg.BaseDelay
+	for i := 1; i < attempts && delay < d.cfg.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, d.cfg.MaxDelay)
+}
+
+func (d *Dispatcher) post(ctx context.Context, endpoint *Endpoint, delivery *Delivery) (int, string, error) {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return 0, "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "asynx-webhook")
+	req.Header.Set(HeaderEvent, delivery.Event.Type.String())
+	req.Header.Set(HeaderDelivery, delivery.Id)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(endpoint.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	response, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(response), fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, string(response), nil
+}
+
+// claim 领取到期的投递，租约到期前其他实例不会再次领取
+func (d *Dispatcher) claim(id string, now time.Time) error {
+	return d.deliveries.Update(id, func(dl *Delivery) error {
+		if dl.State != DeliveryPending || dl.NextAttemptAt.After(now) {
+			return errClaimed
+		}
+		dl.NextAttemptAt = now.Add(2 * d.cfg.Timeout)
+		return nil
+	})
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery Delivery) {
+	if err := d.claim(delivery.Id, time.Now()); err != nil {
+		if !errors.Is(err, errClaimed) && !errors.Is(err, persist.ErrNotFound) {
+			logrus.Errorf("Failed to claim webhook delivery %s: %v", delivery.Id, err)
+		}
+		return
+	}
+
+	endpoint, ok, err := d.GetEndpoint(delivery.EndpointId)
+	var (
+		status   int
+		response string
+	)
+	// 读取端点失败时按普通失败处理，之后重试
+	gone := err == nil && !ok
+	switch {
+	case err != nil:
+	case gone:
+		err = fmt.Errorf("endpoint %s no longer exists", delivery.EndpointId)
+	default:
+		status, response, err = d.post(ctx, &endpoint, &delivery)
+	}
+
+	updateErr := d.deliveries.Update(delivery.Id, func(dl *Delivery) error {
+		dl.Attempts++
+		dl.StatusCode = status
+		dl.Response = response
+		if err == nil {
+			now := time.Now()
+			dl.State = DeliverySucceeded
+			dl.DeliveredAt = &now
+			dl.LastError = ""
+			return nil
+		}
+
+		dl.LastError = err.Error()
+		if gone || dl.Attempts >= d.cfg.MaxAttempts {
+			dl.State = DeliveryFailed
+			logrus.Errorf("Webhook delivery %s to %s failed after %d attempts: %v", dl.Id, endpoint.Url, dl.Attempts, err)
+			return nil
+		}
+		dl.NextAttemptAt = time.Now().Add(d.backoff(dl.Attempts))
+		logrus.Warnf("Webhook delivery %s to %s failed (attempt %d), retry at %s: %v", dl.Id, endpoint.Url, dl.Attempts, dl.NextAttemptAt.Format(time.RFC3339), err)
+		return nil
+	})
+	if updateErr != nil && !errors.Is(updateErr, persist.ErrNotFound) {
+		logrus.Errorf("Failed to update webhook delivery %s: %v", delivery.Id, updateErr)
+	}
+}
+
+// Flush 投递所有到期的记录，并清理超过保留期限的已结束记录
+func (d *Dispatcher) Flush(ctx context.Context) {
+	d.sending.Lock()
+	defer d.sending.Unlock()
+
+	now := time.Now()
+	deliveries, err := d.deliveries.List()
+	if err != nil {
+		logrus.Errorf("Failed to list webhook deliveries: %v", err)
+		return
+	}
+	slices.SortFunc(deliveries, func(a, b Delivery) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+		if delivery.State == DeliveryPending && !delivery.NextAttemptAt.After(now) {
+			d.deliver(ctx, delivery)
+		}
+	}
+
+	before := now.Add(-d.cfg.Retention)
+	if _, err := d.deliveries.DeleteFunc(func(_ string, delivery Delivery) bool {
+		return delivery.State != DeliveryPending && delivery.CreatedAt.Before(before)
+	}); err != nil {
+		logrus.Errorf("Failed to prune webhook deliveries: %v", err)
+	}
+}
+
+// Run 启动投递循环，直到 ctx 结束
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.Flush(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"aidanwoods.dev/go-paseto"
+	"asynclab.club/asynx/backend/pkg/config"
+	"asynclab.club/asynx/backend/pkg/event"
+	"asynclab.club/asynx/backend/pkg/persist"
+)
+
+// recorder 记录端点收到的请求，status 为返回的状态码
+type recorder struct {
+	mu         sync.Mutex
+	status     int
+	events     []event.Event
+	deliveries []string
+}
+
+func newRecorder(t *testing.T) (*recorder, *httptest.Server) {
+	rec := &recorder{status: http.StatusOK}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e event.Event
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			t.Error(err)
+		}
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		rec.events = append(rec.events, e)
+		rec.deliveries = append(rec.deliveries, r.Header.Get(HeaderDelivery))
+		w.WriteHeader(rec.status)
+	}))
+	t.Cleanup(server.Close)
+	return rec, server
+}
+
+func (r *recorder) received() ([]event.Event, []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]event.Event(nil), r.events...), append([]string(nil), r.deliveries...)
+}
+
+func TestEndpointsAreSharedBetweenInstances(t *testing.T) {
+	rec, server := newRecorder(t)
+
+	cfg := &config.ConfigWebhook{MaxAttempts: 1, Timeout: time.Second, Retention: time.Hour}
+	shared := persist.NewMemoryBackend()
+	busA, busB := event.NewBus(), event.NewBus()
+	replicaA := NewDispatcher(cfg, shared, busA)
+	replicaB := NewDispatcher(cfg, shared, busB)
+
+	if err := replicaA.CreateEndpoint(Endpoint{Id: "hook", Url: server.URL, Events: []string{"user.*"}, Active: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 在实例 B 上产生的事件投递给在实例 A 上注册的端点，投递记录在实例 A 上也可见
+	busB.Publish(event.Event{Type: event.UserCreated, Subject: "2024000001"})
+	replicaB.Flush(context.Background())
+	if events, _ := rec.received(); len(events) != 1 || events[0].Type != event.UserCreated || events[0].Subject != "2024000001" {
+		t.Fatalf("got %+v, want the event on replica B delivered once", events)
+	}
+	if deliveries, err := replicaA.ListDeliveries("hook"); err != nil || len(deliveries) != 1 || deliveries[0].State != DeliverySucceeded {
+		t.Errorf("got deliveries %+v, %v on replica A", deliveries, err)
+	}
+
+	// 在实例 B 上停用后实例 A 不再投递
+	if err := replicaB.UpdateEndpoint("hook", func(e *Endpoint) error {
+		e.Active = false
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	busA.Publish(event.Event{Type: event.UserDeleted, Subject: "2024000001"})
+	if deliveries, _ := replicaA.ListDeliveries("hook"); len(deliveries) != 1 {
+		t.Errorf("endpoint deactivated on replica B queued %d deliveries on replica A", len(deliveries)-1)
+	}
+}
+
+func TestDirectoryEventsAreDeliveredOnce(t *testing.T) {
+	rec, server := newRecorder(t)
+
+	cfg := &config.ConfigWebhook{MaxAttempts: 1, Timeout: time.Second, Retention: time.Hour}
+	shared := persist.NewMemoryBackend()
+	busA, busB := event.NewBus(), event.NewBus()
+	replicaA := NewDispatcher(cfg, shared, busA)
+	replicaB := NewDispatcher(cfg, shared, busB)
+	if err := replicaA.CreateEndpoint(Endpoint{Id: "hook", Url: server.URL, Events: []string{"*"}, Active: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 两个实例的目录监听看到同一个外部修改，并看到实例 A 自己写入的回声
+	external := event.Event{Id: "directory:uuid-1:csn-1:user.updated", Type: event.UserUpdated, Subject: "2024000001", Source: event.SourceDirectory}
+	busA.Publish(external)
+	busB.Publish(external)
+	busA.Publish(event.Event{Id: "asynx-write", Type: event.UserCreated, Subject: "2024000002"})
+	busB.Publish(event.Event{Id: "directory:uuid-2:csn-1:user.created", Type: event.UserCreated, Subject: "2024000002", Source: event.SourceReplica})
+
+	replicaA.Flush(context.Background())
+	replicaB.Flush(context.Background())
+	events, deliveries := rec.received()
+	if len(events) != 2 || events[0].Id != external.Id || events[1].Id != "asynx-write" {
+		t.Fatalf("got %+v, want the external change and the asynx write once each", events)
+	}
+	if deliveries[0] != DeliveryId("hook", external.Id) {
+		t.Errorf("got delivery id %q, want %q", deliveries[0], DeliveryId("hook", external.Id))
+	}
+}
+
+func TestFailedDeliveryIsRetriedByAnotherInstance(t *testing.T) {
+	rec, server := newRecorder(t)
+	rec.status = http.StatusServiceUnavailable
+
+	cfg := &config.ConfigWebhook{MaxAttempts: 3, Timeout: time.Second, Retention: time.Hour}
+	shared := persist.NewMemoryBackend()
+	busA := event.NewBus()
+	replicaA := NewDispatcher(cfg, shared, busA)
+	replicaB := NewDispatcher(cfg, shared, event.NewBus())
+	if err := replicaA.CreateEndpoint(Endpoint{Id: "hook", Url: server.URL, Events: []string{"*"}, Active: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	busA.Publish(event.Event{Id: "e1", Type: event.UserCreated, Subject: "2024000001"})
+	replicaA.Flush(context.Background())
+	deliveries, err := replicaB.ListDeliveries("hook")
+	if err != nil || len(deliveries) != 1 || deliveries[0].State != DeliveryPending || deliveries[0].Attempts != 1 {
+		t.Fatalf("got %+v, %v after the first attempt", deliveries, err)
+	}
+
+	// 退避结束后由实例 B 重试，投递 Id 不变
+	rec.mu.Lock()
+	rec.status = http.StatusOK
+	rec.mu.Unlock()
+	if err := replicaB.deliveries.Update(deliveries[0].Id, func(dl *Delivery) error {
+		dl.NextAttemptAt = time.Now()
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	replicaB.Flush(context.Background())
+	replicaA.Flush(context.Background())
+
+	_, ids := rec.received()
+	if len(ids) != 2 || ids[0] != ids[1] {
+		t.Errorf("got delivery ids %v, want the same id for the retry", ids)
+	}
+	if delivery, ok, err := replicaA.GetDelivery(deliveries[0].Id); err != nil || !ok || delivery.State != DeliverySucceeded || delivery.Attempts != 2 {
+		t.Errorf("got %+v, %v, %v", delivery, ok, err)
+	}
+}
+
+func TestSecretIsEncryptedAtRest(t *testing.T) {
+	config.PasetoKey = paseto.NewV4SymmetricKey()
+	signatures := make(chan bool, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signatures <- r.Header.Get(HeaderSignature) == Sign("s3cret", r.Header.Get(HeaderTimestamp), body)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := &config.ConfigWebhook{MaxAttempts: 1, Timeout: time.Second, Retention: time.Hour}
+	shared := persist.NewMemoryBackend()
+	bus := event.NewBus()
+	dispatcher := NewDispatcher(cfg, shared, bus)
+	if err := dispatcher.CreateEndpoint(Endpoint{Id: "hook", Url: server.URL, Secret: "s3cret", Events: []string{"user.*"}, Active: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, _, err := persist.NewSharedCollection[Endpoint](shared, "webhooks").Get("hook")
+	if err != nil || stored.Secret == "" || strings.Contains(stored.Secret, "s3cret") {
+		t.Fatalf("got stored secret %q, %v, want it encrypted", stored.Secret, err)
+	}
+	if endpoint, _, err := dispatcher.GetEndpoint("hook"); err != nil || endpoint.Secret != "s3cret" {
+		t.Errorf("got secret %q, %v, want the plaintext", endpoint.Secret, err)
+	}
+
+	bus.Publish(event.Event{Type: event.UserCreated, Subject: "2024000001"})
+	dispatcher.Flush(context.Background())
+	if !<-signatures {
+		t.Error("request is not signed with the endpoint secret")
+	}
+}
@@ -19,8 +19,14 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/hello": {
+        "/access-tokens": {
             "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取自己的个人访问令牌，不包含令牌明文。需要 users.self 权限。拥有 access-tokens.manage 权限的用户可以通过 uid 查看其他用户的令牌。",
                 "consumes": [
                     "application/json"
                 ],
@@ -28,12 +34,56 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "index"
+                    "access-tokens"
+                ],
+                "summary": "获取个人访问令牌列表",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID，默认为当前用户",
+                        "name": "uid",
+                        "in": "query"
+                    }
                 ],
-                "summary": "打招呼",
                 "responses": {
                     "200": {
-                        "description": "Hello, AsyncLab",
+                        "description": "成功返回令牌列表",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/service.AccessToken"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -44,11 +94,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/tokens": {
+            },
             "post": {
-                "description": "通过用户名和密码验证用户身份并生成访问令牌",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "为当前用户创建个人访问令牌，供脚本通过 Authorization: Bearer 头调用接口。令牌明文只在本次返回，服务端只保存摘要。\nscopes 的格式为 \u003c资源\u003e:\u003cread|write\u003e，资源是 /api 下的第一级路径（如 users、webhooks），* 表示全部资源；read 只允许 GET 请求，write 允许全部请求。令牌不能访问 access-tokens 接口。\nrole 的权限必须是自己当前角色权限的子集，为空时使用当前角色；持有者被降级后令牌也随之降级，持有者的新角色与令牌角色互不包含时令牌失效。需要 users.self 权限。",
                 "consumes": [
                     "application/json"
                 ],
@@ -56,9 +109,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tokens"
+                    "access-tokens"
                 ],
-                "summary": "创建访问令牌",
+                "summary": "创建个人访问令牌",
                 "parameters": [
                     {
                         "description": "创建令牌请求",
@@ -66,18 +119,18 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/controller.CreateTokenRequest"
+                            "$ref": "#/definitions/service.AccessTokenSpec"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "返回访问令牌",
+                        "description": "成功返回令牌（包含明文）",
                         "schema": {
                             "type": "object",
                             "properties": {
                                 "data": {
-                                    "type": "string"
+                                    "$ref": "#/definitions/service.AccessToken"
                                 }
                             }
                         }
@@ -94,7 +147,18 @@ const docTemplate = `{
                         }
                     },
                     "401": {
-                        "description": "用户名或密码错误",
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -118,14 +182,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/users": {
-            "get": {
+        "/access-tokens/{id}": {
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "获取所有用户列表信息（包含角色和类别）。需要 ADMIN 角色权限才能查看所有用户，DEFAULT 用户只能查看自己组织单元的用户。",
+                "description": "删除个人访问令牌，立即失效。需要 users.self 权限。拥有 access-tokens.manage 权限的用户可以撤销任何用户的令牌，其他用户只能撤销自己的。",
                 "consumes": [
                     "application/json"
                 ],
@@ -133,20 +197,26 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "access-tokens"
+                ],
+                "summary": "撤销个人访问令牌",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "令牌ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "获取用户列表",
                 "responses": {
                     "200": {
-                        "description": "成功返回用户列表",
+                        "description": "成功撤销，返回 'ok'",
                         "schema": {
                             "type": "object",
                             "properties": {
                                 "data": {
-                                    "type": "array",
-                                    "items": {
-                                        "$ref": "#/definitions/service.UserProfile"
-                                    }
+                                    "type": "string"
                                 }
                             }
                         }
@@ -162,8 +232,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "403": {
-                        "description": "权限不足",
+                    "404": {
+                        "description": "令牌不存在",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -185,14 +255,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/auth/session": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "创建新用户账号。需要 ADMIN 角色权限。",
+                "description": "用 Authorization 头中的登录令牌换取 asynx_forward_auth Cookie（HttpOnly、Secure、SameSite=Lax），前端在登录后调用。\nCookie 中的令牌只能用于 /api/auth/verify，不能访问其他接口；个人访问令牌不能换取。\nCookie 会发给 FORWARD_AUTH_COOKIE_DOMAIN 下的所有主机，反向代理必须在把请求转发给上游应用之前删除它",
                 "consumes": [
                     "application/json"
                 ],
@@ -200,23 +272,12 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
-                ],
-                "summary": "注册新用户",
-                "parameters": [
-                    {
-                        "description": "注册用户请求",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/controller.RequestRegister"
-                        }
-                    }
+                    "auth"
                 ],
+                "summary": "签发转发认证 Cookie",
                 "responses": {
                     "200": {
-                        "description": "成功注册用户，返回 'ok'",
+                        "description": "成功签发，返回 'ok'",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -226,8 +287,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "400": {
-                        "description": "请求参数错误",
+                    "401": {
+                        "description": "未登录",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -237,8 +298,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "401": {
-                        "description": "未授权访问",
+                    "403": {
+                        "description": "账号已到期",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -248,8 +309,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "403": {
-                        "description": "权限不足",
+                    "404": {
+                        "description": "用户不存在",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -259,8 +320,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "409": {
-                        "description": "用户已存在",
+                    "500": {
+                        "description": "服务器内部错误",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -269,9 +330,24 @@ const docTemplate = `{
                                 }
                             }
                         }
-                    },
-                    "500": {
-                        "description": "服务器内部错误",
+                    }
+                }
+            },
+            "delete": {
+                "description": "前端退出登录时调用，各子域名上的应用随之失去登录状态",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "清除转发认证 Cookie",
+                "responses": {
+                    "200": {
+                        "description": "成功清除，返回 'ok'",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -284,14 +360,74 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/{uid}": {
+        "/auth/verify": {
+            "get": {
+                "description": "供 nginx auth_request 和 Traefik ForwardAuth 使用，只读取 asynx_forward_auth Cookie 中的转发认证令牌，不接受登录令牌和访问令牌。\n反向代理应在转发给上游应用前删除该 Cookie，如 nginx 中用 map 从 $http_cookie 去掉后以 proxy_set_header Cookie 传递。\n通过时返回 200 并设置 X-Auth-User、X-Auth-Role、X-Auth-Groups 头；未登录时跳转到登录页，登录后回到原地址；\n权限不足时返回 403。原地址取自 X-Original-URL 头，或 X-Forwarded-Proto、X-Forwarded-Host、X-Forwarded-Uri 头。\nnginx 的 auth_request 只接受 2xx、401 和 403，需要传 redirect=false，再通过 error_page 401 跳转到响应的 Location 头",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "反向代理转发认证",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "要求的最低角色，默认为任意有角色的用户",
+                        "name": "role",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "要求拥有的权限，如 audit.read，可以重复或用逗号分隔，需要全部满足",
+                        "name": "permission",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "要求所属的组，可以重复或用逗号分隔，满足其一即可",
+                        "name": "group",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "未登录时是否返回 302，默认为 true；为 false 时返回 401",
+                        "name": "redirect",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "认证通过"
+                    },
+                    "302": {
+                        "description": "未登录，跳转到登录页"
+                    },
+                    "400": {
+                        "description": "参数错误"
+                    },
+                    "401": {
+                        "description": "未登录"
+                    },
+                    "403": {
+                        "description": "权限不足"
+                    }
+                }
+            }
+        },
+        "/broadcasts": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "根据用户ID获取用户详细信息（包含角色和类别）。需要 RESTRICTED 或更高权限。ADMIN 用户可以查看所有用户信息，DEFAULT 用户只能查看自己组织单元的用户信息，RESTRICTED 用户只能查看自己的信息。",
+                "description": "获取所有群发记录及投递统计，不包含收件人明细。需要 mail.manage 权限。",
                 "consumes": [
                     "application/json"
                 ],
@@ -299,37 +435,20 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
-                ],
-                "summary": "获取用户信息",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "用户ID，使用 'me' 可获取当前用户信息",
-                        "name": "uid",
-                        "in": "path",
-                        "required": true
-                    }
+                    "broadcasts"
                 ],
+                "summary": "获取群发记录",
                 "responses": {
                     "200": {
-                        "description": "成功返回用户信息",
-                        "schema": {
-                            "type": "object",
-                            "properties": {
-                                "data": {
-                                    "$ref": "#/definitions/service.UserProfile"
-                                }
-                            }
-                        }
-                    },
-                    "400": {
-                        "description": "请求参数错误",
+                        "description": "成功返回群发记录",
                         "schema": {
                             "type": "object",
                             "properties": {
                                 "data": {
-                                    "type": "string"
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/service.Broadcast"
+                                    }
                                 }
                             }
                         }
@@ -355,38 +474,16 @@ const docTemplate = `{
                                 }
                             }
                         }
-                    },
-                    "404": {
-                        "description": "用户不存在",
-                        "schema": {
-                            "type": "object",
-                            "properties": {
-                                "data": {
-                                    "type": "string"
-                                }
-                            }
-                        }
-                    },
-                    "500": {
-                        "description": "服务器内部错误",
-                        "schema": {
-                            "type": "object",
-                            "properties": {
-                                "data": {
-                                    "type": "string"
-                                }
-                            }
-                        }
                     }
                 }
             },
-            "delete": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "删除指定用户账号。需要 ADMIN 角色权限。不允许删除当前登录用户。",
+                "description": "向某一账号类型、角色或组的所有用户群发邮件。正文支持 Markdown 或 HTML，经邮件模板渲染后进入发件箱，按发件箱的速率限制投递。dryRun 为 true 时只返回收件人列表。需要 mail.manage 权限。",
                 "consumes": [
                     "application/json"
                 ],
@@ -394,26 +491,28 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "broadcasts"
                 ],
-                "summary": "删除用户",
+                "summary": "群发邮件",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "用户ID，不能使用 'me'",
-                        "name": "uid",
-                        "in": "path",
-                        "required": true
+                        "description": "群发请求\nformat: markdown|html\ntarget 中 category、role、group 只能指定一个",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.RequestBroadcast"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "成功删除用户，返回 'ok'",
+                        "description": "成功返回投递报告",
                         "schema": {
                             "type": "object",
                             "properties": {
                                 "data": {
-                                    "type": "string"
+                                    "$ref": "#/definitions/service.Broadcast"
                                 }
                             }
                         }
@@ -452,7 +551,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "用户不存在",
+                        "description": "组不存在",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -476,14 +575,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/{uid}/category": {
+        "/broadcasts/{id}": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "获取指定用户的账号类型（system|member|external）。需要 RESTRICTED 或更高权限。ADMIN 用户可以查看所有用户信息，DEFAULT 用户只能查看自己组织单元的用户信息，RESTRICTED 用户只能查看自己的信息。",
+                "description": "获取一次群发的投递报告，包含每个收件人的最新投递状态。需要 mail.manage 权限。",
                 "consumes": [
                     "application/json"
                 ],
@@ -491,33 +590,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "broadcasts"
                 ],
-                "summary": "获取账号类型",
-                "deprecated": true,
+                "summary": "获取投递报告",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "用户ID，使用 'me' 可获取当前用户类型",
-                        "name": "uid",
+                        "description": "群发ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "成功返回账号类型: system|member|external",
+                        "description": "成功返回投递报告",
                         "schema": {
                             "type": "object",
                             "properties": {
                                 "data": {
-                                    "type": "string"
+                                    "$ref": "#/definitions/service.Broadcast"
                                 }
                             }
                         }
                     },
-                    "400": {
-                        "description": "请求参数错误",
+                    "401": {
+                        "description": "未授权访问",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -527,19 +625,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "401": {
-                        "description": "未授权访问",
-                        "schema": {
-                            "type": "object",
-                            "properties": {
-                                "data": {
-                                    "type": "string"
-                                }
-                            }
-                        }
-                    },
-                    "403": {
-                        "description": "权限不足",
+                    "403": {
+                        "description": "权限不足",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -550,18 +637,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "用户不存在",
-                        "schema": {
-                            "type": "object",
-                            "properties": {
-                                "data": {
-                                    "type": "string"
-                                }
-                            }
-                        }
-                    },
-                    "500": {
-                        "description": "服务器内部错误",
+                        "description": "群发记录不存在",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -572,14 +648,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/cache": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "修改指定用户的账号类型。需要 ADMIN 角色权限。",
+                "description": "获取用户和角色组读缓存的条目数与命中率，命中计数从进程启动开始累计。需要 system.manage 权限。",
                 "consumes": [
                     "application/json"
                 ],
@@ -587,41 +665,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
-                ],
-                "summary": "更改账号类型",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "用户ID，不能使用 'me'",
-                        "name": "uid",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "修改账号类型请求\nsystem|member|external",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/controller.RequestModifyCategory"
-                        }
-                    }
+                    "cache"
                 ],
+                "summary": "获取缓存状态",
                 "responses": {
                     "200": {
-                        "description": "成功修改账号类型，返回 'ok'",
+                        "description": "成功返回缓存状态",
                         "schema": {
                             "type": "object",
                             "properties": {
                                 "data": {
-                                    "type": "string"
+                                    "$ref": "#/definitions/service.CacheStatus"
                                 }
                             }
                         }
                     },
-                    "400": {
-                        "description": "请求参数错误",
+                    "401": {
+                        "description": "未授权访问",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -631,8 +691,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "401": {
-                        "description": "未授权访问",
+                    "403": {
+                        "description": "权限不足",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -641,9 +701,29 @@ const docTemplate = `{
                                 }
                             }
                         }
-                    },
-                    "403": {
-                        "description": "权限不足",
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "清空用户和角色组读缓存，用于在其他副本或外部工具修改目录后立即生效。未启用缓存时不做任何事。需要 system.manage 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cache"
+                ],
+                "summary": "清空缓存",
+                "responses": {
+                    "200": {
+                        "description": "成功清空，返回 'ok'",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -653,8 +733,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "404": {
-                        "description": "用户不存在",
+                    "401": {
+                        "description": "未授权访问",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -664,8 +744,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "服务器内部错误",
+                    "403": {
+                        "description": "权限不足",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -678,14 +758,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/{uid}/password": {
-            "put": {
+        "/dev/mails": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "修改指定用户的密码。需要 RESTRICTED 或更高权限。ADMIN 用户可以修改任何用户密码，其他用户只能修改自己的密码。",
+                "description": "获取内存邮件投递器中保存的邮件，按发送时间倒序。仅在 MAIL_TRANSPORT=memory 时可用。需要 mail.manage 权限。",
                 "consumes": [
                     "application/json"
                 ],
@@ -693,41 +773,34 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "dev"
                 ],
-                "summary": "修改密码",
+                "summary": "获取已发送邮件",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "用户ID，使用 'me' 可修改当前用户密码",
-                        "name": "uid",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "修改密码请求",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/controller.RequestChangePassword"
-                        }
+                        "description": "只返回发给该地址的邮件",
+                        "name": "to",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "成功修改密码，返回 'ok'",
+                        "description": "成功返回邮件列表",
                         "schema": {
                             "type": "object",
                             "properties": {
                                 "data": {
-                                    "type": "string"
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/client.Mail"
+                                    }
                                 }
                             }
                         }
                     },
-                    "400": {
-                        "description": "请求参数错误或密码不符合要求",
+                    "401": {
+                        "description": "未授权访问",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -737,8 +810,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "401": {
-                        "description": "未授权访问",
+                    "403": {
+                        "description": "权限不足",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -747,9 +820,29 @@ const docTemplate = `{
                                 }
                             }
                         }
-                    },
-                    "403": {
-                        "description": "权限不足",
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "清空内存邮件投递器中保存的所有邮件。仅在 MAIL_TRANSPORT=memory 时可用。需要 mail.manage 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "dev"
+                ],
+                "summary": "清空已发送邮件",
+                "responses": {
+                    "200": {
+                        "description": "成功清空，返回 'ok'",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -759,8 +852,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "404": {
-                        "description": "用户不存在",
+                    "401": {
+                        "description": "未授权访问",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -770,8 +863,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "服务器内部错误",
+                    "403": {
+                        "description": "权限不足",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -784,14 +877,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/{uid}/role": {
+        "/dev/mails/{id}": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "获取指定用户的账号角色（admin|default|restricted）。需要 RESTRICTED 或更高权限。ADMIN 用户可以查看所有用户信息，DEFAULT 用户只能查看自己组织单元的用户信息，RESTRICTED 用户只能查看自己的信息。",
+                "description": "根据ID获取内存邮件投递器中保存的一封邮件。仅在 MAIL_TRANSPORT=memory 时可用。需要 mail.manage 权限。",
                 "consumes": [
                     "application/json"
                 ],
@@ -799,38 +892,26 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "dev"
                 ],
-                "summary": "获取账号角色",
-                "deprecated": true,
+                "summary": "获取已发送邮件详情",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "用户ID，使用 'me' 可获取当前用户角色",
-                        "name": "uid",
+                        "description": "邮件ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "成功返回账号角色: admin|default|restricted",
-                        "schema": {
-                            "type": "object",
-                            "properties": {
-                                "data": {
-                                    "type": "string"
-                                }
-                            }
-                        }
-                    },
-                    "400": {
-                        "description": "请求参数错误",
+                        "description": "成功返回邮件",
                         "schema": {
                             "type": "object",
                             "properties": {
                                 "data": {
-                                    "type": "string"
+                                    "$ref": "#/definitions/client.Mail"
                                 }
                             }
                         }
@@ -857,8 +938,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "服务器内部错误",
+                    "404": {
+                        "description": "邮件不存在",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -869,52 +950,42 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/events": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "修改指定用户的账号角色。需要 ADMIN 角色权限。非SYSTEM用户必须用学号作为用户名。不允许操作当前登录用户。",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "以 Server-Sent Events 推送目录变更。事件名为事件类型（如 user.created、user.role_changed），id 为事件ID，data 为事件 JSON。断线后浏览器会带上 Last-Event-ID 头自动续传；该事件已不在缓冲区时先推送一条 reset 事件，客户端应重新拉取用户列表。需要 audit.read 权限。",
                 "produces": [
-                    "application/json"
+                    "text/event-stream"
                 ],
                 "tags": [
-                    "users"
+                    "events"
                 ],
-                "summary": "更改账号角色",
+                "summary": "订阅目录变更事件",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "用户ID，不能使用 'me'",
-                        "name": "uid",
-                        "in": "path",
-                        "required": true
+                        "description": "逗号分隔的事件过滤条件，支持 user.*、group.* 和 *，默认订阅全部",
+                        "name": "events",
+                        "in": "query"
                     },
                     {
-                        "description": "修改账号角色请求\nadmin|default|restricted",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/controller.RequestModifyRole"
-                        }
+                        "type": "string",
+                        "description": "上次收到的事件ID",
+                        "name": "Last-Event-ID",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "成功修改账号角色，返回 'ok'",
+                        "description": "事件流",
                         "schema": {
-                            "type": "object",
-                            "properties": {
-                                "data": {
-                                    "type": "string"
-                                }
-                            }
+                            "type": "string"
                         }
                     },
                     "400": {
@@ -949,9 +1020,25 @@ const docTemplate = `{
                                 }
                             }
                         }
-                    },
-                    "404": {
-                        "description": "用户不存在",
+                    }
+                }
+            }
+        },
+        "/hello": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "index"
+                ],
+                "summary": "打招呼",
+                "responses": {
+                    "200": {
+                        "description": "Hello, AsyncLab",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -960,9 +1047,45 @@ const docTemplate = `{
                                 }
                             }
                         }
+                    }
+                }
+            }
+        },
+        "/invitations": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取全部邀请，包括已过期、已用完和已撤销的，按创建时间排序。需要 users.write 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "invitations"
+                ],
+                "summary": "获取邀请列表",
+                "responses": {
+                    "200": {
+                        "description": "成功返回邀请列表",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/service.Invitation"
+                                    }
+                                }
+                            }
+                        }
                     },
-                    "500": {
-                        "description": "服务器内部错误",
+                    "401": {
+                        "description": "未授权访问",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -971,81 +1094,7612 @@ const docTemplate = `{
                                 }
                             }
                         }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "创建绑定账号类型（member|external|alumni）和角色的邀请链接，可以限制为某个邮箱域名或单个邮箱，限定域名时受邀人需要先验证邮箱，链接只在创建时返回一次。\n需要 users.write 权限，委派管理员只能邀请到被委派的账号类型，没有 users.role.grant 权限时角色不能高于自己。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "invitations"
+                ],
+                "summary": "创建邀请",
+                "parameters": [
+                    {
+                        "description": "邀请",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.InvitationSpec"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回邀请和链接",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/service.Invitation"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/invitations/accept": {
+            "post": {
+                "description": "使用邀请注册账号，密码由受邀人设置并需满足强度要求，欢迎邮件中不附带密码。限定域名的邀请需要提供验证邮件链接中的 verification，邮箱必须与验证的邮箱一致。无需登录。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "invitations"
+                ],
+                "summary": "接受邀请",
+                "parameters": [
+                    {
+                        "description": "注册信息",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.InvitationAcceptSpec"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功注册，返回 'ok'",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误或邀请无效",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "用户已存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/invitations/lookup": {
+            "get": {
+                "description": "使用邀请链接中的 token 查看邀请的账号类型、角色和邮箱限制。提供验证邮件链接中的 verification 时，一并返回已验证的邮箱和姓名。无需登录。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "invitations"
+                ],
+                "summary": "查看邀请",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "邀请链接中的 token",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "验证邮件链接中的 verification",
+                        "name": "verification",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回邀请内容",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/service.InvitationPrompt"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "邀请或邮箱验证无效、已过期、已用完或已撤销",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/invitations/verify": {
+            "post": {
+                "description": "限定域名的邀请需要先验证邮箱：向受邀人填写的邮箱发送验证链接，链接中的 verification 用于接受邀请。同一邮箱每分钟最多发送一次，重新发送后之前的链接失效。无需登录。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "invitations"
+                ],
+                "summary": "验证邀请邮箱",
+                "parameters": [
+                    {
+                        "description": "邮箱和姓名",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.InvitationVerifySpec"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功发送验证邮件，返回 'ok'",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误、邀请无效或邀请不限定域名",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "发送过于频繁",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/invitations/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "撤销邀请后链接不能再使用，已通过该邀请注册的账号不受影响。需要 users.write 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "invitations"
+                ],
+                "summary": "撤销邀请",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "邀请ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功撤销，返回 'ok'",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "邀请不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取本实例的调度状态和全部后台任务，包括 cron 表达式、上次运行结果和下次运行时间。只有主实例运行任务，其他实例的记录可能不是最新的。需要 audit.read 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "获取后台任务列表",
+                "responses": {
+                    "200": {
+                        "description": "成功返回调度状态",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/service.SchedulerStatus"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/metrics": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "以 Prometheus 文本格式输出任务的运行次数、失败次数、耗时、上次成功和下次运行时间，以及本实例是否为主实例。可以使用带 jobs:read 权限范围的访问令牌抓取。需要 audit.read 权限。",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "获取后台任务指标",
+                "responses": {
+                    "200": {
+                        "description": "Prometheus 指标",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{name}/run": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "在后台立即运行一次任务，不影响下次计划运行的时间，运行结果通过任务列表查看。只能在主实例上触发。需要 system.manage 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "立即运行后台任务",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务名",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功触发，返回 'ok'",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "任务不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "本实例不是主实例或任务正在运行",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/lifecycle/exemptions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取不会被转为 alumni 的成员。需要 users.read 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "lifecycle"
+                ],
+                "summary": "获取生命周期豁免列表",
+                "responses": {
+                    "200": {
+                        "description": "成功返回豁免列表",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/service.LifecycleExemption"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/lifecycle/exemptions/{uid}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "使 member 账号不会被生命周期策略转为 alumni，已存在的豁免会被覆盖。需要 users.write 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "lifecycle"
+                ],
+                "summary": "豁免成员",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "豁免请求",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.RequestLifecycleExempt"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回豁免",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/service.LifecycleExemption"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误或用户不是 member",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "取消成员的豁免，之后的生命周期任务会按入学年份处理该成员。需要 users.write 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "lifecycle"
+                ],
+                "summary": "取消豁免",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功取消，返回 'ok'",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "豁免不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/lifecycle/preview": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "按入学年份列出在指定时间之前会被转为 alumni 的 member 账号，包括已被豁免的成员，按转换时间排序。\n默认列出提醒期内会被转换的成员。生命周期策略未启用时返回 400。需要 users.read 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "lifecycle"
+                ],
+                "summary": "预览转为 alumni 的成员",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "截止时间，RFC 3339 格式",
+                        "name": "before",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回成员列表",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/service.LifecycleCandidate"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误或策略未启用",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/mail/templates": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取所有可用的邮件模板名称。需要 mail.manage 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mail"
+                ],
+                "summary": "获取邮件模板列表",
+                "responses": {
+                    "200": {
+                        "description": "成功返回模板列表",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "type": "string"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/mail/templates/{name}/preview": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "使用示例数据渲染邮件模板，返回标题、HTML 正文和纯文本正文。覆盖目录中的修改会立即生效。需要 mail.manage 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mail"
+                ],
+                "summary": "预览邮件模板",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "模板名称\nwelcome|password-reset|role-changed|role-expiring|category-changed|account-disabled|account-deleted|broadcast",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "语言，留空使用默认语言\nzh|en",
+                        "name": "lang",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回渲染结果",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/mail.Rendered"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "模板不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "模板渲染失败",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/oidc/authorizations/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取等待当前用户确认的 OIDC 授权请求，供授权确认页面展示。consentRequired 为 false 时用户已经同意过，前端可以直接提交。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "获取授权请求",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "授权请求ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回授权请求",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/service.OidcAuthorizationPrompt"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "授权请求不存在或已过期",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "同意或拒绝 OIDC 授权请求，返回应用的回跳地址，前端应直接跳转。同意后会记住授权的范围，之后同一应用不再询问。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "确认授权请求",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "授权请求ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "确认请求",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.RequestDecideAuthorization"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回回跳地址",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "授权请求不存在或已过期",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/oidc/clients": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取所有已注册的 OIDC 客户端，不包含密钥。需要 oidc.manage 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "获取 OIDC 客户端列表",
+                "responses": {
+                    "200": {
+                        "description": "成功返回客户端列表",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/oidc.Client"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "注册一个使用 asynx 登录的应用。机密客户端的密钥自动生成，只在本次返回；公共客户端没有密钥，必须使用 PKCE。roles 为空时所有有角色的用户都可以登录。需要 oidc.manage 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "注册 OIDC 客户端",
+                "parameters": [
+                    {
+                        "description": "注册请求\nroles: admin|default|restricted",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.OidcClientSpec"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回客户端（机密客户端包含密钥）",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/oidc.Client"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/oidc/clients/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "根据ID获取 OIDC 客户端，不包含密钥。需要 oidc.manage 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "获取 OIDC 客户端",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "客户端ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回客户端",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/oidc.Client"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "客户端不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "修改客户端的名称、回跳地址、允许的角色和是否跳过授权确认。rotateSecret 为 true 或由公共客户端改为机密客户端时生成新密钥并只在本次返回。需要 oidc.manage 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "修改 OIDC 客户端",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "客户端ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "修改请求",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.RequestUpdateOidcClient"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回客户端",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/oidc.Client"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "客户端不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "删除客户端以及所有用户对它的授权，已签发的令牌在过期前仍然有效。需要 oidc.manage 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "删除 OIDC 客户端",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "客户端ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功删除，返回 'ok'",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "客户端不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/oidc/consents": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取当前用户授权过的 OIDC 应用及授权范围。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "获取已授权的应用",
+                "responses": {
+                    "200": {
+                        "description": "成功返回授权列表",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/service.OidcConsent"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/oidc/consents/{clientId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "撤销当前用户对某个 OIDC 应用的授权，下次登录该应用时需要重新确认。已签发的令牌在过期前仍然有效。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "撤销应用授权",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "客户端ID",
+                        "name": "clientId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功撤销，返回 'ok'",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "授权不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operations": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取所有多步骤操作（注册、角色切换等）及其每一步的状态。需要 audit.read 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operations"
+                ],
+                "summary": "获取操作日志",
+                "responses": {
+                    "200": {
+                        "description": "成功返回操作列表",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/saga.Operation"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operations/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "根据ID获取一个多步骤操作的状态。需要 audit.read 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operations"
+                ],
+                "summary": "获取操作详情",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "操作ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回操作",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/saga.Operation"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "操作不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operations/{id}/retry": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "对补偿失败（failed）的操作重新执行补偿。需要 system.manage 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operations"
+                ],
+                "summary": "重试补偿",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "操作ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "补偿完成，返回 'ok'",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "操作不处于 failed 状态",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "操作不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "补偿再次失败",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/ou-admins": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取所有被委派管理部分账号类型的用户。需要 users.role.grant 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ou-admins"
+                ],
+                "summary": "获取委派管理员列表",
+                "responses": {
+                    "200": {
+                        "description": "成功返回委派管理员列表",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/service.OuAdmin"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/ou-admins/{uid}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "委派用户管理指定账号类型（system|member|external|alumni）的用户，整体替换原有的委派。\n委派管理员可以在这些账号类型中注册、删除用户，重置密码和修改角色，但不能管理角色高于自己的用户，也不能授予高于自己的角色。需要 users.role.grant 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ou-admins"
+                ],
+                "summary": "委派账号类型管理员",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "委派请求",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.RequestSetOuAdmin"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回委派",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/service.OuAdmin"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "撤销用户被委派的全部管理权限。需要 users.role.grant 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ou-admins"
+                ],
+                "summary": "撤销委派",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功撤销，返回 'ok'",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "委派不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/outbox": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取邮件队列中的消息，可按状态过滤。已发送的消息不保留正文。需要 audit.read 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "outbox"
+                ],
+                "summary": "获取发件箱",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "消息状态\npending|sent|dead",
+                        "name": "state",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回消息列表",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/outbox.Message"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/outbox/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "根据ID获取邮件队列中的一条消息。需要 audit.read 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "outbox"
+                ],
+                "summary": "获取邮件详情",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "消息ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回消息",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/outbox.Message"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "消息不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "从邮件队列中删除一条消息，通常用于丢弃无法投递的死信。需要 mail.manage 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "outbox"
+                ],
+                "summary": "删除邮件",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "消息ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功删除，返回 'ok'",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "消息不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/outbox/{id}/resend": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "将死信或待发送的消息重置重试次数并立即重新投递。需要 mail.manage 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "outbox"
+                ],
+                "summary": "重新发送邮件",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "消息ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "已重新入队，返回 'ok'",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "消息已发送",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "消息不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/registration-requests": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取自助注册申请，按提交时间排序。需要 users.write 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "registration-requests"
+                ],
+                "summary": "获取注册申请列表",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "按状态过滤: unverified|pending|approved|rejected",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回申请列表",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/service.RegistrationRequest"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "以学号申请 member 账号，需要附带工作量证明，并受每个 IP 的频率限制。提交后向邮箱发送验证链接，验证后申请交由管理员审核。无需登录。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "registration-requests"
+                ],
+                "summary": "提交注册申请",
+                "parameters": [
+                    {
+                        "description": "注册申请",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.RegistrationSpec"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回申请",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/service.RegistrationRequest"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误或工作量证明无效",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "未开放自助注册",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "用户已存在或已有进行中的申请",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "提交过于频繁",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/registration-requests/challenge": {
+            "get": {
+                "description": "提交注册申请前需要找到 nonce，使 SHA-256(\"\u003cchallenge\u003e:\u003cnonce\u003e\") 至少有 difficulty 个前导零比特。每个题目只能使用一次。无需登录。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "registration-requests"
+                ],
+                "summary": "获取工作量证明题目",
+                "responses": {
+                    "200": {
+                        "description": "成功返回题目",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/service.RegistrationChallenge"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "未开放自助注册",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/registration-requests/verify": {
+            "post": {
+                "description": "使用验证邮件中链接的 token 验证邮箱，验证后申请进入审核队列。重复验证不会报错。无需登录。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "registration-requests"
+                ],
+                "summary": "验证注册邮箱",
+                "parameters": [
+                    {
+                        "description": "验证请求",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.RequestVerifyRegistration"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回申请",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/service.RegistrationRequest"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "令牌无效或已过期",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/registration-requests/{id}/approve": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "按已验证邮箱的申请创建账号，初始密码通过欢迎邮件发送。需要 users.write 权限，委派管理员只能使用被委派的账号类型，没有 users.role.grant 权限时角色不能高于自己。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "registration-requests"
+                ],
+                "summary": "批准注册申请",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "申请ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "账号类型（system|member|external|alumni）和角色",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.RequestApproveRegistration"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回申请",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/service.RegistrationRequest"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "申请不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "申请不在待审核状态或用户已存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/registration-requests/{id}/reject": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "拒绝待审核或尚未验证邮箱的申请，不会通知申请人。需要 users.write 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "registration-requests"
+                ],
+                "summary": "拒绝注册申请",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "申请ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "拒绝理由",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.RequestRejectRegistration"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回申请",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/service.RegistrationRequest"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "申请不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "申请已处理",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/role-grants": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取尚未到期的临时角色，按到期时间排序，包括到期后恢复的角色和是否已发送提醒。需要 users.role.grant 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "role-grants"
+                ],
+                "summary": "获取临时角色列表",
+                "responses": {
+                    "200": {
+                        "description": "成功返回临时角色列表",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/service.RoleGrant"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/role-grants/{uid}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "取消临时角色的期限，用户永久保留当前角色。需要 users.role.grant 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "role-grants"
+                ],
+                "summary": "取消角色期限",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功取消，返回 'ok'",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "临时角色不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/roles": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取可以授予用户的角色及其权限，包括内置的 admin、default、restricted 和通过 ROLE_PERMISSIONS 配置的自定义角色。需要 users.self 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "roles"
+                ],
+                "summary": "获取角色列表",
+                "responses": {
+                    "200": {
+                        "description": "成功返回角色列表",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/controller.RoleInfo"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/service-accounts": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取所有服务账号及其负责人、团队和允许的权限范围。需要 service-accounts.manage 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "service-accounts"
+                ],
+                "summary": "获取服务账号列表",
+                "responses": {
+                    "200": {
+                        "description": "成功返回服务账号列表",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/service.ServiceAccount"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "在 system 下创建服务账号。服务账号没有邮箱，不能通过密码登录，只能使用 API 密钥访问接口；scopes 限制了其密钥可以申请的权限范围，格式与个人访问令牌相同。\n用户名由小写字母、数字和连字符组成，以字母开头，长度 3 到 32。需要 service-accounts.manage 权限，没有 users.role.grant 权限时角色不能高于自己。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "service-accounts"
+                ],
+                "summary": "创建服务账号",
+                "parameters": [
+                    {
+                        "description": "创建服务账号请求",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.RequestCreateServiceAccount"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回服务账号",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/service.ServiceAccount"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "用户已存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/service-accounts/{uid}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "根据用户名获取服务账号。需要 service-accounts.manage 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "service-accounts"
+                ],
+                "summary": "获取服务账号",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "服务账号用户名",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回服务账号",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/service.ServiceAccount"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "服务账号不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "修改服务账号的描述、负责人、权限范围和角色。收窄权限范围时，超出新范围的密钥会被撤销。需要 service-accounts.manage 权限，没有 users.role.grant 权限时原角色和新角色都不能高于自己。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "service-accounts"
+                ],
+                "summary": "修改服务账号",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "服务账号用户名",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "修改请求",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.ServiceAccountSpec"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回服务账号",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/service.ServiceAccount"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "服务账号不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "删除服务账号及其全部密钥。需要 service-accounts.manage 权限，没有 users.role.grant 权限时服务账号的角色不能高于自己。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "service-accounts"
+                ],
+                "summary": "删除服务账号",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "服务账号用户名",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功删除，返回 'ok'",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "服务账号不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/service-accounts/{uid}/keys": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取服务账号的 API 密钥，不包含密钥明文。需要 service-accounts.manage 权限，没有 users.role.grant 权限时服务账号的角色不能高于自己。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "service-accounts"
+                ],
+                "summary": "获取服务账号的密钥列表",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "服务账号用户名",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回密钥列表",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/service.AccessToken"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "服务账号不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "为服务账号签发 API 密钥，权限范围必须在服务账号允许的范围之内，角色的权限必须是服务账号角色权限的子集。密钥明文只在本次返回。需要 service-accounts.manage 权限，没有 users.role.grant 权限时服务账号的角色不能高于自己。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "service-accounts"
+                ],
+                "summary": "签发服务账号密钥",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "服务账号用户名",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "签发请求",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.AccessTokenSpec"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回密钥（包含明文）",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/service.AccessToken"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "服务账号不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/service-accounts/{uid}/keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "删除服务账号的 API 密钥，立即失效。需要 service-accounts.manage 权限，没有 users.role.grant 权限时服务账号的角色不能高于自己。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "service-accounts"
+                ],
+                "summary": "撤销服务账号密钥",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "服务账号用户名",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "密钥ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功撤销，返回 'ok'",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "服务账号或密钥不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/service-accounts/{uid}/keys/{id}/rotate": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "以相同的名称、权限范围、角色和有效时长签发新密钥，并立即撤销旧密钥。新密钥明文只在本次返回。需要 service-accounts.manage 权限，没有 users.role.grant 权限时服务账号的角色不能高于自己。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "service-accounts"
+                ],
+                "summary": "轮换服务账号密钥",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "服务账号用户名",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "密钥ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回新密钥（包含明文）",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/service.AccessToken"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "服务账号或密钥不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tokens": {
+            "post": {
+                "description": "通过用户名和密码验证用户身份并生成访问令牌",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tokens"
+                ],
+                "summary": "创建访问令牌",
+                "parameters": [
+                    {
+                        "description": "创建令牌请求",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.CreateTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "返回访问令牌",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "用户名或密码错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取所有用户列表信息（包含角色和类别）。需要 users.read 权限；拥有 users.self 权限时，拥有 users.read.ou 权限可以查看自己组织单元的用户，委派管理员还可以查看被委派账号类型的用户；都不满足时返回 403。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "获取用户列表",
+                "responses": {
+                    "200": {
+                        "description": "成功返回用户列表",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/service.UserProfile"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "创建新用户账号，可以指定账号到期时间。需要 users.write 权限；委派管理员可以在被委派的账号类型中创建用户。没有 users.role.grant 权限时角色不能高于自己。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "注册新用户",
+                "parameters": [
+                    {
+                        "description": "注册用户请求",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.RequestRegister"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功注册用户，返回 'ok'",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "用户已存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{uid}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "根据用户ID获取用户详细信息（包含角色和类别）。需要 users.read 权限；拥有 users.self 权限时可以查看自己的信息，拥有 users.read.ou 权限时还可以查看自己组织单元的用户信息，委派管理员还可以查看被委派账号类型的用户信息。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "获取用户信息",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID，使用 'me' 可获取当前用户信息",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回用户信息",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/service.UserProfile"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "删除指定用户账号。需要 users.write 权限，委派管理员只能删除被委派账号类型中角色不高于自己的用户。不允许删除当前登录用户。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "删除用户",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID，不能使用 'me'",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功删除用户，返回 'ok'",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{uid}/category": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取指定用户的账号类型（system|member|external|alumni）。需要 users.read 权限；拥有 users.self 权限时可以查看自己的信息，拥有 users.read.ou 权限时还可以查看自己组织单元的用户信息，委派管理员还可以查看被委派账号类型的用户信息。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "获取账号类型",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID，使用 'me' 可获取当前用户类型",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回账号类型: system|member|external|alumni",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "修改指定用户的账号类型。需要 users.write 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "更改账号类型",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID，不能使用 'me'",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "修改账号类型请求\nsystem|member|external|alumni",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.RequestModifyCategory"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功修改账号类型，返回 'ok'",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{uid}/expiry": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "设置或取消指定用户的账号到期时间，常用于 external 类型的访客账号。到期时间向上取整到天（UTC）并写入 shadowExpire，使用 PAM 的主机同样会拒绝登录；到期后不能再登录或使用访问令牌，到期前会邮件提醒。\n需要 users.write 权限，委派管理员只能修改被委派账号类型中角色不高于自己的用户。不允许操作当前登录用户。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "更改账号到期时间",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID，不能使用 'me'",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "修改到期时间请求",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.RequestModifyExpiry"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功修改到期时间，返回 'ok'",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{uid}/language": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "修改指定用户接收通知邮件所使用的语言，留空表示使用默认语言。需要 users.write 权限；拥有 users.self 权限时可以修改自己。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "更改邮件语言",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID，使用 'me' 可修改当前用户",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "修改语言请求\nzh|en",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.RequestModifyLanguage"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功修改语言，返回 'ok'",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{uid}/notifications": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取指定用户的邮件通知设置，mandatory 的通知与账号安全相关，不能关闭。需要 users.read 权限；拥有 users.self 权限时可以查看自己。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "获取通知设置",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID，使用 'me' 可查看当前用户",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回通知设置",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/service.NotificationSetting"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "设置指定用户关闭的邮件通知，未列出的通知均为开启。密码被重置等安全通知不能关闭。需要 users.write 权限；拥有 users.self 权限时可以修改自己。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "更改通知设置",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID，使用 'me' 可修改当前用户",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "关闭的通知\nrole-changed|role-expiring|category-changed|account-deleted",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.RequestModifyNotificationSettings"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功修改通知设置，返回 'ok'",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{uid}/password": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "修改指定用户的密码。需要 users.write 权限；拥有 users.self 权限时可以修改自己的密码，委派管理员还可以修改被委派账号类型中用户的密码；没有 users.role.grant 权限时不能修改角色高于自己的用户的密码。修改他人密码时总会邮件通知该用户。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "修改密码",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID，使用 'me' 可修改当前用户密码",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "修改密码请求",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.RequestChangePassword"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功修改密码，返回 'ok'",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误或密码不符合要求",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{uid}/role": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取指定用户的账号角色（admin|default|restricted 或自定义角色）。需要 users.read 权限；拥有 users.self 权限时可以查看自己的信息，拥有 users.read.ou 权限时还可以查看自己组织单元的用户信息，委派管理员还可以查看被委派账号类型的用户信息。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "获取账号角色",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID，使用 'me' 可获取当前用户角色",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回账号角色: admin|default|restricted",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "修改指定用户的账号角色。指定 expiresAt 时为临时授予，到期后自动恢复为授予前的角色，到期前会邮件提醒；不指定时永久授予，并取消原有的期限。\n需要 users.role.grant 权限；委派管理员也可以修改被委派账号类型中的用户，但授予的角色和被修改用户的原角色都不能高于自己。非SYSTEM用户必须用学号作为用户名。不允许操作当前登录用户。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "更改账号角色",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户ID，不能使用 'me'",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "修改账号角色请求\nadmin|default|restricted",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.RequestModifyRole"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功修改账号角色，返回 'ok'",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取所有已注册的 Webhook 端点，不包含密钥。需要 webhooks.manage 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "获取 Webhook 列表",
+                "responses": {
+                    "200": {
+                        "description": "成功返回端点列表",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/webhook.Endpoint"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "注册一个 Webhook 端点。事件发生时向该地址 POST JSON，请求头 X-Asynx-Signature 为 \"sha256=\" 加上以密钥对 \"\u003cX-Asynx-Timestamp\u003e.\u003cbody\u003e\" 计算的 HMAC-SHA256。密钥留空时自动生成，只在本次返回。需要 webhooks.manage 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "注册 Webhook",
+                "parameters": [
+                    {
+                        "description": "注册请求\nevents: user.created|user.deleted|user.updated|user.role_changed|user.category_changed|user.password_changed|group.member_added|group.member_removed，支持 user.*、group.* 和 *",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.RequestCreateWebhook"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回端点（包含密钥）",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/webhook.Endpoint"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "根据ID获取 Webhook 端点，不包含密钥。需要 webhooks.manage 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "获取 Webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "端点ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回端点",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/webhook.Endpoint"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "端点不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "修改 Webhook 端点的地址、订阅事件和启用状态。rotateSecret 为 true 时生成新密钥并只在本次返回。需要 webhooks.manage 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "修改 Webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "端点ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "修改请求",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.RequestUpdateWebhook"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回端点",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/webhook.Endpoint"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "端点不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "删除 Webhook 端点及其投递记录。需要 webhooks.manage 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "删除 Webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "端点ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功删除，返回 'ok'",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "端点不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取 Webhook 端点的投递历史，按时间倒序。需要 audit.read 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "获取投递记录",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "端点ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回投递记录",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/webhook.Delivery"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "端点不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries/{deliveryId}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取一次 Webhook 投递的详情，包含事件内容和最后一次响应。需要 audit.read 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "获取投递详情",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "端点ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "投递ID",
+                        "name": "deliveryId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回投递详情",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/webhook.Delivery"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "投递记录不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries/{deliveryId}/redeliver": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "以同一事件重新投递一次，生成新的投递记录，原记录保持不变。需要 webhooks.manage 权限。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "重新投递",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "端点ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "投递ID",
+                        "name": "deliveryId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回新的投递记录",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "$ref": "#/definitions/webhook.Delivery"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未授权访问",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "投递记录不存在",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "data": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "cache.Stats": {
+            "type": "object",
+            "properties": {
+                "entries": {
+                    "type": "integer"
+                },
+                "flushes": {
+                    "type": "integer"
+                },
+                "hitRate": {
+                    "type": "number"
+                },
+                "hits": {
+                    "type": "integer"
+                },
+                "misses": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "client.Mail": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "html": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "replyTo": {
+                    "type": "string"
+                },
+                "sentAt": {
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.CreateTokenRequest": {
+            "type": "object",
+            "required": [
+                "password",
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.RequestApproveRegistration": {
+            "type": "object",
+            "required": [
+                "category",
+                "role"
+            ],
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.RequestBroadcast": {
+            "type": "object",
+            "required": [
+                "body",
+                "subject"
+            ],
+            "properties": {
+                "body": {
+                    "type": "string"
+                },
+                "dryRun": {
+                    "type": "boolean"
+                },
+                "format": {
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "target": {
+                    "$ref": "#/definitions/service.BroadcastTarget"
+                }
+            }
+        },
+        "controller.RequestChangePassword": {
+            "type": "object",
+            "required": [
+                "password"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.RequestCreateServiceAccount": {
+            "type": "object",
+            "required": [
+                "owner",
+                "role",
+                "scopes",
+                "username"
+            ],
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "displayName": {
+                    "type": "string"
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "team": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.RequestCreateWebhook": {
+            "type": "object",
+            "required": [
+                "events",
+                "url"
+            ],
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.RequestDecideAuthorization": {
+            "type": "object",
+            "properties": {
+                "approve": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "controller.RequestLifecycleExempt": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.RequestModifyCategory": {
+            "type": "object",
+            "required": [
+                "category"
+            ],
+            "properties": {
+                "category": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.RequestModifyExpiry": {
+            "type": "object",
+            "properties": {
+                "expiresAt": {
+                    "description": "为空时取消到期",
+                    "type": "string"
+                }
+            }
+        },
+        "controller.RequestModifyLanguage": {
+            "type": "object",
+            "properties": {
+                "language": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.RequestModifyNotificationSettings": {
+            "type": "object",
+            "properties": {
+                "disabled": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "controller.RequestModifyRole": {
+            "type": "object",
+            "required": [
+                "role"
+            ],
+            "properties": {
+                "expiresAt": {
+                    "description": "为空时永久授予",
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.RequestRegister": {
+            "type": "object",
+            "required": [
+                "category",
+                "givenName",
+                "mail",
+                "role",
+                "surName",
+                "username"
+            ],
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "description": "账号到期时间，向上取整到天（UTC），为空时不过期",
+                    "type": "string"
+                },
+                "givenName": {
+                    "type": "string"
+                },
+                "language": {
+                    "type": "string"
+                },
+                "mail": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "surName": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.RequestRejectRegistration": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.RequestSetOuAdmin": {
+            "type": "object",
+            "required": [
+                "categories"
+            ],
+            "properties": {
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "controller.RequestUpdateOidcClient": {
+            "type": "object",
+            "required": [
+                "name",
+                "redirectUris"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "postLogoutRedirectUris": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "public": {
+                    "type": "boolean"
+                },
+                "redirectUris": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "rotateSecret": {
+                    "type": "boolean"
+                },
+                "skipConsent": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "controller.RequestUpdateWebhook": {
+            "type": "object",
+            "required": [
+                "events",
+                "url"
+            ],
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "rotateSecret": {
+                    "type": "boolean"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.RequestVerifyRegistration": {
+            "type": "object",
+            "required": [
+                "token"
+            ],
+            "properties": {
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.RoleInfo": {
+            "type": "object",
+            "properties": {
+                "builtin": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "$ref": "#/definitions/security.Role"
+                },
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/security.Permission"
+                    }
+                }
+            }
+        },
+        "event.Event": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "type": "string"
+                },
+                "data": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "time": {
+                    "type": "string"
+                },
+                "type": {
+                    "$ref": "#/definitions/event.Type"
+                }
+            }
+        },
+        "event.Type": {
+            "type": "string",
+            "enum": [
+                "user.created",
+                "user.deleted",
+                "user.updated",
+                "user.role_changed",
+                "user.category_changed",
+                "user.password_changed",
+                "group.member_added",
+                "group.member_removed"
+            ],
+            "x-enum-varnames": [
+                "UserCreated",
+                "UserDeleted",
+                "UserUpdated",
+                "UserRoleChanged",
+                "UserCategoryChanged",
+                "UserPasswordChanged",
+                "GroupMemberAdded",
+                "GroupMemberRemoved"
+            ]
+        },
+        "mail.ContentFormat": {
+            "type": "string",
+            "enum": [
+                "markdown",
+                "html"
+            ],
+            "x-enum-varnames": [
+                "ContentMarkdown",
+                "ContentHtml"
+            ]
+        },
+        "mail.Rendered": {
+            "type": "object",
+            "properties": {
+                "html": {
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "mail.Template": {
+            "type": "string",
+            "enum": [
+                "welcome",
+                "password-reset",
+                "role-changed",
+                "role-expiring",
+                "account-expiring",
+                "alumni-transition",
+                "category-changed",
+                "account-disabled",
+                "account-deleted",
+                "registration-verify",
+                "invitation-verify",
+                "broadcast"
+            ],
+            "x-enum-varnames": [
+                "TemplateWelcome",
+                "TemplatePasswordReset",
+                "TemplateRoleChanged",
+                "TemplateRoleExpiring",
+                "TemplateAccountExpiring",
+                "TemplateAlumniTransition",
+                "TemplateCategoryChanged",
+                "TemplateAccountDisabled",
+                "TemplateAccountDeleted",
+                "TemplateRegistrationVerify",
+                "TemplateInvitationVerify",
+                "TemplateBroadcast"
+            ]
+        },
+        "oidc.Client": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "postLogoutRedirectUris": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "public": {
+                    "description": "公共客户端没有密钥，必须使用 PKCE",
+                    "type": "boolean"
+                },
+                "redirectUris": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "roles": {
+                    "description": "允许登录的角色，为空时不限制",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "description": "只在创建或轮换时返回",
+                    "type": "string"
+                },
+                "secretHash": {
+                    "type": "string"
+                },
+                "skipConsent": {
+                    "description": "实验室内部应用可以跳过授权确认",
+                    "type": "boolean"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "outbox.Message": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "body": {
+                    "type": "string"
+                },
+                "bulk": {
+                    "description": "群发邮件，投递优先级低于普通邮件",
+                    "type": "boolean"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "language": {
+                    "type": "string"
+                },
+                "lastError": {
+                    "type": "string"
+                },
+                "nextAttemptAt": {
+                    "type": "string"
+                },
+                "ref": {
+                    "description": "调用方关联的记录，投递结果通过 Subscribe 回报",
+                    "type": "string"
+                },
+                "sentAt": {
+                    "type": "string"
+                },
+                "state": {
+                    "$ref": "#/definitions/outbox.MessageState"
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "template": {
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "outbox.MessageState": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "sent",
+                "dead"
+            ],
+            "x-enum-varnames": [
+                "MessagePending",
+                "MessageSent",
+                "MessageDead"
+            ]
+        },
+        "saga.Operation": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "state": {
+                    "$ref": "#/definitions/saga.OperationState"
+                },
+                "steps": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/saga.StepRecord"
+                    }
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "saga.OperationState": {
+            "type": "string",
+            "enum": [
+                "running",
+                "succeeded",
+                "compensating",
+                "compensated",
+                "failed"
+            ],
+            "x-enum-varnames": [
+                "OperationRunning",
+                "OperationSucceeded",
+                "OperationCompensating",
+                "OperationCompensated",
+                "OperationFailed"
+            ]
+        },
+        "saga.StepRecord": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "state": {
+                    "$ref": "#/definitions/saga.StepState"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "saga.StepState": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "running",
+                "done",
+                "compensated"
+            ],
+            "x-enum-varnames": [
+                "StepPending",
+                "StepRunning",
+                "StepDone",
+                "StepCompensated"
+            ]
+        },
+        "scheduler.JobState": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "failures": {
+                    "type": "integer"
+                },
+                "lastDuration": {
+                    "type": "string"
+                },
+                "lastError": {
+                    "type": "string"
+                },
+                "lastRunAt": {
+                    "type": "string"
+                },
+                "lastRunBy": {
+                    "description": "上次运行任务的实例",
+                    "type": "string"
+                },
+                "lastStatus": {
+                    "$ref": "#/definitions/scheduler.JobStatus"
+                },
+                "lastSuccessAt": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "nextRunAt": {
+                    "description": "禁用的任务没有下次运行时间",
+                    "type": "string"
+                },
+                "running": {
+                    "type": "boolean"
+                },
+                "runs": {
+                    "type": "integer"
+                },
+                "schedule": {
+                    "type": "string"
+                }
+            }
+        },
+        "scheduler.JobStatus": {
+            "type": "string",
+            "enum": [
+                "succeeded",
+                "failed"
+            ],
+            "x-enum-varnames": [
+                "JobSucceeded",
+                "JobFailed"
+            ]
+        },
+        "security.OuUser": {
+            "type": "string",
+            "enum": [
+                "system",
+                "member",
+                "external",
+                "alumni",
+                "unknown"
+            ],
+            "x-enum-comments": {
+                "OuUserAlumni": "毕业的成员，由生命周期策略从 member 转入"
+            },
+            "x-enum-descriptions": [
+                "",
+                "",
+                "",
+                "毕业的成员，由生命周期策略从 member 转入",
+                ""
+            ],
+            "x-enum-varnames": [
+                "OuUserSystem",
+                "OuUserMember",
+                "OuUserExternal",
+                "OuUserAlumni",
+                "OuUserUnknown"
+            ]
+        },
+        "security.Permission": {
+            "type": "string",
+            "enum": [
+                "users.self",
+                "users.read.ou",
+                "users.read",
+                "users.write",
+                "users.role.grant",
+                "groups.manage",
+                "audit.read",
+                "mail.manage",
+                "webhooks.manage",
+                "oidc.manage",
+                "service-accounts.manage",
+                "access-tokens.manage",
+                "system.manage"
+            ],
+            "x-enum-comments": {
+                "PermAccessTokensManage": "查看和撤销其他用户的个人访问令牌",
+                "PermAuditRead": "查看操作记录、发件箱、事件流和 Webhook 投递记录",
+                "PermGroupsManage": "管理附加组的成员",
+                "PermMailManage": "预览模板、重发和删除邮件、群发通知",
+                "PermOidcManage": "管理 OIDC 客户端",
+                "PermServiceAccountsManage": "管理服务账号及其密钥",
+                "PermSystemManage": "重试操作、清空缓存等运维操作",
+                "PermUsersRead": "查看所有用户",
+                "PermUsersReadOu": "查看同一分类下的用户",
+                "PermUsersRoleGrant": "修改用户的角色",
+                "PermUsersSelf": "查看和修改自己的资料、密码、通知设置，管理自己的令牌和授权",
+                "PermUsersWrite": "注册和删除用户，修改任何用户的分类、密码和设置",
+                "PermWebhooksManage": "管理 Webhook 端点"
+            },
+            "x-enum-descriptions": [
+                "查看和修改自己的资料、密码、通知设置，管理自己的令牌和授权",
+                "查看同一分类下的用户",
+                "查看所有用户",
+                "注册和删除用户，修改任何用户的分类、密码和设置",
+                "修改用户的角色",
+                "管理附加组的成员",
+                "查看操作记录、发件箱、事件流和 Webhook 投递记录",
+                "预览模板、重发和删除邮件、群发通知",
+                "管理 Webhook 端点",
+                "管理 OIDC 客户端",
+                "管理服务账号及其密钥",
+                "查看和撤销其他用户的个人访问令牌",
+                "重试操作、清空缓存等运维操作"
+            ],
+            "x-enum-varnames": [
+                "PermUsersSelf",
+                "PermUsersReadOu",
+                "PermUsersRead",
+                "PermUsersWrite",
+                "PermUsersRoleGrant",
+                "PermGroupsManage",
+                "PermAuditRead",
+                "PermMailManage",
+                "PermWebhooksManage",
+                "PermOidcManage",
+                "PermServiceAccountsManage",
+                "PermAccessTokensManage",
+                "PermSystemManage"
+            ]
+        },
+        "security.Role": {
+            "type": "string",
+            "enum": [
+                "admin",
+                "default",
+                "restricted",
+                "anonymous"
+            ],
+            "x-enum-varnames": [
+                "RoleAdmin",
+                "RoleDefault",
+                "RoleRestricted",
+                "RoleAnonymous"
+            ]
+        },
+        "service.AccessToken": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "lastUsedAt": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "role": {
+                    "$ref": "#/definitions/security.Role"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secretHash": {
+                    "type": "string"
+                },
+                "token": {
+                    "description": "明文令牌，只在创建时返回",
+                    "type": "string"
+                },
+                "uid": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.AccessTokenSpec": {
+            "type": "object",
+            "required": [
+                "expiresAt",
+                "name",
+                "scopes"
+            ],
+            "properties": {
+                "expiresAt": {
+                    "description": "RFC 3339 格式",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "role": {
+                    "description": "为空时使用当前角色",
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "service.Broadcast": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "dryRun": {
+                    "type": "boolean"
+                },
+                "format": {
+                    "$ref": "#/definitions/mail.ContentFormat"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "operator": {
+                    "type": "string"
+                },
+                "recipients": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.BroadcastRecipient"
+                    }
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "summary": {
+                    "$ref": "#/definitions/service.BroadcastSummary"
+                },
+                "target": {
+                    "$ref": "#/definitions/service.BroadcastTarget"
+                }
+            }
+        },
+        "service.BroadcastRecipient": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "mail": {
+                    "type": "string"
+                },
+                "messageId": {
+                    "type": "string"
+                },
+                "state": {
+                    "description": "pending|sent|dead|skipped|dry-run",
+                    "type": "string"
+                },
+                "uid": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.BroadcastSummary": {
+            "type": "object",
+            "properties": {
+                "dead": {
+                    "type": "integer"
+                },
+                "pending": {
+                    "type": "integer"
+                },
+                "sent": {
+                    "type": "integer"
+                },
+                "skipped": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "service.BroadcastTarget": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "group": {
+                    "type": "string"
+                },
+                "groupOu": {
+                    "description": "Group 所在的组织单元，默认为 additional",
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.CacheStatus": {
+            "type": "object",
+            "properties": {
+                "caches": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/cache.Stats"
+                    }
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "ttl": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.Invitation": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "createdBy": {
+                    "type": "string"
+                },
+                "domain": {
+                    "description": "只允许该域名下的邮箱使用",
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "link": {
+                    "description": "邀请链接，只在创建时返回",
+                    "type": "string"
+                },
+                "mail": {
+                    "description": "只允许该邮箱使用",
+                    "type": "string"
+                },
+                "maxUses": {
+                    "type": "integer"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "revokedAt": {
+                    "type": "string"
+                },
+                "revokedBy": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "secretHash": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/service.InvitationStatus"
+                },
+                "usedBy": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "uses": {
+                    "type": "integer"
+                }
+            }
+        },
+        "service.InvitationAcceptSpec": {
+            "type": "object",
+            "required": [
+                "givenName",
+                "mail",
+                "password",
+                "surName",
+                "token",
+                "username"
+            ],
+            "properties": {
+                "givenName": {
+                    "type": "string"
+                },
+                "language": {
+                    "type": "string"
+                },
+                "mail": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "surName": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                },
+                "verification": {
+                    "description": "限定域名的邀请需要提供验证邮件链接中的 verification",
+                    "type": "string"
+                }
+            }
+        },
+        "service.InvitationPrompt": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "domain": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "givenName": {
+                    "type": "string"
+                },
+                "mail": {
+                    "type": "string"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "surName": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.InvitationSpec": {
+            "type": "object",
+            "required": [
+                "category",
+                "expiresAt",
+                "maxUses",
+                "role"
+            ],
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "domain": {
+                    "description": "与 Mail 至多指定一个",
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "description": "RFC 3339 格式",
+                    "type": "string"
+                },
+                "mail": {
+                    "description": "指定时 MaxUses 只能为 1",
+                    "type": "string"
+                },
+                "maxUses": {
+                    "type": "integer"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.InvitationStatus": {
+            "type": "string",
+            "enum": [
+                "active",
+                "expired",
+                "exhausted",
+                "revoked"
+            ],
+            "x-enum-comments": {
+                "InvitationExhausted": "使用次数已用完"
+            },
+            "x-enum-descriptions": [
+                "",
+                "",
+                "使用次数已用完",
+                ""
+            ],
+            "x-enum-varnames": [
+                "InvitationActive",
+                "InvitationExpired",
+                "InvitationExhausted",
+                "InvitationRevoked"
+            ]
+        },
+        "service.InvitationVerifySpec": {
+            "type": "object",
+            "required": [
+                "givenName",
+                "mail",
+                "surName",
+                "token"
+            ],
+            "properties": {
+                "givenName": {
+                    "type": "string"
+                },
+                "language": {
+                    "type": "string"
+                },
+                "mail": {
+                    "type": "string"
+                },
+                "surName": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.LifecycleCandidate": {
+            "type": "object",
+            "properties": {
+                "dueAt": {
+                    "description": "转为 alumni 的时间",
+                    "type": "string"
+                },
+                "enrollmentYear": {
+                    "type": "integer"
+                },
+                "exempt": {
+                    "description": "已被豁免，不会转换",
+                    "type": "boolean"
+                },
+                "givenName": {
+                    "type": "string"
+                },
+                "notifiedAt": {
+                    "description": "已发送提醒的时间",
+                    "type": "string"
+                },
+                "role": {
+                    "$ref": "#/definitions/security.Role"
+                },
+                "surName": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.LifecycleExemption": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "createdBy": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "uid": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.NotificationSetting": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "kind": {
+                    "$ref": "#/definitions/mail.Template"
+                },
+                "mandatory": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "service.OidcAuthorizationPrompt": {
+            "type": "object",
+            "properties": {
+                "clientId": {
+                    "type": "string"
+                },
+                "clientName": {
+                    "type": "string"
+                },
+                "consentRequired": {
+                    "description": "为 false 时前端可以直接同意",
+                    "type": "boolean"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "controller.CreateTokenRequest": {
+        },
+        "service.OidcClientSpec": {
             "type": "object",
             "required": [
-                "password",
-                "username"
+                "name",
+                "redirectUris"
             ],
             "properties": {
-                "password": {
+                "name": {
                     "type": "string"
                 },
-                "username": {
+                "postLogoutRedirectUris": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "public": {
+                    "type": "boolean"
+                },
+                "redirectUris": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "skipConsent": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "service.OidcConsent": {
+            "type": "object",
+            "properties": {
+                "clientId": {
                     "type": "string"
+                },
+                "clientName": {
+                    "type": "string"
+                },
+                "grantedAt": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "controller.RequestChangePassword": {
+        "service.OuAdmin": {
             "type": "object",
-            "required": [
-                "password"
-            ],
             "properties": {
-                "password": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "grantedBy": {
+                    "type": "string"
+                },
+                "ous": {
+                    "description": "可以管理的账号类型",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/security.OuUser"
+                    }
+                },
+                "uid": {
+                    "type": "string"
+                },
+                "updatedAt": {
                     "type": "string"
                 }
             }
         },
-        "controller.RequestModifyCategory": {
+        "service.RegistrationChallenge": {
             "type": "object",
-            "required": [
-                "category"
-            ],
             "properties": {
-                "category": {
+                "challenge": {
+                    "type": "string"
+                },
+                "difficulty": {
+                    "description": "要求的 SHA-256 前导零比特数",
+                    "type": "integer"
+                },
+                "expiresAt": {
                     "type": "string"
                 }
             }
         },
-        "controller.RequestModifyRole": {
+        "service.RegistrationRequest": {
             "type": "object",
-            "required": [
-                "role"
-            ],
             "properties": {
+                "category": {
+                    "description": "批准时选择的账号类型",
+                    "type": "string"
+                },
+                "clientIp": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "decidedAt": {
+                    "type": "string"
+                },
+                "decidedBy": {
+                    "type": "string"
+                },
+                "givenName": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "language": {
+                    "type": "string"
+                },
+                "mail": {
+                    "type": "string"
+                },
+                "note": {
+                    "description": "申请人的附言",
+                    "type": "string"
+                },
+                "rejectReason": {
+                    "description": "拒绝的理由，只对管理员可见",
+                    "type": "string"
+                },
                 "role": {
+                    "description": "批准时选择的角色",
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/service.RegistrationStatus"
+                },
+                "surName": {
+                    "type": "string"
+                },
+                "tokenHash": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                },
+                "verifiedAt": {
                     "type": "string"
                 }
             }
         },
-        "controller.RequestRegister": {
+        "service.RegistrationSpec": {
             "type": "object",
             "required": [
-                "category",
                 "givenName",
                 "mail",
-                "role",
                 "surName",
                 "username"
             ],
             "properties": {
-                "category": {
+                "challenge": {
+                    "description": "由 GET /registration-requests/challenge 获取",
                     "type": "string"
                 },
                 "givenName": {
                     "type": "string"
                 },
+                "language": {
+                    "type": "string"
+                },
                 "mail": {
                     "type": "string"
                 },
-                "role": {
+                "nonce": {
+                    "description": "使 SHA-256(\"\u003cchallenge\u003e:\u003cnonce\u003e\") 满足难度的解",
+                    "type": "string"
+                },
+                "note": {
                     "type": "string"
                 },
                 "surName": {
@@ -1056,35 +8710,151 @@ const docTemplate = `{
                 }
             }
         },
-        "security.OuUser": {
+        "service.RegistrationStatus": {
             "type": "string",
             "enum": [
-                "system",
-                "member",
-                "external",
-                "unknown"
+                "unverified",
+                "pending",
+                "approved",
+                "rejected"
+            ],
+            "x-enum-comments": {
+                "RegistrationPending": "等待管理员审核",
+                "RegistrationUnverified": "等待申请人验证邮箱"
+            },
+            "x-enum-descriptions": [
+                "等待申请人验证邮箱",
+                "等待管理员审核",
+                "",
+                ""
             ],
             "x-enum-varnames": [
-                "OuUserSystem",
-                "OuUserMember",
-                "OuUserExternal",
-                "OuUserUnknown"
+                "RegistrationUnverified",
+                "RegistrationPending",
+                "RegistrationApproved",
+                "RegistrationRejected"
             ]
         },
-        "security.Role": {
-            "type": "string",
-            "enum": [
-                "admin",
-                "default",
-                "restricted",
-                "anonymous"
+        "service.RoleGrant": {
+            "type": "object",
+            "properties": {
+                "expiresAt": {
+                    "type": "string"
+                },
+                "grantedAt": {
+                    "type": "string"
+                },
+                "grantedBy": {
+                    "type": "string"
+                },
+                "notifiedAt": {
+                    "description": "已发送到期提醒的时间",
+                    "type": "string"
+                },
+                "previous": {
+                    "$ref": "#/definitions/security.Role"
+                },
+                "role": {
+                    "$ref": "#/definitions/security.Role"
+                },
+                "uid": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.SchedulerStatus": {
+            "type": "object",
+            "properties": {
+                "instance": {
+                    "type": "string"
+                },
+                "isLeader": {
+                    "type": "boolean"
+                },
+                "jobs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/scheduler.JobState"
+                    }
+                },
+                "leader": {
+                    "description": "已知的主实例，租约被释放时为空",
+                    "type": "string"
+                }
+            }
+        },
+        "service.ServiceAccount": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "displayName": {
+                    "type": "string"
+                },
+                "owner": {
+                    "description": "负责人的用户名",
+                    "type": "string"
+                },
+                "role": {
+                    "description": "读取时从角色组查询，不保存",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/security.Role"
+                        }
+                    ]
+                },
+                "scopes": {
+                    "description": "密钥可以申请的权限范围",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "team": {
+                    "type": "string"
+                },
+                "uid": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.ServiceAccountSpec": {
+            "type": "object",
+            "required": [
+                "owner",
+                "role",
+                "scopes"
             ],
-            "x-enum-varnames": [
-                "RoleAdmin",
-                "RoleDefault",
-                "RoleRestricted",
-                "RoleAnonymous"
-            ]
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "displayName": {
+                    "type": "string"
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "team": {
+                    "type": "string"
+                }
+            }
         },
         "service.UserProfile": {
             "type": "object",
@@ -1092,9 +8862,16 @@ const docTemplate = `{
                 "category": {
                     "$ref": "#/definitions/security.OuUser"
                 },
+                "expiresAt": {
+                    "description": "账号失效的时间，为空表示不过期",
+                    "type": "string"
+                },
                 "givenName": {
                     "type": "string"
                 },
+                "language": {
+                    "type": "string"
+                },
                 "mail": {
                     "type": "string"
                 },
@@ -1108,6 +8885,92 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "webhook.Delivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "deliveredAt": {
+                    "type": "string"
+                },
+                "endpointId": {
+                    "type": "string"
+                },
+                "event": {
+                    "$ref": "#/definitions/event.Event"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "lastError": {
+                    "type": "string"
+                },
+                "nextAttemptAt": {
+                    "type": "string"
+                },
+                "redeliveryOf": {
+                    "type": "string"
+                },
+                "response": {
+                    "type": "string"
+                },
+                "state": {
+                    "$ref": "#/definitions/webhook.DeliveryState"
+                },
+                "statusCode": {
+                    "type": "integer"
+                }
+            }
+        },
+        "webhook.DeliveryState": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "succeeded",
+                "failed"
+            ],
+            "x-enum-varnames": [
+                "DeliveryPending",
+                "DeliverySucceeded",
+                "DeliveryFailed"
+            ]
+        },
+        "webhook.Endpoint": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
         }
     },
     "securityDefinitions": {
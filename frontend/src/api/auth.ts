@@ -14,4 +14,26 @@ export function createToken(reqData: LoginRequest) {
         method: 'POST',
         data: reqData
     })
+}
+
+/**
+ * 用当前的登录令牌换取转发认证 Cookie，各子域名上的反向代理据此判断登录状态
+ * @returns 'ok'
+ */
+export function createForwardAuthSession() {
+    return request({
+        url: '/auth/session',
+        method: 'POST'
+    })
+}
+
+/**
+ * 清除转发认证 Cookie
+ * @returns 'ok'
+ */
+export function deleteForwardAuthSession() {
+    return request({
+        url: '/auth/session',
+        method: 'DELETE'
+    })
 } 
\ No newline at end of file
@@ -0,0 +1,42 @@
+import request from '../utils/request'
+import type { InvitationAcceptRequest, InvitationPrompt, InvitationVerifyRequest } from './types'
+
+/**
+ * 查看邀请内容
+ * @param {string} token 邀请链接中的 token
+ * @param {string} verification 验证邮件链接中的 verification，可选
+ * @returns 账号类型、角色和邮箱限制，以及已验证的邮箱
+ */
+export function getInvitation(token: string, verification?: string) {
+    return request<any, { data: InvitationPrompt }>({
+        url: '/invitations/lookup',
+        method: 'GET',
+        params: { token, verification }
+    })
+}
+
+/**
+ * 限定域名的邀请需要先验证邮箱，向填写的邮箱发送验证链接
+ * @param {InvitationVerifyRequest} reqData 邮箱和姓名
+ * @returns 'ok'
+ */
+export function verifyInvitationMail(reqData: InvitationVerifyRequest) {
+    return request<any, { data: string }>({
+        url: '/invitations/verify',
+        method: 'POST',
+        data: reqData
+    })
+}
+
+/**
+ * 使用邀请注册账号，密码由受邀人自己设置
+ * @param {InvitationAcceptRequest} reqData 注册信息
+ * @returns 'ok'
+ */
+export function acceptInvitation(reqData: InvitationAcceptRequest) {
+    return request<any, { data: string }>({
+        url: '/invitations/accept',
+        method: 'POST',
+        data: reqData
+    })
+}
@@ -0,0 +1,28 @@
+import request from '../utils/request'
+import type { OidcAuthorizationPrompt } from './types'
+
+/**
+ * 获取等待确认的授权请求
+ * @param {string} id 授权请求ID
+ * @returns 客户端名称、申请的权限范围以及是否需要用户确认
+ */
+export function getAuthorization(id: string) {
+    return request<any, OidcAuthorizationPrompt>({
+        url: `/oidc/authorizations/${id}`,
+        method: 'GET'
+    })
+}
+
+/**
+ * 同意或拒绝授权请求
+ * @param {string} id 授权请求ID
+ * @param {boolean} approve 是否同意
+ * @returns 应跳转到的客户端回跳地址
+ */
+export function decideAuthorization(id: string, approve: boolean) {
+    return request<any, string>({
+        url: `/oidc/authorizations/${id}`,
+        method: 'POST',
+        data: { approve }
+    })
+}
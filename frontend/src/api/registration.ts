@@ -0,0 +1,39 @@
+import request from '../utils/request'
+import type { RegistrationChallenge, RegistrationRequest, RegistrationSubmitRequest } from './types'
+
+/**
+ * 获取提交注册申请所需的工作量证明题目
+ * @returns 题目、难度和到期时间
+ */
+export function getRegistrationChallenge() {
+    return request<any, { data: RegistrationChallenge }>({
+        url: '/registration-requests/challenge',
+        method: 'GET'
+    })
+}
+
+/**
+ * 提交注册申请，提交后需要通过邮件中的链接验证邮箱
+ * @param {RegistrationSubmitRequest} reqData 申请信息和工作量证明
+ * @returns 创建的申请
+ */
+export function submitRegistration(reqData: RegistrationSubmitRequest) {
+    return request<any, { data: RegistrationRequest }>({
+        url: '/registration-requests',
+        method: 'POST',
+        data: reqData
+    })
+}
+
+/**
+ * 验证注册邮箱
+ * @param {string} token 验证邮件链接中的 token
+ * @returns 验证后的申请
+ */
+export function verifyRegistration(token: string) {
+    return request<any, { data: RegistrationRequest }>({
+        url: '/registration-requests/verify',
+        method: 'POST',
+        data: { token }
+    })
+}
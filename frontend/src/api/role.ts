@@ -0,0 +1,13 @@
+import request from '../utils/request'
+import type { RoleInfo } from './types'
+
+/**
+ * 获取可以授予用户的角色
+ * @returns 内置角色和配置中的自定义角色及其权限
+ */
+export function getRoles() {
+    return request<any, RoleInfo[]>({
+        url: '/roles',
+        method: 'GET'
+    })
+}
@@ -17,6 +17,7 @@ export interface User {
     mail: string
     role: string
     category: string
+    expiresAt?: string // 账号到期时间，为空表示不过期
 }
 
 /**
@@ -37,6 +38,7 @@ export interface RegisterRequest {
     mail: string
     role: string
     category: string
+    expiresAt?: string // 账号到期时间，为空时不过期
 }
 
 /**
@@ -51,6 +53,14 @@ export interface ChangePasswordRequest {
  */
 export interface ModifyRoleRequest {
     role: string
+    expiresAt?: string // 临时授予的到期时间，为空时永久授予
+}
+
+/**
+ * 修改账号到期时间请求接口
+ */
+export interface ModifyExpiryRequest {
+    expiresAt?: string // 为空时取消到期
 }
 
 /**
@@ -61,11 +71,106 @@ export interface ModifyCategoryRequest {
 }
 
 /**
- * 角色类型枚举
+ * 角色类型枚举，自定义角色的名称由后端配置决定
  */
-export type RoleType = 'admin' | 'default' | 'restricted'
+export type RoleType = 'admin' | 'default' | 'restricted' | (string & {})
+
+/**
+ * 角色及其权限接口
+ */
+export interface RoleInfo {
+    name: RoleType
+    permissions: string[]
+    builtin: boolean
+}
 
 /**
  * 账号类型枚举
  */
-export type CategoryType = 'system' | 'member' | 'external' 
\ No newline at end of file
+export type CategoryType = 'system' | 'member' | 'external' | 'alumni' 
+
+/**
+ * OIDC 授权请求接口
+ */
+export interface OidcAuthorizationPrompt {
+    id: string
+    clientId: string
+    clientName: string
+    scopes: string[]
+    consentRequired: boolean
+    expiresAt: string
+}
+
+/**
+ * 工作量证明题目
+ */
+export interface RegistrationChallenge {
+    challenge: string
+    difficulty: number
+    expiresAt: string
+}
+
+/**
+ * 自助注册申请接口
+ */
+export interface RegistrationSubmitRequest {
+    username: string
+    surName: string
+    givenName: string
+    mail: string
+    language?: string
+    note?: string
+    challenge: string
+    nonce: string
+}
+
+export type RegistrationStatus = 'unverified' | 'pending' | 'approved' | 'rejected'
+
+export interface RegistrationRequest {
+    id: string
+    username: string
+    surName: string
+    givenName: string
+    mail: string
+    status: RegistrationStatus
+    createdAt: string
+}
+
+/**
+ * 邀请内容接口
+ */
+export interface InvitationPrompt {
+    category: CategoryType
+    role: RoleType
+    domain?: string
+    mail?: string
+    surName?: string
+    givenName?: string
+    note?: string
+    expiresAt: string
+}
+
+/**
+ * 邀请邮箱验证请求接口
+ */
+export interface InvitationVerifyRequest {
+    token: string
+    surName: string
+    givenName: string
+    mail: string
+    language?: string
+}
+
+/**
+ * 接受邀请请求接口
+ */
+export interface InvitationAcceptRequest {
+    token: string
+    verification?: string
+    username: string
+    surName: string
+    givenName: string
+    mail: string
+    password: string
+    language?: string
+}
@@ -3,7 +3,8 @@ import type {
     RegisterRequest, 
     ChangePasswordRequest, 
     ModifyRoleRequest, 
-    ModifyCategoryRequest 
+    ModifyCategoryRequest,
+    ModifyExpiryRequest
 } from './types'
 
 /**
@@ -70,7 +71,7 @@ export function deleteUser(uid: string) {
  * 更改账号类型
  * @param {string} uid 用户ID，不能使用 'me'
  * @param {Object} reqData 修改账号类型请求数据
- * @param {string} reqData.category 账号类型 system|member|external
+ * @param {string} reqData.category 账号类型 system|member|external|alumni
  * @returns 修改结果
  */
 export function modifyUserCategory(uid: string, reqData: ModifyCategoryRequest) {
@@ -109,6 +110,7 @@ export function changeMyPassword(reqData: ChangePasswordRequest) {
  * @param {string} uid 用户ID，不能使用 'me'
  * @param {Object} reqData 修改账号角色请求数据
  * @param {string} reqData.role 角色 admin|default|restricted
+ * @param {string} reqData.expiresAt 临时授予的到期时间，为空时永久授予
  * @returns 修改结果
  */
 export function modifyUserRole(uid: string, reqData: ModifyRoleRequest) {
@@ -117,4 +119,19 @@ export function modifyUserRole(uid: string, reqData: ModifyRoleRequest) {
         method: 'PUT',
         data: reqData
     })
-} 
\ No newline at end of file
+} 
+
+/**
+ * 更改账号到期时间
+ * @param {string} uid 用户ID，不能使用 'me'
+ * @param {Object} reqData 修改到期时间请求数据
+ * @param {string} reqData.expiresAt 账号到期时间，为空时取消到期
+ * @returns 修改结果
+ */
+export function modifyUserExpiry(uid: string, reqData: ModifyExpiryRequest) {
+    return request({
+        url: `/users/${uid}/expiry`,
+        method: 'PUT',
+        data: reqData
+    })
+}
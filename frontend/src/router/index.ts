@@ -21,6 +21,24 @@ const routes: RouteRecordRaw[] = [
       requiresAuth: false
     }
   },
+  {
+    path: '/register',
+    name: 'Register',
+    component: () => import('../views/Register.vue'),
+    meta: {
+      title: '申请账号',
+      requiresAuth: false
+    }
+  },
+  {
+    path: '/invite',
+    name: 'Invite',
+    component: () => import('../views/Invite.vue'),
+    meta: {
+      title: '接受邀请',
+      requiresAuth: false
+    }
+  },
   {
     path: '/dashboard',
     name: 'Dashboard',
@@ -39,6 +57,15 @@ const routes: RouteRecordRaw[] = [
       requiresAuth: true
     }
   },
+  {
+    path: '/oauth/consent',
+    name: 'OAuthConsent',
+    component: () => import('../views/OAuthConsent.vue'),
+    meta: {
+      title: '授权确认',
+      requiresAuth: true
+    }
+  },
   {
     path: '/:pathMatch(.*)*',
     name: 'NotFound',
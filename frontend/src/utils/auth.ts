@@ -8,6 +8,9 @@ import type { User } from '@/api/types'
 // Token 相关常量
 const TOKEN_KEY = 'asynx_token'
 const TOKEN_EXPIRATION_DAY = 1 // 存Cookie的token的过期时间 => 1天
+// 转发认证 Cookie 所在的上级域名（如 asynclab.club），与后端的 FORWARD_AUTH_COOKIE_DOMAIN 一致，
+// 只用于判断登录后能否跳回该域名下的地址。登录令牌本身只保存在当前主机上
+const COOKIE_DOMAIN: string | undefined = import.meta.env.VITE_COOKIE_DOMAIN || undefined
 
 // 用户名相关常量
 const USERNAME_KEY = 'async_is_remember_username'
@@ -34,7 +37,7 @@ export function getToken(): string | undefined {
  * @returns 设置结果
  */
 export function setToken(token: string): string | undefined {
-    return Cookies.set(TOKEN_KEY, token, { expires: TOKEN_EXPIRATION_DAY })
+    return Cookies.set(TOKEN_KEY, token, { expires: TOKEN_EXPIRATION_DAY, sameSite: 'Lax' })
 }
 
 /**
@@ -43,6 +46,29 @@ export function setToken(token: string): string | undefined {
 export function removeToken(): void {
     sessionStorage.removeItem(TOKEN_KEY)
     Cookies.remove(TOKEN_KEY)
+    if (COOKIE_DOMAIN) {
+        // 清除旧版本保存在上级域名上的登录令牌
+        Cookies.remove(TOKEN_KEY, { domain: COOKIE_DOMAIN })
+    }
+}
+
+/**
+ * 判断登录后能否跳转到外部地址，只允许同源或 Cookie 所在域名下的地址，防止开放重定向
+ * @param target 完整地址
+ * @returns 是否允许跳转
+ */
+export function isTrustedRedirect(target: string): boolean {
+    let url: URL
+    try {
+        url = new URL(target, window.location.origin)
+    } catch {
+        return false
+    }
+    if (url.protocol !== 'http:' && url.protocol !== 'https:') return false
+    if (url.origin === window.location.origin) return true
+    if (!COOKIE_DOMAIN) return false
+    const domain = COOKIE_DOMAIN.replace(/^\./, '')
+    return url.hostname === domain || url.hostname.endsWith('.' + domain)
 }
 
 /**
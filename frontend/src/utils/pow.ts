@@ -0,0 +1,34 @@
+/**
+ * 工作量证明：寻找 nonce，使 SHA-256("<challenge>:<nonce>") 至少有 difficulty 个前导零比特
+ */
+
+const leadingZeroBits = (hash: Uint8Array) => {
+  let zeros = 0
+  for (const byte of hash) {
+    if (byte !== 0) {
+      return zeros + Math.clz32(byte) - 24
+    }
+    zeros += 8
+  }
+  return zeros
+}
+
+// 每批并行计算的哈希数量
+const batchSize = 256
+
+export async function solvePow(challenge: string, difficulty: number): Promise<string> {
+  if (difficulty <= 0) {
+    return ''
+  }
+  const encoder = new TextEncoder()
+  for (let start = 0; ; start += batchSize) {
+    const nonces = Array.from({ length: batchSize }, (_, i) => String(start + i))
+    const hashes = await Promise.all(
+      nonces.map((nonce) => crypto.subtle.digest('SHA-256', encoder.encode(`${challenge}:${nonce}`)))
+    )
+    const found = hashes.findIndex((hash) => leadingZeroBits(new Uint8Array(hash)) >= difficulty)
+    if (found >= 0) {
+      return nonces[found]
+    }
+  }
+}
@@ -37,12 +37,25 @@ const request = axios.create({
     baseURL: getApiBaseUrl()
 })
 
+// 不需要认证的接口
+const publicApis = ['/login', '/tokens', '/registration-requests/challenge', '/registration-requests/verify', '/invitations/lookup', '/invitations/verify', '/invitations/accept']
+
+const isPublicApi = (config?: InternalAxiosRequestConfig) => {
+    if (!config) {
+        return false
+    }
+    // 提交注册申请无需登录，同一地址的申请列表需要登录
+    if (config.url === '/registration-requests' && config.method === 'post') {
+        return true
+    }
+    return publicApis.includes(config.url || '')
+}
+
 // 添加请求拦截-token处理
 request.interceptors.request.use(
     (config: InternalAxiosRequestConfig) => {
         // 不需要认证的接口直接放行
-        const publicApis = ['/login', '/tokens']
-        if (publicApis.includes(config.url || '')) {
+        if (isPublicApi(config)) {
             return config
         }
 
@@ -85,7 +98,7 @@ request.interceptors.response.use(
             useFailedTip('响应错误：' + error.message)
         }
         
-        if (!getToken()) {
+        if (!getToken() && !isPublicApi(error.config)) {
             useWarningConfirm('登录异常，即将跳转登录页，重新登录').then(() => {
                 // router.push('/login')
                 window.location.reload()